@@ -0,0 +1,383 @@
+package alerts
+
+import (
+	"ai-search/internal/events"
+	"ai-search/internal/indexer"
+	"ai-search/internal/metrics"
+	"ai-search/internal/retriever"
+	"ai-search/internal/store"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Documents are matched against a saved search in batches of batchSize,
+// retrieving up to resultsPerDocument chunks for each
+const (
+	batchSize          = 100
+	resultsPerDocument = 3
+)
+
+// Alerter defines the interface for saved search alerts
+type Alerter interface {
+	// Observe queues the documents of index events for the next check. It
+	// is an events.Handler.
+	Observe(event events.Event)
+
+	// Check matches the queued documents against every saved search and
+	// sends an alert to each search they match, returning how many were sent
+	Check(ctx context.Context) (int, error)
+
+	// Run checks on the given interval until the context is cancelled
+	Run(ctx context.Context, interval time.Duration)
+}
+
+// SMTPConfig sets the mail server alerts are emailed through
+type SMTPConfig struct {
+	Addr     string // host:port, email alerts are disabled when empty
+	Username string // Authenticates with PLAIN auth when set
+	Password string
+	From     string
+}
+
+// Config holds alerting configuration
+type Config struct {
+	Store     store.Store
+	Retriever retriever.Retriever
+	SMTP      SMTPConfig
+	Timeout   time.Duration // Allowed per webhook delivery
+}
+
+// Match is a newly indexed document matching a saved search
+type Match struct {
+	DocumentID string  `json:"document_id"`
+	URL        string  `json:"url,omitempty"`
+	Title      string  `json:"title,omitempty"`
+	Score      float32 `json:"score"`
+	Text       string  `json:"text,omitempty"` // The best matching chunk
+}
+
+// Alert is sent to a saved search's webhook as JSON, and emailed
+type Alert struct {
+	SavedSearch *store.SavedSearch `json:"saved_search"`
+	Matches     []*Match           `json:"matches"`
+	Time        time.Time          `json:"time"`
+}
+
+// alerter implements the Alerter interface
+type alerter struct {
+	config     Config
+	httpClient *http.Client
+
+	mutex   sync.Mutex
+	pending map[string]bool
+}
+
+// NewAlerter creates a new alerter instance
+func NewAlerter(config Config) Alerter {
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+
+	return &alerter{
+		config:     config,
+		httpClient: &http.Client{Timeout: config.Timeout},
+		pending:    make(map[string]bool),
+	}
+}
+
+// Observe queues the documents of document.indexed events
+func (a *alerter) Observe(event events.Event) {
+	if event.Type != events.DocumentIndexed {
+		return
+	}
+
+	a.mutex.Lock()
+	for _, id := range event.DocumentIDs {
+		a.pending[id] = true
+	}
+	a.mutex.Unlock()
+}
+
+// take removes and returns the queued documents
+func (a *alerter) take() []string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	ids := make([]string, 0, len(a.pending))
+	for id := range a.pending {
+		ids = append(ids, id)
+	}
+	a.pending = make(map[string]bool)
+
+	sort.Strings(ids)
+	return ids
+}
+
+// requeue returns documents to the queue after a failed check
+func (a *alerter) requeue(ids []string) {
+	a.mutex.Lock()
+	for _, id := range ids {
+		a.pending[id] = true
+	}
+	a.mutex.Unlock()
+}
+
+// Check matches the queued documents against every saved search. A
+// document is recorded as alerted before delivery, so that when several
+// servers receive the same events only one alerts it; a failed delivery
+// is logged and not retried.
+func (a *alerter) Check(ctx context.Context) (int, error) {
+	ids := a.take()
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	searches, err := a.config.Store.ListSavedSearches(ctx)
+	if err != nil {
+		a.requeue(ids)
+		return 0, err
+	}
+
+	sent := 0
+	for _, search := range searches {
+		if ctx.Err() != nil {
+			return sent, ctx.Err()
+		}
+
+		matches, err := a.match(ctx, search, ids)
+		if err != nil {
+			log.Printf("Saved search %d failed: %v", search.ID, err)
+			continue
+		}
+		if len(matches) == 0 {
+			continue
+		}
+
+		matches, err = a.record(ctx, search, matches)
+		if err != nil {
+			log.Printf("Failed to record alerts of saved search %d: %v", search.ID, err)
+			continue
+		}
+		if len(matches) == 0 {
+			continue
+		}
+
+		if a.send(ctx, &Alert{SavedSearch: search, Matches: matches, Time: time.Now().UTC()}) {
+			sent++
+		}
+	}
+
+	return sent, nil
+}
+
+// match runs a saved search over the given documents, returning the best
+// scoring chunk of each document scoring at least the search's minimum
+func (a *alerter) match(ctx context.Context, search *store.SavedSearch, ids []string) ([]*Match, error) {
+	var access *indexer.Access
+	if len(search.Principals) > 0 {
+		access = &indexer.Access{Principals: search.Principals}
+	}
+
+	best := make(map[string]*Match)
+	for start := 0; start < len(ids); start += batchSize {
+		batch := ids[start:min(start+batchSize, len(ids))]
+		opts := indexer.SearchOptions{
+			Filters:   search.Filters,
+			Documents: batch,
+			Access:    access,
+		}
+
+		results, err := a.config.Retriever.Retrieve(ctx, search.Query, len(batch)*resultsPerDocument, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, result := range results {
+			if result.Score < search.MinScore {
+				continue
+			}
+			attributes, _ := result.Metadata["attributes"].(map[string]string)
+			if !access.Allows(attributes[indexer.ACLAttribute]) {
+				continue
+			}
+			if existing := best[result.DocumentID]; existing != nil && existing.Score >= result.Score {
+				continue
+			}
+
+			url, _ := result.Metadata["url"].(string)
+			title, _ := result.Metadata["title"].(string)
+			best[result.DocumentID] = &Match{
+				DocumentID: result.DocumentID,
+				URL:        url,
+				Title:      title,
+				Score:      result.Score,
+				Text:       result.Text,
+			}
+		}
+	}
+
+	matches := make([]*Match, 0, len(best))
+	for _, match := range best {
+		matches = append(matches, match)
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	return matches, nil
+}
+
+// record keeps the matches the saved search has not alerted before
+func (a *alerter) record(ctx context.Context, search *store.SavedSearch, matches []*Match) ([]*Match, error) {
+	ids := make([]string, len(matches))
+	for i, match := range matches {
+		ids[i] = match.DocumentID
+	}
+
+	recorded, err := a.config.Store.RecordAlerts(ctx, search.ID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	isNew := make(map[string]bool, len(recorded))
+	for _, id := range recorded {
+		isNew[id] = true
+	}
+
+	var fresh []*Match
+	for _, match := range matches {
+		if isNew[match.DocumentID] {
+			fresh = append(fresh, match)
+		}
+	}
+	return fresh, nil
+}
+
+// send delivers an alert to the saved search's webhook and email,
+// reporting whether any delivery succeeded
+func (a *alerter) send(ctx context.Context, alert *Alert) bool {
+	delivered := false
+
+	if alert.SavedSearch.Webhook != "" {
+		if err := a.postWebhook(ctx, alert); err != nil {
+			log.Printf("Failed to deliver alert of saved search %d to %s: %v", alert.SavedSearch.ID, alert.SavedSearch.Webhook, err)
+			metrics.AlertsFailed.Inc("webhook")
+		} else {
+			metrics.AlertsSent.Inc("webhook")
+			delivered = true
+		}
+	}
+
+	if alert.SavedSearch.Email != "" {
+		if err := a.sendEmail(alert); err != nil {
+			log.Printf("Failed to email alert of saved search %d to %s: %v", alert.SavedSearch.ID, alert.SavedSearch.Email, err)
+			metrics.AlertsFailed.Inc("email")
+		} else {
+			metrics.AlertsSent.Inc("email")
+			delivered = true
+		}
+	}
+
+	return delivered
+}
+
+// postWebhook sends an alert as a JSON POST
+func (a *alerter) postWebhook(ctx context.Context, alert *Alert) error {
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", alert.SavedSearch.Webhook, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sendEmail mails an alert as plain text listing its matches
+func (a *alerter) sendEmail(alert *Alert) error {
+	smtpConfig := a.config.SMTP
+	if smtpConfig.Addr == "" {
+		return fmt.Errorf("SMTP server not configured")
+	}
+
+	var auth smtp.Auth
+	if smtpConfig.Username != "" {
+		host, _, err := net.SplitHostPort(smtpConfig.Addr)
+		if err != nil {
+			return fmt.Errorf("invalid SMTP address: %w", err)
+		}
+		auth = smtp.PlainAuth("", smtpConfig.Username, smtpConfig.Password, host)
+	}
+
+	return smtp.SendMail(smtpConfig.Addr, auth, smtpConfig.From, []string{alert.SavedSearch.Email}, emailMessage(smtpConfig.From, alert))
+}
+
+// emailMessage formats an alert as an email with headers
+func emailMessage(from string, alert *Alert) []byte {
+	search := alert.SavedSearch
+	name := search.Name
+	if name == "" {
+		name = search.Query
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", from)
+	fmt.Fprintf(&body, "To: %s\r\n", search.Email)
+	fmt.Fprintf(&body, "Subject: %d new results for %s\r\n", len(alert.Matches), strings.ReplaceAll(name, "\n", " "))
+	fmt.Fprintf(&body, "Date: %s\r\n", alert.Time.Format(time.RFC1123Z))
+	body.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+
+	fmt.Fprintf(&body, "New documents match your saved search %q:\r\n\r\n", search.Query)
+	for _, match := range alert.Matches {
+		title := match.Title
+		if title == "" {
+			title = match.URL
+		}
+		fmt.Fprintf(&body, "%s (score %.2f)\r\n%s\r\n\r\n", title, match.Score, match.URL)
+	}
+
+	return []byte(body.String())
+}
+
+// Run checks for new matches on the given interval until the context is
+// cancelled
+func (a *alerter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		sent, err := a.Check(ctx)
+		if err != nil {
+			log.Printf("Saved search alert error: %v", err)
+		}
+		if sent > 0 {
+			log.Printf("Sent %d saved search alerts", sent)
+		}
+	}
+}
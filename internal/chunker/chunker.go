@@ -3,9 +3,11 @@ package chunker
 import (
 	"crypto/sha256"
 	"fmt"
-	"regexp"
 	"strings"
 	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
 )
 
 // Chunker defines the interface for text chunking
@@ -23,21 +25,36 @@ type Chunk struct {
 	Metadata map[string]interface{}
 }
 
+// Chunking strategies
+const (
+	StrategySentence = "sentence" // Break chunks between sentences
+	StrategyFixed    = "fixed"    // Fill chunks to the size, breaking between words
+)
+
 // Config holds chunker configuration
 type Config struct {
 	ChunkSize    int
 	OverlapSize  int
 	MinChunkSize int
+	Strategy     string
+
+	// Tables also indexes each data table of a document as chunks of its
+	// own, so a table is retrieved whole rather than split across the
+	// chunks of the surrounding text
+	Tables bool
+
+	// FoldDiacritics also keyword-searches chunks with accents folded, so
+	// that "café" and "cafe" match each other. Chunk text keeps its accents.
+	FoldDiacritics bool
 }
 
-// textChunker implements the Chunker interface
-type textChunker struct {
-	config Config
+// ValidStrategy reports whether name is a known chunking strategy
+func ValidStrategy(name string) bool {
+	return name == StrategySentence || name == StrategyFixed
 }
 
-// NewTextChunker creates a new text chunker
-func NewTextChunker(config Config) Chunker {
-	// Set defaults
+// WithDefaults returns the config with unset fields given their defaults
+func (config Config) WithDefaults() Config {
 	if config.ChunkSize == 0 {
 		config.ChunkSize = 1000 // Default chunk size
 	}
@@ -47,9 +64,21 @@ func NewTextChunker(config Config) Chunker {
 	if config.MinChunkSize == 0 {
 		config.MinChunkSize = 100 // Minimum chunk size
 	}
+	if !ValidStrategy(config.Strategy) {
+		config.Strategy = StrategySentence
+	}
+	return config
+}
+
+// textChunker implements the Chunker interface
+type textChunker struct {
+	config Config
+}
 
+// NewTextChunker creates a new text chunker
+func NewTextChunker(config Config) Chunker {
 	return &textChunker{
-		config: config,
+		config: config.WithDefaults(),
 	}
 }
 
@@ -59,18 +88,49 @@ func (c *textChunker) Chunk(text string) []*Chunk {
 		return []*Chunk{}
 	}
 
-	// Clean and normalize text
-	text = c.cleanText(text)
+	// Clean and normalize the prose to NFC, keeping fenced code blocks
+	// verbatim, so that text composed differently is embedded alike.
+	// Prose is split into its lines, the paragraphs, headings and list
+	// items of the page, and those into sentences for better chunk
+	// boundaries, or into words when chunks are filled to a fixed size;
+	// code blocks between their lines when they do not fit a chunk.
+	var units []unit
+	var normalized []string
+	for _, segment := range splitCode(text) {
+		if segment.code {
+			normalized = append(normalized, segment.text)
+			units = append(units, codeUnits(segment.text, c.config.ChunkSize)...)
+			continue
+		}
 
-	// Split into sentences for better chunk boundaries
-	sentences := c.splitIntoSentences(text)
+		for _, line := range proseLines(segment.text) {
+			prose := c.cleanText(Normalize(line))
+			if prose == "" {
+				continue
+			}
+			normalized = append(normalized, prose)
+			var pieces []string
+			if c.config.Strategy == StrategyFixed {
+				pieces = strings.Fields(prose)
+			} else {
+				pieces = splitIntoSentences(prose)
+			}
+			for i, piece := range pieces {
+				units = append(units, unit{text: piece, line: i == 0})
+			}
+		}
+	}
+	text = strings.Join(normalized, "\n")
 
 	var chunks []*Chunk
 	var currentChunk strings.Builder
 	var startPos int
 	chunkID := 0
+	lastCode := false
+
+	for _, u := range units {
+		sentence := u.text
 
-	for _, sentence := range sentences {
 		// Check if adding this sentence would exceed chunk size
 		if currentChunk.Len()+len(sentence) > c.config.ChunkSize && currentChunk.Len() > 0 {
 			// Create chunk from current content
@@ -81,18 +141,29 @@ func (c *textChunker) Chunk(text string) []*Chunk {
 				chunkID++
 			}
 
-			// Start new chunk with overlap
-			overlapText := c.getOverlapText(chunkText)
+			// Start new chunk with overlap. A chunk ending in code has
+			// none, as a tail of code out of its block would read as prose.
 			currentChunk.Reset()
-			currentChunk.WriteString(overlapText)
-			startPos = c.calculateStartPos(text, overlapText)
+			if lastCode {
+				startPos = c.calculateStartPos(text, sentence)
+			} else {
+				overlapText := c.getOverlapText(chunkText)
+				currentChunk.WriteString(overlapText)
+				startPos = c.calculateStartPos(text, overlapText)
+			}
 		}
 
-		// Add current sentence
+		// Add current sentence, code blocks and lines of prose on lines of
+		// their own
 		if currentChunk.Len() > 0 {
-			currentChunk.WriteString(" ")
+			if u.code || lastCode || u.line {
+				currentChunk.WriteString("\n")
+			} else {
+				currentChunk.WriteString(" ")
+			}
 		}
 		currentChunk.WriteString(sentence)
+		lastCode = u.code
 	}
 
 	// Add final chunk if it has content
@@ -107,29 +178,143 @@ func (c *textChunker) Chunk(text string) []*Chunk {
 	return chunks
 }
 
-// cleanText cleans and normalizes text
+// proseLines splits prose into its lines. A line starting in lower case
+// after one that does not end a sentence continues a sentence wrapped
+// across lines, as in text extracted from PDFs, and is joined to it.
+func proseLines(prose string) []string {
+	var lines []string
+	for _, line := range strings.Split(prose, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		if len(lines) > 0 && continuesLine(lines[len(lines)-1], trimmed) {
+			lines[len(lines)-1] += " " + trimmed
+			continue
+		}
+		lines = append(lines, trimmed)
+	}
+	return lines
+}
+
+// continuesLine reports whether line continues the sentence of the line
+// before
+func continuesLine(before, line string) bool {
+	first, _ := utf8.DecodeRuneInString(line)
+	last := before[len(before)-1]
+	return unicode.IsLower(first) && !isTerminator(last) && last != ':'
+}
+
+// Normalize returns text in Unicode normalization form NFC, composing
+// letters and their combining accents into single characters the way most
+// text is written. Queries are normalized alike to match indexed chunks.
+func Normalize(text string) string {
+	return norm.NFC.String(text)
+}
+
+// cleanText cleans and normalizes text in a single pass. Runs of
+// whitespace collapse to one space and control characters are dropped;
+// text that is already clean is returned without copying.
 func (c *textChunker) cleanText(text string) string {
-	// Remove extra whitespace
-	text = regexp.MustCompile(`\s+`).ReplaceAllString(text, " ")
+	if isClean(text) {
+		return text
+	}
+
+	var builder strings.Builder
+	builder.Grow(len(text))
+
+	pendingSpace := false
+	for i := 0; i < len(text); i++ {
+		// Bytes below 0x80 never occur inside multi-byte UTF-8 sequences,
+		// so scanning bytes is safe here
+		ch := text[i]
+		switch {
+		case isSpace(ch):
+			pendingSpace = builder.Len() > 0
+		case ch < 0x20 || ch == 0x7F:
+			// Remove control characters
+		default:
+			if pendingSpace {
+				builder.WriteByte(' ')
+				pendingSpace = false
+			}
+			builder.WriteByte(ch)
+		}
+	}
+
+	return builder.String()
+}
+
+// isClean reports whether text needs no normalization
+func isClean(text string) bool {
+	if text == "" {
+		return true
+	}
+	if text[0] == ' ' || text[len(text)-1] == ' ' {
+		return false
+	}
 
-	// Remove control characters
-	text = regexp.MustCompile(`[\x00-\x1F\x7F]`).ReplaceAllString(text, "")
+	prevSpace := false
+	for i := 0; i < len(text); i++ {
+		ch := text[i]
+		if ch == ' ' {
+			if prevSpace {
+				return false
+			}
+			prevSpace = true
+			continue
+		}
+		if ch < 0x20 || ch == 0x7F {
+			return false
+		}
+		prevSpace = false
+	}
 
-	return strings.TrimSpace(text)
+	return true
 }
 
-// splitIntoSentences splits text into sentences
-func (c *textChunker) splitIntoSentences(text string) []string {
-	// Simple sentence splitting based on punctuation
-	re := regexp.MustCompile(`[.!?]+\s+`)
-	sentences := re.Split(text, -1)
+// isSpace matches the ASCII whitespace class used for normalization
+func isSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\f' || ch == '\r'
+}
+
+// isTerminator reports whether ch ends a sentence
+func isTerminator(ch byte) bool {
+	return ch == '.' || ch == '!' || ch == '?'
+}
 
+// splitIntoSentences splits text into sentences at runs of terminal
+// punctuation followed by whitespace. Sentences are substrings of text.
+func splitIntoSentences(text string) []string {
 	var result []string
-	for _, sentence := range sentences {
-		sentence = strings.TrimSpace(sentence)
-		if sentence != "" {
+
+	start := 0
+	for i := 0; i < len(text); {
+		if !isTerminator(text[i]) {
+			i++
+			continue
+		}
+
+		// Consume the punctuation run and require whitespace after it
+		end := i
+		for i < len(text) && isTerminator(text[i]) {
+			i++
+		}
+		if i >= len(text) || !isSpace(text[i]) {
+			continue
+		}
+		for i < len(text) && isSpace(text[i]) {
+			i++
+		}
+
+		if sentence := strings.TrimSpace(text[start:end]); sentence != "" {
 			result = append(result, sentence)
 		}
+		start = i
+	}
+
+	if sentence := strings.TrimSpace(text[start:]); sentence != "" {
+		result = append(result, sentence)
 	}
 
 	return result
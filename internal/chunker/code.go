@@ -0,0 +1,97 @@
+package chunker
+
+import (
+	"regexp"
+	"strings"
+)
+
+// codeFencePattern matches a line opening or closing a fenced code block,
+// as the parsers write <pre> elements and Markdown code fences
+var codeFencePattern = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})(.*)$")
+
+// unit is a piece of text the chunker fills chunks with: a sentence or
+// word of prose, or a fenced code block or part of one
+type unit struct {
+	text string
+	code bool
+	line bool // Whether the unit starts a line of prose
+}
+
+// splitCode splits text into runs of prose and the fenced code blocks
+// between them, fences included. An unclosed fence runs to the end of the
+// text.
+func splitCode(text string) []unit {
+	if !strings.Contains(text, "```") && !strings.Contains(text, "~~~") {
+		return []unit{{text: text}}
+	}
+
+	var segments []unit
+	var current strings.Builder
+	fence := ""
+	flush := func(code bool) {
+		if block := current.String(); strings.TrimSpace(block) != "" {
+			segments = append(segments, unit{text: strings.Trim(block, "\r\n"), code: code})
+		}
+		current.Reset()
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		match := codeFencePattern.FindStringSubmatch(strings.TrimRight(line, "\r\n"))
+		switch {
+		case fence == "" && match != nil && !(match[1][0] == '`' && strings.Contains(match[2], "`")):
+			flush(false)
+			fence = match[1]
+			current.WriteString(line)
+		case fence != "" && match != nil && strings.HasPrefix(match[1], fence) && strings.TrimSpace(match[2]) == "":
+			current.WriteString(line)
+			flush(true)
+			fence = ""
+		default:
+			current.WriteString(line)
+		}
+	}
+	flush(fence != "")
+
+	return segments
+}
+
+// codeUnits splits a fenced code block longer than size between its lines,
+// repeating the fences around each part so that every part reads as code.
+// A single line longer than size is kept whole.
+func codeUnits(block string, size int) []unit {
+	if len(block) <= size {
+		return []unit{{text: block, code: true}}
+	}
+
+	lines := strings.Split(block, "\n")
+	open, body, closing := lines[0], lines[1:], ""
+	if last := len(body) - 1; last >= 0 && codeFencePattern.MatchString(body[last]) {
+		closing, body = body[last], body[:last]
+	}
+	if closing == "" {
+		closing = strings.TrimLeft(open, " ")
+		closing = closing[:len(closing)-len(strings.TrimLeft(closing, "`~"))]
+	}
+
+	var units []unit
+	var part strings.Builder
+	flush := func() {
+		if part.Len() > 0 {
+			units = append(units, unit{text: open + "\n" + part.String() + "\n" + closing, code: true})
+			part.Reset()
+		}
+	}
+	room := size - len(open) - len(closing) - 2
+	for _, line := range body {
+		if part.Len() > 0 && part.Len()+1+len(line) > room {
+			flush()
+		}
+		if part.Len() > 0 {
+			part.WriteString("\n")
+		}
+		part.WriteString(line)
+	}
+	flush()
+
+	return units
+}
@@ -0,0 +1,313 @@
+package chunker
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Emoji handling of the text filter
+const (
+	EmojiKeep      = "keep"      // Leave emoji as written
+	EmojiNormalize = "normalize" // Reduce each emoji sequence to its base emoji, once per run
+	EmojiStrip     = "strip"     // Drop emoji
+)
+
+// maxNoticeWords is the most words a sentence mentioning cookies may have
+// to be taken for a cookie notice; longer ones are about cookies
+const maxNoticeWords = 60
+
+// boilerplateSentences are sentences that pages carry around their content
+// in cookie banners, share buttons and navigation, keyed by sentenceKey
+var boilerplateSentences = map[string]bool{
+	"accept": true, "accept all": true, "accept all cookies": true, "accept cookies": true,
+	"allow all": true, "allow all cookies": true, "allow cookies": true, "reject all": true,
+	"reject all cookies": true, "decline": true, "decline all": true, "got it": true, "i agree": true,
+	"ok, got it": true, "manage cookies": true, "manage preferences": true, "cookie settings": true,
+	"cookie preferences": true, "customize settings": true, "necessary cookies only": true,
+	"share": true, "share this": true, "share this article": true, "share this post": true,
+	"share this page": true, "share on facebook": true, "share on twitter": true, "share on x": true,
+	"share on linkedin": true, "share via email": true, "tweet": true, "pin it": true, "email this": true,
+	"copy link": true, "print this page": true, "skip to content": true, "skip to main content": true,
+	"back to top": true, "scroll to top": true, "follow us": true, "subscribe to our newsletter": true,
+	"sign up for our newsletter": true,
+}
+
+// noticeMarkers are words that, with "cookie", make a sentence a cookie
+// notice rather than text about cookies
+var noticeMarkers = []string{
+	"we use", "this site uses", "this website uses", "uses cookies", "consent", "accept",
+	"agree", "by continuing", "your experience", "your preferences",
+}
+
+// FilterConfig holds text filter configuration
+type FilterConfig struct {
+	// Boilerplate drops the sentences of cookie notices, consent buttons,
+	// share links and navigation that pages commonly carry
+	Boilerplate bool
+
+	// Phrases are further sentences to drop, such as those BoilerplateFinder
+	// found repeated across the corpus. Case and spacing do not matter.
+	Phrases []string
+
+	// Emoji is EmojiKeep, EmojiNormalize or EmojiStrip, keep by default
+	Emoji string
+}
+
+// ValidEmoji reports whether name is a known emoji handling
+func ValidEmoji(name string) bool {
+	return name == EmojiKeep || name == EmojiNormalize || name == EmojiStrip
+}
+
+// filteredChunker drops boilerplate from the text another chunker chunks
+type filteredChunker struct {
+	chunker     Chunker
+	boilerplate bool
+	phrases     map[string]bool
+	emoji       string
+}
+
+// NewFilteredChunker wraps a chunker so that boilerplate sentences are
+// dropped from documents and their emoji normalized before they are
+// chunked. Fenced code blocks are kept as they are. A config filtering
+// nothing returns the chunker unchanged.
+func NewFilteredChunker(chunker Chunker, config FilterConfig) Chunker {
+	if !ValidEmoji(config.Emoji) {
+		config.Emoji = EmojiKeep
+	}
+	if !config.Boilerplate && len(config.Phrases) == 0 && config.Emoji == EmojiKeep {
+		return chunker
+	}
+
+	phrases := make(map[string]bool, len(config.Phrases))
+	for _, phrase := range config.Phrases {
+		if key := sentenceKey(phrase); key != "" {
+			phrases[key] = true
+		}
+	}
+	return &filteredChunker{
+		chunker:     chunker,
+		boilerplate: config.Boilerplate,
+		phrases:     phrases,
+		emoji:       config.Emoji,
+	}
+}
+
+// Chunk filters text and splits what remains into chunks
+func (c *filteredChunker) Chunk(text string) []*Chunk {
+	return c.chunker.Chunk(c.filter(text))
+}
+
+// filter drops the boilerplate sentences of the prose of a text, line by
+// line, and normalizes its emoji
+func (c *filteredChunker) filter(text string) string {
+	var segments []string
+	for _, segment := range splitCode(text) {
+		if segment.code {
+			segments = append(segments, segment.text)
+			continue
+		}
+
+		var lines []string
+		for _, line := range strings.Split(segment.text, "\n") {
+			var kept []string
+			for _, sentence := range splitPunctuated(line) {
+				if c.isBoilerplate(sentenceKey(sentence)) {
+					continue
+				}
+				if sentence = filterEmoji(sentence, c.emoji); strings.TrimSpace(sentence) != "" {
+					kept = append(kept, sentence)
+				}
+			}
+			if len(kept) > 0 {
+				lines = append(lines, strings.Join(kept, " "))
+			}
+		}
+		if len(lines) > 0 {
+			segments = append(segments, strings.Join(lines, "\n"))
+		}
+	}
+	return strings.Join(segments, "\n")
+}
+
+// splitPunctuated splits text into sentences the way splitIntoSentences
+// does, but keeping the punctuation that ends each
+func splitPunctuated(text string) []string {
+	var result []string
+
+	start := 0
+	for i := 0; i < len(text); {
+		if !isTerminator(text[i]) {
+			i++
+			continue
+		}
+		for i < len(text) && isTerminator(text[i]) {
+			i++
+		}
+		if i < len(text) && !isSpace(text[i]) {
+			continue
+		}
+
+		if sentence := strings.TrimSpace(text[start:i]); sentence != "" {
+			result = append(result, sentence)
+		}
+		start = i
+	}
+
+	if sentence := strings.TrimSpace(text[start:]); sentence != "" {
+		result = append(result, sentence)
+	}
+	return result
+}
+
+// isBoilerplate reports whether the sentence with the given key is dropped
+func (c *filteredChunker) isBoilerplate(key string) bool {
+	if c.phrases[key] {
+		return true
+	}
+	return c.boilerplate && (boilerplateSentences[key] || isCookieNotice(key))
+}
+
+// isCookieNotice reports whether a sentence asks for or announces the use
+// of cookies
+func isCookieNotice(key string) bool {
+	if !strings.Contains(key, "cookie") || len(strings.Fields(key)) > maxNoticeWords {
+		return false
+	}
+	for _, marker := range noticeMarkers {
+		if strings.Contains(key, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// sentenceKey returns the form sentences are compared in: lower case,
+// single-spaced and without punctuation or emoji around it
+func sentenceKey(sentence string) string {
+	key := strings.ToLower(strings.Join(strings.Fields(sentence), " "))
+	return strings.TrimFunc(key, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSpace(r) || unicode.IsSymbol(r) || isEmojiPart(r)
+	})
+}
+
+// filterEmoji normalizes or strips the emoji of a sentence
+func filterEmoji(sentence, mode string) string {
+	if mode == EmojiKeep || !strings.ContainsFunc(sentence, isEmojiPart) {
+		return sentence
+	}
+
+	var builder strings.Builder
+	builder.Grow(len(sentence))
+	var last rune
+	joined := false
+	for _, r := range sentence {
+		switch {
+		case !isEmojiPart(r):
+			builder.WriteRune(r)
+			last = r
+		case mode == EmojiStrip:
+			// Dropped with its modifiers and joiners
+		case r == zeroWidthJoiner:
+			joined = true
+		case isEmojiModifier(r):
+			// Skin tones, variation selectors and tags shade the emoji
+			// before them
+		case joined:
+			// An emoji joined to the one before makes a sequence, kept
+			// as its first emoji
+			joined = false
+		case r != last:
+			builder.WriteRune(r)
+			last = r
+		}
+	}
+	return builder.String()
+}
+
+// zeroWidthJoiner joins emoji into a single sequence, as in family emoji
+const zeroWidthJoiner = '\u200d'
+
+// isEmojiPart reports whether a rune is an emoji or a part of an emoji
+// sequence
+func isEmojiPart(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF, // Pictographs, emoticons, symbols and flags
+		r >= 0x2600 && r <= 0x27BF, // Miscellaneous symbols and dingbats
+		r >= 0x2B00 && r <= 0x2BFF, // Arrows and stars
+		r == zeroWidthJoiner, r == 0x20E3:
+		return true
+	}
+	return isEmojiModifier(r)
+}
+
+// isEmojiModifier reports whether a rune shades the emoji before it: a
+// skin tone, a variation selector or a tag
+func isEmojiModifier(r rune) bool {
+	return (r >= 0x1F3FB && r <= 0x1F3FF) || r == 0xFE0E || r == 0xFE0F || (r >= 0xE0020 && r <= 0xE007F)
+}
+
+// maxBoilerplateCandidates bounds the sentences counted at once. Past it,
+// sentences seen in a single document so far are dropped.
+const maxBoilerplateCandidates = 2_000_000
+
+// BoilerplateFinder finds the sentences repeated across the documents of a
+// corpus, such as site footers and legal notices
+type BoilerplateFinder struct {
+	counts map[string]int
+}
+
+// NewBoilerplateFinder creates a finder with no documents counted yet
+func NewBoilerplateFinder() *BoilerplateFinder {
+	return &BoilerplateFinder{counts: make(map[string]int)}
+}
+
+// Add counts the distinct sentences of a document's prose
+func (f *BoilerplateFinder) Add(text string) {
+	seen := make(map[string]bool)
+	for _, segment := range splitCode(text) {
+		if segment.code {
+			continue
+		}
+		for _, line := range strings.Split(segment.text, "\n") {
+			for _, sentence := range splitPunctuated(line) {
+				if key := sentenceKey(sentence); key != "" && !seen[key] {
+					seen[key] = true
+					f.counts[key]++
+				}
+			}
+		}
+	}
+
+	if len(f.counts) > maxBoilerplateCandidates {
+		for key, count := range f.counts {
+			if count == 1 {
+				delete(f.counts, key)
+			}
+		}
+	}
+}
+
+// Boilerplate returns the sentences found in at least minDocuments of the
+// documents counted, most repeated first
+func (f *BoilerplateFinder) Boilerplate(minDocuments int) []string {
+	var sentences []string
+	for key, count := range f.counts {
+		if count >= minDocuments {
+			sentences = append(sentences, key)
+		}
+	}
+	sort.Slice(sentences, func(i, j int) bool {
+		if f.counts[sentences[i]] != f.counts[sentences[j]] {
+			return f.counts[sentences[i]] > f.counts[sentences[j]]
+		}
+		return sentences[i] < sentences[j]
+	})
+	return sentences
+}
+
+// Documents returns how many documents a sentence returned by Boilerplate
+// was found in
+func (f *BoilerplateFinder) Documents(sentence string) int {
+	return f.counts[sentence]
+}
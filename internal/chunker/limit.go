@@ -0,0 +1,120 @@
+package chunker
+
+import (
+	"sort"
+	"strings"
+)
+
+// Sampling strategies choosing the chunks kept from a document with more
+// chunks than allowed
+const (
+	SamplingHeadTail   = "head-tail"  // Keep the first and last chunks
+	SamplingImportance = "importance" // Keep the first chunk and those with the most distinct words
+)
+
+// TotalChunksKey is the chunk metadata key recording how many chunks the
+// document had before it was sampled
+const TotalChunksKey = "document_chunks_total"
+
+// ValidSampling reports whether name is a known sampling strategy
+func ValidSampling(name string) bool {
+	return name == SamplingHeadTail || name == SamplingImportance
+}
+
+// limitedChunker caps the chunks another chunker produces for a document
+type limitedChunker struct {
+	chunker   Chunker
+	maxChunks int
+	sampling  string
+}
+
+// NewLimitedChunker wraps a chunker so that no document yields more than
+// maxChunks chunks. Documents with more are sampled with the given
+// strategy and their chunks record the original count under
+// TotalChunksKey. A maxChunks of 0 or less returns the chunker unchanged.
+func NewLimitedChunker(chunker Chunker, maxChunks int, sampling string) Chunker {
+	if maxChunks <= 0 {
+		return chunker
+	}
+	if !ValidSampling(sampling) {
+		sampling = SamplingHeadTail
+	}
+	return &limitedChunker{
+		chunker:   chunker,
+		maxChunks: maxChunks,
+		sampling:  sampling,
+	}
+}
+
+// Chunk splits text into chunks and samples them down to the cap
+func (c *limitedChunker) Chunk(text string) []*Chunk {
+	chunks := c.chunker.Chunk(text)
+	if len(chunks) <= c.maxChunks {
+		return chunks
+	}
+
+	var kept []*Chunk
+	if c.sampling == SamplingImportance {
+		kept = sampleImportance(chunks, c.maxChunks)
+	} else {
+		kept = sampleHeadTail(chunks, c.maxChunks)
+	}
+
+	for _, chunk := range kept {
+		if chunk.Metadata == nil {
+			chunk.Metadata = make(map[string]interface{})
+		}
+		chunk.Metadata[TotalChunksKey] = len(chunks)
+	}
+	return kept
+}
+
+// Truncated returns how many chunks a document had before the chunks
+// given were sampled from them, and false if they were not sampled
+func Truncated(chunks []*Chunk) (int, bool) {
+	if len(chunks) == 0 || chunks[0].Metadata == nil {
+		return 0, false
+	}
+	total, ok := chunks[0].Metadata[TotalChunksKey].(int)
+	return total, ok
+}
+
+// sampleHeadTail keeps the first and last chunks of a document, which
+// usually hold its introduction and conclusion
+func sampleHeadTail(chunks []*Chunk, maxChunks int) []*Chunk {
+	head := maxChunks - maxChunks/2
+	kept := make([]*Chunk, 0, maxChunks)
+	kept = append(kept, chunks[:head]...)
+	return append(kept, chunks[len(chunks)-maxChunks/2:]...)
+}
+
+// sampleImportance keeps the first chunk and the chunks with the most
+// distinct words, so repeated boilerplate such as long tables or logs is
+// dropped first. The chunks keep their document order.
+func sampleImportance(chunks []*Chunk, maxChunks int) []*Chunk {
+	scores := make([]int, len(chunks))
+	for j, chunk := range chunks {
+		words := make(map[string]struct{})
+		for _, word := range strings.Fields(strings.ToLower(chunk.Text)) {
+			words[word] = struct{}{}
+		}
+		scores[j] = len(words)
+	}
+
+	order := make([]int, len(chunks)-1)
+	for j := range order {
+		order[j] = j + 1
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[order[a]] > scores[order[b]]
+	})
+
+	selected := append([]int{0}, order[:maxChunks-1]...)
+	sort.Ints(selected)
+
+	kept := make([]*Chunk, len(selected))
+	for j, index := range selected {
+		kept[j] = chunks[index]
+	}
+	return kept
+}
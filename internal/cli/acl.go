@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"ai-search/internal/config"
+	"ai-search/internal/server"
+)
+
+// newACLConfig builds how the server identifies callers for document ACLs
+// from ACL_IDENTITY and its settings
+func newACLConfig(cfg *config.Config) (server.ACLConfig, error) {
+	aclConfig := server.ACLConfig{
+		Identity:     cfg.ACLIdentity,
+		JWTSecret:    cfg.ACLJWTSecret,
+		JWTIssuer:    cfg.ACLJWTIssuer,
+		JWTAudience:  cfg.ACLJWTAudience,
+		GroupsClaim:  cfg.ACLGroupsClaim,
+		UserHeader:   cfg.ACLUserHeader,
+		GroupsHeader: cfg.ACLGroupsHeader,
+	}
+
+	switch cfg.ACLIdentity {
+	case "":
+		return aclConfig, nil
+	case server.IdentityJWT:
+		if cfg.ACLJWTPublicKeyFile != "" {
+			data, err := os.ReadFile(cfg.ACLJWTPublicKeyFile)
+			if err != nil {
+				return aclConfig, fmt.Errorf("failed to read ACL_JWT_PUBLIC_KEY_FILE: %w", err)
+			}
+			if aclConfig.JWTPublicKey, err = server.ParseRSAPublicKey(data); err != nil {
+				return aclConfig, fmt.Errorf("invalid ACL_JWT_PUBLIC_KEY_FILE %s: %w", cfg.ACLJWTPublicKeyFile, err)
+			}
+		}
+		if aclConfig.JWTSecret == "" && aclConfig.JWTPublicKey == nil {
+			return aclConfig, fmt.Errorf("ACL_IDENTITY=jwt requires ACL_JWT_SECRET or ACL_JWT_PUBLIC_KEY_FILE")
+		}
+		fmt.Println("Enforcing document ACLs for callers identified by JWT")
+	case server.IdentityHeader:
+		fmt.Printf("Enforcing document ACLs for callers identified by %s and %s\n", aclConfig.UserHeader, aclConfig.GroupsHeader)
+	default:
+		return aclConfig, fmt.Errorf("invalid ACL_IDENTITY %q: must be jwt or header", cfg.ACLIdentity)
+	}
+	return aclConfig, nil
+}
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"ai-search/internal/chunker"
+	"ai-search/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	boilerplateMinDocs int
+	boilerplateOutput  string
+	boilerplateShow    int
+)
+
+// boilerplateCmd represents the boilerplate command
+var boilerplateCmd = &cobra.Command{
+	Use:   "boilerplate",
+	Short: "Find sentences repeated across the corpus",
+	Long: `Count the documents each sentence of the stored documents occurs in and
+write those found in at least --min-docs of them, such as site footers, legal
+notices and newsletter prompts, to --output, one per line. With TEXT_FILTER_FILE
+naming that file, later crawls drop these sentences before chunking; review it
+first, as a short sentence that is common but informative is listed too. Each
+run replaces the file.`,
+	RunE: runBoilerplate,
+}
+
+func init() {
+	boilerplateCmd.Flags().IntVar(&boilerplateMinDocs, "min-docs", 10, "Documents a sentence must occur in")
+	boilerplateCmd.Flags().StringVar(&boilerplateOutput, "output", "", "File to write the sentences to (defaults to TEXT_FILTER_FILE)")
+	boilerplateCmd.Flags().IntVar(&boilerplateShow, "show", 20, "Most repeated sentences to print")
+
+	rootCmd.AddCommand(boilerplateCmd)
+}
+
+func runBoilerplate(cmd *cobra.Command, args []string) error {
+	if boilerplateMinDocs < 2 {
+		return fmt.Errorf("--min-docs must be at least 2")
+	}
+
+	cfg := config.LoadConfig()
+	output := boilerplateOutput
+	if output == "" {
+		output = cfg.TextFilterFile
+	}
+	if output == "" {
+		return fmt.Errorf("no output file: set --output or TEXT_FILTER_FILE")
+	}
+
+	documentStore := newDocumentStore(cfg)
+	defer documentStore.Close()
+
+	docs, err := documentStore.ListDocuments(context.Background())
+	if err != nil {
+		return err
+	}
+	finder := chunker.NewBoilerplateFinder()
+	for _, doc := range docs {
+		finder.Add(doc.Content)
+	}
+	sentences := finder.Boilerplate(boilerplateMinDocs)
+
+	var file strings.Builder
+	fmt.Fprintf(&file, "# Sentences found in at least %d of %d documents\n", boilerplateMinDocs, len(docs))
+	for _, sentence := range sentences {
+		file.WriteString(sentence + "\n")
+	}
+	if err := os.WriteFile(output, []byte(file.String()), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", output, err)
+	}
+
+	fmt.Printf("Wrote %d boilerplate sentences to %s\n", len(sentences), output)
+	for _, sentence := range sentences[:min(boilerplateShow, len(sentences))] {
+		fmt.Printf("  %5d docs  %s\n", finder.Documents(sentence), sentence)
+	}
+	return nil
+}
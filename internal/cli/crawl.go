@@ -2,24 +2,70 @@ package cli
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"ai-search/internal/chunker"
 	"ai-search/internal/config"
 	"ai-search/internal/crawler"
+	"ai-search/internal/dedup"
 	"ai-search/internal/embeddings"
+	"ai-search/internal/events"
 	"ai-search/internal/indexer"
+	"ai-search/internal/metrics"
+	"ai-search/internal/parser"
 	"ai-search/internal/store"
 
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 var (
-	crawlURL   string
-	crawlDepth int
+	crawlURL         string
+	crawlDepth       int
+	crawlMetricsAddr string
+	crawlMeta        map[string]string
+	crawlMetaFile    string
+	crawlAuthFile    string
+	crawlRulesFile   string
+	crawlSitemaps    bool
+	crawlVersion     string
+	crawlDefaultVer  string
+	crawlResume      bool
+	crawlQueue       string
+	crawlRenderJS    bool
+	crawlQuiet       bool
+	crawlVerbose     bool
+	crawlID          string
+	crawlMaxPages    int
+	crawlMaxTime     time.Duration
+	crawlCollection  string
+	crawlChunkSize   int
+	crawlOverlap     int
+	crawlStrategy    string
+	crawlTables      bool
+	crawlContextual  bool
+	crawlLanguages   string
+	crawlDetectLang  bool
+	crawlCountries   string
+	crawlWARCDir     string
+	crawlReplay      string
+	crawlReportPath  string
+	crawlShadow      string
+	crawlNofollow    bool
+	crawlBFS         bool
+	crawlDeny        []string
 )
 
 // crawlCmd represents the crawl command
@@ -34,6 +80,40 @@ and implementing polite crawling with rate limiting.`,
 func init() {
 	crawlCmd.Flags().StringVarP(&crawlURL, "url", "u", "", "Starting URL to crawl (required)")
 	crawlCmd.Flags().IntVarP(&crawlDepth, "depth", "d", 1, "Maximum crawl depth")
+	crawlCmd.Flags().IntVar(&crawlMaxPages, "max-pages", 0, "Stop after fetching this many pages (0 for no limit)")
+	crawlCmd.Flags().DurationVar(&crawlMaxTime, "max-time", 0, "Stop crawling after this long, e.g. 30m or 2h (0 for no limit)")
+	crawlCmd.Flags().BoolVar(&crawlBFS, "breadth-first", false, "Crawl every page of a depth before any page of the next, so --max-pages keeps the shallowest pages")
+	crawlCmd.Flags().StringArrayVar(&crawlDeny, "deny", nil, "Regular expression of URLs not to crawl (repeatable); PATCH /api/crawls/{id} adds more while the crawl runs")
+	crawlCmd.Flags().StringVar(&crawlMetricsAddr, "metrics-addr", "", "Address to serve Prometheus metrics on during the crawl (e.g. localhost:9091)")
+
+	crawlCmd.Flags().BoolVar(&crawlNofollow, "respect-nofollow", false, "Don't follow links marked rel=\"nofollow\" or rel=\"ugc\" (defaults to RESPECT_NOFOLLOW)")
+	crawlCmd.Flags().BoolVar(&crawlSitemaps, "sitemaps", false, "Seed the crawl from the site's sitemap.xml")
+	crawlCmd.Flags().BoolVar(&crawlResume, "resume", false, "Continue an interrupted crawl of the same URL, or --crawl-id, from its persisted frontier")
+	crawlCmd.Flags().BoolVar(&crawlRenderJS, "render-js", false, "Render pages in headless Chrome before parsing (for JavaScript-heavy sites)")
+	crawlCmd.Flags().StringVar(&crawlQueue, "queue", "", "Crawl queue backend: memory or redis (defaults to CRAWL_QUEUE)")
+	crawlCmd.Flags().StringVar(&crawlID, "crawl-id", "", "Name of the crawl, updated through PATCH /api/crawls/{id}; processes with the same ID share one queue (defaults to the start URL)")
+	crawlCmd.Flags().StringVar(&crawlVersion, "version", "", "Corpus version of the crawled documents (e.g. v1.2, v2.0, latest)")
+	crawlCmd.Flags().StringVar(&crawlDefaultVer, "default-version", "", "Corpus version searched when a request names none, saved as the collection's setting (\"\" searches every version)")
+	crawlCmd.Flags().StringVar(&crawlCollection, "collection", "", "Collection to index into (defaults to COLLECTION_NAME)")
+	crawlCmd.Flags().IntVar(&crawlChunkSize, "chunk-size", 0, "Chunk size in characters, saved as the collection's setting")
+	crawlCmd.Flags().IntVar(&crawlOverlap, "chunk-overlap", 0, "Characters shared by consecutive chunks, saved as the collection's setting")
+	crawlCmd.Flags().StringVar(&crawlStrategy, "chunk-strategy", "", "Chunking strategy, sentence or fixed, saved as the collection's setting")
+	crawlCmd.Flags().BoolVar(&crawlTables, "chunk-tables", false, "Also index each data table as chunks of its own, saved as the collection's setting")
+	crawlCmd.Flags().BoolVar(&crawlContextual, "contextual", false, "Situate each chunk in its document with the LLM before indexing, saved as the collection's setting")
+	crawlCmd.Flags().StringToStringVar(&crawlMeta, "meta", nil, "Attribute to attach to every crawled document (key=value, repeatable)")
+	crawlCmd.Flags().StringVar(&crawlAuthFile, "auth-file", "", "JSON file of per-domain headers, cookies and basic auth (defaults to CRAWL_AUTH_FILE)")
+	crawlCmd.Flags().StringVar(&crawlRulesFile, "rules", "", "YAML file of per-domain CSS selector extraction rules (defaults to EXTRACTION_RULES_FILE)")
+	crawlCmd.Flags().StringVar(&crawlLanguages, "languages", "", "Only index pages in these languages, comma-separated (defaults to ALLOWED_LANGUAGES)")
+	crawlCmd.Flags().BoolVar(&crawlDetectLang, "detect-language", false, "Identify the language of pages that do not declare one from their text")
+	crawlCmd.Flags().StringVar(&crawlCountries, "countries", "", "Only index pages targeting these countries, comma-separated ISO codes (defaults to ALLOWED_COUNTRIES)")
+	crawlCmd.Flags().StringVar(&crawlWARCDir, "warc-dir", "", "Record every fetched response into WARC files in this directory (defaults to WARC_DIR)")
+	crawlCmd.Flags().StringVar(&crawlReplay, "replay", "", "Crawl the responses recorded in these WARC files or directories, comma-separated, instead of the web")
+	crawlCmd.Flags().StringVar(&crawlShadow, "shadow-collection", "", "Also index every document into this collection with the SHADOW_* chunking and embedding settings (defaults to SHADOW_COLLECTION)")
+	crawlCmd.Flags().StringVar(&crawlReportPath, "report", "", "Write a JSON report of every URL's status, depth, content hash, chunks and indexing outcome to this file")
+	crawlCmd.Flags().StringVar(&crawlMetaFile, "meta-file", "", "JSON file of per-URL-prefix attributes: [{\"prefix\": \"...\", \"attributes\": {...}}]")
+
+	crawlCmd.Flags().BoolVarP(&crawlQuiet, "quiet", "q", false, "Only print errors and the final summary")
+	crawlCmd.Flags().BoolVarP(&crawlVerbose, "verbose", "v", false, "Log every fetched and indexed page")
 
 	crawlCmd.MarkFlagRequired("url")
 }
@@ -45,19 +125,85 @@ func runCrawl(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("invalid URL: %w", err)
 	}
 
+	// The crawl is named by --crawl-id, or else by its start URL, so that
+	// --resume, a shared queue and crawl updates find the same crawl
+	crawlKey := crawlID
+	if crawlKey == "" {
+		crawlKey = startURL.String()
+	}
+
+	// Load custom document attributes
+	if crawlVersion != "" {
+		if crawlMeta == nil {
+			crawlMeta = make(map[string]string)
+		}
+		crawlMeta[versionAttribute] = crawlVersion
+	}
+	metaRules, err := loadMetadataRules(crawlMetaFile)
+	if err != nil {
+		return err
+	}
+
+	if crawlQuiet && crawlVerbose {
+		return fmt.Errorf("--quiet and --verbose are mutually exclusive")
+	}
+
+	for _, pattern := range crawlDeny {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid deny pattern %q: %w", pattern, err)
+		}
+	}
+
+	if crawlStrategy != "" && !chunker.ValidStrategy(crawlStrategy) {
+		return fmt.Errorf("invalid chunk strategy %q: expected sentence or fixed", crawlStrategy)
+	}
+
 	// Load configuration
 	cfg := config.LoadConfig()
+	if crawlCollection != "" {
+		cfg.CollectionName = crawlCollection
+	}
+	if crawlAuthFile != "" {
+		cfg.CrawlAuthFile = crawlAuthFile
+	}
+	if crawlRulesFile != "" {
+		cfg.ExtractionRulesFile = crawlRulesFile
+	}
+	if crawlLanguages != "" {
+		cfg.AllowedLanguages = crawlLanguages
+	}
+	cfg.DetectLanguage = cfg.DetectLanguage || crawlDetectLang
+	if crawlCountries != "" {
+		cfg.AllowedCountries = crawlCountries
+	}
+	if crawlWARCDir != "" {
+		cfg.WARCDir = crawlWARCDir
+	}
+	if crawlShadow != "" {
+		cfg.ShadowCollection = crawlShadow
+	}
+	auth, err := loadCrawlAuth(cfg.CrawlAuthFile)
+	if err != nil {
+		return err
+	}
+	rules, err := loadExtractionRules(cfg.ExtractionRulesFile)
+	if err != nil {
+		return err
+	}
 
 	// Validate required configuration for indexing
-	if cfg.EmbeddingAPIKey == "" {
+	if cfg.EmbeddingAPIKey == "" && cfg.EmbeddingModel != embeddings.MockModel {
 		return fmt.Errorf("EMBEDDING_API_KEY environment variable is required for indexing")
 	}
 
-	fmt.Printf("Starting crawl of %s (depth: %d)\n", crawlURL, crawlDepth)
-	fmt.Println("Initializing components...")
+	// Detailed logs go to the structured logger, drawn above the progress line
+	display := newProgress(os.Stderr, crawlQuiet)
+	logger := newCrawlLogger(display)
+
+	display.Printf("Starting crawl of %s (depth: %d)\n", crawlURL, crawlDepth)
 
 	// Initialize components
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	// Initialize store
@@ -71,37 +217,118 @@ func runCrawl(cmd *cobra.Command, args []string) error {
 		SSLMode:  cfg.DatabaseSSLMode,
 	}
 	documentStore := store.NewStore(storeConfig)
+
+	// Announce indexed documents so caches drop stale search results
+	detector := newDetector(cfg)
+	publisher, err := newPublisher(cfg)
+	if err != nil {
+		return err
+	}
+	defer publisher.Close()
 	defer documentStore.Close()
 
-	// Initialize chunker
-	chunkerConfig := chunker.Config{
-		ChunkSize:    cfg.ChunkSize,
-		OverlapSize:  cfg.OverlapSize,
-		MinChunkSize: cfg.MinChunkSize,
+	// Title pages that have neither a <title> nor a heading
+	titler, err := newPageTitler(cfg)
+	if err != nil {
+		return err
 	}
-	textChunker := chunker.NewTextChunker(chunkerConfig)
 
 	// Initialize embedder
+	embeddingModels, err := embeddings.ParseModels(cfg.EmbeddingModels)
+	if err != nil {
+		return err
+	}
 	embedderConfig := embeddings.Config{
 		Model:     cfg.EmbeddingModel,
 		APIKey:    cfg.EmbeddingAPIKey,
 		BaseURL:   cfg.EmbeddingBaseURL,
 		BatchSize: 10,
 		Timeout:   30,
+		Models:    embeddingModels,
 	}
 	embedder := embeddings.NewEmbedder(embedderConfig)
 
-	// Initialize indexer
+	// Initialize indexer. Chunk flags replace the chunking settings stored
+	// with the collection.
+	chunking := chunkingConfig(cfg)
+	overrideChunking := false
+	if crawlChunkSize > 0 {
+		chunking.ChunkSize = crawlChunkSize
+		overrideChunking = true
+	}
+	if crawlOverlap > 0 {
+		chunking.OverlapSize = crawlOverlap
+		overrideChunking = true
+	}
+	if crawlStrategy != "" {
+		chunking.Strategy = crawlStrategy
+		overrideChunking = true
+	}
+	if cmd.Flags().Changed("chunk-tables") {
+		chunking.Tables = crawlTables
+		overrideChunking = true
+	}
+	contextual, overrideEnrichment := cfg.ContextualEnrichment, false
+	if cmd.Flags().Changed("contextual") {
+		contextual, overrideEnrichment = crawlContextual, true
+	}
+	defaultVersion, overrideDefaultVersion := cfg.DefaultVersion, false
+	if cmd.Flags().Changed("default-version") {
+		defaultVersion, overrideDefaultVersion = crawlDefaultVer, true
+	}
 	indexerConfig := indexer.Config{
-		Embedder:       embedder,
-		Chunker:        textChunker,
-		ChromaURL:      cfg.ChromaURL,
-		ElasticURL:     cfg.ElasticURL,
-		CollectionName: cfg.CollectionName,
+		Embedder:         embedder,
+		ChromaURL:        cfg.ChromaURL,
+		ElasticURL:       cfg.ElasticURL,
+		ElasticIndex:     cfg.ElasticIndex,
+		CollectionName:   cfg.CollectionName,
+		Chunking:         chunking,
+		OverrideChunking: overrideChunking,
+		MaxRetries:       cfg.ElasticMaxRetries,
+		DeadLetters:      &storeDeadLetters{store: documentStore},
+
+		ContextualEnrichment: contextual,
+		OverrideEnrichment:   overrideEnrichment,
+
+		DefaultVersion:         defaultVersion,
+		OverrideDefaultVersion: overrideDefaultVersion,
 	}
 	hybridIndexer := indexer.NewIndexer(indexerConfig)
 	defer hybridIndexer.Close()
 
+	// Initialize chunker
+	textChunker, err := documentChunker(cfg, hybridIndexer)
+	if err != nil {
+		return err
+	}
+	chunkEmbedder, err := newChunkEmbedder(cfg, embedder, hybridIndexer)
+	if err != nil {
+		return err
+	}
+	if !crawlQuiet {
+		collectionChunking := hybridIndexer.Chunking()
+		display.Printf("Chunking %s: %d characters, %d overlap, %s strategy\n", cfg.CollectionName,
+			collectionChunking.ChunkSize, collectionChunking.OverlapSize, collectionChunking.Strategy)
+		if collectionChunking.Tables {
+			display.Printf("Tables are indexed as chunks of their own\n")
+		}
+		if hybridIndexer.ContextualEnrichment() {
+			display.Printf("Contextual enrichment is on: each chunk costs one LLM call\n")
+		}
+	}
+
+	// Index into the shadow collection too, to compare its settings
+	shadow, err := newShadowIndex(cfg, documentStore, logger)
+	if err != nil {
+		return err
+	}
+	defer shadow.Close()
+	if shadow != nil {
+		shadowChunking := shadow.indexer.Chunking()
+		display.Printf("Shadow indexing into %s: %d characters, %d overlap, %s strategy\n", cfg.ShadowCollection,
+			shadowChunking.ChunkSize, shadowChunking.OverlapSize, shadowChunking.Strategy)
+	}
+
 	// Create crawler configuration
 	crawlerConfig := crawler.Config{
 		MaxWorkers:    cfg.MaxWorkers,
@@ -110,113 +337,469 @@ func runCrawl(cmd *cobra.Command, args []string) error {
 		UserAgent:     cfg.UserAgent,
 		Timeout:       cfg.Timeout,
 		RespectRobots: cfg.RespectRobots,
+		MaxPerHost:    cfg.MaxPerHost,
+		MaxPages:      crawlMaxPages,
+		MaxDuration:   crawlMaxTime,
+		Proxies:       crawlProxies(cfg),
+		Auth:          auth,
+
+		RespectNofollow: cfg.RespectNofollow || crawlNofollow,
+		BreadthFirst:    crawlBFS,
+		DenyPatterns:    crawlDeny,
+
+		Metadata: crawlMeta,
+
+		AllowedLanguages: splitList(cfg.AllowedLanguages),
+		DetectLanguage:   cfg.DetectLanguage,
+		AllowedCountries: splitList(cfg.AllowedCountries),
+
+		UseSitemaps:    cfg.UseSitemaps || crawlSitemaps,
+		MaxSitemapURLs: cfg.MaxSitemapURLs,
+
+		AutoTuneWorkers: cfg.AutoTuneWorkers,
+		MaxAutoWorkers:  cfg.MaxAutoWorkers,
+
+		Logger: logger,
+
+		RenderJS:      cfg.RenderJS || crawlRenderJS,
+		RenderTimeout: cfg.RenderTimeout,
+		RenderSettle:  cfg.RenderSettle,
+		BrowserPath:   cfg.BrowserPath,
+
+		Parser: parser.Config{
+			StreamingThreshold: cfg.StreamingThreshold,
+			MaxElementText:     cfg.MaxElementText,
+			MaxTextSize:        cfg.MaxTextSize,
+			Rules:              rules,
+			StripParams:        stripParams(cfg),
+			DisablePDF:         !cfg.ParsePDF,
+			DisableOffice:      !cfg.ParseOffice,
+		},
+		Transport: crawler.TransportConfig{
+			MaxIdleConns:        cfg.MaxIdleConns,
+			MaxIdleConnsPerHost: cfg.MaxIdleConnsPerHost,
+			IdleConnTimeout:     cfg.IdleConnTimeout,
+			TLSHandshakeTimeout: cfg.TLSHandshakeTimeout,
+
+			DisableHTTP2:       !cfg.HTTP2,
+			DisableKeepAlives:  !cfg.KeepAlives,
+			InsecureSkipVerify: cfg.InsecureSkipVerify,
+			DNSCacheTTL:        cfg.DNSCacheTTL,
+		},
+
+		Frontier: &storeFrontier{store: documentStore, crawlID: crawlKey},
+		Resume:   crawlResume,
+
+		WARCDir:         cfg.WARCDir,
+		WARCMaxFileSize: cfg.WARCMaxFileSize,
+	}
+
+	// Replaying an archive re-runs parsing, chunking and indexing without
+	// touching the network, so there is no host to be polite to
+	if crawlReplay != "" {
+		fetcher, err := crawler.NewWARCFetcher(splitList(crawlReplay))
+		if err != nil {
+			return err
+		}
+		crawlerConfig.Fetcher = fetcher
+		crawlerConfig.RateLimit = -1
+		crawlerConfig.WARCDir = ""
+		display.Printf("Replaying WARC archive %s\n", crawlReplay)
+	}
+
+	// A Redis queue lets crawl processes on several machines share the work
+	queueBackend := crawlQueue
+	if queueBackend == "" {
+		queueBackend = cfg.CrawlQueue
+	}
+	switch queueBackend {
+	case "", "memory":
+	case "redis":
+		// The shared queue is the crawl's frontier, kept while any of its
+		// processes runs
+		if crawlResume {
+			return fmt.Errorf("--resume cannot be used with the redis queue, whose crawls continue while any of their processes runs")
+		}
+		queue, err := crawler.NewRedisQueue(cfg.RedisURL, crawlKey)
+		if err != nil {
+			return err
+		}
+		defer queue.Close()
+		crawlerConfig.Queue = queue
+		crawlerConfig.Frontier = nil
+		display.Printf("Using shared Redis crawl queue %q\n", crawlKey)
+
+		// Processes sharing a queue share robots.txt rules too
+		robotsStore, err := crawler.NewRedisRobotsStore(cfg.RedisURL)
+		if err != nil {
+			return err
+		}
+		defer robotsStore.Close()
+		crawlerConfig.RobotsStore = robotsStore
+	default:
+		return fmt.Errorf("unknown crawl queue: %s", queueBackend)
+	}
+	if crawlerConfig.RobotsStore == nil {
+		crawlerConfig.RobotsStore = &storeRobots{store: documentStore}
+	}
+	crawlerConfig.RobotsTTL = time.Duration(cfg.RobotsCacheTTL) * time.Hour
+	crawlerConfig.RobotsCacheSize = cfg.RobotsCacheSize
+
+	// The report follows every URL through the crawler's hooks
+	var report *crawlReport
+	if crawlReportPath != "" {
+		report = newCrawlReport(startURL.String())
+		crawlerConfig.Hooks = append(crawlerConfig.Hooks, report)
 	}
 
 	// Create crawler instance
 	c := crawler.NewCrawler(crawlerConfig)
+	display.domains = c.DomainStats
+	display.stats = c.Stats
+
+	// Expose pipeline metrics while the crawl runs
+	if crawlMetricsAddr != "" {
+		go func() {
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", metrics.Handler())
+			if err := http.ListenAndServe(crawlMetricsAddr, mux); err != nil {
+				logger.Errorf("Metrics server error: %v", err)
+			}
+		}()
+		display.Printf("Serving metrics on http://%s/metrics\n", crawlMetricsAddr)
+	}
 
-	fmt.Println("Starting crawl and indexing...")
+	// A first SIGINT or SIGTERM stops fetching; pages already fetched are
+	// still indexed for up to SHUTDOWN_GRACE so that their URLs, recorded
+	// as visited, are not lost to --resume. A second signal stops at once.
+	crawlCtx, stopCrawl := context.WithCancel(ctx)
+	defer stopCrawl()
+	quit := make(chan os.Signal, 2)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+	go func() {
+		select {
+		case <-quit:
+		case <-ctx.Done():
+			return
+		}
+		grace := time.Duration(cfg.ShutdownGrace) * time.Second
+		display.Printf("Stopping crawl, indexing pages already fetched for up to %s...\n", grace)
+		stopCrawl()
+
+		select {
+		case <-quit:
+		case <-time.After(grace):
+		case <-ctx.Done():
+			return
+		}
+		display.Printf("Stopping indexing\n")
+		cancel()
+	}()
 
 	// Start crawling
-	pageChan, errorChan := c.Crawl(ctx, startURL, crawlDepth)
+	pageChan, errorChan := c.Crawl(crawlCtx, startURL, crawlDepth)
+
+	// Apply the seeds, deny patterns and rate limits sent to PATCH
+	// /api/crawls/{id} while the crawl runs
+	updates := &crawlUpdates{
+		store:     documentStore,
+		crawlID:   crawlKey,
+		crawler:   c,
+		logger:    logger,
+		rateLimit: crawlerConfig.RateLimit,
+	}
+	if !crawlResume {
+		if err := updates.skipRecorded(crawlCtx); err != nil {
+			logger.Warnf("Failed to load crawl updates: %v", err)
+		}
+	}
+	go updates.follow(crawlCtx)
 
-	// Process results
-	pageCount := 0
-	errorCount := 0
-	indexedCount := 0
+	display.Start()
 
-	for {
-		select {
-		case page, ok := <-pageChan:
-			if !ok {
-				// Channel closed, check for errors
-				select {
-				case err := <-errorChan:
-					if err != nil {
-						fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-						errorCount++
-					}
-				default:
-					// No more errors
-				}
-				goto done
+	// Collect crawl errors until the crawler closes the channel
+	errorsDone := make(chan struct{})
+	go func() {
+		defer close(errorsDone)
+		for err := range errorChan {
+			if err != nil {
+				logger.Errorf("Crawl error: %v", err)
+				display.Failed()
 			}
+		}
+	}()
 
-			pageCount++
-			fmt.Printf("Processing page %d: %s\n", pageCount, page.Title)
-
-			// Save document to store
-			doc := &store.Document{
-				ID:      page.ContentHash,
-				URL:     page.URL.String(),
-				Title:   page.Title,
-				Content: page.Content,
-				Meta: map[string]interface{}{
-					"meta_desc":    page.MetaDesc,
-					"links_count":  len(page.Links),
-					"depth":        page.Depth,
-					"content_hash": page.ContentHash,
-				},
-			}
+	// Index writers consume crawled pages concurrently
+	indexWorkers := cfg.IndexWorkers
+	if indexWorkers < 1 {
+		indexWorkers = 1
+	}
+	metrics.StageWorkers.Set(float64(indexWorkers), metrics.StageIndex)
+	metrics.StageWorkers.Set(float64(indexWorkers), metrics.StageEmbed)
+
+	var wg sync.WaitGroup
+	for i := 0; i < indexWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for page := range pageChan {
+				metrics.StageQueueDepth.Set(float64(len(pageChan)), metrics.StageIndex)
+
+				display.Fetched()
+				logger.Debugf("Processing page %s: %s", page.URL, page.Title)
+
+				if err := titler.title(ctx, page); err != nil {
+					logger.Warnf("%v", err)
+				}
 
-			if err := documentStore.SaveDocument(ctx, doc); err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to save document: %v\n", err)
-				continue
-			}
+				attributes := metaRules.attributesFor(page)
+				if _, set := attributes[languageAttribute]; !set && page.Language != "" {
+					attributes[languageAttribute] = page.Language
+				}
+				chunkCount, err := indexPage(ctx, page, attributes, documentStore, detector, textChunker, chunkEmbedder, hybridIndexer, shadow, publisher)
+				if report != nil {
+					report.indexed(page, chunkCount, err)
+				}
+				if errors.Is(err, errDuplicateContent) {
+					logger.Infof("Recorded %s as an alternate URL of %s", page.URL, documentID(page.ContentHash, attributes))
+					continue
+				}
+				if err != nil {
+					logger.Errorf("%v", err)
+					display.Failed()
+					continue
+				}
+				if chunkCount == 0 {
+					logger.Infof("No chunks created for %s", page.URL)
+					continue
+				}
 
-			// Chunk the content
-			chunks := textChunker.Chunk(page.Content)
-			if len(chunks) == 0 {
-				fmt.Printf("  No chunks created for %s\n", page.Title)
-				continue
+				display.Indexed()
+				logger.Infof("Indexed %d chunks for %s", chunkCount, page.URL)
 			}
+		}()
+	}
 
-			// Generate embeddings for chunks
-			var chunkTexts []string
-			for _, chunk := range chunks {
-				chunkTexts = append(chunkTexts, chunk.Text)
-			}
+	wg.Wait()
+	<-errorsDone
+	display.Stop()
 
-			embeddings, err := embedder.EmbedBatch(ctx, chunkTexts)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to generate embeddings: %v\n", err)
-				continue
-			}
+	if crawlCtx.Err() != nil {
+		fmt.Printf("Crawl stopped. %s. Continue it with --resume.\n", display.Summary())
+	} else {
+		fmt.Printf("Crawl completed. %s.\n", display.Summary())
+	}
+	// Record the run for analytics exports. The crawl context may be
+	// cancelled by now, so the run is saved regardless.
+	stats := c.Stats()
+	run := &store.CrawlRun{
+		CrawlID:         crawlID,
+		StartURL:        startURL.String(),
+		Fetched:         stats.Fetched,
+		Indexed:         display.IndexedCount(),
+		Failed:          stats.Failed,
+		SkippedByRobots: stats.SkippedByRobots,
+		Duplicates:      stats.Duplicates,
+		Filtered:        stats.Filtered,
+		BytesDownloaded: stats.BytesDownloaded,
+		Duration:        stats.Elapsed,
+		Stopped:         crawlCtx.Err() != nil,
+	}
+	if run.CrawlID == "" {
+		run.CrawlID = run.StartURL
+	}
+	if err := documentStore.SaveCrawlRun(context.Background(), run); err != nil {
+		logger.Warnf("Failed to record crawl run: %v", err)
+	}
 
-			// Save chunks to store
-			if err := documentStore.SaveChunks(ctx, doc.ID, chunks); err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to save chunks: %v\n", err)
-				continue
-			}
+	if report := display.DomainReport(); report != "" && !crawlQuiet {
+		fmt.Printf("Requests per domain:\n%s", report)
+	}
+	if report != nil {
+		if err := report.write(crawlReportPath); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote crawl report to %s\n", crawlReportPath)
+	}
+	return nil
+}
 
-			// Index in vector and keyword search
-			indexDoc := &indexer.Document{
-				ID:      doc.ID,
-				URL:     doc.URL,
-				Title:   doc.Title,
-				Content: doc.Content,
-				Meta:    doc.Meta,
-			}
+// errDuplicateContent reports a page whose content is already indexed
+// under another URL
+var errDuplicateContent = errors.New("content already indexed under another URL")
 
-			if err := hybridIndexer.Index(ctx, indexDoc, chunks, embeddings); err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to index document: %v\n", err)
-				continue
-			}
+// indexPage saves, chunks, embeds and indexes a single crawled page. It
+// returns the number of chunks indexed.
+func indexPage(ctx context.Context, page *crawler.Page, attributes map[string]string, documentStore store.Store, detector dedup.Detector, textChunker chunker.Chunker, embedder *chunkEmbedder, hybridIndexer indexer.Indexer, shadow *shadowIndex, publisher events.Publisher) (int, error) {
+	docID := documentID(page.ContentHash, attributes)
 
-			indexedCount++
-			fmt.Printf("  Indexed %d chunks for %s\n", len(chunks), page.Title)
+	// Content already indexed from another URL is recorded as an alternate
+	// URL of that document instead of being indexed again
+	duplicate, err := documentStore.AddAlternateURL(ctx, docID, page.URL.String())
+	if err != nil {
+		return 0, err
+	}
+	if duplicate {
+		return 0, errDuplicateContent
+	}
 
-		case err := <-errorChan:
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Crawl error: %v\n", err)
-				errorCount++
-			}
+	// Save document to store
+	doc := &store.Document{
+		ID:      docID,
+		URL:     page.URL.String(),
+		Title:   page.Title,
+		Content: page.Content,
+		Meta: map[string]interface{}{
+			"meta_desc":    page.MetaDesc,
+			"keywords":     page.Keywords,
+			"links_count":  len(page.Links),
+			"depth":        page.Depth,
+			"content_hash": page.ContentHash,
+			"title_source": page.TitleSource,
+		},
+	}
+	if len(attributes) > 0 {
+		doc.Meta["attributes"] = attributes
+	}
+	if len(page.RedirectedFrom) > 0 {
+		doc.Meta["redirected_from"] = page.RedirectedFrom
+	}
+	if page.Author != "" {
+		doc.Meta["author"] = page.Author
+	}
+	if !page.Published.IsZero() {
+		doc.Meta["published"] = page.Published.UTC().Format(time.RFC3339)
+	}
+	if !page.Modified.IsZero() {
+		doc.Meta["modified"] = page.Modified.UTC().Format(time.RFC3339)
+	}
+	if len(page.Tables) > 0 {
+		doc.Meta[tablesMetaKey] = page.Tables
+	}
+	if len(page.LanguageVariants) > 0 {
+		variants := make(map[string]string, len(page.LanguageVariants))
+		for hreflang, variant := range page.LanguageVariants {
+			variants[hreflang] = variant.String()
 		}
+		doc.Meta[store.LanguageVariantsMetaKey] = variants
 	}
 
-done:
-	fmt.Printf("\nCrawl completed. Processed %d pages, indexed %d pages, %d errors.\n", pageCount, indexedCount, errorCount)
-	return nil
+	if detector != nil {
+		fingerprint := detector.Fingerprint(page.Content)
+		doc.Fingerprint = &fingerprint
+	}
+
+	if err := documentStore.SaveDocument(ctx, doc); err != nil {
+		return 0, fmt.Errorf("failed to save document: %w", err)
+	}
+	// A soft-deleted document keeps its chunks indexed, marked deleted,
+	// until it is restored or purged
+	if doc.DeletedAt != nil {
+		return 0, nil
+	}
+
+	indexDoc := &indexer.Document{
+		ID:      doc.ID,
+		URL:     doc.URL,
+		Title:   doc.Title,
+		Content: doc.Content,
+		Meta:    doc.Meta,
+
+		Description: page.MetaDesc,
+		Keywords:    page.Keywords,
+		Attributes:  attributes,
+		Language:    attributes[languageAttribute],
+	}
+	if indexDoc.Language == "" {
+		indexDoc.Language = page.Language
+	}
+
+	chunkCount, err := indexDocument(ctx, indexDoc, documentStore, textChunker, embedder, hybridIndexer)
+	if err != nil {
+		deadLetterDocument(ctx, documentStore, indexDoc, err)
+		return 0, err
+	}
+	shadow.index(ctx, indexDoc)
+	if chunkCount > 0 {
+		publisher.Publish(ctx, events.DocumentIndexed, doc.ID)
+	}
+
+	return chunkCount, nil
+}
+
+// indexDocument chunks, embeds and indexes a saved document. It returns
+// the number of chunks indexed.
+func indexDocument(ctx context.Context, indexDoc *indexer.Document, documentStore store.Store, textChunker chunker.Chunker, embedder *chunkEmbedder, hybridIndexer indexer.Indexer) (int, error) {
+	version := indexDoc.Attributes[versionAttribute]
+
+	// Chunk the content
+	chunks := chunkDocument(indexDoc, textChunker, hybridIndexer)
+	if len(chunks) == 0 {
+		return 0, nil
+	}
+
+	// Record that a document over the chunk cap was only partly indexed
+	if total, truncated := chunker.Truncated(chunks); truncated {
+		meta := map[string]interface{}{
+			"chunks_total":   total,
+			"chunks_indexed": len(chunks),
+		}
+		if err := documentStore.UpdateDocumentMeta(ctx, indexDoc.ID, meta); err != nil {
+			return 0, err
+		}
+		if indexDoc.Meta == nil {
+			indexDoc.Meta = make(map[string]interface{})
+		}
+		for key, value := range meta {
+			indexDoc.Meta[key] = value
+		}
+		metrics.TruncatedDocuments.Inc()
+	}
+	if version != "" {
+		for _, chunk := range chunks {
+			chunk.ID = versionedID(version, chunk.ID)
+		}
+	}
+	if indexDoc.Language != "" {
+		for _, chunk := range chunks {
+			chunk.Metadata[indexer.LanguageKey] = indexDoc.Language
+		}
+	}
+
+	// Generate embeddings for chunks
+	embeddings, err := embedder.embed(ctx, indexDoc, chunks)
+	if err != nil {
+		return 0, &stageError{stage: stageEmbedding, err: fmt.Errorf("failed to generate embeddings: %w", err)}
+	}
+
+	// Save chunks to store
+	if err := documentStore.SaveChunks(ctx, indexDoc.ID, chunks); err != nil {
+		return 0, fmt.Errorf("failed to save chunks: %w", err)
+	}
+
+	// Index in vector and keyword search
+	if err := hybridIndexer.Index(ctx, indexDoc, chunks, embeddings); err != nil {
+		return 0, &stageError{stage: stageIndex, err: fmt.Errorf("failed to index document: %w", err)}
+	}
+
+	return len(chunks), nil
+}
+
+// documentID returns the ID of a document with the given content hash.
+// Documents of different versions are kept side by side, so the version is
+// part of the document identity.
+func documentID(contentHash string, attributes map[string]string) string {
+	if version := attributes[versionAttribute]; version != "" {
+		return versionedID(version, contentHash)
+	}
+	return contentHash
+}
+
+// versionedID derives a version-specific ID from a content-based ID
+func versionedID(version, id string) string {
+	hash := sha256.Sum256([]byte(version + ":" + id))
+	return fmt.Sprintf("%x", hash)
 }
 
 // truncateText truncates text to the specified length
@@ -226,3 +809,133 @@ func truncateText(text string, maxLen int) string {
 	}
 	return text[:maxLen] + "..."
 }
+
+// chunkingConfig returns the configured chunking settings, used for
+// collections that have none stored
+func chunkingConfig(cfg *config.Config) chunker.Config {
+	return chunker.Config{
+		ChunkSize:    cfg.ChunkSize,
+		OverlapSize:  cfg.OverlapSize,
+		MinChunkSize: cfg.MinChunkSize,
+		Strategy:     cfg.ChunkStrategy,
+		Tables:       cfg.ChunkTables,
+
+		FoldDiacritics: cfg.FoldDiacritics,
+	}
+}
+
+// documentChunker returns the collection's chunker capped at the
+// configured number of chunks per document, filtering boilerplate first
+func documentChunker(cfg *config.Config, hybridIndexer indexer.Indexer) (chunker.Chunker, error) {
+	phrases, err := loadTextFilter(cfg.TextFilterFile)
+	if err != nil {
+		return nil, err
+	}
+	limited := chunker.NewLimitedChunker(hybridIndexer.Chunker(), cfg.MaxChunksPerDocument, cfg.ChunkSampling)
+	return chunker.NewFilteredChunker(limited, chunker.FilterConfig{
+		Boilerplate: cfg.TextFilter,
+		Phrases:     phrases,
+		Emoji:       cfg.TextFilterEmoji,
+	}), nil
+}
+
+// loadTextFilter reads the sentences to drop before chunking, one per
+// line, skipping blank lines and # comments
+func loadTextFilter(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read text filter: %w", err)
+	}
+	var phrases []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			phrases = append(phrases, line)
+		}
+	}
+	return phrases, nil
+}
+
+// loadCrawlAuth reads per-domain credentials from a JSON file, expanding
+// ${ENV_VAR} references so secrets can stay out of it
+func loadCrawlAuth(path string) ([]crawler.DomainAuth, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read auth file: %w", err)
+	}
+	var auth []crawler.DomainAuth
+	if err := json.Unmarshal(data, &auth); err != nil {
+		return nil, fmt.Errorf("invalid auth file %s: %w", path, err)
+	}
+
+	for i := range auth {
+		if auth[i].Domain == "" {
+			return nil, fmt.Errorf("invalid auth file %s: entry %d has no domain", path, i+1)
+		}
+		for name, value := range auth[i].Headers {
+			auth[i].Headers[name] = os.ExpandEnv(value)
+		}
+		for name, value := range auth[i].Cookies {
+			auth[i].Cookies[name] = os.ExpandEnv(value)
+		}
+		auth[i].Username = os.ExpandEnv(auth[i].Username)
+		auth[i].Password = os.ExpandEnv(auth[i].Password)
+	}
+	return auth, nil
+}
+
+// loadExtractionRules reads per-domain extraction rules from a YAML file,
+// rejecting rules whose selectors do not parse
+func loadExtractionRules(path string) ([]parser.ExtractionRule, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read extraction rules: %w", err)
+	}
+	var rules []parser.ExtractionRule
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid extraction rules file %s: %w", path, err)
+	}
+
+	for i, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid extraction rules file %s: entry %d: %w", path, i+1, err)
+		}
+	}
+	return rules, nil
+}
+
+// stripParams returns the query parameters dropped from crawled URLs, nil
+// for the parser's defaults
+func stripParams(cfg *config.Config) []string {
+	if strings.EqualFold(strings.TrimSpace(cfg.StripQueryParams), "none") {
+		return []string{}
+	}
+	return splitList(cfg.StripQueryParams)
+}
+
+// crawlProxies returns the configured proxy URLs
+func crawlProxies(cfg *config.Config) []string {
+	return splitList(cfg.Proxies)
+}
+
+// splitList returns the non-empty entries of a comma-separated list
+func splitList(list string) []string {
+	var entries []string
+	for _, entry := range strings.Split(list, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"context"
+	"time"
+
+	"ai-search/internal/crawler"
+	"ai-search/internal/store"
+
+	"github.com/sirupsen/logrus"
+)
+
+// crawlUpdateInterval is how often a running crawl polls for the updates
+// made to it through PATCH /api/crawls/{id}
+const crawlUpdateInterval = 5 * time.Second
+
+// crawlUpdates applies the updates recorded for a crawl to its crawler
+// while it runs, so seeds, deny patterns and a lower rate limit take effect
+// without restarting it
+type crawlUpdates struct {
+	store   store.Store
+	crawlID string
+	crawler crawler.Crawler
+	logger  *logrus.Logger
+
+	// rateLimit is the crawl's requests per second, 0 or less for none
+	rateLimit float64
+	lastID    int64
+}
+
+// skipRecorded starts from the updates recorded from now on. A resumed
+// crawl applies the earlier ones again instead, as its crawler starts
+// afresh.
+func (u *crawlUpdates) skipRecorded(ctx context.Context) error {
+	updates, err := u.store.ListCrawlUpdates(ctx, u.crawlID, u.lastID)
+	if err != nil {
+		return err
+	}
+	if len(updates) > 0 {
+		u.lastID = updates[len(updates)-1].ID
+	}
+	return nil
+}
+
+// follow polls for updates and applies them until ctx is done
+func (u *crawlUpdates) follow(ctx context.Context) {
+	ticker := time.NewTicker(crawlUpdateInterval)
+	defer ticker.Stop()
+
+	for {
+		updates, err := u.store.ListCrawlUpdates(ctx, u.crawlID, u.lastID)
+		if err != nil && ctx.Err() == nil {
+			u.logger.Warnf("Failed to poll crawl updates: %v", err)
+		}
+		for _, update := range updates {
+			u.apply(ctx, update)
+			u.lastID = update.ID
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// apply makes one update to the running crawl
+func (u *crawlUpdates) apply(ctx context.Context, update *store.CrawlUpdate) {
+	if len(update.DenyPatterns) > 0 {
+		if err := u.crawler.Deny(update.DenyPatterns); err != nil {
+			u.logger.Warnf("Crawl update %d: %v", update.ID, err)
+		} else {
+			u.logger.Infof("Crawl update %d: denying %d more URL patterns", update.ID, len(update.DenyPatterns))
+		}
+	}
+
+	if len(update.Seeds) > 0 {
+		if err := u.crawler.AddSeeds(ctx, update.Seeds); err != nil {
+			u.logger.Warnf("Crawl update %d: %v", update.ID, err)
+		} else {
+			u.logger.Infof("Crawl update %d: added %d seeds", update.ID, len(update.Seeds))
+		}
+	}
+
+	// Updates only slow a crawl down; speeding up needs a restart
+	if update.RateLimit > 0 {
+		if u.rateLimit > 0 && update.RateLimit >= u.rateLimit {
+			u.logger.Warnf("Crawl update %d: ignoring rate limit %.2f/s, not below the current %.2f/s",
+				update.ID, update.RateLimit, u.rateLimit)
+		} else {
+			u.crawler.SetRateLimit(update.RateLimit)
+			u.rateLimit = update.RateLimit
+			u.logger.Infof("Crawl update %d: rate limit lowered to %.2f requests per second per host", update.ID, update.RateLimit)
+		}
+	}
+}
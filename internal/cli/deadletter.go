@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"ai-search/internal/events"
+	"ai-search/internal/indexer"
+	"ai-search/internal/metrics"
+	"ai-search/internal/store"
+)
+
+// Stages of indexing a page recorded in dead letters, besides the chunk
+// level indexer.StageElasticsearch
+const (
+	stageEmbedding   = "embedding"
+	stageIndex       = "index"
+	stageReplication = "replication" // Retried by the replicate command
+)
+
+// stageError is an error of one stage of indexing a document
+type stageError struct {
+	stage string
+	err   error
+}
+
+func (e *stageError) Error() string { return e.err.Error() }
+
+func (e *stageError) Unwrap() error { return e.err }
+
+// storeDeadLetters adapts the document store to the indexer.DeadLetterQueue interface
+type storeDeadLetters struct {
+	store store.Store
+}
+
+// Add records a chunk that permanently failed to index
+func (d *storeDeadLetters) Add(ctx context.Context, item *indexer.FailedChunk) error {
+	return d.store.AddDeadLetter(ctx, &store.DeadLetter{
+		Stage:      item.Stage,
+		DocumentID: item.DocumentID,
+		ChunkID:    item.ChunkID,
+		Payload:    item.Payload,
+		Error:      item.Error,
+		Attempts:   item.Attempts,
+	})
+}
+
+// storeEventDeadLetters adapts the document store to the
+// events.DeadLetterQueue interface, recording each document of an event
+// that failed to replicate
+type storeEventDeadLetters struct {
+	store store.Store
+}
+
+// Add records the documents of an event that permanently failed
+func (d *storeEventDeadLetters) Add(ctx context.Context, item *events.FailedEvent) error {
+	metrics.DeadLetters.Inc(stageReplication)
+
+	payload, err := json.Marshal(item.Event)
+	if err != nil {
+		return err
+	}
+	for _, documentID := range item.Event.DocumentIDs {
+		err := d.store.AddDeadLetter(ctx, &store.DeadLetter{
+			Stage:      stageReplication,
+			DocumentID: documentID,
+			Payload:    payload,
+			Error:      item.Error,
+			Attempts:   item.Attempts,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deadLetterDocument records a document that failed to embed or index so
+// that retry-failed can process it again. Chunks the indexer already
+// dead-lettered, and documents of an interrupted crawl, are not recorded.
+func deadLetterDocument(ctx context.Context, documentStore store.Store, doc *indexer.Document, err error) {
+	var failure *stageError
+	if !errors.As(err, &failure) || errors.Is(err, indexer.ErrDeadLettered) || ctx.Err() != nil {
+		return
+	}
+	metrics.DeadLetters.Inc(failure.stage)
+
+	payload, marshalErr := json.Marshal(doc)
+	if marshalErr != nil {
+		log.Printf("Failed to dead-letter document %s: %v", doc.ID, marshalErr)
+		return
+	}
+
+	err = documentStore.AddDeadLetter(context.WithoutCancel(ctx), &store.DeadLetter{
+		Stage:      failure.stage,
+		DocumentID: doc.ID,
+		Payload:    payload,
+		Error:      failure.Error(),
+		Attempts:   1,
+	})
+	if err != nil {
+		log.Printf("Failed to dead-letter document %s: %v", doc.ID, err)
+	}
+}
@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"ai-search/internal/config"
+	"ai-search/internal/embeddings"
+	"ai-search/internal/events"
+	"ai-search/internal/indexer"
+	"ai-search/internal/retention"
+	"ai-search/internal/store"
+
+	"github.com/spf13/cobra"
+)
+
+// deleteCmd represents the delete command
+var deleteCmd = &cobra.Command{
+	Use:   "delete <doc-id>",
+	Short: "Delete a document",
+	Long: `Soft-delete a document. It is excluded from search immediately and purged
+from all stores once DELETE_RETENTION_HOURS have passed. Until then it can be
+brought back with the restore command.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDelete,
+}
+
+// purgeCmd represents the purge command
+var purgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Purge deleted documents past their restore window",
+	Long: `Remove documents deleted more than DELETE_RETENTION_HOURS ago from
+Elasticsearch, ChromaDB and PostgreSQL. The server also purges hourly.`,
+	RunE: runPurge,
+}
+
+// newDocumentStore builds a document store from the application configuration
+func newDocumentStore(cfg *config.Config) store.Store {
+	return store.NewStore(store.Config{
+		Type:     cfg.DatabaseType,
+		Host:     cfg.DatabaseHost,
+		Port:     cfg.DatabasePort,
+		Database: cfg.DatabaseName,
+		Username: cfg.DatabaseUser,
+		Password: cfg.DatabasePassword,
+		SSLMode:  cfg.DatabaseSSLMode,
+	})
+}
+
+func runDelete(cmd *cobra.Command, args []string) error {
+	cfg := config.LoadConfig()
+
+	documentStore := newDocumentStore(cfg)
+	defer documentStore.Close()
+
+	hybridIndexer, err := newDeletionIndexer(cfg, documentStore)
+	if err != nil {
+		return err
+	}
+	defer hybridIndexer.Close()
+
+	ctx := context.Background()
+	if err := retention.SoftDelete(ctx, documentStore, hybridIndexer, args[0]); err != nil {
+		return err
+	}
+	if err := publishEvent(ctx, cfg, events.DocumentDeleted, args[0]); err != nil {
+		return err
+	}
+
+	fmt.Printf("Document %s deleted, restorable for %d hours\n", args[0], cfg.DeleteRetention)
+	return nil
+}
+
+// restoreDocument clears the deletion mark of a soft-deleted document
+func restoreDocument(cfg *config.Config, id string) error {
+	documentStore := newDocumentStore(cfg)
+	defer documentStore.Close()
+
+	hybridIndexer, err := newDeletionIndexer(cfg, documentStore)
+	if err != nil {
+		return err
+	}
+	defer hybridIndexer.Close()
+
+	ctx := context.Background()
+	if err := retention.Restore(ctx, documentStore, hybridIndexer, id); err != nil {
+		return err
+	}
+	if err := publishEvent(ctx, cfg, events.DocumentRestored, id); err != nil {
+		return err
+	}
+
+	fmt.Printf("Document %s restored\n", id)
+	return nil
+}
+
+func runPurge(cmd *cobra.Command, args []string) error {
+	cfg := config.LoadConfig()
+
+	documentStore := newDocumentStore(cfg)
+	defer documentStore.Close()
+
+	hybridIndexer, err := newDeletionIndexer(cfg, documentStore)
+	if err != nil {
+		return err
+	}
+	defer hybridIndexer.Close()
+
+	publisher, err := newPublisher(cfg)
+	if err != nil {
+		return err
+	}
+	defer publisher.Close()
+
+	purger := retention.NewPurger(retention.Config{
+		Store:   documentStore,
+		Indexer: hybridIndexer,
+		Window:  time.Duration(cfg.DeleteRetention) * time.Hour,
+		Events:  publisher,
+	})
+
+	purged, err := purger.Purge(context.Background())
+	fmt.Printf("Purged %d deleted documents\n", len(purged))
+	return err
+}
+
+// newDeletionIndexer opens the main collection's index for marking and
+// purging deleted documents
+func newDeletionIndexer(cfg *config.Config, documentStore store.Store) (indexer.Indexer, error) {
+	embeddingModels, err := embeddings.ParseModels(cfg.EmbeddingModels)
+	if err != nil {
+		return nil, err
+	}
+	embedder := embeddings.NewEmbedder(embeddings.Config{
+		Model:   cfg.EmbeddingModel,
+		APIKey:  cfg.EmbeddingAPIKey,
+		BaseURL: cfg.EmbeddingBaseURL,
+		Models:  embeddingModels,
+	})
+	return indexer.NewIndexer(indexer.Config{
+		Embedder:       embedder,
+		ChromaURL:      cfg.ChromaURL,
+		ElasticURL:     cfg.ElasticURL,
+		ElasticIndex:   cfg.ElasticIndex,
+		CollectionName: cfg.CollectionName,
+		Chunking:       chunkingConfig(cfg),
+		MaxRetries:     cfg.ElasticMaxRetries,
+		DeadLetters:    &storeDeadLetters{store: documentStore},
+
+		ContextualEnrichment: cfg.ContextualEnrichment,
+		DefaultVersion:       cfg.DefaultVersion,
+	}), nil
+}
@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"ai-search/internal/config"
+	"ai-search/internal/embeddings"
+	"ai-search/internal/indexer"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	driftSample    int
+	driftThreshold float64
+	driftWorst     int
+)
+
+// driftCmd represents the eval drift command
+var driftCmd = &cobra.Command{
+	Use:   "drift",
+	Short: "Check that stored chunk embeddings match the configured embedding model",
+	Long: `Sample chunks from the vector index, embed their text again with
+EMBEDDING_MODEL the way they were indexed, and compare each stored embedding
+with the new one by cosine similarity. The same model and provider give
+similarities close to 1; a changed model, provider or EMBED_TITLE setting
+gives much lower ones, and queries embedded by the server then no longer
+match the index. The command fails when the mean similarity is below
+--threshold or the dimensions differ, so it can alert from a scheduled job.`,
+	RunE: runDrift,
+}
+
+func init() {
+	driftCmd.Flags().IntVar(&driftSample, "sample", 100, "Chunks to sample")
+	driftCmd.Flags().Float64Var(&driftThreshold, "threshold", 0.95, "Lowest acceptable mean cosine similarity")
+	driftCmd.Flags().IntVar(&driftWorst, "worst", 5, "Least similar chunks to list")
+
+	evalCmd.AddCommand(driftCmd)
+}
+
+// chunkDrift is the similarity of a chunk's stored and fresh embeddings
+type chunkDrift struct {
+	chunk      *indexer.StoredChunk
+	similarity float64
+}
+
+func runDrift(cmd *cobra.Command, args []string) error {
+	if driftSample <= 0 {
+		return fmt.Errorf("--sample must be positive")
+	}
+
+	cfg := config.LoadConfig()
+	if cfg.EmbeddingAPIKey == "" && cfg.EmbeddingModel != embeddings.MockModel {
+		return fmt.Errorf("EMBEDDING_API_KEY environment variable is required to embed chunks")
+	}
+
+	embeddingModels, err := embeddings.ParseModels(cfg.EmbeddingModels)
+	if err != nil {
+		return err
+	}
+	embedder := embeddings.NewEmbedder(embeddings.Config{
+		Model:   cfg.EmbeddingModel,
+		APIKey:  cfg.EmbeddingAPIKey,
+		BaseURL: cfg.EmbeddingBaseURL,
+		Models:  embeddingModels,
+	})
+	hybridIndexer := indexer.NewIndexer(indexer.Config{
+		Embedder:       embedder,
+		ChromaURL:      cfg.ChromaURL,
+		ElasticURL:     cfg.ElasticURL,
+		ElasticIndex:   cfg.ElasticIndex,
+		CollectionName: cfg.CollectionName,
+		Chunking:       chunkingConfig(cfg),
+
+		ContextualEnrichment: cfg.ContextualEnrichment,
+		DefaultVersion:       cfg.DefaultVersion,
+	})
+	defer hybridIndexer.Close()
+
+	ctx := context.Background()
+	chunks, err := hybridIndexer.SampleChunks(ctx, driftSample)
+	if err != nil {
+		return err
+	}
+	if len(chunks) == 0 {
+		fmt.Printf("No chunks with embeddings in %s\n", cfg.CollectionName)
+		return nil
+	}
+
+	// Chunks are embedded again with the same document context they were
+	// indexed with; context sentences are stored, not written again
+	contextual := &chunkEmbedder{embedder: embedder, title: cfg.EmbedTitle, urlPath: cfg.EmbedURLPath}
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		prefix := contextual.prefix(&indexer.Document{Title: chunk.Title, URL: chunk.URL})
+		texts[i] = embeddingText(prefix, chunk.Context, chunk.Text)
+	}
+	fresh, err := embedder.EmbedBatch(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("failed to embed sampled chunks: %w", err)
+	}
+
+	fmt.Printf("Sampled %d chunks of %s, embedded again with %s\n", len(chunks), cfg.CollectionName, cfg.EmbeddingModel)
+
+	drifts := make([]chunkDrift, len(chunks))
+	var sum float64
+	below := 0
+	for i, chunk := range chunks {
+		// Vectors of different sizes cannot come from the same model
+		if len(chunk.Embedding) != len(fresh[i]) {
+			return fmt.Errorf("embedding drift: the index holds %d-dimensional embeddings but %s produces %d dimensions",
+				len(chunk.Embedding), cfg.EmbeddingModel, len(fresh[i]))
+		}
+		similarity := cosineSimilarity(chunk.Embedding, fresh[i])
+		drifts[i] = chunkDrift{chunk: chunk, similarity: similarity}
+		sum += similarity
+		if similarity < driftThreshold {
+			below++
+		}
+	}
+	sort.Slice(drifts, func(i, j int) bool { return drifts[i].similarity < drifts[j].similarity })
+	mean := sum / float64(len(drifts))
+
+	fmt.Printf("Cosine similarity: mean %.4f, min %.4f, median %.4f\n",
+		mean, drifts[0].similarity, drifts[len(drifts)/2].similarity)
+	fmt.Printf("%d of %d chunks below %.2f\n", below, len(drifts), driftThreshold)
+	if driftWorst > 0 {
+		fmt.Println("Least similar chunks:")
+		for _, drift := range drifts[:min(driftWorst, len(drifts))] {
+			fmt.Printf("  %.4f  %s  %s\n", drift.similarity, drift.chunk.ChunkID, drift.chunk.URL)
+		}
+	}
+
+	if mean < driftThreshold {
+		return fmt.Errorf("embedding drift: mean similarity %.4f is below %.2f; %s no longer matches the embeddings in %s, reindex it or restore the model it was built with",
+			mean, driftThreshold, cfg.EmbeddingModel, cfg.CollectionName)
+	}
+	fmt.Println("No embedding drift")
+	return nil
+}
+
+// cosineSimilarity returns the cosine of the angle between two vectors of
+// the same size, 0 when either is zero
+func cosineSimilarity(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"strings"
+
+	"ai-search/internal/chunker"
+	"ai-search/internal/config"
+	"ai-search/internal/embeddings"
+	"ai-search/internal/indexer"
+)
+
+// chunkEmbedder embeds the chunks of a document. Chunk text is optionally
+// prefixed with the document's title and the section path of its URL, and
+// in collections with contextual enrichment the chunk's context sentence,
+// so chunks that never name the page's subject still embed close to it.
+// The stored chunk text is left as it is.
+type chunkEmbedder struct {
+	embedder embeddings.Embedder
+	enricher *chunkEnricher
+	title    bool
+	urlPath  bool
+}
+
+// newChunkEmbedder builds a chunk embedder with the EMBED_TITLE and
+// EMBED_URL_PATH settings and the collection's contextual enrichment
+func newChunkEmbedder(cfg *config.Config, embedder embeddings.Embedder, hybridIndexer indexer.Indexer) (*chunkEmbedder, error) {
+	enricher, err := newChunkEnricher(cfg, hybridIndexer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &chunkEmbedder{
+		embedder: embedder,
+		enricher: enricher,
+		title:    cfg.EmbedTitle,
+		urlPath:  cfg.EmbedURLPath,
+	}, nil
+}
+
+// embed enriches a document's chunks when the collection asks for it and
+// returns their embeddings
+func (e *chunkEmbedder) embed(ctx context.Context, doc *indexer.Document, chunks []*chunker.Chunk) ([][]float32, error) {
+	if err := e.enricher.enrich(ctx, doc, chunks); err != nil {
+		return nil, err
+	}
+	prefix := e.prefix(doc)
+
+	texts := make([]string, len(chunks))
+	for j, chunk := range chunks {
+		sentence, _ := chunk.Metadata[indexer.ChunkContextKey].(string)
+		texts[j] = embeddingText(prefix, sentence, chunk.Text)
+	}
+	return e.embedder.EmbedBatch(ctx, texts)
+}
+
+// embeddingText is the text embedded for a chunk: the document context,
+// then the chunk's context sentence when it has one, then its text
+func embeddingText(prefix, sentence, text string) string {
+	if sentence != "" {
+		return prefix + sentence + "\n\n" + text
+	}
+	return prefix + text
+}
+
+// prefix returns the document context put before each chunk's text
+func (e *chunkEmbedder) prefix(doc *indexer.Document) string {
+	var prefix strings.Builder
+	if e.title && doc.Title != "" {
+		prefix.WriteString("Title: " + doc.Title + "\n")
+	}
+	if e.urlPath {
+		if section := sectionPath(doc.URL); section != "" {
+			prefix.WriteString("Section: " + section + "\n")
+		}
+	}
+	if prefix.Len() == 0 {
+		return ""
+	}
+	prefix.WriteString("\n")
+	return prefix.String()
+}
+
+// sectionPath renders the path of a URL as a breadcrumb of words, e.g.
+// https://example.com/docs/api/getting-started.html becomes
+// "example.com > docs > api > getting started"
+func sectionPath(rawURL string) string {
+	pageURL, err := url.Parse(rawURL)
+	if err != nil || pageURL.Host == "" {
+		return ""
+	}
+
+	sections := []string{pageURL.Hostname()}
+	for _, segment := range strings.Split(strings.Trim(pageURL.Path, "/"), "/") {
+		if unescaped, err := url.PathUnescape(segment); err == nil {
+			segment = unescaped
+		}
+		segment = strings.TrimSuffix(segment, path.Ext(segment))
+		words := strings.FieldsFunc(segment, func(r rune) bool {
+			return r == '-' || r == '_' || r == '+' || r == ' '
+		})
+		if len(words) > 0 {
+			sections = append(sections, strings.Join(words, " "))
+		}
+	}
+	return strings.Join(sections, " > ")
+}
@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"ai-search/internal/chunker"
+	"ai-search/internal/config"
+	"ai-search/internal/indexer"
+	"ai-search/internal/llm"
+)
+
+// situateTimeout bounds generating the context of one chunk, and
+// situateWorkers the chunks of a document situated at once
+const (
+	situateTimeout = 30 * time.Second
+	situateWorkers = 4
+)
+
+// chunkEnricher has the LLM write a sentence situating each chunk within
+// its document (contextual retrieval). The sentence is kept in the chunk's
+// metadata under indexer.ChunkContextKey, from where it is embedded and
+// keyword-indexed with the chunk.
+type chunkEnricher struct {
+	llm llm.LLM
+}
+
+// newChunkEnricher returns an enricher when the collection uses contextual
+// enrichment, nil otherwise
+func newChunkEnricher(cfg *config.Config, hybridIndexer indexer.Indexer) (*chunkEnricher, error) {
+	if !hybridIndexer.ContextualEnrichment() {
+		return nil, nil
+	}
+	if cfg.LLMAPIKey == "" && cfg.LLMProvider != llm.MockProvider {
+		return nil, fmt.Errorf("LLM_API_KEY environment variable is required for contextual enrichment of %s", cfg.CollectionName)
+	}
+
+	return &chunkEnricher{
+		llm: llm.NewLLM(llm.Config{
+			Provider: cfg.LLMProvider,
+			Model:    cfg.LLMModel,
+			APIKey:   cfg.LLMAPIKey,
+			BaseURL:  cfg.LLMBaseURL,
+		}),
+	}, nil
+}
+
+// enrich situates every chunk of a document. It fails if any chunk cannot
+// be situated, so a document is never indexed half enriched.
+func (e *chunkEnricher) enrich(ctx context.Context, doc *indexer.Document, chunks []*chunker.Chunk) error {
+	if e == nil {
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	slots := make(chan struct{}, situateWorkers)
+
+	for _, chunk := range chunks {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			chunkCtx, cancel := context.WithTimeout(ctx, situateTimeout)
+			defer cancel()
+
+			sentence, err := e.llm.SituateChunk(chunkCtx, doc.Content, chunk.Text)
+			if err != nil {
+				once.Do(func() { firstErr = fmt.Errorf("failed to situate chunk %s of %s: %w", chunk.ID, doc.URL, err) })
+				return
+			}
+			if chunk.Metadata == nil {
+				chunk.Metadata = make(map[string]interface{})
+			}
+			chunk.Metadata[indexer.ChunkContextKey] = sentence
+		}()
+	}
+
+	wg.Wait()
+	return firstErr
+}
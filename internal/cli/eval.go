@@ -0,0 +1,237 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"ai-search/internal/config"
+	"ai-search/internal/store"
+
+	"github.com/spf13/cobra"
+)
+
+// Training data formats written by export-triplets
+const (
+	tripletFormat = "triplets" // One {"query", "positive", "negative"} per line
+	groupedFormat = "grouped"  // One {"query", "pos": [...], "neg": [...]} per query
+)
+
+var (
+	tripletOutput     string
+	tripletFormatFlag string
+	tripletNegatives  int
+	tripletJudgedOnly bool
+)
+
+// evalCmd groups the retrieval evaluation commands
+var evalCmd = &cobra.Command{
+	Use:   "eval",
+	Short: "Evaluate retrieval and export training data",
+}
+
+// exportTripletsCmd represents the eval export-triplets command
+var exportTripletsCmd = &cobra.Command{
+	Use:   "export-triplets",
+	Short: "Export (query, positive, hard negative) triplets for fine-tuning embeddings",
+	Long: `Export training triplets mined from the query log and search feedback.
+Chunks judged relevant to a query are its positives. Its hard negatives are
+the chunks judged irrelevant, then (unless --judged-only) the chunks the
+search returned for it that were not judged relevant, best ranked first, as
+those are the ones the current model confuses with the answer.
+
+The triplets format writes one {"query", "positive", "negative"} object per
+line for sentence-transformers; grouped writes one {"query", "pos", "neg"}
+object per query as used by FlagEmbedding.`,
+	RunE: runExportTriplets,
+}
+
+func init() {
+	exportTripletsCmd.Flags().StringVarP(&tripletOutput, "output", "o", "", "File to write (defaults to stdout)")
+	exportTripletsCmd.Flags().StringVar(&tripletFormatFlag, "format", tripletFormat, "Output format (triplets or grouped)")
+	exportTripletsCmd.Flags().IntVar(&tripletNegatives, "negatives", 3, "Hard negatives per positive")
+	exportTripletsCmd.Flags().BoolVar(&tripletJudgedOnly, "judged-only", false, "Only use chunks judged irrelevant as negatives")
+
+	evalCmd.AddCommand(exportTripletsCmd)
+}
+
+// judgedQuery is a query with its positive and hard negative chunk IDs
+type judgedQuery struct {
+	query     string
+	positives []string
+	negatives []string
+}
+
+func runExportTriplets(cmd *cobra.Command, args []string) error {
+	if tripletFormatFlag != tripletFormat && tripletFormatFlag != groupedFormat {
+		return fmt.Errorf("unknown format %q, expected %s or %s", tripletFormatFlag, tripletFormat, groupedFormat)
+	}
+	if tripletNegatives <= 0 {
+		return fmt.Errorf("--negatives must be positive")
+	}
+
+	cfg := config.LoadConfig()
+	documentStore := newDocumentStore(cfg)
+	defer documentStore.Close()
+
+	ctx := context.Background()
+	queries, err := judgedQueries(ctx, documentStore, tripletJudgedOnly)
+	if err != nil {
+		return err
+	}
+
+	// Load the text of every chunk involved at once
+	var chunkIDs []string
+	for _, q := range queries {
+		chunkIDs = append(chunkIDs, q.positives...)
+		chunkIDs = append(chunkIDs, q.negatives...)
+	}
+	texts, err := documentStore.GetChunkTexts(ctx, chunkIDs)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if tripletOutput != "" {
+		file, err := os.Create(tripletOutput)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+	var written, exported int
+	for _, q := range queries {
+		positives := chunkTexts(q.positives, texts, 0)
+		negatives := chunkTexts(q.negatives, texts, tripletNegatives)
+		if len(positives) == 0 || len(negatives) == 0 {
+			continue
+		}
+		exported++
+
+		if tripletFormatFlag == groupedFormat {
+			record := map[string]interface{}{"query": q.query, "pos": positives, "neg": negatives}
+			if err := encoder.Encode(record); err != nil {
+				return fmt.Errorf("failed to write record: %w", err)
+			}
+			written++
+			continue
+		}
+
+		for _, positive := range positives {
+			for _, negative := range negatives {
+				record := map[string]string{"query": q.query, "positive": positive, "negative": negative}
+				if err := encoder.Encode(record); err != nil {
+					return fmt.Errorf("failed to write triplet: %w", err)
+				}
+				written++
+			}
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Exported %d records from %d of %d judged queries\n", written, exported, len(queries))
+	return nil
+}
+
+// judgedQueries pairs the queries with positive feedback with their hard
+// negatives: chunks judged irrelevant, then unless judgedOnly the chunks
+// logged for the query that were not judged relevant, best ranked first
+func judgedQueries(ctx context.Context, documentStore store.Store, judgedOnly bool) ([]*judgedQuery, error) {
+	feedback, err := documentStore.ListFeedback(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byQuery := make(map[string]*judgedQuery)
+	var queries []*judgedQuery
+	judged := make(map[string]map[string]bool)
+	for _, f := range feedback {
+		q, exists := byQuery[f.Query]
+		if !exists {
+			q = &judgedQuery{query: f.Query}
+			byQuery[f.Query] = q
+			queries = append(queries, q)
+			judged[f.Query] = make(map[string]bool)
+		}
+		judged[f.Query][f.ChunkID] = true
+		if f.Relevant {
+			q.positives = append(q.positives, f.ChunkID)
+		} else {
+			q.negatives = append(q.negatives, f.ChunkID)
+		}
+	}
+
+	// Only queries with an answer make triplets
+	kept := queries[:0]
+	for _, q := range queries {
+		if len(q.positives) > 0 {
+			kept = append(kept, q)
+		}
+	}
+	queries = kept
+	if judgedOnly || len(queries) == 0 {
+		return queries, nil
+	}
+
+	names := make([]string, len(queries))
+	for i, q := range queries {
+		names[i] = q.query
+	}
+	logs, err := documentStore.ListQueryLogs(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rank each returned chunk by its best position across searches
+	ranks := make(map[string]map[string]int)
+	for _, entry := range logs {
+		if ranks[entry.Query] == nil {
+			ranks[entry.Query] = make(map[string]int)
+		}
+		for rank, chunkID := range entry.ChunkIDs {
+			if best, exists := ranks[entry.Query][chunkID]; !exists || rank < best {
+				ranks[entry.Query][chunkID] = rank
+			}
+		}
+	}
+
+	for _, q := range queries {
+		var shown []string
+		for chunkID := range ranks[q.query] {
+			if !judged[q.query][chunkID] {
+				shown = append(shown, chunkID)
+			}
+		}
+		sort.Slice(shown, func(i, j int) bool {
+			ri, rj := ranks[q.query][shown[i]], ranks[q.query][shown[j]]
+			if ri != rj {
+				return ri < rj
+			}
+			return shown[i] < shown[j]
+		})
+		q.negatives = append(q.negatives, shown...)
+	}
+
+	return queries, nil
+}
+
+// chunkTexts returns the texts of the chunks that still exist, at most
+// limit of them unless limit is 0
+func chunkTexts(chunkIDs []string, texts map[string]string, limit int) []string {
+	var result []string
+	for _, chunkID := range chunkIDs {
+		if limit > 0 && len(result) == limit {
+			break
+		}
+		if text, ok := texts[chunkID]; ok {
+			result = append(result, text)
+		}
+	}
+	return result
+}
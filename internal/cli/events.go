@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"context"
+	"strings"
+
+	"ai-search/internal/config"
+	"ai-search/internal/events"
+)
+
+// newPublisher builds the index mutation event publisher
+func newPublisher(cfg *config.Config) (events.Publisher, error) {
+	var webhooks []string
+	for _, webhook := range strings.Split(cfg.InvalidationWebhooks, ",") {
+		if webhook = strings.TrimSpace(webhook); webhook != "" {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+
+	return events.NewPublisher(events.Config{
+		Webhooks:     webhooks,
+		RedisURL:     cfg.RedisURL,
+		RedisChannel: cfg.InvalidationChannel,
+		RedisStream:  cfg.OutboxStream,
+	})
+}
+
+// publishEvent publishes a single index mutation event
+func publishEvent(ctx context.Context, cfg *config.Config, eventType string, documentIDs ...string) error {
+	publisher, err := newPublisher(cfg)
+	if err != nil {
+		return err
+	}
+	defer publisher.Close()
+
+	publisher.Publish(ctx, eventType, documentIDs...)
+	return nil
+}
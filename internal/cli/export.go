@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"ai-search/internal/config"
+	"ai-search/internal/export"
+	"ai-search/internal/store"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportEvery       int
+	exportDestination string
+	exportFormat      string
+	exportDatasets    string
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export query logs, feedback and crawl stats to a warehouse",
+	Long: `Ship the query log, relevance feedback and crawl run stats recorded since
+the last export to a directory, S3, BigQuery or a PostgreSQL warehouse, as
+Parquet or CSV files partitioned by day. Each dataset remembers how far it was
+exported to each destination, so rows are shipped once and a failed export is
+retried on the next run. With --every the export is repeated on a schedule
+until interrupted.`,
+	RunE: runExport,
+}
+
+func init() {
+	exportCmd.Flags().IntVar(&exportEvery, "every", -1, "Minutes between exports (0 runs once, defaults to EXPORT_INTERVAL)")
+	exportCmd.Flags().StringVar(&exportDestination, "destination", "", "Directory, s3://bucket/prefix, bigquery://project/dataset or postgres:// URL (defaults to EXPORT_DESTINATION)")
+	exportCmd.Flags().StringVar(&exportFormat, "format", "", "File format: parquet or csv (defaults to EXPORT_FORMAT)")
+	exportCmd.Flags().StringVar(&exportDatasets, "datasets", "", "Comma-separated datasets: query_log, feedback, crawl_runs (defaults to EXPORT_DATASETS, all when empty)")
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	cfg := config.LoadConfig()
+
+	// Override config with command line flags
+	if exportDestination != "" {
+		cfg.ExportDestination = exportDestination
+	}
+	if exportFormat != "" {
+		cfg.ExportFormat = exportFormat
+	}
+	if exportDatasets != "" {
+		cfg.ExportDatasets = exportDatasets
+	}
+	if cfg.ExportDestination == "" {
+		return fmt.Errorf("no export destination: set EXPORT_DESTINATION or --destination")
+	}
+
+	destination, err := export.NewDestination(cfg.ExportDestination, export.DestinationConfig{
+		S3Region:              cfg.ExportS3Region,
+		S3Endpoint:            cfg.ExportS3Endpoint,
+		AWSAccessKeyID:        cfg.AWSAccessKeyID,
+		AWSSecretAccessKey:    cfg.AWSSecretAccessKey,
+		AWSSessionToken:       cfg.AWSSessionToken,
+		GoogleCredentialsFile: cfg.GoogleCredentialsFile,
+	})
+	if err != nil {
+		return err
+	}
+
+	documentStore := store.NewStore(store.Config{
+		Type:     cfg.DatabaseType,
+		Host:     cfg.DatabaseHost,
+		Port:     cfg.DatabasePort,
+		Database: cfg.DatabaseName,
+		Username: cfg.DatabaseUser,
+		Password: cfg.DatabasePassword,
+		SSLMode:  cfg.DatabaseSSLMode,
+	})
+	defer documentStore.Close()
+
+	exporter, err := export.NewExporter(export.Config{
+		Store:       documentStore,
+		Destination: destination,
+		Format:      cfg.ExportFormat,
+		Datasets:    splitList(cfg.ExportDatasets),
+		PageSize:    cfg.ExportPageSize,
+	})
+	if err != nil {
+		return err
+	}
+
+	interval := exportEvery
+	if interval < 0 {
+		interval = cfg.ExportInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runOnce := func() error {
+		fmt.Printf("Exporting to %s...\n", destination.Name())
+		results, err := exporter.Export(ctx)
+		for _, result := range results {
+			if result.Rows == 0 {
+				fmt.Printf("  %s: no new rows\n", result.Dataset)
+				continue
+			}
+			if result.Batches > 1 {
+				fmt.Printf("  %s: %d rows in %d batches, the last to %s\n", result.Dataset, result.Rows, result.Batches, result.Location)
+				continue
+			}
+			fmt.Printf("  %s: %d rows to %s\n", result.Dataset, result.Rows, result.Location)
+		}
+		return err
+	}
+
+	if interval == 0 {
+		return runOnce()
+	}
+
+	fmt.Printf("Exporting every %d minutes. Press Ctrl+C to stop.\n", interval)
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	ticker := time.NewTicker(time.Duration(interval) * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		if err := runOnce(); err != nil {
+			fmt.Fprintf(os.Stderr, "Export failed: %v\n", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-quit:
+			fmt.Println("\nStopping scheduled exports")
+			return nil
+		}
+	}
+}
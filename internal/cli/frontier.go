@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"context"
+
+	"ai-search/internal/crawler"
+	"ai-search/internal/store"
+)
+
+// storeFrontier adapts the document store to the crawler.Frontier interface
+type storeFrontier struct {
+	store   store.Store
+	crawlID string
+}
+
+// Add records URLs waiting to be crawled
+func (f *storeFrontier) Add(ctx context.Context, urls []crawler.FrontierURL) error {
+	entries := make([]*store.FrontierURL, 0, len(urls))
+	for _, u := range urls {
+		entries = append(entries, &store.FrontierURL{URL: u.URL, Depth: u.Depth})
+	}
+	return f.store.AddFrontierURLs(ctx, f.crawlID, entries)
+}
+
+// MarkVisited records that a URL has been crawled
+func (f *storeFrontier) MarkVisited(ctx context.Context, rawURL string) error {
+	return f.store.MarkFrontierVisited(ctx, f.crawlID, rawURL)
+}
+
+// Load returns the pending and visited URLs of a previous run
+func (f *storeFrontier) Load(ctx context.Context) ([]crawler.FrontierURL, []string, error) {
+	pending, visited, err := f.store.GetFrontier(ctx, f.crawlID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	urls := make([]crawler.FrontierURL, 0, len(pending))
+	for _, u := range pending {
+		urls = append(urls, crawler.FrontierURL{URL: u.URL, Depth: u.Depth})
+	}
+	return urls, visited, nil
+}
+
+// Clear forgets the URLs of previous runs
+func (f *storeFrontier) Clear(ctx context.Context) error {
+	return f.store.ClearFrontier(ctx, f.crawlID)
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"ai-search/internal/server"
+
+	"gopkg.in/yaml.v3"
+)
+
+// apiKeyEntry is an entry of the API keys file
+type apiKeyEntry struct {
+	ID              string  `yaml:"id"`
+	Key             string  `yaml:"key"`
+	RateLimit       float64 `yaml:"rate_limit,omitempty"` // Requests per second
+	Burst           int     `yaml:"burst,omitempty"`
+	MonthlyRequests int64   `yaml:"monthly_requests,omitempty"`
+	MonthlyTokens   int64   `yaml:"monthly_tokens,omitempty"`
+}
+
+// loadAPIKeysFile reads API keys with their rate limits and monthly quotas
+// from a YAML file
+func loadAPIKeysFile(path string) ([]server.APIKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API keys file: %w", err)
+	}
+	var entries []apiKeyEntry
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid API keys file %s: %w", path, err)
+	}
+
+	ids := make(map[string]bool)
+	secrets := make(map[string]bool)
+	var keys []server.APIKey
+	for i, entry := range entries {
+		if entry.ID == "" || entry.Key == "" {
+			return nil, fmt.Errorf("invalid API keys file %s: entry %d needs an id and a key", path, i+1)
+		}
+		if ids[entry.ID] {
+			return nil, fmt.Errorf("invalid API keys file %s: id %s is used twice", path, entry.ID)
+		}
+		if secrets[entry.Key] {
+			return nil, fmt.Errorf("invalid API keys file %s: the key of %s is used twice", path, entry.ID)
+		}
+		ids[entry.ID] = true
+		secrets[entry.Key] = true
+
+		keys = append(keys, server.APIKey{
+			ID:              entry.ID,
+			Key:             entry.Key,
+			RateLimit:       entry.RateLimit,
+			Burst:           entry.Burst,
+			MonthlyRequests: entry.MonthlyRequests,
+			MonthlyTokens:   entry.MonthlyTokens,
+		})
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("invalid API keys file %s: no keys", path)
+	}
+	return keys, nil
+}
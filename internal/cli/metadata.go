@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"ai-search/internal/crawler"
+)
+
+// Attributes with special meaning at index and query time
+const (
+	versionAttribute  = "version"  // Corpus version of a document
+	languageAttribute = "language" // Primary language subtag of a document
+)
+
+// metadataRule attaches attributes to every URL starting with Prefix
+type metadataRule struct {
+	Prefix     string            `json:"prefix"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+// metadataRules resolves the custom attributes for crawled URLs
+type metadataRules struct {
+	rules []metadataRule
+}
+
+// loadMetadataRules loads an optional JSON file of per-prefix attributes
+func loadMetadataRules(path string) (*metadataRules, error) {
+	rules := &metadataRules{}
+	if path == "" {
+		return rules, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata file: %w", err)
+	}
+	if err := json.Unmarshal(data, &rules.rules); err != nil {
+		return nil, fmt.Errorf("invalid metadata file %s: %w", path, err)
+	}
+
+	return rules, nil
+}
+
+// attributesFor returns the attributes for a page. The crawl's metadata,
+// carried by the page, applies first, then rules matching its URL from the
+// shortest to the longest prefix so more specific rules win.
+func (m *metadataRules) attributesFor(page *crawler.Page) map[string]string {
+	attributes := make(map[string]string)
+	for key, value := range page.Metadata {
+		attributes[key] = value
+	}
+
+	pageURL := page.URL.String()
+
+	var matched []metadataRule
+	for _, rule := range m.rules {
+		if strings.HasPrefix(pageURL, rule.Prefix) {
+			matched = append(matched, rule)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		return len(matched[i].Prefix) < len(matched[j].Prefix)
+	})
+
+	for _, rule := range matched {
+		for key, value := range rule.Attributes {
+			attributes[key] = value
+		}
+	}
+
+	return attributes
+}
@@ -0,0 +1,286 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ai-search/internal/crawler"
+	"ai-search/internal/metrics"
+
+	"github.com/sirupsen/logrus"
+)
+
+// progress renders crawl counters on a single refreshed terminal line.
+// It doubles as the log writer so log lines do not garble the display.
+type progress struct {
+	out   *os.File
+	tty   bool
+	quiet bool
+	start time.Time
+
+	fetched int64
+	indexed int64
+	failed  int64
+
+	// domains reports per-host request rates and stats the crawler's own
+	// counters once the crawl has started
+	domains func() []crawler.DomainStats
+	stats   func() crawler.CrawlStats
+
+	mutex sync.Mutex
+	drawn bool
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// progressInterval is how often the line is redrawn on a terminal, and
+// logInterval how often progress is printed when output is redirected
+const (
+	progressInterval = 500 * time.Millisecond
+	logInterval      = 10 * time.Second
+)
+
+// newProgress creates a progress display writing to out
+func newProgress(out *os.File, quiet bool) *progress {
+	tty := false
+	if info, err := out.Stat(); err == nil {
+		tty = info.Mode()&os.ModeCharDevice != 0
+	}
+
+	return &progress{
+		out:   out,
+		tty:   tty,
+		quiet: quiet,
+		start: time.Now(),
+		done:  make(chan struct{}),
+	}
+}
+
+// Start begins refreshing the display
+func (p *progress) Start() {
+	if p.quiet {
+		return
+	}
+
+	interval := logInterval
+	if p.tty {
+		interval = progressInterval
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.mutex.Lock()
+				p.draw()
+				p.mutex.Unlock()
+			case <-p.done:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the display, leaving the final counters on screen
+func (p *progress) Stop() {
+	close(p.done)
+	p.wg.Wait()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.drawn {
+		fmt.Fprintln(p.out)
+		p.drawn = false
+	}
+}
+
+// Fetched, Indexed and Failed count pages
+func (p *progress) Fetched() { atomic.AddInt64(&p.fetched, 1) }
+func (p *progress) Indexed() { atomic.AddInt64(&p.indexed, 1) }
+func (p *progress) Failed()  { atomic.AddInt64(&p.failed, 1) }
+
+// IndexedCount returns the number of pages indexed so far
+func (p *progress) IndexedCount() int64 { return atomic.LoadInt64(&p.indexed) }
+
+// Printf prints a status message unless quiet
+func (p *progress) Printf(format string, args ...interface{}) {
+	if p.quiet {
+		return
+	}
+	fmt.Fprintf(p, format, args...)
+}
+
+// Write writes log output above the progress line
+func (p *progress) Write(b []byte) (int, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.clear()
+	n, err := p.out.Write(b)
+	if p.tty && !p.quiet && p.drawn {
+		p.draw()
+	}
+	return n, err
+}
+
+// Summary returns the final counters
+func (p *progress) Summary() string {
+	summary := fmt.Sprintf("Processed %d pages, indexed %d pages, %d failed in %s",
+		atomic.LoadInt64(&p.fetched), atomic.LoadInt64(&p.indexed), atomic.LoadInt64(&p.failed),
+		time.Since(p.start).Round(time.Second))
+	if p.stats != nil {
+		stats := p.stats()
+		summary += fmt.Sprintf(" (%s downloaded, %d disallowed by robots.txt, %d duplicates",
+			formatBytes(stats.BytesDownloaded), stats.SkippedByRobots, stats.Duplicates)
+		if len(stats.SkippedByLanguage) > 0 {
+			summary += ", skipped by language: " + formatCounts(stats.SkippedByLanguage)
+		}
+		if len(stats.SkippedByCountry) > 0 {
+			summary += ", skipped by country: " + formatCounts(stats.SkippedByCountry)
+		}
+		summary += ")"
+	}
+	return summary
+}
+
+// formatCounts renders counts by key, largest first, e.g. "de 12, fr 3"
+func formatCounts(counts map[string]int64) string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = fmt.Sprintf("%s %d", key, counts[key])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatBytes renders a byte count with a binary unit, e.g. 1.5 MiB
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// busiestDomain returns the host receiving the most requests per minute
+func (p *progress) busiestDomain() *crawler.DomainStats {
+	if p.domains == nil {
+		return nil
+	}
+
+	var busiest *crawler.DomainStats
+	domains := p.domains()
+	for i := range domains {
+		if busiest == nil || domains[i].PerMinute > busiest.PerMinute {
+			busiest = &domains[i]
+		}
+	}
+	return busiest
+}
+
+// DomainReport returns a line per host with its request counts and rates
+func (p *progress) DomainReport() string {
+	if p.domains == nil {
+		return ""
+	}
+
+	var report strings.Builder
+	for _, domain := range p.domains() {
+		fmt.Fprintf(&report, "  %s: %d requests, peak %d/min", domain.Host, domain.Requests, domain.PeakPerMinute)
+		if domain.CrawlDelay > 0 {
+			fmt.Fprintf(&report, " (Crawl-delay %s)", domain.CrawlDelay)
+		}
+		report.WriteString("\n")
+	}
+	return report.String()
+}
+
+// clear erases the progress line; the caller holds the lock
+func (p *progress) clear() {
+	if p.tty && p.drawn {
+		io.WriteString(p.out, "\r\033[K")
+	}
+}
+
+// draw renders the progress line; the caller holds the lock
+func (p *progress) draw() {
+	fetched := atomic.LoadInt64(&p.fetched)
+	elapsed := time.Since(p.start)
+	rate := float64(fetched) / elapsed.Seconds()
+
+	// Queued URLs waiting to be crawled plus pages waiting to be indexed
+	queued := int64(metrics.StageQueueDepth.Value(metrics.StageIndex))
+	var downloaded int64
+	if p.stats != nil {
+		stats := p.stats()
+		queued += int64(stats.Queued)
+		downloaded = stats.BytesDownloaded
+	} else {
+		queued += int64(metrics.StageQueueDepth.Value(metrics.StageCrawl))
+	}
+	eta := "--"
+	if rate > 0 && queued > 0 {
+		eta = "~" + (time.Duration(float64(queued)/rate) * time.Second).Round(time.Second).String()
+	}
+
+	line := fmt.Sprintf("fetched %d | indexed %d | failed %d | %.1f pages/s | queued %d | %s | ETA %s | %s elapsed",
+		fetched, atomic.LoadInt64(&p.indexed), atomic.LoadInt64(&p.failed),
+		rate, queued, formatBytes(downloaded), eta, elapsed.Round(time.Second))
+	if busiest := p.busiestDomain(); busiest != nil {
+		line += fmt.Sprintf(" | %s %d req/min", busiest.Host, busiest.PerMinute)
+	}
+
+	if p.tty {
+		p.clear()
+		io.WriteString(p.out, line)
+		p.drawn = true
+		return
+	}
+	fmt.Fprintln(p.out, line)
+}
+
+// newCrawlLogger creates the crawl logger writing through the progress
+// display, at a level chosen by --quiet and --verbose
+func newCrawlLogger(display *progress) *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(display)
+	logger.SetFormatter(&logrus.TextFormatter{
+		DisableTimestamp: true,
+	})
+
+	switch {
+	case crawlQuiet:
+		logger.SetLevel(logrus.ErrorLevel)
+	case crawlVerbose:
+		logger.SetLevel(logrus.DebugLevel)
+	default:
+		logger.SetLevel(logrus.WarnLevel)
+	}
+
+	return logger
+}
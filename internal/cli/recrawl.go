@@ -0,0 +1,264 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"ai-search/internal/config"
+	"ai-search/internal/crawler"
+	"ai-search/internal/embeddings"
+	"ai-search/internal/events"
+	"ai-search/internal/indexer"
+	"ai-search/internal/parser"
+	"ai-search/internal/scheduler"
+	"ai-search/internal/store"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	recrawlEvery time.Duration
+	recrawlLimit int
+)
+
+// recrawlCmd represents the recrawl command
+var recrawlCmd = &cobra.Command{
+	Use:   "recrawl",
+	Short: "Re-fetch stored documents whose recrawl interval has passed",
+	Long: `Re-fetch documents last crawled longer ago than their domain's recrawl
+interval (RECRAWL_INTERVAL, overridden per domain by RECRAWL_POLICIES) and
+reindex those whose content changed. Documents shown most in search results
+are recrawled first. With --every the recrawl repeats until interrupted.`,
+	RunE: runRecrawl,
+}
+
+func init() {
+	recrawlCmd.Flags().DurationVar(&recrawlEvery, "every", 0, "Time between recrawl passes, e.g. 1h (0 runs once)")
+	recrawlCmd.Flags().IntVar(&recrawlLimit, "limit", 0, "Documents recrawled per pass (defaults to RECRAWL_BATCH_SIZE)")
+}
+
+func runRecrawl(cmd *cobra.Command, args []string) error {
+	cfg := config.LoadConfig()
+
+	if cfg.EmbeddingAPIKey == "" && cfg.EmbeddingModel != embeddings.MockModel {
+		return fmt.Errorf("EMBEDDING_API_KEY environment variable is required for indexing")
+	}
+
+	policies, err := scheduler.ParsePolicies(cfg.RecrawlPolicies)
+	if err != nil {
+		return err
+	}
+
+	documentStore := newDocumentStore(cfg)
+	defer documentStore.Close()
+
+	detector := newDetector(cfg)
+	publisher, err := newPublisher(cfg)
+	if err != nil {
+		return err
+	}
+	defer publisher.Close()
+
+	titler, err := newPageTitler(cfg)
+	if err != nil {
+		return err
+	}
+
+	auth, err := loadCrawlAuth(cfg.CrawlAuthFile)
+	if err != nil {
+		return err
+	}
+	rules, err := loadExtractionRules(cfg.ExtractionRulesFile)
+	if err != nil {
+		return err
+	}
+
+	// Initialize the indexing pipeline
+	embeddingModels, err := embeddings.ParseModels(cfg.EmbeddingModels)
+	if err != nil {
+		return err
+	}
+	embedder := embeddings.NewEmbedder(embeddings.Config{
+		Model:   cfg.EmbeddingModel,
+		APIKey:  cfg.EmbeddingAPIKey,
+		BaseURL: cfg.EmbeddingBaseURL,
+		Models:  embeddingModels,
+	})
+	hybridIndexer := indexer.NewIndexer(indexer.Config{
+		Embedder:       embedder,
+		ChromaURL:      cfg.ChromaURL,
+		ElasticURL:     cfg.ElasticURL,
+		ElasticIndex:   cfg.ElasticIndex,
+		CollectionName: cfg.CollectionName,
+		Chunking:       chunkingConfig(cfg),
+		MaxRetries:     cfg.ElasticMaxRetries,
+		DeadLetters:    &storeDeadLetters{store: documentStore},
+
+		ContextualEnrichment: cfg.ContextualEnrichment,
+		DefaultVersion:       cfg.DefaultVersion,
+	})
+	defer hybridIndexer.Close()
+	textChunker, err := documentChunker(cfg, hybridIndexer)
+	if err != nil {
+		return err
+	}
+	chunkEmbedder, err := newChunkEmbedder(cfg, embedder, hybridIndexer)
+	if err != nil {
+		return err
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	shadow, err := newShadowIndex(cfg, documentStore, logger)
+	if err != nil {
+		return err
+	}
+	defer shadow.Close()
+
+	c := crawler.NewCrawler(crawler.Config{
+		RateLimit:     cfg.RateLimit,
+		MaxPageSize:   cfg.MaxPageSize,
+		UserAgent:     cfg.UserAgent,
+		Timeout:       cfg.Timeout,
+		RespectRobots: cfg.RespectRobots,
+		Proxies:       crawlProxies(cfg),
+		Auth:          auth,
+		Logger:        logger,
+
+		RespectNofollow: cfg.RespectNofollow,
+
+		AllowedLanguages: splitList(cfg.AllowedLanguages),
+		DetectLanguage:   cfg.DetectLanguage,
+		AllowedCountries: splitList(cfg.AllowedCountries),
+
+		WARCDir:         cfg.WARCDir,
+		WARCMaxFileSize: cfg.WARCMaxFileSize,
+
+		RenderJS:      cfg.RenderJS,
+		RenderTimeout: cfg.RenderTimeout,
+		RenderSettle:  cfg.RenderSettle,
+		BrowserPath:   cfg.BrowserPath,
+		Parser: parser.Config{
+			StreamingThreshold: cfg.StreamingThreshold,
+			MaxElementText:     cfg.MaxElementText,
+			MaxTextSize:        cfg.MaxTextSize,
+			Rules:              rules,
+			StripParams:        stripParams(cfg),
+			DisablePDF:         !cfg.ParsePDF,
+			DisableOffice:      !cfg.ParseOffice,
+		},
+		Transport: crawler.TransportConfig{
+			MaxIdleConns:        cfg.MaxIdleConns,
+			MaxIdleConnsPerHost: cfg.MaxIdleConnsPerHost,
+			IdleConnTimeout:     cfg.IdleConnTimeout,
+			TLSHandshakeTimeout: cfg.TLSHandshakeTimeout,
+
+			DisableHTTP2:       !cfg.HTTP2,
+			DisableKeepAlives:  !cfg.KeepAlives,
+			InsecureSkipVerify: cfg.InsecureSkipVerify,
+			DNSCacheTTL:        cfg.DNSCacheTTL,
+		},
+		RobotsStore:     &storeRobots{store: documentStore},
+		RobotsTTL:       time.Duration(cfg.RobotsCacheTTL) * time.Hour,
+		RobotsCacheSize: cfg.RobotsCacheSize,
+	})
+
+	// A changed page is indexed under its new content hash and replaces
+	// the document it was recrawled for
+	reindex := func(ctx context.Context, doc *store.RecrawlCandidate, page *crawler.Page) error {
+		old, err := documentStore.GetDocument(ctx, doc.DocumentID)
+		if err != nil {
+			return err
+		}
+		attributes := documentAttributes(old)
+
+		if err := titler.title(ctx, page); err != nil {
+			log.Printf("%v", err)
+		}
+
+		// Content that now duplicates another document still replaces this one
+		_, err = indexPage(ctx, page, attributes, documentStore, detector, textChunker, chunkEmbedder, hybridIndexer, shadow, publisher)
+		if err != nil && !errors.Is(err, errDuplicateContent) {
+			return err
+		}
+
+		newID := documentID(page.ContentHash, attributes)
+		if newID == old.ID {
+			return nil
+		}
+		if err == nil {
+			if err := documentStore.CarryProvenance(ctx, old.ID, newID); err != nil {
+				return err
+			}
+		}
+		if err := hybridIndexer.Delete(ctx, old.ID); err != nil {
+			return fmt.Errorf("failed to remove replaced document from index: %w", err)
+		}
+		shadow.delete(ctx, old.ID)
+		if err := documentStore.DeleteDocument(ctx, old.ID); err != nil {
+			return err
+		}
+		publisher.Publish(ctx, events.DocumentDeleted, old.ID)
+		return nil
+	}
+
+	batchSize := recrawlLimit
+	if batchSize <= 0 {
+		batchSize = cfg.RecrawlBatchSize
+	}
+
+	recrawler := scheduler.NewScheduler(scheduler.Config{
+		Store:           documentStore,
+		Crawler:         c,
+		Reindex:         reindex,
+		DefaultInterval: time.Duration(cfg.RecrawlInterval) * time.Hour,
+		Policies:        policies,
+		BatchSize:       batchSize,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if recrawlEvery == 0 {
+		result, err := recrawler.RecrawlDue(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Recrawled %d of %d due documents: %d updated, %d unchanged, %d failed\n",
+			result.Checked, result.Due, result.Updated, result.Unchanged, result.Failed)
+		return nil
+	}
+
+	fmt.Printf("Recrawling due documents every %s. Press Ctrl+C to stop.\n", recrawlEvery)
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-quit
+		fmt.Println("\nStopping scheduled recrawls")
+		cancel()
+	}()
+
+	recrawler.Run(ctx, recrawlEvery)
+	return nil
+}
+
+// documentAttributes returns the custom attributes stored with a document
+func documentAttributes(doc *store.Document) map[string]string {
+	attributes := make(map[string]string)
+	stored, _ := doc.Meta["attributes"].(map[string]interface{})
+	for key, value := range stored {
+		if s, ok := value.(string); ok {
+			attributes[key] = s
+		}
+	}
+	return attributes
+}
@@ -0,0 +1,192 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"ai-search/internal/config"
+	"ai-search/internal/events"
+	"ai-search/internal/indexer"
+	"ai-search/internal/metrics"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	replicateSeed        bool
+	replicateMetricsAddr string
+)
+
+// replicateCmd represents the replicate command
+var replicateCmd = &cobra.Command{
+	Use:   "replicate",
+	Short: "Mirror index mutations to a warm-standby ChromaDB and Elasticsearch",
+	Long: `Keep a standby ChromaDB and Elasticsearch pair (REPLICA_CHROMA_URL and
+REPLICA_ELASTIC_URL) in step with the primary so a failover needs no rebuild
+from PostgreSQL. Every process publishing index mutations appends them to the
+OUTBOX_STREAM Redis stream; this command reads it as the REPLICA_NAME consumer
+group and copies each mutated document's chunks, embeddings included, from
+the primary indexes to the standby. It resumes where it stopped and retries
+events that fail, up to REPLICA_ATTEMPTS times; the documents of an event
+that still fails are dead-lettered and mirrored again the next time the
+command starts.
+
+Run it once with --seed to copy every stored document before following the
+stream.`,
+	RunE: runReplicate,
+}
+
+func init() {
+	replicateCmd.Flags().BoolVar(&replicateSeed, "seed", false, "Copy every stored document to the standby before following the stream")
+	replicateCmd.Flags().StringVar(&replicateMetricsAddr, "metrics-addr", "", "Address to serve Prometheus metrics on (e.g. localhost:9092)")
+}
+
+func runReplicate(cmd *cobra.Command, args []string) error {
+	cfg := config.LoadConfig()
+	if cfg.OutboxStream == "" {
+		return fmt.Errorf("OUTBOX_STREAM environment variable is required for replication")
+	}
+	if cfg.ReplicaChromaURL == "" || cfg.ReplicaElasticURL == "" {
+		return fmt.Errorf("REPLICA_CHROMA_URL and REPLICA_ELASTIC_URL environment variables are required for replication")
+	}
+
+	primary := indexer.NewIndexer(indexer.Config{
+		ChromaURL:      cfg.ChromaURL,
+		ElasticURL:     cfg.ElasticURL,
+		ElasticIndex:   cfg.ElasticIndex,
+		CollectionName: cfg.CollectionName,
+		Chunking:       chunkingConfig(cfg),
+		MaxRetries:     cfg.ElasticMaxRetries,
+
+		ContextualEnrichment: cfg.ContextualEnrichment,
+		DefaultVersion:       cfg.DefaultVersion,
+	})
+	defer primary.Close()
+
+	// The standby collection takes the primary's settings as they are
+	replica := indexer.NewIndexer(indexer.Config{
+		ChromaURL:        cfg.ReplicaChromaURL,
+		ElasticURL:       cfg.ReplicaElasticURL,
+		ElasticIndex:     cfg.ElasticIndex,
+		CollectionName:   cfg.CollectionName,
+		Chunking:         primary.Chunking(),
+		OverrideChunking: true,
+		MaxRetries:       cfg.ElasticMaxRetries,
+
+		ContextualEnrichment: primary.ContextualEnrichment(),
+		OverrideEnrichment:   true,
+
+		DefaultVersion:         primary.DefaultVersion(),
+		OverrideDefaultVersion: true,
+	})
+	defer replica.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-quit
+		fmt.Println("\nStopping replication...")
+		cancel()
+	}()
+
+	if replicateMetricsAddr != "" {
+		go func() {
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", metrics.Handler())
+			if err := http.ListenAndServe(replicateMetricsAddr, mux); err != nil {
+				fmt.Fprintf(os.Stderr, "Metrics server error: %v\n", err)
+			}
+		}()
+	}
+
+	// mirror copies a document's indexed chunks to the standby, removing
+	// them there when the primary no longer has them
+	mirror := func(ctx context.Context, documentID string) error {
+		doc, err := primary.Export(ctx, documentID)
+		if err == nil {
+			err = replica.Import(ctx, doc)
+		}
+		if err != nil {
+			metrics.ReplicatedDocuments.Inc("error")
+			return fmt.Errorf("failed to replicate %s: %w", documentID, err)
+		}
+		metrics.ReplicatedDocuments.Inc("success")
+		return nil
+	}
+
+	documentStore := newDocumentStore(cfg)
+	defer documentStore.Close()
+
+	if replicateSeed {
+		docs, err := documentStore.ListDocuments(ctx)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Seeding %d documents\n", len(docs))
+		for j, doc := range docs {
+			if err := mirror(ctx, doc.ID); err != nil {
+				return err
+			}
+			if (j+1)%100 == 0 {
+				fmt.Printf("Seeded %d of %d documents\n", j+1, len(docs))
+			}
+		}
+	}
+
+	// Documents given up on by an earlier run are mirrored again first
+	items, err := documentStore.ListDeadLetters(ctx, stageReplication)
+	if err != nil {
+		return err
+	}
+	if len(items) > 0 {
+		fmt.Printf("Retrying %d documents that failed to replicate\n", len(items))
+	}
+	for _, item := range items {
+		if err := mirror(ctx, item.DocumentID); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			if err := documentStore.UpdateDeadLetter(ctx, item.ID, err.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := documentStore.DeleteDeadLetter(ctx, item.ID); err != nil {
+			return err
+		}
+	}
+
+	consumer, err := events.NewConsumer(events.ConsumerConfig{
+		RedisURL:    cfg.RedisURL,
+		Stream:      cfg.OutboxStream,
+		Group:       cfg.ReplicaName,
+		MaxAttempts: cfg.ReplicaAttempts,
+		DeadLetters: &storeEventDeadLetters{store: documentStore},
+	})
+	if err != nil {
+		return err
+	}
+	defer consumer.Close()
+
+	fmt.Printf("Replicating %s to %s and %s. Press Ctrl+C to stop.\n",
+		cfg.OutboxStream, cfg.ReplicaChromaURL, cfg.ReplicaElasticURL)
+
+	// Every event type is handled alike: whatever happened to a document,
+	// the standby is made to hold what the primary holds now
+	return consumer.Consume(ctx, func(ctx context.Context, event events.Event) error {
+		for _, documentID := range event.DocumentIDs {
+			if err := mirror(ctx, documentID); err != nil {
+				return err
+			}
+		}
+		metrics.ReplicationLag.Set(time.Since(event.Time).Seconds())
+		return nil
+	})
+}
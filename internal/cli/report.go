@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"ai-search/internal/crawler"
+)
+
+// Crawl statuses of the URLs in a crawl report
+const (
+	reportFetched = "fetched"
+	reportFailed  = "failed"
+	reportSkipped = "skipped"
+)
+
+// Indexing outcomes of the URLs in a crawl report
+const (
+	outcomeIndexed     = "indexed"
+	outcomeDuplicate   = "duplicate"    // Content already indexed under another URL
+	outcomeNoChunks    = "no_chunks"    // Too little text to chunk
+	outcomeIndexFailed = "index_failed" // Saving, embedding or indexing failed
+	outcomeNotIndexed  = "not_indexed"  // Never reached indexing
+)
+
+// reportEntry is the outcome of one URL of a crawl
+type reportEntry struct {
+	URL         string `json:"url"`
+	Status      string `json:"status"`
+	HTTPStatus  int    `json:"http_status,omitempty"`
+	Reason      string `json:"reason,omitempty"` // Skip reason or fetch error
+	Depth       *int   `json:"depth,omitempty"`  // Known for fetched pages
+	ContentHash string `json:"content_hash,omitempty"`
+	Chunks      int    `json:"chunks"`
+	Outcome     string `json:"outcome"`
+	Error       string `json:"error,omitempty"` // Indexing error
+}
+
+// crawlReport collects the outcome of every URL a crawl reaches, for
+// --report to write as JSON. It observes the crawl as crawler.Hooks.
+type crawlReport struct {
+	crawler.NopHooks
+
+	startURL  string
+	startedAt time.Time
+
+	mutex      sync.Mutex
+	entries    []*reportEntry
+	byURL      map[string]*reportEntry
+	httpStatus map[string]int
+}
+
+// newCrawlReport creates an empty report of a crawl from startURL
+func newCrawlReport(startURL string) *crawlReport {
+	return &crawlReport{
+		startURL:   startURL,
+		startedAt:  time.Now(),
+		entries:    []*reportEntry{},
+		byURL:      make(map[string]*reportEntry),
+		httpStatus: make(map[string]int),
+	}
+}
+
+// entry returns the entry of a URL, adding it on first sight; the caller
+// holds the lock
+func (r *crawlReport) entry(pageURL string) *reportEntry {
+	if entry, exists := r.byURL[pageURL]; exists {
+		return entry
+	}
+	entry := &reportEntry{URL: pageURL, Outcome: outcomeNotIndexed}
+	if status, fetched := r.httpStatus[pageURL]; fetched {
+		entry.HTTPStatus = status
+	}
+	r.entries = append(r.entries, entry)
+	r.byURL[pageURL] = entry
+	return entry
+}
+
+// OnFetch notes the HTTP status of every response
+func (r *crawlReport) OnFetch(ctx context.Context, pageURL *url.URL, resp *http.Response) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.httpStatus[pageURL.String()] = resp.StatusCode
+	return nil
+}
+
+// OnError records a URL that could not be fetched or parsed
+func (r *crawlReport) OnError(ctx context.Context, pageURL *url.URL, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	entry := r.entry(pageURL.String())
+	entry.Status, entry.Reason = reportFailed, err.Error()
+}
+
+// OnSkip records a URL that was not crawled
+func (r *crawlReport) OnSkip(ctx context.Context, pageURL *url.URL, reason string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	entry := r.entry(pageURL.String())
+	entry.Status, entry.Reason = reportSkipped, reason
+}
+
+// indexed records how indexing a fetched page went
+func (r *crawlReport) indexed(page *crawler.Page, chunks int, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// Only pages served with 200 OK are parsed, after any redirects
+	entry := r.entry(page.URL.String())
+	depth := page.Depth
+	entry.Status, entry.Reason = reportFetched, ""
+	entry.HTTPStatus = http.StatusOK
+	entry.Depth = &depth
+	entry.ContentHash = page.ContentHash
+	entry.Chunks = chunks
+	entry.Outcome = indexOutcome(chunks, err)
+	if entry.Outcome == outcomeIndexFailed {
+		entry.Error = err.Error()
+	}
+}
+
+// indexOutcome names how indexing a page went for the crawl report
+func indexOutcome(chunks int, err error) string {
+	switch {
+	case errors.Is(err, errDuplicateContent):
+		return outcomeDuplicate
+	case err != nil:
+		return outcomeIndexFailed
+	case chunks == 0:
+		return outcomeNoChunks
+	default:
+		return outcomeIndexed
+	}
+}
+
+// write saves the report as indented JSON
+func (r *crawlReport) write(path string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	outcomes := make(map[string]int)
+	for _, entry := range r.entries {
+		outcomes[entry.Outcome]++
+	}
+	report := struct {
+		StartURL   string         `json:"start_url"`
+		StartedAt  time.Time      `json:"started_at"`
+		FinishedAt time.Time      `json:"finished_at"`
+		Outcomes   map[string]int `json:"outcomes"`
+		URLs       []*reportEntry `json:"urls"`
+	}{
+		StartURL:   r.startURL,
+		StartedAt:  r.startedAt,
+		FinishedAt: time.Now(),
+		Outcomes:   outcomes,
+		URLs:       r.entries,
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal crawl report: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write crawl report: %w", err)
+	}
+	return nil
+}
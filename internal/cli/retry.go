@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"ai-search/internal/config"
+	"ai-search/internal/embeddings"
+	"ai-search/internal/events"
+	"ai-search/internal/indexer"
+	"ai-search/internal/store"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	retryStage string
+	retryList  bool
+)
+
+// retryFailedCmd represents the retry-failed command
+var retryFailedCmd = &cobra.Command{
+	Use:   "retry-failed",
+	Short: "Reprocess pages and chunks that failed to index",
+	Long: `Reprocess the dead letters recorded when pages failed to embed or index, or
+chunks were rejected by Elasticsearch after all retries. Run it once the
+underlying issue is fixed. Items that succeed are removed; items that fail
+again are kept with the new error. Documents deleted in the meantime are
+skipped.`,
+	RunE: runRetryFailed,
+}
+
+func init() {
+	retryFailedCmd.Flags().StringVar(&retryStage, "stage", "", "Only reprocess items of this stage (embedding, index or elasticsearch)")
+	retryFailedCmd.Flags().BoolVar(&retryList, "list", false, "List the failed items without reprocessing them")
+}
+
+func runRetryFailed(cmd *cobra.Command, args []string) error {
+	cfg := config.LoadConfig()
+
+	documentStore := newDocumentStore(cfg)
+	defer documentStore.Close()
+
+	ctx := context.Background()
+	items, err := documentStore.ListDeadLetters(ctx, retryStage)
+	if err != nil {
+		return err
+	}
+
+	if retryList {
+		for _, item := range items {
+			id := item.DocumentID
+			if item.ChunkID != "" {
+				id = item.ChunkID
+			}
+			fmt.Printf("%d\t%s\t%s\t%d attempts\t%s\n", item.ID, item.Stage, id, item.Attempts, item.Error)
+		}
+		fmt.Printf("%d failed items\n", len(items))
+		return nil
+	}
+	if len(items) == 0 {
+		fmt.Println("No failed items to reprocess")
+		return nil
+	}
+
+	if cfg.EmbeddingAPIKey == "" && cfg.EmbeddingModel != embeddings.MockModel {
+		return fmt.Errorf("EMBEDDING_API_KEY environment variable is required for indexing")
+	}
+
+	// Initialize the indexing pipeline
+	embeddingModels, err := embeddings.ParseModels(cfg.EmbeddingModels)
+	if err != nil {
+		return err
+	}
+	embedder := embeddings.NewEmbedder(embeddings.Config{
+		Model:   cfg.EmbeddingModel,
+		APIKey:  cfg.EmbeddingAPIKey,
+		BaseURL: cfg.EmbeddingBaseURL,
+		Models:  embeddingModels,
+	})
+	hybridIndexer := indexer.NewIndexer(indexer.Config{
+		Embedder:       embedder,
+		ChromaURL:      cfg.ChromaURL,
+		ElasticURL:     cfg.ElasticURL,
+		ElasticIndex:   cfg.ElasticIndex,
+		CollectionName: cfg.CollectionName,
+		Chunking:       chunkingConfig(cfg),
+		MaxRetries:     cfg.ElasticMaxRetries,
+		DeadLetters:    &storeDeadLetters{store: documentStore},
+
+		ContextualEnrichment: cfg.ContextualEnrichment,
+		DefaultVersion:       cfg.DefaultVersion,
+	})
+	defer hybridIndexer.Close()
+	textChunker, err := documentChunker(cfg, hybridIndexer)
+	if err != nil {
+		return err
+	}
+	chunkEmbedder, err := newChunkEmbedder(cfg, embedder, hybridIndexer)
+	if err != nil {
+		return err
+	}
+
+	publisher, err := newPublisher(cfg)
+	if err != nil {
+		return err
+	}
+	defer publisher.Close()
+
+	// reprocess indexes one dead letter again. It returns false for items
+	// of documents deleted since they failed, and for replication items,
+	// which the replicate command mirrors again when it starts.
+	reprocess := func(item *store.DeadLetter) (bool, error) {
+		if item.Stage == stageReplication {
+			return false, nil
+		}
+
+		doc, err := documentStore.GetDocument(ctx, item.DocumentID)
+		if err != nil {
+			return false, err
+		}
+		if doc.DeletedAt != nil {
+			return false, nil
+		}
+
+		switch item.Stage {
+		case indexer.StageElasticsearch:
+			return true, hybridIndexer.RetryChunk(ctx, &indexer.FailedChunk{
+				Stage:      item.Stage,
+				DocumentID: item.DocumentID,
+				ChunkID:    item.ChunkID,
+				Payload:    item.Payload,
+				Error:      item.Error,
+				Attempts:   item.Attempts,
+			})
+
+		case stageEmbedding, stageIndex:
+			var indexDoc indexer.Document
+			if err := json.Unmarshal(item.Payload, &indexDoc); err != nil {
+				return true, fmt.Errorf("failed to unmarshal document: %w", err)
+			}
+			chunkCount, err := indexDocument(ctx, &indexDoc, documentStore, textChunker, chunkEmbedder, hybridIndexer)
+			// Chunks the indexer dead-lettered are now retried on their own
+			if err != nil && !errors.Is(err, indexer.ErrDeadLettered) {
+				return true, err
+			}
+			if chunkCount > 0 {
+				publisher.Publish(ctx, events.DocumentIndexed, indexDoc.ID)
+			}
+			return true, nil
+
+		default:
+			return true, fmt.Errorf("unknown stage %q", item.Stage)
+		}
+	}
+
+	var succeeded, skipped, failed int
+	for _, item := range items {
+		processed, err := reprocess(item)
+		if err != nil {
+			failed++
+			fmt.Printf("Failed to reprocess %s item %d: %v\n", item.Stage, item.ID, err)
+			if err := documentStore.UpdateDeadLetter(ctx, item.ID, err.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+		if !processed {
+			skipped++
+			continue
+		}
+
+		succeeded++
+		if err := documentStore.DeleteDeadLetter(ctx, item.ID); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Reprocessed %d failed items: %d succeeded, %d failed, %d skipped\n",
+		len(items), succeeded, failed, skipped)
+	return nil
+}
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"ai-search/internal/crawler"
+	"ai-search/internal/store"
+)
+
+// storeRobots adapts the document store to the crawler.RobotsStore interface
+type storeRobots struct {
+	store store.Store
+}
+
+// Get returns the unexpired rules stored for a host
+func (r *storeRobots) Get(ctx context.Context, host string) (*crawler.Robots, bool, error) {
+	data, err := r.store.GetRobotsRules(ctx, host)
+	if err != nil || data == nil {
+		return nil, false, err
+	}
+
+	var robots crawler.Robots
+	if err := json.Unmarshal(data, &robots); err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal robots.txt rules: %w", err)
+	}
+	return &robots, true, nil
+}
+
+// Set stores the rules for a host until they expire
+func (r *storeRobots) Set(ctx context.Context, host string, robots *crawler.Robots, ttl time.Duration) error {
+	data, err := json.Marshal(robots)
+	if err != nil {
+		return fmt.Errorf("failed to marshal robots.txt rules: %w", err)
+	}
+	return r.store.SaveRobotsRules(ctx, host, data, ttl)
+}
+
+// Close is a no-op; the document store is closed by its owner
+func (r *storeRobots) Close() error {
+	return nil
+}
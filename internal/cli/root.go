@@ -23,5 +23,15 @@ func Execute() error {
 func init() {
 	// Add subcommands here
 	rootCmd.AddCommand(crawlCmd)
+	rootCmd.AddCommand(recrawlCmd)
 	rootCmd.AddCommand(serverCmd)
+	rootCmd.AddCommand(snapshotCmd)
+	rootCmd.AddCommand(restoreCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(deleteCmd)
+	rootCmd.AddCommand(purgeCmd)
+	rootCmd.AddCommand(retryFailedCmd)
+	rootCmd.AddCommand(evalCmd)
+	rootCmd.AddCommand(replicateCmd)
+	rootCmd.AddCommand(versionCmd)
 }
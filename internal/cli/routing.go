@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"ai-search/internal/config"
+	"ai-search/internal/embeddings"
+	"ai-search/internal/indexer"
+	"ai-search/internal/llm"
+	"ai-search/internal/retriever"
+	"ai-search/internal/store"
+
+	"gopkg.in/yaml.v3"
+)
+
+// routedCollection is an entry of the routing file, a collection queries
+// can be routed to
+type routedCollection struct {
+	Label        string   `yaml:"label"`
+	Collection   string   `yaml:"collection"`
+	ElasticIndex string   `yaml:"elastic_index,omitempty"` // Defaults to the collection name lower-cased
+	Description  string   `yaml:"description,omitempty"`
+	Keywords     []string `yaml:"keywords,omitempty"`
+	Default      bool     `yaml:"default,omitempty"`
+
+	// SourceWeights replace SOURCE_WEIGHTS for the collection's results
+	SourceWeights map[string]float64 `yaml:"source_weights,omitempty"`
+}
+
+// loadRoutingFile reads the collections queries are routed between from a
+// YAML file
+func loadRoutingFile(path string) ([]routedCollection, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read routing file: %w", err)
+	}
+	var collections []routedCollection
+	if err := yaml.Unmarshal(data, &collections); err != nil {
+		return nil, fmt.Errorf("invalid routing file %s: %w", path, err)
+	}
+
+	labels := make(map[string]bool)
+	for i, collection := range collections {
+		if collection.Label == "" || collection.Collection == "" {
+			return nil, fmt.Errorf("invalid routing file %s: entry %d needs a label and a collection", path, i+1)
+		}
+		if labels[collection.Label] {
+			return nil, fmt.Errorf("invalid routing file %s: label %s is used twice", path, collection.Label)
+		}
+		labels[collection.Label] = true
+		for sourceType, weight := range collection.SourceWeights {
+			if err := retriever.ValidateSourceWeight(sourceType, weight); err != nil {
+				return nil, fmt.Errorf("invalid routing file %s: entry %d: %w", path, i+1, err)
+			}
+		}
+	}
+	if len(collections) == 0 {
+		return nil, fmt.Errorf("invalid routing file %s: no collections", path)
+	}
+	return collections, nil
+}
+
+// newRouter builds a retriever routing queries between the collections of
+// ROUTING_FILE, each searched like the main collection. The main
+// collection's indexer is reused when the file names it. The returned
+// function closes the other indexers.
+func newRouter(cfg *config.Config, mainIndexer indexer.Indexer, embedder embeddings.Embedder, documentStore store.Store, base retriever.Config, llmClient llm.LLM) (retriever.Retriever, func(), error) {
+	collections, err := loadRoutingFile(cfg.RoutingFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var classifier retriever.QueryClassifier
+	switch cfg.RoutingClassifier {
+	case "", "keywords":
+		classifier = retriever.KeywordClassifier{}
+	case "llm":
+		classifier = &llmClassifier{llm: llmClient}
+	default:
+		return nil, nil, fmt.Errorf("unknown routing classifier: %s", cfg.RoutingClassifier)
+	}
+
+	var opened []indexer.Indexer
+	closeAll := func() {
+		for _, collectionIndexer := range opened {
+			collectionIndexer.Close()
+		}
+	}
+
+	var sources []retriever.Source
+	for _, collection := range collections {
+		collectionIndexer := mainIndexer
+		if collection.Collection != cfg.CollectionName {
+			elasticIndex := collection.ElasticIndex
+			if elasticIndex == "" {
+				elasticIndex = strings.ToLower(collection.Collection)
+			}
+			collectionIndexer = indexer.NewIndexer(indexer.Config{
+				Embedder:       embedder,
+				ChromaURL:      cfg.ChromaURL,
+				ElasticURL:     cfg.ElasticURL,
+				ElasticIndex:   elasticIndex,
+				CollectionName: collection.Collection,
+				Chunking:       chunkingConfig(cfg),
+
+				ContextualEnrichment: cfg.ContextualEnrichment,
+				DefaultVersion:       cfg.DefaultVersion,
+
+				DescriptionBoost: cfg.DescriptionBoost,
+				KeywordsBoost:    cfg.KeywordsBoost,
+
+				MaxRetries:  cfg.ElasticMaxRetries,
+				DeadLetters: &storeDeadLetters{store: documentStore},
+			})
+			opened = append(opened, collectionIndexer)
+		}
+
+		sourceConfig := base
+		sourceConfig.Indexer = collectionIndexer
+		if collection.SourceWeights != nil {
+			sourceConfig.SourceWeights = collection.SourceWeights
+		}
+		sources = append(sources, retriever.Source{
+			Label:       collection.Label,
+			Description: collection.Description,
+			Keywords:    collection.Keywords,
+			Default:     collection.Default,
+			Retriever:   retriever.NewHybridRetriever(sourceConfig),
+		})
+	}
+
+	router := retriever.NewRouter(retriever.RouterConfig{
+		Sources:    sources,
+		Classifier: classifier,
+	})
+	return router, closeAll, nil
+}
+
+// llmClassifier implements the retriever.QueryClassifier interface by
+// asking the LLM which source descriptions fit a query
+type llmClassifier struct {
+	llm llm.LLM
+}
+
+// Classify picks the sources of a query with the LLM
+func (c *llmClassifier) Classify(ctx context.Context, query string, sources []retriever.Source) ([]string, error) {
+	categories := make(map[string]string, len(sources))
+	for _, source := range sources {
+		description := source.Description
+		if description == "" {
+			description = strings.Join(source.Keywords, ", ")
+		}
+		categories[source.Label] = description
+	}
+	return c.llm.Classify(ctx, query, categories)
+}
@@ -3,15 +3,18 @@ package cli
 import (
 	"context"
 	"fmt"
-	"os"
-	"os/signal"
-	"syscall"
+	"net/url"
+	"strings"
+	"time"
 
-	"ai-search/internal/chunker"
+	"ai-search/internal/alerts"
 	"ai-search/internal/config"
+	"ai-search/internal/dedup"
 	"ai-search/internal/embeddings"
 	"ai-search/internal/indexer"
 	"ai-search/internal/llm"
+	"ai-search/internal/parser"
+	"ai-search/internal/retention"
 	"ai-search/internal/retriever"
 	"ai-search/internal/server"
 	"ai-search/internal/store"
@@ -37,10 +40,10 @@ func runServer(cmd *cobra.Command, args []string) error {
 	cfg := config.LoadConfig()
 
 	// Validate required configuration
-	if cfg.LLMAPIKey == "" {
+	if cfg.LLMAPIKey == "" && cfg.LLMProvider != llm.MockProvider {
 		return fmt.Errorf("LLM_API_KEY environment variable is required")
 	}
-	if cfg.EmbeddingAPIKey == "" {
+	if cfg.EmbeddingAPIKey == "" && cfg.EmbeddingModel != embeddings.MockModel {
 		return fmt.Errorf("EMBEDDING_API_KEY environment variable is required")
 	}
 
@@ -68,31 +71,38 @@ func runServer(cmd *cobra.Command, args []string) error {
 	documentStore := store.NewStore(storeConfig)
 	defer documentStore.Close()
 
-	// Initialize chunker
-	chunkerConfig := chunker.Config{
-		ChunkSize:    cfg.ChunkSize,
-		OverlapSize:  cfg.OverlapSize,
-		MinChunkSize: cfg.MinChunkSize,
-	}
-	textChunker := chunker.NewTextChunker(chunkerConfig)
-
 	// Initialize embedder
+	embeddingModels, err := embeddings.ParseModels(cfg.EmbeddingModels)
+	if err != nil {
+		return err
+	}
 	embedderConfig := embeddings.Config{
 		Model:     cfg.EmbeddingModel,
 		APIKey:    cfg.EmbeddingAPIKey,
 		BaseURL:   cfg.EmbeddingBaseURL,
 		BatchSize: 10,
 		Timeout:   30,
+		Models:    embeddingModels,
 	}
 	embedder := embeddings.NewEmbedder(embedderConfig)
 
 	// Initialize indexer
 	indexerConfig := indexer.Config{
 		Embedder:       embedder,
-		Chunker:        textChunker,
 		ChromaURL:      cfg.ChromaURL,
 		ElasticURL:     cfg.ElasticURL,
+		ElasticIndex:   cfg.ElasticIndex,
 		CollectionName: cfg.CollectionName,
+		Chunking:       chunkingConfig(cfg),
+
+		ContextualEnrichment: cfg.ContextualEnrichment,
+		DefaultVersion:       cfg.DefaultVersion,
+
+		DescriptionBoost: cfg.DescriptionBoost,
+		KeywordsBoost:    cfg.KeywordsBoost,
+
+		MaxRetries:  cfg.ElasticMaxRetries,
+		DeadLetters: &storeDeadLetters{store: documentStore},
 	}
 	hybridIndexer := indexer.NewIndexer(indexerConfig)
 	defer hybridIndexer.Close()
@@ -107,9 +117,30 @@ func runServer(cmd *cobra.Command, args []string) error {
 	}
 	llmClient := llm.NewLLM(llmConfig)
 
-	// Initialize retriever
+	// Initialize retriever. Reranked orders are cached across every
+	// retriever, as candidates identify a collection's results.
+	sourceWeights, err := retriever.ParseSourceWeights(cfg.SourceWeights)
+	if err != nil {
+		return fmt.Errorf("invalid SOURCE_WEIGHTS: %w", err)
+	}
 	retrieverConfig := retriever.Config{
-		Indexer: hybridIndexer,
+		Indexer:       hybridIndexer,
+		SourceWeights: sourceWeights,
+	}
+	if cfg.EnableReranking {
+		retrieverConfig.RerankCache = retriever.NewRerankCache(retriever.RerankCacheConfig{
+			TTL:        time.Duration(cfg.RerankCacheTTL) * time.Second,
+			MaxEntries: cfg.RerankCacheSize,
+		})
+	}
+	if cfg.CrossLingual {
+		for _, language := range strings.Split(cfg.QueryTranslationLanguages, ",") {
+			if language = parser.NormalizeLanguage(language); language != "" {
+				retrieverConfig.TranslateTo = append(retrieverConfig.TranslateTo, language)
+			}
+		}
+		retrieverConfig.Translator = llmClient
+		fmt.Printf("Cross-lingual retrieval enabled (%s)\n", strings.Join(retrieverConfig.TranslateTo, ", "))
 	}
 	hybridRetriever := retriever.NewHybridRetriever(retrieverConfig)
 
@@ -121,38 +152,166 @@ func runServer(cmd *cobra.Command, args []string) error {
 		fmt.Printf("LLM reranking disabled\n")
 	}
 
-	// Initialize server
-	serverConfig := server.Config{
-		Host:      cfg.ServerHost,
-		Port:      cfg.ServerPort,
-		Retriever: hybridRetriever,
+	// Queries can be routed between several collections, whose results
+	// are merged and labelled with their source
+	var searchRetriever retriever.Retriever = hybridRetriever
+	if cfg.RoutingFile != "" {
+		router, closeRouted, err := newRouter(cfg, hybridIndexer, embedder, documentStore, retrieverConfig, llmClient)
+		if err != nil {
+			return err
+		}
+		defer closeRouted()
+		if cfg.EnableReranking {
+			router.SetReranker(&llmReranker{llm: llmClient})
+		}
+		searchRetriever = router
+		fmt.Printf("Routing queries between the collections of %s\n", cfg.RoutingFile)
 	}
-	httpServer := server.NewServer(serverConfig)
 
-	// Start server
-	fmt.Printf("\nServer starting on http://%s:%d\n", cfg.ServerHost, cfg.ServerPort)
-	fmt.Println("Press Ctrl+C to stop the server")
+	// Searches can also be fanned out to other ai-search deployments
+	localRetriever := searchRetriever
+	if cfg.FederatedInstances != "" {
+		instances, err := parseFederatedInstances(cfg.FederatedInstances, time.Duration(cfg.FederationTimeout)*time.Second, cfg.FederationAPIKey)
+		if err != nil {
+			return err
+		}
+		instances = append([]retriever.Instance{{Name: cfg.InstanceName, Retriever: localRetriever}}, instances...)
+		searchRetriever = retriever.NewFederation(retriever.FederationConfig{Instances: instances})
+		fmt.Printf("Federating searches across %d instances\n", len(instances))
+	}
+
+	// Clients of a metered API authenticate with keys
+	var apiKeys []server.APIKey
+	if cfg.APIKeysFile != "" {
+		apiKeys, err = loadAPIKeysFile(cfg.APIKeysFile)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Requiring one of %d API keys\n", len(apiKeys))
+	}
+
+	// Documents with an ACL are only searchable by the callers it grants
+	aclConfig, err := newACLConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	// The shadow collection is searched the same way for comparisons
+	var shadowRetriever retriever.Retriever
+	shadowIndexer, _, err := newShadowIndexer(cfg, documentStore)
+	if err != nil {
+		return err
+	}
+	if shadowIndexer != nil {
+		defer shadowIndexer.Close()
+		shadowConfig := retrieverConfig
+		shadowConfig.Indexer = shadowIndexer
+		shadowRetriever = retriever.NewHybridRetriever(shadowConfig)
+		if cfg.EnableReranking {
+			shadowRetriever.SetReranker(&llmReranker{llm: llmClient})
+		}
+		fmt.Printf("Shadow collection %s available at /api/admin/compare\n", cfg.ShadowCollection)
+	}
 
-	// Handle graceful shutdown
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
+	// Initialize duplicate detector
+	detector := newDetector(cfg)
 
-	// Start server in goroutine
+	// Initialize index mutation events for cache invalidation
+	publisher, err := newPublisher(cfg)
+	if err != nil {
+		return err
+	}
+	defer publisher.Close()
+
+	// Initialize purger for soft-deleted documents
+	purger := retention.NewPurger(retention.Config{
+		Store:   documentStore,
+		Indexer: hybridIndexer,
+		Window:  time.Duration(cfg.DeleteRetention) * time.Hour,
+		Events:  publisher,
+	})
+
+	// Searches skip soft-deleted documents by their mark in the index, so
+	// documents deleted before the index carried it are marked now
 	go func() {
-		if err := httpServer.Start(ctx); err != nil {
-			fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
-			os.Exit(1)
+		marked, err := retention.MarkDeleted(context.Background(), documentStore, hybridIndexer)
+		if err != nil {
+			fmt.Printf("Failed to mark deleted documents in the index: %v\n", err)
+		} else if marked > 0 {
+			fmt.Printf("Marked %d deleted documents in the index\n", marked)
 		}
 	}()
 
-	// Wait for interrupt signal
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	// Saved searches are alerted of the documents crawls index, which
+	// reach this process through INVALIDATION_CHANNEL
+	alerter := alerts.NewAlerter(alerts.Config{
+		Store:     documentStore,
+		Retriever: localRetriever,
+		SMTP: alerts.SMTPConfig{
+			Addr:     cfg.SMTPAddr,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+		},
+	})
+
+	// Initialize server
+	serverConfig := server.Config{
+		Host:       cfg.ServerHost,
+		Port:       cfg.ServerPort,
+		Retriever:  searchRetriever,
+		Store:      documentStore,
+		Indexer:    hybridIndexer,
+		Dedup:      detector,
+		LLM:        llmClient,
+		AdminToken: cfg.AdminToken,
+		Purger:     purger,
+		Events:     publisher,
+
+		Alerts:        alerter,
+		AlertInterval: time.Duration(cfg.AlertInterval) * time.Second,
 
-	fmt.Println("\nShutting down server...")
-	cancel()
+		CacheTTL:        time.Duration(cfg.CacheTTL) * time.Second,
+		CacheMaxEntries: cfg.CacheMaxEntries,
 
+		DefaultLanguage: parser.NormalizeLanguage(cfg.DefaultLanguage),
+
+		Snippets:      cfg.Snippets,
+		SnippetLength: cfg.SnippetLength,
+
+		LogQueries: cfg.LogQueries,
+
+		DrainDelay:    time.Duration(cfg.DrainDelay) * time.Second,
+		ShutdownGrace: time.Duration(cfg.ShutdownGrace) * time.Second,
+
+		DebugAddr: cfg.DebugAddr,
+
+		ShadowRetriever: shadowRetriever,
+		LocalRetriever:  localRetriever,
+
+		APIKeys: apiKeys,
+		ACL:     aclConfig,
+
+		SLOAvailability:  cfg.SLOAvailability,
+		SLOLatency:       time.Duration(cfg.SLOLatency) * time.Millisecond,
+		SLOLatencyTarget: cfg.SLOLatencyTarget,
+
+		SearchLimits: routeLimits(cfg.SearchTimeout, cfg.SearchMaxConcurrent),
+		AnswerLimits: routeLimits(cfg.AnswerTimeout, cfg.AnswerMaxConcurrent),
+		IngestLimits: routeLimits(cfg.IngestTimeout, cfg.IngestMaxConcurrent),
+	}
+	httpServer := server.NewServer(serverConfig)
+
+	// Start server
+	fmt.Printf("\nServer starting on http://%s:%d\n", cfg.ServerHost, cfg.ServerPort)
+	fmt.Println("Press Ctrl+C to stop the server")
+
+	// Start blocks until SIGINT or SIGTERM, then drains in-flight requests
+	// and background work before returning
+	if err := httpServer.Start(ctx); err != nil {
+		return fmt.Errorf("server error: %w", err)
+	}
+	fmt.Println("Server stopped")
 	return nil
 }
 
@@ -209,3 +368,41 @@ func (r *llmReranker) Rerank(ctx context.Context, query string, results []*index
 
 	return rerankedResults, nil
 }
+
+// parseFederatedInstances parses remote instances given as name=url,
+// comma-separated, authenticating with apiKey when it is set
+func parseFederatedInstances(spec string, timeout time.Duration, apiKey string) ([]retriever.Instance, error) {
+	var instances []retriever.Instance
+	for _, entry := range splitList(spec) {
+		name, rawURL, ok := strings.Cut(entry, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid federated instance %q: expected name=url", entry)
+		}
+		parsed, err := url.Parse(rawURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid URL for federated instance %q", entry)
+		}
+		instances = append(instances, retriever.Instance{
+			Name:      name,
+			Retriever: retriever.NewRemoteRetriever(retriever.RemoteConfig{URL: rawURL, Timeout: timeout, APIKey: apiKey}),
+		})
+	}
+	return instances, nil
+}
+
+// routeLimits converts configured seconds and concurrency into server limits
+func routeLimits(timeout, maxConcurrent int) server.RouteLimits {
+	return server.RouteLimits{
+		Timeout:       time.Duration(timeout) * time.Second,
+		MaxConcurrent: maxConcurrent,
+	}
+}
+
+// newDetector builds the near-duplicate detector, whose fingerprints are
+// saved with indexed documents and compared by the duplicate report
+func newDetector(cfg *config.Config) dedup.Detector {
+	return dedup.NewDetector(dedup.Config{
+		ShingleSize: cfg.DedupShingleSize,
+		MaxDistance: cfg.DedupMaxDistance,
+	})
+}
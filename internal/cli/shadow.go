@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"context"
+	"strings"
+
+	"ai-search/internal/chunker"
+	"ai-search/internal/config"
+	"ai-search/internal/embeddings"
+	"ai-search/internal/indexer"
+	"ai-search/internal/store"
+
+	"github.com/sirupsen/logrus"
+)
+
+// shadowIndex indexes crawled documents a second time into the shadow
+// collection, with its own chunking and embedding settings, so a change
+// can be tried on real data beside production. Chunks are not saved to
+// the store, which holds production's, and shadow failures are logged
+// without failing the production pipeline.
+type shadowIndex struct {
+	indexer  indexer.Indexer
+	chunker  chunker.Chunker
+	embedder *chunkEmbedder
+	logger   logrus.FieldLogger
+}
+
+// newShadowIndexer opens the shadow collection, or returns nil when
+// SHADOW_COLLECTION is not set. Chunking settings set for the shadow
+// replace those stored with its collection.
+func newShadowIndexer(cfg *config.Config, documentStore store.Store) (indexer.Indexer, embeddings.Embedder, error) {
+	if cfg.ShadowCollection == "" {
+		return nil, nil, nil
+	}
+
+	embeddingModels, err := embeddings.ParseModels(cfg.EmbeddingModels)
+	if err != nil {
+		return nil, nil, err
+	}
+	model := cfg.ShadowEmbeddingModel
+	if model == "" {
+		model = cfg.EmbeddingModel
+	}
+	embedder := embeddings.NewEmbedder(embeddings.Config{
+		Model:     model,
+		APIKey:    cfg.EmbeddingAPIKey,
+		BaseURL:   cfg.EmbeddingBaseURL,
+		BatchSize: 10,
+		Timeout:   30,
+		Models:    embeddingModels,
+	})
+
+	chunking := chunkingConfig(cfg)
+	overrideChunking := false
+	if cfg.ShadowChunkSize > 0 {
+		chunking.ChunkSize = cfg.ShadowChunkSize
+		overrideChunking = true
+	}
+	if cfg.ShadowOverlapSize > 0 {
+		chunking.OverlapSize = cfg.ShadowOverlapSize
+		overrideChunking = true
+	}
+	if cfg.ShadowChunkStrategy != "" {
+		chunking.Strategy = cfg.ShadowChunkStrategy
+		overrideChunking = true
+	}
+
+	shadowIndexer := indexer.NewIndexer(indexer.Config{
+		Embedder:         embedder,
+		ChromaURL:        cfg.ChromaURL,
+		ElasticURL:       cfg.ElasticURL,
+		CollectionName:   cfg.ShadowCollection,
+		ElasticIndex:     shadowElasticIndex(cfg),
+		Chunking:         chunking,
+		OverrideChunking: overrideChunking,
+		MaxRetries:       cfg.ElasticMaxRetries,
+		DeadLetters:      &storeDeadLetters{store: documentStore},
+
+		ContextualEnrichment: cfg.ContextualEnrichment,
+		DefaultVersion:       cfg.DefaultVersion,
+
+		DescriptionBoost: cfg.DescriptionBoost,
+		KeywordsBoost:    cfg.KeywordsBoost,
+	})
+	return shadowIndexer, embedder, nil
+}
+
+// shadowElasticIndex names the Elasticsearch index of the shadow collection
+func shadowElasticIndex(cfg *config.Config) string {
+	return strings.ToLower(cfg.ShadowCollection)
+}
+
+// newShadowIndex sets up shadow indexing for a crawl, or returns nil when
+// SHADOW_COLLECTION is not set
+func newShadowIndex(cfg *config.Config, documentStore store.Store, logger logrus.FieldLogger) (*shadowIndex, error) {
+	shadowIndexer, embedder, err := newShadowIndexer(cfg, documentStore)
+	if err != nil || shadowIndexer == nil {
+		return nil, err
+	}
+	chunkEmbedder, err := newChunkEmbedder(cfg, embedder, shadowIndexer)
+	if err != nil {
+		shadowIndexer.Close()
+		return nil, err
+	}
+	textChunker, err := documentChunker(cfg, shadowIndexer)
+	if err != nil {
+		shadowIndexer.Close()
+		return nil, err
+	}
+
+	return &shadowIndex{
+		indexer:  shadowIndexer,
+		chunker:  textChunker,
+		embedder: chunkEmbedder,
+		logger:   logger,
+	}, nil
+}
+
+// index chunks, embeds and indexes a document into the shadow collection
+func (s *shadowIndex) index(ctx context.Context, doc *indexer.Document) {
+	if s == nil {
+		return
+	}
+
+	chunks := chunkDocument(doc, s.chunker, s.indexer)
+	if len(chunks) == 0 {
+		return
+	}
+	if version := doc.Attributes[versionAttribute]; version != "" {
+		for _, chunk := range chunks {
+			chunk.ID = versionedID(version, chunk.ID)
+		}
+	}
+
+	embeddings, err := s.embedder.embed(ctx, doc, chunks)
+	if err != nil {
+		s.logger.Warnf("Failed to embed %s for the shadow collection: %v", doc.URL, err)
+		return
+	}
+	if err := s.indexer.Index(ctx, doc, chunks, embeddings); err != nil {
+		s.logger.Warnf("Failed to index %s in the shadow collection: %v", doc.URL, err)
+	}
+}
+
+// delete removes a document from the shadow collection
+func (s *shadowIndex) delete(ctx context.Context, documentID string) {
+	if s == nil {
+		return
+	}
+	if err := s.indexer.Delete(ctx, documentID); err != nil {
+		s.logger.Warnf("Failed to remove %s from the shadow collection: %v", documentID, err)
+	}
+}
+
+// Close closes the shadow collection
+func (s *shadowIndex) Close() error {
+	if s == nil {
+		return nil
+	}
+	return s.indexer.Close()
+}
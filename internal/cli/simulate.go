@@ -0,0 +1,292 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"ai-search/internal/config"
+	"ai-search/internal/crawler"
+	"ai-search/internal/crawltest"
+	"ai-search/internal/embeddings"
+	"ai-search/internal/events"
+	"ai-search/internal/indexer"
+	"ai-search/internal/parser"
+	"ai-search/internal/retriever"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	simulateDepth   int
+	simulateExpect  []string
+	simulateResults int
+	simulateMeta    map[string]string
+)
+
+// simulateCmd represents the simulate command
+var simulateCmd = &cobra.Command{
+	Use:   "simulate <fixtures-dir>",
+	Short: "Crawl and index recorded pages without external services",
+	Long: `Serve the files of a fixture directory over a local HTTP server, crawl it
+from its root and run every page through the same saving, chunking, embedding
+and indexing as the crawl command, into an in-memory store and index with
+embeddings hashed from the text. No database, vector store, search engine or
+embedding API is involved, and the same fixtures always produce the same
+documents, chunks and rankings, so pipeline changes can be checked in CI.
+
+Files are served at their path in the directory, index.html also at its
+directory's path. Write {{base}} in a fixture for the server's base URL.
+
+Each --expect "query=/path" searches the index for the query and fails the
+run unless the fixture at /path is among the top --results results. The run
+also fails when a page cannot be indexed.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSimulate,
+}
+
+func init() {
+	simulateCmd.Flags().IntVarP(&simulateDepth, "depth", "d", 3, "Maximum crawl depth")
+	simulateCmd.Flags().StringArrayVar(&simulateExpect, "expect", nil, "Search that must return a fixture, as query=/path (repeatable)")
+	simulateCmd.Flags().IntVar(&simulateResults, "results", 3, "Results an expected fixture must be among")
+	simulateCmd.Flags().StringToStringVar(&simulateMeta, "meta", nil, "Attribute to attach to every crawled document (key=value, repeatable)")
+
+	rootCmd.AddCommand(simulateCmd)
+}
+
+// expectation is a search that must return a fixture
+type expectation struct {
+	query string
+	path  string
+}
+
+func runSimulate(cmd *cobra.Command, args []string) error {
+	var expectations []expectation
+	for _, expect := range simulateExpect {
+		query, path, ok := strings.Cut(expect, "=/")
+		if !ok || strings.TrimSpace(query) == "" {
+			return fmt.Errorf("invalid expectation %q: expected query=/path", expect)
+		}
+		expectations = append(expectations, expectation{query: strings.TrimSpace(query), path: "/" + path})
+	}
+
+	// Chunking, parsing and embedding text follow the configuration, the
+	// backends are in memory
+	ctx := context.Background()
+	sim, err := newSimulation(ctx, config.LoadConfig(), args[0], simulateDepth, simulateMeta)
+	if err != nil {
+		return err
+	}
+	defer sim.close()
+
+	// The report names pages by path, which unlike the server's port is
+	// the same on every run
+	docs, err := sim.store.ListDocuments(ctx)
+	if err != nil {
+		return err
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].URL < docs[j].URL })
+
+	fmt.Printf("Crawled %d pages: %d documents, %d chunks, %d duplicates, %d failed\n",
+		sim.pages, len(docs), sim.chunks, sim.duplicates, len(sim.failures))
+	for _, doc := range docs {
+		docChunks, err := sim.store.GetChunks(ctx, doc.ID)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("  %-40s %3d chunks  %.12s  %s\n", sim.fixturePath(doc.URL), len(docChunks), doc.ID, doc.Title)
+	}
+	for _, crawlError := range sim.crawlErrors {
+		fmt.Printf("Crawl error: %s\n", crawlError)
+	}
+	for _, failure := range sim.failures {
+		fmt.Printf("Failed: %s\n", failure)
+	}
+
+	// Check the expected searches against the index
+	missed := 0
+	for _, expected := range expectations {
+		rank, err := sim.rank(ctx, expected.query, expected.path, simulateResults)
+		if err != nil {
+			return err
+		}
+		if rank == 0 {
+			missed++
+			fmt.Printf("FAIL %q: %s not in the top %d\n", expected.query, expected.path, simulateResults)
+			continue
+		}
+		fmt.Printf("ok   %q: %s ranked %d\n", expected.query, expected.path, rank)
+	}
+
+	if len(sim.failures) > 0 {
+		return fmt.Errorf("%d pages failed to index", len(sim.failures))
+	}
+	if missed > 0 {
+		return fmt.Errorf("%d of %d expected searches failed", missed, len(expectations))
+	}
+	return nil
+}
+
+// simulation is a crawl of a fixture directory indexed in memory
+type simulation struct {
+	server  *httptest.Server
+	store   *crawltest.Store
+	indexer *crawltest.Indexer
+
+	pages, duplicates, chunks int
+	crawlErrors               []string // Errors of the crawl, by fixture path
+	failures                  []string // Pages that failed to index
+}
+
+// newSimulation serves a fixture directory, crawls it from its root and
+// runs every page through the same saving, chunking, embedding and
+// indexing as the crawl command. The caller closes the simulation.
+func newSimulation(ctx context.Context, cfg *config.Config, dir string, depth int, meta map[string]string) (*simulation, error) {
+	fixtures, err := crawltest.LoadFixtures(dir)
+	if err != nil {
+		return nil, err
+	}
+	server := crawltest.NewServer(fixtures)
+
+	sim := &simulation{server: server}
+	if err := sim.run(ctx, cfg, depth, meta); err != nil {
+		server.Close()
+		return nil, err
+	}
+	return sim, nil
+}
+
+// run crawls the fixtures and indexes the pages
+func (sim *simulation) run(ctx context.Context, cfg *config.Config, depth int, meta map[string]string) error {
+	startURL, err := url.Parse(sim.server.URL + "/")
+	if err != nil {
+		return err
+	}
+
+	documentStore := crawltest.NewStore()
+	embedder := embeddings.NewMockEmbedder(0)
+	memoryIndexer := crawltest.NewIndexer(embedder, chunkingConfig(cfg))
+	textChunker, err := documentChunker(cfg, memoryIndexer)
+	if err != nil {
+		return err
+	}
+	chunkEmbedder := &chunkEmbedder{
+		embedder: embedder,
+		title:    cfg.EmbedTitle,
+		urlPath:  cfg.EmbedURLPath,
+	}
+	sim.store = documentStore
+	sim.indexer = memoryIndexer
+
+	detector := newDetector(cfg)
+	publisher, err := events.NewPublisher(events.Config{})
+	if err != nil {
+		return err
+	}
+	defer publisher.Close()
+
+	rules, err := loadExtractionRules(cfg.ExtractionRulesFile)
+	if err != nil {
+		return err
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(os.Stderr)
+	logger.SetLevel(logrus.WarnLevel)
+
+	// A single worker crawling breadth first visits pages in the same
+	// order on every run, so the same URL wins among duplicates
+	c := crawler.NewCrawler(crawler.Config{
+		MaxWorkers:    1,
+		RateLimit:     -1,
+		MaxPageSize:   cfg.MaxPageSize,
+		UserAgent:     cfg.UserAgent,
+		Timeout:       cfg.Timeout,
+		RespectRobots: true,
+		BreadthFirst:  true,
+		Metadata:      meta,
+
+		Parser: parser.Config{
+			StreamingThreshold: cfg.StreamingThreshold,
+			MaxElementText:     cfg.MaxElementText,
+			MaxTextSize:        cfg.MaxTextSize,
+			Rules:              rules,
+			StripParams:        stripParams(cfg),
+			DisablePDF:         !cfg.ParsePDF,
+			DisableOffice:      !cfg.ParseOffice,
+		},
+
+		Transport: crawler.TransportConfig{
+			InsecureSkipVerify: true,
+		},
+		RobotsStore: &storeRobots{store: documentStore},
+		Logger:      logger,
+	})
+
+	pageChan, errorChan := c.Crawl(ctx, startURL, depth)
+
+	errorsDone := make(chan struct{})
+	go func() {
+		defer close(errorsDone)
+		for err := range errorChan {
+			if err != nil {
+				sim.crawlErrors = append(sim.crawlErrors, strings.ReplaceAll(err.Error(), sim.server.URL, ""))
+			}
+		}
+	}()
+
+	metaRules := &metadataRules{}
+	for page := range pageChan {
+		sim.pages++
+		attributes := metaRules.attributesFor(page)
+		if _, set := attributes[languageAttribute]; !set && page.Language != "" {
+			attributes[languageAttribute] = page.Language
+		}
+
+		chunkCount, err := indexPage(ctx, page, attributes, documentStore, detector, textChunker, chunkEmbedder, memoryIndexer, nil, publisher)
+		if errors.Is(err, errDuplicateContent) {
+			sim.duplicates++
+			continue
+		}
+		if err != nil {
+			sim.failures = append(sim.failures, fmt.Sprintf("%s: %v", page.URL.Path, err))
+			continue
+		}
+		sim.chunks += chunkCount
+	}
+	<-errorsDone
+	return nil
+}
+
+// rank searches the index and returns the rank of the fixture at path
+// among the top results, 0 when it is not among them
+func (sim *simulation) rank(ctx context.Context, query, path string, limit int) (int, error) {
+	searcher := retriever.NewHybridRetriever(retriever.Config{Indexer: sim.indexer})
+	results, err := searcher.Retrieve(ctx, query, limit, indexer.SearchOptions{})
+	if err != nil {
+		return 0, err
+	}
+	for i, result := range results {
+		resultURL, _ := result.Metadata["url"].(string)
+		if sim.fixturePath(resultURL) == path {
+			return i + 1, nil
+		}
+	}
+	return 0, nil
+}
+
+// fixturePath returns the path of a fixture URL on the simulation server
+func (sim *simulation) fixturePath(rawURL string) string {
+	return strings.TrimPrefix(rawURL, sim.server.URL)
+}
+
+// close stops the fixture server
+func (sim *simulation) close() {
+	sim.server.Close()
+}
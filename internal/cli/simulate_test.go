@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"context"
+	"testing"
+
+	"ai-search/internal/config"
+)
+
+// TestSimulateFixtureSite crawls the recorded fixture site into memory and
+// checks that searches find the expected pages
+func TestSimulateFixtureSite(t *testing.T) {
+	ctx := context.Background()
+	sim, err := newSimulation(ctx, config.LoadConfig(), "../crawltest/testdata/site", 3, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sim.close()
+
+	for _, failure := range sim.failures {
+		t.Errorf("page failed to index: %s", failure)
+	}
+	for _, crawlError := range sim.crawlErrors {
+		t.Errorf("crawl error: %s", crawlError)
+	}
+
+	docs, err := sim.store.ListDocuments(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexed := make(map[string]bool)
+	for _, doc := range docs {
+		indexed[sim.fixturePath(doc.URL)] = true
+	}
+	for _, path := range []string{"/", "/docs/install.html", "/docs/pricing.html", "/blog/launch.html"} {
+		if !indexed[path] {
+			t.Errorf("%s was not indexed", path)
+		}
+	}
+	// robots.txt disallows /private/
+	if indexed["/private/roadmap.html"] {
+		t.Error("/private/roadmap.html was indexed despite robots.txt")
+	}
+
+	expectations := []expectation{
+		{query: "how much does the team plan cost", path: "/docs/pricing.html"},
+		{query: "install postgresql elasticsearch", path: "/docs/install.html"},
+		{query: "launching a hosted search engine", path: "/blog/launch.html"},
+	}
+	for _, expected := range expectations {
+		rank, err := sim.rank(ctx, expected.query, expected.path, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rank != 1 {
+			t.Errorf("%q: %s ranked %d, want 1", expected.query, expected.path, rank)
+		}
+	}
+}
+
+// TestSimulateAttachesMetadata checks that attributes given to the crawl
+// reach every document
+func TestSimulateAttachesMetadata(t *testing.T) {
+	ctx := context.Background()
+	sim, err := newSimulation(ctx, config.LoadConfig(), "../crawltest/testdata/site", 3, map[string]string{"team": "docs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sim.close()
+
+	docs, err := sim.store.ListDocuments(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) == 0 {
+		t.Fatal("no documents indexed")
+	}
+	for _, doc := range docs {
+		// Metadata reads back as it was decoded from JSON
+		attributes, _ := doc.Meta["attributes"].(map[string]interface{})
+		if attributes["team"] != "docs" {
+			t.Errorf("%s has attributes %v, want team=docs", sim.fixturePath(doc.URL), attributes)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"ai-search/internal/config"
+	"ai-search/internal/events"
+	"ai-search/internal/snapshot"
+	"ai-search/internal/store"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	snapshotEvery int
+	snapshotList  bool
+	restoreID     string
+)
+
+// snapshotCmd represents the snapshot command
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Snapshot Elasticsearch, ChromaDB and PostgreSQL together",
+	Long: `Take a coordinated snapshot of the keyword index, the vector collection
+and the document database. With --every the snapshot is repeated on a schedule
+until interrupted, keeping the most recent SNAPSHOT_RETAIN snapshots.`,
+	RunE: runSnapshot,
+}
+
+// restoreCmd represents the restore command
+var restoreCmd = &cobra.Command{
+	Use:   "restore [doc-id]",
+	Short: "Restore a deleted document or all stores from a snapshot",
+	Long: `Restore a soft-deleted document that has not been purged yet, or with
+--snapshot restore Elasticsearch, ChromaDB and PostgreSQL from a snapshot taken
+with the snapshot command, replacing their current contents. Every document the
+snapshot holds is then published as indexed and every one it dropped as
+purged, so caches and the standby follow.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runRestore,
+}
+
+func init() {
+	snapshotCmd.Flags().IntVar(&snapshotEvery, "every", -1, "Minutes between snapshots (0 runs once, defaults to SNAPSHOT_INTERVAL)")
+	snapshotCmd.Flags().BoolVar(&snapshotList, "list", false, "List available snapshots")
+
+	restoreCmd.Flags().StringVar(&restoreID, "snapshot", "", "Snapshot ID to restore")
+}
+
+// newSnapshotter builds a snapshotter from the application configuration
+func newSnapshotter(cfg *config.Config) (snapshot.Snapshotter, error) {
+	return snapshot.NewSnapshotter(snapshot.Config{
+		Dir:            cfg.SnapshotDir,
+		Retain:         cfg.SnapshotRetain,
+		ElasticURL:     cfg.ElasticURL,
+		ESIndex:        cfg.ElasticIndex,
+		ESRepository:   cfg.SnapshotESRepository,
+		ESLocation:     cfg.SnapshotESLocation,
+		ChromaURL:      cfg.ChromaURL,
+		CollectionName: cfg.CollectionName,
+		DatabaseHost:   cfg.DatabaseHost,
+		DatabasePort:   cfg.DatabasePort,
+		DatabaseName:   cfg.DatabaseName,
+		DatabaseUser:   cfg.DatabaseUser,
+		DatabasePass:   cfg.DatabasePassword,
+
+		ShadowCollection: cfg.ShadowCollection,
+		ShadowESIndex:    shadowElasticIndex(cfg),
+	})
+}
+
+func runSnapshot(cmd *cobra.Command, args []string) error {
+	cfg := config.LoadConfig()
+
+	snapshotter, err := newSnapshotter(cfg)
+	if err != nil {
+		return err
+	}
+
+	if snapshotList {
+		manifests, err := snapshotter.List()
+		if err != nil {
+			return err
+		}
+		for _, m := range manifests {
+			fmt.Printf("%s  %s  %d vectors\n", m.ID, m.CreatedAt.Format(time.RFC3339), m.ChromaRecords)
+		}
+		return nil
+	}
+
+	interval := snapshotEvery
+	if interval < 0 {
+		interval = cfg.SnapshotInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	takeSnapshot := func() error {
+		fmt.Println("Taking snapshot...")
+		manifest, err := snapshotter.Create(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Snapshot %s created (%d vectors)\n", manifest.ID, manifest.ChromaRecords)
+		return nil
+	}
+
+	if interval == 0 {
+		return takeSnapshot()
+	}
+
+	fmt.Printf("Taking snapshots every %d minutes. Press Ctrl+C to stop.\n", interval)
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	ticker := time.NewTicker(time.Duration(interval) * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		if err := takeSnapshot(); err != nil {
+			fmt.Fprintf(os.Stderr, "Snapshot failed: %v\n", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-quit:
+			fmt.Println("\nStopping scheduled snapshots")
+			return nil
+		}
+	}
+}
+
+func runRestore(cmd *cobra.Command, args []string) error {
+	if (len(args) == 1) == (restoreID != "") {
+		return fmt.Errorf("specify either a document ID or --snapshot")
+	}
+
+	cfg := config.LoadConfig()
+
+	if len(args) == 1 {
+		return restoreDocument(cfg, args[0])
+	}
+
+	snapshotter, err := newSnapshotter(cfg)
+	if err != nil {
+		return err
+	}
+
+	documentStore := newDocumentStore(cfg)
+	defer documentStore.Close()
+
+	ctx := context.Background()
+	before, err := storedDocumentIDs(ctx, documentStore)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Restoring snapshot %s...\n", restoreID)
+	if err := snapshotter.Restore(ctx, restoreID); err != nil {
+		return err
+	}
+
+	after, err := storedDocumentIDs(ctx, documentStore)
+	if err != nil {
+		return err
+	}
+	if err := publishRestore(ctx, cfg, before, after); err != nil {
+		return err
+	}
+
+	fmt.Printf("Snapshot %s restored\n", restoreID)
+	return nil
+}
+
+// restoreEventSize is the number of documents named in each event published
+// after a snapshot is restored
+const restoreEventSize = 1000
+
+// storedDocumentIDs returns the IDs of every stored document, soft-deleted
+// ones included
+func storedDocumentIDs(ctx context.Context, documentStore store.Store) (map[string]bool, error) {
+	docs, err := documentStore.ListDocuments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := documentStore.ListDeletedDocumentIDs(ctx, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]bool, len(docs)+len(deleted))
+	for _, doc := range docs {
+		ids[doc.ID] = true
+	}
+	for _, id := range deleted {
+		ids[id] = true
+	}
+	return ids, nil
+}
+
+// publishRestore publishes the documents a snapshot restored as indexed and
+// those it dropped as purged, so caches and the outbox's consumers, such
+// as the standby, follow the restored indexes
+func publishRestore(ctx context.Context, cfg *config.Config, before, after map[string]bool) error {
+	publisher, err := newPublisher(cfg)
+	if err != nil {
+		return err
+	}
+	defer publisher.Close()
+
+	var restored, dropped []string
+	for id := range after {
+		restored = append(restored, id)
+	}
+	for id := range before {
+		if !after[id] {
+			dropped = append(dropped, id)
+		}
+	}
+	sort.Strings(restored)
+	sort.Strings(dropped)
+
+	publish := func(eventType string, ids []string) {
+		for start := 0; start < len(ids); start += restoreEventSize {
+			publisher.Publish(ctx, eventType, ids[start:min(start+restoreEventSize, len(ids))]...)
+		}
+	}
+	publish(events.DocumentIndexed, restored)
+	publish(events.DocumentPurged, dropped)
+	return nil
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"ai-search/internal/config"
+	"ai-search/internal/suggest"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	suggestMaxWords   int
+	suggestMinDocs    int
+	suggestMaxShare   float64
+	suggestMaxPhrases int
+	suggestShow       int
+)
+
+// suggestCmd represents the suggest command
+var suggestCmd = &cobra.Command{
+	Use:   "suggest",
+	Short: "Build query suggestions from the corpus",
+	Long: `Extract the phrases of up to --max-words words that recur across the
+stored documents and store the most informative ones as query suggestions,
+served to autocomplete by GET /api/suggest?q=<prefix>. Phrases found in fewer
+than --min-docs documents are too rare to suggest, and those in more than
+--max-share of them too common to inform. Suggestions need no query logs, so
+run this after the first crawl of a fresh deployment and again as the corpus
+grows; each run replaces the previous suggestions.`,
+	RunE: runSuggest,
+}
+
+func init() {
+	suggestCmd.Flags().IntVar(&suggestMaxWords, "max-words", 3, "Longest phrase in words")
+	suggestCmd.Flags().IntVar(&suggestMinDocs, "min-docs", 3, "Documents a phrase must occur in")
+	suggestCmd.Flags().Float64Var(&suggestMaxShare, "max-share", 0.5, "Share of documents above which a phrase is too common")
+	suggestCmd.Flags().IntVar(&suggestMaxPhrases, "max-phrases", 50000, "Suggestions to keep")
+	suggestCmd.Flags().IntVar(&suggestShow, "show", 20, "Top suggestions to print")
+
+	rootCmd.AddCommand(suggestCmd)
+}
+
+func runSuggest(cmd *cobra.Command, args []string) error {
+	if suggestMaxShare > 1 {
+		return fmt.Errorf("--max-share must be at most 1")
+	}
+
+	cfg := config.LoadConfig()
+
+	documentStore := newDocumentStore(cfg)
+	defer documentStore.Close()
+
+	builder := suggest.NewBuilder(suggest.Config{
+		Store:        documentStore,
+		MaxWords:     suggestMaxWords,
+		MinDocuments: suggestMinDocs,
+		MaxShare:     suggestMaxShare,
+		MaxPhrases:   suggestMaxPhrases,
+	})
+	suggestions, err := builder.Build(context.Background())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Stored %d query suggestions\n", len(suggestions))
+	for _, suggestion := range suggestions[:min(suggestShow, len(suggestions))] {
+		fmt.Printf("  %8.2f  %5d docs  %s\n", suggestion.Score, suggestion.Documents, suggestion.Phrase)
+	}
+	return nil
+}
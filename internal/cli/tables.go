@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"ai-search/internal/chunker"
+	"ai-search/internal/indexer"
+	"ai-search/internal/parser"
+)
+
+// tablesMetaKey is the document metadata key the data tables of a page are
+// stored under
+const tablesMetaKey = "tables"
+
+// chunkDocument splits a document's content into chunks and, when the
+// collection indexes tables on their own, appends the chunks of its tables
+func chunkDocument(doc *indexer.Document, textChunker chunker.Chunker, hybridIndexer indexer.Indexer) []*chunker.Chunk {
+	chunks := textChunker.Chunk(doc.Content)
+
+	chunking := hybridIndexer.Chunking()
+	if !chunking.Tables {
+		return chunks
+	}
+	for i, table := range documentTables(doc.Meta) {
+		chunks = append(chunks, tableChunks(doc.Content, i, table, chunking.ChunkSize)...)
+	}
+	return chunks
+}
+
+// documentTables returns the tables stored in a document's metadata, which
+// hold parser tables for a fresh page and decoded JSON for a stored one
+func documentTables(meta map[string]interface{}) []*parser.Table {
+	stored, ok := meta[tablesMetaKey]
+	if !ok {
+		return nil
+	}
+	if tables, ok := stored.([]*parser.Table); ok {
+		return tables
+	}
+
+	// Round-trip through JSON to read tables decoded from the store
+	var tables []*parser.Table
+	if data, err := json.Marshal(stored); err == nil {
+		json.Unmarshal(data, &tables)
+	}
+	return tables
+}
+
+// tableChunks splits a table into chunks of whole rows of at most about
+// chunkSize characters, each starting with the table's caption. The chunks
+// point at the table's text within the document content.
+func tableChunks(content string, index int, table *parser.Table, chunkSize int) []*chunker.Chunk {
+	if len(table.Rows) == 0 {
+		return nil
+	}
+
+	startPos := strings.Index(content, table.Text())
+	if startPos < 0 {
+		startPos = 0
+	}
+	endPos := startPos + len(table.Text())
+
+	prefix := ""
+	if table.Caption != "" {
+		prefix = table.Caption + ". "
+	}
+
+	var chunks []*chunker.Chunk
+	var text strings.Builder
+	flush := func() {
+		if text.Len() == 0 {
+			return
+		}
+		chunkText := prefix + text.String()
+		hash := sha256.Sum256([]byte(fmt.Sprintf("table-%d-%d-%s", index, len(chunks), chunkText)))
+		chunks = append(chunks, &chunker.Chunk{
+			ID:       fmt.Sprintf("%x", hash[:8]),
+			Text:     chunkText,
+			StartPos: startPos,
+			EndPos:   endPos,
+			Metadata: map[string]interface{}{
+				"chunk_size": len(chunkText),
+				"table":      index,
+			},
+		})
+		text.Reset()
+	}
+
+	for i := range table.Rows {
+		row := table.RowText(i) + "."
+		if text.Len() > 0 && len(prefix)+text.Len()+len(row) >= chunkSize {
+			flush()
+		}
+		if text.Len() > 0 {
+			text.WriteString(" ")
+		}
+		text.WriteString(row)
+	}
+	flush()
+
+	return chunks
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"ai-search/internal/config"
+	"ai-search/internal/crawler"
+	"ai-search/internal/llm"
+)
+
+// titleTimeout bounds generating the title of one page
+const titleTimeout = 30 * time.Second
+
+// pageTitler titles pages that have neither a <title> nor a heading with
+// the LLM, replacing the title derived from their URL
+type pageTitler struct {
+	llm llm.LLM
+}
+
+// newPageTitler returns a titler when GENERATE_TITLES is set, nil otherwise
+func newPageTitler(cfg *config.Config) (*pageTitler, error) {
+	if !cfg.GenerateTitles {
+		return nil, nil
+	}
+	if cfg.LLMAPIKey == "" && cfg.LLMProvider != llm.MockProvider {
+		return nil, fmt.Errorf("LLM_API_KEY environment variable is required for GENERATE_TITLES")
+	}
+
+	return &pageTitler{
+		llm: llm.NewLLM(llm.Config{
+			Provider: cfg.LLMProvider,
+			Model:    cfg.LLMModel,
+			APIKey:   cfg.LLMAPIKey,
+			BaseURL:  cfg.LLMBaseURL,
+		}),
+	}, nil
+}
+
+// title generates a title for a page titled after its URL. The URL title
+// is kept if generation fails.
+func (t *pageTitler) title(ctx context.Context, page *crawler.Page) error {
+	if t == nil || page.TitleSource != crawler.TitleFromURL || page.Content == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, titleTimeout)
+	defer cancel()
+
+	title, err := t.llm.Title(ctx, page.Content)
+	if err != nil {
+		return fmt.Errorf("failed to title %s: %w", page.URL, err)
+	}
+	if title != "" {
+		page.Title = title
+		page.TitleSource = crawler.TitleGenerated
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package cli
+
+import (
+	"fmt"
+
+	"ai-search/internal/version"
+
+	"github.com/spf13/cobra"
+)
+
+// versionCmd represents the version command
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print version and build information",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		info := version.Get()
+		fmt.Printf("ai-search %s\n", info.Version)
+		fmt.Printf("  Commit:     %s\n", info.Commit)
+		fmt.Printf("  Build date: %s\n", info.BuildDate)
+		fmt.Printf("  Go version: %s\n", info.GoVersion)
+	},
+}
@@ -14,6 +14,33 @@ type Config struct {
 	// Server configuration
 	ServerHost string
 	ServerPort int
+	AdminToken string
+
+	// Seconds readiness fails before shutdown starts, and seconds in-flight
+	// requests and pending work get to finish once it has
+	DrainDelay    int
+	ShutdownGrace int
+
+	// DebugAddr serves pprof profiles and metrics without authentication
+	DebugAddr string
+
+	// Search SLOs: the fraction of searches that must succeed, and the
+	// fraction that must finish within SLOLatency milliseconds
+	SLOAvailability  float64
+	SLOLatency       int
+	SLOLatencyTarget float64
+
+	// Seconds a request may take and requests handled at once by the
+	// search, answer and ingest routes; 0 for no limit
+	SearchTimeout       int
+	SearchMaxConcurrent int
+	AnswerTimeout       int
+	AnswerMaxConcurrent int
+	IngestTimeout       int
+	IngestMaxConcurrent int
+
+	// DeleteRetention is how many hours soft-deleted documents can be restored
+	DeleteRetention int
 
 	// Database configuration
 	DatabaseType     string
@@ -25,9 +52,58 @@ type Config struct {
 	DatabaseSSLMode  string
 
 	// Vector database configuration
-	ChromaURL      string
-	ElasticURL     string
-	CollectionName string
+	ChromaURL       string
+	ElasticURL      string
+	ElasticIndex    string
+	CollectionName  string
+	DefaultVersion  string
+	DefaultLanguage string
+
+	// SourceWeights weight search results by the source_type attribute of
+	// their documents, as type=weight, comma-separated
+	SourceWeights string
+
+	// RoutingFile is a YAML file of collections the server routes queries
+	// between, classifying them with RoutingClassifier, keywords or llm
+	RoutingFile       string
+	RoutingClassifier string
+
+	// FederatedInstances are remote ai-search deployments, as name=url,
+	// comma-separated, whose results the server fuses with its own, which
+	// are labelled InstanceName
+	FederatedInstances string
+	InstanceName       string
+	FederationTimeout  int // Seconds allowed for each remote search
+	FederationAPIKey   string
+
+	// APIKeysFile is a YAML file of API keys with their rate limits and
+	// monthly quotas; when set the search API requires a key
+	APIKeysFile string
+
+	// ACLIdentity enforces the acl attribute of documents at query time,
+	// identifying callers by a bearer JWT ("jwt") or by headers set by an
+	// authenticating proxy ("header"); empty shows every document to all
+	ACLIdentity         string
+	ACLJWTSecret        string // HS256 signing secret
+	ACLJWTPublicKeyFile string // PEM public key or certificate for RS256
+	ACLJWTIssuer        string
+	ACLJWTAudience      string
+	ACLGroupsClaim      string
+	ACLUserHeader       string
+	ACLGroupsHeader     string
+
+	// Keyword search boosts of the meta description and keywords fields
+	DescriptionBoost float64
+	KeywordsBoost    float64
+
+	// Retries of Elasticsearch writes answered with 429 or 503 before the
+	// chunks are dead-lettered
+	ElasticMaxRetries int
+
+	// Cross-lingual retrieval translates queries into these languages and
+	// merges the results; pair it with a multilingual embedding model
+	CrossLingual              bool
+	QueryTranslationLanguages string
 
 	// LLM configuration
 	LLMProvider     string
@@ -35,16 +111,60 @@ type Config struct {
 	LLMAPIKey       string
 	LLMBaseURL      string
 	EnableReranking bool
+	GenerateTitles  bool // Ask the LLM to title pages with neither a <title> nor a heading
+
+	// Reranked orders are reused for RerankCacheTTL seconds by searches
+	// with the same query and candidates; 0 disables the cache
+	RerankCacheTTL  int
+	RerankCacheSize int
 
 	// Embedding configuration
 	EmbeddingModel   string
 	EmbeddingAPIKey  string
 	EmbeddingBaseURL string
+	EmbeddingModels  string // Custom models as name:dimensions:max_tokens[:price], comma-separated
 
 	// Chunking configuration
-	ChunkSize    int
-	OverlapSize  int
-	MinChunkSize int
+	ChunkSize     int
+	OverlapSize   int
+	MinChunkSize  int
+	ChunkStrategy string // sentence or fixed
+	ChunkTables   bool   // Also index each data table as chunks of its own
+
+	// FoldDiacritics keyword-searches chunks with accents folded as well
+	FoldDiacritics bool
+
+	// TextFilter drops cookie notices, share links and other boilerplate
+	// sentences from documents before they are chunked, along with the
+	// sentences listed in TextFilterFile, one per line, as the boilerplate
+	// command writes them. TextFilterEmoji is keep, normalize or strip.
+	TextFilter      bool
+	TextFilterFile  string
+	TextFilterEmoji string
+
+	// Documents with more chunks are sampled down to MaxChunksPerDocument
+	// (0 disables the cap) using ChunkSampling, head-tail or importance
+	MaxChunksPerDocument int
+	ChunkSampling        string
+
+	// ShadowCollection, when set, has crawls index every document a second
+	// time into this collection with the Shadow* chunking and embedding
+	// settings (0 or empty for the production ones), so changes can be
+	// compared through /api/admin/compare before cutover
+	ShadowCollection     string
+	ShadowChunkSize      int
+	ShadowOverlapSize    int
+	ShadowChunkStrategy  string
+	ShadowEmbeddingModel string
+
+	// Prefix chunk text with the document title and URL section path
+	// when embedding it
+	EmbedTitle   bool
+	EmbedURLPath bool
+
+	// New collections have the LLM situate each chunk in its document
+	// before it is embedded and keyword-indexed
+	ContextualEnrichment bool
 
 	// Crawler configuration
 	MaxWorkers    int
@@ -53,6 +173,142 @@ type Config struct {
 	UserAgent     string
 	Timeout       int
 	RespectRobots bool
+	MaxPerHost    int    // Concurrent requests allowed per host, 0 for no limit
+	Proxies       string // Comma-separated proxy URLs requests rotate through
+
+	// RespectNofollow skips links marked rel="nofollow" or rel="ugc"
+	RespectNofollow bool
+
+	// CrawlAuthFile is a JSON file of per-domain headers, cookies and basic
+	// auth credentials
+	CrawlAuthFile string
+
+	// ExtractionRulesFile is a YAML file of per-domain CSS selectors
+	// overriding the generic extraction of text and titles
+	ExtractionRulesFile string
+
+	// StripQueryParams are the query parameters dropped from crawled URLs
+	// (comma-separated, * suffix for prefixes), empty for the defaults and
+	// "none" to keep every parameter
+	StripQueryParams string
+
+	// AllowedLanguages skips crawled pages in other languages before they
+	// are embedded (comma-separated primary subtags, empty for all);
+	// DetectLanguage identifies the language of pages not declaring one
+	AllowedLanguages string
+	DetectLanguage   bool
+
+	// AllowedCountries skips crawled pages targeting other countries
+	// (comma-separated ISO 3166 codes, empty for all)
+	AllowedCountries string
+
+	// WARCDir records every crawled response into WARC files in this
+	// directory, rotated at WARCMaxFileSize bytes
+	WARCDir         string
+	WARCMaxFileSize int64
+
+	// RobotsCacheTTL is how many hours robots.txt rules are reused at most,
+	// and RobotsCacheSize how many hosts' rules are kept in memory
+	RobotsCacheTTL  int
+	RobotsCacheSize int
+
+	// Recrawl configuration
+	RecrawlInterval  int    // Hours before a document is recrawled
+	RecrawlPolicies  string // Per-domain intervals as domain=duration, comma-separated
+	RecrawlBatchSize int
+
+	// Sitemap configuration
+	UseSitemaps    bool
+	MaxSitemapURLs int
+
+	// JavaScript rendering configuration
+	RenderJS      bool
+	RenderTimeout int
+	RenderSettle  int
+	BrowserPath   string
+
+	// Response cache and invalidation configuration
+	CacheTTL             int // Seconds, 0 disables the search response cache
+	CacheMaxEntries      int
+	InvalidationWebhooks string // Comma-separated URLs receiving mutation events
+	InvalidationChannel  string // Redis pub/sub channel for mutation events
+
+	// Saved searches are matched against newly indexed documents every
+	// AlertInterval seconds; email alerts go through the SMTP server at
+	// SMTPAddr (host:port)
+	AlertInterval int
+	SMTPAddr      string
+	SMTPUsername  string
+	SMTPPassword  string
+	SMTPFrom      string
+
+	// Mutation events are also appended to OutboxStream, a Redis stream the
+	// replicate command mirrors to the standby ChromaDB and Elasticsearch
+	OutboxStream      string
+	ReplicaChromaURL  string
+	ReplicaElasticURL string
+	ReplicaName       string // Consumer group of the replica on the stream
+	ReplicaAttempts   int    // Deliveries of a failing event before it is dead-lettered
+
+	// Search results carry a query-focused snippet of at most SnippetLength
+	// characters instead of the chunk text when Snippets is set
+	Snippets      bool
+	SnippetLength int
+
+	// LogQueries records searches and their results for training data
+	LogQueries bool
+
+	// Crawl queue configuration ("memory" or "redis" to share a crawl
+	// between processes)
+	CrawlQueue string
+	RedisURL   string
+
+	// HTTP transport configuration
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     int
+	TLSHandshakeTimeout int
+
+	HTTP2              bool
+	KeepAlives         bool
+	InsecureSkipVerify bool
+	DNSCacheTTL        int // Seconds, 0 disables the DNS cache
+
+	// Parser configuration
+	StreamingThreshold int
+	MaxElementText     int
+	MaxTextSize        int
+	ParsePDF           bool // Index PDF documents and follow links to them
+	ParseOffice        bool // The same for Word, PowerPoint and Excel documents
+
+	// Worker pool configuration
+	IndexWorkers    int
+	AutoTuneWorkers bool
+	MaxAutoWorkers  int
+
+	// Deduplication configuration
+	DedupShingleSize int
+	DedupMaxDistance int
+
+	// Snapshot configuration
+	SnapshotDir          string
+	SnapshotInterval     int // Minutes between scheduled snapshots, 0 runs once
+	SnapshotRetain       int
+	SnapshotESRepository string
+	SnapshotESLocation   string
+
+	// Analytics export configuration
+	ExportDestination     string
+	ExportFormat          string
+	ExportDatasets        string
+	ExportInterval        int // Minutes between scheduled exports, 0 runs once
+	ExportPageSize        int // Rows shipped per batch
+	ExportS3Region        string
+	ExportS3Endpoint      string
+	AWSAccessKeyID        string
+	AWSSecretAccessKey    string
+	AWSSessionToken       string
+	GoogleCredentialsFile string
 }
 
 // LoadConfig loads configuration from environment variables with defaults
@@ -88,6 +344,25 @@ func LoadConfig() *Config {
 		// Server defaults
 		ServerHost: getEnv("SERVER_HOST", "localhost"),
 		ServerPort: getEnvInt("SERVER_PORT", 8080),
+		AdminToken: getEnv("ADMIN_TOKEN", ""),
+
+		DrainDelay:    getEnvInt("DRAIN_DELAY", 5),
+		ShutdownGrace: getEnvInt("SHUTDOWN_GRACE", 30),
+
+		DebugAddr: getEnv("DEBUG_ADDR", ""),
+
+		SLOAvailability:  getEnvFloat("SLO_AVAILABILITY", 0.999),
+		SLOLatency:       getEnvInt("SLO_LATENCY_MS", 500),
+		SLOLatencyTarget: getEnvFloat("SLO_LATENCY_TARGET", 0.95),
+
+		SearchTimeout:       getEnvInt("SEARCH_TIMEOUT", 10),
+		SearchMaxConcurrent: getEnvInt("SEARCH_MAX_CONCURRENT", 100),
+		AnswerTimeout:       getEnvInt("ANSWER_TIMEOUT", 60),
+		AnswerMaxConcurrent: getEnvInt("ANSWER_MAX_CONCURRENT", 10),
+		IngestTimeout:       getEnvInt("INGEST_TIMEOUT", 30),
+		IngestMaxConcurrent: getEnvInt("INGEST_MAX_CONCURRENT", 20),
+
+		DeleteRetention: getEnvInt("DELETE_RETENTION_HOURS", 168),
 
 		// Database defaults
 		DatabaseType:     getEnv("DATABASE_TYPE", "postgres"),
@@ -99,9 +374,40 @@ func LoadConfig() *Config {
 		DatabaseSSLMode:  getEnv("DATABASE_SSL_MODE", "disable"),
 
 		// Vector database defaults
-		ChromaURL:      getEnv("CHROMA_URL", "http://localhost:8000"),
-		ElasticURL:     getEnv("ELASTIC_URL", "http://localhost:9200"),
-		CollectionName: getEnv("COLLECTION_NAME", "ai_search_documents"),
+		ChromaURL:       getEnv("CHROMA_URL", "http://localhost:8000"),
+		ElasticURL:      getEnv("ELASTIC_URL", "http://localhost:9200"),
+		ElasticIndex:    getEnv("ELASTIC_INDEX", "ai_search_documents"),
+		CollectionName:  getEnv("COLLECTION_NAME", "ai_search_documents"),
+		DefaultVersion:  getEnv("DEFAULT_VERSION", ""),
+		DefaultLanguage: getEnv("DEFAULT_LANGUAGE", ""),
+		SourceWeights:   getEnv("SOURCE_WEIGHTS", ""),
+
+		RoutingFile:       getEnv("ROUTING_FILE", ""),
+		RoutingClassifier: getEnv("ROUTING_CLASSIFIER", "keywords"),
+
+		FederatedInstances: getEnv("FEDERATED_INSTANCES", ""),
+		InstanceName:       getEnv("INSTANCE_NAME", "local"),
+		FederationTimeout:  getEnvInt("FEDERATION_TIMEOUT", 5),
+		FederationAPIKey:   getEnv("FEDERATION_API_KEY", ""),
+
+		APIKeysFile: getEnv("API_KEYS_FILE", ""),
+
+		ACLIdentity:         getEnv("ACL_IDENTITY", ""),
+		ACLJWTSecret:        getEnv("ACL_JWT_SECRET", ""),
+		ACLJWTPublicKeyFile: getEnv("ACL_JWT_PUBLIC_KEY_FILE", ""),
+		ACLJWTIssuer:        getEnv("ACL_JWT_ISSUER", ""),
+		ACLJWTAudience:      getEnv("ACL_JWT_AUDIENCE", ""),
+		ACLGroupsClaim:      getEnv("ACL_GROUPS_CLAIM", "groups"),
+		ACLUserHeader:       getEnv("ACL_USER_HEADER", "X-Forwarded-User"),
+		ACLGroupsHeader:     getEnv("ACL_GROUPS_HEADER", "X-Forwarded-Groups"),
+
+		DescriptionBoost: getEnvFloat("DESCRIPTION_BOOST", 1.2),
+		KeywordsBoost:    getEnvFloat("KEYWORDS_BOOST", 1.0),
+
+		ElasticMaxRetries: getEnvInt("ELASTIC_MAX_RETRIES", 4),
+
+		CrossLingual:              getEnvBool("CROSS_LINGUAL", false),
+		QueryTranslationLanguages: getEnv("QUERY_TRANSLATION_LANGUAGES", "en"),
 
 		// LLM defaults
 		LLMProvider:     getEnv("LLM_PROVIDER", "openrouter"),
@@ -109,16 +415,43 @@ func LoadConfig() *Config {
 		LLMAPIKey:       getEnv("LLM_API_KEY", ""),
 		LLMBaseURL:      getEnv("LLM_BASE_URL", "https://openrouter.ai/api/v1"),
 		EnableReranking: getEnvBool("ENABLE_RERANKING", false),
+		GenerateTitles:  getEnvBool("GENERATE_TITLES", false),
+
+		RerankCacheTTL:  getEnvInt("RERANK_CACHE_TTL", 3600),
+		RerankCacheSize: getEnvInt("RERANK_CACHE_SIZE", 1000),
 
 		// Embedding defaults (OpenAI)
 		EmbeddingModel:   getEnv("EMBEDDING_MODEL", "text-embedding-3-small"),
 		EmbeddingAPIKey:  getEnv("EMBEDDING_API_KEY", ""),
 		EmbeddingBaseURL: getEnv("EMBEDDING_BASE_URL", "https://api.openai.com/v1"),
+		EmbeddingModels:  getEnv("EMBEDDING_MODELS", ""),
 
 		// Chunking defaults
-		ChunkSize:    getEnvInt("CHUNK_SIZE", 1000),
-		OverlapSize:  getEnvInt("OVERLAP_SIZE", 200),
-		MinChunkSize: getEnvInt("MIN_CHUNK_SIZE", 100),
+		ChunkSize:     getEnvInt("CHUNK_SIZE", 1000),
+		OverlapSize:   getEnvInt("OVERLAP_SIZE", 200),
+		MinChunkSize:  getEnvInt("MIN_CHUNK_SIZE", 100),
+		ChunkStrategy: getEnv("CHUNK_STRATEGY", "sentence"),
+		ChunkTables:   getEnvBool("CHUNK_TABLES", false),
+
+		FoldDiacritics: getEnvBool("FOLD_DIACRITICS", false),
+
+		TextFilter:      getEnvBool("TEXT_FILTER", false),
+		TextFilterFile:  getEnv("TEXT_FILTER_FILE", ""),
+		TextFilterEmoji: getEnv("TEXT_FILTER_EMOJI", "keep"),
+
+		MaxChunksPerDocument: getEnvInt("MAX_CHUNKS_PER_DOCUMENT", 500),
+		ChunkSampling:        getEnv("CHUNK_SAMPLING", "head-tail"),
+
+		ShadowCollection:     getEnv("SHADOW_COLLECTION", ""),
+		ShadowChunkSize:      getEnvInt("SHADOW_CHUNK_SIZE", 0),
+		ShadowOverlapSize:    getEnvInt("SHADOW_OVERLAP_SIZE", 0),
+		ShadowChunkStrategy:  getEnv("SHADOW_CHUNK_STRATEGY", ""),
+		ShadowEmbeddingModel: getEnv("SHADOW_EMBEDDING_MODEL", ""),
+
+		EmbedTitle:   getEnvBool("EMBED_TITLE", false),
+		EmbedURLPath: getEnvBool("EMBED_URL_PATH", false),
+
+		ContextualEnrichment: getEnvBool("CONTEXTUAL_ENRICHMENT", false),
 
 		// Crawler defaults
 		MaxWorkers:    getEnvInt("MAX_WORKERS", 5),
@@ -127,6 +460,118 @@ func LoadConfig() *Config {
 		UserAgent:     getEnv("USER_AGENT", "ai-search/1.0"),
 		Timeout:       getEnvInt("TIMEOUT", 30),
 		RespectRobots: getEnvBool("RESPECT_ROBOTS", false),
+		MaxPerHost:    getEnvInt("MAX_PER_HOST", 2),
+		Proxies:       getEnv("PROXIES", ""),
+
+		RespectNofollow: getEnvBool("RESPECT_NOFOLLOW", false),
+
+		CrawlAuthFile: getEnv("CRAWL_AUTH_FILE", ""),
+
+		ExtractionRulesFile: getEnv("EXTRACTION_RULES_FILE", ""),
+		StripQueryParams:    getEnv("STRIP_QUERY_PARAMS", ""),
+
+		AllowedLanguages: getEnv("ALLOWED_LANGUAGES", ""),
+		DetectLanguage:   getEnvBool("DETECT_LANGUAGE", false),
+		AllowedCountries: getEnv("ALLOWED_COUNTRIES", ""),
+
+		WARCDir:         getEnv("WARC_DIR", ""),
+		WARCMaxFileSize: int64(getEnvInt("WARC_MAX_FILE_SIZE", 1024*1024*1024)),
+
+		RobotsCacheTTL:  getEnvInt("ROBOTS_CACHE_TTL", 24),
+		RobotsCacheSize: getEnvInt("ROBOTS_CACHE_SIZE", 10000),
+
+		// Recrawl defaults
+		RecrawlInterval:  getEnvInt("RECRAWL_INTERVAL", 24),
+		RecrawlPolicies:  getEnv("RECRAWL_POLICIES", ""),
+		RecrawlBatchSize: getEnvInt("RECRAWL_BATCH_SIZE", 100),
+
+		// Sitemap defaults
+		UseSitemaps:    getEnvBool("USE_SITEMAPS", false),
+		MaxSitemapURLs: getEnvInt("MAX_SITEMAP_URLS", 10000),
+
+		// JavaScript rendering defaults
+		RenderJS:      getEnvBool("RENDER_JS", false),
+		RenderTimeout: getEnvInt("RENDER_TIMEOUT", 30),
+		RenderSettle:  getEnvInt("RENDER_SETTLE_MS", 500),
+		BrowserPath:   getEnv("BROWSER_PATH", ""),
+
+		// Response cache and invalidation defaults
+		CacheTTL:             getEnvInt("CACHE_TTL", 300),
+		CacheMaxEntries:      getEnvInt("CACHE_MAX_ENTRIES", 1000),
+		InvalidationWebhooks: getEnv("INVALIDATION_WEBHOOKS", ""),
+		InvalidationChannel:  getEnv("INVALIDATION_CHANNEL", ""),
+
+		// Saved search alert defaults
+		AlertInterval: getEnvInt("ALERT_INTERVAL", 60),
+		SMTPAddr:      getEnv("SMTP_ADDR", ""),
+		SMTPUsername:  getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:  getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:      getEnv("SMTP_FROM", "ai-search@localhost"),
+
+		// Replication defaults
+		OutboxStream:      getEnv("OUTBOX_STREAM", ""),
+		ReplicaChromaURL:  getEnv("REPLICA_CHROMA_URL", ""),
+		ReplicaElasticURL: getEnv("REPLICA_ELASTIC_URL", ""),
+		ReplicaName:       getEnv("REPLICA_NAME", "replica"),
+		ReplicaAttempts:   getEnvInt("REPLICA_ATTEMPTS", 10),
+
+		// Snippet defaults
+		Snippets:      getEnvBool("SEARCH_SNIPPETS", false),
+		SnippetLength: getEnvInt("SNIPPET_LENGTH", 240),
+
+		// Query log defaults
+		LogQueries: getEnvBool("LOG_QUERIES", true),
+
+		// Crawl queue defaults
+		CrawlQueue: getEnv("CRAWL_QUEUE", "memory"),
+		RedisURL:   getEnv("REDIS_URL", "redis://localhost:6379/0"),
+
+		// HTTP transport defaults
+		MaxIdleConns:        getEnvInt("MAX_IDLE_CONNS", 100),
+		MaxIdleConnsPerHost: getEnvInt("MAX_IDLE_CONNS_PER_HOST", 10),
+		IdleConnTimeout:     getEnvInt("IDLE_CONN_TIMEOUT", 90),
+		TLSHandshakeTimeout: getEnvInt("TLS_HANDSHAKE_TIMEOUT", 10),
+
+		HTTP2:              getEnvBool("HTTP2", true),
+		KeepAlives:         getEnvBool("KEEP_ALIVES", true),
+		InsecureSkipVerify: getEnvBool("TLS_INSECURE_SKIP_VERIFY", false),
+		DNSCacheTTL:        getEnvInt("DNS_CACHE_TTL", 300),
+
+		// Parser defaults
+		StreamingThreshold: getEnvInt("STREAMING_THRESHOLD", 512*1024),
+		MaxElementText:     getEnvInt("MAX_ELEMENT_TEXT", 64*1024),
+		MaxTextSize:        getEnvInt("MAX_TEXT_SIZE", 2*1024*1024),
+		ParsePDF:           getEnvBool("PARSE_PDF", true),
+		ParseOffice:        getEnvBool("PARSE_OFFICE", true),
+
+		// Worker pool defaults
+		IndexWorkers:    getEnvInt("INDEX_WORKERS", 2),
+		AutoTuneWorkers: getEnvBool("AUTO_TUNE_WORKERS", false),
+		MaxAutoWorkers:  getEnvInt("MAX_AUTO_WORKERS", 20),
+
+		// Deduplication defaults
+		DedupShingleSize: getEnvInt("DEDUP_SHINGLE_SIZE", 3),
+		DedupMaxDistance: getEnvInt("DEDUP_MAX_DISTANCE", 3),
+
+		// Snapshot defaults
+		SnapshotDir:          getEnv("SNAPSHOT_DIR", "./snapshots"),
+		SnapshotInterval:     getEnvInt("SNAPSHOT_INTERVAL", 0),
+		SnapshotRetain:       getEnvInt("SNAPSHOT_RETAIN", 7),
+		SnapshotESRepository: getEnv("SNAPSHOT_ES_REPOSITORY", "ai_search_backups"),
+		SnapshotESLocation:   getEnv("SNAPSHOT_ES_LOCATION", "/usr/share/elasticsearch/snapshots"),
+
+		// Analytics export defaults
+		ExportDestination:     getEnv("EXPORT_DESTINATION", ""),
+		ExportFormat:          getEnv("EXPORT_FORMAT", "parquet"),
+		ExportDatasets:        getEnv("EXPORT_DATASETS", ""),
+		ExportInterval:        getEnvInt("EXPORT_INTERVAL", 0),
+		ExportPageSize:        getEnvInt("EXPORT_PAGE_SIZE", 10000),
+		ExportS3Region:        getEnv("EXPORT_S3_REGION", "us-east-1"),
+		ExportS3Endpoint:      getEnv("EXPORT_S3_ENDPOINT", ""),
+		AWSAccessKeyID:        getEnv("AWS_ACCESS_KEY_ID", ""),
+		AWSSecretAccessKey:    getEnv("AWS_SECRET_ACCESS_KEY", ""),
+		AWSSessionToken:       getEnv("AWS_SESSION_TOKEN", ""),
+		GoogleCredentialsFile: getEnv("GOOGLE_APPLICATION_CREDENTIALS", ""),
 	}
 
 	return config
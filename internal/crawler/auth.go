@@ -0,0 +1,80 @@
+package crawler
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DomainAuth holds the credentials sent with requests to a domain, for
+// crawling intranet wikis and staging sites behind simple authentication
+type DomainAuth struct {
+	// Domain is a host such as wiki.example.com; its subdomains match too
+	Domain string `json:"domain"`
+
+	Headers  map[string]string `json:"headers,omitempty"`
+	Cookies  map[string]string `json:"cookies,omitempty"`
+	Username string            `json:"username,omitempty"` // HTTP basic auth
+	Password string            `json:"password,omitempty"`
+
+	// AllowHTTP sends the credentials over plain HTTP too, for hosts on a
+	// trusted network without TLS. Otherwise only HTTPS requests get them.
+	AllowHTTP bool `json:"allow_http,omitempty"`
+}
+
+// matches reports whether a host is the domain or one of its subdomains
+func (a *DomainAuth) matches(host string) bool {
+	domain := strings.ToLower(strings.TrimPrefix(a.Domain, "."))
+	host = strings.ToLower(host)
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// authFor returns the credentials of the most specific domain matching a
+// host, or nil
+func authFor(auths []DomainAuth, host string) *DomainAuth {
+	var best *DomainAuth
+	for i := range auths {
+		if auths[i].matches(host) && (best == nil || len(auths[i].Domain) > len(best.Domain)) {
+			best = &auths[i]
+		}
+	}
+	return best
+}
+
+// authForURL returns the credentials to send with a request to a URL, or
+// nil. Credentials go over HTTPS only unless their domain allows HTTP.
+func authForURL(auths []DomainAuth, target *url.URL) *DomainAuth {
+	auth := authFor(auths, target.Hostname())
+	if auth == nil || (target.Scheme != "https" && !auth.AllowHTTP) {
+		return nil
+	}
+	return auth
+}
+
+// authTransport adds each domain's credentials to the requests sent to it.
+// Adding them per request rather than to the crawler's requests keeps them
+// from following a redirect to another host.
+type authTransport struct {
+	base  http.RoundTripper
+	auths []DomainAuth
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	auth := authForURL(t.auths, req.URL)
+	if auth == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	for name, value := range auth.Headers {
+		req.Header.Set(name, value)
+	}
+	for name, value := range auth.Cookies {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+	if auth.Username != "" || auth.Password != "" {
+		req.SetBasicAuth(auth.Username, auth.Password)
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,90 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sync"
+)
+
+// denyList holds the patterns of URLs a crawl does not fetch. Patterns are
+// only ever added, narrowing a crawl while it runs.
+type denyList struct {
+	mutex    sync.RWMutex
+	patterns []*regexp.Regexp
+}
+
+// add compiles patterns and adds them, adding none if any is invalid
+func (d *denyList) add(patterns []string) error {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid deny pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.patterns = append(d.patterns, compiled...)
+	return nil
+}
+
+// denies reports whether a URL matches any of the patterns
+func (d *denyList) denies(rawURL string) bool {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	for _, pattern := range d.patterns {
+		if pattern.MatchString(rawURL) {
+			return true
+		}
+	}
+	return false
+}
+
+// Deny stops the crawl fetching URLs that match any of the patterns,
+// including URLs already queued
+func (c *crawler) Deny(patterns []string) error {
+	return c.deny.add(patterns)
+}
+
+// AddSeeds queues more start URLs, at depth 0, on the running crawl
+func (c *crawler) AddSeeds(ctx context.Context, rawURLs []string) error {
+	seeds := make([]*url.URL, 0, len(rawURLs))
+	for _, raw := range rawURLs {
+		seed, err := c.normalizer.Normalize(raw, nil)
+		if err != nil || !c.normalizer.IsValid(seed) {
+			return fmt.Errorf("invalid seed URL: %s", raw)
+		}
+		seeds = append(seeds, seed)
+	}
+
+	c.runMutex.Lock()
+	frontier := c.frontier
+	c.runMutex.Unlock()
+	if frontier == nil {
+		return fmt.Errorf("no crawl is running")
+	}
+
+	// Seeds go to the shared queue for any crawl process to pick up; it
+	// drops those enqueued before
+	if c.config.Queue != nil {
+		urls := make([]FrontierURL, 0, len(seeds))
+		for _, seed := range seeds {
+			urls = append(urls, FrontierURL{URL: seed.String(), Depth: 0})
+		}
+		if err := c.config.Queue.Push(ctx, urls); err != nil {
+			return fmt.Errorf("failed to push seeds to crawl queue: %w", err)
+		}
+		return nil
+	}
+
+	c.persistLinks(ctx, seeds, 0)
+	for _, seed := range seeds {
+		frontier.push(urlWithDepth{url: seed, depth: 0})
+	}
+	return nil
+}
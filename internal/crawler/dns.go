@@ -0,0 +1,84 @@
+package crawler
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// dnsCache resolves hosts once per TTL so large crawls do not query DNS
+// for every new connection
+type dnsCache struct {
+	dialer   *net.Dialer
+	resolver *net.Resolver
+	ttl      time.Duration
+
+	mutex   sync.Mutex
+	entries map[string]*dnsEntry
+}
+
+// dnsEntry holds the addresses of a host and when they were resolved
+type dnsEntry struct {
+	addrs    []string
+	resolved time.Time
+}
+
+// newDNSCache creates a DNS cache dialing through dialer
+func newDNSCache(dialer *net.Dialer, ttl time.Duration) *dnsCache {
+	return &dnsCache{
+		dialer:   dialer,
+		resolver: net.DefaultResolver,
+		ttl:      ttl,
+		entries:  make(map[string]*dnsEntry),
+	}
+}
+
+// DialContext dials the cached addresses of the host in turn until one
+// connects
+func (d *dnsCache) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || net.ParseIP(host) != nil {
+		return d.dialer.DialContext(ctx, network, address)
+	}
+
+	addrs, err := d.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, addr := range addrs {
+		conn, err := d.dialer.DialContext(ctx, network, net.JoinHostPort(addr, port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	// Resolve again next time in case the host moved
+	d.mutex.Lock()
+	delete(d.entries, host)
+	d.mutex.Unlock()
+	return nil, lastErr
+}
+
+// lookup returns the addresses of a host, resolving it when the cached
+// entry is missing or expired
+func (d *dnsCache) lookup(ctx context.Context, host string) ([]string, error) {
+	d.mutex.Lock()
+	entry, exists := d.entries[host]
+	d.mutex.Unlock()
+	if exists && time.Since(entry.resolved) < d.ttl {
+		return entry.addrs, nil
+	}
+
+	addrs, err := d.resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mutex.Lock()
+	d.entries[host] = &dnsEntry{addrs: addrs, resolved: time.Now()}
+	d.mutex.Unlock()
+	return addrs, nil
+}
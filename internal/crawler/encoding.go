@@ -0,0 +1,101 @@
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"ai-search/internal/parser"
+
+	"github.com/andybalholm/brotli"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/transform"
+)
+
+// acceptEncoding lists the content codings the crawler decodes. Setting it
+// explicitly turns off the transport's transparent gzip handling, so the
+// page size limit is applied by the caller to the decoded bytes.
+const acceptEncoding = "gzip, br"
+
+// decodeBody returns a reader of the response body with its
+// Content-Encoding removed
+func decodeBody(resp *Response) (io.Reader, error) {
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	switch encoding {
+	case "", "identity":
+		return resp.Body, nil
+	case "gzip", "x-gzip":
+		reader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode gzip body: %w", err)
+		}
+		return reader, nil
+	case "br":
+		return brotli.NewReader(resp.Body), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding: %s", encoding)
+	}
+}
+
+// sizedBody gives the parser the size of a body read as it was served: its
+// Content-Length, up to maxSize, when it declares one and is not compressed
+func sizedBody(body io.Reader, resp *Response, maxSize int64) io.Reader {
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if encoding != "" && encoding != "identity" {
+		return body
+	}
+	length, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return body
+	}
+	return parser.WithSize(body, min(length, maxSize))
+}
+
+// utf8CheckLen is how much of a body without a declared charset is checked
+// for being UTF-8 before it is taken for windows-1252
+const utf8CheckLen = 64 << 10
+
+// utf8Body transcodes an HTML body to UTF-8. The charset is taken from a
+// byte order mark, the Content-Type header or a <meta> declaration in the
+// first sniffLen bytes, in that order. A body declaring none is kept as
+// UTF-8 unless its first utf8CheckLen bytes are not valid UTF-8, when it
+// is read as windows-1252 as browsers do.
+func utf8Body(body *bufio.Reader, contentType string) (io.Reader, string) {
+	head, _ := body.Peek(sniffLen)
+	encoding, name, certain := charset.DetermineEncoding(head, contentType)
+	if name == "utf-8" {
+		return body, name
+	}
+
+	// Without a declaration, DetermineEncoding falls back to windows-1252
+	// whenever the head is plain ASCII, as it is for pages with a long
+	// <head>. A <meta> declaration always names a charset.
+	if !certain && name == "windows-1252" && !bytes.Contains(bytes.ToLower(head), []byte("charset")) {
+		checked := bufio.NewReaderSize(body, utf8CheckLen)
+		window, _ := checked.Peek(utf8CheckLen)
+		if validUTF8Prefix(window) {
+			return checked, "utf-8"
+		}
+		return transform.NewReader(checked, encoding.NewDecoder()), name
+	}
+	return transform.NewReader(body, encoding.NewDecoder()), name
+}
+
+// validUTF8Prefix reports whether a prefix of a body is valid UTF-8, but for
+// a rune cut off at its end
+func validUTF8Prefix(prefix []byte) bool {
+	for i := len(prefix) - 1; i >= 0 && i > len(prefix)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(prefix[i]) {
+			if !utf8.FullRune(prefix[i:]) {
+				prefix = prefix[:i]
+			}
+			break
+		}
+	}
+	return utf8.Valid(prefix)
+}
@@ -0,0 +1,79 @@
+package crawler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// Fetcher retrieves the pages, robots.txt files and sitemaps a crawl
+// requests. The default fetches them over HTTP; others may serve them from
+// a cache, a WARC archive or memory so that crawls run without network
+// access.
+type Fetcher interface {
+	// Fetch requests a URL, following any redirects. Responses of every
+	// status are returned; an error means there was no response at all.
+	// The caller closes the response body.
+	Fetch(ctx context.Context, url *url.URL) (*Response, error)
+}
+
+// Response is a fetched URL whose body has yet to be read
+type Response struct {
+	// URL served the body once the redirects from the URLs in
+	// RedirectedFrom, in the order they were requested, were followed
+	URL            *url.URL
+	RedirectedFrom []string
+
+	StatusCode int
+	Header     http.Header
+
+	// Body is still encoded as the Content-Encoding header says; gzip and
+	// br are decoded by the crawler
+	Body io.ReadCloser
+}
+
+// httpResponse presents a response to the OnFetch hooks
+func (r *Response) httpResponse() *http.Response {
+	return &http.Response{
+		Status:     http.StatusText(r.StatusCode),
+		StatusCode: r.StatusCode,
+		Header:     r.Header,
+		Body:       r.Body,
+		Request:    &http.Request{Method: "GET", URL: r.URL, Header: make(http.Header)},
+	}
+}
+
+// httpFetcher fetches URLs over HTTP
+type httpFetcher struct {
+	client    *http.Client
+	userAgent string
+}
+
+// NewHTTPFetcher creates a Fetcher sending GET requests with client, for
+// fetchers that fall back to the network to wrap
+func NewHTTPFetcher(client *http.Client, userAgent string) Fetcher {
+	return &httpFetcher{client: client, userAgent: userAgent}
+}
+
+// Fetch requests a URL over HTTP
+func (f *httpFetcher) Fetch(ctx context.Context, target *url.URL) (*Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", f.userAgent)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{
+		URL:            resp.Request.URL,
+		RedirectedFrom: redirectChain(resp),
+		StatusCode:     resp.StatusCode,
+		Header:         resp.Header,
+		Body:           resp.Body,
+	}, nil
+}
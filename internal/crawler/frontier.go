@@ -0,0 +1,97 @@
+package crawler
+
+import (
+	"context"
+	"net/url"
+)
+
+// Frontier persists the crawl frontier so an interrupted crawl can resume
+type Frontier interface {
+	// Add records URLs waiting to be crawled
+	Add(ctx context.Context, urls []FrontierURL) error
+
+	// MarkVisited records that a URL has been crawled
+	MarkVisited(ctx context.Context, rawURL string) error
+
+	// Load returns the pending and visited URLs of a previous run
+	Load(ctx context.Context) (pending []FrontierURL, visited []string, err error)
+
+	// Clear forgets the URLs of previous runs, for a crawl starting over
+	Clear(ctx context.Context) error
+}
+
+// FrontierURL represents a persisted URL waiting to be crawled
+type FrontierURL struct {
+	URL   string `json:"url"`
+	Depth int    `json:"depth"`
+}
+
+// loadFrontier returns the URLs a crawl starts from, restoring pending
+// and visited URLs from the persisted frontier when resuming. A crawl not
+// resuming clears the frontier first, so that a later resume does not take
+// the pages of earlier runs for visited.
+func (c *crawler) loadFrontier(ctx context.Context, startURL *url.URL, visited map[string]bool) []urlWithDepth {
+	seeds := []urlWithDepth{{url: startURL, depth: 0}}
+	if c.config.Frontier == nil {
+		return seeds
+	}
+
+	if c.config.Resume {
+		pending, visitedURLs, err := c.config.Frontier.Load(ctx)
+		if err != nil {
+			c.logger.Errorf("Failed to load crawl frontier, starting over: %v", err)
+		} else if len(pending) > 0 || len(visitedURLs) > 0 {
+			for _, u := range visitedURLs {
+				visited[u] = true
+			}
+
+			seeds = seeds[:0]
+			for _, p := range pending {
+				if parsed, err := url.Parse(p.URL); err == nil {
+					seeds = append(seeds, urlWithDepth{url: parsed, depth: p.Depth})
+				}
+			}
+			c.logger.Infof("Resuming crawl with %d pending and %d visited URLs", len(seeds), len(visitedURLs))
+			return seeds
+		}
+	}
+
+	if !c.config.Resume {
+		if err := c.config.Frontier.Clear(ctx); err != nil {
+			c.logger.Errorf("Failed to clear crawl frontier: %v", err)
+		}
+	}
+	if err := c.config.Frontier.Add(ctx, []FrontierURL{{URL: startURL.String(), Depth: 0}}); err != nil {
+		c.logger.Errorf("Failed to persist start URL: %v", err)
+	}
+
+	return seeds
+}
+
+// persistLinks records newly discovered links in the frontier
+func (c *crawler) persistLinks(ctx context.Context, links []*url.URL, depth int) {
+	if c.config.Frontier == nil || len(links) == 0 {
+		return
+	}
+
+	urls := make([]FrontierURL, 0, len(links))
+	for _, link := range links {
+		urls = append(urls, FrontierURL{URL: link.String(), Depth: depth})
+	}
+
+	if err := c.config.Frontier.Add(ctx, urls); err != nil {
+		c.logger.Errorf("Failed to persist frontier links: %v", err)
+	}
+}
+
+// persistVisited marks a URL as visited in the frontier. URLs interrupted
+// by cancellation stay pending so a resumed crawl retries them.
+func (c *crawler) persistVisited(ctx context.Context, rawURL string) {
+	if c.config.Frontier == nil || ctx.Err() != nil {
+		return
+	}
+
+	if err := c.config.Frontier.MarkVisited(ctx, rawURL); err != nil {
+		c.logger.Errorf("Failed to persist visited URL %s: %v", rawURL, err)
+	}
+}
@@ -0,0 +1,89 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// Reasons a URL is skipped, reported to Hooks.OnSkip
+const (
+	SkipRobots    = "robots"    // Disallowed by robots.txt
+	SkipDuplicate = "duplicate" // Its final or canonical URL was already crawled
+	SkipClaimed   = "claimed"   // Another crawl process took it
+	SkipFiltered  = "filtered"  // Rejected by OnFetch or OnParse
+	SkipLanguage  = "language"  // Not in one of Config.AllowedLanguages
+	SkipCountry   = "country"   // Not targeting one of Config.AllowedCountries
+	SkipDenied    = "denied"    // Matches one of the crawl's deny patterns
+)
+
+// Hooks observe and steer a crawl inline, so applications embedding the
+// crawler can log, filter or enrich pages without wrapping its channels.
+// Embed NopHooks to implement only some of the methods.
+type Hooks interface {
+	// OnFetch is called with every response before its body is read.
+	// Returning an error skips the page.
+	OnFetch(ctx context.Context, pageURL *url.URL, resp *http.Response) error
+
+	// OnParse is called with every parsed page before it is sent and may
+	// change it. Returning an error drops the page; its links are still
+	// followed.
+	OnParse(ctx context.Context, page *Page) error
+
+	// OnError is called when a URL cannot be fetched or parsed
+	OnError(ctx context.Context, pageURL *url.URL, err error)
+
+	// OnSkip is called when a URL is not crawled, with one of the Skip reasons
+	OnSkip(ctx context.Context, pageURL *url.URL, reason string)
+}
+
+// NopHooks implements Hooks with methods that do nothing
+type NopHooks struct{}
+
+func (NopHooks) OnFetch(context.Context, *url.URL, *http.Response) error { return nil }
+func (NopHooks) OnParse(context.Context, *Page) error                    { return nil }
+func (NopHooks) OnError(context.Context, *url.URL, error)                {}
+func (NopHooks) OnSkip(context.Context, *url.URL, string)                {}
+
+// filterError is returned for a page a hook rejected
+type filterError struct {
+	err error
+}
+
+func (e *filterError) Error() string { return "rejected by hook: " + e.err.Error() }
+
+func (e *filterError) Unwrap() error { return e.err }
+
+// onFetch runs the OnFetch hooks in order until one rejects the response
+func (c *crawler) onFetch(ctx context.Context, pageURL *url.URL, resp *http.Response) error {
+	for _, hooks := range c.config.Hooks {
+		if err := hooks.OnFetch(ctx, pageURL, resp); err != nil {
+			return &filterError{err: err}
+		}
+	}
+	return nil
+}
+
+// onParse runs the OnParse hooks in order until one rejects the page
+func (c *crawler) onParse(ctx context.Context, page *Page) error {
+	for _, hooks := range c.config.Hooks {
+		if err := hooks.OnParse(ctx, page); err != nil {
+			return &filterError{err: err}
+		}
+	}
+	return nil
+}
+
+// onError reports a failed URL to every hook
+func (c *crawler) onError(ctx context.Context, pageURL *url.URL, err error) {
+	for _, hooks := range c.config.Hooks {
+		hooks.OnError(ctx, pageURL, err)
+	}
+}
+
+// onSkip reports a skipped URL to every hook
+func (c *crawler) onSkip(ctx context.Context, pageURL *url.URL, reason string) {
+	for _, hooks := range c.config.Hooks {
+		hooks.OnSkip(ctx, pageURL, reason)
+	}
+}
@@ -0,0 +1,82 @@
+package crawler
+
+import "sync"
+
+// hostScheduler caps in-flight requests per host for URLs taken from a
+// shared queue, which the local priority queue cannot hold back. URLs for a
+// host at its limit are parked and handed to the next worker that finishes
+// a request to that host, so workers never sit blocked on a busy host.
+type hostScheduler struct {
+	limit    int
+	mutex    sync.Mutex
+	inFlight map[string]int
+	parked   map[string][]urlWithDepth
+}
+
+// newHostScheduler creates a scheduler allowing limit requests per host,
+// or any number when limit is 0
+func newHostScheduler(limit int) *hostScheduler {
+	return &hostScheduler{
+		limit:    limit,
+		inFlight: make(map[string]int),
+		parked:   make(map[string][]urlWithDepth),
+	}
+}
+
+// acquire takes a slot for the URL's host, or parks the URL and returns
+// false when the host is at its limit
+func (h *hostScheduler) acquire(item urlWithDepth) bool {
+	if h.limit <= 0 {
+		return true
+	}
+
+	host := item.url.Host
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.inFlight[host] >= h.limit {
+		h.parked[host] = append(h.parked[host], item)
+		return false
+	}
+	h.inFlight[host]++
+	return true
+}
+
+// release gives up a slot for the host. If URLs are parked for the host,
+// the slot passes to the next of them, which the caller must process.
+func (h *hostScheduler) release(host string) (urlWithDepth, bool) {
+	if h.limit <= 0 {
+		return urlWithDepth{}, false
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if queue := h.parked[host]; len(queue) > 0 {
+		next := queue[0]
+		if len(queue) == 1 {
+			delete(h.parked, host)
+		} else {
+			h.parked[host] = queue[1:]
+		}
+		return next, true
+	}
+
+	h.inFlight[host]--
+	if h.inFlight[host] <= 0 {
+		delete(h.inFlight, host)
+	}
+	return urlWithDepth{}, false
+}
+
+// parkedCount returns the number of URLs waiting for a host slot
+func (h *hostScheduler) parkedCount() int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	count := 0
+	for _, queue := range h.parked {
+		count += len(queue)
+	}
+	return count
+}
@@ -0,0 +1,129 @@
+package crawler
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"ai-search/internal/metrics"
+)
+
+// politenessWindow is the span requests per minute are measured over
+const politenessWindow = time.Minute
+
+// DomainStats reports the request rate a crawl imposed on one host
+type DomainStats struct {
+	Host          string        `json:"host"`
+	Requests      int64         `json:"requests"`
+	PerMinute     int           `json:"per_minute"`      // Requests in the last minute
+	PeakPerMinute int           `json:"peak_per_minute"` // Most requests in any minute
+	CrawlDelay    time.Duration `json:"crawl_delay,omitempty"`
+}
+
+// hostRequests holds the request history of one host
+type hostRequests struct {
+	stats  DomainStats
+	recent []time.Time // Requests within the window, oldest first
+	warned bool
+}
+
+// politenessTracker counts requests per host over a sliding minute
+type politenessTracker struct {
+	mutex sync.Mutex
+	hosts map[string]*hostRequests
+}
+
+// newPolitenessTracker creates an empty tracker
+func newPolitenessTracker() *politenessTracker {
+	return &politenessTracker{hosts: make(map[string]*hostRequests)}
+}
+
+// host returns the history of a host; the caller holds the lock
+func (t *politenessTracker) host(name string) *hostRequests {
+	h, exists := t.hosts[name]
+	if !exists {
+		h = &hostRequests{stats: DomainStats{Host: name}}
+		t.hosts[name] = h
+	}
+	return h
+}
+
+// prune drops requests that left the window; the caller holds the lock
+func (h *hostRequests) prune(now time.Time) {
+	cutoff := now.Add(-politenessWindow)
+	i := 0
+	for i < len(h.recent) && !h.recent[i].After(cutoff) {
+		i++
+	}
+	h.recent = h.recent[i:]
+	h.stats.PerMinute = len(h.recent)
+}
+
+// record counts a request to a host
+func (t *politenessTracker) record(name string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	h := t.host(name)
+	h.recent = append(h.recent, now)
+	h.prune(now)
+	h.stats.Requests++
+	if h.stats.PerMinute > h.stats.PeakPerMinute {
+		h.stats.PeakPerMinute = h.stats.PerMinute
+	}
+
+	metrics.CrawlDomainRequests.Inc(name)
+	metrics.CrawlDomainRequestRate.Set(float64(h.stats.PerMinute), name)
+}
+
+// setCrawlDelay records a host's robots.txt Crawl-delay. It returns true
+// the first time the delay is longer than interval, the time the crawler
+// leaves between requests to the host.
+func (t *politenessTracker) setCrawlDelay(name string, delay, interval time.Duration) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	h := t.host(name)
+	h.stats.CrawlDelay = delay
+	if delay > 0 && interval < delay && !h.warned {
+		h.warned = true
+		return true
+	}
+	return false
+}
+
+// publish refreshes the per-minute gauges so idle hosts decay to zero
+func (t *politenessTracker) publish() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	for name, h := range t.hosts {
+		h.prune(now)
+		metrics.CrawlDomainRequestRate.Set(float64(h.stats.PerMinute), name)
+	}
+}
+
+// snapshot returns the stats of every host, busiest first
+func (t *politenessTracker) snapshot() []DomainStats {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	stats := make([]DomainStats, 0, len(t.hosts))
+	for _, h := range t.hosts {
+		h.prune(now)
+		if h.stats.Requests > 0 {
+			stats = append(stats, h.stats)
+		}
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Requests != stats[j].Requests {
+			return stats[i].Requests > stats[j].Requests
+		}
+		return stats[i].Host < stats[j].Host
+	})
+	return stats
+}
@@ -0,0 +1,412 @@
+package crawler
+
+import (
+	"container/heap"
+	"context"
+	"math"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// FrontierScorer rates how important a queued URL is to fetch; higher
+// scores are fetched first. URLs are rescored as more links to them are
+// found. Score is called with the frontier locked, so it must be quick.
+type FrontierScorer interface {
+	Score(candidate FrontierCandidate) float64
+}
+
+// FrontierCandidate is a URL waiting in the frontier
+type FrontierCandidate struct {
+	URL     *url.URL
+	Depth   int
+	InLinks int      // Further links to it found while it was queued
+	Seed    *url.URL // Start URL of the crawl
+}
+
+// seedScorer is the default FrontierScorer
+type seedScorer struct{}
+
+// NewSeedScorer creates the default FrontierScorer, which prefers shallow
+// pages, short clean URLs and pages many others link to, and then those
+// closest to the start URL: on its host and under its path
+func NewSeedScorer() FrontierScorer {
+	return seedScorer{}
+}
+
+// Score rates a URL by its shape and its relevance to the seed
+func (seedScorer) Score(candidate FrontierCandidate) float64 {
+	score := scoreURL(urlWithDepth{url: candidate.URL, depth: candidate.Depth}, candidate.InLinks)
+	if candidate.Seed != nil {
+		score += seedRelevance(candidate.URL, candidate.Seed)
+	}
+	return score
+}
+
+// seedRelevance rates how close a URL is to the seed: other hosts lose and
+// every leading path segment shared with the seed's directory gains
+func seedRelevance(u, seed *url.URL) float64 {
+	if !strings.EqualFold(u.Hostname(), seed.Hostname()) {
+		return -5
+	}
+
+	seedDir := seed.EscapedPath()
+	if i := strings.LastIndexByte(seedDir, '/'); i >= 0 {
+		seedDir = seedDir[:i]
+	}
+	dirSegments := strings.Split(strings.Trim(seedDir, "/"), "/")
+	segments := strings.Split(strings.Trim(u.EscapedPath(), "/"), "/")
+	shared := 0
+	for shared < len(dirSegments) && shared < len(segments) && dirSegments[shared] != "" && segments[shared] == dirSegments[shared] {
+		shared++
+	}
+	return 3 * float64(shared)
+}
+
+// lowValueSegments are path segments of pages that rarely hold content
+// worth indexing, such as account pages, listings and print views
+var lowValueSegments = map[string]bool{
+	"login": true, "logout": true, "signin": true, "signup": true, "register": true,
+	"account": true, "cart": true, "checkout": true, "search": true, "tag": true,
+	"tags": true, "category": true, "archive": true, "page": true, "print": true,
+	"share": true, "feed": true, "rss": true, "calendar": true, "comments": true,
+}
+
+// scoreURL rates how important a URL is to fetch; higher scores are fetched
+// first. Shallow pages, short clean URLs and pages many others link to win.
+func scoreURL(item urlWithDepth, inLinks int) float64 {
+	score := -10 * float64(item.depth)
+
+	// Every in-link counts, with diminishing returns
+	score += 5 * math.Log2(float64(1+inLinks))
+
+	path := strings.Trim(item.url.EscapedPath(), "/")
+	score -= float64(len(path)+len(item.url.RawQuery)) / 20
+
+	if path != "" {
+		segments := strings.Split(path, "/")
+		score -= float64(len(segments))
+		for _, segment := range segments {
+			if lowValueSegments[strings.ToLower(segment)] {
+				score -= 5
+				break
+			}
+		}
+	}
+	if item.url.RawQuery != "" {
+		score -= 3
+	}
+
+	return score
+}
+
+// queuedURL is a URL waiting in the priority queue
+type queuedURL struct {
+	item    urlWithDepth
+	key     string
+	host    string
+	inLinks int
+	score   float64
+	level   int    // Depth in breadth-first crawls, ordered before score
+	seq     uint64 // Insertion order, breaking ties first-in first-out
+	index   int
+}
+
+// before reports whether a queued URL is fetched before another: by
+// ascending level and then descending score
+func (u *queuedURL) before(other *queuedURL) bool {
+	if u.level != other.level {
+		return u.level < other.level
+	}
+	if u.score != other.score {
+		return u.score > other.score
+	}
+	return u.seq < other.seq
+}
+
+// urlHeap orders the queued URLs of a host, the next to fetch first
+type urlHeap []*queuedURL
+
+func (h urlHeap) Len() int { return len(h) }
+
+func (h urlHeap) Less(i, j int) bool { return h[i].before(h[j]) }
+
+func (h urlHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *urlHeap) Push(x any) {
+	entry := x.(*queuedURL)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *urlHeap) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return entry
+}
+
+// priorityQueue is the crawl's local URL frontier. URLs discovered again
+// while still queued gain an in-link and keep their shallowest depth
+// instead of being queued twice. A breadth-first queue hands out no URL of
+// a depth until every URL of the depths above it has been processed.
+//
+// URLs are queued by host so that the queue hands out the best URL whose
+// host is below its in-flight limit. URLs of busy hosts wait in the queue,
+// where they keep their order and gain in-links, rather than with the
+// workers.
+type priorityQueue struct {
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	hosts   map[string]*urlHeap // Queued URLs by host
+	size    int
+	queued  map[string]*queuedURL
+	seq     uint64
+	closed  bool
+	handing bool // A popped URL is waiting for a worker
+
+	scorer FrontierScorer
+	seed   *url.URL
+
+	breadthFirst bool
+	active       map[int]int // URLs handed out and not yet finished, by depth
+
+	perHost  int            // URLs of a host handed out at once, 0 for no limit
+	inFlight map[string]int // URLs handed out and not yet released, by host
+}
+
+// newPriorityQueue creates an empty priority queue ordering the URLs of a
+// crawl from seed by scorer, and by depth first when breadthFirst is set.
+// It hands out at most perHost URLs of a host until they are released, any
+// number when perHost is 0.
+func newPriorityQueue(scorer FrontierScorer, seed *url.URL, breadthFirst bool, perHost int) *priorityQueue {
+	q := &priorityQueue{
+		hosts:        make(map[string]*urlHeap),
+		queued:       make(map[string]*queuedURL),
+		scorer:       scorer,
+		seed:         seed,
+		breadthFirst: breadthFirst,
+		active:       make(map[int]int),
+		perHost:      perHost,
+		inFlight:     make(map[string]int),
+	}
+	q.cond = sync.NewCond(&q.mutex)
+	return q
+}
+
+// score rates a queued URL; the caller holds the lock
+func (q *priorityQueue) score(entry *queuedURL) float64 {
+	return q.scorer.Score(FrontierCandidate{
+		URL:     entry.item.url,
+		Depth:   entry.item.depth,
+		InLinks: entry.inLinks,
+		Seed:    q.seed,
+	})
+}
+
+// push queues a URL or records another link to an already queued one
+func (q *priorityQueue) push(item urlWithDepth) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.closed {
+		return
+	}
+
+	key := item.url.String()
+	if entry, exists := q.queued[key]; exists {
+		entry.inLinks++
+		if item.depth < entry.item.depth {
+			entry.item.depth = item.depth
+			if q.breadthFirst {
+				entry.level = item.depth
+			}
+		}
+		entry.score = q.score(entry)
+		heap.Fix(q.hosts[entry.host], entry.index)
+		return
+	}
+
+	q.seq++
+	entry := &queuedURL{item: item, key: key, host: item.url.Host, seq: q.seq}
+	if q.breadthFirst {
+		entry.level = item.depth
+	}
+	entry.score = q.score(entry)
+	hostHeap, ok := q.hosts[entry.host]
+	if !ok {
+		hostHeap = &urlHeap{}
+		q.hosts[entry.host] = hostHeap
+	}
+	heap.Push(hostHeap, entry)
+	q.queued[key] = entry
+	q.size++
+	q.cond.Signal()
+}
+
+// pop removes the highest scoring URL of a host below its limit, waiting
+// until there is one and, in a breadth-first queue, until the shallower
+// URLs queued or handed out are finished. It returns false once the queue
+// is closed.
+func (q *priorityQueue) pop() (urlWithDepth, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	var entry *queuedURL
+	for !q.closed {
+		if entry = q.next(); entry != nil {
+			break
+		}
+		q.cond.Wait()
+	}
+	if q.closed {
+		return urlWithDepth{}, false
+	}
+
+	hostHeap := q.hosts[entry.host]
+	heap.Pop(hostHeap)
+	if hostHeap.Len() == 0 {
+		delete(q.hosts, entry.host)
+	}
+	delete(q.queued, entry.key)
+	q.size--
+	q.handing = true
+	if q.breadthFirst {
+		q.active[entry.item.depth]++
+	}
+	if q.perHost > 0 {
+		q.inFlight[entry.host]++
+	}
+	return entry.item, true
+}
+
+// next returns the URL to hand out next, or nil when every host with URLs
+// queued is at its limit or a breadth-first queue must wait for shallower
+// URLs; the caller holds the lock
+func (q *priorityQueue) next() *queuedURL {
+	var best *queuedURL
+	shallowest := -1
+	for host, hostHeap := range q.hosts {
+		top := (*hostHeap)[0]
+		if shallowest < 0 || top.level < shallowest {
+			shallowest = top.level
+		}
+		if q.perHost > 0 && q.inFlight[host] >= q.perHost {
+			continue
+		}
+		if best == nil || top.before(best) {
+			best = top
+		}
+	}
+	if best == nil || !q.breadthFirst {
+		return best
+	}
+
+	// Shallower URLs of busy hosts go first
+	if best.level > shallowest {
+		return nil
+	}
+	for depth := range q.active {
+		if depth < best.item.depth {
+			return nil
+		}
+	}
+	return best
+}
+
+// finish records that a URL handed out at depth has been processed,
+// releasing the next depth of a breadth-first queue once it is the last
+func (q *priorityQueue) finish(depth int) {
+	if !q.breadthFirst {
+		return
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.active[depth] <= 0 {
+		return
+	}
+	q.active[depth]--
+	if q.active[depth] == 0 {
+		delete(q.active, depth)
+		q.cond.Broadcast()
+	}
+}
+
+// release frees the slot of a host taken by a URL handed out, once the
+// URL has been processed
+func (q *priorityQueue) release(host string) {
+	if q.perHost <= 0 {
+		return
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.inFlight[host] <= 0 {
+		return
+	}
+	q.inFlight[host]--
+	if q.inFlight[host] == 0 {
+		delete(q.inFlight, host)
+	}
+	q.cond.Broadcast()
+}
+
+// len returns the number of queued URLs
+func (q *priorityQueue) len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.size
+}
+
+// pending returns the number of URLs not yet taken by a worker
+func (q *priorityQueue) pending() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.handing {
+		return q.size + 1
+	}
+	return q.size
+}
+
+// setHanding records whether a popped URL is waiting for a worker
+func (q *priorityQueue) setHanding(handing bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.handing = handing
+}
+
+// close wakes waiting pops and discards further pushes
+func (q *priorityQueue) close() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+// dispatch hands URLs to the workers in priority order until the context
+// is cancelled, then closes the queue
+func (q *priorityQueue) dispatch(ctx context.Context, urlChan chan<- urlWithDepth) {
+	go func() {
+		<-ctx.Done()
+		q.close()
+	}()
+
+	for {
+		item, ok := q.pop()
+		if !ok {
+			return
+		}
+		select {
+		case urlChan <- item:
+			q.setHanding(false)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
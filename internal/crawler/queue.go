@@ -0,0 +1,97 @@
+package crawler
+
+import (
+	"context"
+	"net/url"
+	"time"
+)
+
+// Queue is a URL queue and visited set shared by crawl processes, letting
+// several machines work through one large crawl together
+type Queue interface {
+	// Push enqueues URLs that have not been enqueued before
+	Push(ctx context.Context, urls []FrontierURL) error
+
+	// Pop takes the next URL, waiting up to timeout. It returns false
+	// when the queue stayed empty. The URL is handed out again if the
+	// process dies before acknowledging it.
+	Pop(ctx context.Context, timeout time.Duration) (FrontierURL, bool, error)
+
+	// Ack acknowledges a popped URL once it has been processed
+	Ack(ctx context.Context, item FrontierURL) error
+
+	// Claim marks a URL as visited, returning false if it already was
+	Claim(ctx context.Context, rawURL string) (bool, error)
+
+	// Close releases the queue's connections
+	Close() error
+}
+
+// queuePollTimeout bounds how long a pop blocks so cancellation is noticed
+const queuePollTimeout = time.Second
+
+// pumpQueue feeds URLs popped from the shared queue to the local workers
+func (c *crawler) pumpQueue(ctx context.Context, urlChan chan<- urlWithDepth) {
+	for ctx.Err() == nil {
+		item, ok, err := c.config.Queue.Pop(ctx, queuePollTimeout)
+		if err != nil {
+			if ctx.Err() == nil {
+				c.logger.Errorf("Failed to pop from crawl queue: %v", err)
+				time.Sleep(queuePollTimeout)
+			}
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		parsed, err := url.Parse(item.URL)
+		if err != nil {
+			c.ackURL(ctx, &item)
+			continue
+		}
+
+		select {
+		case urlChan <- urlWithDepth{url: parsed, depth: item.Depth, queued: &item}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// ackURL acknowledges a URL taken from the shared queue
+func (c *crawler) ackURL(ctx context.Context, item *FrontierURL) {
+	if c.config.Queue == nil || item == nil {
+		return
+	}
+	if err := c.config.Queue.Ack(ctx, *item); err != nil {
+		c.logger.Errorf("Failed to acknowledge %s: %v", item.URL, err)
+	}
+}
+
+// pushLinks enqueues discovered links in the shared queue
+func (c *crawler) pushLinks(ctx context.Context, links []*url.URL, depth int) {
+	urls := make([]FrontierURL, 0, len(links))
+	for _, link := range links {
+		urls = append(urls, FrontierURL{URL: link.String(), Depth: depth})
+	}
+
+	if err := c.config.Queue.Push(ctx, urls); err != nil {
+		c.logger.Errorf("Failed to push links to crawl queue: %v", err)
+	}
+}
+
+// claimURL reports whether this process should crawl the URL, consulting
+// the shared visited set when a queue is configured
+func (c *crawler) claimURL(ctx context.Context, rawURL string) bool {
+	if c.config.Queue == nil {
+		return true
+	}
+
+	claimed, err := c.config.Queue.Claim(ctx, rawURL)
+	if err != nil {
+		c.logger.Errorf("Failed to claim %s: %v", rawURL, err)
+		return false
+	}
+	return claimed
+}
@@ -0,0 +1,131 @@
+package crawler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// maxIdleLimiters is how many host limiters are kept before those of idle
+// hosts are dropped
+const maxIdleLimiters = 1024
+
+// hostLimiters spaces the requests to each host with a token bucket per
+// host. A host's bucket holds one request, so requests are never sent in
+// bursts, and a robots.txt Crawl-delay slows it further.
+type hostLimiters struct {
+	mutex    sync.Mutex
+	rate     rate.Limit
+	limiters map[string]*hostLimiter
+}
+
+// hostLimiter is the token bucket of one host
+type hostLimiter struct {
+	*rate.Limiter
+	crawlDelay time.Duration
+}
+
+// newHostLimiters creates limiters allowing requestsPerSecond to each host,
+// or any number when it is 0 or less
+func newHostLimiters(requestsPerSecond float64) *hostLimiters {
+	return &hostLimiters{
+		rate:     requestLimit(requestsPerSecond),
+		limiters: make(map[string]*hostLimiter),
+	}
+}
+
+// requestLimit converts requests per second to a limit, infinite when it
+// is 0 or less
+func requestLimit(requestsPerSecond float64) rate.Limit {
+	if requestsPerSecond <= 0 {
+		return rate.Inf
+	}
+	return rate.Limit(requestsPerSecond)
+}
+
+// wait blocks until a request may be sent to host or ctx is done
+func (l *hostLimiters) wait(ctx context.Context, host string) error {
+	l.mutex.Lock()
+	limiter := l.limiter(host)
+	l.mutex.Unlock()
+	return limiter.Wait(ctx)
+}
+
+// limiter returns the bucket of a host; the caller holds the lock
+func (l *hostLimiters) limiter(host string) *hostLimiter {
+	if limiter, exists := l.limiters[host]; exists {
+		return limiter
+	}
+	if len(l.limiters) >= maxIdleLimiters {
+		l.pruneIdle()
+	}
+	limiter := &hostLimiter{Limiter: rate.NewLimiter(l.rate, 1)}
+	l.limiters[host] = limiter
+	return limiter
+}
+
+// pruneIdle drops the buckets that are full, whose hosts have waited out
+// their interval since their last request and so lose nothing; the caller
+// holds the lock
+func (l *hostLimiters) pruneIdle() {
+	now := time.Now()
+	for host, limiter := range l.limiters {
+		if limiter.TokensAt(now) >= 1 {
+			delete(l.limiters, host)
+		}
+	}
+}
+
+// setCrawlDelay spaces the requests to a host at least delay apart
+func (l *hostLimiters) setCrawlDelay(host string, delay time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	limiter := l.limiter(host)
+	if delay == limiter.crawlDelay {
+		return
+	}
+	limiter.crawlDelay = delay
+	limiter.SetLimit(l.hostRate(limiter))
+}
+
+// hostRate is the configured rate, or slower when the host's Crawl-delay
+// asks for it; the caller holds the lock
+func (l *hostLimiters) hostRate(limiter *hostLimiter) rate.Limit {
+	if limiter.crawlDelay <= 0 {
+		return l.rate
+	}
+	return min(l.rate, rate.Every(limiter.crawlDelay))
+}
+
+// setRate changes the requests allowed per second to every host
+func (l *hostLimiters) setRate(requestsPerSecond float64) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.rate = requestLimit(requestsPerSecond)
+	for _, limiter := range l.limiters {
+		limiter.SetLimit(l.hostRate(limiter))
+	}
+}
+
+// interval returns the time between requests to one host at the configured
+// rate, or 0 when it is unlimited
+func (l *hostLimiters) interval() time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.rate == rate.Inf {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / float64(l.rate))
+}
+
+// reset drops every host's bucket once a crawl has finished
+func (l *hostLimiters) reset() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.limiters = make(map[string]*hostLimiter)
+}
@@ -0,0 +1,54 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// redirectChain returns the URLs a response was redirected from, in the
+// order they were requested, ending before the URL that was finally served
+func redirectChain(resp *http.Response) []string {
+	var chain []string
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		chain = append(chain, req.Response.Request.URL.String())
+	}
+
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
+}
+
+// maxRefreshes bounds the meta refresh and script redirects followed from
+// one URL
+const maxRefreshes = 5
+
+// followRefreshes fetches the page an interstitial redirects to by meta
+// refresh or script, so the interstitial is never indexed. Its URL joins
+// the redirect chain of the page it leads to.
+func (c *crawler) followRefreshes(ctx context.Context, page *Page) (*Page, error) {
+	for hops := 0; page.refresh != nil; hops++ {
+		target := page.refresh
+		if hops == maxRefreshes {
+			return nil, fmt.Errorf("more than %d refreshes from %s", maxRefreshes, page.FetchedURL)
+		}
+		if c.config.RespectRobots && !c.canCrawl(ctx, target) {
+			return nil, fmt.Errorf("robots.txt disallows crawling %s, the refresh target of %s", target, page.FetchedURL)
+		}
+		if err := c.rateLimit(ctx, target); err != nil {
+			return nil, err
+		}
+		c.politeness.record(target.Host)
+
+		c.logger.Debugf("Following refresh from %s to %s", page.FetchedURL, target)
+		next, err := c.fetchAndParse(ctx, target)
+		if err != nil {
+			return nil, err
+		}
+		chain := append(page.RedirectedFrom, page.FetchedURL.String())
+		next.RedirectedFrom = append(chain, next.RedirectedFrom...)
+		page = next
+	}
+	return page, nil
+}
@@ -0,0 +1,251 @@
+package crawler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Processes taking part in a crawl keep a heartbeat key alive. A process
+// whose key expired is taken for dead, and the URLs it had taken but not
+// acknowledged are queued again.
+const (
+	consumerTTL       = 30 * time.Second
+	heartbeatInterval = 10 * time.Second
+)
+
+// pushScript enqueues each URL only the first time it is seen.
+// KEYS: seen set, queue list. ARGV: url, payload pairs.
+var pushScript = redis.NewScript(`
+for i = 1, #ARGV, 2 do
+	if redis.call('SADD', KEYS[1], ARGV[i]) == 1 then
+		redis.call('RPUSH', KEYS[2], ARGV[i + 1])
+	end
+end
+return 0
+`)
+
+// joinScript registers a consumer of a crawl, first clearing the crawl's
+// keys when none of its consumers is alive, so that running a finished
+// crawl again starts it over. It returns 1 when the crawl was running.
+// KEYS: consumers set. ARGV: key prefix, consumer ID, TTL in milliseconds.
+var joinScript = redis.NewScript(`
+local consumers = redis.call('SMEMBERS', KEYS[1])
+local running = 0
+for _, id in ipairs(consumers) do
+	if redis.call('EXISTS', ARGV[1] .. 'consumer:' .. id) == 1 then
+		running = 1
+		break
+	end
+end
+if running == 0 then
+	for _, id in ipairs(consumers) do
+		redis.call('DEL', ARGV[1] .. 'processing:' .. id)
+	end
+	redis.call('DEL', KEYS[1], ARGV[1] .. 'queue', ARGV[1] .. 'seen', ARGV[1] .. 'visited')
+end
+redis.call('SADD', KEYS[1], ARGV[2])
+redis.call('SET', ARGV[1] .. 'consumer:' .. ARGV[2], '1', 'PX', ARGV[3])
+return running
+`)
+
+// recoverScript queues again the URLs taken by consumers whose heartbeat
+// expired and forgets those consumers.
+// KEYS: consumers set, queue list. ARGV: key prefix.
+var recoverScript = redis.NewScript(`
+local recovered = 0
+for _, id in ipairs(redis.call('SMEMBERS', KEYS[1])) do
+	if redis.call('EXISTS', ARGV[1] .. 'consumer:' .. id) == 0 then
+		while redis.call('LMOVE', ARGV[1] .. 'processing:' .. id, KEYS[2], 'RIGHT', 'LEFT') do
+			recovered = recovered + 1
+		end
+		redis.call('SREM', KEYS[1], id)
+	end
+end
+return recovered
+`)
+
+// redisQueue implements Queue on top of Redis lists and sets. Popped URLs
+// move to a processing list of the consumer until they are acknowledged.
+type redisQueue struct {
+	client       *redis.Client
+	prefix       string
+	queueKey     string
+	seenKey      string
+	visitedKey   string
+	consumersKey string
+
+	consumer      string
+	heartbeatKey  string
+	processingKey string
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewRedisQueue creates a queue shared by all crawlers using the same
+// Redis URL and crawl ID. A process joins the crawl while any other
+// process of it runs; otherwise the crawl starts over.
+func NewRedisQueue(redisURL, crawlID string) (Queue, error) {
+	options, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+
+	client := redis.NewClient(options)
+	ctx := context.Background()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	consumer, err := consumerID()
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	prefix := "ai-search:crawl:" + crawlID + ":"
+	q := &redisQueue{
+		client:       client,
+		prefix:       prefix,
+		queueKey:     prefix + "queue",
+		seenKey:      prefix + "seen",
+		visitedKey:   prefix + "visited",
+		consumersKey: prefix + "consumers",
+
+		consumer:      consumer,
+		heartbeatKey:  prefix + "consumer:" + consumer,
+		processingKey: prefix + "processing:" + consumer,
+
+		stop: make(chan struct{}),
+	}
+
+	err = joinScript.Run(ctx, client, []string{q.consumersKey}, prefix, consumer, consumerTTL.Milliseconds()).Err()
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to join crawl: %w", err)
+	}
+	go q.heartbeat()
+
+	return q, nil
+}
+
+// consumerID names this process among the consumers of a crawl
+func consumerID() (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("failed to generate consumer ID: %w", err)
+	}
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(suffix)), nil
+}
+
+// heartbeat keeps the consumer alive until the queue is closed
+func (q *redisQueue) heartbeat() {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		case <-ticker.C:
+			q.client.Set(context.Background(), q.heartbeatKey, "1", consumerTTL)
+		}
+	}
+}
+
+// Push enqueues URLs that have not been enqueued before
+func (q *redisQueue) Push(ctx context.Context, urls []FrontierURL) error {
+	if len(urls) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, 0, len(urls)*2)
+	for _, u := range urls {
+		payload, err := json.Marshal(u)
+		if err != nil {
+			return fmt.Errorf("failed to marshal URL: %w", err)
+		}
+		args = append(args, u.URL, payload)
+	}
+
+	if err := pushScript.Run(ctx, q.client, []string{q.seenKey, q.queueKey}, args...).Err(); err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("failed to push URLs: %w", err)
+	}
+	return nil
+}
+
+// Pop moves the next URL to the consumer's processing list, waiting up to
+// timeout. While the queue is empty, the URLs of dead consumers are
+// queued again.
+func (q *redisQueue) Pop(ctx context.Context, timeout time.Duration) (FrontierURL, bool, error) {
+	var item FrontierURL
+
+	payload, err := q.client.BLMove(ctx, q.queueKey, q.processingKey, "LEFT", "RIGHT", timeout).Result()
+	if errors.Is(err, redis.Nil) {
+		err := recoverScript.Run(ctx, q.client, []string{q.consumersKey, q.queueKey}, q.prefix).Err()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return item, false, fmt.Errorf("failed to recover URLs: %w", err)
+		}
+		return item, false, nil
+	}
+	if err != nil {
+		return item, false, fmt.Errorf("failed to pop URL: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(payload), &item); err != nil {
+		return item, false, fmt.Errorf("failed to unmarshal URL: %w", err)
+	}
+	return item, true, nil
+}
+
+// Ack removes a processed URL from the consumer's processing list
+func (q *redisQueue) Ack(ctx context.Context, item FrontierURL) error {
+	// Payloads are marshaled the same way they were pushed
+	payload, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("failed to marshal URL: %w", err)
+	}
+	if err := q.client.LRem(ctx, q.processingKey, 1, payload).Err(); err != nil {
+		return fmt.Errorf("failed to acknowledge URL: %w", err)
+	}
+	return nil
+}
+
+// Claim marks a URL as visited, returning false if it already was
+func (q *redisQueue) Claim(ctx context.Context, rawURL string) (bool, error) {
+	added, err := q.client.SAdd(ctx, q.visitedKey, rawURL).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim URL: %w", err)
+	}
+	return added == 1, nil
+}
+
+// Close queues again the URLs taken but not processed, leaves the crawl
+// and releases the Redis connection
+func (q *redisQueue) Close() error {
+	q.closeOnce.Do(func() {
+		close(q.stop)
+
+		ctx := context.Background()
+		for {
+			err := q.client.LMove(ctx, q.processingKey, q.queueKey, "RIGHT", "LEFT").Err()
+			if err != nil {
+				break
+			}
+		}
+		q.client.SRem(ctx, q.consumersKey, q.consumer)
+		q.client.Del(ctx, q.heartbeatKey)
+	})
+	return q.client.Close()
+}
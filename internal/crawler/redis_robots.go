@@ -0,0 +1,71 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// robotsKeyPrefix namespaces robots.txt rules in Redis; they are shared by
+// every crawl using the same Redis
+const robotsKeyPrefix = "ai-search:robots:"
+
+// redisRobotsStore implements RobotsStore with expiring Redis keys
+type redisRobotsStore struct {
+	client *redis.Client
+}
+
+// NewRedisRobotsStore creates a robots.txt store shared through Redis
+func NewRedisRobotsStore(redisURL string) (RobotsStore, error) {
+	options, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+
+	client := redis.NewClient(options)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	return &redisRobotsStore{client: client}, nil
+}
+
+// Get returns the unexpired rules stored for a host
+func (s *redisRobotsStore) Get(ctx context.Context, host string) (*Robots, bool, error) {
+	data, err := s.client.Get(ctx, robotsKeyPrefix+host).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get robots.txt rules: %w", err)
+	}
+
+	var robots Robots
+	if err := json.Unmarshal(data, &robots); err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal robots.txt rules: %w", err)
+	}
+	return &robots, true, nil
+}
+
+// Set stores the rules for a host until they expire
+func (s *redisRobotsStore) Set(ctx context.Context, host string, robots *Robots, ttl time.Duration) error {
+	data, err := json.Marshal(robots)
+	if err != nil {
+		return fmt.Errorf("failed to marshal robots.txt rules: %w", err)
+	}
+
+	if err := s.client.Set(ctx, robotsKeyPrefix+host, data, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to set robots.txt rules: %w", err)
+	}
+	return nil
+}
+
+// Close releases the Redis connection
+func (s *redisRobotsStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,249 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// renderer renders pages in a shared headless browser so that content
+// produced by JavaScript is visible to the parser
+type renderer struct {
+	userAgent   string
+	browserPath string
+	auths       []DomainAuth
+	proxy       *url.URL
+	timeout     time.Duration
+	settle      time.Duration
+	maxSize     int64
+
+	// allow decides whether the page may load a subresource, waiting for
+	// the host's rate limiter when it may
+	allow func(ctx context.Context, target *url.URL) bool
+
+	once          sync.Once
+	browserCtx    context.Context
+	cancelBrowser context.CancelFunc
+	startErr      error
+}
+
+// newRenderer creates a renderer; the browser is started on first use.
+// The browser goes through the first of the proxies, as one browser
+// cannot rotate them per request.
+func newRenderer(config Config, proxies []*url.URL, allow func(context.Context, *url.URL) bool) *renderer {
+	r := &renderer{
+		userAgent:   config.UserAgent,
+		browserPath: config.BrowserPath,
+		auths:       config.Auth,
+		timeout:     time.Duration(config.RenderTimeout) * time.Second,
+		settle:      time.Duration(config.RenderSettle) * time.Millisecond,
+		maxSize:     config.MaxPageSize,
+		allow:       allow,
+	}
+	if len(proxies) > 0 {
+		r.proxy = proxies[0]
+	}
+	return r
+}
+
+// start launches the headless browser
+func (r *renderer) start() {
+	options := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.UserAgent(r.userAgent),
+		chromedp.Flag("blink-settings", "imagesEnabled=false"),
+	)
+	if r.browserPath != "" {
+		options = append(options, chromedp.ExecPath(r.browserPath))
+	}
+	if r.proxy != nil {
+		// Chrome takes no credentials in the flag; proxy auth challenges
+		// are answered by answerAuth
+		server := &url.URL{Scheme: r.proxy.Scheme, Host: r.proxy.Host}
+		options = append(options, chromedp.ProxyServer(server.String()))
+	}
+
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), options...)
+	browserCtx, cancelBrowser := chromedp.NewContext(allocCtx)
+
+	// Running an empty task list launches the browser
+	if err := chromedp.Run(browserCtx); err != nil {
+		cancelBrowser()
+		cancelAlloc()
+		r.startErr = fmt.Errorf("failed to start headless browser: %w", err)
+		return
+	}
+
+	r.browserCtx = browserCtx
+	r.cancelBrowser = func() {
+		cancelBrowser()
+		cancelAlloc()
+	}
+}
+
+// Render loads the URL in a new tab and returns the rendered DOM as HTML,
+// truncated to MaxPageSize as fetched pages are
+func (r *renderer) Render(ctx context.Context, targetURL *url.URL) (string, error) {
+	r.once.Do(r.start)
+	if r.startErr != nil {
+		return "", r.startErr
+	}
+
+	tabCtx, cancelTab := chromedp.NewContext(r.browserCtx)
+	defer cancelTab()
+	tabCtx, cancelTimeout := context.WithTimeout(tabCtx, r.timeout)
+	defer cancelTimeout()
+
+	// Close the tab early when the crawl is cancelled
+	stop := context.AfterFunc(ctx, cancelTab)
+	defer stop()
+
+	var html string
+	err := chromedp.Run(tabCtx,
+		r.intercept(targetURL),
+		chromedp.Navigate(targetURL.String()),
+		chromedp.WaitReady("body", chromedp.ByQuery),
+		chromedp.Sleep(r.settle),
+		chromedp.OuterHTML("html", &html, chromedp.ByQuery),
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", targetURL, err)
+	}
+
+	if r.maxSize > 0 && int64(len(html)) > r.maxSize {
+		// Cut at a rune boundary so the DOM stays valid UTF-8
+		end := int(r.maxSize)
+		for end > 0 && !utf8.RuneStart(html[end]) {
+			end--
+		}
+		html = html[:end]
+	}
+	return html, nil
+}
+
+// intercept pauses every request of the tab. The scripts, stylesheets,
+// frames and XHRs the page loads are held to robots.txt and the rate
+// limiter as fetched pages are, and each request gets the headers of its
+// own host only, as authTransport adds them. Basic auth challenges are
+// answered for matching hosts alone; other hosts go without, as do plain
+// HTTP requests unless the domain allows them. Cookies are set for the
+// target's domain.
+func (r *renderer) intercept(targetURL *url.URL) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		chromedp.ListenTarget(ctx, func(ev interface{}) {
+			switch ev := ev.(type) {
+			case *fetch.EventRequestPaused:
+				go r.continueRequest(ctx, targetURL, ev)
+			case *fetch.EventAuthRequired:
+				go r.answerAuth(ctx, ev)
+			}
+		})
+		if err := fetch.Enable().WithHandleAuthRequests(true).Do(ctx); err != nil {
+			return err
+		}
+
+		auth := authForURL(r.auths, targetURL)
+		if auth == nil {
+			return nil
+		}
+		for name, value := range auth.Cookies {
+			// Secure cookies stay off plain HTTP requests to the domain
+			err := network.SetCookie(name, value).
+				WithDomain(targetURL.Hostname()).
+				WithPath("/").
+				WithSecure(!auth.AllowHTTP).
+				Do(ctx)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// continueRequest lets a paused request go on, with the headers of its
+// host's credentials added, or fails it when the crawler does not allow
+// it. The page itself was checked before it was fetched.
+func (r *renderer) continueRequest(ctx context.Context, targetURL *url.URL, ev *fetch.EventRequestPaused) {
+	requestURL, err := url.Parse(ev.Request.URL)
+	if err == nil && r.allow != nil && (requestURL.Scheme == "http" || requestURL.Scheme == "https") &&
+		!isPageRequest(ev, requestURL, targetURL) && !r.allow(ctx, requestURL) {
+		_ = fetch.FailRequest(ev.RequestID, network.ErrorReasonBlockedByClient).Do(ctx)
+		return
+	}
+
+	params := fetch.ContinueRequest(ev.RequestID)
+	if auth := requestAuth(r.auths, ev.Request.URL); auth != nil && len(auth.Headers) > 0 {
+		var headers []*fetch.HeaderEntry
+		for name, value := range ev.Request.Headers {
+			if _, ok := auth.Headers[name]; !ok {
+				headers = append(headers, &fetch.HeaderEntry{Name: name, Value: fmt.Sprint(value)})
+			}
+		}
+		for name, value := range auth.Headers {
+			headers = append(headers, &fetch.HeaderEntry{Name: name, Value: value})
+		}
+		params = params.WithHeaders(headers)
+	}
+	// Fails only once the tab is closed
+	_ = params.Do(ctx)
+}
+
+// isPageRequest reports whether a paused request loads the rendered page
+// itself
+func isPageRequest(ev *fetch.EventRequestPaused, requestURL, targetURL *url.URL) bool {
+	return ev.ResourceType == network.ResourceTypeDocument &&
+		requestURL.Scheme == targetURL.Scheme &&
+		requestURL.Host == targetURL.Host &&
+		requestURL.RequestURI() == targetURL.RequestURI()
+}
+
+// answerAuth answers a basic auth challenge with the credentials of the
+// request's host, leaving other hosts' challenges unanswered. Proxy
+// challenges get the credentials of the proxy URL.
+func (r *renderer) answerAuth(ctx context.Context, ev *fetch.EventAuthRequired) {
+	response := &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseCancelAuth}
+	if ev.AuthChallenge != nil && ev.AuthChallenge.Source == fetch.AuthChallengeSourceProxy {
+		if r.proxy != nil && r.proxy.User != nil {
+			password, _ := r.proxy.User.Password()
+			response = &fetch.AuthChallengeResponse{
+				Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+				Username: r.proxy.User.Username(),
+				Password: password,
+			}
+		}
+	} else if auth := requestAuth(r.auths, ev.Request.URL); auth != nil && (auth.Username != "" || auth.Password != "") {
+		response = &fetch.AuthChallengeResponse{
+			Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+			Username: auth.Username,
+			Password: auth.Password,
+		}
+	}
+	_ = fetch.ContinueWithAuth(ev.RequestID, response).Do(ctx)
+}
+
+// requestAuth returns the credentials to send with a browser request, or
+// nil
+func requestAuth(auths []DomainAuth, rawURL string) *DomainAuth {
+	requestURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	return authForURL(auths, requestURL)
+}
+
+// Close shuts down the browser if it was started; a later Render
+// starts a new one
+func (r *renderer) Close() {
+	if r.cancelBrowser != nil {
+		r.cancelBrowser()
+	}
+	r.once = sync.Once{}
+	r.browserCtx, r.cancelBrowser, r.startErr = nil, nil, nil
+}
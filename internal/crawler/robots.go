@@ -2,138 +2,457 @@ package crawler
 
 import (
 	"bufio"
+	"container/list"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"ai-search/internal/metrics"
 )
 
 // Robots represents a robots.txt file
 type Robots struct {
-	UserAgent  string
-	Disallow   []string
-	CrawlDelay time.Duration
+	UserAgent  string        `json:"user_agent"`
+	Allow      []string      `json:"allow,omitempty"`
+	Disallow   []string      `json:"disallow"`
+	CrawlDelay time.Duration `json:"crawl_delay"`
+
+	// Expires is when the rules must be fetched again, so that copies of
+	// them kept in memory expire with the one in the RobotsStore
+	Expires time.Time `json:"expires,omitempty"`
 }
 
-// RobotsCache caches robots.txt files per domain
+// defaultRobotsTTL is how long robots.txt rules are reused before being fetched again
+const defaultRobotsTTL = 24 * time.Hour
+
+// robotsErrorTTL is how long an unreachable robots.txt allows crawling
+// before it is tried again
+const robotsErrorTTL = 10 * time.Minute
+
+// robotsMinTTL is the shortest time rules are reused, even when robots.txt
+// asks not to be cached, so that it is not fetched for every URL
+const robotsMinTTL = time.Minute
+
+// defaultRobotsCacheSize is how many hosts' rules are kept in memory
+const defaultRobotsCacheSize = 10000
+
+// RobotsStore persists robots.txt rules so that restarts and other crawl
+// processes reuse them instead of fetching robots.txt again
+type RobotsStore interface {
+	// Get returns the unexpired rules stored for a host
+	Get(ctx context.Context, host string) (*Robots, bool, error)
+
+	// Set stores the rules for a host until they expire
+	Set(ctx context.Context, host string, robots *Robots, ttl time.Duration) error
+
+	// Close releases the store's connections
+	Close() error
+}
+
+// robotsEntry is a cached robots.txt with its expiry
+type robotsEntry struct {
+	domain  string
+	robots  *Robots
+	expires time.Time
+}
+
+// RobotsCacheStats reports how robots.txt lookups were served
+type RobotsCacheStats struct {
+	Entries   int   `json:"entries"`
+	Hits      int64 `json:"hits"`       // Served from memory
+	StoreHits int64 `json:"store_hits"` // Served from the persistent store
+	Fetches   int64 `json:"fetches"`    // Fetched from the host
+	Evictions int64 `json:"evictions"`  // Least recently used hosts dropped to stay within the size bound
+}
+
+// RobotsCache caches robots.txt files per domain, in memory and optionally
+// in a persistent store shared with other crawl processes. Rules are kept
+// for as long as robots.txt's Cache-Control or Expires headers allow, up to
+// the cache's TTL, and the least recently used hosts are dropped once
+// maxEntries are cached.
 type RobotsCache struct {
-	cache map[string]*Robots
-	mutex sync.RWMutex
+	cache      map[string]*list.Element // Of *robotsEntry, most recently used first
+	lru        *list.List
+	mutex      sync.Mutex
+	store      RobotsStore
+	ttl        time.Duration
+	maxEntries int
+
+	hits      int64
+	storeHits int64
+	fetches   int64
+	evictions int64
 }
 
-// NewRobotsCache creates a new robots cache
-func NewRobotsCache() *RobotsCache {
+// NewRobotsCache creates a new robots cache keeping rules for at most ttl
+// (24 hours by default) and for at most maxEntries hosts (10000 by
+// default), backed by store unless it is nil
+func NewRobotsCache(store RobotsStore, ttl time.Duration, maxEntries int) *RobotsCache {
+	if ttl <= 0 {
+		ttl = defaultRobotsTTL
+	}
+	if maxEntries <= 0 {
+		maxEntries = defaultRobotsCacheSize
+	}
 	return &RobotsCache{
-		cache: make(map[string]*Robots),
+		cache:      make(map[string]*list.Element),
+		lru:        list.New(),
+		store:      store,
+		ttl:        ttl,
+		maxEntries: maxEntries,
 	}
 }
 
 // GetRobots retrieves robots.txt for a domain
-func (rc *RobotsCache) GetRobots(client *http.Client, domain string, userAgent string) (*Robots, error) {
-	rc.mutex.RLock()
-	if robots, exists := rc.cache[domain]; exists {
-		rc.mutex.RUnlock()
+func (rc *RobotsCache) GetRobots(ctx context.Context, fetcher Fetcher, domain string, userAgent string) (*Robots, error) {
+	if robots, ok := rc.get(domain, userAgent); ok {
+		atomic.AddInt64(&rc.hits, 1)
+		metrics.RobotsCacheLookups.Inc("memory")
 		return robots, nil
 	}
-	rc.mutex.RUnlock()
 
-	// Fetch robots.txt
-	robotsURL := fmt.Sprintf("https://%s/robots.txt", domain)
-	resp, err := client.Get(robotsURL)
-	if err != nil {
-		// If robots.txt is not accessible, allow crawling
-		return &Robots{
-			UserAgent:  userAgent,
-			Disallow:   []string{},
-			CrawlDelay: 0,
-		}, nil
+	// Another process or an earlier run may have fetched it already
+	if rc.store != nil {
+		robots, found, err := rc.store.Get(ctx, domain)
+		if err == nil && found && robots.UserAgent == userAgent {
+			atomic.AddInt64(&rc.storeHits, 1)
+			metrics.RobotsCacheLookups.Inc("store")
+			// Rules stored before they carried their expiry get a full TTL
+			expires := robots.Expires
+			if expires.IsZero() {
+				expires = time.Now().Add(rc.ttl)
+			}
+			rc.put(domain, robots, expires)
+			return robots, nil
+		}
 	}
-	defer resp.Body.Close()
 
-	// Parse robots.txt
-	robots, err := parseRobotsTxt(resp.Body, userAgent)
-	if err != nil {
-		// If parsing fails, allow crawling
-		return &Robots{
+	atomic.AddInt64(&rc.fetches, 1)
+	metrics.RobotsCacheLookups.Inc("fetch")
+	robots, header := fetchRobots(ctx, fetcher, domain, userAgent)
+	ttl := robotsTTL(header, rc.ttl)
+	if robots == nil {
+		// If robots.txt is not accessible, allow crawling for a while
+		robots = &Robots{
 			UserAgent:  userAgent,
 			Disallow:   []string{},
 			CrawlDelay: 0,
-		}, nil
+		}
+		ttl = robotsErrorTTL
 	}
+	robots.Expires = time.Now().Add(ttl)
 
 	// Cache the result
+	rc.put(domain, robots, robots.Expires)
+	if rc.store != nil {
+		if err := rc.store.Set(ctx, domain, robots, ttl); err != nil {
+			return robots, fmt.Errorf("failed to persist robots.txt: %w", err)
+		}
+	}
+
+	return robots, nil
+}
+
+// get returns the unexpired rules cached in memory for a domain, marking
+// them recently used. Expired rules are dropped.
+func (rc *RobotsCache) get(domain string, userAgent string) (*Robots, bool) {
 	rc.mutex.Lock()
-	rc.cache[domain] = robots
+	defer rc.mutex.Unlock()
+
+	element, exists := rc.cache[domain]
+	if !exists {
+		return nil, false
+	}
+	entry := element.Value.(*robotsEntry)
+	if !time.Now().Before(entry.expires) {
+		rc.remove(element)
+		return nil, false
+	}
+	if entry.robots.UserAgent != userAgent {
+		return nil, false
+	}
+	rc.lru.MoveToFront(element)
+	return entry.robots, true
+}
+
+// put caches rules in memory until they expire, dropping the least
+// recently used hosts beyond the size bound
+func (rc *RobotsCache) put(domain string, robots *Robots, expires time.Time) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	if element, exists := rc.cache[domain]; exists {
+		element.Value = &robotsEntry{domain: domain, robots: robots, expires: expires}
+		rc.lru.MoveToFront(element)
+	} else {
+		rc.cache[domain] = rc.lru.PushFront(&robotsEntry{domain: domain, robots: robots, expires: expires})
+	}
+
+	for rc.lru.Len() > rc.maxEntries {
+		rc.remove(rc.lru.Back())
+		atomic.AddInt64(&rc.evictions, 1)
+		metrics.RobotsCacheEvictions.Inc()
+	}
+	metrics.RobotsCacheEntries.Set(float64(len(rc.cache)))
+}
+
+// remove drops a cached host; the mutex must be held
+func (rc *RobotsCache) remove(element *list.Element) {
+	rc.lru.Remove(element)
+	delete(rc.cache, element.Value.(*robotsEntry).domain)
+}
+
+// Stats returns the cache's lookup counts
+func (rc *RobotsCache) Stats() RobotsCacheStats {
+	rc.mutex.Lock()
+	entries := len(rc.cache)
 	rc.mutex.Unlock()
 
-	return robots, nil
+	return RobotsCacheStats{
+		Entries:   entries,
+		Hits:      atomic.LoadInt64(&rc.hits),
+		StoreHits: atomic.LoadInt64(&rc.storeHits),
+		Fetches:   atomic.LoadInt64(&rc.fetches),
+		Evictions: atomic.LoadInt64(&rc.evictions),
+	}
 }
 
-// parseRobotsTxt parses a robots.txt file
-func parseRobotsTxt(body io.Reader, userAgent string) (*Robots, error) {
-	robots := &Robots{
-		UserAgent:  userAgent,
-		Disallow:   []string{},
-		CrawlDelay: 0,
+// fetchRobots fetches and parses robots.txt, returning nil rules when it
+// cannot, and the response's headers when there was one
+func fetchRobots(ctx context.Context, fetcher Fetcher, domain string, userAgent string) (*Robots, http.Header) {
+	robotsURL := &url.URL{Scheme: "https", Host: domain, Path: "/robots.txt"}
+	resp, err := fetcher.Fetch(ctx, robotsURL)
+	if err != nil {
+		return nil, nil
 	}
+	defer resp.Body.Close()
 
-	scanner := bufio.NewScanner(body)
-	var currentUserAgent string
-	var inUserAgentSection bool
+	body, err := decodeBody(resp)
+	if err != nil {
+		return nil, resp.Header
+	}
+
+	// Parse robots.txt
+	robots, err := parseRobotsTxt(body, userAgent)
+	if err != nil {
+		return nil, resp.Header
+	}
+	return robots, resp.Header
+}
+
+// robotsTTL returns how long the rules of a robots.txt response may be
+// reused according to its Cache-Control max-age, less its Age, or its
+// Expires header, within robotsMinTTL and maxTTL. no-store and no-cache
+// get robotsMinTTL; without caching headers the rules are kept for maxTTL,
+// as RFC 9309 allows for up to 24 hours.
+func robotsTTL(header http.Header, maxTTL time.Duration) time.Duration {
+	if header == nil {
+		return maxTTL
+	}
 
+	ttl, found := time.Duration(0), false
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store", "no-cache":
+			return robotsMinTTL
+		case "max-age":
+			if seconds, err := strconv.ParseInt(strings.Trim(value, "\""), 10, 64); err == nil {
+				ttl, found = time.Duration(seconds)*time.Second, true
+			}
+		}
+	}
+	if found {
+		if age, err := strconv.ParseInt(header.Get("Age"), 10, 64); err == nil && age > 0 {
+			ttl -= time.Duration(age) * time.Second
+		}
+	} else if expires := header.Get("Expires"); expires != "" {
+		// An invalid Expires, such as 0, means already expired
+		expiresAt, err := http.ParseTime(expires)
+		if err != nil {
+			return robotsMinTTL
+		}
+		date, err := http.ParseTime(header.Get("Date"))
+		if err != nil {
+			date = time.Now()
+		}
+		ttl, found = expiresAt.Sub(date), true
+	}
+
+	if !found {
+		return maxTTL
+	}
+	return min(max(ttl, robotsMinTTL), maxTTL)
+}
+
+// robotsGroup holds the rules of the robots.txt groups applying to one
+// user agent
+type robotsGroup struct {
+	allow      []string
+	disallow   []string
+	crawlDelay time.Duration
+}
+
+// parseRobotsTxt parses the rules of a robots.txt file that apply to
+// userAgent. As in RFC 9309, the groups naming the crawler's product token
+// are used and the * groups only when there are none; consecutive
+// User-agent lines share the group that follows them.
+func parseRobotsTxt(body io.Reader, userAgent string) (*Robots, error) {
+	token := userAgentToken(userAgent)
+
+	var specific, wildcard robotsGroup
+	var foundSpecific bool
+	var inSpecific, inWildcard, readingAgents bool
+
+	scanner := bufio.NewScanner(body)
 	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+		line := scanner.Text()
+		if comment := strings.IndexByte(line, '#'); comment >= 0 {
+			line = line[:comment]
+		}
 
-		// Skip empty lines and comments
-		if line == "" || strings.HasPrefix(line, "#") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
 			continue
 		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
 
 		// Parse User-agent directive
-		if strings.HasPrefix(strings.ToLower(line), "user-agent:") {
-			currentUserAgent = strings.TrimSpace(line[11:])
-			inUserAgentSection = (currentUserAgent == "*" || currentUserAgent == userAgent)
+		if key == "user-agent" {
+			if !readingAgents {
+				inSpecific, inWildcard = false, false
+			}
+			readingAgents = true
+
+			if value == "*" {
+				inWildcard = true
+			} else if strings.EqualFold(value, token) || value == userAgent {
+				inSpecific = true
+				foundSpecific = true
+			}
 			continue
 		}
+		readingAgents = false
 
 		// Only process directives for our user agent
-		if !inUserAgentSection {
+		var group *robotsGroup
+		switch {
+		case inSpecific:
+			group = &specific
+		case inWildcard:
+			group = &wildcard
+		default:
 			continue
 		}
 
-		// Parse Disallow directive
-		if strings.HasPrefix(strings.ToLower(line), "disallow:") {
-			path := strings.TrimSpace(line[9:])
-			if path != "" {
-				robots.Disallow = append(robots.Disallow, path)
+		switch key {
+		case "allow":
+			if value != "" {
+				group.allow = append(group.allow, value)
 			}
-			continue
-		}
-
-		// Parse Crawl-delay directive
-		if strings.HasPrefix(strings.ToLower(line), "crawl-delay:") {
-			delayStr := strings.TrimSpace(line[12:])
-			if delay, err := strconv.Atoi(delayStr); err == nil {
-				robots.CrawlDelay = time.Duration(delay) * time.Second
+		case "disallow":
+			// An empty Disallow allows everything
+			if value != "" {
+				group.disallow = append(group.disallow, value)
+			}
+		case "crawl-delay":
+			if delay, err := strconv.ParseFloat(value, 64); err == nil && delay >= 0 {
+				group.crawlDelay = time.Duration(delay * float64(time.Second))
 			}
-			continue
 		}
 	}
 
+	group := wildcard
+	if foundSpecific {
+		group = specific
+	}
+
+	robots := &Robots{
+		UserAgent:  userAgent,
+		Allow:      group.allow,
+		Disallow:   group.disallow,
+		CrawlDelay: group.crawlDelay,
+	}
+	if robots.Disallow == nil {
+		robots.Disallow = []string{}
+	}
 	return robots, scanner.Err()
 }
 
-// CanCrawl checks if a URL can be crawled according to robots.txt
+// userAgentToken returns the product token of a User-Agent header, e.g.
+// "AISearchBot" for "AISearchBot/1.0 (+https://example.com/bot)"
+func userAgentToken(userAgent string) string {
+	token, _, _ := strings.Cut(userAgent, "/")
+	token, _, _ = strings.Cut(token, " ")
+	return token
+}
+
+// CanCrawl checks if a URL can be crawled according to robots.txt. urlPath
+// is the path with its query string. The longest matching rule decides,
+// with Allow winning a tie; /robots.txt itself is always allowed.
 func (r *Robots) CanCrawl(urlPath string) bool {
-	for _, disallowPath := range r.Disallow {
-		if strings.HasPrefix(urlPath, disallowPath) {
+	if urlPath == "" {
+		urlPath = "/"
+	}
+	if urlPath == "/robots.txt" {
+		return true
+	}
+
+	allowed, longest := true, -1
+	for _, pattern := range r.Disallow {
+		if len(pattern) > longest && robotsMatch(pattern, urlPath) {
+			allowed, longest = false, len(pattern)
+		}
+	}
+	for _, pattern := range r.Allow {
+		if len(pattern) >= longest && robotsMatch(pattern, urlPath) {
+			allowed, longest = true, len(pattern)
+		}
+	}
+	return allowed
+}
+
+// robotsMatch reports whether a robots.txt path pattern matches urlPath.
+// Patterns match path prefixes; * matches any run of characters and a
+// trailing $ anchors the pattern to the end of the path.
+func robotsMatch(pattern, urlPath string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(urlPath, parts[0]) {
+		return false
+	}
+	rest := urlPath[len(parts[0]):]
+	if len(parts) == 1 {
+		return !anchored || rest == ""
+	}
+
+	// Matching each literal part as early as possible never prevents a
+	// match of the parts after it
+	for _, part := range parts[1 : len(parts)-1] {
+		index := strings.Index(rest, part)
+		if index < 0 {
 			return false
 		}
+		rest = rest[index+len(part):]
+	}
+
+	last := parts[len(parts)-1]
+	if anchored {
+		return strings.HasSuffix(rest, last)
 	}
-	return true
+	return strings.Contains(rest, last)
 }
 
 // GetCrawlDelay returns the crawl delay for this robots.txt
@@ -0,0 +1,184 @@
+package crawler
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxSitemapDepth bounds how many sitemap index levels are followed
+const maxSitemapDepth = 3
+
+// maxSitemapSize is the most a sitemap may take decompressed, the 50MB the
+// protocol allows
+const maxSitemapSize = 50 * 1024 * 1024
+
+// sitemapDocument covers both <urlset> sitemaps and <sitemapindex> files
+type sitemapDocument struct {
+	XMLName  xml.Name
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+// sitemapLoc is a <url> or <sitemap> entry
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+// seedFromSitemaps enqueues the pages listed in the start host's sitemaps
+func (c *crawler) seedFromSitemaps(ctx context.Context, startURL *url.URL, frontier *priorityQueue) {
+	sitemaps := c.discoverSitemaps(ctx, startURL)
+	pages := c.sitemapURLs(ctx, sitemaps, c.config.MaxSitemapURLs)
+	c.logger.Infof("Seeding %d URLs from %d sitemap(s)", len(pages), len(sitemaps))
+
+	for _, page := range pages {
+		// The sitemap protocol only allows URLs on the sitemap's own host
+		if !strings.EqualFold(page.Hostname(), startURL.Hostname()) {
+			continue
+		}
+
+		// Sitemap entries are treated as links from the start page
+		frontier.push(urlWithDepth{url: page, depth: 1})
+	}
+}
+
+// discoverSitemaps returns sitemap URLs declared in robots.txt, falling
+// back to /sitemap.xml on the start URL's host
+func (c *crawler) discoverSitemaps(ctx context.Context, startURL *url.URL) []string {
+	var sitemaps []string
+
+	robotsURL := &url.URL{Scheme: startURL.Scheme, Host: startURL.Host, Path: "/robots.txt"}
+	if resp, err := c.fetcher.Fetch(ctx, robotsURL); err == nil {
+		if body, err := decodeBody(resp); err == nil && resp.StatusCode == http.StatusOK {
+			scanner := bufio.NewScanner(io.LimitReader(body, c.config.MaxPageSize))
+			for scanner.Scan() {
+				line := strings.TrimSpace(scanner.Text())
+				if strings.HasPrefix(strings.ToLower(line), "sitemap:") {
+					sitemaps = append(sitemaps, strings.TrimSpace(line[len("sitemap:"):]))
+				}
+			}
+		}
+		resp.Body.Close()
+	}
+
+	if len(sitemaps) == 0 {
+		sitemaps = append(sitemaps, fmt.Sprintf("%s://%s/sitemap.xml", startURL.Scheme, startURL.Host))
+	}
+
+	return sitemaps
+}
+
+// sitemapURLs fetches the given sitemaps, following sitemap index files,
+// and returns up to limit normalized page URLs
+func (c *crawler) sitemapURLs(ctx context.Context, sitemaps []string, limit int) []*url.URL {
+	var pages []*url.URL
+	seen := make(map[string]bool)
+
+	var walk func(sitemapURL string, level int)
+	walk = func(sitemapURL string, level int) {
+		if level > maxSitemapDepth || len(pages) >= limit || seen[sitemapURL] || ctx.Err() != nil {
+			return
+		}
+		seen[sitemapURL] = true
+
+		doc, err := c.fetchSitemap(ctx, sitemapURL)
+		if err != nil {
+			c.logger.Debugf("Failed to fetch sitemap %s: %v", sitemapURL, err)
+			return
+		}
+
+		// Like page entries, an index only lists sitemaps on its own host
+		for _, entry := range doc.Sitemaps {
+			loc := strings.TrimSpace(entry.Loc)
+			if !sameHost(loc, sitemapURL) {
+				c.logger.Debugf("Skipping sitemap %s listed by %s on another host", loc, sitemapURL)
+				continue
+			}
+			walk(loc, level+1)
+		}
+
+		for _, entry := range doc.URLs {
+			if len(pages) >= limit {
+				return
+			}
+			normalized, err := c.normalizer.Normalize(strings.TrimSpace(entry.Loc), nil)
+			if err != nil || !c.normalizer.IsValid(normalized) {
+				continue
+			}
+			pages = append(pages, normalized)
+		}
+	}
+
+	for _, sitemapURL := range sitemaps {
+		walk(sitemapURL, 0)
+	}
+
+	return pages
+}
+
+// sameHost reports whether two URLs are on the same host
+func sameHost(a, b string) bool {
+	urlA, errA := url.Parse(a)
+	urlB, errB := url.Parse(b)
+	return errA == nil && errB == nil && strings.EqualFold(urlA.Hostname(), urlB.Hostname())
+}
+
+// fetchSitemap downloads and decodes a sitemap, transparently handling
+// gzip. Sitemaps are fetched like pages, honouring robots.txt and the
+// host's rate limit.
+func (c *crawler) fetchSitemap(ctx context.Context, sitemapURL string) (*sitemapDocument, error) {
+	target, err := url.Parse(sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+	if c.config.RespectRobots && !c.canCrawl(ctx, target) {
+		return nil, fmt.Errorf("robots.txt disallows crawling %s", target)
+	}
+	if err := c.rateLimit(ctx, target); err != nil {
+		return nil, err
+	}
+	c.politeness.record(target.Host)
+
+	resp, err := c.fetcher.Fetch(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	decoded, err := decodeBody(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sitemaps may be larger than pages, up to the 50MB the protocol
+	// allows, which bounds them once decompressed too
+	body := bufio.NewReader(io.LimitReader(decoded, maxSitemapSize))
+
+	// Detect gzip by magic bytes since servers label .xml.gz inconsistently
+	var reader io.Reader = body
+	if magic, err := body.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip sitemap: %w", err)
+		}
+		defer gz.Close()
+		reader = io.LimitReader(gz, maxSitemapSize)
+	}
+
+	var doc sitemapDocument
+	if err := xml.NewDecoder(reader).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid sitemap XML: %w", err)
+	}
+
+	return &doc, nil
+}
@@ -0,0 +1,74 @@
+package crawler
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"ai-search/internal/parser"
+)
+
+// sniffLen is the number of leading body bytes inspected when sniffing
+const sniffLen = 1024
+
+// htmlMarkers are tags whose presence near the start of a body marks it as HTML
+var htmlMarkers = [][]byte{
+	[]byte("<!doctype html"),
+	[]byte("<html"),
+	[]byte("<head"),
+	[]byte("<body"),
+	[]byte("<title"),
+	[]byte("<meta"),
+}
+
+// isHTMLContentType reports whether a declared Content-Type is HTML
+func isHTMLContentType(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	return strings.Contains(contentType, "text/html") || strings.Contains(contentType, "application/xhtml+xml")
+}
+
+// isSniffableContentType reports whether a declared Content-Type is too
+// generic to trust, so the body should be sniffed instead
+func isSniffableContentType(contentType string) bool {
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	return contentType == "" ||
+		strings.HasPrefix(contentType, "text/plain") ||
+		strings.HasPrefix(contentType, "application/octet-stream")
+}
+
+// contentMediaType returns the media type a body is parsed as: the declared
+// one, or, when the declared type is missing or too generic to trust, the
+// type of the parser registered for the URL's file name or else the
+// sniffed one. A text format such as Markdown named by the file name is
+// taken as such whatever HTML the document embeds.
+func contentMediaType(parsers *parser.Registry, contentType, urlPath string, head []byte) string {
+	if isHTMLContentType(contentType) || !isSniffableContentType(contentType) {
+		return parser.MediaType(contentType)
+	}
+	named := parsers.TypeByName(urlPath)
+	if named != "" && (strings.HasPrefix(named, "text/") || !looksLikeHTML(head)) {
+		return named
+	}
+	if looksLikeHTML(head) {
+		return "text/html"
+	}
+	return parser.MediaType(http.DetectContentType(head))
+}
+
+// looksLikeHTML sniffs the leading bytes of a body for HTML
+func looksLikeHTML(head []byte) bool {
+	if strings.Contains(http.DetectContentType(head), "text/html") {
+		return true
+	}
+
+	// DetectContentType only matches markers at the very start; allow a
+	// BOM, comments or an XML prolog before the first tag
+	lower := bytes.ToLower(head)
+	for _, marker := range htmlMarkers {
+		if bytes.Contains(lower, marker) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,168 @@
+package crawler
+
+import (
+	"io"
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultProgressInterval is how often progress is reported when
+// Config.ProgressInterval is unset
+const defaultProgressInterval = time.Second
+
+// CrawlStats reports the progress of a crawl
+type CrawlStats struct {
+	Fetched         int64         `json:"fetched"`
+	Failed          int64         `json:"failed"`
+	SkippedByRobots int64         `json:"skipped_by_robots"`
+	Duplicates      int64         `json:"duplicates"` // Pages whose final or canonical URL was already crawled
+	Filtered        int64         `json:"filtered"`   // Pages rejected by hooks
+	Denied          int64         `json:"denied"`     // URLs matching a deny pattern
+	Queued          int           `json:"queued"`     // URLs waiting to be fetched
+	BytesDownloaded int64         `json:"bytes_downloaded"`
+	PagesPerSecond  float64       `json:"pages_per_second"`
+	Elapsed         time.Duration `json:"elapsed"`
+	Finished        bool          `json:"finished"`
+
+	// Pages skipped for their language or the country they target, by
+	// language or country
+	SkippedByLanguage map[string]int64 `json:"skipped_by_language,omitempty"`
+	SkippedByCountry  map[string]int64 `json:"skipped_by_country,omitempty"`
+}
+
+// crawlStats counts the progress of the current crawl
+type crawlStats struct {
+	fetched         int64
+	failed          int64
+	skippedByRobots int64
+	duplicates      int64
+	filtered        int64
+	denied          int64
+	bytes           int64
+
+	mutex    sync.Mutex
+	start    time.Time
+	end      time.Time
+	queued   func() int
+	finished bool
+
+	skippedLanguages map[string]int64
+	skippedCountries map[string]int64
+}
+
+// begin resets the counters for a crawl whose queue length is reported by
+// queued
+func (s *crawlStats) begin(queued func() int) {
+	atomic.StoreInt64(&s.fetched, 0)
+	atomic.StoreInt64(&s.failed, 0)
+	atomic.StoreInt64(&s.skippedByRobots, 0)
+	atomic.StoreInt64(&s.duplicates, 0)
+	atomic.StoreInt64(&s.filtered, 0)
+	atomic.StoreInt64(&s.denied, 0)
+	atomic.StoreInt64(&s.bytes, 0)
+
+	s.mutex.Lock()
+	s.start = time.Now()
+	s.end = time.Time{}
+	s.queued = queued
+	s.finished = false
+	s.skippedLanguages = nil
+	s.skippedCountries = nil
+	s.mutex.Unlock()
+}
+
+// skipOffTarget counts a page skipped for its language or country, one of
+// SkipLanguage or SkipCountry
+func (s *crawlStats) skipOffTarget(reason, value string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counts := &s.skippedLanguages
+	if reason == SkipCountry {
+		counts = &s.skippedCountries
+	}
+	if *counts == nil {
+		*counts = make(map[string]int64)
+	}
+	(*counts)[value]++
+}
+
+// finish stops the crawl clock
+func (s *crawlStats) finish() {
+	s.mutex.Lock()
+	s.end = time.Now()
+	s.queued = nil
+	s.finished = true
+	s.mutex.Unlock()
+}
+
+// snapshot returns the current counters
+func (s *crawlStats) snapshot() CrawlStats {
+	s.mutex.Lock()
+	start, end, queued, finished := s.start, s.end, s.queued, s.finished
+	skippedLanguages := maps.Clone(s.skippedLanguages)
+	skippedCountries := maps.Clone(s.skippedCountries)
+	s.mutex.Unlock()
+
+	stats := CrawlStats{
+		Fetched:         atomic.LoadInt64(&s.fetched),
+		Failed:          atomic.LoadInt64(&s.failed),
+		SkippedByRobots: atomic.LoadInt64(&s.skippedByRobots),
+		Duplicates:      atomic.LoadInt64(&s.duplicates),
+		Filtered:        atomic.LoadInt64(&s.filtered),
+		Denied:          atomic.LoadInt64(&s.denied),
+		BytesDownloaded: atomic.LoadInt64(&s.bytes),
+		Finished:        finished,
+
+		SkippedByLanguage: skippedLanguages,
+		SkippedByCountry:  skippedCountries,
+	}
+	if queued != nil {
+		stats.Queued = queued()
+	}
+	if !start.IsZero() {
+		if end.IsZero() {
+			end = time.Now()
+		}
+		stats.Elapsed = end.Sub(start)
+		if seconds := stats.Elapsed.Seconds(); seconds > 0 {
+			stats.PagesPerSecond = float64(stats.Fetched) / seconds
+		}
+	}
+	return stats
+}
+
+// countingBody counts the bytes read from a response body
+type countingBody struct {
+	io.ReadCloser
+	count *int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	atomic.AddInt64(b.count, int64(n))
+	return n, err
+}
+
+// Stats reports the progress of the current or last crawl
+func (c *crawler) Stats() CrawlStats {
+	return c.stats.snapshot()
+}
+
+// reportProgress passes the crawl's stats to Config.Progress every
+// ProgressInterval until done is closed
+func (c *crawler) reportProgress(done <-chan struct{}) {
+	ticker := time.NewTicker(c.config.ProgressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			c.config.Progress(c.stats.snapshot())
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package crawler
+
+import (
+	"net/url"
+	"strings"
+)
+
+// countryDomains maps the country-code domains that differ from their
+// country's ISO 3166 code
+var countryDomains = map[string]string{
+	"uk": "gb",
+}
+
+// genericCountryDomains are country-code domains mostly registered for
+// their letters rather than their country
+var genericCountryDomains = map[string]bool{
+	"ai": true, "cc": true, "co": true, "fm": true, "gg": true, "io": true,
+	"ly": true, "me": true, "so": true, "to": true, "tv": true, "ws": true,
+}
+
+// targetSet normalizes a list of allowed values into a set, nil when the
+// list allows everything
+func targetSet(values []string, normalize func(string) string) map[string]bool {
+	var set map[string]bool
+	for _, value := range values {
+		if value = normalize(value); value != "" {
+			if set == nil {
+				set = make(map[string]bool)
+			}
+			set[value] = true
+		}
+	}
+	return set
+}
+
+// normalizeCountry lower-cases a country code
+func normalizeCountry(code string) string {
+	return strings.ToLower(strings.TrimSpace(code))
+}
+
+// pageCountry returns the country a page targets: the region of its
+// declared language, or else that of its host's country-code domain
+func pageCountry(region string, pageURL *url.URL) string {
+	if region != "" {
+		return region
+	}
+
+	host := pageURL.Hostname()
+	tld := strings.ToLower(host[strings.LastIndexByte(host, '.')+1:])
+	if len(tld) != 2 || genericCountryDomains[tld] {
+		return ""
+	}
+	if country, ok := countryDomains[tld]; ok {
+		return country
+	}
+	return tld
+}
+
+// offTarget returns the skip reason and the offending value when a page is
+// outside the allowed languages or countries. Pages whose language or
+// country is unknown are kept.
+func (c *crawler) offTarget(page *Page) (string, string) {
+	if c.allowedLanguages != nil && page.Language != "" && !c.allowedLanguages[page.Language] {
+		return SkipLanguage, page.Language
+	}
+	if c.allowedCountries != nil && page.Country != "" && !c.allowedCountries[page.Country] {
+		return SkipCountry, page.Country
+	}
+	return "", ""
+}
@@ -0,0 +1,54 @@
+package crawler
+
+import (
+	"net/url"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Sources of a page title
+const (
+	TitleFromTag     = "title"     // The page's <title>
+	TitleFromHeading = "heading"   // The page's first heading
+	TitleFromURL     = "url"       // The last segment of the URL path
+	TitleGenerated   = "generated" // Written by a language model from the text
+)
+
+// pageTitle returns the title of a page and where it came from, falling
+// back to the first heading and then the URL for pages without a <title>
+func pageTitle(title, heading string, pageURL *url.URL) (string, string) {
+	if title = strings.Join(strings.Fields(title), " "); title != "" {
+		return title, TitleFromTag
+	}
+	if heading != "" {
+		return heading, TitleFromHeading
+	}
+	return titleFromURL(pageURL), TitleFromURL
+}
+
+// titleFromURL turns the last path segment of a URL into a title, e.g.
+// /docs/getting-started.html becomes "Getting started". The site root is
+// titled with its host name.
+func titleFromURL(pageURL *url.URL) string {
+	slug := path.Base(strings.TrimSuffix(pageURL.Path, "/"))
+	if slug == "." || slug == "/" || slug == "" {
+		return pageURL.Hostname()
+	}
+	if unescaped, err := url.PathUnescape(slug); err == nil {
+		slug = unescaped
+	}
+	slug = strings.TrimSuffix(slug, path.Ext(slug))
+
+	words := strings.FieldsFunc(slug, func(r rune) bool {
+		return r == '-' || r == '_' || r == '+' || unicode.IsSpace(r)
+	})
+	if len(words) == 0 {
+		return pageURL.Hostname()
+	}
+
+	title := strings.Join(words, " ")
+	first, size := utf8.DecodeRuneInString(title)
+	return string(unicode.ToUpper(first)) + title[size:]
+}
@@ -0,0 +1,92 @@
+package crawler
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TransportConfig holds HTTP transport tuning for crawl clients
+type TransportConfig struct {
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     int // Seconds an idle keep-alive connection is kept
+	TLSHandshakeTimeout int // Seconds allowed for a TLS handshake
+
+	DisableHTTP2       bool // Speak HTTP/1.1 only
+	DisableKeepAlives  bool // Open a new connection for every request
+	InsecureSkipVerify bool // Accept any TLS certificate, for staging hosts
+	DNSCacheTTL        int  // Seconds resolved addresses are reused, 0 disables
+
+	// TLS overrides the TLS client configuration, InsecureSkipVerify is
+	// applied on a copy of it
+	TLS *tls.Config
+}
+
+var (
+	transports      = make(map[TransportConfig]*http.Transport)
+	transportsMutex sync.Mutex
+)
+
+// sharedTransport returns a transport for the given configuration, reusing
+// the same instance for identical configurations so that every client
+// shares one connection pool and TLS session cache
+func sharedTransport(config TransportConfig) *http.Transport {
+	if config.MaxIdleConns == 0 {
+		config.MaxIdleConns = 100
+	}
+	if config.MaxIdleConnsPerHost == 0 {
+		config.MaxIdleConnsPerHost = 10
+	}
+	if config.IdleConnTimeout == 0 {
+		config.IdleConnTimeout = 90
+	}
+	if config.TLSHandshakeTimeout == 0 {
+		config.TLSHandshakeTimeout = 10
+	}
+
+	transportsMutex.Lock()
+	defer transportsMutex.Unlock()
+
+	if transport, exists := transports[config]; exists {
+		return transport
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     !config.DisableHTTP2,
+		MaxIdleConns:          config.MaxIdleConns,
+		MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
+		IdleConnTimeout:       time.Duration(config.IdleConnTimeout) * time.Second,
+		TLSHandshakeTimeout:   time.Duration(config.TLSHandshakeTimeout) * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		DisableKeepAlives:     config.DisableKeepAlives,
+	}
+	if config.DNSCacheTTL > 0 {
+		transport.DialContext = newDNSCache(dialer, time.Duration(config.DNSCacheTTL)*time.Second).DialContext
+	}
+	if config.TLS != nil || config.InsecureSkipVerify {
+		tlsConfig := &tls.Config{}
+		if config.TLS != nil {
+			tlsConfig = config.TLS.Clone()
+		}
+		if config.InsecureSkipVerify {
+			tlsConfig.InsecureSkipVerify = true
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	if config.DisableHTTP2 {
+		// A non-nil empty map keeps the transport from upgrading to HTTP/2
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+	transports[config] = transport
+
+	return transport
+}
@@ -0,0 +1,145 @@
+package crawler
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// mobileLabels are the host labels sites serve mobile and AMP variants
+// under, as in m.example.com or en.m.wikipedia.org
+var mobileLabels = map[string]bool{"m": true, "mobile": true, "amp": true}
+
+// ampQueryParams mark the AMP variant of a page in its query string
+var ampQueryParams = map[string]bool{"amp": true, "outputtype": true, "output": true}
+
+// ampCacheSuffix is the host suffix of the Google AMP cache, which serves
+// pages under /c/s/<host>/<path>, or /c/<host>/<path> for plain HTTP
+const ampCacheSuffix = ".cdn.ampproject.org"
+
+// desktopURL maps the URL of an AMP or mobile variant of a page to the URL
+// of its desktop version, reporting false when it is no variant. The /amp
+// path and ?amp query markers are only trusted on AMP documents, since
+// other pages may use them for anything.
+func desktopURL(variant *url.URL, amp bool) (*url.URL, bool) {
+	desktop := *variant
+	changed := false
+
+	if origin, ok := ampCacheOrigin(variant); ok {
+		desktop, changed, amp = *origin, true, true
+	}
+
+	if host := desktopHost(desktop.Hostname()); host != desktop.Hostname() {
+		if port := desktop.Port(); port != "" {
+			host += ":" + port
+		}
+		desktop.Host = host
+		changed = true
+	}
+
+	if amp {
+		if path := desktopPath(desktop.Path); path != desktop.Path {
+			desktop.Path, desktop.RawPath = path, ""
+			changed = true
+		}
+		if query := desktopQuery(desktop.Query()); query != nil {
+			desktop.RawQuery = query.Encode()
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil, false
+	}
+	return &desktop, true
+}
+
+// ampCacheOrigin returns the URL an AMP cache URL serves a copy of
+func ampCacheOrigin(u *url.URL) (*url.URL, bool) {
+	if !strings.HasSuffix(strings.ToLower(u.Hostname()), ampCacheSuffix) {
+		return nil, false
+	}
+
+	// The path is /c/, /v/ or /i/ for documents, viewer and images, then
+	// s/ when the origin is served over HTTPS
+	parts := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 3)
+	if len(parts) < 2 || len(parts[0]) != 1 {
+		return nil, false
+	}
+	scheme, rest := "http", strings.Join(parts[1:], "/")
+	if parts[1] == "s" && len(parts) == 3 {
+		scheme, rest = "https", parts[2]
+	}
+	host, path, _ := strings.Cut(rest, "/")
+	if !strings.Contains(host, ".") {
+		return nil, false
+	}
+	return &url.URL{Scheme: scheme, Host: host, Path: "/" + path, RawQuery: u.RawQuery}, true
+}
+
+// desktopHost drops the mobile label from a host, keeping the last two
+// labels so that hosts such as m.co are left alone
+func desktopHost(host string) string {
+	labels := strings.Split(host, ".")
+	for i := 0; i < len(labels)-2; i++ {
+		if mobileLabels[strings.ToLower(labels[i])] {
+			return strings.Join(append(labels[:i:i], labels[i+1:]...), ".")
+		}
+	}
+	return host
+}
+
+// desktopPath drops the AMP markers of a path: a leading or trailing /amp
+// segment and an .amp extension
+func desktopPath(path string) string {
+	switch {
+	case strings.HasSuffix(path, "/amp"):
+		path = strings.TrimSuffix(path, "/amp")
+	case strings.HasSuffix(path, "/amp/"):
+		path = strings.TrimSuffix(path, "amp/")
+	case strings.HasPrefix(path, "/amp/"):
+		path = strings.TrimPrefix(path, "/amp")
+	case strings.HasSuffix(path, ".amp.html"):
+		path = strings.TrimSuffix(path, ".amp.html") + ".html"
+	case strings.HasSuffix(path, ".amp"):
+		path = strings.TrimSuffix(path, ".amp")
+	}
+	if path == "" {
+		path = "/"
+	}
+	return path
+}
+
+// desktopQuery drops the AMP markers of a query, returning nil when it has
+// none
+func desktopQuery(query url.Values) url.Values {
+	changed := false
+	for key, values := range query {
+		if !ampQueryParams[strings.ToLower(key)] {
+			continue
+		}
+		// ?output=amp and ?outputType=amp, but ?amp with any value
+		if strings.EqualFold(key, "amp") || (len(values) == 1 && strings.EqualFold(values[0], "amp")) {
+			query.Del(key)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return query
+}
+
+// sameSite reports whether two URLs are on the same registrable domain,
+// as docs.example.com and www.example.com are, falling back to comparing
+// hosts when the domain cannot be told, as for IP addresses
+func sameSite(a, b *url.URL) bool {
+	hostA, hostB := strings.ToLower(a.Hostname()), strings.ToLower(b.Hostname())
+	if hostA == hostB {
+		return true
+	}
+	siteA, errA := publicsuffix.EffectiveTLDPlusOne(hostA)
+	siteB, errB := publicsuffix.EffectiveTLDPlusOne(hostB)
+	return errA == nil && errB == nil && siteA == siteB
+}
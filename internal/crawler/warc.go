@@ -0,0 +1,395 @@
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultWARCFileSize is the size WARC files are rotated at, the 1GB the
+// WARC standard recommends
+const defaultWARCFileSize = 1 << 30
+
+// warcRedirectedFrom lists the URLs a recorded response was redirected
+// from, so replay serves it for them too
+const warcRedirectedFrom = "X-Redirected-From"
+
+// warcRecorder is a Fetcher writing every response of another into
+// gzip-compressed WARC files. Each record is a gzip member of its own,
+// written whole, so files stay readable if the crawl dies mid-way.
+type warcRecorder struct {
+	fetcher     Fetcher
+	dir         string
+	prefix      string
+	maxFileSize int64
+	maxBodySize int64
+
+	mutex  sync.Mutex
+	file   *os.File
+	size   int64
+	serial int
+}
+
+// newWARCRecorder records the responses of fetcher into WARC files in dir,
+// starting a new file once one reaches maxFileSize. Bodies are recorded up
+// to maxBodySize bytes, beyond which they are marked truncated.
+func newWARCRecorder(fetcher Fetcher, dir string, maxFileSize, maxBodySize int64) (*warcRecorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create WARC directory: %w", err)
+	}
+	if maxFileSize <= 0 {
+		maxFileSize = defaultWARCFileSize
+	}
+	return &warcRecorder{
+		fetcher:     fetcher,
+		dir:         dir,
+		prefix:      "crawl-" + time.Now().UTC().Format("20060102150405"),
+		maxFileSize: maxFileSize,
+		maxBodySize: maxBodySize,
+	}, nil
+}
+
+// Fetch fetches a URL and records the response before returning it
+func (r *warcRecorder) Fetch(ctx context.Context, target *url.URL) (*Response, error) {
+	resp, err := r.fetcher.Fetch(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, r.maxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	truncated := int64(len(body)) > r.maxBodySize
+	if truncated {
+		body = body[:r.maxBodySize]
+	}
+
+	if err := r.record(target, resp, body, truncated); err != nil {
+		return nil, fmt.Errorf("failed to record response: %w", err)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// record appends a response record
+func (r *warcRecorder) record(target *url.URL, resp *Response, body []byte, truncated bool) error {
+	// The HTTP block is rebuilt from the parsed response with the body as
+	// received, still content-encoded, and framed by its recorded length
+	var block bytes.Buffer
+	fmt.Fprintf(&block, "HTTP/1.1 %d %s\r\n", resp.StatusCode, http.StatusText(resp.StatusCode))
+	header := resp.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	header.Del("Transfer-Encoding")
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	header.Write(&block)
+	block.WriteString("\r\n")
+	block.Write(body)
+
+	served := target
+	if resp.URL != nil {
+		served = resp.URL
+	}
+	fields := []string{
+		"WARC-Type: response",
+		"WARC-Target-URI: " + served.String(),
+		"Content-Type: application/http;msgtype=response",
+	}
+	if truncated {
+		fields = append(fields, "WARC-Truncated: length")
+	}
+	if len(resp.RedirectedFrom) > 0 {
+		fields = append(fields, warcRedirectedFrom+": "+strings.Join(resp.RedirectedFrom, " "))
+	}
+	return r.write(warcRecord(fields, block.Bytes()))
+}
+
+// write appends a compressed record to the current file, starting a new
+// one when it is full
+func (r *warcRecorder) write(record []byte) error {
+	var member bytes.Buffer
+	gz := gzip.NewWriter(&member)
+	gz.Write(record)
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.file == nil || r.size >= r.maxFileSize {
+		if err := r.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := r.file.Write(member.Bytes())
+	r.size += int64(n)
+	return err
+}
+
+// rotate closes the current file and opens the next, beginning it with a
+// warcinfo record; the caller holds the lock
+func (r *warcRecorder) rotate() error {
+	if r.file != nil {
+		r.file.Close()
+		r.file = nil
+	}
+	r.serial++
+	name := fmt.Sprintf("%s-%05d.warc.gz", r.prefix, r.serial)
+	file, err := os.Create(filepath.Join(r.dir, name))
+	if err != nil {
+		return fmt.Errorf("failed to create WARC file: %w", err)
+	}
+	r.file, r.size = file, 0
+
+	var member bytes.Buffer
+	gz := gzip.NewWriter(&member)
+	gz.Write(warcRecord([]string{
+		"WARC-Type: warcinfo",
+		"WARC-Filename: " + name,
+		"Content-Type: application/warc-fields",
+	}, []byte("software: ai-search\r\nformat: WARC File Format 1.1\r\n")))
+	gz.Close()
+	n, err := r.file.Write(member.Bytes())
+	r.size += int64(n)
+	return err
+}
+
+// warcRecord formats a WARC record, adding the fields every record has
+func warcRecord(fields []string, block []byte) []byte {
+	var record bytes.Buffer
+	record.WriteString("WARC/1.1\r\n")
+	fmt.Fprintf(&record, "WARC-Record-ID: <urn:uuid:%s>\r\n", newRecordID())
+	fmt.Fprintf(&record, "WARC-Date: %s\r\n", time.Now().UTC().Format(time.RFC3339))
+	for _, field := range fields {
+		record.WriteString(field + "\r\n")
+	}
+	fmt.Fprintf(&record, "Content-Length: %d\r\n\r\n", len(block))
+	record.Write(block)
+	record.WriteString("\r\n\r\n")
+	return record.Bytes()
+}
+
+// newRecordID returns a random version 4 UUID
+func newRecordID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:])
+}
+
+// errNotArchived is returned for URLs the replayed archives lack
+var errNotArchived = errors.New("not in the WARC archive")
+
+// warcEntry locates a response record in a WARC file
+type warcEntry struct {
+	path           string
+	offset         int64
+	compressed     bool
+	url            *url.URL
+	redirectedFrom []string
+}
+
+// warcFetcher replays the responses recorded in WARC files
+type warcFetcher struct {
+	entries map[string]warcEntry
+}
+
+// NewWARCFetcher creates a Fetcher serving the responses recorded in the
+// given WARC files, or the .warc and .warc.gz files of the given
+// directories, instead of fetching them. A URL recorded more than once is
+// served its latest response, files being read in name order.
+func NewWARCFetcher(paths []string) (Fetcher, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open WARC archive: %w", err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		for _, pattern := range []string{"*.warc", "*.warc.gz"} {
+			matches, _ := filepath.Glob(filepath.Join(path, pattern))
+			files = append(files, matches...)
+		}
+	}
+	sort.Strings(files)
+
+	f := &warcFetcher{entries: make(map[string]warcEntry)}
+	for _, file := range files {
+		if err := f.index(file); err != nil {
+			return nil, fmt.Errorf("failed to read WARC file %s: %w", file, err)
+		}
+	}
+	if len(f.entries) == 0 {
+		return nil, fmt.Errorf("no responses found in WARC archive %s", strings.Join(paths, ", "))
+	}
+	return f, nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// index records where the response records of a file start
+func (f *warcFetcher) index(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	counter := &countingReader{Reader: file}
+	reader := bufio.NewReader(counter)
+	compressed := strings.HasSuffix(path, ".gz")
+	for {
+		offset := counter.n - int64(reader.Buffered())
+		if _, err := reader.Peek(1); err == io.EOF {
+			return nil
+		}
+
+		// A compressed record is a gzip member of its own, which the gzip
+		// reader leaves the following one of unread
+		record := reader
+		var gz *gzip.Reader
+		if compressed {
+			if gz, err = gzip.NewReader(reader); err != nil {
+				return err
+			}
+			gz.Multistream(false)
+			record = bufio.NewReader(gz)
+		}
+
+		fields, err := readWARCFields(record)
+		if err != nil {
+			return err
+		}
+		length, err := strconv.ParseInt(fields.Get("Content-Length"), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid record length: %w", err)
+		}
+		if _, err := record.Discard(int(length) + len("\r\n\r\n")); err != nil {
+			return err
+		}
+		if gz != nil {
+			if _, err := io.Copy(io.Discard, gz); err != nil {
+				return err
+			}
+		}
+
+		if fields.Get("WARC-Type") != "response" || !strings.Contains(fields.Get("Content-Type"), "msgtype=response") {
+			continue
+		}
+		target, err := url.Parse(strings.Trim(fields.Get("WARC-Target-URI"), "<>"))
+		if err != nil {
+			continue
+		}
+		entry := warcEntry{
+			path:           path,
+			offset:         offset,
+			compressed:     compressed,
+			url:            target,
+			redirectedFrom: strings.Fields(fields.Get(warcRedirectedFrom)),
+		}
+		f.entries[target.String()] = entry
+		for _, from := range entry.redirectedFrom {
+			f.entries[from] = entry
+		}
+	}
+}
+
+// readWARCFields reads the version line and named fields of a record
+func readWARCFields(reader *bufio.Reader) (textproto.MIMEHeader, error) {
+	version, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(version, "WARC/") {
+		return nil, fmt.Errorf("invalid record version %q", strings.TrimSpace(version))
+	}
+	return textproto.NewReader(reader).ReadMIMEHeader()
+}
+
+// Fetch replays the response recorded for a URL
+func (f *warcFetcher) Fetch(ctx context.Context, target *url.URL) (*Response, error) {
+	entry, ok := f.entries[target.String()]
+	if !ok {
+		return nil, fmt.Errorf("%s is %w", target, errNotArchived)
+	}
+
+	file, err := os.Open(entry.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if _, err := file.Seek(entry.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var source io.Reader = file
+	if entry.compressed {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		source = gz
+	}
+	reader := bufio.NewReader(source)
+	fields, err := readWARCFields(reader)
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.ParseInt(fields.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid record length: %w", err)
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(io.LimitReader(reader, length)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recorded response: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+
+	return &Response{
+		URL:            entry.url,
+		RedirectedFrom: entry.redirectedFrom,
+		StatusCode:     resp.StatusCode,
+		Header:         resp.Header,
+		Body:           io.NopCloser(bytes.NewReader(body)),
+	}, nil
+}
@@ -0,0 +1,106 @@
+// Package crawltest runs the crawl pipeline against recorded pages instead
+// of the web and the production backends. A fixture directory is served by
+// an httptest TLS server, and the pages crawled from it are indexed into an
+// in-memory store and index with embeddings derived from the text alone, so
+// a crawl of the same fixtures always yields the same documents, chunks and
+// search results without any external service.
+package crawltest
+
+import (
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// BasePlaceholder is replaced in served fixtures with the base URL of the
+// server, so that recorded pages can link to each other absolutely
+const BasePlaceholder = "{{base}}"
+
+// Fixture is a recorded response served at one path
+type Fixture struct {
+	ContentType string
+	Body        []byte
+}
+
+// Fixtures maps URL paths such as "/docs/install.html" to their responses
+type Fixtures map[string]*Fixture
+
+// LoadFixtures reads every file under dir as a fixture served at its path
+// relative to dir. An index.html is also served at its directory's path,
+// and a robots.txt at the root applies to the crawl like a live one.
+func LoadFixtures(dir string) (Fixtures, error) {
+	fixtures := make(Fixtures)
+	err := filepath.WalkDir(dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		body, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+
+		urlPath := "/" + filepath.ToSlash(rel)
+		contentType := mime.TypeByExtension(path.Ext(urlPath))
+		if contentType == "" {
+			contentType = http.DetectContentType(body)
+		}
+		fixture := &Fixture{ContentType: contentType, Body: body}
+
+		fixtures[urlPath] = fixture
+		if path.Base(urlPath) == "index.html" {
+			fixtures[strings.TrimSuffix(urlPath, "index.html")] = fixture
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load fixtures: %w", err)
+	}
+	if len(fixtures) == 0 {
+		return nil, fmt.Errorf("no fixtures found in %s", dir)
+	}
+	return fixtures, nil
+}
+
+// NewServer serves the fixtures over HTTPS on a local port, answering 404
+// for any other path. URL normalization upgrades links to https, so the
+// crawler must skip verifying the server's self-signed certificate. The
+// caller closes the server.
+func NewServer(fixtures Fixtures) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fixture, ok := fixtures[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		body := fixture.Body
+		if isText(fixture.ContentType) {
+			body = []byte(strings.ReplaceAll(string(body), BasePlaceholder, server.URL))
+		}
+		w.Header().Set("Content-Type", fixture.ContentType)
+		if r.Method == "HEAD" {
+			return
+		}
+		w.Write(body)
+	}))
+	return server
+}
+
+// isText reports whether a content type is textual, so that the base URL
+// placeholder can be replaced without corrupting binary documents
+func isText(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "xml") || strings.HasSuffix(mediaType, "json")
+}
@@ -0,0 +1,323 @@
+package crawltest
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"ai-search/internal/chunker"
+	"ai-search/internal/embeddings"
+	"ai-search/internal/indexer"
+)
+
+// Indexer is an in-memory indexer.Indexer. Search scores every chunk by
+// the cosine similarity of its embedding with the query's and by the share
+// of query words it contains, weighted 0.7 and 0.3 like the hybrid index,
+// and breaks ties by chunk ID so that rankings are stable. Language
+// preferences are not applied, and indexed documents cannot be exported.
+type Indexer struct {
+	embedder embeddings.Embedder
+	chunking chunker.Config
+	chunker  chunker.Chunker
+
+	mutex     sync.RWMutex
+	documents map[string][]*entry
+	deleted   map[string]bool // Soft-deleted documents
+}
+
+// entry is an indexed chunk
+type entry struct {
+	doc       *indexer.Document
+	chunk     *chunker.Chunk
+	embedding []float32
+	words     map[string]bool
+	position  int
+	count     int
+}
+
+// NewIndexer creates an empty in-memory index with the given chunking
+// settings, embedding queries with embedder
+func NewIndexer(embedder embeddings.Embedder, chunking chunker.Config) *Indexer {
+	chunking = chunking.WithDefaults()
+	return &Indexer{
+		embedder:  embedder,
+		chunking:  chunking,
+		chunker:   chunker.NewTextChunker(chunking),
+		documents: make(map[string][]*entry),
+		deleted:   make(map[string]bool),
+	}
+}
+
+// Index replaces the indexed chunks of a document
+func (i *Indexer) Index(ctx context.Context, doc *indexer.Document, chunks []*chunker.Chunk, embeddings [][]float32) error {
+	if len(chunks) != len(embeddings) {
+		return fmt.Errorf("chunks and embeddings count mismatch")
+	}
+
+	entries := make([]*entry, len(chunks))
+	for j, chunk := range chunks {
+		text := chunk.Text
+		if sentence, _ := chunk.Metadata[indexer.ChunkContextKey].(string); sentence != "" {
+			text = sentence + " " + text
+		}
+
+		entryWords := make(map[string]bool)
+		for _, word := range indexWords(doc.Title + " " + text) {
+			entryWords[word] = true
+		}
+		entries[j] = &entry{
+			doc:       doc,
+			chunk:     chunk,
+			embedding: embeddings[j],
+			words:     entryWords,
+			position:  j,
+			count:     len(chunks),
+		}
+	}
+
+	i.mutex.Lock()
+	i.documents[doc.ID] = entries
+	i.mutex.Unlock()
+	return nil
+}
+
+// Search ranks the indexed chunks matching the options against a query
+func (i *Indexer) Search(ctx context.Context, query string, limit int, opts indexer.SearchOptions) ([]*indexer.SearchResult, error) {
+	queryEmbedding, err := i.embedder.Embed(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get query embedding: %w", err)
+	}
+	queryWords := indexWords(query)
+
+	included := toSet(opts.Documents)
+	excluded := toSet(opts.ExcludeDocuments)
+
+	i.mutex.RLock()
+	var results []*indexer.SearchResult
+	for docID, entries := range i.documents {
+		if (len(included) > 0 && !included[docID]) || excluded[docID] || i.deleted[docID] {
+			continue
+		}
+		for _, entry := range entries {
+			if !matchesFilters(entry.doc, opts.Filters) || !opts.Access.Allows(entry.doc.Attributes[indexer.ACLAttribute]) {
+				continue
+			}
+
+			score := 0.7*cosine(queryEmbedding, entry.embedding) + 0.3*wordShare(queryWords, entry.words)
+			if score <= 0 {
+				continue
+			}
+			results = append(results, entry.result(score))
+		}
+	}
+	i.mutex.RUnlock()
+
+	sort.Slice(results, func(a, b int) bool {
+		if results[a].Score != results[b].Score {
+			return results[a].Score > results[b].Score
+		}
+		return results[a].ChunkID < results[b].ChunkID
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// result turns an indexed chunk into a search result
+func (e *entry) result(score float32) *indexer.SearchResult {
+	metadata := make(map[string]interface{}, len(e.chunk.Metadata)+6)
+	for key, value := range e.chunk.Metadata {
+		metadata[key] = value
+	}
+	metadata["document_id"] = e.doc.ID
+	metadata["chunk_id"] = e.chunk.ID
+	metadata["title"] = e.doc.Title
+	metadata["url"] = e.doc.URL
+	if e.doc.Description != "" {
+		metadata["description"] = e.doc.Description
+	}
+	if len(e.doc.Keywords) > 0 {
+		metadata["keywords"] = strings.Join(e.doc.Keywords, ", ")
+	}
+	if len(e.doc.Attributes) > 0 {
+		metadata["attributes"] = e.doc.Attributes
+	}
+
+	return &indexer.SearchResult{
+		DocumentID: e.doc.ID,
+		ChunkID:    e.chunk.ID,
+		Score:      score,
+		Text:       e.chunk.Text,
+		Metadata:   metadata,
+		StartPos:   e.chunk.StartPos,
+		EndPos:     e.chunk.EndPos,
+		Position:   e.position,
+		ChunkCount: e.count,
+	}
+}
+
+// Delete removes all chunks of a document from the index
+func (i *Indexer) Delete(ctx context.Context, documentID string) error {
+	i.mutex.Lock()
+	delete(i.documents, documentID)
+	delete(i.deleted, documentID)
+	i.mutex.Unlock()
+	return nil
+}
+
+// SetDeleted marks a document soft-deleted, or clears the mark
+func (i *Indexer) SetDeleted(ctx context.Context, documentID string, deleted bool) error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	if deleted {
+		i.deleted[documentID] = true
+	} else {
+		delete(i.deleted, documentID)
+	}
+	return nil
+}
+
+// Chunker returns the chunker built from the chunking settings
+func (i *Indexer) Chunker() chunker.Chunker {
+	return i.chunker
+}
+
+// Chunking returns the chunking settings of the index
+func (i *Indexer) Chunking() chunker.Config {
+	return i.chunking
+}
+
+// ContextualEnrichment is off: the index never asks for context sentences
+func (i *Indexer) ContextualEnrichment() bool {
+	return false
+}
+
+// DefaultVersion is empty: searches cover every version
+func (i *Indexer) DefaultVersion() string {
+	return ""
+}
+
+// RetryChunk has nothing to do, since in-memory writes never fail
+func (i *Indexer) RetryChunk(ctx context.Context, item *indexer.FailedChunk) error {
+	return nil
+}
+
+// Export is not supported by the in-memory index
+func (i *Indexer) Export(ctx context.Context, documentID string) (*indexer.IndexedDocument, error) {
+	return nil, fmt.Errorf("the in-memory index cannot export documents")
+}
+
+// Import is not supported by the in-memory index
+func (i *Indexer) Import(ctx context.Context, doc *indexer.IndexedDocument) error {
+	return fmt.Errorf("the in-memory index cannot import documents")
+}
+
+// SampleChunks returns the first n chunks in order of chunk ID, rather than
+// picked at random, so that samples are reproducible
+func (i *Indexer) SampleChunks(ctx context.Context, n int) ([]*indexer.StoredChunk, error) {
+	i.mutex.RLock()
+	var chunks []*indexer.StoredChunk
+	for _, entries := range i.documents {
+		for _, entry := range entries {
+			sentence, _ := entry.chunk.Metadata[indexer.ChunkContextKey].(string)
+			chunks = append(chunks, &indexer.StoredChunk{
+				ChunkID:    entry.chunk.ID,
+				DocumentID: entry.doc.ID,
+				Title:      entry.doc.Title,
+				URL:        entry.doc.URL,
+				Text:       entry.chunk.Text,
+				Context:    sentence,
+				Embedding:  entry.embedding,
+			})
+		}
+	}
+	i.mutex.RUnlock()
+
+	sort.Slice(chunks, func(a, b int) bool { return chunks[a].ChunkID < chunks[b].ChunkID })
+	if len(chunks) > n {
+		chunks = chunks[:n]
+	}
+	return chunks, nil
+}
+
+// Documents returns the IDs of the indexed documents in order
+func (i *Indexer) Documents() []string {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	ids := make([]string, 0, len(i.documents))
+	for id := range i.documents {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Close is a no-op
+func (i *Indexer) Close() error {
+	return nil
+}
+
+// matchesFilters reports whether a document's attributes match every filter
+func matchesFilters(doc *indexer.Document, filters map[string]string) bool {
+	for key, value := range filters {
+		if doc.Attributes[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// cosine returns the cosine similarity of two vectors
+func cosine(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float32(dot / math.Sqrt(normA*normB))
+}
+
+// wordShare returns the share of query words found in a chunk
+func wordShare(queryWords []string, chunkWords map[string]bool) float32 {
+	if len(queryWords) == 0 {
+		return 0
+	}
+
+	found := 0
+	for _, word := range queryWords {
+		if chunkWords[word] {
+			found++
+		}
+	}
+	return float32(found) / float32(len(queryWords))
+}
+
+// toSet returns the set of the given strings
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
+
+// indexWords splits a text into lower-cased words of letters and digits
+func indexWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
@@ -0,0 +1,576 @@
+package crawltest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"ai-search/internal/chunker"
+	"ai-search/internal/store"
+)
+
+// Store is an in-memory store.Store holding what a crawl writes: documents
+// and their chunks, the crawl frontier, robots.txt rules, dead letters and
+// crawl runs. Metadata is round-tripped through JSON like the PostgreSQL
+// store does, so reads see the same types. The methods serving search
+// analytics, API keys and alerts are not implemented and panic.
+type Store struct {
+	store.Store
+
+	mutex       sync.Mutex
+	documents   map[string]*store.Document
+	order       []string // Document IDs in the order they were first saved
+	chunks      map[string][]*chunker.Chunk
+	chunkOwners map[string]string
+	frontier    map[string]*frontier
+	robots      map[string]robotsEntry
+	deadLetters []*store.DeadLetter
+	nextLetter  int64
+	runs        []*store.CrawlRun
+}
+
+// frontier is the persisted frontier of one crawl
+type frontier struct {
+	pending []*store.FrontierURL
+	known   map[string]bool
+	visited []string
+}
+
+// robotsEntry is robots.txt rules stored until they expire
+type robotsEntry struct {
+	rules   []byte
+	expires time.Time
+}
+
+// NewStore creates an empty in-memory store
+func NewStore() *Store {
+	return &Store{
+		documents:   make(map[string]*store.Document),
+		chunks:      make(map[string][]*chunker.Chunk),
+		chunkOwners: make(map[string]string),
+		frontier:    make(map[string]*frontier),
+		robots:      make(map[string]robotsEntry),
+	}
+}
+
+// SaveDocument saves a document, keeping the alternate URLs recorded for it
+func (s *Store) SaveDocument(ctx context.Context, doc *store.Document) error {
+	meta, err := roundTrip(doc.Meta)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now().UTC()
+	saved := &store.Document{
+		ID:        doc.ID,
+		URL:       doc.URL,
+		Title:     doc.Title,
+		Content:   doc.Content,
+		Meta:      meta,
+		CreatedAt: now,
+		UpdatedAt: now,
+
+		Fingerprint: doc.Fingerprint,
+	}
+	if existing, ok := s.documents[doc.ID]; ok {
+		saved.CreatedAt = existing.CreatedAt
+		saved.DeletedAt = existing.DeletedAt
+		doc.DeletedAt = existing.DeletedAt
+		if alternates, ok := existing.Meta["alternate_urls"]; ok {
+			if saved.Meta == nil {
+				saved.Meta = make(map[string]interface{})
+			}
+			saved.Meta["alternate_urls"] = alternates
+		}
+	} else {
+		s.order = append(s.order, doc.ID)
+	}
+	s.documents[doc.ID] = saved
+	return nil
+}
+
+// GetDocument retrieves a document by ID
+func (s *Store) GetDocument(ctx context.Context, id string) (*store.Document, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	doc, ok := s.documents[id]
+	if !ok {
+		return nil, fmt.Errorf("document not found: %s", id)
+	}
+	return copyDocument(doc), nil
+}
+
+// ListDocuments returns the documents that are not soft-deleted, oldest
+// first
+func (s *Store) ListDocuments(ctx context.Context) ([]*store.Document, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var docs []*store.Document
+	for _, id := range s.order {
+		if doc := s.documents[id]; doc.DeletedAt == nil {
+			docs = append(docs, copyDocument(doc))
+		}
+	}
+	return docs, nil
+}
+
+// ListFingerprints lists the fingerprints of the documents that are not
+// soft-deleted, oldest first
+func (s *Store) ListFingerprints(ctx context.Context) ([]*store.DocumentFingerprint, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var fingerprints []*store.DocumentFingerprint
+	for _, id := range s.order {
+		if doc := s.documents[id]; doc.DeletedAt == nil {
+			fingerprints = append(fingerprints, &store.DocumentFingerprint{
+				ID:          doc.ID,
+				URL:         doc.URL,
+				Title:       doc.Title,
+				Length:      len(doc.Content),
+				Fingerprint: doc.Fingerprint,
+			})
+		}
+	}
+	return fingerprints, nil
+}
+
+// SetFingerprint records the fingerprint of a document
+func (s *Store) SetFingerprint(ctx context.Context, id string, fingerprint uint64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if doc, ok := s.documents[id]; ok {
+		doc.Fingerprint = &fingerprint
+	}
+	return nil
+}
+
+// DeleteDocument deletes a document and its chunks
+func (s *Store) DeleteDocument(ctx context.Context, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.documents[id]; !ok {
+		return nil
+	}
+	delete(s.documents, id)
+	for i, docID := range s.order {
+		if docID == id {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+	s.dropChunks(id)
+	return nil
+}
+
+// SoftDeleteDocument marks a document as deleted
+func (s *Store) SoftDeleteDocument(ctx context.Context, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	doc, ok := s.documents[id]
+	if !ok {
+		return fmt.Errorf("document not found: %s", id)
+	}
+	if doc.DeletedAt == nil {
+		now := time.Now().UTC()
+		doc.DeletedAt = &now
+	}
+	return nil
+}
+
+// RestoreDocument clears the deletion mark of a document
+func (s *Store) RestoreDocument(ctx context.Context, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	doc, ok := s.documents[id]
+	if !ok || doc.DeletedAt == nil {
+		return fmt.Errorf("deleted document not found: %s", id)
+	}
+	doc.DeletedAt = nil
+	return nil
+}
+
+// ListDeletedDocumentIDs returns the soft-deleted document IDs, limited to
+// those deleted before the given time unless it is zero
+func (s *Store) ListDeletedDocumentIDs(ctx context.Context, deletedBefore time.Time) ([]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var ids []string
+	for _, id := range s.order {
+		doc := s.documents[id]
+		if doc.DeletedAt != nil && (deletedBefore.IsZero() || doc.DeletedAt.Before(deletedBefore)) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// SaveChunks replaces the chunks of a document
+func (s *Store) SaveChunks(ctx context.Context, docID string, chunks []*chunker.Chunk) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	saved := make([]*chunker.Chunk, len(chunks))
+	for i, chunk := range chunks {
+		metadata, err := roundTrip(chunk.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to marshal chunk metadata: %w", err)
+		}
+		saved[i] = &chunker.Chunk{
+			ID:       chunk.ID,
+			Text:     chunk.Text,
+			StartPos: chunk.StartPos,
+			EndPos:   chunk.EndPos,
+			Metadata: metadata,
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.dropChunks(docID)
+	s.chunks[docID] = saved
+	for _, chunk := range saved {
+		s.chunkOwners[chunk.ID] = docID
+	}
+	return nil
+}
+
+// dropChunks removes the chunks of a document. The caller holds the mutex.
+func (s *Store) dropChunks(docID string) {
+	for _, chunk := range s.chunks[docID] {
+		delete(s.chunkOwners, chunk.ID)
+	}
+	delete(s.chunks, docID)
+}
+
+// GetChunks retrieves the chunks of a document
+func (s *Store) GetChunks(ctx context.Context, docID string) ([]*chunker.Chunk, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	chunks := make([]*chunker.Chunk, len(s.chunks[docID]))
+	for i, chunk := range s.chunks[docID] {
+		copied := *chunk
+		chunks[i] = &copied
+	}
+	return chunks, nil
+}
+
+// GetChunkDocumentID returns the ID of the document a chunk belongs to
+func (s *Store) GetChunkDocumentID(ctx context.Context, chunkID string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	docID, ok := s.chunkOwners[chunkID]
+	if !ok {
+		return "", fmt.Errorf("chunk not found: %s", chunkID)
+	}
+	return docID, nil
+}
+
+// GetChunkTexts returns the text of the given chunks, skipping those that
+// do not exist or belong to soft-deleted documents
+func (s *Store) GetChunkTexts(ctx context.Context, chunkIDs []string) (map[string]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	wanted := make(map[string]bool, len(chunkIDs))
+	for _, id := range chunkIDs {
+		wanted[id] = true
+	}
+
+	texts := make(map[string]string)
+	for docID, chunks := range s.chunks {
+		if doc, ok := s.documents[docID]; !ok || doc.DeletedAt != nil {
+			continue
+		}
+		for _, chunk := range chunks {
+			if wanted[chunk.ID] {
+				texts[chunk.ID] = chunk.Text
+			}
+		}
+	}
+	return texts, nil
+}
+
+// AddFrontierURLs records pending URLs of a crawl, ignoring known URLs
+func (s *Store) AddFrontierURLs(ctx context.Context, crawlID string, urls []*store.FrontierURL) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	crawl := s.crawlFrontier(crawlID)
+	for _, frontierURL := range urls {
+		if crawl.known[frontierURL.URL] {
+			continue
+		}
+		crawl.known[frontierURL.URL] = true
+		crawl.pending = append(crawl.pending, &store.FrontierURL{URL: frontierURL.URL, Depth: frontierURL.Depth})
+	}
+	return nil
+}
+
+// MarkFrontierVisited marks a crawl URL as visited
+func (s *Store) MarkFrontierVisited(ctx context.Context, crawlID string, url string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	crawl := s.crawlFrontier(crawlID)
+	crawl.known[url] = true
+	for i, pending := range crawl.pending {
+		if pending.URL == url {
+			crawl.pending = append(crawl.pending[:i], crawl.pending[i+1:]...)
+			break
+		}
+	}
+	crawl.visited = append(crawl.visited, url)
+	return nil
+}
+
+// GetFrontier retrieves the pending and visited URLs of a crawl
+func (s *Store) GetFrontier(ctx context.Context, crawlID string) ([]*store.FrontierURL, []string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	crawl := s.crawlFrontier(crawlID)
+	pending := append([]*store.FrontierURL(nil), crawl.pending...)
+	visited := append([]string(nil), crawl.visited...)
+	return pending, visited, nil
+}
+
+// ClearFrontier removes the pending and visited URLs of a crawl
+func (s *Store) ClearFrontier(ctx context.Context, crawlID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.frontier, crawlID)
+	return nil
+}
+
+// crawlFrontier returns the frontier of a crawl, creating it when it is
+// new. The caller holds the mutex.
+func (s *Store) crawlFrontier(crawlID string) *frontier {
+	crawl, ok := s.frontier[crawlID]
+	if !ok {
+		crawl = &frontier{known: make(map[string]bool)}
+		s.frontier[crawlID] = crawl
+	}
+	return crawl
+}
+
+// MarkCrawled records that a document was just crawled
+func (s *Store) MarkCrawled(ctx context.Context, id string, status int) error {
+	return nil
+}
+
+// UpdateDocumentMeta sets the given metadata keys of a document, keeping
+// the others
+func (s *Store) UpdateDocumentMeta(ctx context.Context, id string, meta map[string]interface{}) error {
+	updates, err := roundTrip(meta)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	doc, ok := s.documents[id]
+	if !ok {
+		return nil
+	}
+	if doc.Meta == nil {
+		doc.Meta = make(map[string]interface{})
+	}
+	for key, value := range updates {
+		doc.Meta[key] = value
+	}
+	return nil
+}
+
+// AddAlternateURL records url as another address of a stored document
+// whose own URL differs. It returns false if no such document exists.
+func (s *Store) AddAlternateURL(ctx context.Context, id string, url string) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	doc, ok := s.documents[id]
+	if !ok || doc.URL == url || doc.DeletedAt != nil {
+		return false, nil
+	}
+	if doc.Meta == nil {
+		doc.Meta = make(map[string]interface{})
+	}
+
+	alternates, _ := doc.Meta["alternate_urls"].([]interface{})
+	for _, alternate := range alternates {
+		if alternate == url {
+			return true, nil
+		}
+	}
+	doc.Meta["alternate_urls"] = append(alternates, url)
+	return true, nil
+}
+
+// GetRobotsRules returns the unexpired robots.txt rules stored for a host
+func (s *Store) GetRobotsRules(ctx context.Context, host string) ([]byte, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entry, ok := s.robots[host]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, nil
+	}
+	return entry.rules, nil
+}
+
+// SaveRobotsRules stores robots.txt rules for a host for the given time
+func (s *Store) SaveRobotsRules(ctx context.Context, host string, rules []byte, ttl time.Duration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.robots[host] = robotsEntry{rules: rules, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+// AddDeadLetter records a pipeline item that permanently failed
+func (s *Store) AddDeadLetter(ctx context.Context, item *store.DeadLetter) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.nextLetter++
+	item.ID = s.nextLetter
+	item.CreatedAt = time.Now().UTC()
+	if item.Attempts == 0 {
+		item.Attempts = 1
+	}
+
+	copied := *item
+	s.deadLetters = append(s.deadLetters, &copied)
+	return nil
+}
+
+// ListDeadLetters returns failed pipeline items, oldest first, limited to
+// one stage unless stage is empty
+func (s *Store) ListDeadLetters(ctx context.Context, stage string) ([]*store.DeadLetter, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var items []*store.DeadLetter
+	for _, item := range s.deadLetters {
+		if stage == "" || item.Stage == stage {
+			copied := *item
+			items = append(items, &copied)
+		}
+	}
+	return items, nil
+}
+
+// UpdateDeadLetter records another failed attempt at a dead letter
+func (s *Store) UpdateDeadLetter(ctx context.Context, id int64, errMsg string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, item := range s.deadLetters {
+		if item.ID == id {
+			item.Error = errMsg
+			item.Attempts++
+		}
+	}
+	return nil
+}
+
+// DeleteDeadLetter removes a dead letter once it has been reprocessed
+func (s *Store) DeleteDeadLetter(ctx context.Context, id int64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i, item := range s.deadLetters {
+		if item.ID == id {
+			s.deadLetters = append(s.deadLetters[:i], s.deadLetters[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// SaveCrawlRun records the stats of a finished crawl
+func (s *Store) SaveCrawlRun(ctx context.Context, run *store.CrawlRun) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	run.ID = int64(len(s.runs) + 1)
+	run.CreatedAt = time.Now().UTC()
+	copied := *run
+	s.runs = append(s.runs, &copied)
+	return nil
+}
+
+// ListCrawlRunsBetween returns the crawls recorded from one time up to
+// another, oldest first, at most limit of them unless limit is 0
+func (s *Store) ListCrawlRunsBetween(ctx context.Context, from, to time.Time, limit int) ([]*store.CrawlRun, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var runs []*store.CrawlRun
+	for _, run := range s.runs {
+		if !run.CreatedAt.Before(from) && run.CreatedAt.Before(to) {
+			copied := *run
+			runs = append(runs, &copied)
+		}
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].CreatedAt.Before(runs[j].CreatedAt) })
+	if limit > 0 && len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs, nil
+}
+
+// Close is a no-op
+func (s *Store) Close() error {
+	return nil
+}
+
+// copyDocument returns a copy of a stored document whose metadata can be
+// changed without touching the store
+func copyDocument(doc *store.Document) *store.Document {
+	copied := *doc
+	if doc.Meta != nil {
+		copied.Meta = make(map[string]interface{}, len(doc.Meta))
+		for key, value := range doc.Meta {
+			copied.Meta[key] = value
+		}
+	}
+	return &copied
+}
+
+// roundTrip encodes metadata as JSON and decodes it back, as it would be
+// read from the database
+func roundTrip(meta map[string]interface{}) (map[string]interface{}, error) {
+	if meta == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
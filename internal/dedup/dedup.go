@@ -0,0 +1,212 @@
+package dedup
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Detector defines the interface for near-duplicate detection
+type Detector interface {
+	// Fingerprint computes a similarity-preserving fingerprint for text
+	Fingerprint(text string) uint64
+
+	// Clusters groups items whose fingerprints are within the configured distance
+	Clusters(items []*Item) []*Cluster
+}
+
+// Item represents a document considered for deduplication
+type Item struct {
+	ID          string
+	URL         string
+	Title       string
+	Fingerprint uint64
+	Length      int
+}
+
+// Cluster represents a group of near-duplicate documents
+type Cluster struct {
+	ID          string  `json:"id"`
+	Canonical   *Item   `json:"canonical"`
+	Duplicates  []*Item `json:"duplicates"`
+	MaxDistance int     `json:"max_distance"`
+}
+
+// Config holds detector configuration
+type Config struct {
+	ShingleSize int // Number of words per shingle
+	MaxDistance int // Maximum Hamming distance between fingerprints in a cluster
+}
+
+// simhashDetector implements the Detector interface using SimHash fingerprints
+type simhashDetector struct {
+	config Config
+}
+
+// NewDetector creates a new near-duplicate detector
+func NewDetector(config Config) Detector {
+	if config.ShingleSize == 0 {
+		config.ShingleSize = 3
+	}
+	if config.MaxDistance == 0 {
+		config.MaxDistance = 3
+	}
+
+	return &simhashDetector{
+		config: config,
+	}
+}
+
+// Fingerprint computes a 64-bit SimHash over word shingles of the text
+func (d *simhashDetector) Fingerprint(text string) uint64 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) == 0 {
+		return 0
+	}
+
+	var weights [64]int
+	size := d.config.ShingleSize
+	if len(words) < size {
+		size = len(words)
+	}
+
+	for i := 0; i+size <= len(words); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:i+size], " ")))
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<uint(bit)) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var fingerprint uint64
+	for bit := 0; bit < 64; bit++ {
+		if weights[bit] > 0 {
+			fingerprint |= 1 << uint(bit)
+		}
+	}
+
+	return fingerprint
+}
+
+// Clusters groups items whose fingerprints are within the configured distance
+func (d *simhashDetector) Clusters(items []*Item) []*Cluster {
+	// Union-find over items that are close enough to each other
+	parent := make([]int, len(items))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	// Items with one fingerprint are joined at once, and only the first
+	// of them is compared with others
+	first := make(map[uint64]int)
+	var distinct []int
+	for i, item := range items {
+		if j, ok := first[item.Fingerprint]; ok {
+			parent[find(i)] = find(j)
+			continue
+		}
+		first[item.Fingerprint] = i
+		distinct = append(distinct, i)
+	}
+
+	// Fingerprints within MaxDistance bits of each other agree on all the
+	// bits of at least one of MaxDistance+1 bands, so only fingerprints
+	// sharing the bits of a band are compared
+	for _, mask := range bandMasks(d.config.MaxDistance) {
+		buckets := make(map[uint64][]int)
+		for _, i := range distinct {
+			key := items[i].Fingerprint & mask
+			buckets[key] = append(buckets[key], i)
+		}
+		for _, bucket := range buckets {
+			for a := 0; a < len(bucket); a++ {
+				for b := a + 1; b < len(bucket); b++ {
+					i, j := bucket[a], bucket[b]
+					if find(i) != find(j) && Distance(items[i].Fingerprint, items[j].Fingerprint) <= d.config.MaxDistance {
+						parent[find(i)] = find(j)
+					}
+				}
+			}
+		}
+	}
+
+	groups := make(map[int][]*Item)
+	for i, item := range items {
+		root := find(i)
+		groups[root] = append(groups[root], item)
+	}
+
+	var clusters []*Cluster
+	for _, members := range groups {
+		if len(members) < 2 {
+			continue
+		}
+
+		// Prefer the longest document as canonical, then the shortest URL
+		sort.Slice(members, func(a, b int) bool {
+			if members[a].Length != members[b].Length {
+				return members[a].Length > members[b].Length
+			}
+			return len(members[a].URL) < len(members[b].URL)
+		})
+
+		cluster := &Cluster{
+			ID:         members[0].ID,
+			Canonical:  members[0],
+			Duplicates: members[1:],
+		}
+		for _, dup := range cluster.Duplicates {
+			if dist := Distance(cluster.Canonical.Fingerprint, dup.Fingerprint); dist > cluster.MaxDistance {
+				cluster.MaxDistance = dist
+			}
+		}
+		clusters = append(clusters, cluster)
+	}
+
+	// Largest clusters first
+	sort.Slice(clusters, func(a, b int) bool {
+		if len(clusters[a].Duplicates) != len(clusters[b].Duplicates) {
+			return len(clusters[a].Duplicates) > len(clusters[b].Duplicates)
+		}
+		return clusters[a].ID < clusters[b].ID
+	})
+
+	return clusters
+}
+
+// bandMasks splits the 64 bits of a fingerprint into maxDistance+1 bands
+// and returns the mask of each. Distances of 64 bits and over leave one band
+// of no bits, in which every fingerprint is compared with every other.
+func bandMasks(maxDistance int) []uint64 {
+	if maxDistance >= 64 {
+		return []uint64{0}
+	}
+
+	count := max(maxDistance, 0) + 1
+	masks := make([]uint64, count)
+	for bit := 0; bit < 64; bit++ {
+		masks[bit*count/64] |= 1 << uint(bit)
+	}
+	return masks
+}
+
+// Distance returns the Hamming distance between two fingerprints
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
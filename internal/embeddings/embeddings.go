@@ -6,8 +6,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"strconv"
 	"time"
+
+	"ai-search/internal/metrics"
 )
 
 // Embedder defines the interface for generating embeddings
@@ -20,6 +24,10 @@ type Embedder interface {
 
 	// Dimensions returns the embedding dimension size
 	Dimensions() int
+
+	// Model returns the registry entry of the embedding model, with zero
+	// limits when the model is unknown
+	Model() ModelInfo
 }
 
 // Config holds embedder configuration
@@ -29,13 +37,14 @@ type Config struct {
 	Timeout   int
 	APIKey    string
 	BaseURL   string
+	Models    []ModelInfo // Custom models added to the registry
 }
 
 // openAIEmbedder implements the Embedder interface using OpenAI API
 type openAIEmbedder struct {
 	config     Config
 	httpClient *http.Client
-	dimensions int
+	model      ModelInfo
 }
 
 // OpenAIRequest represents the request structure for OpenAI API
@@ -63,6 +72,9 @@ func NewEmbedder(config Config) Embedder {
 	if config.Model == "" {
 		config.Model = "text-embedding-3-small" // Default model
 	}
+	if config.Model == MockModel {
+		return NewMockEmbedder(0)
+	}
 	if config.BatchSize == 0 {
 		config.BatchSize = 10 // Default batch size
 	}
@@ -77,10 +89,16 @@ func NewEmbedder(config Config) Embedder {
 		Timeout: time.Duration(config.Timeout) * time.Second,
 	}
 
+	model, ok := NewRegistry(config.Models...).Lookup(config.Model)
+	if !ok {
+		log.Printf("Warning: unknown embedding model %s, dimensions and token limits are not checked", config.Model)
+		model = ModelInfo{Name: config.Model}
+	}
+
 	return &openAIEmbedder{
 		config:     config,
 		httpClient: httpClient,
-		dimensions: 1536, // text-embedding-3-small dimensions
+		model:      model,
 	}
 }
 
@@ -107,6 +125,13 @@ func (e *openAIEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]fl
 	// Split into batches if necessary
 	var allEmbeddings [][]float32
 
+	// Pending batches count towards the embedding stage queue depth
+	pending := (len(texts) + e.config.BatchSize - 1) / e.config.BatchSize
+	metrics.StageQueueDepth.Add(float64(pending), metrics.StageEmbed)
+	defer func() {
+		metrics.StageQueueDepth.Add(-float64(pending), metrics.StageEmbed)
+	}()
+
 	for i := 0; i < len(texts); i += e.config.BatchSize {
 		end := i + e.config.BatchSize
 		if end > len(texts) {
@@ -114,10 +139,22 @@ func (e *openAIEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]fl
 		}
 
 		batch := texts[i:end]
+		pending--
+		metrics.StageQueueDepth.Add(-1, metrics.StageEmbed)
+		metrics.StageBusyWorkers.Add(1, metrics.StageEmbed)
+		metrics.UpdateUtilization(metrics.StageEmbed)
+		startTime := time.Now()
+
 		embeddings, err := e.embedBatch(ctx, batch)
+
+		metrics.StageDuration.Observe(time.Since(startTime).Seconds(), metrics.StageEmbed)
+		metrics.StageBusyWorkers.Add(-1, metrics.StageEmbed)
+		metrics.UpdateUtilization(metrics.StageEmbed)
 		if err != nil {
+			metrics.StageProcessed.Inc(metrics.StageEmbed, "error")
 			return nil, err
 		}
+		metrics.StageProcessed.Inc(metrics.StageEmbed, "success")
 
 		allEmbeddings = append(allEmbeddings, embeddings...)
 	}
@@ -127,9 +164,14 @@ func (e *openAIEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]fl
 
 // embedBatch processes a single batch of texts
 func (e *openAIEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	input := make([]string, len(texts))
+	for i, text := range texts {
+		input[i] = e.model.Truncate(text)
+	}
+
 	request := OpenAIRequest{
 		Model: e.config.Model,
-		Input: texts,
+		Input: input,
 	}
 
 	jsonData, err := json.Marshal(request)
@@ -145,11 +187,18 @@ func (e *openAIEmbedder) embedBatch(ctx context.Context, texts []string) ([][]fl
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+e.config.APIKey)
 
+	batchSize := metrics.BatchSizeBucket(len(texts))
+	metrics.EmbeddingBatchSize.Observe(float64(len(texts)), e.config.Model)
+
+	startTime := time.Now()
 	resp, err := e.httpClient.Do(req)
+	metrics.EmbeddingRequestDuration.Observe(time.Since(startTime).Seconds(), e.config.Model, batchSize)
 	if err != nil {
+		metrics.EmbeddingRequests.Inc(e.config.Model, batchSize, "error")
 		return nil, fmt.Errorf("failed to make request: %w", err)
 	}
 	defer resp.Body.Close()
+	metrics.EmbeddingRequests.Inc(e.config.Model, batchSize, strconv.Itoa(resp.StatusCode))
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
@@ -160,11 +209,16 @@ func (e *openAIEmbedder) embedBatch(ctx context.Context, texts []string) ([][]fl
 	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
+	metrics.EmbeddingTokens.Add(float64(response.Usage.TotalTokens), e.config.Model)
+	metrics.EmbeddingCost.Add(e.model.Cost(response.Usage.TotalTokens), e.config.Model)
 
 	// Sort embeddings by index to maintain order
 	embeddings := make([][]float32, len(texts))
 	for _, data := range response.Data {
 		if data.Index < len(embeddings) {
+			if e.model.Dimensions > 0 && len(data.Embedding) != e.model.Dimensions {
+				return nil, fmt.Errorf("embedding has %d dimensions, expected %d for model %s", len(data.Embedding), e.model.Dimensions, e.config.Model)
+			}
 			embeddings[data.Index] = data.Embedding
 		}
 	}
@@ -174,5 +228,10 @@ func (e *openAIEmbedder) embedBatch(ctx context.Context, texts []string) ([][]fl
 
 // Dimensions returns the embedding dimension size
 func (e *openAIEmbedder) Dimensions() int {
-	return e.dimensions
+	return e.model.Dimensions
+}
+
+// Model returns the registry entry of the embedding model
+func (e *openAIEmbedder) Model() ModelInfo {
+	return e.model
 }
@@ -0,0 +1,93 @@
+package embeddings
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// MockModel is the model name selecting the mock embedder, which runs
+// offline without an API key
+const MockModel = "mock"
+
+// mockDimensions is the default size of mock embeddings
+const mockDimensions = 256
+
+// mockEmbedder is an Embedder that hashes the words of a text into a
+// fixed-size vector instead of calling a model
+type mockEmbedder struct {
+	dimensions int
+}
+
+// NewMockEmbedder creates an embedder of deterministic vectors of the given
+// size, 256 when zero. Each word of a text is hashed into one dimension, so
+// texts sharing words embed close to each other and the same text always
+// embeds the same way, which is enough for search to rank sensibly in tests
+// and offline runs.
+func NewMockEmbedder(dimensions int) Embedder {
+	if dimensions <= 0 {
+		dimensions = mockDimensions
+	}
+	return &mockEmbedder{dimensions: dimensions}
+}
+
+// Embed returns the normalized word-hash vector of a text
+func (e *mockEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vector := make([]float32, e.dimensions)
+	for _, word := range mockWords(text) {
+		hash := fnv.New32a()
+		hash.Write([]byte(word))
+		sum := hash.Sum32()
+
+		// The top bit picks the sign so that collisions partly cancel out
+		if sum&(1<<31) != 0 {
+			vector[sum%uint32(e.dimensions)]--
+		} else {
+			vector[sum%uint32(e.dimensions)]++
+		}
+	}
+
+	var norm float64
+	for _, value := range vector {
+		norm += float64(value * value)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vector {
+			vector[i] *= scale
+		}
+	}
+	return vector, nil
+}
+
+// EmbedBatch embeds each text in turn
+func (e *mockEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector, err := e.Embed(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+// Dimensions returns the embedding dimension size
+func (e *mockEmbedder) Dimensions() int {
+	return e.dimensions
+}
+
+// Model describes the mock embedder, which is free and has no token limit
+func (e *mockEmbedder) Model() ModelInfo {
+	return ModelInfo{Name: MockModel, Dimensions: e.dimensions}
+}
+
+// mockWords splits a text into lower-cased words of letters and digits
+func mockWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
@@ -0,0 +1,124 @@
+package embeddings
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// charsPerToken approximates how many characters of English text make up one
+// token, used to estimate token counts without a tokenizer
+const charsPerToken = 4
+
+// ModelInfo describes an embedding model
+type ModelInfo struct {
+	Name       string  `json:"name"`
+	Dimensions int     `json:"dimensions"`
+	MaxTokens  int     `json:"max_tokens"`
+	Price      float64 `json:"price"` // USD per million input tokens
+}
+
+// knownModels lists the embedding models recognised out of the box
+var knownModels = []ModelInfo{
+	{Name: "text-embedding-3-small", Dimensions: 1536, MaxTokens: 8191, Price: 0.02},
+	{Name: "text-embedding-3-large", Dimensions: 3072, MaxTokens: 8191, Price: 0.13},
+	{Name: "text-embedding-ada-002", Dimensions: 1536, MaxTokens: 8191, Price: 0.10},
+	{Name: MockModel, Dimensions: mockDimensions},
+}
+
+// Registry maps model names to their dimensions, token limits and pricing
+type Registry struct {
+	models map[string]ModelInfo
+}
+
+// NewRegistry creates a registry holding the known models plus any custom ones,
+// which override known models of the same name
+func NewRegistry(custom ...ModelInfo) *Registry {
+	r := &Registry{models: make(map[string]ModelInfo)}
+	for _, m := range knownModels {
+		r.Register(m)
+	}
+	for _, m := range custom {
+		r.Register(m)
+	}
+	return r
+}
+
+// Register adds or replaces a model
+func (r *Registry) Register(model ModelInfo) {
+	r.models[model.Name] = model
+}
+
+// Lookup returns the model with the given name
+func (r *Registry) Lookup(name string) (ModelInfo, bool) {
+	m, ok := r.models[name]
+	return m, ok
+}
+
+// ParseModels parses custom models from a comma-separated list of
+// name:dimensions:max_tokens[:price] entries
+func ParseModels(spec string) ([]ModelInfo, error) {
+	var models []ModelInfo
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		fields := strings.Split(entry, ":")
+		if len(fields) < 3 || len(fields) > 4 || fields[0] == "" {
+			return nil, fmt.Errorf("invalid embedding model %q: expected name:dimensions:max_tokens[:price]", entry)
+		}
+
+		dimensions, err := strconv.Atoi(fields[1])
+		if err != nil || dimensions <= 0 {
+			return nil, fmt.Errorf("invalid dimensions for embedding model %q", fields[0])
+		}
+		maxTokens, err := strconv.Atoi(fields[2])
+		if err != nil || maxTokens < 0 {
+			return nil, fmt.Errorf("invalid max tokens for embedding model %q", fields[0])
+		}
+
+		model := ModelInfo{Name: fields[0], Dimensions: dimensions, MaxTokens: maxTokens}
+		if len(fields) == 4 {
+			model.Price, err = strconv.ParseFloat(fields[3], 64)
+			if err != nil || model.Price < 0 {
+				return nil, fmt.Errorf("invalid price for embedding model %q", fields[0])
+			}
+		}
+		models = append(models, model)
+	}
+	return models, nil
+}
+
+// EstimateTokens approximates the number of tokens in text
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+}
+
+// Truncate shortens text to roughly fit within the model's token limit
+func (m ModelInfo) Truncate(text string) string {
+	if m.MaxTokens == 0 {
+		return text
+	}
+
+	limit := m.MaxTokens * charsPerToken
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+
+	runes := 0
+	for i := range text {
+		if runes == limit {
+			return text[:i]
+		}
+		runes++
+	}
+	return text
+}
+
+// Cost returns the estimated cost in USD of embedding the given number of tokens
+func (m ModelInfo) Cost(tokens int) float64 {
+	return float64(tokens) * m.Price / 1e6
+}
@@ -0,0 +1,226 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Event types published on index mutations
+const (
+	DocumentIndexed  = "document.indexed"
+	DocumentDeleted  = "document.deleted"
+	DocumentRestored = "document.restored"
+	DocumentPurged   = "document.purged"
+)
+
+// Event describes a change to indexed documents
+type Event struct {
+	Type        string    `json:"type"`
+	DocumentIDs []string  `json:"document_ids"`
+	Time        time.Time `json:"time"`
+}
+
+// Handler consumes events
+type Handler func(event Event)
+
+// Publisher defines the interface for publishing invalidation events
+type Publisher interface {
+	// Publish delivers an event to local subscribers, webhooks and Redis
+	Publish(ctx context.Context, eventType string, documentIDs ...string)
+
+	// Subscribe registers a handler for events published in this process
+	Subscribe(handler Handler)
+
+	// Listen delivers events published by other processes through Redis to
+	// local subscribers until the context is cancelled
+	Listen(ctx context.Context) error
+
+	// Close releases the publisher's connections
+	Close() error
+}
+
+// Config holds event publishing configuration
+type Config struct {
+	Webhooks     []string // URLs receiving each event as a JSON POST
+	RedisURL     string
+	RedisChannel string // Redis pub/sub channel, disabled when empty
+	Timeout      int    // Seconds allowed per webhook delivery
+
+	// RedisStream is a Redis stream every event is also appended to, an
+	// outbox that consumers such as replicas read at their own pace.
+	// It is trimmed to about StreamMaxLen events.
+	RedisStream  string
+	StreamMaxLen int64
+}
+
+// publisher implements the Publisher interface
+type publisher struct {
+	config     Config
+	httpClient *http.Client
+	redis      *redis.Client
+
+	mutex    sync.RWMutex
+	handlers []Handler
+}
+
+// NewPublisher creates a new publisher instance
+func NewPublisher(config Config) (Publisher, error) {
+	if config.Timeout == 0 {
+		config.Timeout = 5
+	}
+	if config.StreamMaxLen == 0 {
+		config.StreamMaxLen = 1000000
+	}
+
+	p := &publisher{
+		config: config,
+		httpClient: &http.Client{
+			Timeout: time.Duration(config.Timeout) * time.Second,
+		},
+	}
+
+	if config.RedisChannel != "" || config.RedisStream != "" {
+		options, err := redis.ParseURL(config.RedisURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Redis URL: %w", err)
+		}
+		p.redis = redis.NewClient(options)
+	}
+
+	return p, nil
+}
+
+// Publish delivers an event to local subscribers, webhooks and Redis.
+// Delivery failures are logged since the mutation itself has succeeded.
+func (p *publisher) Publish(ctx context.Context, eventType string, documentIDs ...string) {
+	event := Event{
+		Type:        eventType,
+		DocumentIDs: documentIDs,
+		Time:        time.Now().UTC(),
+	}
+
+	p.dispatch(event)
+
+	if len(p.config.Webhooks) == 0 && p.redis == nil {
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to marshal event: %v", err)
+		return
+	}
+
+	for _, webhook := range p.config.Webhooks {
+		if err := p.postWebhook(ctx, webhook, payload); err != nil {
+			log.Printf("Failed to deliver %s event to %s: %v", eventType, webhook, err)
+		}
+	}
+
+	if p.config.RedisChannel != "" {
+		if err := p.redis.Publish(ctx, p.config.RedisChannel, payload).Err(); err != nil {
+			log.Printf("Failed to publish %s event to Redis: %v", eventType, err)
+		}
+	}
+
+	if p.config.RedisStream != "" {
+		err := p.redis.XAdd(ctx, &redis.XAddArgs{
+			Stream: p.config.RedisStream,
+			MaxLen: p.config.StreamMaxLen,
+			Approx: true,
+			Values: map[string]interface{}{streamField: payload},
+		}).Err()
+		if err != nil {
+			log.Printf("Failed to append %s event to %s: %v", eventType, p.config.RedisStream, err)
+		}
+	}
+}
+
+// postWebhook sends an event payload to a webhook URL
+func (p *publisher) postWebhook(ctx context.Context, webhook string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", webhook, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Subscribe registers a handler for events published in this process
+func (p *publisher) Subscribe(handler Handler) {
+	p.mutex.Lock()
+	p.handlers = append(p.handlers, handler)
+	p.mutex.Unlock()
+}
+
+// dispatch calls every local handler
+func (p *publisher) dispatch(event Event) {
+	p.mutex.RLock()
+	handlers := p.handlers
+	p.mutex.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+// Listen delivers events published by other processes through Redis to
+// local subscribers until the context is cancelled
+func (p *publisher) Listen(ctx context.Context) error {
+	if p.config.RedisChannel == "" {
+		return nil
+	}
+
+	subscription := p.redis.Subscribe(ctx, p.config.RedisChannel)
+	defer subscription.Close()
+
+	// Wait for the subscription to be confirmed so failures surface early
+	if _, err := subscription.Receive(ctx); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", p.config.RedisChannel, err)
+	}
+
+	messages := subscription.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case message, ok := <-messages:
+			if !ok {
+				return nil
+			}
+
+			var event Event
+			if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+				log.Printf("Ignoring invalid event: %v", err)
+				continue
+			}
+			p.dispatch(event)
+		}
+	}
+}
+
+// Close releases the Redis connection
+func (p *publisher) Close() error {
+	if p.redis != nil {
+		return p.redis.Close()
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// streamField holds the JSON event in each stream entry
+const streamField = "event"
+
+// StreamHandler processes an event read from the outbox stream. An error
+// leaves the event unacknowledged to be processed again.
+type StreamHandler func(ctx context.Context, event Event) error
+
+// DeadLetterQueue keeps events that still failed after all attempts
+type DeadLetterQueue interface {
+	Add(ctx context.Context, item *FailedEvent) error
+}
+
+// FailedEvent is an event of the outbox stream the handler kept failing on
+type FailedEvent struct {
+	ID       string // Stream entry ID
+	Event    Event
+	Error    string
+	Attempts int
+}
+
+// Consumer reads the outbox stream as a member of a consumer group, so a
+// consumer that stops resumes after the last event it processed
+type Consumer interface {
+	// Consume passes events to the handler in order until the context is
+	// cancelled, retrying an event until the handler accepts it or it is
+	// dead-lettered
+	Consume(ctx context.Context, handler StreamHandler) error
+
+	// Close releases the Redis connection
+	Close() error
+}
+
+// ConsumerConfig holds outbox stream consumer configuration
+type ConsumerConfig struct {
+	RedisURL   string
+	Stream     string
+	Group      string        // Consumer group, one per replica
+	Name       string        // Consumer within the group
+	RetryDelay time.Duration // Wait before retrying a failed event
+
+	// MaxAttempts is the number of times an event is delivered before it
+	// is given up on and passed to DeadLetters, 10 by default. Deliveries
+	// are counted by Redis, so they add up across restarts.
+	MaxAttempts int
+	DeadLetters DeadLetterQueue // Events given up on are only logged when nil
+}
+
+// streamConsumer implements the Consumer interface
+type streamConsumer struct {
+	config ConsumerConfig
+	redis  *redis.Client
+}
+
+// NewConsumer creates a new outbox stream consumer
+func NewConsumer(config ConsumerConfig) (Consumer, error) {
+	if config.Stream == "" {
+		return nil, fmt.Errorf("no outbox stream configured")
+	}
+	if config.Group == "" {
+		config.Group = "replica"
+	}
+	if config.Name == "" {
+		config.Name = config.Group
+	}
+	if config.RetryDelay == 0 {
+		config.RetryDelay = 5 * time.Second
+	}
+	if config.MaxAttempts == 0 {
+		config.MaxAttempts = 10
+	}
+
+	options, err := redis.ParseURL(config.RedisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+
+	return &streamConsumer{config: config, redis: redis.NewClient(options)}, nil
+}
+
+// Consume passes events to the handler in order until the context is
+// cancelled. A new group starts from the oldest event the stream retains.
+func (c *streamConsumer) Consume(ctx context.Context, handler StreamHandler) error {
+	err := c.redis.XGroupCreateMkStream(ctx, c.config.Stream, c.config.Group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group %s: %w", c.config.Group, err)
+	}
+
+	// Events read before a restart but never acknowledged come first
+	position := "0"
+	for ctx.Err() == nil {
+		streams, err := c.redis.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    c.config.Group,
+			Consumer: c.config.Name,
+			Streams:  []string{c.config.Stream, position},
+			Count:    100,
+			Block:    5 * time.Second,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return fmt.Errorf("failed to read %s: %w", c.config.Stream, err)
+		}
+
+		var messages []redis.XMessage
+		for _, stream := range streams {
+			messages = append(messages, stream.Messages...)
+		}
+		if position == "0" && len(messages) == 0 {
+			position = ">"
+			continue
+		}
+
+		if !c.process(ctx, messages, handler) {
+			// Read the failed event again from the pending entries
+			position = "0"
+			select {
+			case <-ctx.Done():
+			case <-time.After(c.config.RetryDelay):
+			}
+		}
+	}
+
+	return nil
+}
+
+// process handles messages in order, acknowledging each one handled or
+// dead-lettered. It stops at the first failure to retry and reports
+// whether all were handled.
+func (c *streamConsumer) process(ctx context.Context, messages []redis.XMessage, handler StreamHandler) bool {
+	for _, message := range messages {
+		var event Event
+		payload, _ := message.Values[streamField].(string)
+		if err := json.Unmarshal([]byte(payload), &event); err != nil {
+			log.Printf("Skipping invalid event %s: %v", message.ID, err)
+		} else if err := handler(ctx, event); err != nil {
+			log.Printf("Failed to process %s event %s: %v", event.Type, message.ID, err)
+			if ctx.Err() != nil {
+				return false
+			}
+			attempts := c.deliveries(ctx, message.ID)
+			if attempts < c.config.MaxAttempts {
+				return false
+			}
+			if !c.deadLetter(ctx, &FailedEvent{ID: message.ID, Event: event, Error: err.Error(), Attempts: attempts}) {
+				return false
+			}
+		}
+
+		if err := c.redis.XAck(ctx, c.config.Stream, c.config.Group, message.ID).Err(); err != nil {
+			log.Printf("Failed to acknowledge event %s: %v", message.ID, err)
+			return false
+		}
+	}
+	return true
+}
+
+// deliveries returns how many times a pending message was delivered to the
+// group, 0 when it cannot be told
+func (c *streamConsumer) deliveries(ctx context.Context, id string) int {
+	pending, err := c.redis.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: c.config.Stream,
+		Group:  c.config.Group,
+		Start:  id,
+		End:    id,
+		Count:  1,
+	}).Result()
+	if err != nil || len(pending) == 0 {
+		if err != nil {
+			log.Printf("Failed to count deliveries of event %s: %v", id, err)
+		}
+		return 0
+	}
+	return int(pending[0].RetryCount)
+}
+
+// deadLetter gives up on an event, reporting whether it may be
+// acknowledged
+func (c *streamConsumer) deadLetter(ctx context.Context, item *FailedEvent) bool {
+	log.Printf("Giving up on %s event %s after %d attempts", item.Event.Type, item.ID, item.Attempts)
+	if c.config.DeadLetters == nil {
+		return true
+	}
+	if err := c.config.DeadLetters.Add(ctx, item); err != nil {
+		log.Printf("Failed to dead-letter event %s: %v", item.ID, err)
+		return false
+	}
+	return true
+}
+
+// Close releases the Redis connection
+func (c *streamConsumer) Close() error {
+	return c.redis.Close()
+}
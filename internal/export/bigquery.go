@@ -0,0 +1,291 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bigQueryScope is the OAuth scope of load jobs
+const bigQueryScope = "https://www.googleapis.com/auth/bigquery"
+
+// bigQueryPollInterval is how often a load job is checked until it is done
+const bigQueryPollInterval = 2 * time.Second
+
+// bigQueryDestination appends batches to one BigQuery table per dataset
+// with load jobs, authenticating as a service account
+type bigQueryDestination struct {
+	project    string
+	dataset    string
+	account    serviceAccount
+	key        *rsa.PrivateKey
+	httpClient *http.Client
+
+	mutex       sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// serviceAccount is the part of a Google service account key file used
+type serviceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// newBigQueryDestination creates a destination for bigquery://project/dataset
+func newBigQueryDestination(parsed *url.URL, config DestinationConfig) (Destination, error) {
+	dataset := trimSlashes(parsed.Path)
+	if parsed.Host == "" || dataset == "" {
+		return nil, fmt.Errorf("BigQuery export destination needs a project and dataset: bigquery://project/dataset")
+	}
+	if config.GoogleCredentialsFile == "" {
+		return nil, fmt.Errorf("BigQuery export destination needs GOOGLE_APPLICATION_CREDENTIALS")
+	}
+
+	data, err := os.ReadFile(config.GoogleCredentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Google credentials: %w", err)
+	}
+	var account serviceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("invalid Google credentials file: %w", err)
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("invalid Google credentials file: no private key")
+	}
+	parsedKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Google credentials private key: %w", err)
+	}
+	key, ok := parsedKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid Google credentials private key: not an RSA key")
+	}
+
+	return &bigQueryDestination{
+		project:    parsed.Host,
+		dataset:    dataset,
+		account:    account,
+		key:        key,
+		httpClient: &http.Client{Timeout: config.Timeout},
+	}, nil
+}
+
+// Name identifies the project and dataset
+func (d *bigQueryDestination) Name() string {
+	return "bigquery://" + d.project + "/" + d.dataset
+}
+
+// LoadsRows is false: batches are uploaded as files to load jobs
+func (d *bigQueryDestination) LoadsRows() bool {
+	return false
+}
+
+// Write appends a batch to its table, created on first use, and waits for
+// the load job to finish
+func (d *bigQueryDestination) Write(ctx context.Context, batch *Batch) (string, error) {
+	token, err := d.accessToken(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	load := map[string]interface{}{
+		"destinationTable": map[string]string{
+			"projectId": d.project,
+			"datasetId": d.dataset,
+			"tableId":   batch.Table.Name,
+		},
+		"writeDisposition":  "WRITE_APPEND",
+		"createDisposition": "CREATE_IF_NEEDED",
+	}
+	if batch.Format == FormatCSV {
+		load["sourceFormat"] = "CSV"
+		load["skipLeadingRows"] = 1
+		load["schema"] = map[string]interface{}{"fields": bigQuerySchema(batch.Table)}
+	} else {
+		// Parquet files carry their own schema
+		load["sourceFormat"] = "PARQUET"
+	}
+	jobConfig, err := json.Marshal(map[string]interface{}{"configuration": map[string]interface{}{"load": load}})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal load job: %w", err)
+	}
+
+	// The job and the data are sent together as a multipart upload
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json; charset=UTF-8"}})
+	part.Write(jobConfig)
+	part, _ = writer.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/octet-stream"}})
+	part.Write(batch.Data)
+	writer.Close()
+
+	uploadURL := fmt.Sprintf("https://bigquery.googleapis.com/upload/bigquery/v2/projects/%s/jobs?uploadType=multipart", url.PathEscape(d.project))
+	req, err := http.NewRequestWithContext(ctx, "POST", uploadURL, &body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create load job request: %w", err)
+	}
+	req.Header.Set("Content-Type", "multipart/related; boundary="+writer.Boundary())
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	var job bigQueryJob
+	if err := d.do(req, &job); err != nil {
+		return "", fmt.Errorf("failed to start BigQuery load job: %w", err)
+	}
+
+	// Load jobs run asynchronously; the batch is only shipped once it is done
+	for job.Status.State != "DONE" {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(bigQueryPollInterval):
+		}
+
+		jobURL := fmt.Sprintf("https://bigquery.googleapis.com/bigquery/v2/projects/%s/jobs/%s?location=%s",
+			url.PathEscape(d.project), url.PathEscape(job.JobReference.JobID), url.QueryEscape(job.JobReference.Location))
+		req, err := http.NewRequestWithContext(ctx, "GET", jobURL, nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to create job status request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		if err := d.do(req, &job); err != nil {
+			return "", fmt.Errorf("failed to check BigQuery load job: %w", err)
+		}
+	}
+	if job.Status.ErrorResult != nil {
+		return "", fmt.Errorf("BigQuery load job %s failed: %s", job.JobReference.JobID, job.Status.ErrorResult.Message)
+	}
+
+	return fmt.Sprintf("bigquery://%s/%s.%s (job %s)", d.project, d.dataset, batch.Table.Name, job.JobReference.JobID), nil
+}
+
+// bigQueryJob is the part of a BigQuery job resource used
+type bigQueryJob struct {
+	JobReference struct {
+		JobID    string `json:"jobId"`
+		Location string `json:"location"`
+	} `json:"jobReference"`
+	Status struct {
+		State       string `json:"state"`
+		ErrorResult *struct {
+			Message string `json:"message"`
+		} `json:"errorResult"`
+	} `json:"status"`
+}
+
+// bigQuerySchema returns the table schema of a load job
+func bigQuerySchema(table *Table) []map[string]string {
+	fields := make([]map[string]string, len(table.Columns))
+	for i, column := range table.Columns {
+		fieldType := "STRING"
+		switch column.Type {
+		case TypeInt64:
+			fieldType = "INTEGER"
+		case TypeFloat64:
+			fieldType = "FLOAT"
+		case TypeBool:
+			fieldType = "BOOLEAN"
+		case TypeTimestamp:
+			fieldType = "TIMESTAMP"
+		}
+		fields[i] = map[string]string{"name": column.Name, "type": fieldType, "mode": "REQUIRED"}
+	}
+	return fields
+}
+
+// do sends a request and decodes its JSON response
+func (d *bigQueryDestination) do(req *http.Request, v interface{}) error {
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// accessToken returns an OAuth token for the service account, exchanging a
+// signed JWT for a new one shortly before the last expires
+func (d *bigQueryDestination) accessToken(ctx context.Context) (string, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.token != "" && time.Until(d.tokenExpiry) > time.Minute {
+		return d.token, nil
+	}
+
+	now := time.Now()
+	assertion, err := d.signJWT(map[string]interface{}{
+		"iss":   d.account.ClientEmail,
+		"scope": bigQueryScope,
+		"aud":   d.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", d.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := d.do(req, &token); err != nil {
+		return "", fmt.Errorf("failed to get Google access token: %w", err)
+	}
+
+	d.token = token.AccessToken
+	d.tokenExpiry = now.Add(time.Duration(token.ExpiresIn) * time.Second)
+	return d.token, nil
+}
+
+// signJWT signs claims with the service account's key (RS256)
+func (d *bigQueryDestination) signJWT(claims map[string]interface{}) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(nil, d.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token request: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,124 @@
+package export
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBigQueryAccessTokenExchangesSignedJWT(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+			return
+		}
+		if grant := r.PostForm.Get("grant_type"); grant != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			t.Errorf("grant_type = %q", grant)
+		}
+
+		parts := strings.Split(r.PostForm.Get("assertion"), ".")
+		if len(parts) != 3 {
+			t.Errorf("assertion has %d parts, want 3", len(parts))
+			return
+		}
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+			t.Errorf("assertion signature does not verify: %v", err)
+		}
+
+		payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		var claims map[string]interface{}
+		if err := json.Unmarshal(payload, &claims); err != nil {
+			t.Error(err)
+			return
+		}
+		if claims["iss"] != "exporter@project.iam.gserviceaccount.com" || claims["scope"] != bigQueryScope ||
+			claims["aud"] != "http://"+r.Host+"/token" {
+			t.Errorf("unexpected claims: %v", claims)
+		}
+
+		fmt.Fprintf(w, `{"access_token": "token-%d", "expires_in": 3600}`, requests)
+	}))
+	defer server.Close()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentials, err := json.Marshal(serviceAccount{
+		ClientEmail: "exporter@project.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		TokenURI:    server.URL + "/token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentialsFile := filepath.Join(t.TempDir(), "credentials.json")
+	if err := os.WriteFile(credentialsFile, credentials, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, _ := url.Parse("bigquery://project/analytics")
+	destination, err := newBigQueryDestination(parsed, DestinationConfig{GoogleCredentialsFile: credentialsFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bigQuery := destination.(*bigQueryDestination)
+
+	// The token is reused until shortly before it expires
+	for i := 0; i < 2; i++ {
+		token, err := bigQuery.accessToken(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "token-1" {
+			t.Errorf("token = %q, want %q", token, "token-1")
+		}
+	}
+	if requests != 1 {
+		t.Errorf("token endpoint called %d times, want 1", requests)
+	}
+}
+
+func TestBigQuerySchema(t *testing.T) {
+	fields := bigQuerySchema(&Table{Columns: datasetColumns[DatasetFeedback]})
+	want := []string{"query:STRING", "chunk_id:STRING", "relevant:BOOLEAN", "created_at:TIMESTAMP"}
+	if len(fields) != len(want) {
+		t.Fatalf("schema has %d fields, want %d", len(fields), len(want))
+	}
+	for i, field := range fields {
+		if got := field["name"] + ":" + field["type"]; got != want[i] || field["mode"] != "REQUIRED" {
+			t.Errorf("field %d = %v, want %s", i, field, want[i])
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// timestampLayout writes timestamps in UTC with microseconds, a form
+// BigQuery and PostgreSQL both load
+const timestampLayout = "2006-01-02T15:04:05.000000Z"
+
+// encode writes a table in the given format
+func encode(table *Table, format string) ([]byte, error) {
+	if format == FormatCSV {
+		return encodeCSV(table)
+	}
+	return encodeParquet(table)
+}
+
+// encodeCSV writes a table as CSV with a header row
+func encodeCSV(table *Table) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	header := make([]string, len(table.Columns))
+	for i, column := range table.Columns {
+		header[i] = column.Name
+	}
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+
+	record := make([]string, len(table.Columns))
+	for _, row := range table.Rows {
+		for i, value := range row {
+			record[i] = formatValue(value)
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// formatValue writes a value as CSV text
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.UTC().Format(timestampLayout)
+	default:
+		return fmt.Sprint(v)
+	}
+}
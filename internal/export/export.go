@@ -0,0 +1,306 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"ai-search/internal/store"
+)
+
+// Datasets that can be exported
+const (
+	DatasetQueryLog  = "query_log"  // Searches and the chunks they returned
+	DatasetFeedback  = "feedback"   // Relevance feedback on search results
+	DatasetCrawlRuns = "crawl_runs" // Stats of finished crawls
+)
+
+// Datasets lists every dataset, in export order
+var Datasets = []string{DatasetQueryLog, DatasetFeedback, DatasetCrawlRuns}
+
+// Formats files are written in
+const (
+	FormatParquet = "parquet"
+	FormatCSV     = "csv"
+)
+
+// defaultLag leaves out rows from the last minute, which may still be
+// committing, so the next export does not skip them
+const defaultLag = time.Minute
+
+// defaultPageSize is the number of rows shipped in a batch by default
+const defaultPageSize = 10000
+
+// Exporter ships analytics data to a warehouse
+type Exporter interface {
+	// Export ships the rows of every dataset recorded since its last export
+	Export(ctx context.Context) ([]*Result, error)
+}
+
+// Result describes the export of one dataset
+type Result struct {
+	Dataset  string
+	From     time.Time
+	To       time.Time
+	Rows     int
+	Batches  int
+	Location string // Where the last batch went, empty when there were none
+}
+
+// ColumnType is the type of a table column
+type ColumnType int
+
+// Column types
+const (
+	TypeString ColumnType = iota
+	TypeInt64
+	TypeFloat64
+	TypeBool
+	TypeTimestamp
+)
+
+// Column is a column of an exported table
+type Column struct {
+	Name string
+	Type ColumnType
+}
+
+// Table is a batch of rows of a dataset. Values are string, int64, float64,
+// bool or time.Time according to their column's type.
+type Table struct {
+	Name    string
+	Columns []Column
+	Rows    [][]interface{}
+}
+
+// Config holds exporter configuration
+type Config struct {
+	Store       store.Store
+	Destination Destination
+
+	// Format of the files written, parquet by default. Destinations that
+	// load rows directly, such as PostgreSQL, ignore it.
+	Format string
+
+	// Datasets to export, all by default
+	Datasets []string
+
+	// Lag leaves out the most recent rows (a minute by default)
+	Lag time.Duration
+
+	// PageSize bounds the rows read and shipped in one batch (10000 by
+	// default). Rows recorded at the same instant are never split across
+	// batches, so a batch may exceed it.
+	PageSize int
+}
+
+// exporter implements the Exporter interface
+type exporter struct {
+	config Config
+}
+
+// NewExporter creates a new exporter instance
+func NewExporter(config Config) (Exporter, error) {
+	if config.Format == "" {
+		config.Format = FormatParquet
+	}
+	if config.Format != FormatParquet && config.Format != FormatCSV {
+		return nil, fmt.Errorf("unknown export format: %s", config.Format)
+	}
+	if len(config.Datasets) == 0 {
+		config.Datasets = Datasets
+	}
+	for _, dataset := range config.Datasets {
+		if _, ok := datasetColumns[dataset]; !ok {
+			return nil, fmt.Errorf("unknown export dataset: %s", dataset)
+		}
+	}
+	if config.Lag == 0 {
+		config.Lag = defaultLag
+	}
+	if config.PageSize <= 0 {
+		config.PageSize = defaultPageSize
+	}
+
+	return &exporter{config: config}, nil
+}
+
+// Export ships the rows of every dataset recorded since its last export.
+// Each dataset keeps its own cursor per destination, advanced only once its
+// rows are shipped, so a failed export is retried from where it stopped.
+// Windows end at the database's clock, which the rows' times are recorded
+// by, whatever the time zones of the database and this process.
+func (e *exporter) Export(ctx context.Context) ([]*Result, error) {
+	now, err := e.config.Store.CurrentTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+	to := now.Add(-e.config.Lag)
+
+	var results []*Result
+	for _, dataset := range e.config.Datasets {
+		result, err := e.exportDataset(ctx, dataset, to)
+		if err != nil {
+			return results, fmt.Errorf("failed to export %s: %w", dataset, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// exportDataset ships the rows of a dataset recorded before to, a page at
+// a time. The cursor advances past each page once it is shipped.
+func (e *exporter) exportDataset(ctx context.Context, dataset string, to time.Time) (*Result, error) {
+	cursor := dataset + "@" + e.config.Destination.Name()
+	from, err := e.config.Store.GetExportCursor(ctx, cursor)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{Dataset: dataset, From: from, To: to}
+
+	for from.Before(to) {
+		table, end, err := e.loadPage(ctx, dataset, from, to)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(table.Rows) > 0 {
+			// Destinations loading rows directly take them unencoded
+			var data []byte
+			if !e.config.Destination.LoadsRows() {
+				data, err = encode(table, e.config.Format)
+				if err != nil {
+					return nil, fmt.Errorf("failed to encode %s: %w", e.config.Format, err)
+				}
+			}
+
+			batch := &Batch{Table: table, Format: e.config.Format, Data: data, From: from, To: end}
+			result.Location, err = e.config.Destination.Write(ctx, batch)
+			if err != nil {
+				return nil, err
+			}
+			result.Rows += len(table.Rows)
+			result.Batches++
+		}
+
+		if err := e.config.Store.SaveExportCursor(ctx, cursor, end); err != nil {
+			return nil, err
+		}
+		from = end
+	}
+	return result, nil
+}
+
+// loadPage reads the next page of a dataset's rows recorded from one time
+// up to another, and returns the time the page ends at. A full page ends at
+// the time of its last row, whose rows are left to the next page so that
+// rows recorded at the same instant stay together.
+func (e *exporter) loadPage(ctx context.Context, dataset string, from, to time.Time) (*Table, time.Time, error) {
+	table, err := e.load(ctx, dataset, from, to, e.config.PageSize)
+	if err != nil || len(table.Rows) < e.config.PageSize {
+		return table, to, err
+	}
+
+	last := rowTime(table.Rows[len(table.Rows)-1])
+	rows := table.Rows
+	for len(rows) > 0 && !rowTime(rows[len(rows)-1]).Before(last) {
+		rows = rows[:len(rows)-1]
+	}
+	if len(rows) > 0 {
+		table.Rows = rows
+		return table, last, nil
+	}
+
+	// The whole page was recorded at one instant: take every row of it.
+	// Times are stored with microsecond precision.
+	end := last.Add(time.Microsecond)
+	table, err = e.load(ctx, dataset, last, end, 0)
+	return table, end, err
+}
+
+// rowTime returns the time a row was recorded, its last column
+func rowTime(row []interface{}) time.Time {
+	t, _ := row[len(row)-1].(time.Time)
+	return t
+}
+
+// datasetColumns are the columns of each dataset, ending with the time
+// rows were recorded, which exports page by
+var datasetColumns = map[string][]Column{
+	DatasetQueryLog: {
+		{Name: "id", Type: TypeInt64},
+		{Name: "query", Type: TypeString},
+		{Name: "language", Type: TypeString},
+		{Name: "chunk_ids", Type: TypeString}, // JSON array in rank order
+		{Name: "result_count", Type: TypeInt64},
+		{Name: "created_at", Type: TypeTimestamp},
+	},
+	DatasetFeedback: {
+		{Name: "query", Type: TypeString},
+		{Name: "chunk_id", Type: TypeString},
+		{Name: "relevant", Type: TypeBool},
+		{Name: "created_at", Type: TypeTimestamp},
+	},
+	DatasetCrawlRuns: {
+		{Name: "id", Type: TypeInt64},
+		{Name: "crawl_id", Type: TypeString},
+		{Name: "start_url", Type: TypeString},
+		{Name: "fetched", Type: TypeInt64},
+		{Name: "indexed", Type: TypeInt64},
+		{Name: "failed", Type: TypeInt64},
+		{Name: "skipped_by_robots", Type: TypeInt64},
+		{Name: "duplicates", Type: TypeInt64},
+		{Name: "filtered", Type: TypeInt64},
+		{Name: "bytes_downloaded", Type: TypeInt64},
+		{Name: "duration_seconds", Type: TypeFloat64},
+		{Name: "stopped", Type: TypeBool},
+		{Name: "created_at", Type: TypeTimestamp},
+	},
+}
+
+// load reads the rows of a dataset recorded from one time up to another,
+// at most limit of them unless limit is 0
+func (e *exporter) load(ctx context.Context, dataset string, from, to time.Time, limit int) (*Table, error) {
+	table := &Table{Name: dataset, Columns: datasetColumns[dataset]}
+
+	switch dataset {
+	case DatasetQueryLog:
+		entries, err := e.config.Store.ListQueryLogsBetween(ctx, from, to, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			chunkIDs, err := json.Marshal(entry.ChunkIDs)
+			if err != nil {
+				return nil, err
+			}
+			table.Rows = append(table.Rows, []interface{}{
+				entry.ID, entry.Query, entry.Language, string(chunkIDs), int64(len(entry.ChunkIDs)), entry.CreatedAt,
+			})
+		}
+
+	case DatasetFeedback:
+		feedback, err := e.config.Store.ListFeedbackBetween(ctx, from, to, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range feedback {
+			table.Rows = append(table.Rows, []interface{}{f.Query, f.ChunkID, f.Relevant, f.CreatedAt})
+		}
+
+	case DatasetCrawlRuns:
+		runs, err := e.config.Store.ListCrawlRunsBetween(ctx, from, to, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, run := range runs {
+			table.Rows = append(table.Rows, []interface{}{
+				run.ID, run.CrawlID, run.StartURL, run.Fetched, run.Indexed, run.Failed, run.SkippedByRobots,
+				run.Duplicates, run.Filtered, run.BytesDownloaded, run.Duration.Seconds(), run.Stopped, run.CreatedAt,
+			})
+		}
+	}
+
+	return table, nil
+}
@@ -0,0 +1,111 @@
+package export
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"ai-search/internal/store"
+)
+
+// runStore holds crawl runs and export cursors in memory
+type runStore struct {
+	store.Store
+	now     time.Time
+	runs    []*store.CrawlRun
+	cursors map[string]time.Time
+}
+
+func (s *runStore) CurrentTimestamp(ctx context.Context) (time.Time, error) {
+	return s.now, nil
+}
+
+func (s *runStore) GetExportCursor(ctx context.Context, name string) (time.Time, error) {
+	return s.cursors[name], nil
+}
+
+func (s *runStore) SaveExportCursor(ctx context.Context, name string, until time.Time) error {
+	s.cursors[name] = until
+	return nil
+}
+
+func (s *runStore) ListCrawlRunsBetween(ctx context.Context, from, to time.Time, limit int) ([]*store.CrawlRun, error) {
+	var runs []*store.CrawlRun
+	for _, run := range s.runs {
+		if !run.CreatedAt.Before(from) && run.CreatedAt.Before(to) {
+			runs = append(runs, run)
+		}
+	}
+	if limit > 0 && len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs, nil
+}
+
+// batchDestination keeps the batches written to it
+type batchDestination struct {
+	batches []*Batch
+}
+
+func (d *batchDestination) Name() string    { return "test" }
+func (d *batchDestination) LoadsRows() bool { return true }
+
+func (d *batchDestination) Write(ctx context.Context, batch *Batch) (string, error) {
+	d.batches = append(d.batches, batch)
+	return "test", nil
+}
+
+func TestExportPagesWithoutSplittingInstants(t *testing.T) {
+	base := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	// Runs 3 to 7 share an instant, more than a page holds
+	offsets := []int{0, 1, 2, 3, 3, 3, 3, 3, 4, 5}
+	runStore := &runStore{now: base.Add(time.Hour), cursors: map[string]time.Time{}}
+	for i, offset := range offsets {
+		runStore.runs = append(runStore.runs, &store.CrawlRun{
+			ID:        int64(i + 1),
+			CreatedAt: base.Add(time.Duration(offset) * time.Second),
+		})
+	}
+
+	destination := &batchDestination{}
+	exporter, err := NewExporter(Config{
+		Store:       runStore,
+		Destination: destination,
+		Datasets:    []string{DatasetCrawlRuns},
+		PageSize:    3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := exporter.Export(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sizes []int
+	var ids []int64
+	for _, batch := range destination.batches {
+		sizes = append(sizes, len(batch.Table.Rows))
+		for _, row := range batch.Table.Rows {
+			ids = append(ids, row[0].(int64))
+		}
+	}
+	// A full page leaves the rows of its last instant to the next one
+	wantSizes := []int{2, 1, 5, 2}
+	if !reflect.DeepEqual(sizes, wantSizes) {
+		t.Fatalf("batch sizes = %v, want %v", sizes, wantSizes)
+	}
+	for i, id := range ids {
+		if id != int64(i+1) {
+			t.Fatalf("exported runs %v, want each once in order", ids)
+		}
+	}
+
+	if results[0].Rows != len(offsets) || results[0].Batches != len(wantSizes) {
+		t.Errorf("result has %d rows in %d batches, want %d in %d", results[0].Rows, results[0].Batches, len(offsets), len(wantSizes))
+	}
+	if cursor := runStore.cursors[DatasetCrawlRuns+"@test"]; !cursor.Equal(runStore.now.Add(-defaultLag)) {
+		t.Errorf("cursor = %v, want %v", cursor, runStore.now.Add(-defaultLag))
+	}
+}
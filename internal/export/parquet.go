@@ -0,0 +1,368 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// parquetRowGroupSize is the number of rows in each row group, so that no
+// page grows unbounded
+const parquetRowGroupSize = 100000
+
+// Parquet format constants, from parquet.thrift
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetRequired = 0
+
+	parquetConvertedUTF8            = 0
+	parquetConvertedTimestampMicros = 10
+
+	parquetEncodingPlain = 0
+	parquetEncodingRLE   = 3
+
+	parquetCodecGzip = 2
+
+	parquetDataPage = 0
+)
+
+// Thrift compact protocol types
+const (
+	thriftTrue   = 1
+	thriftFalse  = 2
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// encodeParquet writes a table as a Parquet file of required columns, each
+// column chunk a single gzip-compressed PLAIN page per row group
+func encodeParquet(table *Table) ([]byte, error) {
+	var file bytes.Buffer
+	file.WriteString("PAR1")
+
+	var rowGroups []parquetRowGroup
+	for start := 0; start < len(table.Rows); start += parquetRowGroupSize {
+		end := min(start+parquetRowGroupSize, len(table.Rows))
+		rowGroup := parquetRowGroup{rows: int64(end - start)}
+
+		for i, column := range table.Columns {
+			values, err := plainValues(column, table.Rows[start:end], i)
+			if err != nil {
+				return nil, err
+			}
+			compressed, err := gzipBytes(values)
+			if err != nil {
+				return nil, err
+			}
+
+			header := pageHeader(len(values), len(compressed), end-start)
+			chunk := parquetColumnChunk{
+				column:           column,
+				offset:           int64(file.Len()),
+				values:           int64(end - start),
+				uncompressedSize: int64(len(header) + len(values)),
+				compressedSize:   int64(len(header) + len(compressed)),
+			}
+			file.Write(header)
+			file.Write(compressed)
+
+			rowGroup.columns = append(rowGroup.columns, chunk)
+			rowGroup.size += chunk.uncompressedSize
+		}
+		rowGroups = append(rowGroups, rowGroup)
+	}
+
+	footer := fileMetaData(table, rowGroups)
+	file.Write(footer)
+	binary.Write(&file, binary.LittleEndian, uint32(len(footer)))
+	file.WriteString("PAR1")
+	return file.Bytes(), nil
+}
+
+// parquetRowGroup and parquetColumnChunk describe what was written, for the
+// file footer
+type parquetRowGroup struct {
+	columns []parquetColumnChunk
+	rows    int64
+	size    int64
+}
+
+type parquetColumnChunk struct {
+	column           Column
+	offset           int64
+	values           int64
+	uncompressedSize int64
+	compressedSize   int64
+}
+
+// plainValues PLAIN-encodes column i of the rows
+func plainValues(column Column, rows [][]interface{}, i int) ([]byte, error) {
+	var buf bytes.Buffer
+	var bits byte
+	for n, row := range rows {
+		value := row[i]
+		var ok bool
+		switch column.Type {
+		case TypeString:
+			var s string
+			if s, ok = value.(string); ok {
+				binary.Write(&buf, binary.LittleEndian, uint32(len(s)))
+				buf.WriteString(s)
+			}
+		case TypeInt64:
+			var v int64
+			if v, ok = value.(int64); ok {
+				binary.Write(&buf, binary.LittleEndian, v)
+			}
+		case TypeFloat64:
+			var v float64
+			if v, ok = value.(float64); ok {
+				binary.Write(&buf, binary.LittleEndian, math.Float64bits(v))
+			}
+		case TypeTimestamp:
+			var t time.Time
+			if t, ok = value.(time.Time); ok {
+				binary.Write(&buf, binary.LittleEndian, t.UnixMicro())
+			}
+		case TypeBool:
+			// Booleans are bit-packed, least significant bit first
+			var v bool
+			if v, ok = value.(bool); ok {
+				if v {
+					bits |= 1 << (n % 8)
+				}
+				if n%8 == 7 || n == len(rows)-1 {
+					buf.WriteByte(bits)
+					bits = 0
+				}
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("column %s has a value of type %T", column.Name, value)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// gzipBytes compresses page data
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// pageHeader encodes the PageHeader of a data page
+func pageHeader(uncompressedSize, compressedSize, values int) []byte {
+	w := newThriftWriter()
+	w.i32(1, parquetDataPage)
+	w.i32(2, int32(uncompressedSize))
+	w.i32(3, int32(compressedSize))
+	w.beginStruct(5) // DataPageHeader
+	w.i32(1, int32(values))
+	w.i32(2, parquetEncodingPlain)
+	w.i32(3, parquetEncodingRLE)
+	w.i32(4, parquetEncodingRLE)
+	w.endStruct()
+	w.endStruct()
+	return w.bytes()
+}
+
+// fileMetaData encodes the FileMetaData footer
+func fileMetaData(table *Table, rowGroups []parquetRowGroup) []byte {
+	var rows int64
+	for _, rowGroup := range rowGroups {
+		rows += rowGroup.rows
+	}
+
+	w := newThriftWriter()
+	w.i32(1, 1) // Format version
+
+	// The schema is a root element followed by one element per column
+	w.listHeader(2, thriftStruct, len(table.Columns)+1)
+	w.beginElement()
+	w.str(4, "schema")
+	w.i32(5, int32(len(table.Columns)))
+	w.endStruct()
+	for _, column := range table.Columns {
+		w.beginElement()
+		w.i32(1, physicalType(column.Type))
+		w.i32(3, parquetRequired)
+		w.str(4, column.Name)
+		switch column.Type {
+		case TypeString:
+			w.i32(6, parquetConvertedUTF8)
+			w.beginStruct(10) // LogicalType
+			w.beginStruct(1)  // STRING
+			w.endStruct()
+			w.endStruct()
+		case TypeTimestamp:
+			w.i32(6, parquetConvertedTimestampMicros)
+			w.beginStruct(10) // LogicalType
+			w.beginStruct(8)  // TIMESTAMP
+			w.boolean(1, true)
+			w.beginStruct(2) // TimeUnit
+			w.beginStruct(2) // MICROS
+			w.endStruct()
+			w.endStruct()
+			w.endStruct()
+			w.endStruct()
+		}
+		w.endStruct()
+	}
+
+	w.i64(3, rows)
+
+	w.listHeader(4, thriftStruct, len(rowGroups))
+	for _, rowGroup := range rowGroups {
+		w.beginElement()
+		w.listHeader(1, thriftStruct, len(rowGroup.columns))
+		for _, chunk := range rowGroup.columns {
+			w.beginElement()
+			w.i64(2, chunk.offset)
+			w.beginStruct(3) // ColumnMetaData
+			w.i32(1, physicalType(chunk.column.Type))
+			w.listHeader(2, thriftI32, 2)
+			w.varint(zigzag(parquetEncodingPlain))
+			w.varint(zigzag(parquetEncodingRLE))
+			w.listHeader(3, thriftBinary, 1)
+			w.varint(uint64(len(chunk.column.Name)))
+			w.buf.WriteString(chunk.column.Name)
+			w.i32(4, parquetCodecGzip)
+			w.i64(5, chunk.values)
+			w.i64(6, chunk.uncompressedSize)
+			w.i64(7, chunk.compressedSize)
+			w.i64(9, chunk.offset)
+			w.endStruct()
+			w.endStruct()
+		}
+		w.i64(2, rowGroup.size)
+		w.i64(3, rowGroup.rows)
+		w.endStruct()
+	}
+
+	w.str(6, "ai-search")
+	w.endStruct()
+	return w.bytes()
+}
+
+// physicalType returns the Parquet type a column is stored as
+func physicalType(columnType ColumnType) int32 {
+	switch columnType {
+	case TypeInt64, TypeTimestamp:
+		return parquetInt64
+	case TypeFloat64:
+		return parquetDouble
+	case TypeBool:
+		return parquetBoolean
+	default:
+		return parquetByteArray
+	}
+}
+
+// thriftWriter encodes structs in the Thrift compact protocol, as Parquet
+// metadata is written. Fields must be written in increasing ID order.
+type thriftWriter struct {
+	buf    bytes.Buffer
+	fields []int16 // Last field ID written in each open struct
+}
+
+// newThriftWriter starts writing a top-level struct
+func newThriftWriter() *thriftWriter {
+	return &thriftWriter{fields: []int16{0}}
+}
+
+// bytes returns the encoded struct once it is ended
+func (w *thriftWriter) bytes() []byte {
+	return w.buf.Bytes()
+}
+
+// fieldHeader writes a field's ID, as a delta from the previous field when
+// small, and its type
+func (w *thriftWriter) fieldHeader(id int16, fieldType byte) {
+	last := &w.fields[len(w.fields)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | fieldType)
+	} else {
+		w.buf.WriteByte(fieldType)
+		w.varint(zigzag(int64(id)))
+	}
+	*last = id
+}
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.fieldHeader(id, thriftI32)
+	w.varint(zigzag(int64(v)))
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.fieldHeader(id, thriftI64)
+	w.varint(zigzag(v))
+}
+
+func (w *thriftWriter) str(id int16, s string) {
+	w.fieldHeader(id, thriftBinary)
+	w.varint(uint64(len(s)))
+	w.buf.WriteString(s)
+}
+
+func (w *thriftWriter) boolean(id int16, v bool) {
+	if v {
+		w.fieldHeader(id, thriftTrue)
+	} else {
+		w.fieldHeader(id, thriftFalse)
+	}
+}
+
+// beginStruct starts a struct field, closed with endStruct
+func (w *thriftWriter) beginStruct(id int16) {
+	w.fieldHeader(id, thriftStruct)
+	w.fields = append(w.fields, 0)
+}
+
+// beginElement starts a struct within a list, closed with endStruct
+func (w *thriftWriter) beginElement() {
+	w.fields = append(w.fields, 0)
+}
+
+// endStruct writes the stop byte of the innermost open struct
+func (w *thriftWriter) endStruct() {
+	w.buf.WriteByte(0)
+	w.fields = w.fields[:len(w.fields)-1]
+}
+
+// listHeader starts a list field of size elements, written after it
+func (w *thriftWriter) listHeader(id int16, elementType byte, size int) {
+	w.fieldHeader(id, thriftList)
+	if size < 15 {
+		w.buf.WriteByte(byte(size)<<4 | elementType)
+	} else {
+		w.buf.WriteByte(0xf0 | elementType)
+		w.varint(uint64(size))
+	}
+}
+
+func (w *thriftWriter) varint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	w.buf.Write(buf[:binary.PutUvarint(buf[:], v)])
+}
+
+// zigzag maps signed integers to unsigned ones so small magnitudes stay small
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
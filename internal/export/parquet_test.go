@@ -0,0 +1,227 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParquetRoundTrip(t *testing.T) {
+	table := &Table{
+		Name: "test",
+		Columns: []Column{
+			{Name: "id", Type: TypeInt64},
+			{Name: "query", Type: TypeString},
+			{Name: "score", Type: TypeFloat64},
+			{Name: "relevant", Type: TypeBool},
+			{Name: "created_at", Type: TypeTimestamp},
+		},
+	}
+	// More than eight rows so booleans span several bytes
+	base := time.Date(2024, 1, 2, 3, 4, 5, 678000, time.UTC)
+	for i := 0; i < 11; i++ {
+		table.Rows = append(table.Rows, []interface{}{
+			int64(i - 3), fmt.Sprintf("query %d é", i), float64(i) / 3, i%3 == 0, base.Add(time.Duration(i) * time.Second),
+		})
+	}
+
+	data, err := encodeParquet(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	columns, rows := decodeParquet(t, data)
+
+	var names []string
+	for _, column := range table.Columns {
+		names = append(names, column.Name)
+	}
+	if !reflect.DeepEqual(columns, names) {
+		t.Errorf("columns = %v, want %v", columns, names)
+	}
+	if !reflect.DeepEqual(rows, table.Rows) {
+		t.Errorf("rows = %v, want %v", rows, table.Rows)
+	}
+}
+
+func TestParquetRejectsMistypedValues(t *testing.T) {
+	table := &Table{
+		Columns: []Column{{Name: "id", Type: TypeInt64}},
+		Rows:    [][]interface{}{{"1"}},
+	}
+	if _, err := encodeParquet(table); err == nil {
+		t.Error("encodeParquet accepted a string in an int64 column")
+	}
+}
+
+// decodeParquet reads the columns and rows of a file written by
+// encodeParquet, following its footer and page headers
+func decodeParquet(t *testing.T, data []byte) ([]string, [][]interface{}) {
+	t.Helper()
+	if len(data) < 12 || string(data[:4]) != "PAR1" || string(data[len(data)-4:]) != "PAR1" {
+		t.Fatal("missing PAR1 magic")
+	}
+	footerSize := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footerStart := len(data) - 8 - footerSize
+	metadata, _ := readThriftStruct(t, data[footerStart:len(data)-8])
+
+	schema := metadata[2].([]interface{})
+	var names []string
+	var types []int64
+	for _, element := range schema[1:] {
+		fields := element.(thriftFields)
+		names = append(names, string(fields[4].([]byte)))
+		types = append(types, fields[1].(int64))
+	}
+	if got := schema[0].(thriftFields)[5].(int64); got != int64(len(names)) {
+		t.Fatalf("root schema has %d children, want %d", got, len(names))
+	}
+
+	var rows [][]interface{}
+	for _, group := range metadata[4].([]interface{}) {
+		rowGroup := group.(thriftFields)
+		count := int(rowGroup[3].(int64))
+		groupRows := make([][]interface{}, count)
+		for i := range groupRows {
+			groupRows[i] = make([]interface{}, len(names))
+		}
+
+		for c, chunk := range rowGroup[1].([]interface{}) {
+			columnMeta := chunk.(thriftFields)[3].(thriftFields)
+			if codec := columnMeta[4].(int64); codec != parquetCodecGzip {
+				t.Fatalf("column %s has codec %d", names[c], codec)
+			}
+			offset := int(columnMeta[9].(int64))
+			header, headerSize := readThriftStruct(t, data[offset:])
+			compressedSize := int(header[3].(int64))
+			if total := int(columnMeta[7].(int64)); total != headerSize+compressedSize {
+				t.Fatalf("column %s chunk size %d, want %d", names[c], total, headerSize+compressedSize)
+			}
+
+			reader, err := gzip.NewReader(bytes.NewReader(data[offset+headerSize : offset+headerSize+compressedSize]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			values, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if size := int(header[2].(int64)); size != len(values) {
+				t.Fatalf("column %s page has %d bytes, header says %d", names[c], len(values), size)
+			}
+
+			timestamp := schema[c+1].(thriftFields)[6] == int64(parquetConvertedTimestampMicros)
+			for i := 0; i < count; i++ {
+				switch types[c] {
+				case parquetInt64:
+					v := int64(binary.LittleEndian.Uint64(values))
+					values = values[8:]
+					if timestamp {
+						groupRows[i][c] = time.UnixMicro(v).UTC()
+					} else {
+						groupRows[i][c] = v
+					}
+				case parquetDouble:
+					groupRows[i][c] = math.Float64frombits(binary.LittleEndian.Uint64(values))
+					values = values[8:]
+				case parquetBoolean:
+					groupRows[i][c] = values[i/8]&(1<<(i%8)) != 0
+				case parquetByteArray:
+					size := int(binary.LittleEndian.Uint32(values))
+					groupRows[i][c] = string(values[4 : 4+size])
+					values = values[4+size:]
+				}
+			}
+		}
+		rows = append(rows, groupRows...)
+	}
+	if got := metadata[3].(int64); got != int64(len(rows)) {
+		t.Errorf("footer counts %d rows, read %d", got, len(rows))
+	}
+	return names, rows
+}
+
+// thriftFields is a decoded Thrift struct by field ID. Integers decode to
+// int64, binaries to []byte, lists to []interface{}.
+type thriftFields map[int16]interface{}
+
+// readThriftStruct decodes a Thrift compact protocol struct and returns
+// the number of bytes it took
+func readThriftStruct(t *testing.T, data []byte) (thriftFields, int) {
+	t.Helper()
+	r := &thriftReader{data: data}
+	fields := r.readStruct(t)
+	return fields, r.pos
+}
+
+type thriftReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *thriftReader) readStruct(t *testing.T) thriftFields {
+	fields := thriftFields{}
+	var id int16
+	for {
+		header := r.data[r.pos]
+		r.pos++
+		if header == 0 {
+			return fields
+		}
+		fieldType := header & 0x0f
+		if delta := int16(header >> 4); delta != 0 {
+			id += delta
+		} else {
+			id = int16(r.zigzag())
+		}
+		fields[id] = r.readValue(t, fieldType)
+	}
+}
+
+func (r *thriftReader) readValue(t *testing.T, valueType byte) interface{} {
+	switch valueType {
+	case thriftTrue:
+		return true
+	case thriftFalse:
+		return false
+	case thriftI32, thriftI64:
+		return r.zigzag()
+	case thriftBinary:
+		size := int(r.varint())
+		value := r.data[r.pos : r.pos+size]
+		r.pos += size
+		return value
+	case thriftStruct:
+		return r.readStruct(t)
+	case thriftList:
+		header := r.data[r.pos]
+		r.pos++
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(r.varint())
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			list[i] = r.readValue(t, header&0x0f)
+		}
+		return list
+	}
+	t.Fatalf("unexpected Thrift type %d", valueType)
+	return nil
+}
+
+func (r *thriftReader) varint() uint64 {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) zigzag() int64 {
+	v := r.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
@@ -0,0 +1,173 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// s3Destination uploads batches as objects to an S3 bucket, signing
+// requests with AWS Signature Version 4
+type s3Destination struct {
+	bucket     string
+	prefix     string
+	config     DestinationConfig
+	httpClient *http.Client
+}
+
+// newS3Destination creates a destination for s3://bucket/prefix
+func newS3Destination(parsed *url.URL, config DestinationConfig) (Destination, error) {
+	if parsed.Host == "" {
+		return nil, fmt.Errorf("S3 export destination needs a bucket: s3://bucket/prefix")
+	}
+	if config.AWSAccessKeyID == "" || config.AWSSecretAccessKey == "" {
+		return nil, fmt.Errorf("S3 export destination needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	if config.S3Region == "" {
+		config.S3Region = "us-east-1"
+	}
+
+	return &s3Destination{
+		bucket:     parsed.Host,
+		prefix:     trimSlashes(parsed.Path),
+		config:     config,
+		httpClient: &http.Client{Timeout: config.Timeout},
+	}, nil
+}
+
+// Name identifies the bucket and prefix
+func (d *s3Destination) Name() string {
+	return "s3://" + d.bucket + "/" + d.prefix
+}
+
+// LoadsRows is false: batches are uploaded as objects
+func (d *s3Destination) LoadsRows() bool {
+	return false
+}
+
+// Write uploads a batch as an object
+func (d *s3Destination) Write(ctx context.Context, batch *Batch) (string, error) {
+	key := objectKey(d.prefix, batch)
+
+	// Virtual-hosted style on AWS, path style on other endpoints
+	var objectURL string
+	if d.config.S3Endpoint != "" {
+		objectURL = strings.TrimRight(d.config.S3Endpoint, "/") + "/" + d.bucket + "/" + s3Escape(key)
+	} else {
+		objectURL = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", d.bucket, d.config.S3Region, s3Escape(key))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", objectURL, bytes.NewReader(batch.Data))
+	if err != nil {
+		return "", fmt.Errorf("failed to create S3 request: %w", err)
+	}
+	if batch.Format == FormatCSV {
+		req.Header.Set("Content-Type", "text/csv")
+	} else {
+		req.Header.Set("Content-Type", "application/vnd.apache.parquet")
+	}
+	d.sign(req, batch.Data, time.Now())
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload to S3: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("failed to upload to S3: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return "s3://" + d.bucket + "/" + key, nil
+}
+
+// sign adds AWS Signature Version 4 headers to a request, signing its host
+// and every Content-Type and X-Amz header
+func (d *s3Destination) sign(req *http.Request, payload []byte, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if d.config.AWSSessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", d.config.AWSSessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "content-type" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + d.config.S3Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+d.config.AWSSecretAccessKey), date)
+	key = hmacSHA256(key, d.config.S3Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		d.config.AWSAccessKeyID, scope, signedHeaders, signature))
+}
+
+// s3Escape percent-encodes an object key as SigV4 expects, leaving only
+// unreserved characters and slashes
+func s3Escape(key string) string {
+	var escaped strings.Builder
+	for _, b := range []byte(key) {
+		switch {
+		case b >= 'A' && b <= 'Z', b >= 'a' && b <= 'z', b >= '0' && b <= '9',
+			b == '-', b == '_', b == '.', b == '~', b == '/':
+			escaped.WriteByte(b)
+		default:
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+	return escaped.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
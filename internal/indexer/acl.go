@@ -0,0 +1,112 @@
+package indexer
+
+import (
+	"strings"
+
+	chroma "github.com/amikos-tech/chroma-go/pkg/api/v2"
+)
+
+// ACLAttribute names the attribute listing who may see a document, as
+// principals separated by commas, e.g. "group:eng,user:ana@example.com".
+// Documents without it are public.
+const ACLAttribute = "acl"
+
+// ChromaDB keeps no lists in metadata, so each principal of a document's
+// ACL is a boolean key of its own, next to a flag marking restricted
+// documents
+const (
+	aclPrincipalPrefix = "acl:"
+	aclRestrictedKey   = "acl_restricted"
+)
+
+// Access restricts a search to public documents and those whose ACL grants
+// one of the caller's principals
+type Access struct {
+	Principals []string
+}
+
+// ParseACL returns the principals of an ACL attribute, trimmed, lower-cased
+// and without duplicates
+func ParseACL(acl string) []string {
+	var principals []string
+	seen := make(map[string]bool)
+	for _, principal := range strings.Split(acl, ",") {
+		principal = strings.ToLower(strings.TrimSpace(principal))
+		if principal != "" && !seen[principal] {
+			seen[principal] = true
+			principals = append(principals, principal)
+		}
+	}
+	return principals
+}
+
+// Allows reports whether the access sees a document with the given ACL
+// attribute. A nil access sees every document.
+func (a *Access) Allows(acl string) bool {
+	if a == nil {
+		return true
+	}
+	principals := ParseACL(acl)
+	if len(principals) == 0 {
+		return true
+	}
+	for _, principal := range principals {
+		for _, granted := range a.Principals {
+			if principal == granted {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// documentACL returns the principals allowed to see a document, none when
+// it is public
+func documentACL(doc *Document) []string {
+	return ParseACL(doc.Attributes[ACLAttribute])
+}
+
+// chromaACLAttributes returns the metadata marking a chunk's ACL
+func chromaACLAttributes(principals []string) []*chroma.MetaAttribute {
+	attributes := []*chroma.MetaAttribute{chroma.NewBoolAttribute(aclRestrictedKey, len(principals) > 0)}
+	for _, principal := range principals {
+		attributes = append(attributes, chroma.NewBoolAttribute(aclPrincipalPrefix+principal, true))
+	}
+	return attributes
+}
+
+// chromaACLFilter matches the chunks of public documents and of those
+// granting one of the access's principals. Every chunk carries the
+// restricted flag, backfilled by backfillChunkFlags on those indexed before
+// ACLs, since ChromaDB filters cannot match chunks lacking it.
+func chromaACLFilter(access *Access) chroma.WhereClause {
+	clauses := []chroma.WhereClause{chroma.NotEqBool(aclRestrictedKey, true)}
+	for _, principal := range access.Principals {
+		clauses = append(clauses, chroma.EqBool(aclPrincipalPrefix+principal, true))
+	}
+	if len(clauses) == 1 {
+		return clauses[0]
+	}
+	return chroma.Or(clauses...)
+}
+
+// elasticACLFilter matches the chunks of public documents, which have no
+// acl field, and of those granting one of the access's principals
+func elasticACLFilter(access *Access) map[string]interface{} {
+	should := []map[string]interface{}{
+		{"bool": map[string]interface{}{
+			"must_not": map[string]interface{}{"exists": map[string]string{"field": "acl"}},
+		}},
+	}
+	if len(access.Principals) > 0 {
+		should = append(should, map[string]interface{}{
+			"terms": map[string]interface{}{"acl": access.Principals},
+		})
+	}
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"should":               should,
+			"minimum_should_match": 1,
+		},
+	}
+}
@@ -0,0 +1,101 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+
+	chroma "github.com/amikos-tech/chroma-go/pkg/api/v2"
+)
+
+// chunkFlagsKey is the collection metadata key recording which version of
+// the chunk flags every chunk of the collection carries. ChromaDB filters
+// cannot match chunks lacking a key, so chunks indexed before a flag
+// existed get it before searches filter on it.
+const chunkFlagsKey = "chunk_flags"
+
+// chunkFlagsVersion is raised whenever a flag is added to chunkFlags
+const chunkFlagsVersion = 2
+
+// chunkFlags are the boolean keys searches filter chunks on, with the value
+// of chunks indexed before they existed
+var chunkFlags = []struct {
+	key   string
+	value bool
+}{
+	{aclRestrictedKey, false}, // Chunks indexed before ACLs are public
+	{deletedKey, false},       // Soft deletion marks only deleted chunks
+}
+
+// backfillPageSize is the number of chunks read and updated at a time
+const backfillPageSize = 1000
+
+// backfillChunkFlags sets the flags chunks lack to their default value,
+// once per collection and chunkFlagsVersion
+func (i *hybridIndexer) backfillChunkFlags(ctx context.Context) error {
+	if i.collection == nil {
+		return nil
+	}
+	if metadata := i.collection.Metadata(); metadata != nil {
+		if version, ok := metadata.GetInt(chunkFlagsKey); ok && version >= chunkFlagsVersion {
+			return nil
+		}
+	}
+
+	backfilled := 0
+	for offset := 0; ; offset += backfillPageSize {
+		result, err := i.collection.Get(ctx,
+			chroma.WithIncludeGet(chroma.IncludeMetadatas),
+			chroma.WithLimitGet(backfillPageSize),
+			chroma.WithOffsetGet(offset),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to read from ChromaDB: %w", err)
+		}
+
+		ids := result.GetIDs()
+		metadatas := result.GetMetadatas()
+		var updatedIDs []chroma.DocumentID
+		var updates []chroma.DocumentMetadata
+		for j, id := range ids {
+			var missing []*chroma.MetaAttribute
+			for _, flag := range chunkFlags {
+				if j < len(metadatas) && metadatas[j] != nil {
+					if _, ok := metadatas[j].GetBool(flag.key); ok {
+						continue
+					}
+				}
+				missing = append(missing, chroma.NewBoolAttribute(flag.key, flag.value))
+			}
+			if len(missing) > 0 {
+				updatedIDs = append(updatedIDs, id)
+				updates = append(updates, chroma.NewDocumentMetadata(missing...))
+			}
+		}
+
+		// Updated metadata is merged into the chunks' own
+		if len(updatedIDs) > 0 {
+			err := i.collection.Update(ctx,
+				chroma.WithIDsUpdate(updatedIDs...),
+				chroma.WithMetadatasUpdate(updates...),
+			)
+			if err != nil {
+				return fmt.Errorf("failed to update ChromaDB: %w", err)
+			}
+			backfilled += len(updatedIDs)
+		}
+		if len(ids) < backfillPageSize {
+			break
+		}
+	}
+	if backfilled > 0 {
+		fmt.Printf("Backfilled the search flags of %d chunks in '%s'\n", backfilled, i.config.CollectionName)
+	}
+
+	err := i.updateCollectionMetadata(ctx, func(updated chroma.CollectionMetadata) {
+		updated.SetInt(chunkFlagsKey, chunkFlagsVersion)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record chunk flags: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package indexer
+
+import (
+	"ai-search/internal/chunker"
+	"context"
+	"fmt"
+
+	chroma "github.com/amikos-tech/chroma-go/pkg/api/v2"
+)
+
+// Collection metadata keys holding the collection's chunking settings
+const (
+	chunkSizeKey     = "chunk_size"
+	chunkOverlapKey  = "chunk_overlap"
+	chunkMinSizeKey  = "chunk_min_size"
+	chunkStrategyKey = "chunk_strategy"
+	chunkTablesKey   = "chunk_tables"
+	chunkFoldingKey  = "chunk_fold_diacritics"
+
+	// Whether chunks are indexed with an LLM-written context sentence
+	contextualEnrichmentKey = "contextual_enrichment"
+
+	// Corpus version searched when a request names none
+	defaultVersionKey = "default_version"
+)
+
+// ChunkContextKey is the chunk metadata key holding the sentence that
+// situates a chunk within its document when contextual enrichment is on.
+// It is embedded and searched together with the chunk text.
+const ChunkContextKey = "context"
+
+// setChunkingMetadata writes chunking settings into collection metadata
+func setChunkingMetadata(metadata chroma.CollectionMetadata, config chunker.Config) {
+	metadata.SetInt(chunkSizeKey, int64(config.ChunkSize))
+	metadata.SetInt(chunkOverlapKey, int64(config.OverlapSize))
+	metadata.SetInt(chunkMinSizeKey, int64(config.MinChunkSize))
+	metadata.SetString(chunkStrategyKey, config.Strategy)
+	metadata.SetBool(chunkTablesKey, config.Tables)
+	metadata.SetBool(chunkFoldingKey, config.FoldDiacritics)
+}
+
+// chunkingFromMetadata reads the chunking settings persisted with a
+// collection. It returns false for collections created before chunking
+// settings were stored.
+func chunkingFromMetadata(metadata chroma.CollectionMetadata) (chunker.Config, bool) {
+	if metadata == nil {
+		return chunker.Config{}, false
+	}
+
+	size, ok := metadata.GetInt(chunkSizeKey)
+	if !ok {
+		return chunker.Config{}, false
+	}
+	overlap, _ := metadata.GetInt(chunkOverlapKey)
+	minSize, _ := metadata.GetInt(chunkMinSizeKey)
+	strategy, _ := metadata.GetString(chunkStrategyKey)
+	tables, _ := metadata.GetBool(chunkTablesKey)
+	folding, _ := metadata.GetBool(chunkFoldingKey)
+
+	config := chunker.Config{
+		ChunkSize:    int(size),
+		OverlapSize:  int(overlap),
+		MinChunkSize: int(minSize),
+		Strategy:     strategy,
+		Tables:       tables,
+
+		FoldDiacritics: folding,
+	}
+	return config.WithDefaults(), true
+}
+
+// resolveChunking settles the chunking settings of the collection. A new
+// collection keeps the configured settings; an existing one keeps those it
+// was created with unless OverrideChunking is set, in which case the
+// configured settings replace them.
+func (i *hybridIndexer) resolveChunking(ctx context.Context) error {
+	i.chunking = i.config.Chunking.WithDefaults()
+	if i.collection == nil {
+		return nil
+	}
+
+	metadata := i.collection.Metadata()
+	persisted, ok := chunkingFromMetadata(metadata)
+	if ok && !i.config.OverrideChunking {
+		i.chunking = persisted
+		return nil
+	}
+	if ok && persisted == i.chunking {
+		return nil
+	}
+
+	err := i.updateCollectionMetadata(ctx, func(updated chroma.CollectionMetadata) {
+		setChunkingMetadata(updated, i.chunking)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save chunking settings: %w", err)
+	}
+	return nil
+}
+
+// resolveEnrichment settles whether the collection uses contextual
+// enrichment, the same way resolveChunking settles its chunking settings,
+// with OverrideEnrichment in place of OverrideChunking
+func (i *hybridIndexer) resolveEnrichment(ctx context.Context) error {
+	i.enrichment = i.config.ContextualEnrichment
+	if i.collection == nil {
+		return nil
+	}
+
+	var persisted, ok bool
+	if metadata := i.collection.Metadata(); metadata != nil {
+		persisted, ok = metadata.GetBool(contextualEnrichmentKey)
+	}
+	if ok && !i.config.OverrideEnrichment {
+		i.enrichment = persisted
+		return nil
+	}
+	if ok && persisted == i.enrichment {
+		return nil
+	}
+
+	err := i.updateCollectionMetadata(ctx, func(updated chroma.CollectionMetadata) {
+		updated.SetBool(contextualEnrichmentKey, i.enrichment)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save contextual enrichment setting: %w", err)
+	}
+	return nil
+}
+
+// resolveDefaultVersion settles the corpus version searched by default, the
+// same way resolveChunking settles the chunking settings, with
+// OverrideDefaultVersion in place of OverrideChunking
+func (i *hybridIndexer) resolveDefaultVersion(ctx context.Context) error {
+	i.defaultVersion = i.config.DefaultVersion
+	if i.collection == nil {
+		return nil
+	}
+
+	var persisted string
+	var ok bool
+	if metadata := i.collection.Metadata(); metadata != nil {
+		persisted, ok = metadata.GetString(defaultVersionKey)
+	}
+	if ok && !i.config.OverrideDefaultVersion {
+		i.defaultVersion = persisted
+		return nil
+	}
+	if ok && persisted == i.defaultVersion {
+		return nil
+	}
+
+	err := i.updateCollectionMetadata(ctx, func(updated chroma.CollectionMetadata) {
+		updated.SetString(defaultVersionKey, i.defaultVersion)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save default version: %w", err)
+	}
+	return nil
+}
+
+// updateCollectionMetadata applies set to a copy of the collection's
+// metadata and saves it, keeping the keys set leaves alone
+func (i *hybridIndexer) updateCollectionMetadata(ctx context.Context, set func(chroma.CollectionMetadata)) error {
+	updated := chroma.NewEmptyMetadata()
+	if metadata := i.collection.Metadata(); metadata != nil {
+		for _, key := range metadata.Keys() {
+			if value, ok := metadata.GetRaw(key); ok {
+				updated.SetRaw(key, value)
+			}
+		}
+	}
+	set(updated)
+
+	return i.collection.ModifyMetadata(ctx, updated)
+}
+
+// Chunker returns the chunker of the collection
+func (i *hybridIndexer) Chunker() chunker.Chunker {
+	return i.config.Chunker
+}
+
+// Chunking returns the chunking settings of the collection
+func (i *hybridIndexer) Chunking() chunker.Config {
+	return i.chunking
+}
+
+// ContextualEnrichment reports whether the collection's chunks are indexed
+// with a context sentence
+func (i *hybridIndexer) ContextualEnrichment() bool {
+	return i.enrichment
+}
+
+// DefaultVersion returns the corpus version searched in the collection
+// when a request names none
+func (i *hybridIndexer) DefaultVersion() string {
+	return i.defaultVersion
+}
+
+// chunkContext returns the context sentence of a chunk, if it has one
+func chunkContext(chunk *chunker.Chunk) string {
+	sentence, _ := chunk.Metadata[ChunkContextKey].(string)
+	return sentence
+}
@@ -0,0 +1,69 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	chroma "github.com/amikos-tech/chroma-go/pkg/api/v2"
+)
+
+// deletedKey marks the chunks of a soft-deleted document in both indexes,
+// so that searches skip them until the document is restored or purged.
+// ChromaDB chunks carry it unset as well, for filters to match them.
+const deletedKey = "deleted"
+
+// SetDeleted marks the chunks of a document as soft-deleted, or clears the
+// mark when the document is restored
+func (i *hybridIndexer) SetDeleted(ctx context.Context, documentID string, deleted bool) error {
+	if i.collection == nil {
+		return fmt.Errorf("ChromaDB collection not initialized")
+	}
+
+	result, err := i.collection.Get(ctx, chroma.WithWhereGet(chroma.EqString("document_id", documentID)))
+	if err != nil {
+		return fmt.Errorf("failed to read from ChromaDB: %w", err)
+	}
+	if ids := result.GetIDs(); len(ids) > 0 {
+		// Updated metadata is merged into the chunks' own
+		metadatas := make([]chroma.DocumentMetadata, len(ids))
+		for j := range ids {
+			metadatas[j] = chroma.NewDocumentMetadata(chroma.NewBoolAttribute(deletedKey, deleted))
+		}
+		err := i.collection.Update(ctx,
+			chroma.WithIDsUpdate(ids...),
+			chroma.WithMetadatasUpdate(metadatas...),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update ChromaDB: %w", err)
+		}
+	}
+
+	url := fmt.Sprintf("%s/%s/_update_by_query?conflicts=proceed&refresh=true", i.config.ElasticURL, i.config.ElasticIndex)
+	payload := map[string]interface{}{
+		"query": map[string]interface{}{
+			"term": map[string]interface{}{"document_id": documentID},
+		},
+		"script": map[string]interface{}{
+			"source": "ctx._source." + deletedKey + " = params.deleted",
+			"params": map[string]interface{}{"deleted": deleted},
+		},
+	}
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := i.elasticWrite(ctx, "POST", url, "application/json", jsonData)
+	if err != nil {
+		return fmt.Errorf("failed to update Elasticsearch: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Elasticsearch update failed with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
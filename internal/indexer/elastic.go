@@ -0,0 +1,314 @@
+package indexer
+
+import (
+	"ai-search/internal/chunker"
+	"ai-search/internal/metrics"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxRetryBackoff caps the wait between Elasticsearch write attempts
+const maxRetryBackoff = 10 * time.Second
+
+// StageElasticsearch names Elasticsearch writes in dead letters
+const StageElasticsearch = "elasticsearch"
+
+// ErrDeadLettered is returned when some chunks of a document failed to
+// index and were handed to the dead-letter queue
+var ErrDeadLettered = errors.New("chunks were dead-lettered")
+
+// DeadLetterQueue keeps chunks that could not be indexed after all retries
+// so they can be processed again
+type DeadLetterQueue interface {
+	Add(ctx context.Context, item *FailedChunk) error
+}
+
+// FailedChunk is a chunk a backend permanently rejected or never accepted
+type FailedChunk struct {
+	Stage      string
+	DocumentID string
+	ChunkID    string
+	Payload    []byte // The document as it was to be written
+	Error      string
+	Attempts   int
+}
+
+// retryableStatus reports whether Elasticsearch may accept a write that
+// failed with this status if it is sent again later
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+// retryBackoff returns the wait before the given retry, doubling from
+// RetryBackoff each time
+func (i *hybridIndexer) retryBackoff(retry int) time.Duration {
+	backoff := i.config.RetryBackoff << (retry - 1)
+	if backoff <= 0 || backoff > maxRetryBackoff {
+		return maxRetryBackoff
+	}
+	return backoff
+}
+
+// sleepBackoff waits before the given retry. It returns false if the
+// context ended first.
+func (i *hybridIndexer) sleepBackoff(ctx context.Context, retry int) bool {
+	timer := time.NewTimer(i.retryBackoff(retry))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// elasticWrite sends a write request to Elasticsearch, retrying with
+// backoff while it is unreachable or answers 429 or 503. The caller closes
+// the returned response body.
+func (i *hybridIndexer) elasticWrite(ctx context.Context, method, url, contentType string, body []byte) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", contentType)
+
+		resp, err := i.httpClient.Do(req)
+		if err == nil && !retryableStatus(resp.StatusCode) {
+			return resp, nil
+		}
+		if err == nil {
+			resp.Body.Close()
+			err = fmt.Errorf("Elasticsearch returned status %d", resp.StatusCode)
+		}
+
+		if attempt > i.config.MaxRetries || ctx.Err() != nil {
+			return nil, fmt.Errorf("%w after %d attempts", err, attempt)
+		}
+		metrics.ElasticRetries.Inc()
+		if !i.sleepBackoff(ctx, attempt) {
+			return nil, fmt.Errorf("%w after %d attempts", err, attempt)
+		}
+	}
+}
+
+// bulkItem is a chunk waiting to be written through the bulk API
+type bulkItem struct {
+	chunkID  string
+	document []byte
+	attempts int
+	err      string
+}
+
+// bulkResponse is the part of a bulk API response reporting item results
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		ID     string `json:"_id"`
+		Status int    `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// indexInElasticsearch indexes the chunks of a document in Elasticsearch
+// through the bulk API. Chunks rejected with 429 or 503 are retried with
+// backoff; those still failing are dead-lettered and reported.
+func (i *hybridIndexer) indexInElasticsearch(ctx context.Context, doc *Document, chunks []*chunker.Chunk) error {
+	pending := make([]*bulkItem, 0, len(chunks))
+	acl := documentACL(doc)
+	for position, chunk := range chunks {
+		docData := ElasticsearchDoc{
+			DocumentID:  doc.ID,
+			ChunkID:     chunk.ID,
+			Text:        chunk.Text,
+			Context:     chunkContext(chunk),
+			Title:       doc.Title,
+			URL:         doc.URL,
+			Description: doc.Description,
+			Keywords:    doc.Keywords,
+			Metadata:    chunk.Metadata,
+			Attributes:  doc.Attributes,
+			ACL:         acl,
+			Language:    doc.Language,
+			StartPos:    chunk.StartPos,
+			EndPos:      chunk.EndPos,
+			Position:    position,
+			ChunkCount:  len(chunks),
+		}
+
+		document, err := json.Marshal(docData)
+		if err != nil {
+			return err
+		}
+		pending = append(pending, &bulkItem{chunkID: chunk.ID, document: document})
+	}
+
+	return i.bulkWrite(ctx, doc.ID, pending)
+}
+
+// bulkWrite indexes the chunks of a document through the bulk API,
+// retrying those rejected with 429 or 503 and dead-lettering the rest
+func (i *hybridIndexer) bulkWrite(ctx context.Context, documentID string, pending []*bulkItem) error {
+	total := len(pending)
+	var failed []*bulkItem
+	for retry := 1; len(pending) > 0; retry++ {
+		retryable, rejected := i.bulkIndex(ctx, pending)
+		failed = append(failed, rejected...)
+
+		if len(retryable) == 0 {
+			break
+		}
+		if retry > i.config.MaxRetries {
+			failed = append(failed, retryable...)
+			break
+		}
+		metrics.ElasticRetries.Add(float64(len(retryable)))
+		if !i.sleepBackoff(ctx, retry) {
+			failed = append(failed, retryable...)
+			break
+		}
+		pending = retryable
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	i.deadLetter(ctx, documentID, failed)
+	return fmt.Errorf("%d of %d chunks failed (%s): %w", len(failed), total, failed[0].err, ErrDeadLettered)
+}
+
+// bulkIndex sends one bulk request and splits the items that failed into
+// those worth retrying and those Elasticsearch rejected for good
+func (i *hybridIndexer) bulkIndex(ctx context.Context, items []*bulkItem) (retryable, rejected []*bulkItem) {
+	indexName := i.config.ElasticIndex
+
+	var body bytes.Buffer
+	for _, item := range items {
+		item.attempts++
+		action, _ := json.Marshal(map[string]interface{}{
+			"index": map[string]string{"_index": indexName, "_id": item.chunkID},
+		})
+		body.Write(action)
+		body.WriteByte('\n')
+		body.Write(item.document)
+		body.WriteByte('\n')
+	}
+
+	url := fmt.Sprintf("%s/_bulk", i.config.ElasticURL)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, &body)
+	if err != nil {
+		return nil, failAll(items, err.Error())
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := i.httpClient.Do(req)
+	if err != nil {
+		return failAll(items, err.Error()), nil
+	}
+	defer resp.Body.Close()
+
+	if retryableStatus(resp.StatusCode) {
+		return failAll(items, fmt.Sprintf("Elasticsearch returned status %d", resp.StatusCode)), nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, failAll(items, fmt.Sprintf("Elasticsearch bulk request failed with status %d: %s", resp.StatusCode, message))
+	}
+
+	var response bulkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, failAll(items, fmt.Sprintf("failed to decode bulk response: %v", err))
+	}
+	if !response.Errors {
+		return nil, nil
+	}
+
+	// Items are reported in request order
+	for j, result := range response.Items {
+		if j >= len(items) {
+			break
+		}
+		for _, outcome := range result {
+			if outcome.Status >= 200 && outcome.Status < 300 {
+				continue
+			}
+
+			item := items[j]
+			item.err = fmt.Sprintf("status %d", outcome.Status)
+			if outcome.Error != nil {
+				item.err = fmt.Sprintf("%s: %s", outcome.Error.Type, outcome.Error.Reason)
+			}
+			if retryableStatus(outcome.Status) {
+				retryable = append(retryable, item)
+			} else {
+				rejected = append(rejected, item)
+			}
+		}
+	}
+
+	return retryable, rejected
+}
+
+// RetryChunk writes a dead-lettered chunk to its backend again. Failures
+// are returned rather than dead-lettered a second time.
+func (i *hybridIndexer) RetryChunk(ctx context.Context, item *FailedChunk) error {
+	if item.Stage != StageElasticsearch {
+		return fmt.Errorf("cannot retry chunks of stage %q", item.Stage)
+	}
+
+	url := fmt.Sprintf("%s/%s/_doc/%s", i.config.ElasticURL, i.config.ElasticIndex, item.ChunkID)
+	resp, err := i.elasticWrite(ctx, "PUT", url, "application/json", item.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to index chunk %s in Elasticsearch: %w", item.ChunkID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("Elasticsearch rejected chunk %s with status %d: %s", item.ChunkID, resp.StatusCode, message)
+	}
+	return nil
+}
+
+// failAll records the same error on every item
+func failAll(items []*bulkItem, message string) []*bulkItem {
+	for _, item := range items {
+		item.err = message
+	}
+	return items
+}
+
+// deadLetter records chunks that could not be indexed. It runs even when
+// the request that indexed them was cancelled.
+func (i *hybridIndexer) deadLetter(ctx context.Context, documentID string, items []*bulkItem) {
+	metrics.DeadLetters.Add(float64(len(items)), StageElasticsearch)
+	if i.config.DeadLetters == nil {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	for _, item := range items {
+		err := i.config.DeadLetters.Add(ctx, &FailedChunk{
+			Stage:      StageElasticsearch,
+			DocumentID: documentID,
+			ChunkID:    item.chunkID,
+			Payload:    item.document,
+			Error:      item.err,
+			Attempts:   item.attempts,
+		})
+		if err != nil {
+			fmt.Printf("Failed to dead-letter chunk %s: %v\n", item.chunkID, err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// foldedAnalyzer names the analyzer that lower-cases words and folds their
+// accents, as in "café" to "cafe", and foldedSubfield the subfield of each
+// text field analyzed by it
+const (
+	foldedAnalyzer = "folded"
+	foldedSubfield = "folded"
+)
+
+// foldedFields are the text fields keyword-searched with accents folded
+// when the collection folds diacritics
+var foldedFields = []string{"text", "context", "title", "description", "keywords"}
+
+// foldingSettings returns the analysis settings defining the folded
+// analyzer. Folding keeps the original token too, so accented queries still
+// rank exact spellings first.
+func foldingSettings() map[string]interface{} {
+	return map[string]interface{}{
+		"analysis": map[string]interface{}{
+			"filter": map[string]interface{}{
+				"folding": map[string]interface{}{"type": "asciifolding", "preserve_original": true},
+			},
+			"analyzer": map[string]interface{}{
+				foldedAnalyzer: map[string]interface{}{
+					"tokenizer": "standard",
+					"filter":    []string{"lowercase", "folding"},
+				},
+			},
+		},
+	}
+}
+
+// foldingMappings returns the mapping of the text fields with a folded
+// subfield each
+func foldingMappings() map[string]interface{} {
+	properties := make(map[string]interface{}, len(foldedFields))
+	for _, field := range foldedFields {
+		properties[field] = map[string]interface{}{
+			"type":     "text",
+			"analyzer": "standard",
+			"fields": map[string]interface{}{
+				foldedSubfield: map[string]string{"type": "text", "analyzer": foldedAnalyzer},
+			},
+		}
+	}
+	return properties
+}
+
+// foldedSearchFields returns the folded subfields of the searched text
+// fields, boosted like them
+func foldedSearchFields(fields []string) []string {
+	var folded []string
+	for _, field := range fields {
+		name, boost, boosted := strings.Cut(field, "^")
+		if !slices.Contains(foldedFields, name) {
+			continue
+		}
+		name += "." + foldedSubfield
+		if boosted {
+			name += "^" + boost
+		}
+		folded = append(folded, name)
+	}
+	return folded
+}
+
+// addFoldingMapping maps the folded subfields of an index created before
+// the collection folded diacritics. Every index is created with the folded
+// analyzer, but analyzers cannot be added to an open index, so one created
+// before it existed keeps matching accents exactly until it is recreated.
+// Chunks indexed before are folded once they are indexed again.
+func (i *hybridIndexer) addFoldingMapping(ctx context.Context, url string) {
+	mapping, _ := json.Marshal(map[string]interface{}{"properties": foldingMappings()})
+	req, _ := http.NewRequestWithContext(ctx, "PUT", url+"/_mapping", strings.NewReader(string(mapping)))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := i.httpClient.Do(req)
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		fmt.Printf("Elasticsearch index '%s' was created without diacritics folding; recreate it to fold diacritics\n", i.config.ElasticIndex)
+	}
+}
@@ -3,6 +3,7 @@ package indexer
 import (
 	"ai-search/internal/chunker"
 	"ai-search/internal/embeddings"
+	"ai-search/internal/metrics"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -11,6 +12,7 @@ import (
 	"time"
 
 	chroma "github.com/amikos-tech/chroma-go/pkg/api/v2"
+	chromaembeddings "github.com/amikos-tech/chroma-go/pkg/embeddings"
 )
 
 // Indexer defines the interface for indexing content
@@ -19,7 +21,41 @@ type Indexer interface {
 	Index(ctx context.Context, doc *Document, chunks []*chunker.Chunk, embeddings [][]float32) error
 
 	// Search performs a search query
-	Search(ctx context.Context, query string, limit int) ([]*SearchResult, error)
+	Search(ctx context.Context, query string, limit int, opts SearchOptions) ([]*SearchResult, error)
+
+	// Delete removes all chunks of a document from the index
+	Delete(ctx context.Context, documentID string) error
+
+	// SetDeleted marks the chunks of a soft-deleted document so searches
+	// skip them, or clears the mark when the document is restored
+	SetDeleted(ctx context.Context, documentID string, deleted bool) error
+
+	// Chunker returns the chunker configured for the collection
+	Chunker() chunker.Chunker
+
+	// Chunking returns the chunking settings of the collection
+	Chunking() chunker.Config
+
+	// ContextualEnrichment reports whether the collection's chunks are
+	// indexed with an LLM-written sentence situating them in their document
+	ContextualEnrichment() bool
+
+	// DefaultVersion returns the corpus version searched in the collection
+	// when a request names none, "" to search every version
+	DefaultVersion() string
+
+	// RetryChunk writes a dead-lettered chunk to its backend again
+	RetryChunk(ctx context.Context, item *FailedChunk) error
+
+	// Export reads what the indexes hold for a document, embeddings included
+	Export(ctx context.Context, documentID string) (*IndexedDocument, error)
+
+	// Import replaces a document's indexed chunks with exported ones
+	Import(ctx context.Context, doc *IndexedDocument) error
+
+	// SampleChunks reads up to n chunks picked at random with their stored
+	// embeddings, to check them against the configured embedding model
+	SampleChunks(ctx context.Context, n int) ([]*StoredChunk, error)
 
 	// Close closes the indexer
 	Close() error
@@ -32,8 +68,65 @@ type Document struct {
 	Title   string
 	Content string
 	Meta    map[string]interface{}
+
+	// Description and Keywords come from the page's meta tags and are
+	// searched as separately boosted fields
+	Description string
+	Keywords    []string
+
+	// Attributes are caller-provided fields (team, product, visibility...)
+	// indexed as exact-match filters and returned with results
+	Attributes map[string]string
+
+	// Language is the primary language subtag of the document, declared or
+	// detected, whose analyzer its chunks are also keyword-searched with
+	Language string
+}
+
+// SearchOptions holds optional constraints for a search
+type SearchOptions struct {
+	// Filters restricts results to documents whose attributes match exactly
+	Filters map[string]string
+
+	// ExcludeDocuments drops results belonging to these document IDs
+	ExcludeDocuments []string
+
+	// Documents, when set, restricts results to these document IDs
+	Documents []string
+
+	// Access, when set, hides documents whose ACL does not grant the
+	// caller
+	Access *Access
+
+	// Language ranks results whose language attribute matches higher
+	Language string
+
+	// SourceWeights multiply the scores of results by their source type,
+	// such as {"git": 1.5}, over the weights of the collection searched
+	SourceWeights map[string]float64
+
+	// Debug, when set, is filled with details of how the search ran
+	Debug *SearchDebug
+}
+
+// SearchDebug records how a search was executed
+type SearchDebug struct {
+	Queries []DebugQuery `json:"queries"`
+}
+
+// DebugQuery is one query issued against the index, the original or a
+// translation
+type DebugQuery struct {
+	Text     string `json:"text"`
+	Language string `json:"language,omitempty"` // Target language of a translation
+	Source   string `json:"source,omitempty"`   // Routed source searched
+	Results  int    `json:"results"`
+	Error    string `json:"error,omitempty"`
 }
 
+// attributePrefix namespaces custom attributes in ChromaDB metadata
+const attributePrefix = "attr_"
+
 // SearchResult represents a search result
 type SearchResult struct {
 	DocumentID string
@@ -41,15 +134,53 @@ type SearchResult struct {
 	Score      float32
 	Text       string
 	Metadata   map[string]interface{}
+
+	// Where the chunk lies in its document: its character range, its index
+	// among the document's chunks and how many chunks the document has.
+	// ChunkCount is 0 for chunks indexed before positions were recorded.
+	StartPos   int
+	EndPos     int
+	Position   int
+	ChunkCount int
 }
 
 // Config holds indexer configuration
 type Config struct {
 	Embedder       embeddings.Embedder
-	Chunker        chunker.Chunker
+	Chunker        chunker.Chunker // Built from the collection's chunking settings when nil
 	ChromaURL      string
 	ElasticURL     string
 	CollectionName string
+	ElasticIndex   string // Elasticsearch index of the keyword entries
+
+	// Boosts of the meta description and keywords fields in keyword
+	// search, relative to title^1.5 and text^2
+	DescriptionBoost float64
+	KeywordsBoost    float64
+
+	// Chunking is used for collections without chunking settings of their
+	// own and is persisted with them. With OverrideChunking it replaces the
+	// settings of an existing collection.
+	Chunking         chunker.Config
+	OverrideChunking bool
+
+	// ContextualEnrichment is persisted with new collections the same way,
+	// and replaces an existing collection's setting with OverrideEnrichment
+	ContextualEnrichment bool
+	OverrideEnrichment   bool
+
+	// DefaultVersion, the corpus version searched when a request names
+	// none, is persisted the same way and replaces an existing
+	// collection's with OverrideDefaultVersion
+	DefaultVersion         string
+	OverrideDefaultVersion bool
+
+	// Elasticsearch writes answered with 429 or 503 are retried up to
+	// MaxRetries times, waiting RetryBackoff and doubling it each time.
+	// Chunks that still fail are added to DeadLetters when it is set.
+	MaxRetries   int
+	RetryBackoff time.Duration
+	DeadLetters  DeadLetterQueue
 }
 
 // hybridIndexer implements the Indexer interface using ChromaDB and Elasticsearch
@@ -58,18 +189,33 @@ type hybridIndexer struct {
 	httpClient   *http.Client
 	chromaClient chroma.Client
 	collection   chroma.Collection
+	chunking     chunker.Config
+	enrichment   bool
+
+	defaultVersion string
 }
 
 // ChromaDB structures are now handled by the chroma-go client
 
 // Elasticsearch structures
 type ElasticsearchDoc struct {
-	DocumentID string                 `json:"document_id"`
-	ChunkID    string                 `json:"chunk_id"`
-	Text       string                 `json:"text"`
-	Title      string                 `json:"title"`
-	URL        string                 `json:"url"`
-	Metadata   map[string]interface{} `json:"metadata"`
+	DocumentID  string                 `json:"document_id"`
+	ChunkID     string                 `json:"chunk_id"`
+	Text        string                 `json:"text"`
+	Context     string                 `json:"context,omitempty"` // Sentence situating the chunk in its document
+	Title       string                 `json:"title"`
+	URL         string                 `json:"url"`
+	Description string                 `json:"description,omitempty"`
+	Keywords    []string               `json:"keywords,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata"`
+	Attributes  map[string]string      `json:"attributes,omitempty"`
+	ACL         []string               `json:"acl,omitempty"` // Principals allowed to see the document, none when public
+	Language    string                 `json:"language,omitempty"`
+	StartPos    int                    `json:"start_pos"`
+	EndPos      int                    `json:"end_pos"`
+	Position    int                    `json:"position"`
+	ChunkCount  int                    `json:"chunk_count,omitempty"`
+	Deleted     bool                   `json:"deleted,omitempty"` // Soft-deleted, see SetDeleted
 }
 
 type ElasticsearchResponse struct {
@@ -94,6 +240,21 @@ func NewIndexer(config Config) Indexer {
 	if config.CollectionName == "" {
 		config.CollectionName = "ai_search_documents"
 	}
+	if config.ElasticIndex == "" {
+		config.ElasticIndex = "ai_search_documents"
+	}
+	if config.DescriptionBoost == 0 {
+		config.DescriptionBoost = 1.2
+	}
+	if config.KeywordsBoost == 0 {
+		config.KeywordsBoost = 1.0
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = 4
+	}
+	if config.RetryBackoff == 0 {
+		config.RetryBackoff = 500 * time.Millisecond
+	}
 
 	httpClient := &http.Client{
 		Timeout: 30 * time.Second,
@@ -117,6 +278,10 @@ func NewIndexer(config Config) Indexer {
 	ctx := context.Background()
 	indexer.initializeCollections(ctx)
 
+	if indexer.config.Chunker == nil {
+		indexer.config.Chunker = chunker.NewTextChunker(indexer.chunking)
+	}
+
 	return indexer
 }
 
@@ -125,6 +290,20 @@ func (i *hybridIndexer) initializeCollections(ctx context.Context) {
 	// Create ChromaDB collection
 	i.createChromaCollection(ctx)
 
+	// Settle the chunking settings stored with the collection
+	if err := i.resolveChunking(ctx); err != nil {
+		fmt.Printf("Failed to resolve chunking settings: %v\n", err)
+	}
+	if err := i.resolveEnrichment(ctx); err != nil {
+		fmt.Printf("Failed to resolve contextual enrichment setting: %v\n", err)
+	}
+	if err := i.resolveDefaultVersion(ctx); err != nil {
+		fmt.Printf("Failed to resolve default version: %v\n", err)
+	}
+	if err := i.backfillChunkFlags(ctx); err != nil {
+		fmt.Printf("Failed to backfill chunk flags: %v\n", err)
+	}
+
 	// Create Elasticsearch index
 	i.createElasticsearchIndex(ctx)
 }
@@ -143,7 +322,7 @@ func (i *hybridIndexer) createChromaCollection(ctx context.Context) {
 
 // createElasticsearchIndex creates an Elasticsearch index
 func (i *hybridIndexer) createElasticsearchIndex(ctx context.Context) {
-	indexName := "ai_search_documents"
+	indexName := i.config.ElasticIndex
 	url := fmt.Sprintf("%s/%s", i.config.ElasticURL, indexName)
 
 	// Check if index exists
@@ -151,19 +330,54 @@ func (i *hybridIndexer) createElasticsearchIndex(ctx context.Context) {
 	resp, err := i.httpClient.Do(req)
 	if err == nil && resp.StatusCode == 200 {
 		resp.Body.Close()
+		i.addACLMapping(ctx, url)
+		i.addLanguageMapping(ctx, url)
+		if i.chunking.FoldDiacritics {
+			i.addFoldingMapping(ctx, url)
+		}
 		return // Index already exists
 	}
 
 	// Create index with mapping
+	properties := map[string]interface{}{
+		"document_id": map[string]string{"type": "keyword"},
+		"chunk_id":    map[string]string{"type": "keyword"},
+		"text":        map[string]string{"type": "text", "analyzer": "standard"},
+		"context":     map[string]string{"type": "text", "analyzer": "standard"},
+		"title":       map[string]string{"type": "text", "analyzer": "standard"},
+		"url":         map[string]string{"type": "keyword"},
+		"description": map[string]string{"type": "text", "analyzer": "standard"},
+		"keywords":    map[string]string{"type": "text", "analyzer": "standard"},
+		"metadata":    map[string]string{"type": "object"},
+		"attributes":  map[string]string{"type": "object"},
+		"acl":         map[string]string{"type": "keyword"},
+		"start_pos":   map[string]string{"type": "integer"},
+		"end_pos":     map[string]string{"type": "integer"},
+		"position":    map[string]string{"type": "integer"},
+		"chunk_count": map[string]string{"type": "integer"},
+		deletedKey:    map[string]string{"type": "boolean"},
+	}
+	for field, fieldMapping := range languageMappings() {
+		properties[field] = fieldMapping
+	}
+	if i.chunking.FoldDiacritics {
+		for field, fieldMapping := range foldingMappings() {
+			properties[field] = fieldMapping
+		}
+	}
 	mapping := map[string]interface{}{
+		"settings": foldingSettings(),
 		"mappings": map[string]interface{}{
-			"properties": map[string]interface{}{
-				"document_id": map[string]string{"type": "keyword"},
-				"chunk_id":    map[string]string{"type": "keyword"},
-				"text":        map[string]string{"type": "text", "analyzer": "standard"},
-				"title":       map[string]string{"type": "text", "analyzer": "standard"},
-				"url":         map[string]string{"type": "keyword"},
-				"metadata":    map[string]string{"type": "object"},
+			"properties": properties,
+			// Custom attributes are exact-match filter fields
+			"dynamic_templates": []map[string]interface{}{
+				{
+					"attributes_as_keywords": map[string]interface{}{
+						"path_match":         "attributes.*",
+						"match_mapping_type": "string",
+						"mapping":            map[string]string{"type": "keyword"},
+					},
+				},
 			},
 		},
 	}
@@ -178,22 +392,48 @@ func (i *hybridIndexer) createElasticsearchIndex(ctx context.Context) {
 	}
 }
 
+// addACLMapping maps the acl field of an index created before documents
+// had ACLs as a keyword, so ACL filters match principals exactly. Mapping a
+// field that exists already fails harmlessly.
+func (i *hybridIndexer) addACLMapping(ctx context.Context, url string) {
+	mapping := `{"properties":{"acl":{"type":"keyword"}}}`
+	req, _ := http.NewRequestWithContext(ctx, "PUT", url+"/_mapping", strings.NewReader(mapping))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := i.httpClient.Do(req)
+	if err == nil {
+		resp.Body.Close()
+	}
+}
+
 // Index indexes a document with its chunks and embeddings
 func (i *hybridIndexer) Index(ctx context.Context, doc *Document, chunks []*chunker.Chunk, embeddings [][]float32) error {
 	if len(chunks) != len(embeddings) {
 		return fmt.Errorf("chunks and embeddings count mismatch")
 	}
 
+	metrics.StageBusyWorkers.Add(1, metrics.StageIndex)
+	metrics.UpdateUtilization(metrics.StageIndex)
+	startTime := time.Now()
+	defer func() {
+		metrics.StageDuration.Observe(time.Since(startTime).Seconds(), metrics.StageIndex)
+		metrics.StageBusyWorkers.Add(-1, metrics.StageIndex)
+		metrics.UpdateUtilization(metrics.StageIndex)
+	}()
+
 	// Index in ChromaDB (vector search)
 	if err := i.indexInChroma(ctx, doc, chunks, embeddings); err != nil {
+		metrics.StageProcessed.Inc(metrics.StageIndex, "error")
 		return fmt.Errorf("failed to index in ChromaDB: %w", err)
 	}
 
 	// Index in Elasticsearch (BM25 search)
 	if err := i.indexInElasticsearch(ctx, doc, chunks); err != nil {
+		metrics.StageProcessed.Inc(metrics.StageIndex, "error")
 		return fmt.Errorf("failed to index in Elasticsearch: %w", err)
 	}
 
+	metrics.StageProcessed.Inc(metrics.StageIndex, "success")
 	return nil
 }
 
@@ -207,17 +447,35 @@ func (i *hybridIndexer) indexInChroma(ctx context.Context, doc *Document, chunks
 	documents := make([]string, len(chunks))
 	metadatas := make([]chroma.DocumentMetadata, len(chunks))
 	ids := make([]string, len(chunks))
+	acl := documentACL(doc)
 
 	for j, chunk := range chunks {
 		documents[j] = chunk.Text
-		metadatas[j] = chroma.NewDocumentMetadata(
+		attributes := []*chroma.MetaAttribute{
 			chroma.NewStringAttribute("document_id", doc.ID),
 			chroma.NewStringAttribute("chunk_id", chunk.ID),
 			chroma.NewStringAttribute("title", doc.Title),
 			chroma.NewStringAttribute("url", doc.URL),
 			chroma.NewIntAttribute("start_pos", int64(chunk.StartPos)),
 			chroma.NewIntAttribute("end_pos", int64(chunk.EndPos)),
-		)
+			chroma.NewIntAttribute("position", int64(j)),
+			chroma.NewIntAttribute("chunk_count", int64(len(chunks))),
+		}
+		if doc.Description != "" {
+			attributes = append(attributes, chroma.NewStringAttribute("description", doc.Description))
+		}
+		if len(doc.Keywords) > 0 {
+			attributes = append(attributes, chroma.NewStringAttribute("keywords", strings.Join(doc.Keywords, ", ")))
+		}
+		if doc.Language != "" {
+			attributes = append(attributes, chroma.NewStringAttribute(LanguageKey, doc.Language))
+		}
+		for key, value := range doc.Attributes {
+			attributes = append(attributes, chroma.NewStringAttribute(attributePrefix+key, value))
+		}
+		attributes = append(attributes, chromaACLAttributes(acl)...)
+		attributes = append(attributes, chroma.NewBoolAttribute(deletedKey, false))
+		metadatas[j] = chroma.NewDocumentMetadata(attributes...)
 		ids[j] = chunk.ID
 	}
 
@@ -228,60 +486,33 @@ func (i *hybridIndexer) indexInChroma(ctx context.Context, doc *Document, chunks
 		documentIDs[i] = chroma.DocumentID(id)
 	}
 
-	err := i.collection.Add(ctx,
+	// Chunks are stored with the embeddings computed for them, which
+	// queries are embedded by the same model to be compared with
+	options := []chroma.CollectionAddOption{
 		chroma.WithIDs(documentIDs...),
 		chroma.WithTexts(documents...),
 		chroma.WithMetadatas(metadatas...),
-	)
-	if err != nil {
-		return fmt.Errorf("failed to add to ChromaDB: %w", err)
 	}
-
-	return nil
-}
-
-// indexInElasticsearch indexes documents in Elasticsearch
-func (i *hybridIndexer) indexInElasticsearch(ctx context.Context, doc *Document, chunks []*chunker.Chunk) error {
-	indexName := "ai_search_documents"
-
-	for _, chunk := range chunks {
-		docData := ElasticsearchDoc{
-			DocumentID: doc.ID,
-			ChunkID:    chunk.ID,
-			Text:       chunk.Text,
-			Title:      doc.Title,
-			URL:        doc.URL,
-			Metadata:   chunk.Metadata,
-		}
-
-		jsonData, err := json.Marshal(docData)
+	if len(embeddings) == len(chunks) {
+		vectors, err := chromaembeddings.NewEmbeddingsFromFloat32(embeddings)
 		if err != nil {
-			return err
-		}
-
-		url := fmt.Sprintf("%s/%s/_doc/%s", i.config.ElasticURL, indexName, chunk.ID)
-		req, err := http.NewRequestWithContext(ctx, "PUT", url, strings.NewReader(string(jsonData)))
-		if err != nil {
-			return err
-		}
-		req.Header.Set("Content-Type", "application/json")
-
-		resp, err := i.httpClient.Do(req)
-		if err != nil {
-			return err
-		}
-		resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-			return fmt.Errorf("Elasticsearch request failed with status %d", resp.StatusCode)
+			return fmt.Errorf("failed to convert embeddings: %w", err)
 		}
+		options = append(options, chroma.WithEmbeddings(vectors...))
+	}
+	err := i.collection.Add(ctx, options...)
+	if err != nil {
+		return fmt.Errorf("failed to add to ChromaDB: %w", err)
 	}
 
 	return nil
 }
 
 // Search performs a hybrid search query
-func (i *hybridIndexer) Search(ctx context.Context, query string, limit int) ([]*SearchResult, error) {
+func (i *hybridIndexer) Search(ctx context.Context, query string, limit int, opts SearchOptions) ([]*SearchResult, error) {
+	// Queries are normalized like the chunks they are matched against
+	query = chunker.Normalize(query)
+
 	// Get query embedding
 	queryEmbedding, err := i.config.Embedder.Embed(ctx, query)
 	if err != nil {
@@ -289,13 +520,13 @@ func (i *hybridIndexer) Search(ctx context.Context, query string, limit int) ([]
 	}
 
 	// Vector search in ChromaDB
-	vectorResults, err := i.searchChroma(ctx, queryEmbedding, limit*2) // Get more results for reranking
+	vectorResults, err := i.searchChroma(ctx, queryEmbedding, limit*2, opts) // Get more results for reranking
 	if err != nil {
 		return nil, fmt.Errorf("failed to search ChromaDB: %w", err)
 	}
 
 	// BM25 search in Elasticsearch
-	bm25Results, err := i.searchElasticsearch(ctx, query, limit*2)
+	bm25Results, err := i.searchElasticsearch(ctx, query, limit*2, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search Elasticsearch: %w", err)
 	}
@@ -307,17 +538,20 @@ func (i *hybridIndexer) Search(ctx context.Context, query string, limit int) ([]
 }
 
 // searchChroma performs vector search in ChromaDB
-func (i *hybridIndexer) searchChroma(ctx context.Context, queryEmbedding []float32, limit int) ([]*SearchResult, error) {
+func (i *hybridIndexer) searchChroma(ctx context.Context, queryEmbedding []float32, limit int, opts SearchOptions) ([]*SearchResult, error) {
 	if i.collection == nil {
 		return nil, fmt.Errorf("ChromaDB collection not initialized")
 	}
 
-	// Query ChromaDB using the client
-	queryResult, err := i.collection.Query(ctx,
-		chroma.WithQueryTexts("query"), // Use text query instead of embeddings for now
+	queryOptions := []chroma.CollectionQueryOption{
+		chroma.WithQueryEmbeddings(chromaembeddings.NewEmbeddingFromFloat32(queryEmbedding)),
 		chroma.WithNResults(limit),
 		chroma.WithIncludeQuery(chroma.IncludeDocuments, chroma.IncludeMetadatas, chroma.IncludeDistances),
-	)
+		chroma.WithWhereQuery(chromaFilter(opts.Filters, opts.Documents, opts.ExcludeDocuments, opts.Access)),
+	}
+
+	// Query ChromaDB using the client
+	queryResult, err := i.collection.Query(ctx, queryOptions...)
 	if err != nil {
 		return nil, fmt.Errorf("ChromaDB query failed: %w", err)
 	}
@@ -340,16 +574,23 @@ func (i *hybridIndexer) searchChroma(ctx context.Context, queryEmbedding []float
 				documentText := fmt.Sprintf("%v", document)
 
 				// Convert metadata to map
-				metadataMap := make(map[string]interface{})
-				// For now, just use a simple approach
-				metadataMap["chunk_id"] = fmt.Sprintf("chunk_%d", j)
+				metadataMap := chromaMetadataMap(metadatas[j])
+				documentID, _ := metadataMap["document_id"].(string)
+				chunkID, _ := metadataMap["chunk_id"].(string)
+				if chunkID == "" {
+					chunkID = fmt.Sprintf("chunk_%d", j)
+				}
 
 				results = append(results, &SearchResult{
-					DocumentID: "unknown", // Will be extracted from metadata later
-					ChunkID:    fmt.Sprintf("chunk_%d", j),
+					DocumentID: documentID,
+					ChunkID:    chunkID,
 					Score:      score,
 					Text:       documentText,
 					Metadata:   metadataMap,
+					StartPos:   metadataInt(metadataMap, "start_pos"),
+					EndPos:     metadataInt(metadataMap, "end_pos"),
+					Position:   metadataInt(metadataMap, "position"),
+					ChunkCount: metadataInt(metadataMap, "chunk_count"),
 				})
 			}
 		}
@@ -359,15 +600,60 @@ func (i *hybridIndexer) searchChroma(ctx context.Context, queryEmbedding []float
 }
 
 // searchElasticsearch performs BM25 search in Elasticsearch
-func (i *hybridIndexer) searchElasticsearch(ctx context.Context, query string, limit int) ([]*SearchResult, error) {
-	indexName := "ai_search_documents"
+func (i *hybridIndexer) searchElasticsearch(ctx context.Context, query string, limit int, opts SearchOptions) ([]*SearchResult, error) {
+	indexName := i.config.ElasticIndex
 	url := fmt.Sprintf("%s/%s/_search", i.config.ElasticURL, indexName)
 
+	filters := []map[string]interface{}{}
+	for key, value := range opts.Filters {
+		filters = append(filters, map[string]interface{}{
+			"term": map[string]interface{}{"attributes." + key: value},
+		})
+	}
+	if len(opts.Documents) > 0 {
+		filters = append(filters, map[string]interface{}{
+			"terms": map[string]interface{}{"document_id": opts.Documents},
+		})
+	}
+	if opts.Access != nil {
+		filters = append(filters, elasticACLFilter(opts.Access))
+	}
+
+	mustNot := []map[string]interface{}{
+		{"term": map[string]interface{}{deletedKey: true}},
+	}
+	if len(opts.ExcludeDocuments) > 0 {
+		mustNot = append(mustNot, map[string]interface{}{
+			"terms": map[string]interface{}{"document_id": opts.ExcludeDocuments},
+		})
+	}
+
+	// Chunks are matched by the standard analyzer and by the analyzer of
+	// their language, which stems words and drops stopwords, and with
+	// accents folded when the collection folds diacritics
+	fields := []string{
+		"text^2",
+		"context",
+		"title^1.5",
+		fmt.Sprintf("description^%g", i.config.DescriptionBoost),
+		fmt.Sprintf("keywords^%g", i.config.KeywordsBoost),
+	}
+	if i.chunking.FoldDiacritics {
+		fields = append(fields, foldedSearchFields(fields)...)
+	}
+	fields = append(fields, languageSearchFields(opts.Filters[LanguageKey])...)
+
 	payload := map[string]interface{}{
 		"query": map[string]interface{}{
-			"multi_match": map[string]interface{}{
-				"query":  query,
-				"fields": []string{"text^2", "title^1.5"},
+			"bool": map[string]interface{}{
+				"must": map[string]interface{}{
+					"multi_match": map[string]interface{}{
+						"query":  query,
+						"fields": fields,
+					},
+				},
+				"filter":   filters,
+				"must_not": mustNot,
 			},
 		},
 		"size": limit,
@@ -401,18 +687,102 @@ func (i *hybridIndexer) searchElasticsearch(ctx context.Context, query string, l
 
 	var results []*SearchResult
 	for _, hit := range response.Hits.Hits {
+		metadata := hit.Source.Metadata
+		if metadata == nil {
+			metadata = make(map[string]interface{})
+		}
+		metadata["title"] = hit.Source.Title
+		metadata["url"] = hit.Source.URL
+		if hit.Source.Description != "" {
+			metadata["description"] = hit.Source.Description
+		}
+		if len(hit.Source.Keywords) > 0 {
+			metadata["keywords"] = strings.Join(hit.Source.Keywords, ", ")
+		}
+		if len(hit.Source.Attributes) > 0 {
+			metadata["attributes"] = hit.Source.Attributes
+		}
+
 		results = append(results, &SearchResult{
 			DocumentID: hit.Source.DocumentID,
 			ChunkID:    hit.Source.ChunkID,
 			Score:      float32(hit.Score),
 			Text:       hit.Source.Text,
-			Metadata:   hit.Source.Metadata,
+			Metadata:   metadata,
+			StartPos:   hit.Source.StartPos,
+			EndPos:     hit.Source.EndPos,
+			Position:   hit.Source.Position,
+			ChunkCount: hit.Source.ChunkCount,
 		})
 	}
 
 	return results, nil
 }
 
+// chromaFilter builds a ChromaDB where clause excluding soft-deleted
+// chunks from attribute filters, included and excluded document IDs and
+// the caller's access
+func chromaFilter(filters map[string]string, included, excluded []string, access *Access) chroma.WhereClause {
+	clauses := []chroma.WhereClause{chroma.EqBool(deletedKey, false)}
+	for key, value := range filters {
+		clauses = append(clauses, chroma.EqString(attributePrefix+key, value))
+	}
+	if len(included) > 0 {
+		clauses = append(clauses, chroma.InString("document_id", included...))
+	}
+	if len(excluded) > 0 {
+		clauses = append(clauses, chroma.NinString("document_id", excluded...))
+	}
+	if access != nil {
+		clauses = append(clauses, chromaACLFilter(access))
+	}
+
+	if len(clauses) == 1 {
+		return clauses[0]
+	}
+	return chroma.And(clauses...)
+}
+
+// chromaMetadataMap converts ChromaDB metadata into a result metadata map,
+// gathering prefixed custom attributes under "attributes"
+func chromaMetadataMap(metadata chroma.DocumentMetadata) map[string]interface{} {
+	result := make(map[string]interface{})
+	if metadata == nil {
+		return result
+	}
+
+	// Round-trip through JSON since the metadata interface has no key listing
+	raw := make(map[string]interface{})
+	if data, err := json.Marshal(metadata); err == nil {
+		json.Unmarshal(data, &raw)
+	}
+
+	attributes := make(map[string]string)
+	for key, value := range raw {
+		if key == aclRestrictedKey || key == deletedKey || strings.HasPrefix(key, aclPrincipalPrefix) {
+			continue
+		}
+		if strings.HasPrefix(key, attributePrefix) {
+			attributes[strings.TrimPrefix(key, attributePrefix)] = fmt.Sprintf("%v", value)
+			continue
+		}
+		result[key] = value
+	}
+	if len(attributes) > 0 {
+		result["attributes"] = attributes
+	}
+
+	return result
+}
+
+// metadataInt reads an integer from metadata decoded from JSON
+func metadataInt(metadata map[string]interface{}, key string) int {
+	if value, ok := metadata[key].(float64); ok {
+		return int(value)
+	}
+	return 0
+}
+
 // combineResults combines and reranks results from both search methods
 func (i *hybridIndexer) combineResults(vectorResults, bm25Results []*SearchResult, limit int) []*SearchResult {
 	// Create a map to track unique results
@@ -465,6 +835,46 @@ func (i *hybridIndexer) combineResults(vectorResults, bm25Results []*SearchResul
 	return combinedResults
 }
 
+// Delete removes all chunks of a document from ChromaDB and Elasticsearch
+func (i *hybridIndexer) Delete(ctx context.Context, documentID string) error {
+	if i.collection == nil {
+		return fmt.Errorf("ChromaDB collection not initialized")
+	}
+
+	err := i.collection.Delete(ctx, chroma.WithWhereDelete(chroma.EqString("document_id", documentID)))
+	if err != nil {
+		return fmt.Errorf("failed to delete from ChromaDB: %w", err)
+	}
+
+	indexName := i.config.ElasticIndex
+	url := fmt.Sprintf("%s/%s/_delete_by_query", i.config.ElasticURL, indexName)
+
+	payload := map[string]interface{}{
+		"query": map[string]interface{}{
+			"term": map[string]interface{}{
+				"document_id": documentID,
+			},
+		},
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := i.elasticWrite(ctx, "POST", url, "application/json", jsonData)
+	if err != nil {
+		return fmt.Errorf("failed to delete from Elasticsearch: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Elasticsearch delete failed with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
 // Close closes the indexer
 func (i *hybridIndexer) Close() error {
 	if i.chromaClient != nil {
@@ -0,0 +1,106 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// LanguageKey is the chunk metadata key, and the document attribute, holding
+// the primary language subtag of a document, such as "de"
+const LanguageKey = "language"
+
+// languageFieldPrefix names the fields holding a chunk's text analyzed for
+// its language, as in "text_de"
+const languageFieldPrefix = "text_"
+
+// languageAnalyzers maps primary language subtags to the built-in
+// Elasticsearch analyzers that stem and drop stopwords in that language.
+// Chunks in other languages are searched by the standard analyzer only.
+var languageAnalyzers = map[string]string{
+	"ar": "arabic", "bg": "bulgarian", "ca": "catalan", "cs": "czech", "da": "danish",
+	"de": "german", "el": "greek", "en": "english", "es": "spanish", "eu": "basque",
+	"fa": "persian", "fi": "finnish", "fr": "french", "ga": "irish", "gl": "galician",
+	"hi": "hindi", "hu": "hungarian", "hy": "armenian", "id": "indonesian", "it": "italian",
+	"ja": "cjk", "ko": "cjk", "lt": "lithuanian", "lv": "latvian", "nl": "dutch",
+	"no": "norwegian", "pt": "portuguese", "ro": "romanian", "ru": "russian", "sv": "swedish",
+	"th": "thai", "tr": "turkish", "zh": "cjk",
+}
+
+// languageField returns the field holding text analyzed for a language, or
+// "" when the language has no analyzer of its own
+func languageField(language string) string {
+	if _, ok := languageAnalyzers[language]; !ok {
+		return ""
+	}
+	return languageFieldPrefix + language
+}
+
+// languageMappings returns the mapping of the language field and of the
+// text field of each language with an analyzer
+func languageMappings() map[string]interface{} {
+	properties := map[string]interface{}{
+		"language": map[string]string{"type": "keyword"},
+	}
+	for language, analyzer := range languageAnalyzers {
+		properties[languageField(language)] = map[string]string{"type": "text", "analyzer": analyzer}
+	}
+	return properties
+}
+
+// languageSearchFields returns the language text fields searched alongside
+// the text, boosted like it. A search restricted to one language searches
+// that language's field only.
+func languageSearchFields(language string) []string {
+	if language != "" {
+		if field := languageField(language); field != "" {
+			return []string{field + "^2"}
+		}
+		return nil
+	}
+
+	fields := make([]string, 0, len(languageAnalyzers))
+	for language := range languageAnalyzers {
+		fields = append(fields, languageField(language)+"^2")
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// addLanguageMapping maps the language fields of an index created before
+// chunks were analyzed per language. Chunks indexed before are only found
+// through the standard analyzer until they are indexed again.
+func (i *hybridIndexer) addLanguageMapping(ctx context.Context, url string) {
+	mapping, _ := json.Marshal(map[string]interface{}{"properties": languageMappings()})
+	req, _ := http.NewRequestWithContext(ctx, "PUT", url+"/_mapping", strings.NewReader(string(mapping)))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := i.httpClient.Do(req)
+	if err == nil {
+		resp.Body.Close()
+	}
+}
+
+// MarshalJSON writes the keyword entry of a chunk, adding its text under
+// the field analyzed for its language when there is one
+func (d ElasticsearchDoc) MarshalJSON() ([]byte, error) {
+	type entry ElasticsearchDoc
+	data, err := json.Marshal(entry(d))
+	if err != nil {
+		return nil, err
+	}
+
+	field := languageField(d.Language)
+	if field == "" {
+		return data, nil
+	}
+	text, err := json.Marshal(d.Text)
+	if err != nil {
+		return nil, err
+	}
+	data = append(data[:len(data)-1], `,"`+field+`":`...)
+	data = append(data, text...)
+	return append(data, '}'), nil
+}
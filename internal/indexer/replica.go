@@ -0,0 +1,149 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	chroma "github.com/amikos-tech/chroma-go/pkg/api/v2"
+	chromaembeddings "github.com/amikos-tech/chroma-go/pkg/embeddings"
+)
+
+// maxExportedChunks bounds the chunks of one document read back from
+// Elasticsearch
+const maxExportedChunks = 10000
+
+// IndexedDocument is what the indexes hold for one document: its vectors
+// with their embeddings and its keyword entries. It is exported from one
+// deployment and imported into another so a standby never embeds again.
+type IndexedDocument struct {
+	DocumentID string
+
+	ids        []chroma.DocumentID
+	texts      []string
+	metadatas  []chroma.DocumentMetadata
+	embeddings []chromaembeddings.Embedding
+	keyword    []*ElasticsearchDoc
+}
+
+// Chunks returns how many chunks of the document are indexed
+func (d *IndexedDocument) Chunks() int {
+	return max(len(d.ids), len(d.keyword))
+}
+
+// Export reads the indexed chunks of a document. A document that is not
+// indexed yields an empty IndexedDocument.
+func (i *hybridIndexer) Export(ctx context.Context, documentID string) (*IndexedDocument, error) {
+	if i.collection == nil {
+		return nil, fmt.Errorf("ChromaDB collection not initialized")
+	}
+
+	doc := &IndexedDocument{DocumentID: documentID}
+
+	result, err := i.collection.Get(ctx,
+		chroma.WithWhereGet(chroma.EqString("document_id", documentID)),
+		chroma.WithIncludeGet(chroma.IncludeDocuments, chroma.IncludeMetadatas, chroma.IncludeEmbeddings),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from ChromaDB: %w", err)
+	}
+	doc.ids = result.GetIDs()
+	for _, text := range result.GetDocuments() {
+		doc.texts = append(doc.texts, text.ContentString())
+	}
+	doc.metadatas = result.GetMetadatas()
+	doc.embeddings = result.GetEmbeddings()
+
+	doc.keyword, err = i.exportElasticsearch(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// exportElasticsearch reads the keyword entries of a document
+func (i *hybridIndexer) exportElasticsearch(ctx context.Context, documentID string) ([]*ElasticsearchDoc, error) {
+	payload := map[string]interface{}{
+		"query": map[string]interface{}{
+			"term": map[string]interface{}{"document_id": documentID},
+		},
+		"size": maxExportedChunks,
+	}
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/%s/_search", i.config.ElasticURL, i.config.ElasticIndex)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(string(jsonData)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := i.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from Elasticsearch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("Elasticsearch search failed with status %d: %s", resp.StatusCode, message)
+	}
+
+	var response ElasticsearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode Elasticsearch response: %w", err)
+	}
+
+	docs := make([]*ElasticsearchDoc, len(response.Hits.Hits))
+	for j, hit := range response.Hits.Hits {
+		docs[j] = &hit.Source
+	}
+	return docs, nil
+}
+
+// Import replaces the indexed chunks of a document with those exported
+// from another indexer. Importing an empty IndexedDocument removes the
+// document.
+func (i *hybridIndexer) Import(ctx context.Context, doc *IndexedDocument) error {
+	if err := i.Delete(ctx, doc.DocumentID); err != nil {
+		return err
+	}
+
+	if len(doc.ids) > 0 {
+		options := []chroma.CollectionAddOption{
+			chroma.WithIDs(doc.ids...),
+			chroma.WithTexts(doc.texts...),
+			chroma.WithMetadatas(doc.metadatas...),
+		}
+		// Without stored embeddings the collection computes them again
+		if len(doc.embeddings) == len(doc.ids) {
+			options = append(options, chroma.WithEmbeddings(doc.embeddings...))
+		}
+		if err := i.collection.Add(ctx, options...); err != nil {
+			return fmt.Errorf("failed to add to ChromaDB: %w", err)
+		}
+	}
+
+	if len(doc.keyword) == 0 {
+		return nil
+	}
+	pending := make([]*bulkItem, 0, len(doc.keyword))
+	for _, entry := range doc.keyword {
+		document, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		pending = append(pending, &bulkItem{chunkID: entry.ChunkID, document: document})
+	}
+	if err := i.bulkWrite(ctx, doc.DocumentID, pending); err != nil {
+		return fmt.Errorf("failed to import into Elasticsearch: %w", err)
+	}
+	return nil
+}
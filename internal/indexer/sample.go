@@ -0,0 +1,136 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+
+	chroma "github.com/amikos-tech/chroma-go/pkg/api/v2"
+)
+
+// StoredChunk is a chunk as the vector index holds it: the text its
+// embedding was computed from and the embedding itself
+type StoredChunk struct {
+	ChunkID    string
+	DocumentID string
+	Title      string
+	URL        string
+	Text       string
+	Context    string // Sentence situating the chunk, with contextual enrichment
+	Embedding  []float32
+}
+
+// SampleChunks reads up to n chunks picked at random from the vector index
+// with their stored embeddings. Context sentences are read back from
+// Elasticsearch in collections with contextual enrichment.
+func (i *hybridIndexer) SampleChunks(ctx context.Context, n int) ([]*StoredChunk, error) {
+	if i.collection == nil {
+		return nil, fmt.Errorf("ChromaDB collection not initialized")
+	}
+
+	count, err := i.collection.Count(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count ChromaDB chunks: %w", err)
+	}
+
+	// One read per sampled offset; a diagnostic samples few enough chunks
+	var chunks []*StoredChunk
+	for _, offset := range rand.Perm(count)[:min(n, count)] {
+		result, err := i.collection.Get(ctx,
+			chroma.WithOffsetGet(offset),
+			chroma.WithLimitGet(1),
+			chroma.WithIncludeGet(chroma.IncludeDocuments, chroma.IncludeMetadatas, chroma.IncludeEmbeddings),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read from ChromaDB: %w", err)
+		}
+
+		ids := result.GetIDs()
+		texts := result.GetDocuments()
+		metadatas := result.GetMetadatas()
+		embeddings := result.GetEmbeddings()
+		for j := range ids {
+			if j >= len(texts) || j >= len(embeddings) || embeddings[j] == nil {
+				continue
+			}
+			chunk := &StoredChunk{
+				ChunkID:   string(ids[j]),
+				Text:      texts[j].ContentString(),
+				Embedding: embeddings[j].ContentAsFloat32(),
+			}
+			if j < len(metadatas) {
+				metadata := chromaMetadataMap(metadatas[j])
+				chunk.DocumentID, _ = metadata["document_id"].(string)
+				chunk.Title, _ = metadata["title"].(string)
+				chunk.URL, _ = metadata["url"].(string)
+			}
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	if i.enrichment && len(chunks) > 0 {
+		if err := i.loadChunkContexts(ctx, chunks); err != nil {
+			return nil, err
+		}
+	}
+	return chunks, nil
+}
+
+// loadChunkContexts fills in the context sentences of chunks from their
+// keyword entries
+func (i *hybridIndexer) loadChunkContexts(ctx context.Context, chunks []*StoredChunk) error {
+	ids := make([]string, len(chunks))
+	for j, chunk := range chunks {
+		ids[j] = chunk.ChunkID
+	}
+	jsonData, err := json.Marshal(map[string]interface{}{"ids": ids})
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/%s/_mget?_source_includes=context", i.config.ElasticURL, i.config.ElasticIndex)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(string(jsonData)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := i.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to read from Elasticsearch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("Elasticsearch mget failed with status %d: %s", resp.StatusCode, message)
+	}
+
+	var response struct {
+		Docs []struct {
+			ID     string `json:"_id"`
+			Found  bool   `json:"found"`
+			Source struct {
+				Context string `json:"context"`
+			} `json:"_source"`
+		} `json:"docs"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("failed to decode Elasticsearch response: %w", err)
+	}
+
+	contexts := make(map[string]string)
+	for _, doc := range response.Docs {
+		if doc.Found {
+			contexts[doc.ID] = doc.Source.Context
+		}
+	}
+	for _, chunk := range chunks {
+		chunk.Context = contexts[chunk.ChunkID]
+	}
+	return nil
+}
@@ -7,8 +7,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
+	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // LLM defines the interface for language model interactions
@@ -18,6 +21,19 @@ type LLM interface {
 
 	// Rerank reranks search results based on relevance
 	Rerank(ctx context.Context, query string, results []string) ([]string, error)
+
+	// Translate translates text into the language with the given ISO 639-1 code
+	Translate(ctx context.Context, text string, language string) (string, error)
+
+	// Title generates a short title for a page's text
+	Title(ctx context.Context, text string) (string, error)
+
+	// SituateChunk writes a sentence placing a chunk within its document
+	SituateChunk(ctx context.Context, document string, chunk string) (string, error)
+
+	// Classify picks the categories a query belongs to among the given
+	// labels and descriptions
+	Classify(ctx context.Context, query string, categories map[string]string) ([]string, error)
 }
 
 // Config holds LLM configuration
@@ -76,6 +92,9 @@ func NewLLM(config Config) LLM {
 	if config.Provider == "" {
 		config.Provider = "openrouter"
 	}
+	if config.Provider == MockProvider {
+		return NewMockLLM()
+	}
 	if config.Model == "" {
 		config.Model = "openai/gpt-3.5-turbo" // Default model
 	}
@@ -142,6 +161,7 @@ func (l *openRouterLLM) Generate(ctx context.Context, prompt string) (string, er
 	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
 		return "", fmt.Errorf("failed to decode response: %w", err)
 	}
+	addUsage(ctx, response.Usage.TotalTokens)
 
 	if len(response.Choices) == 0 {
 		return "", fmt.Errorf("no response from LLM")
@@ -175,6 +195,104 @@ func (l *openRouterLLM) Rerank(ctx context.Context, query string, results []stri
 	return rerankedResults, nil
 }
 
+// Translate translates text into the language with the given ISO 639-1 code
+func (l *openRouterLLM) Translate(ctx context.Context, text string, language string) (string, error) {
+	prompt := fmt.Sprintf("Translate the following search query into the language with ISO 639-1 code %q. "+
+		"Keep product names, code and identifiers unchanged. If it is already in that language, repeat it unchanged. "+
+		"Respond with the translation only.\n\nQuery: %s", language, text)
+
+	response, err := l.Generate(ctx, prompt)
+	if err != nil {
+		return "", fmt.Errorf("failed to translate query: %w", err)
+	}
+
+	return strings.Trim(strings.TrimSpace(response), "\""), nil
+}
+
+// titleSampleSize caps the page text sent when generating a title, and
+// situateSampleSize the document text sent when situating a chunk
+const (
+	titleSampleSize   = 2000
+	situateSampleSize = 8000
+)
+
+// truncate cuts text to at most size bytes without splitting a character
+func truncate(text string, size int) string {
+	if len(text) <= size {
+		return text
+	}
+	text = text[:size]
+	for len(text) > 0 && !utf8.ValidString(text) {
+		text = text[:len(text)-1]
+	}
+	return text
+}
+
+// Title generates a short title for a page's text
+func (l *openRouterLLM) Title(ctx context.Context, text string) (string, error) {
+	text = truncate(text, titleSampleSize)
+
+	prompt := "Write a title of at most ten words for the web page below, in the page's language. " +
+		"Respond with the title only.\n\nPage: " + text
+
+	response, err := l.Generate(ctx, prompt)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate title: %w", err)
+	}
+
+	return strings.Trim(strings.TrimSpace(response), "\"'"), nil
+}
+
+// SituateChunk writes a sentence placing a chunk within its document, to
+// be searched and embedded with the chunk. Long documents are represented
+// by their beginning.
+func (l *openRouterLLM) SituateChunk(ctx context.Context, document string, chunk string) (string, error) {
+	prompt := "<document>\n" + truncate(document, situateSampleSize) + "\n</document>\n\n" +
+		"Here is a chunk of the document:\n<chunk>\n" + chunk + "\n</chunk>\n\n" +
+		"Write one short sentence that situates this chunk within the overall document, " +
+		"naming the document's subject, to improve search retrieval of the chunk. " +
+		"Respond with the sentence only, in the document's language."
+
+	response, err := l.Generate(ctx, prompt)
+	if err != nil {
+		return "", fmt.Errorf("failed to situate chunk: %w", err)
+	}
+
+	return strings.TrimSpace(response), nil
+}
+
+// Classify picks the categories a query belongs to, answering with the
+// labels it was given only
+func (l *openRouterLLM) Classify(ctx context.Context, query string, categories map[string]string) ([]string, error) {
+	labels := make([]string, 0, len(categories))
+	for label := range categories {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	var prompt strings.Builder
+	prompt.WriteString("Pick the collections worth searching for the query below. Collections:\n")
+	for _, label := range labels {
+		fmt.Fprintf(&prompt, "- %s: %s\n", label, categories[label])
+	}
+	prompt.WriteString("\nRespond with the names of the matching collections only, comma-separated, " +
+		"most relevant first. Respond with none if no collection fits.\n\nQuery: " + query)
+
+	response, err := l.Generate(ctx, prompt.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to classify query: %w", err)
+	}
+
+	var picked []string
+	for _, label := range strings.FieldsFunc(response, func(r rune) bool { return r == ',' || r == '\n' }) {
+		label = strings.Trim(strings.TrimSpace(label), "\"'`-* ")
+		if _, known := categories[label]; known && !slices.Contains(picked, label) {
+			picked = append(picked, label)
+		}
+	}
+	return picked, nil
+}
+
 // createRerankPrompt creates a prompt for reranking search results
 func (l *openRouterLLM) createRerankPrompt(query string, results []string) string {
 	var builder strings.Builder
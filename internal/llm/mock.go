@@ -0,0 +1,118 @@
+package llm
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// MockProvider is the provider name selecting the mock LLM, which runs
+// offline without an API key
+const MockProvider = "mock"
+
+// mockTitleWords caps the words of a mock title
+const mockTitleWords = 10
+
+// mockLLM is an LLM answering from the words of its input instead of a
+// model, always the same way for the same input
+type mockLLM struct{}
+
+// NewMockLLM creates an LLM whose answers are derived from its input
+// alone: Generate echoes the first line of the prompt, Rerank orders
+// results by the query words they contain, Translate returns the text
+// unchanged, Title and SituateChunk quote the beginning of the text, and
+// Classify picks the categories sharing a word with the query. Tokens are
+// still counted, estimated from the length of the text.
+func NewMockLLM() LLM {
+	return &mockLLM{}
+}
+
+// Generate answers a prompt with its first line
+func (l *mockLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	line, _, _ := strings.Cut(strings.TrimSpace(prompt), "\n")
+	response := "Mock response to: " + truncate(line, 200)
+	addUsage(ctx, (len(prompt)+len(response))/4)
+	return response, nil
+}
+
+// Rerank orders results by the number of query words they contain, keeping
+// the original order among equals
+func (l *mockLLM) Rerank(ctx context.Context, query string, results []string) ([]string, error) {
+	queryWords := mockWords(query)
+	matches := make([]int, len(results))
+	for i, result := range results {
+		resultWords := make(map[string]bool)
+		for _, word := range mockWords(result) {
+			resultWords[word] = true
+		}
+		for _, word := range queryWords {
+			if resultWords[word] {
+				matches[i]++
+			}
+		}
+	}
+
+	order := make([]int, len(results))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return matches[order[a]] > matches[order[b]] })
+
+	reranked := make([]string, len(results))
+	for i, j := range order {
+		reranked[i] = results[j]
+	}
+	return reranked, nil
+}
+
+// Translate returns the text unchanged, as if already in the language
+func (l *mockLLM) Translate(ctx context.Context, text string, language string) (string, error) {
+	return text, nil
+}
+
+// Title returns the first ten words of the text
+func (l *mockLLM) Title(ctx context.Context, text string) (string, error) {
+	words := strings.Fields(truncate(text, titleSampleSize))
+	if len(words) > mockTitleWords {
+		words = words[:mockTitleWords]
+	}
+	return strings.Join(words, " "), nil
+}
+
+// SituateChunk names the document by its first ten words
+func (l *mockLLM) SituateChunk(ctx context.Context, document string, chunk string) (string, error) {
+	title, _ := l.Title(ctx, document)
+	if title == "" {
+		return "", nil
+	}
+	return "This chunk is from the document beginning: " + title, nil
+}
+
+// Classify picks the categories whose label or description shares a word
+// with the query, in order of label
+func (l *mockLLM) Classify(ctx context.Context, query string, categories map[string]string) ([]string, error) {
+	queryWords := make(map[string]bool)
+	for _, word := range mockWords(query) {
+		queryWords[word] = true
+	}
+
+	var picked []string
+	for label, description := range categories {
+		for _, word := range mockWords(label + " " + description) {
+			if queryWords[word] {
+				picked = append(picked, label)
+				break
+			}
+		}
+	}
+	sort.Strings(picked)
+	return picked, nil
+}
+
+// mockWords splits a text into lower-cased words of letters and digits
+func mockWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
@@ -0,0 +1,33 @@
+package llm
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Usage counts the tokens LLM calls made with a context consume, so that
+// they can be billed to the request that caused them
+type Usage struct {
+	tokens atomic.Int64
+}
+
+// Tokens returns the prompt and completion tokens consumed so far
+func (u *Usage) Tokens() int64 {
+	return u.tokens.Load()
+}
+
+type usageKey struct{}
+
+// WithUsage returns a context whose LLM calls add their tokens to the
+// returned usage
+func WithUsage(ctx context.Context) (context.Context, *Usage) {
+	usage := &Usage{}
+	return context.WithValue(ctx, usageKey{}, usage), usage
+}
+
+// addUsage adds tokens to the usage of a context, if it counts any
+func addUsage(ctx context.Context, tokens int) {
+	if usage, ok := ctx.Value(usageKey{}).(*Usage); ok {
+		usage.tokens.Add(int64(tokens))
+	}
+}
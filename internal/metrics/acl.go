@@ -0,0 +1,9 @@
+package metrics
+
+// Enforcement of document ACLs
+var (
+	IdentityRejected = NewCounter("ai_search_identity_rejected_total",
+		"Requests refused for presenting an invalid identity token")
+	ACLFiltered = NewCounter("ai_search_acl_filtered_results_total",
+		"Search results dropped after retrieval because the caller's access does not cover their document")
+)
@@ -0,0 +1,9 @@
+package metrics
+
+// Saved search alerts; the channel label is "webhook" or "email"
+var (
+	AlertsSent = NewCounter("ai_search_alerts_sent_total",
+		"Saved search alerts delivered", "channel")
+	AlertsFailed = NewCounter("ai_search_alerts_failed_total",
+		"Saved search alerts that could not be delivered", "channel")
+)
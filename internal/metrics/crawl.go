@@ -0,0 +1,9 @@
+package metrics
+
+// Per-domain crawl politeness metrics
+var (
+	CrawlDomainRequests = NewCounter("ai_search_crawl_domain_requests_total",
+		"Page requests sent to each crawled host", "host")
+	CrawlDomainRequestRate = NewGauge("ai_search_crawl_domain_requests_per_minute",
+		"Page requests sent to each crawled host in the last minute", "host")
+)
@@ -0,0 +1,33 @@
+package metrics
+
+import "strconv"
+
+// batchSizeBounds are the upper bounds used to bucket embedding batch sizes
+var batchSizeBounds = []float64{1, 2, 4, 8, 16, 32, 64, 128, 256, 512, 1024, 2048}
+
+// Embedding API metrics broken down by model, batch size bucket and upstream status
+var (
+	EmbeddingBatchSize = NewHistogram("ai_search_embedding_batch_size",
+		"Texts sent per embedding API request", batchSizeBounds, "model")
+	EmbeddingRequests = NewCounter("ai_search_embedding_requests_total",
+		"Embedding API requests by upstream HTTP status (\"error\" when no response was received)",
+		"model", "batch_size", "status")
+	EmbeddingRequestDuration = NewHistogram("ai_search_embedding_request_duration_seconds",
+		"Embedding API request latency",
+		[]float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}, "model", "batch_size")
+	EmbeddingTokens = NewCounter("ai_search_embedding_tokens_total",
+		"Tokens billed by the embedding API", "model")
+	EmbeddingCost = NewCounter("ai_search_embedding_cost_dollars_total",
+		"Estimated embedding API spend in USD from the model registry pricing", "model")
+)
+
+// BatchSizeBucket returns the batch size bucket label for n texts, the
+// smallest power-of-two bound that holds it
+func BatchSizeBucket(n int) string {
+	for _, bound := range batchSizeBounds {
+		if float64(n) <= bound {
+			return strconv.Itoa(int(bound))
+		}
+	}
+	return "+Inf"
+}
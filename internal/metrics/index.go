@@ -0,0 +1,11 @@
+package metrics
+
+// Index write metrics
+var (
+	ElasticRetries = NewCounter("ai_search_elasticsearch_retries_total",
+		"Elasticsearch writes retried after a 429 or 503 response")
+	DeadLetters = NewCounter("ai_search_dead_letters_total",
+		"Chunks that permanently failed to index, by stage", "stage")
+	TruncatedDocuments = NewCounter("ai_search_truncated_documents_total",
+		"Documents sampled down to MAX_CHUNKS_PER_DOCUMENT chunks")
+)
@@ -0,0 +1,11 @@
+package metrics
+
+// Metering of API keys; the key label is the key's ID
+var (
+	APIKeyRequests = NewCounter("ai_search_api_key_requests_total",
+		"Requests served for an API key", "key")
+	APIKeyTokens = NewCounter("ai_search_api_key_tokens_total",
+		"LLM tokens consumed by the requests of an API key", "key")
+	APIKeyRejected = NewCounter("ai_search_api_key_rejected_total",
+		"Requests of an API key refused by its rate limit or a monthly quota", "key", "reason")
+)
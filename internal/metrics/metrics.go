@@ -0,0 +1,308 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Registry holds metrics and renders them in the Prometheus text format
+type Registry struct {
+	mutex   sync.RWMutex
+	metrics []metric
+	names   map[string]metric
+}
+
+// metric is implemented by every metric type in this package
+type metric interface {
+	name() string
+	write(w io.Writer)
+}
+
+// desc holds the shared metric description
+type desc struct {
+	metricName string
+	help       string
+	labels     []string
+}
+
+// Default is the process-wide registry served by Handler
+var Default = NewRegistry()
+
+// NewRegistry creates a new empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		names: make(map[string]metric),
+	}
+}
+
+// register adds a metric, returning an existing metric with the same name
+func (r *Registry) register(m metric) metric {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if existing, ok := r.names[m.name()]; ok {
+		return existing
+	}
+	r.names[m.name()] = m
+	r.metrics = append(r.metrics, m)
+	return m
+}
+
+// WritePrometheus writes all metrics in the Prometheus text exposition format
+func (r *Registry) WritePrometheus(w io.Writer) {
+	r.mutex.RLock()
+	metrics := make([]metric, len(r.metrics))
+	copy(metrics, r.metrics)
+	r.mutex.RUnlock()
+
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].name() < metrics[j].name()
+	})
+
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// Handler returns an HTTP handler serving the default registry
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		updateRuntime()
+		Default.WritePrometheus(w)
+	})
+}
+
+// Gauge is a metric that can go up and down
+type Gauge struct {
+	desc
+	mutex  sync.Mutex
+	values map[string]float64
+}
+
+// NewGauge registers a gauge in the default registry
+func NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{
+		desc:   desc{metricName: name, help: help, labels: labels},
+		values: make(map[string]float64),
+	}
+	return Default.register(g).(*Gauge)
+}
+
+// Set sets the gauge for the given label values
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	g.mutex.Lock()
+	g.values[labelKey(labelValues)] = value
+	g.mutex.Unlock()
+}
+
+// Add adds delta to the gauge for the given label values
+func (g *Gauge) Add(delta float64, labelValues ...string) {
+	g.mutex.Lock()
+	g.values[labelKey(labelValues)] += delta
+	g.mutex.Unlock()
+}
+
+// Value returns the current gauge value for the given label values
+func (g *Gauge) Value(labelValues ...string) float64 {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.values[labelKey(labelValues)]
+}
+
+func (g *Gauge) name() string { return g.metricName }
+
+func (g *Gauge) write(w io.Writer) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	writeSamples(w, g.desc, "gauge", g.values)
+}
+
+// Counter is a monotonically increasing metric
+type Counter struct {
+	desc
+	mutex  sync.Mutex
+	values map[string]float64
+}
+
+// NewCounter registers a counter in the default registry
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{
+		desc:   desc{metricName: name, help: help, labels: labels},
+		values: make(map[string]float64),
+	}
+	return Default.register(c).(*Counter)
+}
+
+// Inc increments the counter for the given label values
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds a non-negative value to the counter for the given label values
+func (c *Counter) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		return
+	}
+	c.mutex.Lock()
+	c.values[labelKey(labelValues)] += value
+	c.mutex.Unlock()
+}
+
+// Value returns the current counter value for the given label values
+func (c *Counter) Value(labelValues ...string) float64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.values[labelKey(labelValues)]
+}
+
+func (c *Counter) name() string { return c.metricName }
+
+func (c *Counter) write(w io.Writer) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	writeSamples(w, c.desc, "counter", c.values)
+}
+
+// Histogram samples observations into cumulative buckets
+type Histogram struct {
+	desc
+	buckets []float64
+	mutex   sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+// histogramSeries holds the observations for one label combination
+type histogramSeries struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram registers a histogram with the given upper bounds in the default registry
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	sorted := make([]float64, len(buckets))
+	copy(sorted, buckets)
+	sort.Float64s(sorted)
+
+	h := &Histogram{
+		desc:    desc{metricName: name, help: help, labels: labels},
+		buckets: sorted,
+		series:  make(map[string]*histogramSeries),
+	}
+	return Default.register(h).(*Histogram)
+}
+
+// Observe records a value for the given label values
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	key := labelKey(labelValues)
+	series, ok := h.series[key]
+	if !ok {
+		series = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = series
+	}
+
+	for i, bound := range h.buckets {
+		if value <= bound {
+			series.counts[i]++
+		}
+	}
+	series.count++
+	series.sum += value
+}
+
+func (h *Histogram) name() string { return h.metricName }
+
+func (h *Histogram) write(w io.Writer) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", h.metricName, helpEscaper.Replace(h.help))
+	fmt.Fprintf(w, "# TYPE %s histogram\n", h.metricName)
+
+	for _, key := range sortedKeys(h.series) {
+		series := h.series[key]
+		labels := formatLabels(h.labels, splitKey(key))
+		for i, bound := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, withLabel(labels, "le", formatFloat(bound)), series.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, withLabel(labels, "le", "+Inf"), series.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.metricName, labels, formatFloat(series.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.metricName, labels, series.count)
+	}
+}
+
+// writeSamples writes HELP, TYPE and one line per label combination
+func writeSamples(w io.Writer, d desc, kind string, values map[string]float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.metricName, helpEscaper.Replace(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.metricName, kind)
+	for _, key := range sortedKeys(values) {
+		fmt.Fprintf(w, "%s%s %s\n", d.metricName, formatLabels(d.labels, splitKey(key)), formatFloat(values[key]))
+	}
+}
+
+// The text format escapes backslashes and line feeds in help text, and
+// double quotes too in label values
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// labelKey joins label values into a map key
+func labelKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// splitKey reverses labelKey
+func splitKey(key string) []string {
+	if key == "" {
+		return nil
+	}
+	return strings.Split(key, "\xff")
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatLabels renders a {name="value",...} label set
+func formatLabels(names, values []string) string {
+	if len(names) == 0 || len(values) == 0 {
+		return ""
+	}
+
+	var parts []string
+	for i, name := range names {
+		if i < len(values) {
+			parts = append(parts, fmt.Sprintf("%s=\"%s\"", name, labelEscaper.Replace(values[i])))
+		}
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// withLabel appends one more label to a rendered label set
+func withLabel(labels, name, value string) string {
+	extra := fmt.Sprintf("%s=\"%s\"", name, labelEscaper.Replace(value))
+	if labels == "" {
+		return "{" + extra + "}"
+	}
+	return strings.TrimSuffix(labels, "}") + "," + extra + "}"
+}
+
+// formatFloat renders a float without trailing zeros
+func formatFloat(v float64) string {
+	return fmt.Sprintf("%g", v)
+}
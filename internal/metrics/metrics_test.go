@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// exposition renders the default registry and returns the lines of the
+// named metric
+func exposition(t *testing.T, name string) []string {
+	t.Helper()
+	var buf bytes.Buffer
+	Default.WritePrometheus(&buf)
+
+	var lines []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.HasPrefix(line, name+"{") || strings.HasPrefix(line, name+" ") ||
+			strings.HasPrefix(line, name+"_") || strings.HasPrefix(line, "# HELP "+name+" ") ||
+			strings.HasPrefix(line, "# TYPE "+name+" ") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func assertLines(t *testing.T, got, want []string) {
+	t.Helper()
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("exposition:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCounter(t *testing.T) {
+	counter := NewCounter("test_counter_total", "Things counted", "kind")
+	counter.Inc("b")
+	counter.Add(2.5, "a")
+	counter.Add(-1, "a") // counters only go up
+
+	if got := counter.Value("a"); got != 2.5 {
+		t.Errorf("Value(a) = %g, want 2.5", got)
+	}
+	assertLines(t, exposition(t, "test_counter_total"), []string{
+		"# HELP test_counter_total Things counted",
+		"# TYPE test_counter_total counter",
+		`test_counter_total{kind="a"} 2.5`,
+		`test_counter_total{kind="b"} 1`,
+	})
+}
+
+func TestGaugeWithoutLabels(t *testing.T) {
+	gauge := NewGauge("test_gauge", "A level")
+	gauge.Set(3)
+	gauge.Add(-4.5)
+
+	assertLines(t, exposition(t, "test_gauge"), []string{
+		"# HELP test_gauge A level",
+		"# TYPE test_gauge gauge",
+		"test_gauge -1.5",
+	})
+}
+
+func TestHistogramBucketsAreCumulative(t *testing.T) {
+	// Bounds are sorted when registered
+	histogram := NewHistogram("test_histogram_seconds", "Durations", []float64{1, 0.5}, "stage")
+	for _, value := range []float64{0.25, 0.75, 2} {
+		histogram.Observe(value, "crawl")
+	}
+
+	assertLines(t, exposition(t, "test_histogram_seconds"), []string{
+		"# HELP test_histogram_seconds Durations",
+		"# TYPE test_histogram_seconds histogram",
+		`test_histogram_seconds_bucket{stage="crawl",le="0.5"} 1`,
+		`test_histogram_seconds_bucket{stage="crawl",le="1"} 2`,
+		`test_histogram_seconds_bucket{stage="crawl",le="+Inf"} 3`,
+		`test_histogram_seconds_sum{stage="crawl"} 3`,
+		`test_histogram_seconds_count{stage="crawl"} 3`,
+	})
+}
+
+func TestRegisterReturnsExistingMetric(t *testing.T) {
+	first := NewCounter("test_shared_total", "Shared")
+	second := NewCounter("test_shared_total", "Shared")
+	if first != second {
+		t.Fatal("registering a name twice created a second counter")
+	}
+	first.Inc()
+	if got := second.Value(); got != 1 {
+		t.Errorf("Value() = %g, want 1", got)
+	}
+}
+
+func TestExpositionEscaping(t *testing.T) {
+	counter := NewCounter("test_escaped_total", "Help with a \\ and a\nline feed", "host")
+	counter.Inc("say \"hi\"\\\nthere é")
+
+	assertLines(t, exposition(t, "test_escaped_total"), []string{
+		`# HELP test_escaped_total Help with a \\ and a\nline feed`,
+		"# TYPE test_escaped_total counter",
+		`test_escaped_total{host="say \"hi\"\\\nthere é"} 1`,
+	})
+}
+
+func TestBatchSizeBucket(t *testing.T) {
+	tests := map[int]string{1: "1", 3: "4", 64: "64", 65: "128", 5000: "+Inf"}
+	for n, want := range tests {
+		if got := BatchSizeBucket(n); got != want {
+			t.Errorf("BatchSizeBucket(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package metrics
+
+// Warm-standby replication metrics
+var (
+	ReplicatedDocuments = NewCounter("ai_search_replicated_documents_total",
+		"Documents mirrored to the standby indexes by status (success or error)", "status")
+	ReplicationLag = NewGauge("ai_search_replication_lag_seconds",
+		"Age of the last outbox event applied to the standby indexes")
+)
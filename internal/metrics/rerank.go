@@ -0,0 +1,11 @@
+package metrics
+
+// Rerank cache metrics
+var (
+	RerankCacheLookups = NewCounter("ai_search_rerank_cache_lookups_total",
+		"Rerank cache lookups by result (hit or miss); a miss reranks with the LLM", "result")
+	RerankCacheEntries = NewGauge("ai_search_rerank_cache_entries",
+		"Reranked candidate sets cached")
+	RerankCacheEvictions = NewCounter("ai_search_rerank_cache_evictions_total",
+		"Least recently used outcomes dropped from the rerank cache to stay within RERANK_CACHE_SIZE")
+)
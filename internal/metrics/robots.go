@@ -0,0 +1,11 @@
+package metrics
+
+// robots.txt cache metrics
+var (
+	RobotsCacheLookups = NewCounter("ai_search_robots_cache_lookups_total",
+		"robots.txt lookups by where they were served from (memory, store or fetch)", "source")
+	RobotsCacheEntries = NewGauge("ai_search_robots_cache_entries",
+		"Hosts with robots.txt rules cached in memory")
+	RobotsCacheEvictions = NewCounter("ai_search_robots_cache_evictions_total",
+		"Least recently used hosts dropped from the robots.txt cache to stay within ROBOTS_CACHE_SIZE")
+)
@@ -0,0 +1,10 @@
+package metrics
+
+// Per-route limits of the HTTP server; the route label is search, answer
+// or ingest
+var (
+	RouteInFlight = NewGauge("ai_search_route_in_flight",
+		"Requests being handled by a route group", "route")
+	RouteRejected = NewCounter("ai_search_route_rejected_total",
+		"Requests turned away because a route group was at its concurrency limit", "route")
+)
@@ -0,0 +1,33 @@
+package metrics
+
+import "runtime"
+
+// Go runtime metrics, read when metrics are scraped
+var (
+	Goroutines = NewGauge("ai_search_goroutines",
+		"Goroutines currently running")
+	HeapAllocBytes = NewGauge("ai_search_heap_alloc_bytes",
+		"Bytes of allocated heap objects")
+	HeapInuseBytes = NewGauge("ai_search_heap_inuse_bytes",
+		"Bytes in in-use heap spans")
+	HeapObjects = NewGauge("ai_search_heap_objects",
+		"Allocated heap objects")
+	GCCycles = NewGauge("ai_search_gc_cycles",
+		"Completed garbage collection cycles")
+	GCPauseSeconds = NewGauge("ai_search_gc_pause_seconds",
+		"Cumulative time the world was stopped for garbage collection")
+)
+
+// updateRuntime reads the current Go runtime statistics into the runtime
+// metrics
+func updateRuntime() {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	Goroutines.Set(float64(runtime.NumGoroutine()))
+	HeapAllocBytes.Set(float64(stats.HeapAlloc))
+	HeapInuseBytes.Set(float64(stats.HeapInuse))
+	HeapObjects.Set(float64(stats.HeapObjects))
+	GCCycles.Set(float64(stats.NumGC))
+	GCPauseSeconds.Set(float64(stats.PauseTotalNs) / 1e9)
+}
@@ -0,0 +1,18 @@
+package metrics
+
+// Search service level objective metrics. The slo label is "availability"
+// or "latency", the window label one of the burn rate windows (5m, 30m, 1h
+// or 6h).
+var (
+	SearchRequests = NewCounter("ai_search_search_requests_total",
+		"Search requests by result (success or error)", "result")
+	SearchLatency = NewHistogram("ai_search_search_latency_seconds",
+		"Time taken to answer a search request",
+		[]float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10})
+	SLOBurnRate = NewGauge("ai_search_slo_burn_rate",
+		"Rate the error budget of a search SLO is spent over a window; 1 spends it exactly", "slo", "window")
+	SLOCompliance = NewGauge("ai_search_slo_compliance",
+		"Fraction of search requests over a window meeting an SLO", "slo", "window")
+	SearchLatencyP95 = NewGauge("ai_search_search_latency_p95_seconds",
+		"Approximate 95th percentile search latency over a window", "window")
+)
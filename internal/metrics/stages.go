@@ -0,0 +1,37 @@
+package metrics
+
+// Pipeline stage names used as the "stage" label
+const (
+	StageCrawl = "crawl"
+	StageEmbed = "embed"
+	StageIndex = "index"
+)
+
+// Per-stage worker pool metrics shared by the crawler, embedder and indexer
+var (
+	StageQueueDepth = NewGauge("ai_search_stage_queue_depth",
+		"Items waiting to be processed by a pipeline stage", "stage")
+	StageWorkers = NewGauge("ai_search_stage_workers",
+		"Workers available to a pipeline stage", "stage")
+	StageBusyWorkers = NewGauge("ai_search_stage_busy_workers",
+		"Workers currently processing an item in a pipeline stage", "stage")
+	StageUtilization = NewGauge("ai_search_stage_utilization",
+		"Fraction of busy workers in a pipeline stage", "stage")
+	StageSuggestedWorkers = NewGauge("ai_search_stage_suggested_workers",
+		"Worker count suggested for a pipeline stage based on backpressure", "stage")
+	StageProcessed = NewCounter("ai_search_stage_processed_total",
+		"Items processed by a pipeline stage", "stage", "result")
+	StageDuration = NewHistogram("ai_search_stage_duration_seconds",
+		"Time spent processing one item in a pipeline stage",
+		[]float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}, "stage")
+)
+
+// UpdateUtilization recomputes the utilization gauge of a stage
+func UpdateUtilization(stage string) {
+	workers := StageWorkers.Value(stage)
+	if workers <= 0 {
+		StageUtilization.Set(0, stage)
+		return
+	}
+	StageUtilization.Set(StageBusyWorkers.Value(stage)/workers, stage)
+}
@@ -0,0 +1,339 @@
+package parser
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// Sources of a page's author and dates, in order of precedence: structured
+// data is written for machines, meta tags for link previews and bylines
+// for readers
+const (
+	fromJSONLD = iota
+	fromMeta
+	fromByline
+	authorshipSources
+)
+
+// maxBylineWords is the most words an author name read from a byline may
+// have; longer text is an author bio or a sentence
+const maxBylineWords = 6
+
+// authorship is the author and dates of a page as one source declares them
+type authorship struct {
+	author    string
+	published time.Time
+	modified  time.Time
+}
+
+// Meta tags, by name, property or itemprop in lower case, declaring when a
+// page was published or modified and who wrote it
+var (
+	publishedMeta = map[string]bool{
+		"article:published_time": true, "og:published_time": true, "datepublished": true,
+		"date": true, "pubdate": true, "publishdate": true, "publish-date": true, "publish_date": true,
+		"dc.date": true, "dc.date.issued": true, "dc.date.created": true, "dcterms.issued": true, "dcterms.created": true,
+		"parsely-pub-date": true, "sailthru.date": true, "citation_publication_date": true, "citation_date": true,
+	}
+	modifiedMeta = map[string]bool{
+		"article:modified_time": true, "og:updated_time": true, "datemodified": true,
+		"dc.date.modified": true, "dcterms.modified": true, "last-modified": true, "lastmod": true,
+	}
+	authorMeta = map[string]bool{
+		"author": true, "article:author": true, "dc.creator": true, "dcterms.creator": true,
+		"parsely-author": true, "sailthru.author": true, "citation_author": true, "byl": true,
+	}
+)
+
+// dateLayouts are the date formats pages declare dates in, most common
+// first. Dates without a zone are taken as UTC.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"2006/01/02",
+	time.RFC1123,
+	time.RFC1123Z,
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+}
+
+// declare records the author and dates a source declares, keeping the
+// first of each
+func (p *ParsedContent) declare(source int, author string, published, modified time.Time) {
+	declared := &p.declared[source]
+	if declared.author == "" {
+		declared.author = author
+	}
+	if declared.published.IsZero() {
+		declared.published = published
+	}
+	if declared.modified.IsZero() {
+		declared.modified = modified
+	}
+}
+
+// resolveAuthorship sets the author and dates of a page from the most
+// reliable source declaring each. A page declaring only a modification date
+// is taken as published then.
+func (p *ParsedContent) resolveAuthorship() {
+	for _, declared := range p.declared {
+		if p.Author == "" {
+			p.Author = declared.author
+		}
+		if p.Published.IsZero() {
+			p.Published = declared.published
+		}
+		if p.Modified.IsZero() {
+			p.Modified = declared.modified
+		}
+	}
+	if p.Published.IsZero() {
+		p.Published = p.Modified
+	}
+}
+
+// metaAuthorship records the author or date declared by a meta tag of the
+// given name, property or itemprop
+func (p *ParsedContent) metaAuthorship(name, content string) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	switch {
+	case publishedMeta[name]:
+		p.declare(fromMeta, "", parseDate(content), time.Time{})
+	case modifiedMeta[name]:
+		p.declare(fromMeta, "", time.Time{}, parseDate(content))
+	case authorMeta[name]:
+		p.declare(fromMeta, authorName(content), time.Time{}, time.Time{})
+	}
+}
+
+// timeAuthorship records the date of a <time datetime> element: published
+// or modified when marked by itemprop or pubdate, and otherwise, for the
+// first one, as the date of a byline
+func (p *ParsedContent) timeAuthorship(attrs []html.Attribute) {
+	var datetime, itemprop string
+	pubdate := false
+	for _, attr := range attrs {
+		switch attr.Key {
+		case "datetime":
+			datetime = attr.Val
+		case "itemprop":
+			itemprop = strings.ToLower(attr.Val)
+		case "pubdate":
+			pubdate = true
+		}
+	}
+	date := parseDate(datetime)
+	if date.IsZero() {
+		return
+	}
+
+	switch {
+	case itemprop == "datemodified":
+		p.declare(fromMeta, "", time.Time{}, date)
+	case itemprop == "datepublished" || pubdate:
+		p.declare(fromMeta, "", date, time.Time{})
+	default:
+		p.declare(fromByline, "", date, time.Time{})
+	}
+}
+
+// isJSONLD reports whether a <script> element holds JSON-LD structured data
+func isJSONLD(attrs []html.Attribute) bool {
+	for _, attr := range attrs {
+		if attr.Key == "type" {
+			mediaType, _, _ := strings.Cut(attr.Val, ";")
+			return strings.EqualFold(strings.TrimSpace(mediaType), "application/ld+json")
+		}
+	}
+	return false
+}
+
+// jsonLDAuthorship records the author and dates of the first item of a
+// JSON-LD script that declares any, looking into arrays and @graph
+func (p *ParsedContent) jsonLDAuthorship(script string) {
+	var data any
+	if err := json.Unmarshal([]byte(script), &data); err != nil {
+		return
+	}
+	if item := authoredItem(data); item != nil {
+		published, _ := item["datePublished"].(string)
+		modified, _ := item["dateModified"].(string)
+		author := jsonLDNames(item["author"])
+		if author == "" {
+			author = jsonLDNames(item["creator"])
+		}
+		p.declare(fromJSONLD, author, parseDate(published), parseDate(modified))
+	}
+}
+
+// authoredItem returns the first JSON-LD item with an author or a
+// publication or modification date
+func authoredItem(data any) map[string]any {
+	switch value := data.(type) {
+	case map[string]any:
+		for _, key := range []string{"datePublished", "dateModified", "author"} {
+			if _, ok := value[key]; ok {
+				return value
+			}
+		}
+		return authoredItem(value["@graph"])
+	case []any:
+		for _, element := range value {
+			if item := authoredItem(element); item != nil {
+				return item
+			}
+		}
+	}
+	return nil
+}
+
+// jsonLDNames reads the names of a JSON-LD author, which may be a name, a
+// Person or Organization, or a list of either
+func jsonLDNames(value any) string {
+	switch author := value.(type) {
+	case string:
+		return authorName(author)
+	case map[string]any:
+		name, _ := author["name"].(string)
+		return authorName(name)
+	case []any:
+		var names []string
+		for _, element := range author {
+			if name := jsonLDNames(element); name != "" {
+				names = append(names, name)
+			}
+		}
+		return strings.Join(names, ", ")
+	}
+	return ""
+}
+
+// extractByline records the author named by a byline element: a link with
+// rel="author", an element with itemprop="author", or one whose class names
+// a byline or the author
+func (p *htmlParser) extractByline(n *html.Node, parsed *ParsedContent) {
+	if parsed.declared[fromByline].author != "" || !isByline(n) {
+		return
+	}
+
+	// Microdata names the author in a nested itemprop="name"
+	name := n
+	if child := findItemprop(n, "name"); child != nil {
+		name = child
+	}
+	var text strings.Builder
+	p.extractText(name, &text)
+	parsed.declare(fromByline, bylineAuthor(text.String()), time.Time{}, time.Time{})
+}
+
+// isByline reports whether an element names the author of its page
+func isByline(n *html.Node) bool {
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "rel":
+			if n.Data == "a" && hasToken(attr.Val, "author") {
+				return true
+			}
+		case "itemprop":
+			if hasToken(strings.ToLower(attr.Val), "author") {
+				return true
+			}
+		case "class":
+			for _, class := range strings.Fields(strings.ToLower(attr.Val)) {
+				if strings.Contains(class, "byline") || class == "author" || class == "author-name" ||
+					class == "post-author" || class == "entry-author" || class == "article-author" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// findItemprop returns the first element below n with the given itemprop
+func findItemprop(n *html.Node, itemprop string) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		for _, attr := range c.Attr {
+			if attr.Key == "itemprop" && hasToken(strings.ToLower(attr.Val), itemprop) {
+				return c
+			}
+		}
+		if found := findItemprop(c, itemprop); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// hasToken reports whether a space-separated attribute value holds a token
+func hasToken(value, token string) bool {
+	for _, field := range strings.Fields(value) {
+		if field == token {
+			return true
+		}
+	}
+	return false
+}
+
+// bylineAuthor reads the author out of a byline such as "By Jane Doe |
+// March 3, 2024", returning "" when what remains is too long to be a name
+func bylineAuthor(byline string) string {
+	byline = strings.Join(strings.Fields(byline), " ")
+	for _, prefix := range []string{"written by ", "posted by ", "by "} {
+		if len(byline) > len(prefix) && strings.EqualFold(byline[:len(prefix)], prefix) {
+			byline = byline[len(prefix):]
+			break
+		}
+	}
+	if i := strings.IndexAny(byline, "|•·,"); i >= 0 {
+		byline = byline[:i]
+	}
+	for _, separator := range []string{" - ", " – ", " — ", " on ", " updated ", " published "} {
+		byline, _, _ = strings.Cut(byline, separator)
+	}
+	if len(strings.Fields(byline)) > maxBylineWords {
+		return ""
+	}
+	return authorName(byline)
+}
+
+// authorName cleans an author name, rejecting the profile URLs some pages
+// give as article:author
+func authorName(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	if strings.Contains(name, "://") || strings.HasPrefix(name, "/") {
+		return ""
+	}
+	return name
+}
+
+// parseDate reads a date in one of the dateLayouts, returning the zero time
+// when it has none or an implausible year
+func parseDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			if date.Year() < 1970 || date.Year() > 2100 {
+				return time.Time{}
+			}
+			return date.UTC()
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,63 @@
+package parser
+
+import "strings"
+
+// blockElements are the elements whose text stands apart from the text
+// around it, such as paragraphs, headings and list items. Their text is put
+// on lines of its own so that chunks break along the page's structure.
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "br": true,
+	"caption": true, "dd": true, "details": true, "dialog": true, "div": true, "dl": true,
+	"dt": true, "fieldset": true, "figcaption": true, "figure": true, "footer": true,
+	"form": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"header": true, "hgroup": true, "hr": true, "li": true, "main": true, "nav": true,
+	"ol": true, "p": true, "section": true, "summary": true, "table": true, "title": true,
+	"tr": true, "ul": true,
+}
+
+// textWriter joins the text of a page, inline text with spaces and the
+// text of block-level elements on lines of its own
+type textWriter struct {
+	builder   strings.Builder
+	lineBreak bool // Whether the next text starts a new line
+}
+
+// WriteText adds the text of an element, on a new line if a block-level
+// element started or ended since the text before
+func (w *textWriter) WriteText(text string) {
+	if w.builder.Len() > 0 {
+		if w.lineBreak {
+			w.builder.WriteByte('\n')
+		} else {
+			w.builder.WriteByte(' ')
+		}
+	}
+	w.lineBreak = false
+	w.builder.WriteString(text)
+}
+
+// WriteBlock adds text that makes lines of its own, such as a table or a
+// fenced code block
+func (w *textWriter) WriteBlock(text string) {
+	if text = strings.Trim(text, "\n"); text == "" {
+		return
+	}
+	w.Break()
+	w.WriteText(text)
+	w.Break()
+}
+
+// Break starts a new line for the text that follows
+func (w *textWriter) Break() {
+	w.lineBreak = true
+}
+
+// Len returns the length of the text written so far
+func (w *textWriter) Len() int {
+	return w.builder.Len()
+}
+
+// String returns the text written
+func (w *textWriter) String() string {
+	return w.builder.String()
+}
@@ -0,0 +1,133 @@
+package parser
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// CodeFence is the marker put around code blocks in the text of a parsed
+// page, as in Markdown. A block containing the marker itself is fenced
+// with a longer run of backticks.
+const CodeFence = "```"
+
+// CodeBlock is a block of preformatted code of a page, kept verbatim
+type CodeBlock struct {
+	Language string `json:"language,omitempty"` // From a language-* or lang-* class, empty when undeclared
+	Code     string `json:"code"`
+}
+
+// Text renders the block for the page text as a fenced code block on lines
+// of its own, as in "```go\nfmt.Println()\n```", so that its line breaks and
+// indentation survive and the chunker can tell it from prose
+func (c *CodeBlock) Text() string {
+	fence := CodeFence
+	for strings.Contains(c.Code, fence) {
+		fence += "`"
+	}
+	return "\n" + fence + c.Language + "\n" + c.Code + "\n" + fence + "\n"
+}
+
+// newCodeBlock builds a code block from the raw text of an element,
+// dropping the blank lines around it and trailing spaces. It returns nil
+// when there is no code.
+func (p *htmlParser) newCodeBlock(language, raw string) *CodeBlock {
+	raw = strings.ReplaceAll(raw, "\r\n", "\n")
+	lines := strings.Split(raw, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	code := strings.Trim(strings.Join(lines, "\n"), "\n")
+	if strings.TrimSpace(code) == "" {
+		return nil
+	}
+
+	code = truncateUTF8(code, p.config.MaxElementText)
+	return &CodeBlock{Language: language, Code: code}
+}
+
+// extractCode reads a <pre> element, or a <code> element spanning several
+// lines, into a code block
+func (p *htmlParser) extractCode(n *html.Node) *CodeBlock {
+	var raw strings.Builder
+	rawText(n, &raw)
+	return p.newCodeBlock(codeLanguage(n), raw.String())
+}
+
+// isCodeBlock reports whether a <code> element outside <pre> is laid out
+// as a block, its text spanning several lines, rather than inline code
+func isCodeBlock(n *html.Node) bool {
+	var raw strings.Builder
+	rawText(n, &raw)
+	return strings.Contains(strings.TrimSpace(raw.String()), "\n")
+}
+
+// rawText collects the text of a node as it is, line breaks and
+// indentation included, turning <br> into a line break
+func rawText(n *html.Node, text *strings.Builder) {
+	switch {
+	case n.Type == html.TextNode:
+		text.WriteString(n.Data)
+		return
+	case n.Type == html.ElementNode && n.Data == "br":
+		text.WriteString("\n")
+		return
+	case n.Type == html.ElementNode && (n.Data == "script" || n.Data == "style"):
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		rawText(c, text)
+	}
+}
+
+// codeLanguage returns the language declared by a code element or by the
+// first element nested in it, as highlighters mark <pre><code class=
+// "language-go">
+func codeLanguage(n *html.Node) string {
+	if language := declaredLanguage(n.Attr); language != "" {
+		return language
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			return declaredLanguage(c.Attr)
+		}
+	}
+	return ""
+}
+
+// declaredLanguage reads a code language from a data-lang or
+// data-language attribute or a language-*, lang-* or highlight-* class
+func declaredLanguage(attrs []html.Attribute) string {
+	var class string
+	for _, attr := range attrs {
+		switch attr.Key {
+		case "data-lang", "data-language":
+			if language := codeLanguageName(attr.Val); language != "" {
+				return language
+			}
+		case "class":
+			class = attr.Val
+		}
+	}
+
+	for _, name := range strings.Fields(class) {
+		for _, prefix := range []string{"language-", "lang-", "highlight-"} {
+			if strings.HasPrefix(name, prefix) {
+				if language := codeLanguageName(strings.TrimPrefix(name, prefix)); language != "" {
+					return language
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// codeLanguageName lower-cases a language name, rejecting one that could
+// not follow a code fence
+func codeLanguageName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || strings.ContainsAny(name, "` \t\n") {
+		return ""
+	}
+	return name
+}
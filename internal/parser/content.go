@@ -0,0 +1,170 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+)
+
+// ContentParser extracts the text, title and links of one kind of document
+type ContentParser interface {
+	Parse(content io.Reader, baseURL *url.URL) (*ParsedContent, error)
+}
+
+// ContentParserFunc adapts a function to the ContentParser interface
+type ContentParserFunc func(content io.Reader, baseURL *url.URL) (*ParsedContent, error)
+
+// Parse calls f
+func (f ContentParserFunc) Parse(content io.Reader, baseURL *url.URL) (*ParsedContent, error) {
+	return f(content, baseURL)
+}
+
+// WithSize marks content as size bytes long, as a response's Content-Length
+// tells, so that parsers can choose how to read it before reading any
+func WithSize(content io.Reader, size int64) io.Reader {
+	return &sizedReader{Reader: content, size: size}
+}
+
+// sizedReader is content of a known size
+type sizedReader struct {
+	io.Reader
+	size int64
+}
+
+// Len returns the size of the content, as bytes.Reader and strings.Reader
+// do for theirs
+func (r *sizedReader) Len() int {
+	return int(r.size)
+}
+
+// Format identifies the documents a parser reads: by media type, and by
+// file name for documents served or stored without a specific type
+type Format struct {
+	MediaTypes []string // The first names the format, as in "text/markdown"
+	Globs      []string // Matched against base file names in any case, as in "*.md"
+}
+
+// Registry routes documents to the parser registered for their media type,
+// or for their file name when their type is missing or generic, so that a
+// format is added by registering its parser, without changing the crawler
+// or other callers
+type Registry struct {
+	parsers        map[string]ContentParser
+	globs          []namedType
+	detectLanguage bool
+}
+
+// namedType is the media type of the documents whose file name matches a
+// glob
+type namedType struct {
+	glob      string
+	mediaType string
+}
+
+// NewRegistry creates a registry with parsers for HTML, plain text,
+// Markdown, JSON and, unless disabled, PDF and Office documents registered
+func NewRegistry(config Config) *Registry {
+	htmlParser := NewHTMLParser(config)
+	text := &textParser{maxText: config.MaxTextSize}
+	if text.maxText == 0 {
+		text.maxText = 2 * 1024 * 1024 // 2MB
+	}
+
+	r := &Registry{parsers: make(map[string]ContentParser), detectLanguage: config.DetectLanguage}
+	r.Register(Format{
+		MediaTypes: []string{"text/html", "application/xhtml+xml"},
+		Globs:      []string{"*.html", "*.htm", "*.xhtml"},
+	}, ContentParserFunc(htmlParser.ParseHTML))
+	if !config.DisablePDF {
+		r.Register(Format{MediaTypes: []string{"application/pdf"}, Globs: []string{"*.pdf"}}, ContentParserFunc(text.parsePDF))
+	}
+	if !config.DisableOffice {
+		r.Register(Format{MediaTypes: []string{docxMediaType}, Globs: []string{"*.docx"}}, ContentParserFunc(text.parseOffice))
+		r.Register(Format{MediaTypes: []string{pptxMediaType}, Globs: []string{"*.pptx"}}, ContentParserFunc(text.parseOffice))
+		r.Register(Format{MediaTypes: []string{xlsxMediaType}, Globs: []string{"*.xlsx"}}, ContentParserFunc(text.parseOffice))
+		// Office documents served without a specific type sniff as ZIP
+		r.Register(Format{MediaTypes: []string{"application/zip"}}, ContentParserFunc(text.parseOffice))
+	}
+	r.Register(Format{MediaTypes: []string{"text/plain"}}, ContentParserFunc(text.parseText))
+	// Raw file hosts such as raw.githubusercontent.com serve Markdown as
+	// text/plain
+	r.Register(Format{
+		MediaTypes: []string{"text/markdown", "text/x-markdown"},
+		Globs:      []string{"*.md", "*.markdown", "*.mdown", "*.mkd"},
+	}, ContentParserFunc(text.parseMarkdown))
+	r.Register(Format{MediaTypes: []string{"application/json"}, Globs: []string{"*.json"}}, ContentParserFunc(text.parseJSON))
+	return r
+}
+
+// Register routes documents of a format to a parser, replacing the parser
+// of any media type or glob registered before
+func (r *Registry) Register(format Format, parser ContentParser) {
+	for _, mediaType := range format.MediaTypes {
+		r.parsers[MediaType(mediaType)] = parser
+	}
+	if len(format.MediaTypes) == 0 {
+		return
+	}
+	for _, glob := range format.Globs {
+		glob = strings.ToLower(glob)
+		r.globs = slices.DeleteFunc(r.globs, func(named namedType) bool { return named.glob == glob })
+		r.globs = append(r.globs, namedType{glob: glob, mediaType: MediaType(format.MediaTypes[0])})
+	}
+}
+
+// ParserFor returns the parser of a media type. Types with a structured
+// syntax suffix, such as application/ld+json, fall back to the parser of
+// the syntax. With Config.DetectLanguage the parser also detects the
+// language of documents declaring none.
+func (r *Registry) ParserFor(mediaType string) (ContentParser, bool) {
+	mediaType = MediaType(mediaType)
+	parser, ok := r.parsers[mediaType]
+	if !ok {
+		if i := strings.LastIndexByte(mediaType, '+'); i >= 0 {
+			parser, ok = r.parsers["application/"+mediaType[i+1:]]
+		}
+	}
+	if !ok || !r.detectLanguage {
+		return parser, ok
+	}
+	return ContentParserFunc(func(content io.Reader, baseURL *url.URL) (*ParsedContent, error) {
+		parsed, err := parser.Parse(content, baseURL)
+		if err == nil && parsed.Language == "" {
+			parsed.Language = DetectLanguage(parsed.Text)
+		}
+		return parsed, err
+	}), true
+}
+
+// TypeByName returns the media type of the format whose glob matches a
+// file name or the last element of a path, the format registered last when
+// several do, or "" when none does
+func (r *Registry) TypeByName(name string) string {
+	name = strings.ToLower(path.Base(name))
+	for i := len(r.globs) - 1; i >= 0; i-- {
+		if matched, _ := path.Match(r.globs[i].glob, name); matched {
+			return r.globs[i].mediaType
+		}
+	}
+	return ""
+}
+
+// MediaType returns the lower-case media type of a Content-Type header
+// value without its parameters
+func MediaType(contentType string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType
+	}
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(mediaType))
+}
+
+// contentHash returns the SHA-256 of a document's text
+func contentHash(text string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(text)))
+}
@@ -0,0 +1,208 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// maxHeadingLength is the longest first line taken as the heading of a
+// document without markup
+const maxHeadingLength = 120
+
+// textURLPattern matches the absolute URLs written out in plain text
+var textURLPattern = regexp.MustCompile(`https?://[^\s<>"'()\[\]{}]+`)
+
+// jsonTitleKeys and jsonDescriptionKeys name the top-level fields of a JSON
+// document holding its title and description, in order of preference
+var (
+	jsonTitleKeys       = []string{"title", "name", "headline"}
+	jsonDescriptionKeys = []string{"description", "summary", "abstract"}
+)
+
+// textParser parses the documents that have no HTML markup, keeping up to
+// maxText bytes of their text
+type textParser struct {
+	maxText int
+}
+
+// parseText parses a plain text document
+func (p *textParser) parseText(content io.Reader, baseURL *url.URL) (*ParsedContent, error) {
+	data, err := io.ReadAll(io.LimitReader(content, int64(p.maxText)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read text: %w", err)
+	}
+	text := strings.TrimSpace(strings.ToValidUTF8(string(data), ""))
+
+	parsed := p.document(text)
+	parsed.Links = textLinks(text, baseURL)
+	return parsed, nil
+}
+
+// parseJSON parses a JSON document, indexing its string values. URLs among
+// them become links, and well-known top-level fields give the title and
+// description.
+func (p *textParser) parseJSON(content io.Reader, baseURL *url.URL) (*ParsedContent, error) {
+	decoder := json.NewDecoder(content)
+	decoder.UseNumber()
+	var document any
+	if err := decoder.Decode(&document); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	var text strings.Builder
+	var links []*url.URL
+	var walk func(value any)
+	walk = func(value any) {
+		switch value := value.(type) {
+		case map[string]any:
+			// Fields are visited in a fixed order so the content hash is
+			// stable
+			keys := make([]string, 0, len(value))
+			for key := range value {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				walk(value[key])
+			}
+		case []any:
+			for _, item := range value {
+				walk(item)
+			}
+		case string:
+			value = strings.TrimSpace(value)
+			if link, err := url.Parse(value); err == nil && (link.Scheme == "http" || link.Scheme == "https") && link.Host != "" {
+				links = append(links, link)
+			} else if value != "" && text.Len() < p.maxText {
+				text.WriteString(value)
+				text.WriteString("\n")
+			}
+		}
+	}
+	walk(document)
+
+	parsed := p.document(strings.TrimSpace(text.String()))
+	parsed.Links = links
+	if fields, ok := document.(map[string]any); ok {
+		parsed.Title = jsonField(fields, jsonTitleKeys)
+		parsed.MetaDesc = jsonField(fields, jsonDescriptionKeys)
+	}
+	return parsed, nil
+}
+
+// jsonField returns the first of the named string fields that is set
+func jsonField(fields map[string]any, keys []string) string {
+	for _, key := range keys {
+		if value, ok := fields[key].(string); ok && strings.TrimSpace(value) != "" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// parsePDF extracts the text of a PDF document page by page, and its title,
+// author, subject and keywords from the document information
+func (p *textParser) parsePDF(content io.Reader, baseURL *url.URL) (parsed *ParsedContent, err error) {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+
+	// The PDF reader panics on malformed documents
+	defer func() {
+		if r := recover(); r != nil {
+			parsed, err = nil, fmt.Errorf("failed to parse PDF: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PDF: %w", err)
+	}
+
+	var text strings.Builder
+	fonts := make(map[string]*pdf.Font)
+	for i := 1; i <= reader.NumPage() && text.Len() < p.maxText; i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		for _, name := range page.Fonts() {
+			if _, cached := fonts[name]; !cached {
+				font := page.Font(name)
+				fonts[name] = &font
+			}
+		}
+		pageText, err := page.GetPlainText(fonts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract text of PDF page %d: %w", i, err)
+		}
+		text.WriteString(strings.TrimSpace(pageText))
+		text.WriteString("\n\n")
+	}
+
+	parsed = p.document(strings.TrimSpace(text.String()))
+	info := reader.Trailer().Key("Info")
+	parsed.Title = strings.TrimSpace(info.Key("Title").Text())
+	parsed.MetaDesc = strings.TrimSpace(info.Key("Subject").Text())
+	parsed.Author = strings.TrimSpace(info.Key("Author").Text())
+	parsed.Keywords = documentKeywords(info.Key("Keywords").Text())
+	if lang := reader.Trailer().Key("Root").Key("Lang").Text(); lang != "" {
+		parsed.Language = NormalizeLanguage(lang)
+		parsed.Region = LanguageRegion(lang)
+	}
+	parsed.Links = textLinks(parsed.Text, baseURL)
+	return parsed, nil
+}
+
+// documentKeywords splits the keywords of a PDF or Office document's
+// properties, which authoring tools separate by commas or semicolons
+func documentKeywords(keywords string) []string {
+	var split []string
+	for _, keyword := range strings.FieldsFunc(keywords, func(r rune) bool { return r == ',' || r == ';' }) {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			split = append(split, keyword)
+		}
+	}
+	return split
+}
+
+// document builds the parsed content of a document's text, taking its first
+// line as the heading when it is short enough to be one
+func (p *textParser) document(text string) *ParsedContent {
+	if len(text) > p.maxText {
+		text = strings.ToValidUTF8(text[:p.maxText], "")
+	}
+
+	heading, _, _ := strings.Cut(text, "\n")
+	heading = strings.TrimSpace(strings.TrimLeft(heading, "# "))
+	if len(heading) > maxHeadingLength {
+		heading = ""
+	}
+
+	return &ParsedContent{
+		Heading:     heading,
+		Text:        text,
+		Links:       []*url.URL{},
+		ContentHash: contentHash(text),
+	}
+}
+
+// textLinks returns the absolute URLs written out in a text
+func textLinks(text string, baseURL *url.URL) []*url.URL {
+	links := []*url.URL{}
+	for _, match := range textURLPattern.FindAllString(text, -1) {
+		if link, err := url.Parse(strings.TrimRight(match, ".,;:!?")); err == nil {
+			links = append(links, baseURL.ResolveReference(link))
+		}
+	}
+	return links
+}
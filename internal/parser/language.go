@@ -0,0 +1,141 @@
+package parser
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Detection needs this many words, or letters in scripts written without
+// spaces, to tell languages apart
+const (
+	minDetectWords   = 20
+	minDetectLetters = 50
+)
+
+// languageStopwords are frequent function words of the Latin-script
+// languages told apart by their stopwords
+var languageStopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "it", "for", "with", "as", "are", "this", "be", "on", "was", "by", "not", "you", "have"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "zu", "den", "mit", "sich", "auf", "ein", "eine", "dem", "des", "auch", "es", "sie", "wird", "für"},
+	"fr": {"le", "la", "les", "et", "des", "est", "une", "du", "dans", "que", "pour", "qui", "pas", "sur", "au", "avec", "ce", "il", "sont", "par"},
+	"es": {"el", "la", "los", "las", "y", "que", "del", "en", "un", "una", "por", "con", "para", "es", "se", "su", "al", "lo", "como", "más"},
+	"it": {"il", "di", "che", "la", "e", "per", "un", "una", "non", "sono", "del", "della", "le", "gli", "con", "si", "è", "nel", "alla", "anche"},
+	"pt": {"o", "os", "as", "de", "que", "do", "da", "em", "um", "uma", "para", "com", "não", "se", "dos", "das", "na", "no", "por", "mais"},
+	"nl": {"de", "het", "een", "en", "van", "is", "dat", "niet", "op", "te", "zijn", "voor", "met", "die", "ook", "aan", "er", "maar", "om", "wordt"},
+}
+
+// stopwordLanguages maps each stopword to the languages using it
+var stopwordLanguages = func() map[string][]string {
+	index := make(map[string][]string)
+	for language, stopwords := range languageStopwords {
+		for _, word := range stopwords {
+			index[word] = append(index[word], language)
+		}
+	}
+	return index
+}()
+
+// IsStopword reports whether a lower-case word is one of the frequent
+// function words used to detect languages
+func IsStopword(word string) bool {
+	_, ok := stopwordLanguages[word]
+	return ok
+}
+
+// DetectLanguage guesses the primary language subtag of a text from its
+// script, and for Latin script from its stopwords. It returns "" when the
+// text is too short or no language clearly wins.
+func DetectLanguage(text string) string {
+	if language := detectScript(text); language != "latin" {
+		return language
+	}
+
+	counts := make(map[string]int)
+	words := 0
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		words++
+		for _, language := range stopwordLanguages[word] {
+			counts[language]++
+		}
+	}
+	if words < minDetectWords {
+		return ""
+	}
+
+	best, bestCount, runnerUp := "", 0, 0
+	for language, count := range counts {
+		if count > bestCount || (count == bestCount && language < best) {
+			best, bestCount, runnerUp = language, count, bestCount
+		} else if count > runnerUp {
+			runnerUp = count
+		}
+	}
+
+	// Stopwords make up a good share of running text, so too few of them
+	// or a close second means the text is in some other language
+	if bestCount*10 < words || bestCount*4 < runnerUp*5 {
+		return ""
+	}
+	return best
+}
+
+// detectScript names the language of the dominant non-Latin script of a
+// text, "latin" when Latin letters dominate, or "" when there are too few
+// letters to tell. Cyrillic is taken for Russian, its most common language.
+func detectScript(text string) string {
+	scripts := make(map[string]int)
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			scripts["latin"]++
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			scripts["ja"]++
+		case unicode.Is(unicode.Han, r):
+			scripts["han"]++
+		case unicode.Is(unicode.Hangul, r):
+			scripts["ko"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			scripts["ru"]++
+		case unicode.Is(unicode.Arabic, r):
+			scripts["ar"]++
+		case unicode.Is(unicode.Hebrew, r):
+			scripts["he"]++
+		case unicode.Is(unicode.Greek, r):
+			scripts["el"]++
+		case unicode.Is(unicode.Thai, r):
+			scripts["th"]++
+		case unicode.Is(unicode.Devanagari, r):
+			scripts["hi"]++
+		}
+	}
+	if letters < minDetectLetters {
+		return ""
+	}
+
+	// Japanese mixes kana with Han characters, which alone mean Chinese
+	if scripts["ja"] > 0 && scripts["ja"]*10 >= scripts["han"] {
+		scripts["ja"] += scripts["han"]
+	} else {
+		scripts["zh"] = scripts["han"] + scripts["ja"]
+		scripts["ja"] = 0
+	}
+	delete(scripts, "han")
+
+	best, bestCount := "", 0
+	for script, count := range scripts {
+		if count > bestCount || (count == bestCount && script < best) {
+			best, bestCount = script, count
+		}
+	}
+	if bestCount*2 < letters {
+		return ""
+	}
+	return best
+}
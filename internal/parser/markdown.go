@@ -0,0 +1,275 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// markdownHeadingPattern matches an ATX heading such as "## Install"
+	markdownHeadingPattern = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+
+	// markdownSetextPattern matches the underline turning the line above
+	// into a heading, "=" for level 1 and "-" for level 2
+	markdownSetextPattern = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+
+	// markdownFencePattern matches the opening of a code fence and its
+	// info string
+	markdownFencePattern = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})(.*)$")
+
+	// markdownLinkPattern matches inline links and images, as in
+	// [text](url "title") and ![alt](src)
+	markdownLinkPattern = regexp.MustCompile(`(!?)\[([^\]]*)\]\(\s*<?([^)\s>]*)>?(?:\s+["'(][^)]*["')])?\s*\)`)
+
+	// markdownReferencePattern matches a link reference definition, as in
+	// [id]: https://example.com "Title"
+	markdownReferencePattern = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:\s*<?(\S+?)>?(?:\s+.*)?$`)
+
+	// markdownAutolinkPattern matches an autolink such as <https://example.com>
+	markdownAutolinkPattern = regexp.MustCompile(`<(https?://[^>\s]+)>`)
+
+	// markdownCommentPattern matches an HTML comment on one line
+	markdownCommentPattern = regexp.MustCompile(`<!--.*?-->`)
+)
+
+// markdownFrontMatter holds the fields of a YAML front matter block used
+// for a document's properties
+type markdownFrontMatter struct {
+	Title       string `yaml:"title"`
+	Description string `yaml:"description"`
+	Summary     string `yaml:"summary"`
+	Author      any    `yaml:"author"`
+	Keywords    any    `yaml:"keywords"`
+	Tags        any    `yaml:"tags"`
+	Lang        string `yaml:"lang"`
+}
+
+// parseMarkdown parses a Markdown document. Headings keep their markers
+// and code fences their contents, so the text retains the document's
+// structure; link syntax is reduced to the link text, with the targets,
+// relative ones included, becoming links. The first level-1 heading, or
+// the front matter, gives the title.
+func (p *textParser) parseMarkdown(content io.Reader, baseURL *url.URL) (*ParsedContent, error) {
+	data, err := io.ReadAll(io.LimitReader(content, int64(p.maxText)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Markdown: %w", err)
+	}
+	source := strings.ReplaceAll(strings.ToValidUTF8(string(data), ""), "\r\n", "\n")
+	source = strings.TrimPrefix(source, "\ufeff")
+
+	frontMatter, source := splitFrontMatter(source)
+
+	var (
+		lines     []string
+		targets   []string
+		title     string
+		heading   string
+		fence     string // Marker of the open code fence, empty outside one
+		comment   bool   // Inside a multi-line HTML comment
+		paragraph = -1   // Index of the last paragraph line, which an underline turns into a heading
+	)
+	addHeading := func(level int, text string) {
+		text = strings.TrimSpace(markdownInline(text, nil))
+		if text == "" {
+			return
+		}
+		if heading == "" {
+			heading = text
+		}
+		if level == 1 && title == "" {
+			title = text
+		}
+		lines = append(lines, strings.Repeat("#", level)+" "+text)
+	}
+
+	for _, line := range strings.Split(source, "\n") {
+		if fence != "" {
+			lines = append(lines, line)
+			trimmed := strings.TrimSpace(line)
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+			continue
+		}
+		if comment {
+			if _, after, found := strings.Cut(line, "-->"); found {
+				comment = false
+				line = after
+			} else {
+				continue
+			}
+		}
+		line = markdownCommentPattern.ReplaceAllString(line, "")
+		if before, _, found := strings.Cut(line, "<!--"); found {
+			comment = true
+			line = before
+		}
+
+		if match := markdownFencePattern.FindStringSubmatch(line); match != nil && !isInlineCode(match) {
+			fence = match[1]
+			paragraph = -1
+			lines = append(lines, strings.TrimSpace(line))
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			paragraph = -1
+			lines = append(lines, "")
+			continue
+		}
+		if match := markdownSetextPattern.FindStringSubmatch(line); match != nil && paragraph >= 0 {
+			level := 1
+			if match[1][0] == '-' {
+				level = 2
+			}
+			text := lines[paragraph]
+			lines = lines[:paragraph]
+			addHeading(level, text)
+			paragraph = -1
+			continue
+		}
+		if match := markdownHeadingPattern.FindStringSubmatch(line); match != nil {
+			addHeading(len(match[1]), match[2])
+			paragraph = -1
+			continue
+		}
+		if match := markdownReferencePattern.FindStringSubmatch(line); match != nil {
+			targets = append(targets, match[1])
+			continue
+		}
+
+		lines = append(lines, markdownInline(line, &targets))
+		paragraph = len(lines) - 1
+	}
+
+	text := strings.TrimSpace(strings.Join(lines, "\n"))
+	parsed := p.document(text)
+	if heading != "" {
+		parsed.Heading = heading
+	}
+	parsed.Title = title
+
+	// Bare URLs in the prose become links too; those in code fences do not
+	links := textLinks(markdownProse(text), baseURL)
+	for _, target := range targets {
+		if target == "" || strings.HasPrefix(target, "#") {
+			continue
+		}
+		if link, err := url.Parse(target); err == nil && (link.Scheme == "" || link.Scheme == "http" || link.Scheme == "https") {
+			links = append(links, baseURL.ResolveReference(link))
+		}
+	}
+	parsed.Links = links
+
+	if frontMatter != nil {
+		if value := strings.TrimSpace(frontMatter.Title); value != "" {
+			parsed.Title = value
+		}
+		parsed.MetaDesc = strings.TrimSpace(frontMatter.Description)
+		if parsed.MetaDesc == "" {
+			parsed.MetaDesc = strings.TrimSpace(frontMatter.Summary)
+		}
+		parsed.Author = strings.Join(frontMatterList(frontMatter.Author), ", ")
+		parsed.Keywords = append(frontMatterList(frontMatter.Keywords), frontMatterList(frontMatter.Tags)...)
+		if frontMatter.Lang != "" {
+			parsed.Language = NormalizeLanguage(frontMatter.Lang)
+			parsed.Region = LanguageRegion(frontMatter.Lang)
+		}
+	}
+	return parsed, nil
+}
+
+// splitFrontMatter separates a leading YAML front matter block, fenced by
+// "---" lines, from a Markdown document. The front matter is nil when the
+// document has none or it is not valid YAML.
+func splitFrontMatter(source string) (*markdownFrontMatter, string) {
+	if !strings.HasPrefix(source, "---\n") {
+		return nil, source
+	}
+	block, rest, found := strings.Cut(source[len("---\n"):], "\n---")
+	if !found {
+		return nil, source
+	}
+	if newline := strings.IndexByte(rest, '\n'); newline >= 0 && strings.TrimSpace(rest[:newline]) == "" {
+		rest = rest[newline+1:]
+	} else if strings.TrimSpace(rest) != "" {
+		return nil, source
+	}
+
+	var frontMatter markdownFrontMatter
+	if err := yaml.Unmarshal([]byte(block), &frontMatter); err != nil {
+		return nil, source
+	}
+	return &frontMatter, rest
+}
+
+// frontMatterList returns the strings of a front matter field given either
+// as a list or as one comma separated string
+func frontMatterList(value any) []string {
+	switch value := value.(type) {
+	case string:
+		return documentKeywords(value)
+	case []any:
+		var list []string
+		for _, item := range value {
+			if item, ok := item.(string); ok && strings.TrimSpace(item) != "" {
+				list = append(list, strings.TrimSpace(item))
+			}
+		}
+		return list
+	case map[string]any:
+		// An author given with details, as in {name: ..., email: ...}
+		if name, ok := value["name"].(string); ok && strings.TrimSpace(name) != "" {
+			return []string{strings.TrimSpace(name)}
+		}
+	}
+	return nil
+}
+
+// markdownInline reduces the inline links and images of a line to their
+// text, adding their targets to targets when it is not nil
+func markdownInline(line string, targets *[]string) string {
+	line = markdownLinkPattern.ReplaceAllStringFunc(line, func(match string) string {
+		parts := markdownLinkPattern.FindStringSubmatch(match)
+		if targets != nil && parts[1] == "" {
+			*targets = append(*targets, parts[3])
+		}
+		return parts[2]
+	})
+	return markdownAutolinkPattern.ReplaceAllString(line, "$1")
+}
+
+// isInlineCode reports whether a line matching markdownFencePattern is
+// inline code rather than a fence, as a backtick fence's info string cannot
+// contain backticks
+func isInlineCode(match []string) bool {
+	return match[1][0] == '`' && strings.Contains(match[2], "`")
+}
+
+// markdownProse returns the text outside the code fences of a parsed
+// Markdown document
+func markdownProse(text string) string {
+	var prose strings.Builder
+	fence := ""
+	for _, line := range strings.Split(text, "\n") {
+		if match := markdownFencePattern.FindStringSubmatch(line); match != nil && !isInlineCode(match) {
+			switch {
+			case fence == "":
+				fence = match[1]
+				continue
+			case strings.HasPrefix(match[1], fence) && strings.TrimSpace(match[2]) == "":
+				fence = ""
+				continue
+			}
+		}
+		if fence == "" {
+			prose.WriteString(line)
+			prose.WriteString("\n")
+		}
+	}
+	return prose.String()
+}
@@ -0,0 +1,562 @@
+package parser
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Media types of the Office Open XML documents
+const (
+	docxMediaType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	pptxMediaType = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+	xlsxMediaType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// maxOfficePart caps the decompressed size of a part of an Office document
+// read, so a small archive cannot expand without bound
+const maxOfficePart = 64 * 1024 * 1024
+
+// hyperlinkRelationship ends the type of relationships to hyperlinks
+const hyperlinkRelationship = "/hyperlink"
+
+// officePackage is the ZIP archive of an Office Open XML document
+type officePackage struct {
+	parts map[string]*zip.File
+}
+
+// officeRelationship links a part of a package to another part or, with
+// TargetMode External, to a URL
+type officeRelationship struct {
+	ID         string `xml:"Id,attr"`
+	Type       string `xml:"Type,attr"`
+	Target     string `xml:"Target,attr"`
+	TargetMode string `xml:"TargetMode,attr"`
+}
+
+// officeCoreProperties are the document properties in docProps/core.xml
+type officeCoreProperties struct {
+	Title       string `xml:"title"`
+	Subject     string `xml:"subject"`
+	Creator     string `xml:"creator"`
+	Description string `xml:"description"`
+	Keywords    string `xml:"keywords"`
+	Language    string `xml:"language"`
+	Modified    string `xml:"modified"`
+}
+
+// parseOffice parses a Word, PowerPoint or Excel document in Office Open
+// XML format, telling them apart by their parts. Headings, slide titles
+// and sheet names stay on lines of their own, the first being the heading,
+// and the core properties give the title, author and modification time.
+func (p *textParser) parseOffice(content io.Reader, baseURL *url.URL) (*ParsedContent, error) {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Office document: %w", err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Office document: %w", err)
+	}
+	pkg := &officePackage{parts: make(map[string]*zip.File)}
+	for _, file := range archive.File {
+		pkg.parts[file.Name] = file
+	}
+
+	text := &officeText{maxText: p.maxText}
+	var links []string
+	switch {
+	case pkg.has("word/document.xml"):
+		links, err = pkg.wordText(text)
+	case pkg.has("ppt/presentation.xml"):
+		links, err = pkg.slidesText(text)
+	case pkg.has("xl/workbook.xml"):
+		err = pkg.sheetsText(text)
+	default:
+		return nil, fmt.Errorf("unsupported content type: archive is not a Word, PowerPoint or Excel document")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := p.document(strings.TrimSpace(text.String()))
+	if len(text.headings) > 0 {
+		parsed.Heading = text.headings[0]
+	}
+	parsed.Links = textLinks(parsed.Text, baseURL)
+	for _, link := range links {
+		if parsedLink, err := url.Parse(link); err == nil {
+			parsed.Links = append(parsed.Links, baseURL.ResolveReference(parsedLink))
+		}
+	}
+
+	var properties officeCoreProperties
+	if err := pkg.decode("docProps/core.xml", &properties); err != nil {
+		return nil, err
+	}
+	parsed.Title = strings.TrimSpace(properties.Title)
+	parsed.Author = strings.TrimSpace(properties.Creator)
+	parsed.MetaDesc = strings.TrimSpace(properties.Description)
+	if parsed.MetaDesc == "" {
+		parsed.MetaDesc = strings.TrimSpace(properties.Subject)
+	}
+	parsed.Keywords = documentKeywords(properties.Keywords)
+	if properties.Language != "" {
+		parsed.Language = NormalizeLanguage(properties.Language)
+		parsed.Region = LanguageRegion(properties.Language)
+	}
+	if modified, err := time.Parse(time.RFC3339, strings.TrimSpace(properties.Modified)); err == nil {
+		parsed.Modified = modified
+	}
+	return parsed, nil
+}
+
+// officeText collects the paragraphs of a document up to maxText bytes,
+// noting those that are headings
+type officeText struct {
+	strings.Builder
+	maxText  int
+	headings []string
+}
+
+// paragraph adds a paragraph of text, reporting false once the text is full
+func (t *officeText) paragraph(paragraph string, heading bool) bool {
+	paragraph = strings.TrimSpace(paragraph)
+	if paragraph == "" {
+		return t.Len() < t.maxText
+	}
+	if heading {
+		t.headings = append(t.headings, paragraph)
+		if t.Len() > 0 {
+			t.WriteString("\n")
+		}
+	}
+	t.WriteString(paragraph)
+	t.WriteString("\n")
+	return t.Len() < t.maxText
+}
+
+// has reports whether the package holds a part
+func (pkg *officePackage) has(name string) bool {
+	_, ok := pkg.parts[name]
+	return ok
+}
+
+// open returns a reader of a part's decompressed content, nil when the
+// package has no such part
+func (pkg *officePackage) open(name string) (io.ReadCloser, error) {
+	part, ok := pkg.parts[name]
+	if !ok {
+		return nil, nil
+	}
+	reader, err := part.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s of Office document: %w", name, err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(reader, maxOfficePart), reader}, nil
+}
+
+// decode unmarshals a part's XML into v, leaving v unchanged when the
+// package has no such part
+func (pkg *officePackage) decode(name string, v any) error {
+	reader, err := pkg.open(name)
+	if err != nil || reader == nil {
+		return err
+	}
+	defer reader.Close()
+
+	if err := xml.NewDecoder(reader).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse %s of Office document: %w", name, err)
+	}
+	return nil
+}
+
+// relationships returns the relationships of a part by ID
+func (pkg *officePackage) relationships(name string) (map[string]officeRelationship, error) {
+	var rels struct {
+		Relationships []officeRelationship `xml:"Relationship"`
+	}
+	relsName := path.Join(path.Dir(name), "_rels", path.Base(name)+".rels")
+	if err := pkg.decode(relsName, &rels); err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]officeRelationship, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		byID[rel.ID] = rel
+	}
+	return byID, nil
+}
+
+// hyperlinks returns the URLs a part links to
+func (pkg *officePackage) hyperlinks(name string) ([]string, error) {
+	rels, err := pkg.relationships(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var links []string
+	for _, rel := range rels {
+		if strings.HasSuffix(rel.Type, hyperlinkRelationship) && rel.TargetMode == "External" {
+			links = append(links, rel.Target)
+		}
+	}
+	sort.Strings(links)
+	return links, nil
+}
+
+// relationshipTarget returns the name of the part a relationship of a
+// source part points to
+func relationshipTarget(source string, rel officeRelationship) string {
+	if strings.HasPrefix(rel.Target, "/") {
+		return strings.TrimPrefix(rel.Target, "/")
+	}
+	return path.Join(path.Dir(source), rel.Target)
+}
+
+// wordText extracts the paragraphs of a Word document, headings being the
+// paragraphs in a heading or title style or at an outline level
+func (pkg *officePackage) wordText(text *officeText) ([]string, error) {
+	const name = "word/document.xml"
+	reader, err := pkg.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	decoder := xml.NewDecoder(reader)
+	var paragraph strings.Builder
+	heading, inText := false, false
+	flush := func() bool {
+		full := !text.paragraph(paragraph.String(), heading)
+		paragraph.Reset()
+		return full
+	}
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s of Office document: %w", name, err)
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			switch token.Name.Local {
+			case "p":
+				// Paragraphs nest in text boxes
+				if flush() {
+					return pkg.hyperlinks(name)
+				}
+				heading = false
+			case "pStyle":
+				heading = isHeadingStyle(xmlAttr(token, "val"))
+			case "outlineLvl":
+				heading = true
+			case "t":
+				inText = true
+			case "tab":
+				paragraph.WriteString("\t")
+			case "br", "cr":
+				paragraph.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch token.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				if flush() {
+					return pkg.hyperlinks(name)
+				}
+			}
+		case xml.CharData:
+			if inText {
+				paragraph.Write(token)
+			}
+		}
+	}
+	return pkg.hyperlinks(name)
+}
+
+// isHeadingStyle reports whether a Word paragraph style is a heading
+func isHeadingStyle(style string) bool {
+	style = strings.ToLower(strings.ReplaceAll(style, " ", ""))
+	return strings.HasPrefix(style, "heading") || style == "title" || style == "subtitle"
+}
+
+// slidesText extracts the text of a presentation's slides in order, each
+// slide's title being a heading
+func (pkg *officePackage) slidesText(text *officeText) ([]string, error) {
+	const name = "ppt/presentation.xml"
+	var presentation struct {
+		Slides []struct {
+			ID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sldIdLst>sldId"`
+	}
+	if err := pkg.decode(name, &presentation); err != nil {
+		return nil, err
+	}
+	rels, err := pkg.relationships(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var links []string
+	for _, slide := range presentation.Slides {
+		rel, ok := rels[slide.ID]
+		if !ok {
+			continue
+		}
+		slideName := relationshipTarget(name, rel)
+		full, err := pkg.slideText(slideName, text)
+		if err != nil {
+			return nil, err
+		}
+		slideLinks, err := pkg.hyperlinks(slideName)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, slideLinks...)
+		if full {
+			break
+		}
+	}
+	return links, nil
+}
+
+// slideText extracts the paragraphs of a slide, reporting true once the
+// text is full
+func (pkg *officePackage) slideText(name string, text *officeText) (bool, error) {
+	reader, err := pkg.open(name)
+	if err != nil || reader == nil {
+		return false, err
+	}
+	defer reader.Close()
+
+	decoder := xml.NewDecoder(reader)
+	var paragraph strings.Builder
+	title, inText := false, false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to parse %s of Office document: %w", name, err)
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			switch token.Name.Local {
+			case "ph":
+				placeholder := xmlAttr(token, "type")
+				title = placeholder == "title" || placeholder == "ctrTitle"
+			case "t":
+				inText = true
+			case "br":
+				paragraph.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch token.Name.Local {
+			case "sp":
+				title = false
+			case "t":
+				inText = false
+			case "p":
+				full := !text.paragraph(paragraph.String(), title)
+				paragraph.Reset()
+				if full {
+					return true, nil
+				}
+			}
+		case xml.CharData:
+			if inText {
+				paragraph.Write(token)
+			}
+		}
+	}
+}
+
+// sheetsText extracts the cells of a workbook's sheets in order, a row to
+// a line with its cells separated by tabs, each sheet's name being a
+// heading
+func (pkg *officePackage) sheetsText(text *officeText) error {
+	const name = "xl/workbook.xml"
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := pkg.decode(name, &workbook); err != nil {
+		return err
+	}
+	rels, err := pkg.relationships(name)
+	if err != nil {
+		return err
+	}
+	shared, err := pkg.sharedStrings()
+	if err != nil {
+		return err
+	}
+
+	for _, sheet := range workbook.Sheets {
+		rel, ok := rels[sheet.ID]
+		if !ok {
+			continue
+		}
+		if !text.paragraph(sheet.Name, true) {
+			return nil
+		}
+		full, err := pkg.sheetText(relationshipTarget(name, rel), shared, text)
+		if err != nil || full {
+			return err
+		}
+	}
+	return nil
+}
+
+// sharedStrings returns the strings that cells of a workbook refer to by
+// index
+func (pkg *officePackage) sharedStrings() ([]string, error) {
+	const name = "xl/sharedStrings.xml"
+	reader, err := pkg.open(name)
+	if err != nil || reader == nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	decoder := xml.NewDecoder(reader)
+	var strs []string
+	var item strings.Builder
+	inText, phonetic := false, 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return strs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s of Office document: %w", name, err)
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			switch token.Name.Local {
+			case "si":
+				item.Reset()
+			case "t":
+				inText = true
+			case "rPh":
+				// Phonetic guides repeat the text they annotate
+				phonetic++
+			}
+		case xml.EndElement:
+			switch token.Name.Local {
+			case "si":
+				strs = append(strs, item.String())
+			case "t":
+				inText = false
+			case "rPh":
+				phonetic--
+			}
+		case xml.CharData:
+			if inText && phonetic == 0 {
+				item.Write(token)
+			}
+		}
+	}
+}
+
+// sheetText extracts the rows of a worksheet, reporting true once the text
+// is full
+func (pkg *officePackage) sheetText(name string, shared []string, text *officeText) (bool, error) {
+	reader, err := pkg.open(name)
+	if err != nil || reader == nil {
+		return false, err
+	}
+	defer reader.Close()
+
+	decoder := xml.NewDecoder(reader)
+	var cells []string
+	var value strings.Builder
+	cellType, inValue := "", false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to parse %s of Office document: %w", name, err)
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			switch token.Name.Local {
+			case "c":
+				cellType = xmlAttr(token, "t")
+				value.Reset()
+			case "v", "t":
+				inValue = true
+			}
+		case xml.EndElement:
+			switch token.Name.Local {
+			case "v", "t":
+				inValue = false
+			case "c":
+				if cell := cellText(cellType, value.String(), shared); cell != "" {
+					cells = append(cells, cell)
+				}
+			case "row":
+				full := !text.paragraph(strings.Join(cells, "\t"), false)
+				cells = cells[:0]
+				if full {
+					return true, nil
+				}
+			}
+		case xml.CharData:
+			if inValue {
+				value.Write(token)
+			}
+		}
+	}
+}
+
+// cellText returns the text of a cell of the given type and raw value
+func cellText(cellType, value string, shared []string) string {
+	switch cellType {
+	case "s":
+		if i, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && i >= 0 && i < len(shared) {
+			return strings.TrimSpace(shared[i])
+		}
+		return ""
+	case "b":
+		if value == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	default:
+		return strings.TrimSpace(value)
+	}
+}
+
+// xmlAttr returns the value of an element's attribute by local name
+func xmlAttr(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
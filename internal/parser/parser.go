@@ -1,11 +1,14 @@
 package parser
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"fmt"
 	"io"
 	"net/url"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"golang.org/x/net/html"
 )
@@ -22,10 +25,58 @@ type Parser interface {
 // ParsedContent represents parsed web content
 type ParsedContent struct {
 	Title       string
-	Text        string
+	Heading     string // Text of the first <h1>-<h6>, a fallback title
+	Text        string // Readable text, paragraphs, headings and list items on lines of their own
 	MetaDesc    string
+	Keywords    []string // From <meta name="keywords">
+	Language    string   // Primary language subtag declared by the page or detected, e.g. "en"
+	Region      string   // Region subtag declared with the language, e.g. "gb" for en-GB
 	Links       []*url.URL
+	Canonical   *url.URL // Declared by <link rel="canonical">, nil if absent
 	ContentHash string
+
+	// LanguageVariants are the translations of the page declared by <link
+	// rel="alternate" hreflang>, by lower-case hreflang such as "de",
+	// "pt-br" or "x-default"; nil if it declares none
+	LanguageVariants map[string]*url.URL
+
+	// Author, Published and Modified are the document properties of PDF
+	// and Office documents. HTML pages declare them by JSON-LD, meta tags or
+	// bylines and <time> elements, in that order of precedence; streamed
+	// pages skip bylines. Empty when unknown.
+	Author    string
+	Published time.Time
+	Modified  time.Time
+
+	// NofollowLinks are the Links of anchors marked rel="nofollow" or
+	// rel="ugc", which the site asks crawlers not to follow
+	NofollowLinks []*url.URL
+
+	// AMP is set for AMP documents, marked by <html amp> or <html ⚡>
+	AMP bool
+
+	// Tables are the page's data tables, whose text is rendered row by row
+	// against the column headers rather than as loose cells. Pages streamed
+	// above the StreamingThreshold keep their cells as plain text.
+	Tables []*Table
+
+	// CodeBlocks are the page's <pre> elements and <code> elements spanning
+	// several lines, kept verbatim. Their text is fenced with CodeFence on
+	// lines of its own, so line breaks and indentation survive. Streamed
+	// pages keep only <pre> blocks.
+	CodeBlocks []*CodeBlock
+
+	// Refresh is where a page sends the browser straight away, by a meta
+	// refresh or, for a page with next to no text, a script; nil if nowhere
+	Refresh *url.URL
+
+	// scriptRedirect is the target of the first script doing nothing but
+	// redirecting
+	scriptRedirect *url.URL
+
+	// declared holds the author and dates each source of a page declares,
+	// until they are resolved by precedence
+	declared [authorshipSources]authorship
 }
 
 // URLNormalizer handles URL canonicalization
@@ -37,36 +88,129 @@ type URLNormalizer interface {
 	IsValid(url *url.URL) bool
 }
 
+// Config holds parser configuration
+type Config struct {
+	// StreamingThreshold is the page size in bytes above which the
+	// streaming tokenizer is used instead of building the full node tree
+	StreamingThreshold int
+	// MaxElementText caps the text kept from a single element when streaming
+	MaxElementText int
+	// MaxTextSize caps the total text kept from a page when streaming
+	MaxTextSize int
+
+	// Rules override the generic extraction on the sites they name. Pages
+	// a rule applies to are always parsed into a full node tree.
+	Rules []ExtractionRule
+
+	// StripParams are the query parameters URL normalization drops, so
+	// URLs differing only in tracking parameters are one page. Names match
+	// case-insensitively and a trailing * matches a prefix, as in "utm_*".
+	// Nil means DefaultStripParams; an empty list keeps every parameter.
+	StripParams []string
+
+	// DisablePDF leaves PDF documents unparsed and links to .pdf files
+	// out of the crawl
+	DisablePDF bool
+
+	// DisableOffice does the same for Word, PowerPoint and Excel documents
+	// (.docx, .pptx and .xlsx)
+	DisableOffice bool
+
+	// DetectLanguage sets the Language of documents that declare none to
+	// the language their text is in, when DetectLanguage can tell
+	DetectLanguage bool
+}
+
+// DefaultStripParams are the tracking parameters dropped from URLs unless
+// configured otherwise
+var DefaultStripParams = []string{"utm_*", "gclid", "fbclid", "msclkid", "mc_cid", "mc_eid", "ref"}
+
 // htmlParser implements the Parser interface
-type htmlParser struct{}
+type htmlParser struct {
+	config Config
+	rules  []*compiledRule
+}
 
 // urlNormalizer implements the URLNormalizer interface
-type urlNormalizer struct{}
+type urlNormalizer struct {
+	stripParams []string
+	skipPDF     bool
+	skipOffice  bool
+}
 
 // NewHTMLParser creates a new HTML parser
-func NewHTMLParser() Parser {
-	return &htmlParser{}
+func NewHTMLParser(config Config) Parser {
+	if config.StreamingThreshold == 0 {
+		config.StreamingThreshold = 512 * 1024 // 512KB
+	}
+	if config.MaxElementText == 0 {
+		config.MaxElementText = 64 * 1024 // 64KB
+	}
+	if config.MaxTextSize == 0 {
+		config.MaxTextSize = 2 * 1024 * 1024 // 2MB
+	}
+
+	return &htmlParser{
+		config: config,
+		rules:  compileRules(config.Rules),
+	}
 }
 
-// NewURLNormalizer creates a new URL normalizer
-func NewURLNormalizer() URLNormalizer {
-	return &urlNormalizer{}
+// NewURLNormalizer creates a new URL normalizer dropping the configured
+// tracking parameters, and rejecting links to PDF and Office documents when
+// their parsing is disabled
+func NewURLNormalizer(config Config) URLNormalizer {
+	stripParams := config.StripParams
+	if stripParams == nil {
+		stripParams = DefaultStripParams
+	}
+
+	n := &urlNormalizer{skipPDF: config.DisablePDF, skipOffice: config.DisableOffice}
+	for _, param := range stripParams {
+		if param = strings.ToLower(strings.TrimSpace(param)); param != "" {
+			n.stripParams = append(n.stripParams, param)
+		}
+	}
+	return n
 }
 
 // ParseHTML parses HTML content and extracts structured data
 func (p *htmlParser) ParseHTML(content io.Reader, baseURL *url.URL) (*ParsedContent, error) {
-	doc, err := html.Parse(content)
+	parsed := &ParsedContent{
+		Text:  "",
+		Links: []*url.URL{},
+	}
+
+	// Large pages are tokenized as a stream to cap per-page memory, unless
+	// an extraction rule needs the node tree to select from
+	rule := p.ruleFor(baseURL)
+	reader, large, err := p.measure(content)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse HTML: %w", err)
 	}
+	if large && rule == nil {
+		if err := p.streamData(reader, parsed, baseURL); err != nil {
+			return nil, fmt.Errorf("failed to parse HTML: %w", err)
+		}
+	} else {
+		doc, err := html.Parse(reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse HTML: %w", err)
+		}
 
-	parsed := &ParsedContent{
-		Text:  "",
-		Links: []*url.URL{},
+		// Extract title, meta description, text, and links
+		var text textWriter
+		p.extractData(doc, parsed, &text, baseURL)
+		parsed.Text = text.String()
+		if rule != nil {
+			p.applyRule(doc, rule, parsed)
+		}
 	}
 
-	// Extract title, meta description, text, and links
-	p.extractData(doc, parsed, baseURL)
+	if parsed.Refresh == nil && parsed.scriptRedirect != nil && len(strings.TrimSpace(parsed.Text)) <= maxInterstitialText {
+		parsed.Refresh = parsed.scriptRedirect
+	}
+	parsed.resolveAuthorship()
 
 	// Calculate content hash
 	hash := sha256.Sum256([]byte(parsed.Text))
@@ -75,6 +219,23 @@ func (p *htmlParser) ParseHTML(content io.Reader, baseURL *url.URL) (*ParsedCont
 	return parsed, nil
 }
 
+// measure reports whether content is over the StreamingThreshold, from its
+// size when it is known. Otherwise up to the threshold is read ahead into a
+// buffer that grows with the page, so small pages buffer little; the
+// returned reader yields the whole content again.
+func (p *htmlParser) measure(content io.Reader) (io.Reader, bool, error) {
+	if sized, ok := content.(interface{ Len() int }); ok {
+		return content, sized.Len() > p.config.StreamingThreshold, nil
+	}
+
+	var head bytes.Buffer
+	n, err := io.CopyN(&head, content, int64(p.config.StreamingThreshold)+1)
+	if err != nil && err != io.EOF {
+		return nil, false, err
+	}
+	return io.MultiReader(&head, content), n > int64(p.config.StreamingThreshold), nil
+}
+
 // ParseText extracts readable text from HTML
 func (p *htmlParser) ParseText(content io.Reader) (string, error) {
 	doc, err := html.Parse(content)
@@ -87,62 +248,375 @@ func (p *htmlParser) ParseText(content io.Reader) (string, error) {
 	return strings.TrimSpace(text.String()), nil
 }
 
-// extractData extracts title, meta description, text, and links from HTML node
-func (p *htmlParser) extractData(n *html.Node, parsed *ParsedContent, baseURL *url.URL) {
+// extractData extracts title, meta description, text, and links from HTML
+// node, writing the text of block-level elements on lines of their own
+func (p *htmlParser) extractData(n *html.Node, parsed *ParsedContent, text *textWriter, baseURL *url.URL) {
 	if n.Type == html.ElementNode {
-		// Skip script and style elements
+		// Skip script and style elements, noting scripts that redirect
+		if n.Data == "script" && parsed.scriptRedirect == nil && n.FirstChild != nil {
+			parsed.scriptRedirect = parseScriptRedirect(n.FirstChild.Data, baseURL)
+		}
+		if n.Data == "script" && n.FirstChild != nil && isJSONLD(n.Attr) {
+			parsed.jsonLDAuthorship(n.FirstChild.Data)
+		}
 		if n.Data == "script" || n.Data == "style" {
 			return
 		}
+		p.extractByline(n, parsed)
 
 		switch n.Data {
+		case "html":
+			p.extractLanguage(n, parsed)
+			parsed.AMP = isAMPDocument(n)
 		case "title":
 			if n.FirstChild != nil {
 				parsed.Title = strings.TrimSpace(n.FirstChild.Data)
 			}
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			if parsed.Heading == "" {
+				var heading strings.Builder
+				p.extractText(n, &heading)
+				parsed.Heading = strings.Join(strings.Fields(heading.String()), " ")
+			}
 		case "meta":
-			p.extractMeta(n, parsed)
+			p.extractMeta(n, parsed, baseURL)
+		case "link":
+			p.extractHeadLink(n, parsed, baseURL)
 		case "a":
 			p.extractLink(n, parsed, baseURL)
+		case "time":
+			parsed.timeAuthorship(n.Attr)
+		case "table":
+			if table := p.extractTable(n); table != nil {
+				parsed.Tables = append(parsed.Tables, table)
+				text.WriteBlock(table.Text())
+				p.extractNestedLinks(n, parsed, baseURL)
+				return
+			}
+		case "pre":
+			if block := p.extractCode(n); block != nil {
+				parsed.CodeBlocks = append(parsed.CodeBlocks, block)
+				text.WriteBlock(block.Text())
+			}
+			p.extractNestedLinks(n, parsed, baseURL)
+			return
+		case "code":
+			if isCodeBlock(n) {
+				if block := p.extractCode(n); block != nil {
+					parsed.CodeBlocks = append(parsed.CodeBlocks, block)
+					text.WriteBlock(block.Text())
+				}
+				p.extractNestedLinks(n, parsed, baseURL)
+				return
+			}
 		}
 	} else if n.Type == html.TextNode {
 		// Extract text content
 		content := strings.TrimSpace(n.Data)
 		if content != "" {
-			parsed.Text += content + " "
+			text.WriteText(content)
 		}
 	}
 
 	// Recursively process child nodes
+	block := n.Type == html.ElementNode && blockElements[n.Data]
+	if block {
+		text.Break()
+	}
 	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		p.extractData(c, parsed, baseURL)
+		p.extractData(c, parsed, text, baseURL)
+	}
+	if block {
+		text.Break()
+	}
+}
+
+// streamData extracts the same data as extractData using the streaming
+// tokenizer, without building a node tree
+func (p *htmlParser) streamData(content io.Reader, parsed *ParsedContent, baseURL *url.URL) error {
+	tokenizer := html.NewTokenizer(content)
+
+	var text textWriter
+	skipDepth := 0   // Nesting depth inside script/style elements
+	inTitle := false // Whether the next text token is the page title
+	elementText := 0 // Text bytes kept since the last element boundary
+
+	// Text of the first heading, collected until its end tag
+	var heading strings.Builder
+	headingTag := ""
+
+	// Raw text of the <pre> element being read and its language
+	var code strings.Builder
+	codeDepth := 0
+	language := ""
+
+	// Text of the JSON-LD script being read
+	var jsonLD strings.Builder
+	inJSONLD := false
+
+	for {
+		tokenType := tokenizer.Next()
+		switch tokenType {
+		case html.ErrorToken:
+			if tokenizer.Err() == io.EOF {
+				parsed.Text = text.String()
+				return nil
+			}
+			return tokenizer.Err()
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			elementText = 0
+			if codeDepth > 0 && language == "" {
+				language = declaredLanguage(token.Attr)
+			}
+			if blockElements[token.Data] {
+				text.Break()
+			}
+			switch token.Data {
+			case "html":
+				node := &html.Node{Data: token.Data, Attr: token.Attr}
+				p.extractLanguage(node, parsed)
+				parsed.AMP = isAMPDocument(node)
+			case "script", "style":
+				if tokenType == html.StartTagToken {
+					skipDepth++
+					if token.Data == "script" && isJSONLD(token.Attr) {
+						jsonLD.Reset()
+						inJSONLD = true
+					}
+				}
+			case "title":
+				inTitle = tokenType == html.StartTagToken
+			case "h1", "h2", "h3", "h4", "h5", "h6":
+				if parsed.Heading == "" && headingTag == "" && tokenType == html.StartTagToken {
+					headingTag = token.Data
+				}
+			case "meta":
+				p.extractMeta(&html.Node{Data: token.Data, Attr: token.Attr}, parsed, baseURL)
+			case "link":
+				p.extractHeadLink(&html.Node{Data: token.Data, Attr: token.Attr}, parsed, baseURL)
+			case "a":
+				p.extractLink(&html.Node{Data: token.Data, Attr: token.Attr}, parsed, baseURL)
+			case "time":
+				parsed.timeAuthorship(token.Attr)
+			case "pre":
+				if tokenType == html.StartTagToken {
+					if codeDepth == 0 {
+						code.Reset()
+						language = declaredLanguage(token.Attr)
+					}
+					codeDepth++
+				}
+			case "br":
+				if codeDepth > 0 {
+					code.WriteString("\n")
+				}
+			}
+
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			elementText = 0
+			if blockElements[string(name)] {
+				text.Break()
+			}
+			switch string(name) {
+			case "script", "style":
+				if skipDepth > 0 {
+					skipDepth--
+				}
+				if inJSONLD {
+					parsed.jsonLDAuthorship(jsonLD.String())
+					inJSONLD = false
+				}
+			case "title":
+				inTitle = false
+			case headingTag:
+				parsed.Heading = strings.Join(strings.Fields(heading.String()), " ")
+				headingTag = ""
+			case "pre":
+				if codeDepth == 0 {
+					continue
+				}
+				if codeDepth--; codeDepth > 0 {
+					continue
+				}
+				block := p.newCodeBlock(language, code.String())
+				if block != nil && text.Len()+len(block.Text()) <= p.config.MaxTextSize {
+					parsed.CodeBlocks = append(parsed.CodeBlocks, block)
+					text.WriteBlock(block.Text())
+				}
+			}
+
+		case html.TextToken:
+			if inJSONLD && jsonLD.Len() < p.config.MaxElementText {
+				jsonLD.Write(tokenizer.Text())
+			}
+			if skipDepth > 0 {
+				continue
+			}
+			if codeDepth > 0 {
+				if code.Len() < p.config.MaxElementText {
+					code.Write(tokenizer.Text())
+				}
+				continue
+			}
+
+			data := strings.TrimSpace(string(tokenizer.Text()))
+			if data == "" {
+				continue
+			}
+
+			if inTitle && parsed.Title == "" {
+				parsed.Title = data
+			}
+			if headingTag != "" {
+				heading.WriteString(data)
+				heading.WriteString(" ")
+			}
+
+			// Enforce per-element and per-page text limits
+			if remaining := p.config.MaxElementText - elementText; len(data) > remaining {
+				data = truncateUTF8(data, remaining)
+			}
+			if remaining := p.config.MaxTextSize - text.Len(); len(data) > remaining {
+				data = truncateUTF8(data, remaining)
+			}
+			if data == "" {
+				continue
+			}
+
+			elementText += len(data)
+			text.WriteText(data)
+		}
+	}
+}
+
+// truncateUTF8 shortens s to at most n bytes without splitting a rune
+func truncateUTF8(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
 	}
+	return s[:n]
 }
 
 // extractMeta extracts meta tags
-func (p *htmlParser) extractMeta(n *html.Node, parsed *ParsedContent) {
-	var name, content string
+func (p *htmlParser) extractMeta(n *html.Node, parsed *ParsedContent, baseURL *url.URL) {
+	var name, property, itemprop, httpEquiv, content string
 	for _, attr := range n.Attr {
 		switch attr.Key {
 		case "name":
 			name = attr.Val
+		case "property":
+			property = attr.Val
+		case "itemprop":
+			itemprop = attr.Val
+		case "http-equiv":
+			httpEquiv = attr.Val
 		case "content":
 			content = attr.Val
 		}
 	}
 
+	// Open Graph tags use property and microdata itemprop where other tags
+	// use name
+	for _, key := range []string{name, property, itemprop} {
+		if key != "" && content != "" {
+			parsed.metaAuthorship(key, content)
+		}
+	}
+	if strings.EqualFold(httpEquiv, "last-modified") && content != "" {
+		parsed.metaAuthorship(httpEquiv, content)
+	}
+
 	if name == "description" && content != "" {
 		parsed.MetaDesc = content
 	}
+	if name == "keywords" && content != "" {
+		parsed.Keywords = nil
+		for _, keyword := range strings.Split(content, ",") {
+			if keyword = strings.TrimSpace(keyword); keyword != "" {
+				parsed.Keywords = append(parsed.Keywords, keyword)
+			}
+		}
+	}
+
+	// The <html lang> attribute takes precedence over Content-Language
+	if strings.EqualFold(httpEquiv, "content-language") && parsed.Language == "" {
+		parsed.Language = NormalizeLanguage(content)
+		parsed.Region = LanguageRegion(content)
+	}
+
+	if strings.EqualFold(httpEquiv, "refresh") && parsed.Refresh == nil {
+		parsed.Refresh = parseRefresh(content, baseURL)
+	}
+}
+
+// extractLanguage reads the lang attribute of the <html> element
+func (p *htmlParser) extractLanguage(n *html.Node, parsed *ParsedContent) {
+	for _, attr := range n.Attr {
+		if attr.Key == "lang" {
+			if language := NormalizeLanguage(attr.Val); language != "" {
+				parsed.Language = language
+				parsed.Region = LanguageRegion(attr.Val)
+			}
+			return
+		}
+	}
+}
+
+// isAMPDocument reports whether an <html> element marks an AMP document
+func isAMPDocument(n *html.Node) bool {
+	for _, attr := range n.Attr {
+		if attr.Key == "amp" || attr.Key == "⚡" {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeLanguage reduces a language tag such as "en-US" or "pt_BR" to
+// its lower-case primary subtag, taking the first of a comma-separated list
+func NormalizeLanguage(tag string) string {
+	tag, _, _ = strings.Cut(tag, ",")
+	tag = strings.TrimSpace(tag)
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	return strings.ToLower(tag)
+}
+
+// LanguageRegion returns the lower-case region subtag of a language tag,
+// "br" for "pt-BR" or "tw" for "zh-Hant-TW", or "" when it names none
+func LanguageRegion(tag string) string {
+	tag, _, _ = strings.Cut(tag, ",")
+	subtags := strings.FieldsFunc(strings.TrimSpace(tag), func(r rune) bool {
+		return r == '-' || r == '_'
+	})
+	for _, subtag := range subtags[min(1, len(subtags)):] {
+		if len(subtag) == 2 {
+			return strings.ToLower(subtag)
+		}
+	}
+	return ""
 }
 
 // extractLink extracts links from anchor tags
 func (p *htmlParser) extractLink(n *html.Node, parsed *ParsedContent, baseURL *url.URL) {
 	var href string
+	nofollow := false
 	for _, attr := range n.Attr {
-		if attr.Key == "href" {
+		switch attr.Key {
+		case "href":
 			href = attr.Val
-			break
+		case "rel":
+			nofollow = isNofollow(attr.Val)
 		}
 	}
 
@@ -150,7 +624,62 @@ func (p *htmlParser) extractLink(n *html.Node, parsed *ParsedContent, baseURL *u
 		if linkURL, err := url.Parse(href); err == nil {
 			if resolvedURL := baseURL.ResolveReference(linkURL); resolvedURL != nil {
 				parsed.Links = append(parsed.Links, resolvedURL)
+				if nofollow {
+					parsed.NofollowLinks = append(parsed.NofollowLinks, resolvedURL)
+				}
+			}
+		}
+	}
+}
+
+// isNofollow reports whether a rel attribute asks crawlers not to follow a
+// link: nofollow, or ugc for user-generated content such as comments
+func isNofollow(rel string) bool {
+	for _, value := range strings.Fields(rel) {
+		if strings.EqualFold(value, "nofollow") || strings.EqualFold(value, "ugc") {
+			return true
+		}
+	}
+	return false
+}
+
+// extractHeadLink reads the URL of a <link rel="canonical"> element, or of
+// a <link rel="alternate" hreflang> naming a language variant. The first
+// link wins for the canonical URL and for each hreflang, as browsers and
+// search engines treat them.
+func (p *htmlParser) extractHeadLink(n *html.Node, parsed *ParsedContent, baseURL *url.URL) {
+	var rel, href, hreflang string
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "rel":
+			rel = strings.ToLower(attr.Val)
+		case "href":
+			href = strings.TrimSpace(attr.Val)
+		case "hreflang":
+			hreflang = strings.ToLower(strings.TrimSpace(attr.Val))
+		}
+	}
+	if href == "" {
+		return
+	}
+
+	switch {
+	case hasToken(rel, "canonical"):
+		if parsed.Canonical != nil {
+			return
+		}
+		if linkURL, err := url.Parse(href); err == nil {
+			parsed.Canonical = baseURL.ResolveReference(linkURL)
+		}
+	case hasToken(rel, "alternate") && hreflang != "":
+		if _, ok := parsed.LanguageVariants[hreflang]; ok {
+			return
+		}
+		if linkURL, err := url.Parse(href); err == nil {
+			if parsed.LanguageVariants == nil {
+				parsed.LanguageVariants = make(map[string]*url.URL)
 			}
+			parsed.LanguageVariants[hreflang] = baseURL.ResolveReference(linkURL)
 		}
 	}
 }
@@ -213,12 +742,33 @@ func (n *urlNormalizer) Normalize(rawURL string, baseURL *url.URL) (*url.URL, er
 	// Remove fragment
 	u.Fragment = ""
 
-	// Sort query parameters
-	u.RawQuery = u.Query().Encode()
+	// Drop tracking parameters and sort the rest
+	query := u.Query()
+	for key := range query {
+		if n.stripped(key) {
+			query.Del(key)
+		}
+	}
+	u.RawQuery = query.Encode()
 
 	return u, nil
 }
 
+// stripped reports whether normalization drops a query parameter
+func (n *urlNormalizer) stripped(key string) bool {
+	key = strings.ToLower(key)
+	for _, param := range n.stripParams {
+		if prefix, ok := strings.CutSuffix(param, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == param {
+			return true
+		}
+	}
+	return false
+}
+
 // IsValid checks if a URL is valid for crawling
 func (n *urlNormalizer) IsValid(u *url.URL) bool {
 	// Must have a scheme
@@ -238,13 +788,21 @@ func (n *urlNormalizer) IsValid(u *url.URL) bool {
 
 	// Skip common non-content file extensions
 	ext := strings.ToLower(u.Path)
-	skipExtensions := []string{".pdf", ".doc", ".docx", ".xls", ".xlsx", ".ppt", ".pptx", ".zip", ".rar", ".tar", ".gz", ".jpg", ".jpeg", ".png", ".gif", ".svg", ".ico", ".css", ".js", ".xml", ".json"}
+	skipExtensions := []string{".doc", ".xls", ".ppt", ".zip", ".rar", ".tar", ".gz", ".jpg", ".jpeg", ".png", ".gif", ".svg", ".ico", ".css", ".js", ".xml"}
 	for _, skipExt := range skipExtensions {
 		if strings.HasSuffix(ext, skipExt) {
 			return false
 		}
 	}
 
+	// PDF and Office files are documents only when their parsing is on
+	if n.skipPDF && strings.HasSuffix(ext, ".pdf") {
+		return false
+	}
+	if n.skipOffice && (strings.HasSuffix(ext, ".docx") || strings.HasSuffix(ext, ".pptx") || strings.HasSuffix(ext, ".xlsx")) {
+		return false
+	}
+
 	// Skip common non-content paths
 	skipPaths := []string{"/admin", "/login", "/logout", "/api/", "/static/", "/assets/", "/images/", "/css/", "/js/"}
 	for _, skipPath := range skipPaths {
@@ -255,7 +813,7 @@ func (n *urlNormalizer) IsValid(u *url.URL) bool {
 
 	// Skip URLs with certain query parameters
 	query := u.Query()
-	if query.Get("download") != "" || query.Get("attachment") != "" || query.Get("format") == "pdf" {
+	if query.Get("download") != "" || query.Get("attachment") != "" {
 		return false
 	}
 
@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxRefreshDelay is the longest <meta http-equiv="refresh"> delay, in
+// seconds, taken for a redirect. Longer ones time out a page or reload it.
+const maxRefreshDelay = 5
+
+// maxInterstitialText is the most text a page redirecting by script may
+// have; pages with more are content that happens to navigate somewhere
+const maxInterstitialText = 500
+
+// scriptRedirectPattern matches scripts that only send the browser to a
+// literal URL, e.g. window.location.href = "/new" or location.replace('/new')
+var scriptRedirectPattern = regexp.MustCompile(
+	`^\s*(?:(?:window|document|self|top)\.)?location(?:\.href\s*=\s*|\s*=\s*|\.(?:replace|assign)\(\s*)["']([^"']+)["']\s*\)?\s*;?\s*$`)
+
+// parseRefresh reads the target of a refresh such as "0; url=/new" that
+// fires within maxRefreshDelay seconds
+func parseRefresh(content string, baseURL *url.URL) *url.URL {
+	delay, target, found := strings.Cut(content, ";")
+	if !found {
+		delay, target, found = strings.Cut(content, ",")
+	}
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(delay), 64)
+	if !found || err != nil || seconds > maxRefreshDelay {
+		return nil
+	}
+
+	target = strings.TrimSpace(target)
+	if len(target) > 3 && strings.EqualFold(target[:3], "url") {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(target[3:]), "="); ok {
+			target = strings.TrimSpace(rest)
+		}
+	}
+	return resolveRedirect(strings.Trim(target, `"'`), baseURL)
+}
+
+// parseScriptRedirect reads the target of a script that does nothing but
+// redirect
+func parseScriptRedirect(script string, baseURL *url.URL) *url.URL {
+	match := scriptRedirectPattern.FindStringSubmatch(script)
+	if match == nil {
+		return nil
+	}
+	return resolveRedirect(match[1], baseURL)
+}
+
+// resolveRedirect resolves a redirect target against the page URL, or
+// returns nil if it is empty, invalid or the page itself
+func resolveRedirect(target string, baseURL *url.URL) *url.URL {
+	if target == "" {
+		return nil
+	}
+	targetURL, err := url.Parse(target)
+	if err != nil {
+		return nil
+	}
+	resolved := baseURL.ResolveReference(targetURL)
+	resolved.Fragment = ""
+	if resolved.String() == baseURL.String() {
+		return nil
+	}
+	return resolved
+}
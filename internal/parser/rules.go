@@ -0,0 +1,188 @@
+package parser
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ExtractionRule overrides the generic extraction for the pages of a site
+// whose layout it mangles. Selectors are CSS selector lists.
+type ExtractionRule struct {
+	Domain     string   `yaml:"domain"`                // Also applies to its subdomains
+	PathPrefix string   `yaml:"path_prefix,omitempty"` // Limits the rule to some of the site's pages
+	Content    string   `yaml:"content,omitempty"`     // Elements holding the page's text
+	Exclude    []string `yaml:"exclude,omitempty"`     // Elements left out of the text
+	Title      string   `yaml:"title,omitempty"`       // Element holding the page's title
+}
+
+// compiledRule is an extraction rule with its selectors parsed
+type compiledRule struct {
+	ExtractionRule
+	content selector
+	exclude []selector
+	title   selector
+}
+
+// Validate checks that a rule names a domain and that its selectors parse
+func (r ExtractionRule) Validate() error {
+	_, err := compileRule(r)
+	return err
+}
+
+// compileRule parses the selectors of a rule
+func compileRule(rule ExtractionRule) (*compiledRule, error) {
+	rule.Domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(rule.Domain), "www."))
+	if rule.Domain == "" {
+		return nil, fmt.Errorf("extraction rule has no domain")
+	}
+	if rule.Content == "" && rule.Title == "" && len(rule.Exclude) == 0 {
+		return nil, fmt.Errorf("extraction rule for %s sets no selector", rule.Domain)
+	}
+
+	compiled := &compiledRule{ExtractionRule: rule}
+	var err error
+	if rule.Content != "" {
+		if compiled.content, err = compileSelector(rule.Content); err != nil {
+			return nil, fmt.Errorf("extraction rule for %s: %w", rule.Domain, err)
+		}
+	}
+	if rule.Title != "" {
+		if compiled.title, err = compileSelector(rule.Title); err != nil {
+			return nil, fmt.Errorf("extraction rule for %s: %w", rule.Domain, err)
+		}
+	}
+	for _, source := range rule.Exclude {
+		exclude, err := compileSelector(source)
+		if err != nil {
+			return nil, fmt.Errorf("extraction rule for %s: %w", rule.Domain, err)
+		}
+		compiled.exclude = append(compiled.exclude, exclude)
+	}
+	return compiled, nil
+}
+
+// compileRules parses the selectors of the rules that are valid
+func compileRules(rules []ExtractionRule) []*compiledRule {
+	var compiled []*compiledRule
+	for _, rule := range rules {
+		if c, err := compileRule(rule); err == nil {
+			compiled = append(compiled, c)
+		}
+	}
+	return compiled
+}
+
+// ruleFor returns the rule applying to a page, the one with the longest
+// domain and then path prefix when several do, or nil if none does
+func (p *htmlParser) ruleFor(pageURL *url.URL) *compiledRule {
+	if pageURL == nil {
+		return nil
+	}
+	host := strings.ToLower(pageURL.Hostname())
+
+	var best *compiledRule
+	for _, rule := range p.rules {
+		if host != rule.Domain && !strings.HasSuffix(host, "."+rule.Domain) {
+			continue
+		}
+		if !strings.HasPrefix(pageURL.Path, rule.PathPrefix) {
+			continue
+		}
+		if best == nil || len(rule.Domain) > len(best.Domain) ||
+			(len(rule.Domain) == len(best.Domain) && len(rule.PathPrefix) > len(best.PathPrefix)) {
+			best = rule
+		}
+	}
+	return best
+}
+
+// applyRule replaces the generically extracted title and text of a page
+// with those its rule selects. Selectors matching nothing leave the
+// generic extraction in place, so a redesigned site degrades gracefully.
+func (p *htmlParser) applyRule(doc *html.Node, rule *compiledRule, parsed *ParsedContent) {
+	if rule.title != nil {
+		if matches := findAll(doc, rule.title); len(matches) > 0 {
+			var title strings.Builder
+			p.extractText(matches[0], &title)
+			if text := strings.Join(strings.Fields(title.String()), " "); text != "" {
+				parsed.Title = text
+			}
+		}
+	}
+
+	if rule.content == nil && len(rule.exclude) == 0 {
+		return
+	}
+	var roots []*html.Node
+	if rule.content != nil {
+		roots = findAll(doc, rule.content)
+	} else {
+		roots = []*html.Node{doc}
+	}
+
+	var text textWriter
+	var tables []*Table
+	var code []*CodeBlock
+	for _, root := range roots {
+		p.ruleText(root, rule, &text, &tables, &code)
+		text.Break()
+	}
+	if strings.TrimSpace(text.String()) != "" {
+		parsed.Text = text.String()
+		parsed.Tables = tables
+		parsed.CodeBlocks = code
+	}
+}
+
+// ruleText extracts the readable text of a node like extractText, leaving
+// out the elements a rule excludes and the document head. Data tables and
+// code blocks are rendered like extractData does and collected into tables
+// and code.
+func (p *htmlParser) ruleText(n *html.Node, rule *compiledRule, text *textWriter, tables *[]*Table, code *[]*CodeBlock) {
+	if n.Type == html.ElementNode {
+		if n.Data == "head" {
+			return
+		}
+		for _, exclude := range rule.exclude {
+			if exclude.matches(n) {
+				return
+			}
+		}
+		if n.Data == "table" {
+			if table := p.extractTable(n); table != nil {
+				*tables = append(*tables, table)
+				text.WriteBlock(table.Text())
+				return
+			}
+		}
+		if n.Data == "pre" || (n.Data == "code" && isCodeBlock(n)) {
+			if block := p.extractCode(n); block != nil {
+				*code = append(*code, block)
+				text.WriteBlock(block.Text())
+			}
+			return
+		}
+	}
+	if n.Type == html.TextNode {
+		if content := strings.TrimSpace(n.Data); content != "" {
+			text.WriteText(content)
+		}
+	}
+	if n.Type == html.ElementNode && (n.Data == "script" || n.Data == "style") {
+		return
+	}
+
+	block := n.Type == html.ElementNode && blockElements[n.Data]
+	if block {
+		text.Break()
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		p.ruleText(c, rule, text, tables, code)
+	}
+	if block {
+		text.Break()
+	}
+}
@@ -0,0 +1,308 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// selector is a list of CSS selectors, matching the elements any of them
+// matches. Type, universal, ID, class and attribute selectors are
+// supported, joined by descendant and child combinators; pseudo-classes
+// are not.
+type selector []complexSelector
+
+// complexSelector is compound selectors joined by combinators, held right
+// to left: parts[0] matches the element itself, and combinators[i] joins
+// parts[i] to the ancestor parts[i+1]
+type complexSelector struct {
+	parts       []compoundSelector
+	combinators []byte
+}
+
+// compoundSelector matches an element by its name, ID, classes and
+// attributes
+type compoundSelector struct {
+	tag     string
+	id      string
+	classes []string
+	attrs   []attrSelector
+}
+
+// attrSelector matches an attribute, by presence when op is empty
+type attrSelector struct {
+	key   string
+	op    string
+	value string
+}
+
+// attrOperators are the attribute selector operators, two-character ones
+// first
+var attrOperators = []string{"~=", "^=", "$=", "*=", "|=", "="}
+
+// compileSelector parses a comma-separated list of CSS selectors
+func compileSelector(source string) (selector, error) {
+	var list selector
+	for _, part := range strings.Split(source, ",") {
+		complex, err := compileComplex(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %w", source, err)
+		}
+		list = append(list, complex)
+	}
+	return list, nil
+}
+
+// compileComplex parses a selector of compound selectors and combinators
+func compileComplex(source string) (complexSelector, error) {
+	var c complexSelector
+	if source == "" {
+		return c, fmt.Errorf("empty selector")
+	}
+
+	i := 0
+	for i < len(source) {
+		spaced := false
+		for i < len(source) && isSelectorSpace(source[i]) {
+			i++
+			spaced = true
+		}
+		combinator := byte(0)
+		if i < len(source) && source[i] == '>' {
+			combinator = '>'
+			i++
+			for i < len(source) && isSelectorSpace(source[i]) {
+				i++
+			}
+		} else if spaced {
+			combinator = ' '
+		}
+		if i == len(source) {
+			if combinator == '>' {
+				return c, fmt.Errorf("combinator without a selector after it")
+			}
+			break
+		}
+
+		compound, n, err := compileCompound(source[i:])
+		if err != nil {
+			return c, err
+		}
+		if n == 0 {
+			return c, fmt.Errorf("unexpected %q", source[i])
+		}
+		if len(c.parts) > 0 {
+			c.combinators = append(c.combinators, combinator)
+		} else if combinator == '>' {
+			return c, fmt.Errorf("combinator without a selector before it")
+		}
+		c.parts = append(c.parts, compound)
+		i += n
+	}
+
+	for l, r := 0, len(c.parts)-1; l < r; l, r = l+1, r-1 {
+		c.parts[l], c.parts[r] = c.parts[r], c.parts[l]
+	}
+	for l, r := 0, len(c.combinators)-1; l < r; l, r = l+1, r-1 {
+		c.combinators[l], c.combinators[r] = c.combinators[r], c.combinators[l]
+	}
+	return c, nil
+}
+
+// compileCompound parses the compound selector at the start of source,
+// returning how many bytes it took
+func compileCompound(source string) (compoundSelector, int, error) {
+	var c compoundSelector
+	i := 0
+	name := func() string {
+		start := i
+		for i < len(source) && isSelectorNameChar(source[i]) {
+			i++
+		}
+		return source[start:i]
+	}
+
+	if i < len(source) && source[i] == '*' {
+		i++
+	} else {
+		c.tag = strings.ToLower(name())
+	}
+
+	for i < len(source) {
+		switch source[i] {
+		case '#':
+			i++
+			if c.id = name(); c.id == "" {
+				return c, i, fmt.Errorf("ID selector without a name")
+			}
+		case '.':
+			i++
+			class := name()
+			if class == "" {
+				return c, i, fmt.Errorf("class selector without a name")
+			}
+			c.classes = append(c.classes, class)
+		case '[':
+			end := strings.IndexByte(source[i:], ']')
+			if end < 0 {
+				return c, i, fmt.Errorf("unclosed attribute selector")
+			}
+			attr, err := compileAttr(source[i+1 : i+end])
+			if err != nil {
+				return c, i, err
+			}
+			c.attrs = append(c.attrs, attr)
+			i += end + 1
+		case ':':
+			return c, i, fmt.Errorf("pseudo-classes are not supported")
+		default:
+			return c, i, nil
+		}
+	}
+	return c, i, nil
+}
+
+// compileAttr parses the inside of an attribute selector
+func compileAttr(source string) (attrSelector, error) {
+	for _, op := range attrOperators {
+		if key, value, found := strings.Cut(source, op); found {
+			key = strings.ToLower(strings.TrimSpace(key))
+			if key == "" {
+				return attrSelector{}, fmt.Errorf("attribute selector without a name")
+			}
+			value = strings.Trim(strings.TrimSpace(value), `"'`)
+			return attrSelector{key: key, op: op, value: value}, nil
+		}
+	}
+	key := strings.ToLower(strings.TrimSpace(source))
+	if key == "" {
+		return attrSelector{}, fmt.Errorf("attribute selector without a name")
+	}
+	return attrSelector{key: key}, nil
+}
+
+func isSelectorSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isSelectorNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c >= 0x80
+}
+
+// matches reports whether an element matches any selector of the list
+func (s selector) matches(n *html.Node) bool {
+	for _, complex := range s {
+		if complex.matchFrom(n, 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchFrom reports whether n matches parts[i] and its ancestors the parts
+// after it
+func (c complexSelector) matchFrom(n *html.Node, i int) bool {
+	if !c.parts[i].matches(n) {
+		return false
+	}
+	if i == len(c.parts)-1 {
+		return true
+	}
+	if c.combinators[i] == '>' {
+		return n.Parent != nil && c.matchFrom(n.Parent, i+1)
+	}
+	for ancestor := n.Parent; ancestor != nil; ancestor = ancestor.Parent {
+		if c.matchFrom(ancestor, i+1) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether an element matches a compound selector
+func (c compoundSelector) matches(n *html.Node) bool {
+	if n.Type != html.ElementNode || (c.tag != "" && n.Data != c.tag) {
+		return false
+	}
+	if c.id != "" && attribute(n, "id") != c.id {
+		return false
+	}
+	if len(c.classes) > 0 {
+		classes := strings.Fields(attribute(n, "class"))
+		for _, class := range c.classes {
+			if !containsString(classes, class) {
+				return false
+			}
+		}
+	}
+	for _, attr := range c.attrs {
+		if !attr.matches(n) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches reports whether an element has a matching attribute
+func (a attrSelector) matches(n *html.Node) bool {
+	for _, attr := range n.Attr {
+		if attr.Key != a.key {
+			continue
+		}
+		switch a.op {
+		case "":
+			return true
+		case "=":
+			return attr.Val == a.value
+		case "~=":
+			return containsString(strings.Fields(attr.Val), a.value)
+		case "^=":
+			return a.value != "" && strings.HasPrefix(attr.Val, a.value)
+		case "$=":
+			return a.value != "" && strings.HasSuffix(attr.Val, a.value)
+		case "*=":
+			return a.value != "" && strings.Contains(attr.Val, a.value)
+		case "|=":
+			return attr.Val == a.value || strings.HasPrefix(attr.Val, a.value+"-")
+		}
+	}
+	return false
+}
+
+// findAll returns the elements under root matching s in document order,
+// leaving out those inside another match
+func findAll(root *html.Node, s selector) []*html.Node {
+	var found []*html.Node
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if s.matches(n) {
+			found = append(found, n)
+			return
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(root)
+	return found
+}
+
+// attribute returns the value of an element's attribute, "" if absent
+func attribute(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package parser
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// maxColspan caps how many columns a single cell is repeated across
+const maxColspan = 50
+
+// Table is a data table of a page, kept with its structure so that each
+// row can be read against the column headers
+type Table struct {
+	Caption string     `json:"caption,omitempty"`
+	Headers []string   `json:"headers,omitempty"` // Empty when the table has no header row
+	Rows    [][]string `json:"rows"`
+}
+
+// Text renders the table for indexing: its caption, then a sentence per
+// row pairing each cell with its column header, as in "Plan: Pro; Price:
+// $20." Rows of a table without headers list their cells.
+func (t *Table) Text() string {
+	var text strings.Builder
+	if t.Caption != "" {
+		text.WriteString(t.Caption)
+		text.WriteString(". ")
+	}
+	for i := range t.Rows {
+		if i > 0 {
+			text.WriteString(" ")
+		}
+		text.WriteString(t.RowText(i))
+		text.WriteString(".")
+	}
+	return text.String()
+}
+
+// RowText renders one row of the table without its closing period
+func (t *Table) RowText(i int) string {
+	row := t.Rows[i]
+	if len(t.Headers) == 0 {
+		return strings.Join(row, " | ")
+	}
+
+	parts := make([]string, 0, len(row))
+	for j, cell := range row {
+		if cell == "" {
+			continue
+		}
+		if j < len(t.Headers) && t.Headers[j] != "" {
+			parts = append(parts, t.Headers[j]+": "+cell)
+		} else {
+			parts = append(parts, cell)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// extractTable reads a <table> element into a Table. It returns nil for
+// layout tables: those marked presentational, nesting other tables or
+// without at least two columns and one data row, whose cells are then
+// extracted as ordinary text.
+func (p *htmlParser) extractTable(n *html.Node) *Table {
+	role := strings.ToLower(attribute(n, "role"))
+	if role == "presentation" || role == "none" || containsTable(n) {
+		return nil
+	}
+
+	table := &Table{}
+	columns := 0
+
+	var visit func(node *html.Node, inHead bool)
+	visit = func(node *html.Node, inHead bool) {
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.Data {
+			case "caption":
+				if table.Caption == "" {
+					table.Caption = p.cellText(c)
+				}
+			case "thead":
+				visit(c, true)
+			case "tbody", "tfoot":
+				visit(c, false)
+			case "tr":
+				cells, allHeaders := p.tableRow(c)
+				if len(cells) == 0 {
+					continue
+				}
+				columns = max(columns, len(cells))
+
+				// The first row of header cells, in <thead> or leading the
+				// table, names the columns
+				if table.Headers == nil && len(table.Rows) == 0 && (inHead || allHeaders) {
+					table.Headers = cells
+					continue
+				}
+				if inHead {
+					continue
+				}
+				table.Rows = append(table.Rows, cells)
+			}
+		}
+	}
+	visit(n, false)
+
+	if columns < 2 || len(table.Rows) == 0 {
+		return nil
+	}
+	return table
+}
+
+// tableRow returns the text of a row's cells, a cell spanning several
+// columns repeated across them, and whether every cell is a header cell.
+// Rows whose cells are all empty yield no cells.
+func (p *htmlParser) tableRow(tr *html.Node) ([]string, bool) {
+	var cells []string
+	allHeaders, empty := true, true
+
+	for c := tr.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || (c.Data != "td" && c.Data != "th") {
+			continue
+		}
+		if c.Data == "td" {
+			allHeaders = false
+		}
+
+		text := p.cellText(c)
+		if text != "" {
+			empty = false
+		}
+
+		span, err := strconv.Atoi(attribute(c, "colspan"))
+		if err != nil || span < 1 {
+			span = 1
+		}
+		for i := 0; i < min(span, maxColspan); i++ {
+			cells = append(cells, text)
+		}
+	}
+
+	if empty {
+		return nil, false
+	}
+	return cells, allHeaders
+}
+
+// cellText returns the whitespace-collapsed text of a table cell
+func (p *htmlParser) cellText(n *html.Node) string {
+	var text strings.Builder
+	p.extractText(n, &text)
+	return strings.Join(strings.Fields(text.String()), " ")
+}
+
+// extractNestedLinks extracts the links within an element whose text
+// extractData does not walk, a data table or a code block
+func (p *htmlParser) extractNestedLinks(n *html.Node, parsed *ParsedContent, baseURL *url.URL) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "a" {
+			p.extractLink(c, parsed, baseURL)
+		}
+		p.extractNestedLinks(c, parsed, baseURL)
+	}
+}
+
+// containsTable reports whether a table nests another table
+func containsTable(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "table" {
+			return true
+		}
+		if containsTable(c) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package retention
+
+import (
+	"ai-search/internal/events"
+	"ai-search/internal/indexer"
+	"ai-search/internal/store"
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Purger defines the interface for purging soft-deleted documents
+type Purger interface {
+	// Purge removes documents deleted longer ago than the retention window
+	// from the index and the store, returning the purged document IDs
+	Purge(ctx context.Context) ([]string, error)
+
+	// Run purges on the given interval until the context is cancelled
+	Run(ctx context.Context, interval time.Duration)
+}
+
+// Config holds purge configuration
+type Config struct {
+	Store   store.Store
+	Indexer indexer.Indexer
+	Window  time.Duration // How long soft-deleted documents can be restored
+	Events  events.Publisher
+}
+
+// purger implements the Purger interface
+type purger struct {
+	config Config
+}
+
+// NewPurger creates a new purger instance
+func NewPurger(config Config) Purger {
+	if config.Window == 0 {
+		config.Window = 7 * 24 * time.Hour
+	}
+
+	return &purger{
+		config: config,
+	}
+}
+
+// Purge removes documents deleted longer ago than the retention window
+func (p *purger) Purge(ctx context.Context) ([]string, error) {
+	ids, err := p.config.Store.ListDeletedDocumentIDs(ctx, time.Now().Add(-p.config.Window))
+	if err != nil {
+		return nil, err
+	}
+
+	var purged []string
+	for _, id := range ids {
+		// Remove from the index first so a failure leaves the document
+		// marked as deleted and the purge is retried next time
+		if err = p.config.Indexer.Delete(ctx, id); err != nil {
+			err = fmt.Errorf("failed to purge %s from index: %w", id, err)
+			break
+		}
+		if err = p.config.Store.DeleteDocument(ctx, id); err != nil {
+			err = fmt.Errorf("failed to purge %s from store: %w", id, err)
+			break
+		}
+		purged = append(purged, id)
+	}
+
+	if p.config.Events != nil && len(purged) > 0 {
+		p.config.Events.Publish(ctx, events.DocumentPurged, purged...)
+	}
+
+	return purged, err
+}
+
+// Run purges on the given interval until the context is cancelled
+func (p *purger) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := p.Purge(ctx)
+		if err != nil {
+			log.Printf("Purge error: %v", err)
+		}
+		if len(purged) > 0 {
+			log.Printf("Purged %d deleted documents", len(purged))
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// SoftDelete marks a document deleted in the store and in the index, which
+// searches skip it by, until it is restored or purged. A failure to mark
+// the index undoes the store change, so the deletion can be retried.
+func SoftDelete(ctx context.Context, documentStore store.Store, documentIndexer indexer.Indexer, id string) error {
+	return setDeleted(ctx, documentStore, documentIndexer, id, true)
+}
+
+// Restore clears the deletion mark of a soft-deleted document in the store
+// and in the index, undoing the store change if the index fails
+func Restore(ctx context.Context, documentStore store.Store, documentIndexer indexer.Indexer, id string) error {
+	return setDeleted(ctx, documentStore, documentIndexer, id, false)
+}
+
+// setDeleted sets or clears the deletion mark of a document
+func setDeleted(ctx context.Context, documentStore store.Store, documentIndexer indexer.Indexer, id string, deleted bool) error {
+	update, undo := documentStore.SoftDeleteDocument, documentStore.RestoreDocument
+	if !deleted {
+		update, undo = undo, update
+	}
+
+	if err := update(ctx, id); err != nil {
+		return err
+	}
+	if documentIndexer == nil {
+		return nil
+	}
+	if err := documentIndexer.SetDeleted(ctx, id, deleted); err != nil {
+		if undoErr := undo(ctx, id); undoErr != nil {
+			log.Printf("Failed to undo the deletion mark of %s: %v", id, undoErr)
+		}
+		return fmt.Errorf("failed to mark %s in index: %w", id, err)
+	}
+	return nil
+}
+
+// MarkDeleted marks the documents soft-deleted in the store as deleted in
+// the index too, for documents deleted before the index carried the mark
+func MarkDeleted(ctx context.Context, documentStore store.Store, documentIndexer indexer.Indexer) (int, error) {
+	ids, err := documentStore.ListDeletedDocumentIDs(ctx, time.Time{})
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		if err := documentIndexer.SetDeleted(ctx, id, true); err != nil {
+			return 0, fmt.Errorf("failed to mark %s in index: %w", id, err)
+		}
+	}
+	return len(ids), nil
+}
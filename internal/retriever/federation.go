@@ -0,0 +1,157 @@
+package retriever
+
+import (
+	"ai-search/internal/indexer"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// InstanceKey is the result metadata key holding the name of the instance
+// a federated result came from
+const InstanceKey = "instance"
+
+// defaultFusionK damps the weight of top ranks in reciprocal rank fusion;
+// 60 is the customary value
+const defaultFusionK = 60
+
+// Instance is an ai-search deployment a federation searches
+type Instance struct {
+	Name      string // Names the instance in results, e.g. "platform-team"
+	Retriever Retriever
+}
+
+// FederationConfig holds federation configuration
+type FederationConfig struct {
+	Instances []Instance
+
+	// FusionK is the k of reciprocal rank fusion, 60 by default. Scores of
+	// different instances are not comparable, so results are ranked by the
+	// sum over instances of 1/(k+rank) instead.
+	FusionK int
+}
+
+// federation implements the Retriever interface over several instances
+type federation struct {
+	config FederationConfig
+}
+
+// NewFederation creates a retriever fanning a query out to every instance
+// and fusing their rankings, each result labelled with its instance
+func NewFederation(config FederationConfig) Retriever {
+	if config.FusionK <= 0 {
+		config.FusionK = defaultFusionK
+	}
+	return &federation{config: config}
+}
+
+// fusedResult is a result with its reciprocal rank fusion score
+type fusedResult struct {
+	result *indexer.SearchResult
+	score  float64
+}
+
+// Retrieve searches every instance at once and fuses their results. A
+// passage found by several instances, the same URL at the same position,
+// is returned once with the fused score of all of them.
+func (f *federation) Retrieve(ctx context.Context, query string, limit int, opts indexer.SearchOptions) ([]*indexer.SearchResult, error) {
+	type instanceResults struct {
+		results []*indexer.SearchResult
+		debug   *indexer.SearchDebug
+		err     error
+	}
+	found := make([]instanceResults, len(f.config.Instances))
+	var wg sync.WaitGroup
+	for i, instance := range f.config.Instances {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			instanceOpts := opts
+			if opts.Debug != nil {
+				instanceOpts.Debug = &indexer.SearchDebug{}
+			}
+			results, err := instance.Retriever.Retrieve(ctx, query, limit, instanceOpts)
+			found[i] = instanceResults{results: results, debug: instanceOpts.Debug, err: err}
+		}()
+	}
+	wg.Wait()
+
+	// An unreachable instance only loses its own results
+	fused := make(map[string]*fusedResult)
+	var order []string
+	var failed []string
+	for i, instance := range f.config.Instances {
+		if found[i].err != nil {
+			fmt.Printf("Warning: Federated search of %s failed: %v\n", instance.Name, found[i].err)
+			failed = append(failed, instance.Name)
+		}
+		if opts.Debug != nil {
+			for _, debugQuery := range found[i].debug.Queries {
+				debugQuery.Source = instanceSource(instance.Name, debugQuery.Source)
+				opts.Debug.Queries = append(opts.Debug.Queries, debugQuery)
+			}
+			if found[i].err != nil {
+				opts.Debug.Queries = append(opts.Debug.Queries, indexer.DebugQuery{Text: query, Source: instance.Name, Error: found[i].err.Error()})
+			}
+		}
+
+		for rank, result := range found[i].results {
+			key := passageKey(instance.Name, result)
+			entry, ok := fused[key]
+			if !ok {
+				if result.Metadata == nil {
+					result.Metadata = make(map[string]interface{})
+				}
+				result.Metadata[InstanceKey] = instance.Name
+				entry = &fusedResult{result: result}
+				fused[key] = entry
+				order = append(order, key)
+			}
+			entry.score += 1 / float64(f.config.FusionK+rank+1)
+		}
+	}
+	if len(failed) > 0 && len(failed) == len(f.config.Instances) {
+		return nil, fmt.Errorf("failed to search %s", strings.Join(failed, ", "))
+	}
+
+	merged := make([]*indexer.SearchResult, 0, len(order))
+	for _, key := range order {
+		entry := fused[key]
+		entry.result.Score = float32(entry.score)
+		merged = append(merged, entry.result)
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Score > merged[j].Score
+	})
+	if len(merged) > limit {
+		merged = merged[:limit]
+	}
+	return merged, nil
+}
+
+// passageKey identifies a result across instances by its URL and position,
+// or by its instance and chunk when it has no URL
+func passageKey(instance string, result *indexer.SearchResult) string {
+	if url, ok := result.Metadata["url"].(string); ok && url != "" {
+		return fmt.Sprintf("%s#%d-%d", url, result.StartPos, result.EndPos)
+	}
+	return instance + "/" + result.ChunkID
+}
+
+// instanceSource names the instance a debug query ran on, and the routed
+// source within it if any
+func instanceSource(instance, source string) string {
+	if source == "" {
+		return instance
+	}
+	return instance + "/" + source
+}
+
+// SetReranker sets the reranker of every instance
+func (f *federation) SetReranker(reranker Reranker) {
+	for _, instance := range f.config.Instances {
+		instance.Retriever.SetReranker(reranker)
+	}
+}
@@ -0,0 +1,151 @@
+package retriever
+
+import (
+	"ai-search/internal/indexer"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// FederatedHeader marks searches sent by a federating instance, which the
+// receiving instance answers from its own index only so that instances
+// federating each other never fan out in a loop
+const FederatedHeader = "X-AI-Search-Federated"
+
+// RemoteConfig holds the configuration of a remote ai-search instance
+type RemoteConfig struct {
+	URL     string        // Base URL of the instance, e.g. https://search.team.internal
+	Timeout time.Duration // 5 seconds by default
+
+	// APIKey is sent to instances that require API keys
+	APIKey string
+}
+
+// remoteRetriever implements the Retriever interface over the search API
+// of another ai-search instance
+type remoteRetriever struct {
+	config     RemoteConfig
+	httpClient *http.Client
+}
+
+// remoteRequest and remoteResponse mirror the instance's /api/search
+// request and response
+type remoteRequest struct {
+	Query    string            `json:"query"`
+	Limit    int               `json:"limit"`
+	Filters  map[string]string `json:"filters,omitempty"`
+	Version  string            `json:"version"`
+	Language string            `json:"language,omitempty"`
+	Debug    bool              `json:"debug,omitempty"`
+	Snippets bool              `json:"snippets"`
+
+	SourceWeights map[string]float64 `json:"source_weights,omitempty"`
+}
+
+type remoteResponse struct {
+	Results []struct {
+		DocumentID     string                 `json:"document_id"`
+		ChunkID        string                 `json:"chunk_id"`
+		Score          float32                `json:"score"`
+		Text           string                 `json:"text"`
+		Attributes     map[string]string      `json:"attributes"`
+		Metadata       map[string]interface{} `json:"metadata"`
+		StartPos       int                    `json:"start_pos"`
+		EndPos         int                    `json:"end_pos"`
+		Position       int                    `json:"position"`
+		DocumentChunks int                    `json:"document_chunks"`
+	} `json:"results"`
+	Debug *indexer.SearchDebug `json:"debug"`
+}
+
+// NewRemoteRetriever creates a retriever searching a remote ai-search
+// instance through its search API
+func NewRemoteRetriever(config RemoteConfig) Retriever {
+	if config.Timeout == 0 {
+		config.Timeout = 5 * time.Second
+	}
+	config.URL = strings.TrimRight(config.URL, "/")
+
+	return &remoteRetriever{
+		config:     config,
+		httpClient: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+// Retrieve searches the remote instance. Filters, including the corpus
+// version, are passed on as they are, so the instance's own default version
+// does not apply.
+func (r *remoteRetriever) Retrieve(ctx context.Context, query string, limit int, opts indexer.SearchOptions) ([]*indexer.SearchResult, error) {
+	payload, err := json.Marshal(remoteRequest{
+		Query:    query,
+		Limit:    limit,
+		Filters:  opts.Filters,
+		Version:  "all",
+		Language: opts.Language,
+		Debug:    opts.Debug != nil,
+
+		SourceWeights: opts.SourceWeights,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal search request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", r.config.URL+"/api/search", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create search request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(FederatedHeader, "1")
+	if r.config.APIKey != "" {
+		req.Header.Set("X-API-Key", r.config.APIKey)
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search %s: %w", r.config.URL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to search %s: HTTP %d", r.config.URL, resp.StatusCode)
+	}
+
+	var response remoteResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode search response: %w", err)
+	}
+
+	if opts.Debug != nil && response.Debug != nil {
+		opts.Debug.Queries = append(opts.Debug.Queries, response.Debug.Queries...)
+	}
+
+	results := make([]*indexer.SearchResult, 0, len(response.Results))
+	for _, result := range response.Results {
+		metadata := result.Metadata
+		if metadata == nil {
+			metadata = make(map[string]interface{})
+		}
+		// Attributes decode as a generic map within the metadata
+		if result.Attributes != nil {
+			metadata["attributes"] = result.Attributes
+		}
+		results = append(results, &indexer.SearchResult{
+			DocumentID: result.DocumentID,
+			ChunkID:    result.ChunkID,
+			Score:      result.Score,
+			Text:       result.Text,
+			Metadata:   metadata,
+			StartPos:   result.StartPos,
+			EndPos:     result.EndPos,
+			Position:   result.Position,
+			ChunkCount: result.DocumentChunks,
+		})
+	}
+	return results, nil
+}
+
+// SetReranker does nothing; the remote instance reranks its own results
+func (r *remoteRetriever) SetReranker(reranker Reranker) {}
@@ -0,0 +1,167 @@
+package retriever
+
+import (
+	"ai-search/internal/indexer"
+	"ai-search/internal/metrics"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RerankCacheConfig holds rerank cache configuration
+type RerankCacheConfig struct {
+	TTL        time.Duration // How long an outcome is reused
+	MaxEntries int           // Outcomes kept, 1000 by default
+}
+
+// RerankCache remembers the order the reranker put a query's candidates
+// in, keyed by the query and its candidate set, so repeating a search, as
+// paging through its results does, reuses the order instead of calling
+// the LLM again. Least recently used outcomes are dropped first.
+type RerankCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mutex   sync.Mutex
+	entries map[string]*list.Element // Of *rerankEntry, most recently used first
+	lru     *list.List
+	pending map[string]bool // Keys being reranked
+}
+
+// rerankEntry is the reranked order of a candidate set
+type rerankEntry struct {
+	key      string
+	chunkIDs []string
+	expires  time.Time
+}
+
+// NewRerankCache creates a rerank cache, returning nil when the TTL
+// disables it
+func NewRerankCache(config RerankCacheConfig) *RerankCache {
+	if config.TTL <= 0 {
+		return nil
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = 1000
+	}
+
+	return &RerankCache{
+		ttl:        config.TTL,
+		maxEntries: config.MaxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		pending:    make(map[string]bool),
+	}
+}
+
+// rerankKey identifies a query and its candidates, whatever their order
+func rerankKey(query string, results []*indexer.SearchResult) string {
+	chunkIDs := make([]string, len(results))
+	for i, result := range results {
+		chunkIDs[i] = result.ChunkID
+	}
+	sort.Strings(chunkIDs)
+
+	hash := sha256.New()
+	hash.Write([]byte(query))
+	for _, chunkID := range chunkIDs {
+		hash.Write([]byte{0})
+		hash.Write([]byte(chunkID))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// get returns the candidates in their cached reranked order
+func (c *RerankCache) get(key string, results []*indexer.SearchResult) ([]*indexer.SearchResult, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.entries[key]
+	if ok && time.Now().After(element.Value.(*rerankEntry).expires) {
+		c.remove(element)
+		ok = false
+	}
+	if !ok {
+		metrics.RerankCacheLookups.Inc("miss")
+		return nil, false
+	}
+	c.lru.MoveToFront(element)
+	metrics.RerankCacheLookups.Inc("hit")
+
+	byChunk := make(map[string]*indexer.SearchResult, len(results))
+	for _, result := range results {
+		byChunk[result.ChunkID] = result
+	}
+	reranked := make([]*indexer.SearchResult, 0, len(results))
+	for _, chunkID := range element.Value.(*rerankEntry).chunkIDs {
+		if result, exists := byChunk[chunkID]; exists {
+			reranked = append(reranked, result)
+		}
+	}
+	return reranked, true
+}
+
+// begin claims the reranking of a key, returning false when it is already
+// being reranked. Without a cache every request reranks.
+func (c *RerankCache) begin(key string) bool {
+	if c == nil {
+		return true
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.pending[key] {
+		return false
+	}
+	c.pending[key] = true
+	return true
+}
+
+// put caches the reranked order of a key, or only releases the key when
+// reranking failed and reranked is nil
+func (c *RerankCache) put(key string, reranked []*indexer.SearchResult) {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.pending, key)
+	if reranked == nil {
+		return
+	}
+
+	chunkIDs := make([]string, len(reranked))
+	for i, result := range reranked {
+		chunkIDs[i] = result.ChunkID
+	}
+	entry := &rerankEntry{key: key, chunkIDs: chunkIDs, expires: time.Now().Add(c.ttl)}
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.lru.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+		metrics.RerankCacheEvictions.Inc()
+	}
+	metrics.RerankCacheEntries.Set(float64(c.lru.Len()))
+}
+
+// remove drops an entry; the caller holds the lock
+func (c *RerankCache) remove(element *list.Element) {
+	delete(c.entries, element.Value.(*rerankEntry).key)
+	c.lru.Remove(element)
+	metrics.RerankCacheEntries.Set(float64(c.lru.Len()))
+}
@@ -4,13 +4,17 @@ import (
 	"ai-search/internal/indexer"
 	"context"
 	"fmt"
+	"sort"
 	"time"
 )
 
+// languageBoost multiplies the score of results in the preferred language
+const languageBoost = 1.5
+
 // Retriever defines the interface for document retrieval
 type Retriever interface {
 	// Retrieve retrieves documents based on a query
-	Retrieve(ctx context.Context, query string, limit int) ([]*indexer.SearchResult, error)
+	Retrieve(ctx context.Context, query string, limit int, opts indexer.SearchOptions) ([]*indexer.SearchResult, error)
 
 	// SetReranker sets the reranker for post-processing results
 	SetReranker(reranker Reranker)
@@ -22,10 +26,28 @@ type Reranker interface {
 	Rerank(ctx context.Context, query string, results []*indexer.SearchResult) ([]*indexer.SearchResult, error)
 }
 
+// Translator translates queries for cross-lingual retrieval
+type Translator interface {
+	// Translate translates text into the language with the given ISO 639-1 code
+	Translate(ctx context.Context, text string, language string) (string, error)
+}
+
 // Config holds retriever configuration
 type Config struct {
 	Indexer indexer.Indexer
-	// Add more config as needed
+
+	// Translator and TranslateTo enable cross-lingual retrieval: the query
+	// is also searched in each TranslateTo language and results are merged
+	Translator  Translator
+	TranslateTo []string
+
+	// RerankCache reuses reranked orders for repeated searches; without it
+	// the reranked order is never applied to results
+	RerankCache *RerankCache
+
+	// SourceWeights are the collection's weights of results by source
+	// type, which SearchOptions.SourceWeights override per search
+	SourceWeights map[string]float64
 }
 
 // hybridRetriever implements the Retriever interface
@@ -42,27 +64,48 @@ func NewHybridRetriever(config Config) Retriever {
 }
 
 // Retrieve retrieves documents based on a query
-func (r *hybridRetriever) Retrieve(ctx context.Context, query string, limit int) ([]*indexer.SearchResult, error) {
+func (r *hybridRetriever) Retrieve(ctx context.Context, query string, limit int, opts indexer.SearchOptions) ([]*indexer.SearchResult, error) {
 	// Use the indexer to perform hybrid search
-	results, err := r.config.Indexer.Search(ctx, query, limit*2) // Get more results for reranking
+	results, err := r.config.Indexer.Search(ctx, query, limit*2, opts) // Get more results for reranking
 	if err != nil {
 		return nil, fmt.Errorf("failed to search index: %w", err)
 	}
+	if opts.Debug != nil {
+		opts.Debug.Queries = append(opts.Debug.Queries, indexer.DebugQuery{Text: query, Results: len(results)})
+	}
 
-	// If we have a reranker, do async reranking in background
+	if r.config.Translator != nil && len(r.config.TranslateTo) > 0 {
+		results = r.searchTranslations(ctx, query, limit, opts, results)
+	}
+
+	if opts.Language != "" {
+		boostLanguage(results, opts.Language)
+	}
+	weightSources(results, r.config.SourceWeights, opts.SourceWeights)
+
+	// If we have a reranker, apply the cached order of these candidates or
+	// rerank them in the background for the searches repeating this one
 	if r.reranker != nil && len(results) > 0 {
-		// Start async reranking in background - don't wait for it
-		go func() {
-			rerankCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-			defer cancel()
-
-			_, err := r.reranker.Rerank(rerankCtx, query, results)
-			if err != nil {
-				fmt.Printf("Warning: Async reranking failed: %v\n", err)
-			} else {
-				fmt.Printf("Async reranking completed for query: %s\n", query)
-			}
-		}()
+		key := rerankKey(query, results)
+		if reranked, ok := r.config.RerankCache.get(key, results); ok {
+			results = reranked
+		} else if r.config.RerankCache.begin(key) {
+			// Start async reranking in background - don't wait for it
+			candidates := append([]*indexer.SearchResult(nil), results...)
+			go func() {
+				rerankCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+				defer cancel()
+
+				reranked, err := r.reranker.Rerank(rerankCtx, query, candidates)
+				if err != nil {
+					fmt.Printf("Warning: Async reranking failed: %v\n", err)
+					reranked = nil
+				} else {
+					fmt.Printf("Async reranking completed for query: %s\n", query)
+				}
+				r.config.RerankCache.put(key, reranked)
+			}()
+		}
 	}
 
 	// Limit results to requested amount
@@ -73,6 +116,72 @@ func (r *hybridRetriever) Retrieve(ctx context.Context, query string, limit int)
 	return results, nil
 }
 
+// searchTranslations searches translations of the query and merges their
+// results with those of the original query, keeping each chunk's best score.
+// A failed translation only loses its own results.
+func (r *hybridRetriever) searchTranslations(ctx context.Context, query string, limit int, opts indexer.SearchOptions, results []*indexer.SearchResult) []*indexer.SearchResult {
+	byChunk := make(map[string]*indexer.SearchResult, len(results))
+	order := make([]string, 0, len(results))
+	for _, result := range results {
+		byChunk[result.ChunkID] = result
+		order = append(order, result.ChunkID)
+	}
+
+	for _, language := range r.config.TranslateTo {
+		debugQuery := indexer.DebugQuery{Language: language}
+
+		translated, err := r.config.Translator.Translate(ctx, query, language)
+		if err == nil && translated != "" && translated != query {
+			debugQuery.Text = translated
+
+			var translatedResults []*indexer.SearchResult
+			translatedResults, err = r.config.Indexer.Search(ctx, translated, limit*2, opts)
+			debugQuery.Results = len(translatedResults)
+			for _, result := range translatedResults {
+				existing, ok := byChunk[result.ChunkID]
+				if !ok {
+					order = append(order, result.ChunkID)
+				}
+				if !ok || result.Score > existing.Score {
+					byChunk[result.ChunkID] = result
+				}
+			}
+		}
+
+		if err != nil {
+			fmt.Printf("Warning: Cross-lingual search in %s failed: %v\n", language, err)
+			debugQuery.Error = err.Error()
+		}
+		if opts.Debug != nil && (debugQuery.Text != "" || debugQuery.Error != "") {
+			opts.Debug.Queries = append(opts.Debug.Queries, debugQuery)
+		}
+	}
+
+	merged := make([]*indexer.SearchResult, 0, len(order))
+	for _, chunkID := range order {
+		merged = append(merged, byChunk[chunkID])
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Score > merged[j].Score
+	})
+
+	return merged
+}
+
+// boostLanguage ranks results in the given language above comparable
+// results in other languages
+func boostLanguage(results []*indexer.SearchResult, language string) {
+	for _, result := range results {
+		if attributes, ok := result.Metadata["attributes"].(map[string]string); ok && attributes["language"] == language {
+			result.Score *= languageBoost
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+}
+
 // SetReranker sets the reranker for post-processing results
 func (r *hybridRetriever) SetReranker(reranker Reranker) {
 	r.reranker = reranker
@@ -0,0 +1,190 @@
+package retriever
+
+import (
+	"ai-search/internal/indexer"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SourceKey is the result metadata key holding the label of the source a
+// routed result came from
+const SourceKey = "source"
+
+// Source is a collection a router can search
+type Source struct {
+	Label       string   // Names the source in results, e.g. "code"
+	Description string   // What the collection holds, for LLM classification
+	Keywords    []string // Words and phrases of queries routed to it
+	Default     bool     // Searched when no source matches a query
+	Retriever   Retriever
+}
+
+// QueryClassifier picks the labels of the sources a query should search
+type QueryClassifier interface {
+	Classify(ctx context.Context, query string, sources []Source) ([]string, error)
+}
+
+// RouterConfig holds router configuration
+type RouterConfig struct {
+	Sources []Source
+
+	// Classifier routes queries, KeywordClassifier when nil. Queries it
+	// fails on or routes nowhere search the default sources, or every
+	// source when none is marked default.
+	Classifier QueryClassifier
+}
+
+// router implements the Retriever interface over several collections
+type router struct {
+	config   RouterConfig
+	defaults []string
+}
+
+// NewRouter creates a retriever searching the sources a query is about and
+// merging their results by score, each labelled with its source
+func NewRouter(config RouterConfig) Retriever {
+	if config.Classifier == nil {
+		config.Classifier = KeywordClassifier{}
+	}
+
+	r := &router{config: config}
+	for _, source := range config.Sources {
+		if source.Default {
+			r.defaults = append(r.defaults, source.Label)
+		}
+	}
+	if len(r.defaults) == 0 {
+		for _, source := range config.Sources {
+			r.defaults = append(r.defaults, source.Label)
+		}
+	}
+	return r
+}
+
+// Retrieve searches the sources the query is routed to
+func (r *router) Retrieve(ctx context.Context, query string, limit int, opts indexer.SearchOptions) ([]*indexer.SearchResult, error) {
+	labels, err := r.config.Classifier.Classify(ctx, query, r.config.Sources)
+	if err != nil {
+		fmt.Printf("Warning: Query routing failed, searching default sources: %v\n", err)
+	}
+	if len(labels) == 0 {
+		labels = r.defaults
+	}
+
+	// Sources are searched at once, each recording its own debug queries
+	type sourceResults struct {
+		results []*indexer.SearchResult
+		debug   *indexer.SearchDebug
+		err     error
+	}
+	selected := r.sources(labels)
+	found := make([]sourceResults, len(selected))
+	var wg sync.WaitGroup
+	for i, source := range selected {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sourceOpts := opts
+			if opts.Debug != nil {
+				sourceOpts.Debug = &indexer.SearchDebug{}
+			}
+			results, err := source.Retriever.Retrieve(ctx, query, limit, sourceOpts)
+			found[i] = sourceResults{results: results, debug: sourceOpts.Debug, err: err}
+		}()
+	}
+	wg.Wait()
+
+	// A failing source only loses its own results
+	var merged []*indexer.SearchResult
+	var failed []string
+	for i, source := range selected {
+		if found[i].err != nil {
+			fmt.Printf("Warning: Search of source %s failed: %v\n", source.Label, found[i].err)
+			failed = append(failed, source.Label)
+		}
+		if opts.Debug != nil {
+			for _, debugQuery := range found[i].debug.Queries {
+				debugQuery.Source = source.Label
+				opts.Debug.Queries = append(opts.Debug.Queries, debugQuery)
+			}
+			if found[i].err != nil {
+				opts.Debug.Queries = append(opts.Debug.Queries, indexer.DebugQuery{Text: query, Source: source.Label, Error: found[i].err.Error()})
+			}
+		}
+		for _, result := range found[i].results {
+			if result.Metadata == nil {
+				result.Metadata = make(map[string]interface{})
+			}
+			result.Metadata[SourceKey] = source.Label
+			merged = append(merged, result)
+		}
+	}
+	if len(failed) > 0 && len(failed) == len(selected) {
+		return nil, fmt.Errorf("failed to search %s", strings.Join(failed, ", "))
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Score > merged[j].Score
+	})
+	if len(merged) > limit {
+		merged = merged[:limit]
+	}
+	return merged, nil
+}
+
+// sources returns the sources with the given labels in configuration order
+func (r *router) sources(labels []string) []Source {
+	var selected []Source
+	for _, source := range r.config.Sources {
+		for _, label := range labels {
+			if source.Label == label {
+				selected = append(selected, source)
+				break
+			}
+		}
+	}
+	return selected
+}
+
+// SetReranker sets the reranker of every source
+func (r *router) SetReranker(reranker Reranker) {
+	for _, source := range r.config.Sources {
+		source.Retriever.SetReranker(reranker)
+	}
+}
+
+// KeywordClassifier routes a query to the sources with a keyword in it,
+// matched case-insensitively against whole words or phrases
+type KeywordClassifier struct{}
+
+// Classify returns the labels of the sources whose keywords the query uses
+func (KeywordClassifier) Classify(ctx context.Context, query string, sources []Source) ([]string, error) {
+	words := " " + strings.Join(strings.FieldsFunc(strings.ToLower(query), isWordSeparator), " ") + " "
+
+	var labels []string
+	for _, source := range sources {
+		for _, keyword := range source.Keywords {
+			keyword = strings.Join(strings.FieldsFunc(strings.ToLower(keyword), isWordSeparator), " ")
+			if keyword != "" && strings.Contains(words, " "+keyword+" ") {
+				labels = append(labels, source.Label)
+				break
+			}
+		}
+	}
+	return labels, nil
+}
+
+// isWordSeparator splits lower-cased queries and keywords into words,
+// keeping identifiers such as snake_case, c# and c++ whole
+func isWordSeparator(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r >= 0x80:
+		return false
+	case r == '_' || r == '#' || r == '+':
+		return false
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+package retriever
+
+import (
+	"ai-search/internal/indexer"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SourceTypeAttribute is the document attribute naming the kind of source a
+// document was indexed from, such as "web", "git" or "confluence". Documents
+// without it are taken as DefaultSourceType.
+const (
+	SourceTypeAttribute = "source_type"
+	DefaultSourceType   = "web"
+)
+
+// ParseSourceWeights parses source type weights given as type=weight,
+// comma-separated, as in "web=1,git=1.5,confluence=0.8"
+func ParseSourceWeights(spec string) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	for _, entry := range strings.Split(spec, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		sourceType, rawWeight, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid source weight %q: expected type=weight", entry)
+		}
+		weight, err := strconv.ParseFloat(strings.TrimSpace(rawWeight), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source weight %q: %w", entry, err)
+		}
+		if err := ValidateSourceWeight(strings.TrimSpace(sourceType), weight); err != nil {
+			return nil, err
+		}
+		weights[strings.TrimSpace(sourceType)] = weight
+	}
+	return weights, nil
+}
+
+// ValidateSourceWeight checks that a source type is named and its weight
+// is a non-negative number
+func ValidateSourceWeight(sourceType string, weight float64) error {
+	if sourceType == "" {
+		return fmt.Errorf("source weight %g names no source type", weight)
+	}
+	if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+		return fmt.Errorf("invalid weight %g for source type %s: expected a non-negative number", weight, sourceType)
+	}
+	return nil
+}
+
+// weightSources multiplies the score of each result by the weight of its
+// source type, 1 for types without one, and orders the results by score
+// again. Weights override defaults type by type.
+func weightSources(results []*indexer.SearchResult, defaults, weights map[string]float64) {
+	if len(defaults) == 0 && len(weights) == 0 {
+		return
+	}
+
+	for _, result := range results {
+		sourceType := DefaultSourceType
+		if attributes, ok := result.Metadata["attributes"].(map[string]string); ok && attributes[SourceTypeAttribute] != "" {
+			sourceType = attributes[SourceTypeAttribute]
+		}
+		weight, ok := weights[sourceType]
+		if !ok {
+			weight, ok = defaults[sourceType]
+		}
+		if ok {
+			result.Score *= float32(weight)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+}
@@ -0,0 +1,233 @@
+package scheduler
+
+import (
+	"ai-search/internal/crawler"
+	"ai-search/internal/store"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Scheduler defines the interface for recrawling stored documents
+type Scheduler interface {
+	// RecrawlDue re-fetches the documents whose recrawl interval has passed,
+	// most searched first, up to the batch size
+	RecrawlDue(ctx context.Context) (*Result, error)
+
+	// Run recrawls due documents on the given interval until the context
+	// is cancelled
+	Run(ctx context.Context, interval time.Duration)
+}
+
+// Policy sets how often documents of a domain are recrawled
+type Policy struct {
+	Domain   string // Host, also matching its subdomains
+	Interval time.Duration
+}
+
+// Reindexer replaces a stored document whose content changed with the
+// freshly fetched page
+type Reindexer func(ctx context.Context, doc *store.RecrawlCandidate, page *crawler.Page) error
+
+// Config holds recrawl configuration
+type Config struct {
+	Store           store.Store
+	Crawler         crawler.Crawler
+	Reindex         Reindexer
+	DefaultInterval time.Duration // Used for domains without a policy
+	Policies        []Policy
+	BatchSize       int // Documents recrawled per pass
+}
+
+// Result summarises a recrawl pass
+type Result struct {
+	Due       int // Documents past their interval, including those left for the next pass
+	Checked   int
+	Unchanged int
+	Updated   int
+	Failed    int
+}
+
+// scheduler implements the Scheduler interface
+type scheduler struct {
+	config Config
+}
+
+// NewScheduler creates a new scheduler instance
+func NewScheduler(config Config) Scheduler {
+	if config.DefaultInterval == 0 {
+		config.DefaultInterval = 24 * time.Hour
+	}
+	if config.BatchSize == 0 {
+		config.BatchSize = 100
+	}
+
+	return &scheduler{
+		config: config,
+	}
+}
+
+// ParsePolicies parses per-domain recrawl intervals from a comma-separated
+// list of domain=duration entries, e.g. "docs.example.com=6h,example.org=168h"
+func ParsePolicies(spec string) ([]Policy, error) {
+	var policies []Policy
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		domain, value, ok := strings.Cut(entry, "=")
+		if !ok || domain == "" {
+			return nil, fmt.Errorf("invalid recrawl policy %q: expected domain=interval", entry)
+		}
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid interval for recrawl policy %q", entry)
+		}
+
+		policies = append(policies, Policy{Domain: strings.ToLower(domain), Interval: interval})
+	}
+	return policies, nil
+}
+
+// interval returns the recrawl interval of a host, from the policy of the
+// most specific matching domain
+func (s *scheduler) interval(host string) time.Duration {
+	host = strings.ToLower(host)
+	interval, matched := s.config.DefaultInterval, ""
+	for _, policy := range s.config.Policies {
+		if host != policy.Domain && !strings.HasSuffix(host, "."+policy.Domain) {
+			continue
+		}
+		if len(policy.Domain) > len(matched) {
+			interval, matched = policy.Interval, policy.Domain
+		}
+	}
+	return interval
+}
+
+// dueCandidate is a document past its recrawl interval
+type dueCandidate struct {
+	doc     *store.RecrawlCandidate
+	pageURL *url.URL
+	overdue float64 // Age as a multiple of the interval
+}
+
+// due returns the documents past their interval. Documents users see most
+// in search results come first so the content they read stays freshest,
+// then the most overdue.
+func (s *scheduler) due(ctx context.Context) ([]*dueCandidate, error) {
+	candidates, err := s.config.Store.ListRecrawlCandidates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var due []*dueCandidate
+	for _, doc := range candidates {
+		pageURL, err := url.Parse(doc.URL)
+		if err != nil || pageURL.Host == "" {
+			continue
+		}
+
+		interval := s.interval(pageURL.Hostname())
+		if doc.Age < interval {
+			continue
+		}
+		due = append(due, &dueCandidate{
+			doc:     doc,
+			pageURL: pageURL,
+			overdue: float64(doc.Age) / float64(interval),
+		})
+	}
+
+	sort.SliceStable(due, func(i, j int) bool {
+		if due[i].doc.Impressions != due[j].doc.Impressions {
+			return due[i].doc.Impressions > due[j].doc.Impressions
+		}
+		return due[i].overdue > due[j].overdue
+	})
+	return due, nil
+}
+
+// RecrawlDue re-fetches the documents whose recrawl interval has passed
+func (s *scheduler) RecrawlDue(ctx context.Context) (*Result, error) {
+	due, err := s.due(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Due: len(due)}
+	if len(due) > s.config.BatchSize {
+		due = due[:s.config.BatchSize]
+	}
+
+	for _, candidate := range due {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		result.Checked++
+
+		changed, err := s.recrawl(ctx, candidate)
+		switch {
+		case err != nil:
+			log.Printf("Recrawl of %s failed: %v", candidate.doc.URL, err)
+			result.Failed++
+		case changed:
+			result.Updated++
+		default:
+			result.Unchanged++
+		}
+	}
+
+	return result, nil
+}
+
+// recrawl re-fetches one document, reindexing it only when its content
+// hash changed, and reports whether it did
+func (s *scheduler) recrawl(ctx context.Context, candidate *dueCandidate) (bool, error) {
+	doc := candidate.doc
+
+	page, err := s.config.Crawler.Fetch(ctx, candidate.pageURL)
+	if err != nil {
+		// Wait a full interval before trying an unreachable page again
+		if markErr := s.config.Store.MarkCrawled(ctx, doc.DocumentID, crawler.FetchStatus(err)); markErr != nil {
+			log.Printf("Failed to mark %s crawled: %v", doc.DocumentID, markErr)
+		}
+		return false, err
+	}
+
+	if page.ContentHash == doc.ContentHash {
+		return false, s.config.Store.MarkCrawled(ctx, doc.DocumentID, http.StatusOK)
+	}
+
+	return true, s.config.Reindex(ctx, doc, page)
+}
+
+// Run recrawls due documents on the given interval until the context is cancelled
+func (s *scheduler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		result, err := s.RecrawlDue(ctx)
+		if err != nil {
+			log.Printf("Recrawl error: %v", err)
+		}
+		if result != nil && result.Checked > 0 {
+			log.Printf("Recrawled %d of %d due documents: %d updated, %d unchanged, %d failed",
+				result.Checked, result.Due, result.Updated, result.Unchanged, result.Failed)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
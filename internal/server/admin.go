@@ -0,0 +1,310 @@
+package server
+
+import (
+	"ai-search/internal/dedup"
+	"ai-search/internal/events"
+	"ai-search/internal/retention"
+	"ai-search/internal/store"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DuplicatesResponse represents the duplicate cluster report
+type DuplicatesResponse struct {
+	Clusters  []*dedup.Cluster `json:"clusters"`
+	Total     int              `json:"total"`
+	Documents int              `json:"documents"`
+	Time      int64            `json:"time_ms"`
+}
+
+// ResolveDuplicatesRequest represents a merge or delete action on a cluster
+type ResolveDuplicatesRequest struct {
+	Action string   `json:"action"` // "merge" or "delete"
+	Keep   string   `json:"keep"`
+	Remove []string `json:"remove"`
+}
+
+// ResolveDuplicatesResponse represents the outcome of a resolve action
+type ResolveDuplicatesResponse struct {
+	Action  string   `json:"action"`
+	Keep    string   `json:"keep"`
+	Removed []string `json:"removed"`
+	Failed  []string `json:"failed,omitempty"`
+}
+
+// requireAdmin wraps a handler with bearer token authentication
+func (s *httpServer) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Admin endpoints are disabled unless a token is configured
+		if s.config.AdminToken == "" {
+			http.Error(w, "Admin API disabled", http.StatusNotFound)
+			return
+		}
+
+		if !s.isAdmin(r) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, unrestricted(r))
+	}
+}
+
+// isAdmin reports whether a request bears the admin token, comparing it in
+// constant time so its bytes cannot be guessed from response times
+func (s *httpServer) isAdmin(r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return s.config.AdminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.config.AdminToken)) == 1
+}
+
+// handleDuplicates reports clusters of near-duplicate documents
+func (s *httpServer) handleDuplicates(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.config.Store == nil || s.config.Dedup == nil {
+		http.Error(w, "Deduplication not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	// Fingerprints are computed when documents are indexed; those of
+	// documents indexed before are computed once and saved
+	docs, err := s.config.Store.ListFingerprints(r.Context())
+	if err != nil {
+		log.Printf("Duplicate report error: %v", err)
+		http.Error(w, "Failed to list documents", http.StatusInternalServerError)
+		return
+	}
+
+	items := make([]*dedup.Item, 0, len(docs))
+	for _, doc := range docs {
+		if doc.Fingerprint == nil {
+			fingerprint, err := s.backfillFingerprint(r.Context(), doc.ID)
+			if err != nil {
+				log.Printf("Duplicate report error: %v", err)
+				http.Error(w, "Failed to fingerprint documents", http.StatusInternalServerError)
+				return
+			}
+			doc.Fingerprint = &fingerprint
+		}
+		items = append(items, &dedup.Item{
+			ID:          doc.ID,
+			URL:         doc.URL,
+			Title:       doc.Title,
+			Fingerprint: *doc.Fingerprint,
+			Length:      doc.Length,
+		})
+	}
+
+	clusters := s.config.Dedup.Clusters(items)
+
+	response := DuplicatesResponse{
+		Clusters:  clusters,
+		Total:     len(clusters),
+		Documents: len(docs),
+		Time:      time.Since(startTime).Milliseconds(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// backfillFingerprint computes and saves the fingerprint of a document
+// indexed without one
+func (s *httpServer) backfillFingerprint(ctx context.Context, id string) (uint64, error) {
+	doc, err := s.config.Store.GetDocument(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	fingerprint := s.config.Dedup.Fingerprint(doc.Content)
+	if err := s.config.Store.SetFingerprint(ctx, id, fingerprint); err != nil {
+		return 0, err
+	}
+	return fingerprint, nil
+}
+
+// handleResolveDuplicates merges or deletes duplicate documents
+func (s *httpServer) handleResolveDuplicates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.config.Store == nil {
+		http.Error(w, "Deduplication not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req ResolveDuplicatesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.Action != "merge" && req.Action != "delete" {
+		http.Error(w, "Action must be 'merge' or 'delete'", http.StatusBadRequest)
+		return
+	}
+	if req.Keep == "" || len(req.Remove) == 0 {
+		http.Error(w, "Both 'keep' and 'remove' are required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+
+	keep, err := s.config.Store.GetDocument(ctx, req.Keep)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Document not found: %s", req.Keep), http.StatusNotFound)
+		return
+	}
+
+	response := ResolveDuplicatesResponse{
+		Action:  req.Action,
+		Keep:    req.Keep,
+		Removed: []string{},
+	}
+
+	var mirrorURLs []string
+	for _, id := range req.Remove {
+		if id == req.Keep {
+			continue
+		}
+
+		removed, err := s.config.Store.GetDocument(ctx, id)
+		if err != nil {
+			log.Printf("Resolve duplicates: %v", err)
+			response.Failed = append(response.Failed, id)
+			continue
+		}
+
+		// Removed duplicates are soft-deleted so a wrong resolution can be restored
+		if err := retention.SoftDelete(ctx, s.config.Store, s.config.Indexer, id); err != nil {
+			log.Printf("Resolve duplicates: failed to delete %s from store: %v", id, err)
+			response.Failed = append(response.Failed, id)
+			continue
+		}
+
+		mirrorURLs = append(mirrorURLs, removed.URL)
+		response.Removed = append(response.Removed, id)
+	}
+
+	if len(response.Removed) > 0 {
+		s.publish(ctx, events.DocumentDeleted, response.Removed...)
+	}
+
+	// Merging records the removed URLs as mirrors of the kept document
+	if req.Action == "merge" && len(mirrorURLs) > 0 {
+		if keep.Meta == nil {
+			keep.Meta = make(map[string]interface{})
+		}
+		if existing, ok := keep.Meta["mirror_urls"].([]interface{}); ok {
+			for _, u := range existing {
+				if str, ok := u.(string); ok {
+					mirrorURLs = append(mirrorURLs, str)
+				}
+			}
+		}
+		keep.Meta["mirror_urls"] = mirrorURLs
+
+		if err := s.config.Store.SaveDocument(ctx, keep); err != nil {
+			log.Printf("Resolve duplicates: failed to update %s: %v", keep.ID, err)
+			http.Error(w, "Failed to update kept document", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DocumentActionResponse represents the outcome of a delete or restore
+type DocumentActionResponse struct {
+	DocumentID string `json:"document_id"`
+	Action     string `json:"action"`
+}
+
+// DocumentResponse describes a stored document without its content
+type DocumentResponse struct {
+	DocumentID string                 `json:"document_id"`
+	URL        string                 `json:"url"`
+	Title      string                 `json:"title,omitempty"`
+	Meta       map[string]interface{} `json:"meta,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+	UpdatedAt  time.Time              `json:"updated_at"`
+	DeletedAt  *time.Time             `json:"deleted_at,omitempty"`
+	Provenance *store.Provenance      `json:"provenance,omitempty"`
+}
+
+// handleDocument describes (GET /api/admin/documents/{id}), soft-deletes
+// (DELETE /api/admin/documents/{id}) or restores (POST
+// /api/admin/documents/{id}/restore) a document
+func (s *httpServer) handleDocument(w http.ResponseWriter, r *http.Request) {
+	if s.config.Store == nil {
+		http.Error(w, "Document store not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/admin/documents/")
+	id, action := path, "delete"
+	if strings.HasSuffix(path, "/restore") {
+		id, action = strings.TrimSuffix(path, "/restore"), "restore"
+	}
+	if id == "" || strings.Contains(id, "/") {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	if action == "delete" && r.Method == "GET" {
+		doc, err := s.config.Store.GetDocument(r.Context(), id)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Document not found: %s", id), http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(DocumentResponse{
+			DocumentID: doc.ID,
+			URL:        doc.URL,
+			Title:      doc.Title,
+			Meta:       doc.Meta,
+			CreatedAt:  doc.CreatedAt,
+			UpdatedAt:  doc.UpdatedAt,
+			DeletedAt:  doc.DeletedAt,
+			Provenance: doc.Provenance,
+		})
+		return
+	}
+
+	var err error
+	var eventType string
+	switch {
+	case action == "delete" && r.Method == "DELETE":
+		err = retention.SoftDelete(r.Context(), s.config.Store, s.config.Indexer, id)
+		eventType = events.DocumentDeleted
+	case action == "restore" && r.Method == "POST":
+		err = retention.Restore(r.Context(), s.config.Store, s.config.Indexer, id)
+		eventType = events.DocumentRestored
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		log.Printf("Document %s error: %v", action, err)
+		http.Error(w, fmt.Sprintf("Failed to %s document: %s", action, id), http.StatusNotFound)
+		return
+	}
+	s.publish(r.Context(), eventType, id)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(DocumentActionResponse{DocumentID: id, Action: action})
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"ai-search/internal/indexer"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultAnswerSources is how many search results ground an answer
+const defaultAnswerSources = 5
+
+// AnswerResponse represents a generated answer with its sources
+type AnswerResponse struct {
+	Query    string                  `json:"query"`
+	Answer   string                  `json:"answer"`
+	Language string                  `json:"language,omitempty"`
+	Version  string                  `json:"version,omitempty"`
+	Sources  []*SearchResultResponse `json:"sources"`
+	Time     int64                   `json:"time_ms"`
+	Debug    *indexer.SearchDebug    `json:"debug,omitempty"`
+}
+
+// handleAnswer answers a question from the top search results
+func (s *httpServer) handleAnswer(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+
+	if r.Method != "GET" && r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.config.LLM == nil {
+		http.Error(w, "Answers not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	req, err := parseSearchRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Query == "" {
+		http.Error(w, "Missing query", http.StatusBadRequest)
+		return
+	}
+	if req.Limit <= 0 || req.Limit > 20 {
+		req.Limit = defaultAnswerSources
+	}
+
+	opts, version, language := s.searchOptions(req)
+	results, err := s.retrieve(r.Context(), req.Query, req.Limit, opts)
+	if err != nil {
+		log.Printf("Answer search error: %v", err)
+		http.Error(w, "Search failed", http.StatusInternalServerError)
+		return
+	}
+
+	answer, err := s.config.LLM.Generate(r.Context(), buildAnswerPrompt(req.Query, results, language))
+	if err != nil {
+		log.Printf("Answer generation error: %v", err)
+		http.Error(w, "Answer generation failed", http.StatusBadGateway)
+		return
+	}
+
+	sources := toResultResponses(results)
+	s.addProvenance(r.Context(), sources)
+	if language != "" {
+		localizeResults(sources, s.languageVariants(r.Context(), sources), language)
+	}
+	s.recordImpressions(sources)
+	if sources == nil {
+		sources = []*SearchResultResponse{}
+	}
+
+	response := AnswerResponse{
+		Query:    req.Query,
+		Answer:   strings.TrimSpace(answer),
+		Language: language,
+		Version:  version,
+		Sources:  sources,
+		Time:     time.Since(startTime).Milliseconds(),
+		Debug:    opts.Debug,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// buildAnswerPrompt asks the LLM to answer from numbered sources, in the
+// requested language or else in the language of the question
+func buildAnswerPrompt(query string, results []*indexer.SearchResult, language string) string {
+	var builder strings.Builder
+
+	builder.WriteString("Answer the question using only the sources below. Cite sources by their number, like [1]. ")
+	builder.WriteString("If the sources do not contain the answer, say so.\n")
+	if language != "" {
+		builder.WriteString(fmt.Sprintf("Write the answer in the language with ISO 639-1 code %q, even if the question or sources are in another language.\n\n", language))
+	} else {
+		builder.WriteString("Write the answer in the language of the question.\n\n")
+	}
+
+	builder.WriteString("Sources:\n")
+	for i, result := range results {
+		title, _ := result.Metadata["title"].(string)
+		builder.WriteString(fmt.Sprintf("[%d] %s\n%s\n\n", i+1, title, result.Text))
+	}
+
+	builder.WriteString(fmt.Sprintf("Question: %s\n", query))
+	return builder.String()
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"ai-search/internal/events"
+	"ai-search/internal/indexer"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// responseCache caches search responses until they expire or an index
+// mutation invalidates them
+type responseCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mutex      sync.Mutex
+	entries    map[string]*cacheEntry
+	byDocument map[string]map[string]bool // Document ID to cache keys
+	generation uint64                     // Incremented on every invalidation
+}
+
+// cacheEntry is a cached response and the documents it references
+type cacheEntry struct {
+	response  SearchResponse
+	documents []string
+	expires   time.Time
+}
+
+// newResponseCache creates a cache, returning nil when ttl disables it
+func newResponseCache(ttl time.Duration, maxEntries int) *responseCache {
+	if ttl <= 0 {
+		return nil
+	}
+	if maxEntries <= 0 {
+		maxEntries = 1000
+	}
+
+	return &responseCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*cacheEntry),
+		byDocument: make(map[string]map[string]bool),
+	}
+}
+
+// cacheKey identifies a search by its query, limit, filters, source
+// weights, language, whether results carry snippets and the caller's access
+func cacheKey(query string, limit int, filters map[string]string, sourceWeights map[string]float64, language string, snippets bool, access *indexer.Access) string {
+	keys := make([]string, 0, len(filters))
+	for key := range filters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d\x00%t\x00%s\x00%s", limit, snippets, language, query)
+	for _, key := range keys {
+		fmt.Fprintf(&b, "\x00%s=%s", key, filters[key])
+	}
+	sourceTypes := make([]string, 0, len(sourceWeights))
+	for sourceType := range sourceWeights {
+		sourceTypes = append(sourceTypes, sourceType)
+	}
+	sort.Strings(sourceTypes)
+	for _, sourceType := range sourceTypes {
+		fmt.Fprintf(&b, "\x00weight:%s=%g", sourceType, sourceWeights[sourceType])
+	}
+	if access != nil {
+		principals := append([]string(nil), access.Principals...)
+		sort.Strings(principals)
+		fmt.Fprintf(&b, "\x00acl=%s", strings.Join(principals, ","))
+	}
+	return b.String()
+}
+
+// get returns a cached response that has not expired
+func (c *responseCache) get(key string) (SearchResponse, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return SearchResponse{}, false
+	}
+	if time.Now().After(entry.expires) {
+		c.remove(key)
+		return SearchResponse{}, false
+	}
+	return entry.response, true
+}
+
+// currentGeneration returns the invalidation generation; pass it to put
+// so that responses computed before an invalidation are not cached
+func (c *responseCache) currentGeneration() uint64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.generation
+}
+
+// put caches a response computed during the given generation
+func (c *responseCache) put(key string, response SearchResponse, generation uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	if _, exists := c.entries[key]; exists {
+		c.remove(key)
+	}
+	if len(c.entries) >= c.maxEntries {
+		c.evict()
+	}
+
+	entry := &cacheEntry{
+		response: response,
+		expires:  time.Now().Add(c.ttl),
+	}
+	for _, result := range response.Results {
+		if c.byDocument[result.DocumentID] == nil {
+			c.byDocument[result.DocumentID] = make(map[string]bool)
+		}
+		c.byDocument[result.DocumentID][key] = true
+		entry.documents = append(entry.documents, result.DocumentID)
+	}
+	c.entries[key] = entry
+}
+
+// evict drops expired entries, or an arbitrary entry if none expired
+func (c *responseCache) evict() {
+	now := time.Now()
+	for key, entry := range c.entries {
+		if now.After(entry.expires) {
+			c.remove(key)
+		}
+	}
+	for key := range c.entries {
+		if len(c.entries) < c.maxEntries {
+			break
+		}
+		c.remove(key)
+	}
+}
+
+// remove deletes an entry and its document references; the caller holds the lock
+func (c *responseCache) remove(key string) {
+	entry, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	for _, docID := range entry.documents {
+		delete(c.byDocument[docID], key)
+		if len(c.byDocument[docID]) == 0 {
+			delete(c.byDocument, docID)
+		}
+	}
+	delete(c.entries, key)
+}
+
+// invalidate applies an index mutation event. Removed documents only evict
+// the responses that contain them; new or restored documents may change
+// any ranking, so they clear the cache.
+func (c *responseCache) invalidate(event events.Event) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.generation++
+	switch event.Type {
+	case events.DocumentDeleted, events.DocumentPurged:
+		for _, docID := range event.DocumentIDs {
+			for key := range c.byDocument[docID] {
+				c.remove(key)
+			}
+		}
+	default:
+		c.entries = make(map[string]*cacheEntry)
+		c.byDocument = make(map[string]map[string]bool)
+	}
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ai-search/internal/store"
+)
+
+// defaultContextWindow and maxContextWindow bound neighbors per side
+const (
+	defaultContextWindow = 2
+	maxContextWindow     = 20
+)
+
+// ChunkContextResponse represents a chunk with its neighbors
+type ChunkContextResponse struct {
+	ChunkID    string                  `json:"chunk_id"`
+	DocumentID string                  `json:"document_id"`
+	Title      string                  `json:"title,omitempty"`
+	URL        string                  `json:"url,omitempty"`
+	Provenance *store.Provenance       `json:"provenance,omitempty"`
+	Window     int                     `json:"window"`
+	Chunks     []*ContextChunkResponse `json:"chunks"`
+	Time       int64                   `json:"time_ms"`
+}
+
+// ContextChunkResponse represents one chunk in document order
+type ContextChunkResponse struct {
+	ChunkID  string `json:"chunk_id"`
+	Position int    `json:"position"` // Index of the chunk within its document
+	Text     string `json:"text"`
+	StartPos int    `json:"start_pos"`
+	EndPos   int    `json:"end_pos"`
+	Target   bool   `json:"target,omitempty"` // The requested chunk
+}
+
+// handleChunkContext returns a chunk plus window neighbors on each side
+// (GET /api/chunks/{id}/context?window=2)
+func (s *httpServer) handleChunkContext(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.config.Store == nil {
+		http.Error(w, "Document store not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/chunks/")
+	if !strings.HasSuffix(path, "/context") {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	chunkID := strings.TrimSuffix(path, "/context")
+	if chunkID == "" || strings.Contains(chunkID, "/") {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	window := defaultContextWindow
+	if windowStr := r.URL.Query().Get("window"); windowStr != "" {
+		parsed, err := strconv.Atoi(windowStr)
+		if err != nil || parsed < 0 {
+			http.Error(w, "Invalid window", http.StatusBadRequest)
+			return
+		}
+		window = parsed
+	}
+	if window > maxContextWindow {
+		window = maxContextWindow
+	}
+
+	ctx := r.Context()
+
+	docID, err := s.config.Store.GetChunkDocumentID(ctx, chunkID)
+	if err != nil {
+		http.Error(w, "Chunk not found", http.StatusNotFound)
+		return
+	}
+
+	// Chunks of soft-deleted documents, and of documents the caller's
+	// access does not cover, are hidden like in search
+	doc, err := s.config.Store.GetDocument(ctx, docID)
+	if err != nil || doc.DeletedAt != nil || !s.access(ctx).Allows(documentACL(doc)) {
+		http.Error(w, "Chunk not found", http.StatusNotFound)
+		return
+	}
+
+	chunks, err := s.config.Store.GetChunks(ctx, docID)
+	if err != nil {
+		log.Printf("Chunk context error: %v", err)
+		http.Error(w, "Failed to load chunks", http.StatusInternalServerError)
+		return
+	}
+
+	target := -1
+	for i, chunk := range chunks {
+		if chunk.ID == chunkID {
+			target = i
+			break
+		}
+	}
+	if target < 0 {
+		http.Error(w, "Chunk not found", http.StatusNotFound)
+		return
+	}
+
+	from := max(target-window, 0)
+	to := min(target+window+1, len(chunks))
+
+	response := ChunkContextResponse{
+		ChunkID:    chunkID,
+		DocumentID: docID,
+		Title:      doc.Title,
+		URL:        doc.URL,
+		Provenance: doc.Provenance,
+		Window:     window,
+		Chunks:     make([]*ContextChunkResponse, 0, to-from),
+	}
+	for i := from; i < to; i++ {
+		response.Chunks = append(response.Chunks, &ContextChunkResponse{
+			ChunkID:  chunks[i].ID,
+			Position: i,
+			Text:     chunks[i].Text,
+			StartPos: chunks[i].StartPos,
+			EndPos:   chunks[i].EndPos,
+			Target:   i == target,
+		})
+	}
+	response.Time = time.Since(startTime).Milliseconds()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
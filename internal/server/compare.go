@@ -0,0 +1,138 @@
+package server
+
+import (
+	"ai-search/internal/indexer"
+	"ai-search/internal/retriever"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CompareResponse holds the results of a query in the production and the
+// shadow collections side by side
+type CompareResponse struct {
+	Query      string          `json:"query"`
+	Production *CompareResults `json:"production"`
+	Shadow     *CompareResults `json:"shadow"`
+
+	// Overlap is the share of the documents found by either collection
+	// that both found, 1 when they return the same documents
+	Overlap float64 `json:"overlap"`
+}
+
+// CompareResults are the results of one collection
+type CompareResults struct {
+	Results []*SearchResultResponse `json:"results"`
+	Total   int                     `json:"total"`
+	Time    int64                   `json:"time_ms"`
+	Error   string                  `json:"error,omitempty"`
+	Debug   *indexer.SearchDebug    `json:"debug,omitempty"`
+}
+
+// handleCompare runs a search against the production and the shadow
+// collections at once, for validating new chunking or embedding settings
+// on real data before cutover. It is never cached, logged or counted as
+// traffic.
+func (s *httpServer) handleCompare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.config.ShadowRetriever == nil {
+		http.Error(w, "Shadow collection not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	req, err := parseSearchRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 10
+	}
+	if req.Limit > 100 {
+		req.Limit = 100
+	}
+	opts, _, _ := s.searchOptions(req)
+	snippets := s.config.Snippets
+	if req.Snippets != nil {
+		snippets = *req.Snippets
+	}
+
+	var production, shadow *CompareResults
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		production = s.compareSide(r.Context(), s.retriever, req.Query, req.Limit, opts, snippets)
+	}()
+	go func() {
+		defer wg.Done()
+		shadow = s.compareSide(r.Context(), s.config.ShadowRetriever, req.Query, req.Limit, opts, snippets)
+	}()
+	wg.Wait()
+
+	response := CompareResponse{
+		Query:      req.Query,
+		Production: production,
+		Shadow:     shadow,
+		Overlap:    documentOverlap(production.Results, shadow.Results),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// compareSide runs a search with one retriever, recording its queries
+// apart from the other side's when debugging
+func (s *httpServer) compareSide(ctx context.Context, r retriever.Retriever, query string, limit int, opts indexer.SearchOptions, snippets bool) *CompareResults {
+	startTime := time.Now()
+	if opts.Debug != nil {
+		opts.Debug = &indexer.SearchDebug{}
+	}
+
+	side := &CompareResults{Results: []*SearchResultResponse{}, Debug: opts.Debug}
+	results, err := s.retrieveFrom(ctx, r, query, limit, opts)
+	if err != nil {
+		log.Printf("Compare search error: %v", err)
+		side.Error = "Search failed"
+	} else if responseResults := toResultResponses(results); responseResults != nil {
+		side.Results = responseResults
+	}
+	if snippets {
+		applySnippets(side.Results, query, s.config.SnippetLength)
+	}
+	side.Total = len(side.Results)
+	side.Time = time.Since(startTime).Milliseconds()
+	return side
+}
+
+// documentOverlap returns the Jaccard similarity of the documents of two
+// result lists, 1 when both are empty
+func documentOverlap(a, b []*SearchResultResponse) float64 {
+	inA := make(map[string]bool)
+	for _, result := range a {
+		inA[result.DocumentID] = true
+	}
+	union := len(inA)
+	shared := 0
+	seen := make(map[string]bool)
+	for _, result := range b {
+		if seen[result.DocumentID] {
+			continue
+		}
+		seen[result.DocumentID] = true
+		if inA[result.DocumentID] {
+			shared++
+		} else {
+			union++
+		}
+	}
+	if union == 0 {
+		return 1
+	}
+	return float64(shared) / float64(union)
+}
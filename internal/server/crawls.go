@@ -0,0 +1,94 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"ai-search/internal/store"
+)
+
+// CrawlUpdateRequest changes a running crawl (PATCH /api/crawls/{id}): it
+// adds seeds, denies URL patterns or lowers the rate limit. A crawl can
+// only be narrowed or slowed this way; a rate limit above the crawl's
+// current one is ignored.
+type CrawlUpdateRequest struct {
+	Seeds        []string `json:"seeds,omitempty"`
+	DenyPatterns []string `json:"deny_patterns,omitempty"` // Regular expressions matched against URLs
+	RateLimit    float64  `json:"rate_limit,omitempty"`    // Requests per second to each host
+}
+
+// handleCrawlUpdate records a change to a running crawl, which its crawl
+// processes apply as they poll for updates. The ID is the crawl's
+// --crawl-id, or its start URL, path-escaped.
+func (s *httpServer) handleCrawlUpdate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "PATCH" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.config.Store == nil {
+		http.Error(w, "Store not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	escaped := strings.TrimPrefix(r.URL.EscapedPath(), "/api/crawls/")
+	crawlID, err := url.PathUnescape(escaped)
+	if err != nil || crawlID == "" || strings.Contains(escaped, "/") {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	var req CrawlUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := validateCrawlUpdate(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	update := &store.CrawlUpdate{
+		CrawlID:      crawlID,
+		Seeds:        req.Seeds,
+		DenyPatterns: req.DenyPatterns,
+		RateLimit:    req.RateLimit,
+	}
+	if err := s.config.Store.AddCrawlUpdate(r.Context(), update); err != nil {
+		log.Printf("Crawl update error: %v", err)
+		http.Error(w, "Failed to record crawl update", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(update)
+}
+
+// validateCrawlUpdate checks that an update changes something and that its
+// seeds, patterns and rate limit are usable
+func validateCrawlUpdate(req *CrawlUpdateRequest) error {
+	if len(req.Seeds) == 0 && len(req.DenyPatterns) == 0 && req.RateLimit == 0 {
+		return fmt.Errorf("nothing to update: give seeds, deny_patterns or rate_limit")
+	}
+	for _, seed := range req.Seeds {
+		parsed, err := url.Parse(seed)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("invalid seed URL: %s", seed)
+		}
+	}
+	for _, pattern := range req.DenyPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid deny pattern %q: %v", pattern, err)
+		}
+	}
+	if req.RateLimit < 0 {
+		return fmt.Errorf("rate_limit must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"net/http/pprof"
+
+	"ai-search/internal/metrics"
+)
+
+// debugHandler serves the Go profiles under /debug/pprof/ along with the
+// metrics, whose runtime gauges show goroutines and heap at a glance
+func debugHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/metrics", metrics.Handler())
+	return mux
+}
+
+// startDebugServer serves the profiles without authentication on
+// Config.DebugAddr, which should only be reachable by operators. It has no
+// write timeout so CPU profiles and traces can run as long as asked.
+func (s *httpServer) startDebugServer() {
+	s.debugServer = &http.Server{
+		Addr:    s.config.DebugAddr,
+		Handler: debugHandler(),
+	}
+	go func() {
+		log.Printf("Serving profiles on %s", s.debugServer.Addr)
+		if err := s.debugServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("Debug server failed: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"ai-search/internal/store"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// FeedbackRequest judges whether a search result answered a query
+type FeedbackRequest struct {
+	Query    string `json:"query"`
+	ChunkID  string `json:"chunk_id"`
+	Relevant bool   `json:"relevant"`
+}
+
+// logQuery records a search and the chunks it returned, for mining
+// training data from real traffic. Like impressions it is written in the
+// background.
+func (s *httpServer) logQuery(query, language string, results []*SearchResultResponse) {
+	if s.config.Store == nil || !s.config.LogQueries {
+		return
+	}
+
+	entry := &store.QueryLog{
+		Query:    strings.TrimSpace(query),
+		Language: language,
+		ChunkIDs: make([]string, len(results)),
+	}
+	for i, result := range results {
+		entry.ChunkIDs[i] = result.ChunkID
+	}
+
+	s.goWorker(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), impressionTimeout)
+		defer cancel()
+		if err := s.config.Store.LogQuery(ctx, entry); err != nil {
+			log.Printf("Failed to log query: %v", err)
+		}
+	})
+}
+
+// handleFeedback records whether a search result was relevant to its query
+func (s *httpServer) handleFeedback(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.config.Store == nil {
+		http.Error(w, "Store not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req FeedbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	req.Query = strings.TrimSpace(req.Query)
+	if req.Query == "" || req.ChunkID == "" {
+		http.Error(w, "Missing query or chunk_id", http.StatusBadRequest)
+		return
+	}
+
+	feedback := &store.Feedback{Query: req.Query, ChunkID: req.ChunkID, Relevant: req.Relevant}
+	if err := s.config.Store.AddFeedback(r.Context(), feedback); err != nil {
+		log.Printf("Feedback error: %v", err)
+		http.Error(w, "Failed to record feedback", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(feedback)
+}
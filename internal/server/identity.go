@@ -0,0 +1,283 @@
+package server
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"ai-search/internal/indexer"
+	"ai-search/internal/metrics"
+	"ai-search/internal/store"
+)
+
+// Identity sources for ACLConfig.Identity
+const (
+	IdentityJWT    = "jwt"
+	IdentityHeader = "header"
+)
+
+// jwtLeeway tolerates clock skew when checking token expiry
+const jwtLeeway = time.Minute
+
+// ACLConfig sets how callers are identified when documents carry ACLs. A
+// caller's principals are "user:<subject>", "user:<email>" and
+// "group:<name>" for each of their groups, matched against the principals
+// in a document's acl attribute.
+type ACLConfig struct {
+	// Identity is IdentityJWT, taking the caller from a bearer JWT, or
+	// IdentityHeader, trusting headers set by an authenticating proxy;
+	// empty leaves every document visible to everyone
+	Identity string
+
+	// A JWT is verified with JWTSecret (HS256) or JWTPublicKey (RS256),
+	// and must match JWTIssuer and JWTAudience when they are set. Groups
+	// are read from GroupsClaim, "groups" by default.
+	JWTSecret    string
+	JWTPublicKey *rsa.PublicKey
+	JWTIssuer    string
+	JWTAudience  string
+	GroupsClaim  string
+
+	// UserHeader and GroupsHeader carry the caller and their comma
+	// separated groups, X-Forwarded-User and X-Forwarded-Groups by default
+	UserHeader   string
+	GroupsHeader string
+}
+
+// accessKey is the context key of a request's *indexer.Access
+type accessKey struct{}
+
+// identities resolves the principals of callers
+type identities struct {
+	config ACLConfig
+}
+
+// newIdentities creates the identity resolution of an ACL configuration,
+// nil when documents are visible to everyone
+func newIdentities(config ACLConfig) *identities {
+	if config.Identity == "" {
+		return nil
+	}
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = "groups"
+	}
+	if config.UserHeader == "" {
+		config.UserHeader = "X-Forwarded-User"
+	}
+	if config.GroupsHeader == "" {
+		config.GroupsHeader = "X-Forwarded-Groups"
+	}
+	return &identities{config: config}
+}
+
+// ParseRSAPublicKey parses a PEM encoded RSA public key or certificate for
+// verifying RS256 tokens
+func ParseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "CERTIFICATE":
+		var certificate *x509.Certificate
+		if certificate, err = x509.ParseCertificate(block.Bytes); err == nil {
+			parsed = certificate.PublicKey
+		}
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key is not an RSA key")
+	}
+	return key, nil
+}
+
+// identified resolves the caller's principals for the searches a handler
+// runs. Callers presenting no identity see public documents only; those
+// presenting an invalid token are refused.
+func (s *httpServer) identified(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.identities == nil || r.Method == "OPTIONS" {
+			next(w, r)
+			return
+		}
+
+		principals, err := s.identities.principals(r)
+		if err != nil {
+			log.Printf("Identity rejected: %v", err)
+			metrics.IdentityRejected.Inc()
+			http.Error(w, "Invalid identity token", http.StatusUnauthorized)
+			return
+		}
+		access := &indexer.Access{Principals: principals}
+		next(w, r.WithContext(context.WithValue(r.Context(), accessKey{}, access)))
+	}
+}
+
+// access returns what the caller of a request may see: everything when
+// ACLs are not enforced or for admins, otherwise the access resolved by
+// identified, and public documents only when there is none
+func (s *httpServer) access(ctx context.Context) *indexer.Access {
+	if s.identities == nil {
+		return nil
+	}
+	if access, ok := ctx.Value(accessKey{}).(*indexer.Access); ok {
+		return access
+	}
+	return &indexer.Access{}
+}
+
+// unrestricted marks a request as seeing every document, as admins do
+func unrestricted(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), accessKey{}, (*indexer.Access)(nil)))
+}
+
+// documentACL returns the acl attribute stored with a document
+func documentACL(doc *store.Document) string {
+	attributes, _ := doc.Meta["attributes"].(map[string]interface{})
+	acl, _ := attributes[indexer.ACLAttribute].(string)
+	return acl
+}
+
+// principals returns the caller's principals, none for an anonymous caller
+func (id *identities) principals(r *http.Request) ([]string, error) {
+	var user, email string
+	var groups []string
+
+	switch id.config.Identity {
+	case IdentityHeader:
+		user = strings.TrimSpace(r.Header.Get(id.config.UserHeader))
+		groups = strings.Split(r.Header.Get(id.config.GroupsHeader), ",")
+	case IdentityJWT:
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || strings.Count(token, ".") != 2 {
+			return nil, nil
+		}
+		claims, err := id.verifyJWT(token)
+		if err != nil {
+			return nil, err
+		}
+		user, _ = claims["sub"].(string)
+		email, _ = claims["email"].(string)
+		groups = claimStrings(claims[id.config.GroupsClaim])
+	}
+
+	var principals []string
+	add := func(kind, name string) {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			principals = append(principals, kind+":"+name)
+		}
+	}
+	add("user", user)
+	add("user", email)
+	for _, group := range groups {
+		add("group", group)
+	}
+	return principals, nil
+}
+
+// verifyJWT checks a token's signature, expiry, issuer and audience and
+// returns its claims
+func (id *identities) verifyJWT(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token header")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, fmt.Errorf("malformed token header")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token signature")
+	}
+
+	// The algorithm is the one configured, never the one the token names
+	signingInput := []byte(parts[0] + "." + parts[1])
+	switch {
+	case header.Alg == "HS256" && id.config.JWTSecret != "":
+		mac := hmac.New(sha256.New, []byte(id.config.JWTSecret))
+		mac.Write(signingInput)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return nil, fmt.Errorf("invalid token signature")
+		}
+	case header.Alg == "RS256" && id.config.JWTPublicKey != nil:
+		digest := sha256.Sum256(signingInput)
+		if err := rsa.VerifyPKCS1v15(id.config.JWTPublicKey, crypto.SHA256, digest[:], signature); err != nil {
+			return nil, fmt.Errorf("invalid token signature")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported token algorithm %q", header.Alg)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token claims")
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims")
+	}
+
+	now := time.Now()
+	if exp, ok := claims["exp"].(float64); ok && now.After(time.Unix(int64(exp), 0).Add(jwtLeeway)) {
+		return nil, fmt.Errorf("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(jwtLeeway).Before(time.Unix(int64(nbf), 0)) {
+		return nil, fmt.Errorf("token not yet valid")
+	}
+	if id.config.JWTIssuer != "" && claims["iss"] != id.config.JWTIssuer {
+		return nil, fmt.Errorf("token issuer not accepted")
+	}
+	if id.config.JWTAudience != "" {
+		accepted := false
+		for _, audience := range claimStrings(claims["aud"]) {
+			accepted = accepted || audience == id.config.JWTAudience
+		}
+		if !accepted {
+			return nil, fmt.Errorf("token audience not accepted")
+		}
+	}
+	return claims, nil
+}
+
+// claimStrings returns a claim holding a string or a list of strings
+func claimStrings(claim interface{}) []string {
+	switch claim := claim.(type) {
+	case string:
+		return []string{claim}
+	case []interface{}:
+		var values []string
+		for _, value := range claim {
+			if value, ok := value.(string); ok {
+				values = append(values, value)
+			}
+		}
+		return values
+	}
+	return nil
+}
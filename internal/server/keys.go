@@ -0,0 +1,308 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ai-search/internal/llm"
+	"ai-search/internal/metrics"
+	"ai-search/internal/store"
+
+	"golang.org/x/time/rate"
+)
+
+// APIKeyHeader carries a client's API key; a bearer token is accepted too
+const APIKeyHeader = "X-API-Key"
+
+// usageRefreshInterval is how often a key's monthly usage is reloaded from
+// the store, picking up requests served by other instances
+const usageRefreshInterval = 30 * time.Second
+
+// usageTimeout bounds recording a request's usage in the store
+const usageTimeout = 5 * time.Second
+
+// monthLayout formats the month usage is metered in
+const monthLayout = "2006-01"
+
+// APIKey is a client of the API, whose requests and LLM tokens are metered
+// and held to a rate limit and monthly quotas
+type APIKey struct {
+	ID  string
+	Key string
+
+	// RateLimit is the sustained requests per second, with bursts of up to
+	// Burst requests (1 by default); 0 for no limit
+	RateLimit float64
+	Burst     int
+
+	// Monthly quotas of requests and of LLM tokens consumed answering
+	// them, 0 for none. A request is refused once a quota is used up; the
+	// one that uses up the token quota may overshoot it.
+	MonthlyRequests int64
+	MonthlyTokens   int64
+}
+
+// KeyUsageResponse reports an API key's usage in a month against its
+// quotas (GET /api/keys/{id}/usage?month=2006-01)
+type KeyUsageResponse struct {
+	KeyID    string `json:"key_id"`
+	Month    string `json:"month"`
+	Requests int64  `json:"requests"`
+	Tokens   int64  `json:"tokens"`
+
+	RequestQuota int64   `json:"request_quota,omitempty"`
+	TokenQuota   int64   `json:"token_quota,omitempty"`
+	RateLimit    float64 `json:"rate_limit,omitempty"`
+}
+
+// apiKeys authenticates and meters the requests of API keys
+type apiKeys struct {
+	store store.Store
+	byKey map[string]*meteredKey
+	byID  map[string]*meteredKey
+}
+
+// meteredKey is an API key with its rate limiter and its usage this month.
+// Usage is the stored usage as of the last refresh plus the requests
+// served here since.
+type meteredKey struct {
+	APIKey
+	limiter *rate.Limiter
+
+	mutex     sync.Mutex
+	month     string
+	requests  int64
+	tokens    int64
+	refreshed time.Time
+
+	// pending is usage not yet written to the store
+	pendingRequests int64
+	pendingTokens   int64
+}
+
+// newAPIKeys creates the metering of the given keys, nil when there are
+// none and the API is open
+func newAPIKeys(keys []APIKey, documentStore store.Store) *apiKeys {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	k := &apiKeys{
+		store: documentStore,
+		byKey: make(map[string]*meteredKey),
+		byID:  make(map[string]*meteredKey),
+	}
+	for _, key := range keys {
+		metered := &meteredKey{APIKey: key}
+		if key.RateLimit > 0 {
+			metered.limiter = rate.NewLimiter(rate.Limit(key.RateLimit), max(key.Burst, 1))
+		}
+		k.byKey[key.Key] = metered
+		k.byID[key.ID] = metered
+	}
+	return k
+}
+
+// authenticate returns the key a request presents, nil when it presents
+// none or an unknown one
+func (k *apiKeys) authenticate(r *http.Request) *meteredKey {
+	presented := r.Header.Get(APIKeyHeader)
+	if presented == "" {
+		presented = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	if presented == "" {
+		return nil
+	}
+	return k.byKey[presented]
+}
+
+// usage returns a key's usage in the current month, reloading it from the
+// store when it is stale
+func (k *apiKeys) usage(ctx context.Context, key *meteredKey) (requests, tokens int64) {
+	month := time.Now().UTC().Format(monthLayout)
+
+	key.mutex.Lock()
+	defer key.mutex.Unlock()
+
+	if key.month != month {
+		key.month = month
+		key.requests, key.tokens = 0, 0
+		key.refreshed = time.Time{}
+	}
+	if k.store != nil && time.Since(key.refreshed) > usageRefreshInterval {
+		stored, err := k.store.GetAPIKeyUsage(ctx, key.ID, month)
+		if err != nil {
+			log.Printf("Failed to load usage of API key %s: %v", key.ID, err)
+		} else {
+			key.requests = stored.Requests + key.pendingRequests
+			key.tokens = stored.Tokens + key.pendingTokens
+		}
+		// Retry a failed load only after the interval, not on every request
+		key.refreshed = time.Now()
+	}
+	return key.requests, key.tokens
+}
+
+// add records a request served for a key with the LLM tokens it consumed,
+// returning the month it was counted in
+func (k *apiKeys) add(key *meteredKey, tokens int64) string {
+	key.mutex.Lock()
+	defer key.mutex.Unlock()
+
+	month := time.Now().UTC().Format(monthLayout)
+	if key.month != month {
+		key.month = month
+		key.requests, key.tokens = 0, 0
+		key.refreshed = time.Time{}
+	}
+	key.requests++
+	key.tokens += tokens
+	key.pendingRequests++
+	key.pendingTokens += tokens
+	return month
+}
+
+// stored marks usage as written to the store
+func (k *apiKeys) stored(key *meteredKey, tokens int64) {
+	key.mutex.Lock()
+	defer key.mutex.Unlock()
+	key.pendingRequests--
+	key.pendingTokens -= tokens
+}
+
+// metered wraps a handler with API key authentication, the key's rate limit
+// and monthly quotas, and metering of its requests and the LLM tokens they
+// consume. Without API keys configured the API is open.
+func (s *httpServer) metered(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.keys == nil || r.Method == "OPTIONS" {
+			next(w, r)
+			return
+		}
+
+		key := s.keys.authenticate(r)
+		if key == nil {
+			http.Error(w, "Missing or invalid API key", http.StatusUnauthorized)
+			return
+		}
+
+		if key.limiter != nil && !key.limiter.Allow() {
+			metrics.APIKeyRejected.Inc(key.ID, "rate_limit")
+			w.Header().Set("Retry-After", retryAfterSeconds)
+			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+
+		requests, tokens := s.keys.usage(r.Context(), key)
+		if key.MonthlyRequests > 0 {
+			w.Header().Set("X-Quota-Requests-Remaining", strconv.FormatInt(max(key.MonthlyRequests-requests-1, 0), 10))
+		}
+		if key.MonthlyTokens > 0 {
+			w.Header().Set("X-Quota-Tokens-Remaining", strconv.FormatInt(max(key.MonthlyTokens-tokens, 0), 10))
+		}
+		if key.MonthlyRequests > 0 && requests >= key.MonthlyRequests {
+			metrics.APIKeyRejected.Inc(key.ID, "request_quota")
+			http.Error(w, "Monthly request quota exceeded", http.StatusTooManyRequests)
+			return
+		}
+		if key.MonthlyTokens > 0 && tokens >= key.MonthlyTokens {
+			metrics.APIKeyRejected.Inc(key.ID, "token_quota")
+			http.Error(w, "Monthly token quota exceeded", http.StatusTooManyRequests)
+			return
+		}
+
+		ctx, usage := llm.WithUsage(r.Context())
+		next(w, r.WithContext(ctx))
+
+		consumed := usage.Tokens()
+		month := s.keys.add(key, consumed)
+		metrics.APIKeyRequests.Inc(key.ID)
+		metrics.APIKeyTokens.Add(float64(consumed), key.ID)
+
+		// Usage is written in the background to keep it off the request path
+		if s.config.Store != nil {
+			s.goWorker(func() {
+				ctx, cancel := context.WithTimeout(context.Background(), usageTimeout)
+				defer cancel()
+				if err := s.config.Store.AddAPIKeyUsage(ctx, key.ID, month, 1, consumed); err != nil {
+					log.Printf("Failed to record usage of API key %s: %v", key.ID, err)
+				}
+				s.keys.stored(key, consumed)
+			})
+		}
+	}
+}
+
+// handleKeyUsage reports an API key's usage in a month, the current one by
+// default (GET /api/keys/{id}/usage). A key may read its own usage; the
+// admin token reads any key's.
+func (s *httpServer) handleKeyUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.keys == nil {
+		http.Error(w, "API keys not configured", http.StatusNotFound)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/keys/")
+	if !strings.HasSuffix(path, "/usage") {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	id := strings.TrimSuffix(path, "/usage")
+
+	admin := s.isAdmin(r)
+	caller := s.keys.authenticate(r)
+	if !admin && (caller == nil || caller.ID != id) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	key, ok := s.keys.byID[id]
+	if !ok {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	month := r.URL.Query().Get("month")
+	if month == "" {
+		month = time.Now().UTC().Format(monthLayout)
+	}
+	if _, err := time.Parse(monthLayout, month); err != nil {
+		http.Error(w, "Invalid month, expected YYYY-MM", http.StatusBadRequest)
+		return
+	}
+
+	response := KeyUsageResponse{
+		KeyID:        key.ID,
+		Month:        month,
+		RequestQuota: key.MonthlyRequests,
+		TokenQuota:   key.MonthlyTokens,
+		RateLimit:    key.RateLimit,
+	}
+	switch {
+	case s.config.Store != nil:
+		usage, err := s.config.Store.GetAPIKeyUsage(r.Context(), key.ID, month)
+		if err != nil {
+			log.Printf("Key usage error: %v", err)
+			http.Error(w, "Failed to get key usage", http.StatusInternalServerError)
+			return
+		}
+		response.Requests, response.Tokens = usage.Requests, usage.Tokens
+	case month == time.Now().UTC().Format(monthLayout):
+		// Without a store only this instance's usage this month is known
+		response.Requests, response.Tokens = s.keys.usage(r.Context(), key)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
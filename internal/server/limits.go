@@ -0,0 +1,71 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"ai-search/internal/metrics"
+)
+
+// Route groups with their own limits
+const (
+	routeSearch = "search"
+	routeAnswer = "answer"
+	routeIngest = "ingest"
+)
+
+// retryAfterSeconds is the Retry-After sent with requests turned away by a
+// saturated route
+const retryAfterSeconds = "1"
+
+// RouteLimits bounds the requests of a group of routes
+type RouteLimits struct {
+	// Timeout answers 503 to requests taking longer and cancels their
+	// work; 0 leaves only the server's write timeout
+	Timeout time.Duration
+
+	// MaxConcurrent answers 503 with Retry-After to requests arriving
+	// while this many are in flight; 0 for no limit
+	MaxConcurrent int
+}
+
+// limiter returns a middleware applying a route group's timeout and
+// concurrency limit, shared by every handler it wraps. A concurrency slot is
+// held until the handler returns, even when the client was already answered
+// that it timed out.
+func limiter(route string, limits RouteLimits) func(http.HandlerFunc) http.HandlerFunc {
+	var slots chan struct{}
+	if limits.MaxConcurrent > 0 {
+		slots = make(chan struct{}, limits.MaxConcurrent)
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return limit(route, limits, slots, next)
+	}
+}
+
+// limit wraps a handler with a timeout and the concurrency slots of its
+// route group
+func limit(route string, limits RouteLimits, slots chan struct{}, next http.HandlerFunc) http.HandlerFunc {
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slots != nil {
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			default:
+				metrics.RouteRejected.Inc(route)
+				w.Header().Set("Retry-After", retryAfterSeconds)
+				http.Error(w, "Server busy, retry later", http.StatusServiceUnavailable)
+				return
+			}
+		}
+
+		metrics.RouteInFlight.Add(1, route)
+		defer metrics.RouteInFlight.Add(-1, route)
+		next(w, r)
+	})
+	if limits.Timeout > 0 {
+		handler = http.TimeoutHandler(handler, limits.Timeout, "Request timed out")
+	}
+	return handler.ServeHTTP
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"context"
+	"log"
+)
+
+// addProvenance attaches to each result the crawl history of its document,
+// so that clients can judge how fresh and reliable a hit is. Results from
+// other instances, whose documents are not in the store, get none, and a
+// failed lookup leaves the results as they are.
+func (s *httpServer) addProvenance(ctx context.Context, results []*SearchResultResponse) {
+	if s.config.Store == nil || len(results) == 0 {
+		return
+	}
+
+	seen := make(map[string]bool)
+	var documentIDs []string
+	for _, result := range results {
+		if !seen[result.DocumentID] {
+			seen[result.DocumentID] = true
+			documentIDs = append(documentIDs, result.DocumentID)
+		}
+	}
+
+	provenance, err := s.config.Store.GetProvenance(ctx, documentIDs)
+	if err != nil {
+		log.Printf("Failed to get document provenance: %v", err)
+		return
+	}
+	for _, result := range results {
+		result.Provenance = provenance[result.DocumentID]
+	}
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"ai-search/internal/indexer"
+	"ai-search/internal/store"
+)
+
+// SavedSearchRequest registers a saved search (POST
+// /api/admin/saved-searches). Documents indexed afterwards that match its
+// query with at least MinScore are sent to its webhook, its email or both.
+type SavedSearchRequest struct {
+	Name       string            `json:"name,omitempty"` // Defaults to the query
+	Query      string            `json:"query"`
+	Filters    map[string]string `json:"filters,omitempty"`
+	MinScore   float32           `json:"min_score,omitempty"`
+	Webhook    string            `json:"webhook,omitempty"`
+	Email      string            `json:"email,omitempty"`
+	Principals []string          `json:"principals,omitempty"` // Whose access matches are limited to
+}
+
+// SavedSearchesResponse lists the saved searches
+type SavedSearchesResponse struct {
+	SavedSearches []*store.SavedSearch `json:"saved_searches"`
+	Total         int                  `json:"total"`
+}
+
+// handleSavedSearches lists the saved searches on GET and registers one on
+// POST
+func (s *httpServer) handleSavedSearches(w http.ResponseWriter, r *http.Request) {
+	if s.config.Store == nil {
+		http.Error(w, "Store not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		searches, err := s.config.Store.ListSavedSearches(r.Context())
+		if err != nil {
+			log.Printf("Saved search list error: %v", err)
+			http.Error(w, "Failed to list saved searches", http.StatusInternalServerError)
+			return
+		}
+		if searches == nil {
+			searches = []*store.SavedSearch{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(SavedSearchesResponse{SavedSearches: searches, Total: len(searches)})
+
+	case "POST":
+		var req SavedSearchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if err := validateSavedSearch(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		search := &store.SavedSearch{
+			Name:       req.Name,
+			Query:      req.Query,
+			Filters:    req.Filters,
+			MinScore:   req.MinScore,
+			Webhook:    req.Webhook,
+			Email:      req.Email,
+			Principals: req.Principals,
+		}
+		if err := s.config.Store.AddSavedSearch(r.Context(), search); err != nil {
+			log.Printf("Saved search error: %v", err)
+			http.Error(w, "Failed to save search", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(search)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSavedSearch deletes a saved search, which stops its alerts
+func (s *httpServer) handleSavedSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "DELETE" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.config.Store == nil {
+		http.Error(w, "Store not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/admin/saved-searches/"), 10, 64)
+	if err != nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	deleted, err := s.config.Store.DeleteSavedSearch(r.Context(), id)
+	if err != nil {
+		log.Printf("Saved search delete error: %v", err)
+		http.Error(w, "Failed to delete saved search", http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		http.Error(w, "Saved search not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validateSavedSearch checks that a saved search has a query and somewhere
+// to send its alerts, normalizing its name and principals
+func validateSavedSearch(req *SavedSearchRequest) error {
+	req.Query = strings.TrimSpace(req.Query)
+	if req.Query == "" {
+		return fmt.Errorf("query is required")
+	}
+	if req.Name = strings.TrimSpace(req.Name); req.Name == "" {
+		req.Name = req.Query
+	}
+	if req.MinScore < 0 {
+		return fmt.Errorf("min_score must not be negative")
+	}
+
+	if req.Webhook == "" && req.Email == "" {
+		return fmt.Errorf("give a webhook, an email or both")
+	}
+	if req.Webhook != "" {
+		parsed, err := url.Parse(req.Webhook)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("invalid webhook URL: %s", req.Webhook)
+		}
+	}
+	if req.Email != "" {
+		address, err := mail.ParseAddress(req.Email)
+		if err != nil {
+			return fmt.Errorf("invalid email address: %s", req.Email)
+		}
+		req.Email = address.Address
+	}
+
+	req.Principals = indexer.ParseACL(strings.Join(req.Principals, ","))
+	return nil
+}
@@ -1,7 +1,17 @@
 package server
 
 import (
+	"ai-search/internal/alerts"
+	"ai-search/internal/dedup"
+	"ai-search/internal/events"
+	"ai-search/internal/indexer"
+	"ai-search/internal/llm"
+	"ai-search/internal/metrics"
+	"ai-search/internal/parser"
+	"ai-search/internal/retention"
 	"ai-search/internal/retriever"
+	"ai-search/internal/store"
+	"ai-search/internal/version"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -10,16 +20,21 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
 
 // Server defines the interface for the HTTP API server
 type Server interface {
-	// Start starts the HTTP server
+	// Start starts the HTTP server and blocks until it has shut down after
+	// SIGINT or SIGTERM
 	Start(ctx context.Context) error
 
-	// Stop stops the HTTP server
+	// Stop stops accepting requests and waits up to the shutdown grace
+	// period for in-flight requests and background work to finish
 	Stop(ctx context.Context) error
 
 	// RegisterRoutes registers API routes
@@ -28,41 +43,185 @@ type Server interface {
 
 // Config holds server configuration
 type Config struct {
-	Host      string
-	Port      int
-	Retriever retriever.Retriever
+	Host       string
+	Port       int
+	Retriever  retriever.Retriever
+	Store      store.Store
+	Indexer    indexer.Indexer
+	Dedup      dedup.Detector
+	LLM        llm.LLM
+	AdminToken string
+
+	// Purger removes soft-deleted documents once their restore window ends
+	Purger retention.Purger
+
+	// Events publishes index mutations and delivers them to the response
+	// cache, including mutations made by other processes through Redis
+	Events events.Publisher
+
+	// Alerts matches the documents indexed according to Events against the
+	// saved searches of /api/admin/saved-searches every AlertInterval
+	// (1m by default)
+	Alerts        alerts.Alerter
+	AlertInterval time.Duration
+
+	// CacheTTL is how long search responses are cached, 0 disables caching
+	CacheTTL        time.Duration
+	CacheMaxEntries int
+
+	// DefaultLanguage is preferred when a request names no language
+	DefaultLanguage string
+
+	// Snippets replaces result text with a query-focused snippet of at most
+	// SnippetLength characters unless a request says otherwise
+	Snippets      bool
+	SnippetLength int
+
+	// LogQueries records searches and the chunks they returned, which with
+	// feedback from /api/feedback yields training data for embeddings
+	LogQueries bool
+
+	// On SIGTERM /api/ready fails for DrainDelay (5s by default) so load
+	// balancers stop routing here while requests are still served, then
+	// in-flight requests and background work get up to ShutdownGrace (30s
+	// by default) to finish
+	DrainDelay    time.Duration
+	ShutdownGrace time.Duration
+
+	// Searches are held to SLOs: SLOAvailability of them succeed (99.9% by
+	// default) and SLOLatencyTarget of them (95%) finish within SLOLatency
+	// (500ms). Burn rates are served by /api/slo and as metrics.
+	SLOAvailability  float64
+	SLOLatency       time.Duration
+	SLOLatencyTarget float64
+
+	// Limits apply to /api/search, to /api/answer, which waits on the LLM,
+	// and to the ingest routes that write feedback or change documents
+	SearchLimits RouteLimits
+	AnswerLimits RouteLimits
+	IngestLimits RouteLimits
+
+	// DebugAddr serves pprof profiles and metrics without authentication on
+	// a separate port, e.g. localhost:6060. The profiles are also served to
+	// admins under /api/admin/debug/pprof/.
+	DebugAddr string
+
+	// ShadowRetriever searches the shadow collection for
+	// /api/admin/compare, nil when none is configured
+	ShadowRetriever retriever.Retriever
+
+	// LocalRetriever searches this instance's own index when Retriever
+	// federates several instances. Searches from other instances, marked
+	// with retriever.FederatedHeader, use it so federations never loop.
+	LocalRetriever retriever.Retriever
+
+	// APIKeys, when any are given, are required by the search, answer,
+	// chunk and feedback routes, which meter each key's requests and LLM
+	// tokens against its rate limit and monthly quotas. Usage is kept in
+	// Store so that it is shared between instances.
+	APIKeys []APIKey
+
+	// ACL identifies callers so that searches, answers and chunks only
+	// show them public documents and those whose acl attribute grants
+	// them. Admin routes see every document.
+	ACL ACLConfig
 }
 
 // httpServer implements the Server interface
 type httpServer struct {
-	config    Config
-	server    *http.Server
-	retriever retriever.Retriever
+	config      Config
+	server      *http.Server
+	debugServer *http.Server
+	mux         *http.ServeMux
+	retriever   retriever.Retriever
+	cache       *responseCache
+	slo         *sloTracker
+	keys        *apiKeys
+	identities  *identities
+
+	// draining is set once shutdown begins; workers tracks background
+	// goroutines, stopped through stopWorkers
+	draining    atomic.Bool
+	workers     sync.WaitGroup
+	stopWorkers context.CancelFunc
 }
 
 // SearchRequest represents a search request
 type SearchRequest struct {
-	Query string `json:"query"`
-	Limit int    `json:"limit,omitempty"`
+	Query   string            `json:"query"`
+	Limit   int               `json:"limit,omitempty"`
+	Filters map[string]string `json:"filters,omitempty"`
+	Version string            `json:"version,omitempty"` // Corpus version, "all" searches every version
+
+	// Language is a preferred language code ("de", "pt-BR"); results in it
+	// rank higher and answers are written in it
+	Language string `json:"language,omitempty"`
+
+	// Lang restricts results to documents in a language ("de")
+	Lang string `json:"lang,omitempty"`
+
+	// SourceWeights override the collection's weights of results by source
+	// type, as in {"git": 1.5, "confluence": 0.8}
+	SourceWeights map[string]float64 `json:"source_weights,omitempty"`
+
+	// Debug adds the queries that were run, including translations
+	Debug bool `json:"debug,omitempty"`
+
+	// Snippets overrides the server's default for query-focused snippets
+	Snippets *bool `json:"snippets,omitempty"`
 }
 
 // SearchResponse represents a search response
 type SearchResponse struct {
-	Query   string                  `json:"query"`
-	Results []*SearchResultResponse `json:"results"`
-	Total   int                     `json:"total"`
-	Time    int64                   `json:"time_ms"`
+	Query    string                  `json:"query"`
+	Version  string                  `json:"version,omitempty"`
+	Language string                  `json:"language,omitempty"`
+	Results  []*SearchResultResponse `json:"results"`
+	Total    int                     `json:"total"`
+	Time     int64                   `json:"time_ms"`
+	Cached   bool                    `json:"cached,omitempty"`
+	Debug    *indexer.SearchDebug    `json:"debug,omitempty"`
 }
 
 // SearchResultResponse represents a search result in the API response
 type SearchResultResponse struct {
-	DocumentID string                 `json:"document_id"`
-	ChunkID    string                 `json:"chunk_id"`
-	Score      float32                `json:"score"`
-	Text       string                 `json:"text"`
-	Title      string                 `json:"title,omitempty"`
-	URL        string                 `json:"url,omitempty"`
-	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+	DocumentID  string                 `json:"document_id"`
+	ChunkID     string                 `json:"chunk_id"`
+	Score       float32                `json:"score"`
+	Text        string                 `json:"text,omitempty"`
+	Title       string                 `json:"title,omitempty"`
+	URL         string                 `json:"url,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Attributes  map[string]string      `json:"attributes,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+
+	// Source labels the collection a result came from when queries are
+	// routed between several
+	Source string `json:"source,omitempty"`
+
+	// Instance names the ai-search deployment a federated result came from
+	Instance string `json:"instance,omitempty"`
+
+	// Snippet is the passage of the text that best matches the query, sent
+	// instead of the text when snippets are requested
+	Snippet string `json:"snippet,omitempty"`
+
+	// Where the passage lies in the document, for deep links into the
+	// cached document view: its character range and its index among the
+	// document's chunks (see /api/chunks/{id}/context)
+	StartPos       int `json:"start_pos"`
+	EndPos         int `json:"end_pos"`
+	Position       int `json:"position"`
+	DocumentChunks int `json:"document_chunks,omitempty"`
+
+	// Provenance is the crawl history of the result's document: when it
+	// was first seen and last crawled, how often its content changed and
+	// the HTTP status of the last crawl
+	Provenance *store.Provenance `json:"provenance,omitempty"`
+
+	// LocalizedURL is the address of the translation of the result's page
+	// into the preferred language, when the page declares one elsewhere
+	LocalizedURL string `json:"localized_url,omitempty"`
 }
 
 // HealthResponse represents a health check response
@@ -72,6 +231,12 @@ type HealthResponse struct {
 	Version   string `json:"version"`
 }
 
+// Corpus version handling
+const (
+	versionAttribute = "version"
+	allVersions      = "all"
+)
+
 // NewServer creates a new HTTP server instance
 func NewServer(config Config) Server {
 	if config.Host == "" {
@@ -80,22 +245,79 @@ func NewServer(config Config) Server {
 	if config.Port == 0 {
 		config.Port = 8080
 	}
+	if config.SnippetLength <= 0 {
+		config.SnippetLength = defaultSnippetLength
+	}
+	if config.DrainDelay == 0 {
+		config.DrainDelay = 5 * time.Second
+	}
+	if config.ShutdownGrace == 0 {
+		config.ShutdownGrace = 30 * time.Second
+	}
+	if config.AlertInterval <= 0 {
+		config.AlertInterval = time.Minute
+	}
+	if config.SLOAvailability <= 0 {
+		config.SLOAvailability = defaultSLOAvailability
+	}
+	if config.SLOLatency <= 0 {
+		config.SLOLatency = defaultSLOLatency
+	}
+	if config.SLOLatencyTarget <= 0 {
+		config.SLOLatencyTarget = defaultSLOLatencyTarget
+	}
 
-	return &httpServer{
+	s := &httpServer{
 		config:    config,
+		mux:       http.NewServeMux(),
 		retriever: config.Retriever,
+		cache:     newResponseCache(config.CacheTTL, config.CacheMaxEntries),
+		slo:       newSLOTracker(config),
+		keys:      newAPIKeys(config.APIKeys, config.Store),
+
+		identities: newIdentities(config.ACL),
+	}
+	if s.cache != nil && config.Events != nil {
+		config.Events.Subscribe(s.cache.invalidate)
 	}
+	if config.Alerts != nil && config.Events != nil {
+		config.Events.Subscribe(config.Alerts.Observe)
+	}
+
+	return s
 }
 
 // Start starts the HTTP server
 func (s *httpServer) Start(ctx context.Context) error {
 	s.RegisterRoutes()
 
+	// Background workers outlive ctx until the HTTP server has drained
+	workerCtx, stopWorkers := context.WithCancel(context.WithoutCancel(ctx))
+	s.stopWorkers = stopWorkers
+
+	if s.config.Purger != nil {
+		s.goWorker(func() { s.config.Purger.Run(workerCtx, time.Hour) })
+	}
+
+	s.goWorker(func() { s.slo.run(workerCtx) })
+
+	if s.config.Alerts != nil {
+		s.goWorker(func() { s.config.Alerts.Run(workerCtx, s.config.AlertInterval) })
+	}
+
+	if s.config.Events != nil {
+		s.goWorker(func() {
+			if err := s.config.Events.Listen(workerCtx); err != nil {
+				log.Printf("Event listener stopped: %v", err)
+			}
+		})
+	}
+
 	s.server = &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", s.config.Host, s.config.Port),
-		Handler:      nil, // Use default mux
+		Handler:      s.mux,
 		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
+		WriteTimeout: s.writeTimeout(),
 	}
 
 	// Start server in a goroutine
@@ -106,32 +328,114 @@ func (s *httpServer) Start(ctx context.Context) error {
 		}
 	}()
 
+	if s.config.DebugAddr != "" {
+		s.startDebugServer()
+	}
+
 	// Wait for shutdown signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	select {
+	case <-quit:
+	case <-ctx.Done():
+	}
+
+	// Fail readiness first so the instance is taken out of rotation while
+	// it still answers the requests already routed to it
+	s.draining.Store(true)
+	s.server.SetKeepAlivesEnabled(false)
+	log.Printf("Draining for %s before shutting down...", s.config.DrainDelay)
+	select {
+	case <-quit:
+		log.Println("Second signal received, skipping drain delay")
+	case <-time.After(s.config.DrainDelay):
+	}
 
 	log.Println("Shutting down server...")
 	return s.Stop(ctx)
 }
 
-// Stop stops the HTTP server
+// Stop stops accepting requests and waits up to the shutdown grace period
+// for in-flight requests, then background workers, to finish
 func (s *httpServer) Stop(ctx context.Context) error {
-	if s.server == nil {
-		return nil
-	}
+	s.draining.Store(true)
 
-	shutdownCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.config.ShutdownGrace)
 	defer cancel()
 
-	return s.server.Shutdown(shutdownCtx)
+	var err error
+	if s.server != nil {
+		err = s.server.Shutdown(shutdownCtx)
+	}
+	if s.debugServer != nil {
+		s.debugServer.Close()
+	}
+
+	if s.stopWorkers != nil {
+		s.stopWorkers()
+	}
+	done := make(chan struct{})
+	go func() {
+		s.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-shutdownCtx.Done():
+		log.Println("Shutdown grace period expired with background work still running")
+	}
+
+	return err
+}
+
+// writeTimeout leaves every route group time to answer, timeouts included
+func (s *httpServer) writeTimeout() time.Duration {
+	timeout := 30 * time.Second
+	for _, limits := range []RouteLimits{s.config.SearchLimits, s.config.AnswerLimits, s.config.IngestLimits} {
+		timeout = max(timeout, limits.Timeout+5*time.Second)
+	}
+	return timeout
+}
+
+// goWorker runs fn in the background, tracked so shutdown waits for it
+func (s *httpServer) goWorker(fn func()) {
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+		fn()
+	}()
 }
 
 // RegisterRoutes registers API routes
 func (s *httpServer) RegisterRoutes() {
-	http.HandleFunc("/api/search", s.handleSearch)
-	http.HandleFunc("/api/health", s.handleHealth)
-	http.HandleFunc("/", s.handleRoot)
+	search := limiter(routeSearch, s.config.SearchLimits)
+	answer := limiter(routeAnswer, s.config.AnswerLimits)
+	ingest := limiter(routeIngest, s.config.IngestLimits)
+
+	// Routes go on the server's own mux, as importing net/http/pprof puts
+	// unauthenticated profiles on http.DefaultServeMux
+
+	s.mux.HandleFunc("/api/search", s.trackSLO(s.metered(search(s.identified(s.handleSearch)))))
+	s.mux.HandleFunc("/api/answer", s.metered(answer(s.identified(s.handleAnswer))))
+	s.mux.HandleFunc("/api/suggest", s.metered(s.handleSuggest))
+	s.mux.HandleFunc("/api/health", s.handleHealth)
+	s.mux.HandleFunc("/api/ready", s.handleReady)
+	s.mux.HandleFunc("/api/version", s.handleVersion)
+	s.mux.HandleFunc("/api/slo", s.handleSLO)
+	s.mux.HandleFunc("/api/chunks/", s.metered(s.identified(s.handleChunkContext)))
+	s.mux.HandleFunc("/api/feedback", s.metered(ingest(s.handleFeedback)))
+	s.mux.HandleFunc("/api/keys/", s.handleKeyUsage)
+	s.mux.Handle("/metrics", metrics.Handler())
+	s.mux.HandleFunc("/api/admin/duplicates", s.requireAdmin(s.handleDuplicates))
+	s.mux.HandleFunc("/api/admin/duplicates/resolve", s.requireAdmin(ingest(s.handleResolveDuplicates)))
+	s.mux.HandleFunc("/api/admin/documents/", s.requireAdmin(ingest(s.handleDocument)))
+	s.mux.HandleFunc("/api/admin/traffic", s.requireAdmin(s.handleTraffic))
+	s.mux.HandleFunc("/api/crawls/", s.requireAdmin(ingest(s.handleCrawlUpdate)))
+	s.mux.HandleFunc("/api/admin/saved-searches", s.requireAdmin(ingest(s.handleSavedSearches)))
+	s.mux.HandleFunc("/api/admin/saved-searches/", s.requireAdmin(ingest(s.handleSavedSearch)))
+	s.mux.HandleFunc("/api/admin/compare", s.requireAdmin(search(s.handleCompare)))
+	s.mux.HandleFunc("/api/admin/debug/pprof/", s.requireAdmin(http.StripPrefix("/api/admin", debugHandler()).ServeHTTP))
+	s.mux.HandleFunc("/", s.handleRoot)
 }
 
 // handleSearch handles search requests
@@ -141,7 +445,7 @@ func (s *httpServer) handleSearch(w http.ResponseWriter, r *http.Request) {
 	// Set CORS headers
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+APIKeyHeader)
 
 	// Handle preflight requests
 	if r.Method == "OPTIONS" {
@@ -156,25 +460,10 @@ func (s *httpServer) handleSearch(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse request
-	var req SearchRequest
-	if r.Method == "POST" {
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid JSON", http.StatusBadRequest)
-			return
-		}
-	} else {
-		// GET request - parse query parameters
-		req.Query = r.URL.Query().Get("q")
-		if req.Query == "" {
-			http.Error(w, "Missing query parameter 'q'", http.StatusBadRequest)
-			return
-		}
-
-		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-			if limit, err := strconv.Atoi(limitStr); err == nil {
-				req.Limit = limit
-			}
-		}
+	req, err := parseSearchRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	// Set defaults
@@ -186,14 +475,232 @@ func (s *httpServer) handleSearch(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Perform search
-	results, err := s.retriever.Retrieve(r.Context(), req.Query, req.Limit)
+	opts, version, language := s.searchOptions(req)
+	snippets := s.config.Snippets
+	if req.Snippets != nil {
+		snippets = *req.Snippets
+	}
+
+	// Searches federated from another instance only cover this one's index
+	searcher := s.retriever
+	federated := r.Header.Get(retriever.FederatedHeader) != "" && s.config.LocalRetriever != nil
+	if federated {
+		searcher = s.config.LocalRetriever
+	}
+
+	// Serve repeated searches from the cache
+	key := cacheKey(req.Query, req.Limit, req.Filters, req.SourceWeights, language, snippets, s.access(r.Context()))
+	useCache := s.cache != nil && !req.Debug && !federated
+	var generation uint64
+	if useCache {
+		generation = s.cache.currentGeneration()
+		if cached, ok := s.cache.get(key); ok {
+			cached.Cached = true
+			cached.Time = time.Since(startTime).Milliseconds()
+			s.recordImpressions(cached.Results)
+			s.logQuery(req.Query, language, cached.Results)
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(cached)
+			return
+		}
+	}
+
+	results, err := s.retrieveFrom(r.Context(), searcher, req.Query, req.Limit, opts)
 	if err != nil {
 		log.Printf("Search error: %v", err)
 		http.Error(w, "Search failed", http.StatusInternalServerError)
 		return
 	}
 
-	// Convert results to response format
+	// Create response
+	responseResults := toResultResponses(results)
+	if snippets {
+		applySnippets(responseResults, req.Query, s.config.SnippetLength)
+	}
+	s.addProvenance(r.Context(), responseResults)
+	if language != "" {
+		// Show a page once across its translations, pointing to the one in
+		// the preferred language
+		variants := s.languageVariants(r.Context(), responseResults)
+		responseResults = collapseLanguageVariants(responseResults, variants)
+		localizeResults(responseResults, variants, language)
+	}
+	response := SearchResponse{
+		Query:    req.Query,
+		Version:  version,
+		Language: language,
+		Results:  responseResults,
+		Total:    len(responseResults),
+		Time:     time.Since(startTime).Milliseconds(),
+		Debug:    opts.Debug,
+	}
+	if useCache {
+		s.cache.put(key, response, generation)
+	}
+	s.recordImpressions(responseResults)
+	s.logQuery(req.Query, language, responseResults)
+
+	// Set content type and encode response
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Error encoding response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// parseSearchRequest reads a search request from a JSON body (POST) or
+// from query parameters (GET)
+func parseSearchRequest(r *http.Request) (*SearchRequest, error) {
+	var req SearchRequest
+	if r.Method == "POST" {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, fmt.Errorf("Invalid JSON")
+		}
+		if err := validateSourceWeights(req.SourceWeights); err != nil {
+			return nil, err
+		}
+		return &req, nil
+	}
+
+	// GET request - parse query parameters
+	req.Query = r.URL.Query().Get("q")
+	if req.Query == "" {
+		return nil, fmt.Errorf("Missing query parameter 'q'")
+	}
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil {
+			req.Limit = limit
+		}
+	}
+
+	req.Version = r.URL.Query().Get("version")
+	req.Language = r.URL.Query().Get("language")
+	req.Lang = r.URL.Query().Get("lang")
+	req.Debug, _ = strconv.ParseBool(r.URL.Query().Get("debug"))
+	if snippets, err := strconv.ParseBool(r.URL.Query().Get("snippets")); err == nil {
+		req.Snippets = &snippets
+	}
+
+	// Filters are passed as repeated filter=key:value parameters
+	for _, filter := range r.URL.Query()["filter"] {
+		key, value, ok := strings.Cut(filter, ":")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("Invalid filter, expected key:value")
+		}
+		if req.Filters == nil {
+			req.Filters = make(map[string]string)
+		}
+		req.Filters[key] = value
+	}
+
+	// Source weights are passed as repeated source_weight=type:weight
+	// parameters
+	for _, sourceWeight := range r.URL.Query()["source_weight"] {
+		sourceType, rawWeight, ok := strings.Cut(sourceWeight, ":")
+		weight, err := strconv.ParseFloat(rawWeight, 64)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("Invalid source weight, expected type:weight")
+		}
+		if req.SourceWeights == nil {
+			req.SourceWeights = make(map[string]float64)
+		}
+		req.SourceWeights[sourceType] = weight
+	}
+	if err := validateSourceWeights(req.SourceWeights); err != nil {
+		return nil, err
+	}
+
+	return &req, nil
+}
+
+// validateSourceWeights checks the source weights of a request
+func validateSourceWeights(weights map[string]float64) error {
+	for sourceType, weight := range weights {
+		if err := retriever.ValidateSourceWeight(sourceType, weight); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// searchOptions builds the index search options for a request, returning
+// the corpus version and preferred language in effect
+func (s *httpServer) searchOptions(req *SearchRequest) (indexer.SearchOptions, string, string) {
+	// Restrict to a single corpus version unless all versions are requested
+	version := req.Version
+	if version == "" && s.config.Indexer != nil {
+		version = s.config.Indexer.DefaultVersion()
+	}
+	if version == allVersions {
+		version = ""
+	}
+	if version != "" {
+		if req.Filters == nil {
+			req.Filters = make(map[string]string)
+		}
+		req.Filters[versionAttribute] = version
+	}
+
+	// Restrict to documents in one language, which keyword search then
+	// also matches with that language's analyzer
+	if lang := parser.NormalizeLanguage(req.Lang); lang != "" {
+		if req.Filters == nil {
+			req.Filters = make(map[string]string)
+		}
+		req.Filters[indexer.LanguageKey] = lang
+	}
+
+	// Prefer documents in the requested language without excluding others
+	language := parser.NormalizeLanguage(req.Language)
+	if language == "" {
+		language = s.config.DefaultLanguage
+	}
+
+	opts := indexer.SearchOptions{
+		Filters:  req.Filters,
+		Language: language,
+
+		SourceWeights: req.SourceWeights,
+	}
+	if req.Debug {
+		opts.Debug = &indexer.SearchDebug{}
+	}
+	return opts, version, language
+}
+
+// retrieve runs a search
+func (s *httpServer) retrieve(ctx context.Context, query string, limit int, opts indexer.SearchOptions) ([]*indexer.SearchResult, error) {
+	return s.retrieveFrom(ctx, s.retriever, query, limit, opts)
+}
+
+// retrieveFrom runs a search with the given retriever. Soft-deleted
+// documents stay indexed until purged, marked so the indexes skip them.
+func (s *httpServer) retrieveFrom(ctx context.Context, r retriever.Retriever, query string, limit int, opts indexer.SearchOptions) ([]*indexer.SearchResult, error) {
+	opts.Access = s.access(ctx)
+
+	results, err := r.Retrieve(ctx, query, limit, opts)
+	if err != nil || opts.Access == nil {
+		return results, err
+	}
+
+	// The indexes filter by ACL already; results federated from other
+	// instances or indexed without ACL metadata are checked again here
+	allowed := make([]*indexer.SearchResult, 0, len(results))
+	for _, result := range results {
+		attributes, _ := result.Metadata["attributes"].(map[string]string)
+		if !opts.Access.Allows(attributes[indexer.ACLAttribute]) {
+			metrics.ACLFiltered.Inc()
+			continue
+		}
+		allowed = append(allowed, result)
+	}
+	return allowed, nil
+}
+
+// toResultResponses converts search results to the response format
+func toResultResponses(results []*indexer.SearchResult) []*SearchResultResponse {
 	var responseResults []*SearchResultResponse
 	for _, result := range results {
 		responseResult := &SearchResultResponse{
@@ -202,6 +709,11 @@ func (s *httpServer) handleSearch(w http.ResponseWriter, r *http.Request) {
 			Score:      result.Score,
 			Text:       result.Text,
 			Metadata:   result.Metadata,
+
+			StartPos:       result.StartPos,
+			EndPos:         result.EndPos,
+			Position:       result.Position,
+			DocumentChunks: result.ChunkCount,
 		}
 
 		// Extract title and URL from metadata if available
@@ -211,39 +723,59 @@ func (s *httpServer) handleSearch(w http.ResponseWriter, r *http.Request) {
 		if url, ok := result.Metadata["url"].(string); ok {
 			responseResult.URL = url
 		}
+		if description, ok := result.Metadata["description"].(string); ok {
+			responseResult.Description = description
+		}
+		if attributes, ok := result.Metadata["attributes"].(map[string]string); ok {
+			responseResult.Attributes = attributes
+		}
+		if source, ok := result.Metadata[retriever.SourceKey].(string); ok {
+			responseResult.Source = source
+		}
+		if instance, ok := result.Metadata[retriever.InstanceKey].(string); ok {
+			responseResult.Instance = instance
+		}
 
 		responseResults = append(responseResults, responseResult)
 	}
+	return responseResults
+}
 
-	// Create response
-	response := SearchResponse{
-		Query:   req.Query,
-		Results: responseResults,
-		Total:   len(responseResults),
-		Time:    time.Since(startTime).Milliseconds(),
+// handleHealth handles health check requests
+func (s *httpServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	response := HealthResponse{
+		Status:    "healthy",
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Version:   version.Version,
 	}
 
-	// Set content type and encode response
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Error encoding response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
+	json.NewEncoder(w).Encode(response)
 }
 
-// handleHealth handles health check requests
-func (s *httpServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+// handleReady reports whether the server takes traffic; it fails once
+// shutdown begins so load balancers stop routing requests here
+func (s *httpServer) handleReady(w http.ResponseWriter, r *http.Request) {
 	response := HealthResponse{
-		Status:    "healthy",
+		Status:    "ready",
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
-		Version:   "1.0.0",
+		Version:   version.Version,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	if s.draining.Load() {
+		response.Status = "draining"
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
 	json.NewEncoder(w).Encode(response)
 }
 
+// handleVersion reports build metadata
+func (s *httpServer) handleVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(version.Get())
+}
+
 // handleRoot handles root requests
 func (s *httpServer) handleRoot(w http.ResponseWriter, r *http.Request) {
 	html := `
@@ -314,3 +846,10 @@ func (s *httpServer) handleRoot(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html")
 	w.Write([]byte(html))
 }
+
+// publish announces an index mutation when events are configured
+func (s *httpServer) publish(ctx context.Context, eventType string, documentIDs ...string) {
+	if s.config.Events != nil {
+		s.config.Events.Publish(ctx, eventType, documentIDs...)
+	}
+}
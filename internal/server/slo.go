@@ -0,0 +1,335 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"ai-search/internal/metrics"
+)
+
+// Search SLO defaults: 99.9% of searches succeed and 95% finish in 500ms
+const (
+	defaultSLOAvailability  = 0.999
+	defaultSLOLatency       = 500 * time.Millisecond
+	defaultSLOLatencyTarget = 0.95
+)
+
+// SLO names
+const (
+	sloAvailability = "availability"
+	sloLatency      = "latency"
+)
+
+// sloHistory is how far back requests are counted, in one-minute buckets
+const sloHistory = 6 * 60
+
+// sloUpdateInterval is how often the SLO gauges are recomputed
+const sloUpdateInterval = 15 * time.Second
+
+// sloLatencyBounds are the upper bounds of the latency buckets percentiles
+// are estimated from
+var sloLatencyBounds = [...]time.Duration{
+	10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2500 * time.Millisecond, 5 * time.Second, 10 * time.Second,
+	30 * time.Second,
+}
+
+// sloWindow is a burn rate window
+type sloWindow struct {
+	name     string
+	duration time.Duration
+}
+
+// sloWindows are the windows of the multiwindow burn rate alerts
+var sloWindows = []sloWindow{
+	{"5m", 5 * time.Minute},
+	{"30m", 30 * time.Minute},
+	{"1h", time.Hour},
+	{"6h", 6 * time.Hour},
+}
+
+// sloAlertRule fires when the error budget burns faster than burnRate over
+// both a long window and a short one, the short one showing it still does.
+// The rates spend 2% of a 30-day budget in an hour and 5% in six hours.
+type sloAlertRule struct {
+	severity   string
+	long       string
+	short      string
+	burnRate   float64
+	exhaustsIn string
+}
+
+var sloAlertRules = []sloAlertRule{
+	{severity: "page", long: "1h", short: "5m", burnRate: 14.4, exhaustsIn: "2 days"},
+	{severity: "ticket", long: "6h", short: "30m", burnRate: 6, exhaustsIn: "5 days"},
+}
+
+// SLOObjectives are the targets searches are held to
+type SLOObjectives struct {
+	Availability  float64 `json:"availability"`   // Fraction of searches that must succeed
+	LatencyMillis int64   `json:"latency_ms"`     // Threshold a search must finish within
+	LatencyTarget float64 `json:"latency_target"` // Fraction of searches that must finish within it
+}
+
+// SLOWindowStatus reports the searches of one window
+type SLOWindowStatus struct {
+	Window       string             `json:"window"`
+	Requests     int64              `json:"requests"`
+	Errors       int64              `json:"errors"`
+	Slow         int64              `json:"slow"`
+	Availability float64            `json:"availability"`
+	LatencyMet   float64            `json:"latency_met"` // Fraction of searches within the latency threshold
+	P95Millis    int64              `json:"p95_ms"`
+	BurnRates    map[string]float64 `json:"burn_rates"`
+}
+
+// SLOAlert is a burn rate alert that is firing
+type SLOAlert struct {
+	SLO        string  `json:"slo"`
+	Severity   string  `json:"severity"`
+	Windows    string  `json:"windows"`
+	BurnRate   float64 `json:"burn_rate"`
+	ExhaustsIn string  `json:"exhausts_in"` // How soon a 30-day budget runs out at this rate
+}
+
+// SLOResponse is the search SLO status
+type SLOResponse struct {
+	Status     string            `json:"status"` // ok, or the most severe alert firing
+	Objectives SLOObjectives     `json:"objectives"`
+	Windows    []SLOWindowStatus `json:"windows"`
+	Alerts     []SLOAlert        `json:"alerts"`
+}
+
+// sloBucket counts the searches of one minute
+type sloBucket struct {
+	minute   int64
+	requests int64
+	errors   int64
+	slow     int64
+	latency  [len(sloLatencyBounds) + 1]int64 // Per bound, then over the last
+}
+
+// sloTracker keeps per-minute search counts for the last sloHistory minutes
+type sloTracker struct {
+	objectives SLOObjectives
+	threshold  time.Duration
+
+	mutex   sync.Mutex
+	buckets [sloHistory]sloBucket
+}
+
+// newSLOTracker creates a tracker for the configured objectives
+func newSLOTracker(config Config) *sloTracker {
+	return &sloTracker{
+		objectives: SLOObjectives{
+			Availability:  config.SLOAvailability,
+			LatencyMillis: config.SLOLatency.Milliseconds(),
+			LatencyTarget: config.SLOLatencyTarget,
+		},
+		threshold: config.SLOLatency,
+	}
+}
+
+// record counts a search that took latency and failed or not
+func (t *sloTracker) record(latency time.Duration, failed bool) {
+	minute := time.Now().Unix() / 60
+
+	t.mutex.Lock()
+	bucket := &t.buckets[minute%sloHistory]
+	if bucket.minute != minute {
+		*bucket = sloBucket{minute: minute}
+	}
+	bucket.requests++
+	if failed {
+		bucket.errors++
+	}
+	if latency > t.threshold {
+		bucket.slow++
+	}
+	i := 0
+	for i < len(sloLatencyBounds) && latency > sloLatencyBounds[i] {
+		i++
+	}
+	bucket.latency[i]++
+	t.mutex.Unlock()
+
+	result := "success"
+	if failed {
+		result = "error"
+	}
+	metrics.SearchRequests.Inc(result)
+	metrics.SearchLatency.Observe(latency.Seconds())
+}
+
+// window sums the buckets of the last d, the current minute included
+func (t *sloTracker) window(d time.Duration) sloBucket {
+	now := time.Now().Unix() / 60
+	oldest := now - int64(d/time.Minute) + 1
+
+	var sum sloBucket
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, bucket := range t.buckets {
+		if bucket.minute < oldest || bucket.minute > now {
+			continue
+		}
+		sum.requests += bucket.requests
+		sum.errors += bucket.errors
+		sum.slow += bucket.slow
+		for i, count := range bucket.latency {
+			sum.latency[i] += count
+		}
+	}
+	return sum
+}
+
+// status computes every window and the alerts firing
+func (t *sloTracker) status() *SLOResponse {
+	response := &SLOResponse{Status: "ok", Objectives: t.objectives, Alerts: []SLOAlert{}}
+	burnRates := make(map[string]map[string]float64)
+	for _, w := range sloWindows {
+		sum := t.window(w.duration)
+		status := SLOWindowStatus{
+			Window:       w.name,
+			Requests:     sum.requests,
+			Errors:       sum.errors,
+			Slow:         sum.slow,
+			Availability: 1,
+			LatencyMet:   1,
+			P95Millis:    percentile(sum, 0.95).Milliseconds(),
+			BurnRates:    make(map[string]float64),
+		}
+		if sum.requests > 0 {
+			status.Availability = 1 - float64(sum.errors)/float64(sum.requests)
+			status.LatencyMet = 1 - float64(sum.slow)/float64(sum.requests)
+		}
+		status.BurnRates[sloAvailability] = burnRate(status.Availability, t.objectives.Availability)
+		status.BurnRates[sloLatency] = burnRate(status.LatencyMet, t.objectives.LatencyTarget)
+		burnRates[w.name] = status.BurnRates
+		response.Windows = append(response.Windows, status)
+	}
+
+	for _, rule := range sloAlertRules {
+		for _, slo := range []string{sloAvailability, sloLatency} {
+			long, short := burnRates[rule.long][slo], burnRates[rule.short][slo]
+			if long < rule.burnRate || short < rule.burnRate {
+				continue
+			}
+			response.Alerts = append(response.Alerts, SLOAlert{
+				SLO:        slo,
+				Severity:   rule.severity,
+				Windows:    rule.long + "/" + rule.short,
+				BurnRate:   long,
+				ExhaustsIn: rule.exhaustsIn,
+			})
+			if response.Status == "ok" {
+				response.Status = rule.severity
+			}
+		}
+	}
+	return response
+}
+
+// updateMetrics publishes the burn rates, compliance and percentiles of
+// every window
+func (t *sloTracker) updateMetrics() {
+	for _, status := range t.status().Windows {
+		metrics.SLOBurnRate.Set(status.BurnRates[sloAvailability], sloAvailability, status.Window)
+		metrics.SLOBurnRate.Set(status.BurnRates[sloLatency], sloLatency, status.Window)
+		metrics.SLOCompliance.Set(status.Availability, sloAvailability, status.Window)
+		metrics.SLOCompliance.Set(status.LatencyMet, sloLatency, status.Window)
+		metrics.SearchLatencyP95.Set(float64(status.P95Millis)/1000, status.Window)
+	}
+}
+
+// run keeps the SLO gauges current until ctx is cancelled
+func (t *sloTracker) run(ctx context.Context) {
+	ticker := time.NewTicker(sloUpdateInterval)
+	defer ticker.Stop()
+	for {
+		t.updateMetrics()
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// burnRate is how many times faster than allowed the error budget of an
+// objective is spent when a fraction good of requests meets it
+func burnRate(good, objective float64) float64 {
+	if objective >= 1 {
+		return 0
+	}
+	return (1 - good) / (1 - objective)
+}
+
+// percentile estimates the latency under which a fraction q of the
+// searches finished, interpolating within the bucket it falls in
+func percentile(sum sloBucket, q float64) time.Duration {
+	if sum.requests == 0 {
+		return 0
+	}
+	rank := q * float64(sum.requests)
+	var seen float64
+	for i, count := range sum.latency {
+		if count == 0 || seen+float64(count) < rank {
+			seen += float64(count)
+			continue
+		}
+		// Searches slower than the last bound are reported at it
+		if i == len(sloLatencyBounds) {
+			return sloLatencyBounds[i-1]
+		}
+		var lower time.Duration
+		if i > 0 {
+			lower = sloLatencyBounds[i-1]
+		}
+		fraction := (rank - seen) / float64(count)
+		return lower + time.Duration(fraction*float64(sloLatencyBounds[i]-lower))
+	}
+	return sloLatencyBounds[len(sloLatencyBounds)-1]
+}
+
+// statusRecorder captures the status code a handler writes
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// trackSLO counts the requests of a handler toward the search SLOs; only
+// server errors spend the availability budget
+func (s *httpServer) trackSLO(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			next(w, r)
+			return
+		}
+		startTime := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r)
+		s.slo.record(time.Since(startTime), recorder.status >= 500)
+	}
+}
+
+// handleSLO reports the search SLOs over each window and the burn rate
+// alerts firing
+func (s *httpServer) handleSLO(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.slo.status())
+}
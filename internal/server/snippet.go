@@ -0,0 +1,180 @@
+package server
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// defaultSnippetLength is the snippet size in characters when
+// Config.SnippetLength is unset
+const defaultSnippetLength = 240
+
+// snippetEllipsis marks text cut from either side of a snippet
+const snippetEllipsis = "…"
+
+// snippetStopwords are query words too common to pick a passage by
+var snippetStopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "can": true, "do": true, "does": true, "for": true,
+	"from": true, "how": true, "i": true, "in": true, "is": true, "it": true,
+	"of": true, "on": true, "or": true, "the": true, "to": true, "what": true,
+	"when": true, "where": true, "which": true, "who": true, "why": true,
+	"with": true,
+}
+
+// applySnippets replaces the text of each result with the passage that
+// best matches the query
+func applySnippets(results []*SearchResultResponse, query string, maxLength int) {
+	terms := queryTerms(query)
+	for _, result := range results {
+		result.Snippet = snippet(result.Text, terms, maxLength)
+		result.Text = ""
+	}
+}
+
+// queryTerms returns the distinct lowercased words of a query, without
+// stopwords
+func queryTerms(query string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, word := range words(query) {
+		if snippetStopwords[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+	}
+	return terms
+}
+
+// words splits text into lowercased words
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// snippet returns the window of consecutive sentences of at most maxLength
+// characters that covers the most query terms, preferring the earliest.
+// A single sentence longer than maxLength is cut around its first match.
+func snippet(text string, terms []string, maxLength int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) <= maxLength {
+		return text
+	}
+
+	sentences := splitSentences(text)
+	best, bestStart, bestEnd := -1, 0, 0
+	for i := range sentences {
+		for j := i; j < len(sentences); j++ {
+			if j > i && sentences[j].end-sentences[i].start > maxLength {
+				break
+			}
+			score := termScore(sentences[i].start, sentences[j].end, text, terms)
+			if score > best {
+				best, bestStart, bestEnd = score, sentences[i].start, sentences[j].end
+			}
+		}
+	}
+	if best <= 0 {
+		bestStart, bestEnd = 0, len(text)
+	}
+
+	if bestEnd-bestStart > maxLength {
+		bestStart, bestEnd = cutAround(text, bestStart, bestEnd, terms, maxLength)
+	}
+
+	passage := strings.TrimSpace(text[bestStart:bestEnd])
+	if bestStart > 0 {
+		passage = snippetEllipsis + passage
+	}
+	if bestEnd < len(text) {
+		passage += snippetEllipsis
+	}
+	return passage
+}
+
+// snippetSentence is a sentence's byte range in the text
+type snippetSentence struct {
+	start, end int
+}
+
+// splitSentences splits text after sentence-ending punctuation followed by
+// a space
+func splitSentences(text string) []snippetSentence {
+	var sentences []snippetSentence
+	start := 0
+	for i := 0; i < len(text); i++ {
+		if (text[i] == '.' || text[i] == '!' || text[i] == '?') && i+1 < len(text) && text[i+1] == ' ' {
+			sentences = append(sentences, snippetSentence{start: start, end: i + 1})
+			start = i + 2
+		}
+	}
+	if start < len(text) {
+		sentences = append(sentences, snippetSentence{start: start, end: len(text)})
+	}
+	return sentences
+}
+
+// termScore scores a passage by the query terms it contains: each distinct
+// term counts far more than repeats, so passages covering more of the query
+// win. Terms of four or more letters also match longer words ("crawl"
+// matches "crawling").
+func termScore(start, end int, text string, terms []string) int {
+	counts := make(map[string]int)
+	for _, word := range words(text[start:end]) {
+		for _, term := range terms {
+			if word == term || (len(term) >= 4 && strings.HasPrefix(word, term)) {
+				counts[term]++
+			}
+		}
+	}
+
+	score := 0
+	for _, count := range counts {
+		score += 10 + min(count, 5)
+	}
+	return score
+}
+
+// cutAround narrows a passage to maxLength characters around the first
+// query term in it, at word boundaries
+func cutAround(text string, start, end int, terms []string, maxLength int) (int, int) {
+	lower := strings.ToLower(text[start:end])
+	match := -1
+	for _, term := range terms {
+		if i := strings.Index(lower, term); i >= 0 && (match < 0 || i < match) {
+			match = i
+		}
+	}
+
+	// Show some context before the match
+	from := start
+	if match > maxLength/4 {
+		from = start + match - maxLength/4
+	}
+	to := min(from+maxLength, end)
+	if to == end {
+		from = max(end-maxLength, start)
+	}
+
+	// Move inward to word boundaries, or at least rune boundaries
+	for from > start && !utf8.RuneStart(text[from]) {
+		from--
+	}
+	for to < end && !utf8.RuneStart(text[to]) {
+		to--
+	}
+	if from > start {
+		if i := strings.IndexByte(text[from:to], ' '); i >= 0 {
+			from += i + 1
+		}
+	}
+	if to < end {
+		if i := strings.LastIndexByte(text[from:to], ' '); i > 0 {
+			to = from + i
+		}
+	}
+	return from, to
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"ai-search/internal/store"
+)
+
+// defaultSuggestions and maxSuggestions bound the completions returned
+const (
+	defaultSuggestions = 10
+	maxSuggestions     = 50
+)
+
+// SuggestResponse represents query completions
+type SuggestResponse struct {
+	Query       string              `json:"query"`
+	Suggestions []*store.Suggestion `json:"suggestions"`
+}
+
+// handleSuggest completes a partial query from the suggestions built out of
+// the corpus by the suggest command (GET /api/suggest?q=mach&limit=10)
+func (s *httpServer) handleSuggest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.config.Store == nil {
+		http.Error(w, "Store not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	limit := defaultSuggestions
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(parsed, maxSuggestions)
+	}
+
+	query := r.URL.Query().Get("q")
+	prefix := suggestionPrefix(query)
+	suggestions := []*store.Suggestion{}
+	if prefix != "" {
+		found, err := s.config.Store.ListSuggestions(r.Context(), prefix, limit)
+		if err != nil {
+			log.Printf("Suggest error: %v", err)
+			http.Error(w, "Failed to list suggestions", http.StatusInternalServerError)
+			return
+		}
+		if found != nil {
+			suggestions = found
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&SuggestResponse{Query: query, Suggestions: suggestions})
+}
+
+// suggestionPrefix normalizes a partial query the way suggestions are
+// stored: lower-case words without punctuation, separated by single spaces.
+// A trailing space is kept, so "machine " only completes the next word.
+func suggestionPrefix(query string) string {
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	prefix := strings.Join(words, " ")
+	if prefix != "" && strings.TrimRightFunc(query, unicode.IsSpace) != query {
+		prefix += " "
+	}
+	return prefix
+}
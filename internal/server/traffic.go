@@ -0,0 +1,83 @@
+package server
+
+import (
+	"ai-search/internal/store"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// impressionTimeout bounds recording which documents a response showed
+const impressionTimeout = 5 * time.Second
+
+// TrafficResponse lists the documents that appear most in search results
+type TrafficResponse struct {
+	Documents []*store.DocumentTraffic `json:"documents"`
+	Total     int                      `json:"total"`
+}
+
+// recordImpressions counts the documents shown in a response so that
+// re-crawls can favour the content users actually read. It runs in the
+// background to keep it off the request path.
+func (s *httpServer) recordImpressions(results []*SearchResultResponse) {
+	if s.config.Store == nil || len(results) == 0 {
+		return
+	}
+
+	seen := make(map[string]bool)
+	var documentIDs []string
+	for _, result := range results {
+		if !seen[result.DocumentID] {
+			seen[result.DocumentID] = true
+			documentIDs = append(documentIDs, result.DocumentID)
+		}
+	}
+
+	s.goWorker(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), impressionTimeout)
+		defer cancel()
+		if err := s.config.Store.RecordImpressions(ctx, documentIDs); err != nil {
+			log.Printf("Failed to record impressions: %v", err)
+		}
+	})
+}
+
+// handleTraffic reports the documents that appear most in search results
+// with when they were last crawled
+func (s *httpServer) handleTraffic(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.config.Store == nil {
+		http.Error(w, "Store not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	limit := 50
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(parsed, 1000)
+	}
+
+	documents, err := s.config.Store.ListPopularDocuments(r.Context(), limit)
+	if err != nil {
+		log.Printf("Traffic report error: %v", err)
+		http.Error(w, "Failed to list document traffic", http.StatusInternalServerError)
+		return
+	}
+	if documents == nil {
+		documents = []*store.DocumentTraffic{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TrafficResponse{Documents: documents, Total: len(documents)})
+}
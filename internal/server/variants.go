@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+)
+
+// languageVariants looks up the translations the documents of the results
+// declare, by hreflang. Results from other instances, whose documents are
+// not in the store, have none, and a failed lookup finds none.
+func (s *httpServer) languageVariants(ctx context.Context, results []*SearchResultResponse) map[string]map[string]string {
+	if s.config.Store == nil || len(results) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var documentIDs []string
+	for _, result := range results {
+		if !seen[result.DocumentID] {
+			seen[result.DocumentID] = true
+			documentIDs = append(documentIDs, result.DocumentID)
+		}
+	}
+
+	variants, err := s.config.Store.GetLanguageVariants(ctx, documentIDs)
+	if err != nil {
+		log.Printf("Failed to get document language variants: %v", err)
+		return nil
+	}
+	return variants
+}
+
+// localizeResults points each result whose page declares a translation
+// into the preferred language at that translation
+func localizeResults(results []*SearchResultResponse, variants map[string]map[string]string, language string) {
+	for _, result := range results {
+		if localized := variantURL(variants[result.DocumentID], language); localized != result.URL {
+			result.LocalizedURL = localized
+		}
+	}
+}
+
+// variantURL returns the URL of the variant in a language: the one whose
+// hreflang is the language itself, or else the first of its regional
+// variants, "pt-br" before "pt-pt". It returns "" when there is none.
+func variantURL(variants map[string]string, language string) string {
+	if language == "" || len(variants) == 0 {
+		return ""
+	}
+	if variant, ok := variants[language]; ok {
+		return variant
+	}
+
+	var regional []string
+	for hreflang := range variants {
+		if strings.HasPrefix(hreflang, language+"-") {
+			regional = append(regional, hreflang)
+		}
+	}
+	if len(regional) == 0 {
+		return ""
+	}
+	sort.Strings(regional)
+	return variants[regional[0]]
+}
+
+// collapseLanguageVariants keeps the results of one page of each set of
+// language variants, the one ranked highest, and drops those of its
+// translations. Pages are variants of one another when one declares the
+// other, or both declare a common one.
+func collapseLanguageVariants(results []*SearchResultResponse, variants map[string]map[string]string) []*SearchResultResponse {
+	if len(variants) == 0 {
+		return results
+	}
+
+	kept := make(map[string]bool)    // Documents kept, by ID
+	claimed := make(map[string]bool) // URLs of the variants of kept documents
+	dropped := make(map[string]bool) // Documents dropped, by ID
+	var collapsed []*SearchResultResponse
+	for _, result := range results {
+		if kept[result.DocumentID] {
+			collapsed = append(collapsed, result)
+			continue
+		}
+		if dropped[result.DocumentID] {
+			continue
+		}
+
+		var urls []string
+		if result.URL != "" {
+			urls = append(urls, result.URL)
+		}
+		for _, variant := range variants[result.DocumentID] {
+			urls = append(urls, variant)
+		}
+		isVariant := false
+		for _, url := range urls {
+			if claimed[url] {
+				isVariant = true
+				break
+			}
+		}
+		if isVariant {
+			dropped[result.DocumentID] = true
+			continue
+		}
+
+		kept[result.DocumentID] = true
+		for _, url := range urls {
+			claimed[url] = true
+		}
+		collapsed = append(collapsed, result)
+	}
+	return collapsed
+}
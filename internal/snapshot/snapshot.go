@@ -0,0 +1,581 @@
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	chroma "github.com/amikos-tech/chroma-go/pkg/api/v2"
+	chromaembeddings "github.com/amikos-tech/chroma-go/pkg/embeddings"
+)
+
+// Snapshotter defines the interface for coordinated index snapshots
+type Snapshotter interface {
+	// Create takes a snapshot of Elasticsearch, ChromaDB and PostgreSQL
+	Create(ctx context.Context) (*Manifest, error)
+
+	// Restore rebuilds all three systems from the given snapshot
+	Restore(ctx context.Context, id string) error
+
+	// List returns all available snapshots, newest first
+	List() ([]*Manifest, error)
+}
+
+// Manifest describes a snapshot and the artifacts it is made of
+type Manifest struct {
+	ID             string    `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	ESRepository   string    `json:"es_repository"`
+	ESSnapshot     string    `json:"es_snapshot"`
+	ESIndex        string    `json:"es_index"`
+	ChromaFile     string    `json:"chroma_file"`
+	ChromaRecords  int       `json:"chroma_records"`
+	CollectionName string    `json:"collection_name"`
+	PostgresFile   string    `json:"postgres_file"`
+
+	// CollectionMetadata holds the collection's settings, such as its
+	// chunking, which the collection is recreated with on restore
+	CollectionMetadata json.RawMessage `json:"collection_metadata,omitempty"`
+
+	// Shadow is the snapshot of the shadow collection, if one is configured
+	Shadow *ShadowManifest `json:"shadow,omitempty"`
+}
+
+// ShadowManifest describes the artifacts of the shadow collection
+type ShadowManifest struct {
+	ESIndex            string          `json:"es_index"`
+	ChromaFile         string          `json:"chroma_file"`
+	ChromaRecords      int             `json:"chroma_records"`
+	CollectionName     string          `json:"collection_name"`
+	CollectionMetadata json.RawMessage `json:"collection_metadata,omitempty"`
+}
+
+// Config holds snapshot configuration
+type Config struct {
+	Dir            string // Local directory for manifests, Chroma dumps and pg_dump files
+	Retain         int    // Number of snapshots to keep, 0 keeps all
+	ElasticURL     string
+	ESIndex        string
+	ESRepository   string
+	ESLocation     string // Repository path on the Elasticsearch host (must be in path.repo)
+	ChromaURL      string
+	CollectionName string
+	DatabaseHost   string
+	DatabasePort   int
+	DatabaseName   string
+	DatabaseUser   string
+	DatabasePass   string
+
+	// Shadow collection and its Elasticsearch index, empty when not configured
+	ShadowCollection string
+	ShadowESIndex    string
+}
+
+// chromaRecord is a single line of a Chroma collection dump
+type chromaRecord struct {
+	ID        string                 `json:"id"`
+	Document  string                 `json:"document"`
+	Metadata  map[string]interface{} `json:"metadata"`
+	Embedding []float32              `json:"embedding"`
+}
+
+// snapshotter implements the Snapshotter interface
+type snapshotter struct {
+	config       Config
+	httpClient   *http.Client
+	chromaClient chroma.Client
+}
+
+// NewSnapshotter creates a new snapshotter instance
+func NewSnapshotter(config Config) (Snapshotter, error) {
+	if config.Dir == "" {
+		config.Dir = "./snapshots"
+	}
+	if config.ElasticURL == "" {
+		config.ElasticURL = "http://localhost:9200"
+	}
+	if config.ESIndex == "" {
+		config.ESIndex = "ai_search_documents"
+	}
+	if config.ESRepository == "" {
+		config.ESRepository = "ai_search_backups"
+	}
+	if config.ESLocation == "" {
+		config.ESLocation = "/usr/share/elasticsearch/snapshots"
+	}
+	if config.ChromaURL == "" {
+		config.ChromaURL = "http://localhost:8000"
+	}
+	if config.CollectionName == "" {
+		config.CollectionName = "ai_search_documents"
+	}
+
+	if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	chromaClient, err := chroma.NewHTTPClient(
+		chroma.WithBaseURL(config.ChromaURL),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ChromaDB client: %w", err)
+	}
+
+	return &snapshotter{
+		config: config,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Minute,
+		},
+		chromaClient: chromaClient,
+	}, nil
+}
+
+// Create takes a snapshot of Elasticsearch, ChromaDB and PostgreSQL
+func (s *snapshotter) Create(ctx context.Context) (*Manifest, error) {
+	// Milliseconds keep snapshots taken within a second apart; Mkdir fails
+	// rather than reuse the directory of one taken in the same millisecond
+	now := time.Now().UTC()
+	id := fmt.Sprintf("%s-%03d", now.Format("20060102-150405"), now.Nanosecond()/int(time.Millisecond))
+	snapshotDir := filepath.Join(s.config.Dir, id)
+	if err := os.MkdirAll(s.config.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	if err := os.Mkdir(snapshotDir, 0o755); err != nil {
+		if os.IsExist(err) {
+			return nil, fmt.Errorf("snapshot %s already exists", id)
+		}
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	manifest := &Manifest{
+		ID:             id,
+		CreatedAt:      now,
+		ESRepository:   s.config.ESRepository,
+		ESSnapshot:     "snapshot-" + id,
+		ESIndex:        s.config.ESIndex,
+		ChromaFile:     "chroma.jsonl",
+		CollectionName: s.config.CollectionName,
+		PostgresFile:   "postgres.dump",
+	}
+
+	// PostgreSQL is dumped first since it is the source of truth the
+	// indexes are rebuilt from; anything indexed afterwards is a superset.
+	if err := s.dumpPostgres(ctx, filepath.Join(snapshotDir, manifest.PostgresFile)); err != nil {
+		os.RemoveAll(snapshotDir)
+		return nil, fmt.Errorf("failed to dump PostgreSQL: %w", err)
+	}
+
+	count, metadata, err := s.dumpChroma(ctx, manifest.CollectionName, filepath.Join(snapshotDir, manifest.ChromaFile))
+	if err != nil {
+		os.RemoveAll(snapshotDir)
+		return nil, fmt.Errorf("failed to dump ChromaDB: %w", err)
+	}
+	manifest.ChromaRecords = count
+	manifest.CollectionMetadata = metadata
+
+	if s.config.ShadowCollection != "" {
+		shadow := &ShadowManifest{
+			ESIndex:        s.config.ShadowESIndex,
+			ChromaFile:     "shadow.jsonl",
+			CollectionName: s.config.ShadowCollection,
+		}
+		shadow.ChromaRecords, shadow.CollectionMetadata, err = s.dumpChroma(ctx, shadow.CollectionName, filepath.Join(snapshotDir, shadow.ChromaFile))
+		if err != nil {
+			os.RemoveAll(snapshotDir)
+			return nil, fmt.Errorf("failed to dump shadow collection: %w", err)
+		}
+		manifest.Shadow = shadow
+	}
+
+	if err := s.snapshotElasticsearch(ctx, manifest.ESSnapshot, manifest.indices()); err != nil {
+		os.RemoveAll(snapshotDir)
+		return nil, fmt.Errorf("failed to snapshot Elasticsearch: %w", err)
+	}
+
+	// The manifest is written last so only complete snapshots are listed
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(snapshotDir, "manifest.json"), data, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	if err := s.prune(ctx); err != nil {
+		return manifest, fmt.Errorf("snapshot created but pruning failed: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// Restore rebuilds all three systems from the given snapshot
+func (s *snapshotter) Restore(ctx context.Context, id string) error {
+	manifest, err := s.readManifest(id)
+	if err != nil {
+		return err
+	}
+	snapshotDir := filepath.Join(s.config.Dir, id)
+
+	if err := s.restorePostgres(ctx, filepath.Join(snapshotDir, manifest.PostgresFile)); err != nil {
+		return fmt.Errorf("failed to restore PostgreSQL: %w", err)
+	}
+
+	if err := s.restoreChroma(ctx, manifest.CollectionName, manifest.CollectionMetadata, filepath.Join(snapshotDir, manifest.ChromaFile)); err != nil {
+		return fmt.Errorf("failed to restore ChromaDB: %w", err)
+	}
+	if shadow := manifest.Shadow; shadow != nil {
+		if err := s.restoreChroma(ctx, shadow.CollectionName, shadow.CollectionMetadata, filepath.Join(snapshotDir, shadow.ChromaFile)); err != nil {
+			return fmt.Errorf("failed to restore shadow collection: %w", err)
+		}
+	}
+
+	if err := s.restoreElasticsearch(ctx, manifest); err != nil {
+		return fmt.Errorf("failed to restore Elasticsearch: %w", err)
+	}
+
+	return nil
+}
+
+// List returns all available snapshots, newest first
+func (s *snapshotter) List() ([]*Manifest, error) {
+	entries, err := os.ReadDir(s.config.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot directory: %w", err)
+	}
+
+	var manifests []*Manifest
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		manifest, err := s.readManifest(entry.Name())
+		if err != nil {
+			continue // Incomplete snapshot
+		}
+		manifests = append(manifests, manifest)
+	}
+
+	sort.Slice(manifests, func(i, j int) bool {
+		return manifests[i].CreatedAt.After(manifests[j].CreatedAt)
+	})
+
+	return manifests, nil
+}
+
+// indices returns the Elasticsearch indices the snapshot covers
+func (m *Manifest) indices() []string {
+	indices := []string{m.ESIndex}
+	if m.Shadow != nil && m.Shadow.ESIndex != "" {
+		indices = append(indices, m.Shadow.ESIndex)
+	}
+	return indices
+}
+
+// readManifest loads the manifest of a snapshot
+func (s *snapshotter) readManifest(id string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(s.config.Dir, id, "manifest.json"))
+	if err != nil {
+		return nil, fmt.Errorf("snapshot not found: %s", id)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest for snapshot %s: %w", id, err)
+	}
+
+	return &manifest, nil
+}
+
+// prune removes snapshots beyond the retention count
+func (s *snapshotter) prune(ctx context.Context) error {
+	if s.config.Retain <= 0 {
+		return nil
+	}
+
+	manifests, err := s.List()
+	if err != nil {
+		return err
+	}
+
+	for _, manifest := range manifests[min(len(manifests), s.config.Retain):] {
+		url := fmt.Sprintf("%s/_snapshot/%s/%s", s.config.ElasticURL, manifest.ESRepository, manifest.ESSnapshot)
+		if err := s.doElastic(ctx, "DELETE", url, nil); err != nil {
+			return err
+		}
+		if err := os.RemoveAll(filepath.Join(s.config.Dir, manifest.ID)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dumpPostgres runs pg_dump in custom format
+func (s *snapshotter) dumpPostgres(ctx context.Context, path string) error {
+	cmd := exec.CommandContext(ctx, "pg_dump",
+		"--format=custom",
+		"--file="+path,
+		"--host="+s.config.DatabaseHost,
+		"--port="+strconv.Itoa(s.config.DatabasePort),
+		"--username="+s.config.DatabaseUser,
+		s.config.DatabaseName,
+	)
+	return s.runPostgresTool(cmd)
+}
+
+// restorePostgres runs pg_restore, replacing existing tables
+func (s *snapshotter) restorePostgres(ctx context.Context, path string) error {
+	cmd := exec.CommandContext(ctx, "pg_restore",
+		"--clean",
+		"--if-exists",
+		"--host="+s.config.DatabaseHost,
+		"--port="+strconv.Itoa(s.config.DatabasePort),
+		"--username="+s.config.DatabaseUser,
+		"--dbname="+s.config.DatabaseName,
+		path,
+	)
+	return s.runPostgresTool(cmd)
+}
+
+// runPostgresTool runs a PostgreSQL client binary with credentials from config
+func (s *snapshotter) runPostgresTool(cmd *exec.Cmd) error {
+	var stderr bytes.Buffer
+	cmd.Env = append(os.Environ(), "PGPASSWORD="+s.config.DatabasePass)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", filepath.Base(cmd.Path), err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// dumpChroma writes every record of a collection as JSON lines and
+// returns their count along with the collection's metadata
+func (s *snapshotter) dumpChroma(ctx context.Context, name, path string) (int, json.RawMessage, error) {
+	collection, err := s.chromaClient.GetCollection(ctx, name)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var metadata json.RawMessage
+	if collection.Metadata() != nil {
+		if metadata, err = collection.Metadata().MarshalJSON(); err != nil {
+			return 0, nil, fmt.Errorf("failed to encode collection metadata: %w", err)
+		}
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+
+	const pageSize = 500
+	count := 0
+	for offset := 0; ; offset += pageSize {
+		result, err := collection.Get(ctx,
+			chroma.WithLimitGet(pageSize),
+			chroma.WithOffsetGet(offset),
+			chroma.WithIncludeGet(chroma.IncludeDocuments, chroma.IncludeMetadatas, chroma.IncludeEmbeddings),
+		)
+		if err != nil {
+			return count, nil, err
+		}
+
+		ids := result.GetIDs()
+		documents := result.GetDocuments()
+		metadatas := result.GetMetadatas()
+		embeddings := result.GetEmbeddings()
+
+		for j, id := range ids {
+			record := chromaRecord{
+				ID:       string(id),
+				Metadata: make(map[string]interface{}),
+			}
+			if j < len(documents) && documents[j] != nil {
+				record.Document = documents[j].ContentString()
+			}
+			if j < len(metadatas) && metadatas[j] != nil {
+				// Round-trip through JSON since the metadata interface has no key listing
+				if data, err := json.Marshal(metadatas[j]); err == nil {
+					json.Unmarshal(data, &record.Metadata)
+				}
+			}
+			if j < len(embeddings) && embeddings[j] != nil {
+				record.Embedding = embeddings[j].ContentAsFloat32()
+			}
+
+			if err := encoder.Encode(record); err != nil {
+				return count, nil, err
+			}
+			count++
+		}
+
+		if len(ids) < pageSize {
+			break
+		}
+	}
+
+	return count, metadata, writer.Flush()
+}
+
+// restoreChroma recreates a collection with its metadata from a JSON
+// lines dump
+func (s *snapshotter) restoreChroma(ctx context.Context, name string, rawMetadata json.RawMessage, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var options []chroma.CreateCollectionOption
+	if len(rawMetadata) > 0 {
+		metadata := chroma.NewEmptyMetadata()
+		if err := json.Unmarshal(rawMetadata, metadata); err != nil {
+			return fmt.Errorf("invalid collection metadata: %w", err)
+		}
+		options = append(options, chroma.WithCollectionMetadataCreate(metadata))
+	}
+
+	// Ignore the error: the collection may not exist yet
+	s.chromaClient.DeleteCollection(ctx, name)
+
+	collection, err := s.chromaClient.CreateCollection(ctx, name, options...)
+	if err != nil {
+		return err
+	}
+
+	const batchSize = 500
+	var ids []chroma.DocumentID
+	var texts []string
+	var metadatas []chroma.DocumentMetadata
+	var embeddings []chromaembeddings.Embedding
+
+	flush := func() error {
+		if len(ids) == 0 {
+			return nil
+		}
+		err := collection.Add(ctx,
+			chroma.WithIDs(ids...),
+			chroma.WithTexts(texts...),
+			chroma.WithMetadatas(metadatas...),
+			chroma.WithEmbeddings(embeddings...),
+		)
+		ids, texts, metadatas, embeddings = nil, nil, nil, nil
+		return err
+	}
+
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	for {
+		var record chromaRecord
+		if err := decoder.Decode(&record); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("invalid dump record: %w", err)
+		}
+
+		metadata, err := chroma.NewDocumentMetadataFromMap(record.Metadata)
+		if err != nil {
+			return fmt.Errorf("invalid metadata for %s: %w", record.ID, err)
+		}
+
+		ids = append(ids, chroma.DocumentID(record.ID))
+		texts = append(texts, record.Document)
+		metadatas = append(metadatas, metadata)
+		embeddings = append(embeddings, chromaembeddings.NewEmbeddingFromFloat32(record.Embedding))
+
+		if len(ids) >= batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return flush()
+}
+
+// snapshotElasticsearch registers the repository if needed and takes a
+// snapshot of the given indices
+func (s *snapshotter) snapshotElasticsearch(ctx context.Context, name string, indices []string) error {
+	repoURL := fmt.Sprintf("%s/_snapshot/%s", s.config.ElasticURL, s.config.ESRepository)
+	repo := map[string]interface{}{
+		"type": "fs",
+		"settings": map[string]interface{}{
+			"location": s.config.ESLocation,
+		},
+	}
+	if err := s.doElastic(ctx, "PUT", repoURL, repo); err != nil {
+		return fmt.Errorf("failed to register repository: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/%s?wait_for_completion=true", repoURL, name)
+	body := map[string]interface{}{
+		"indices":              strings.Join(indices, ","),
+		"include_global_state": false,
+	}
+	return s.doElastic(ctx, "PUT", url, body)
+}
+
+// restoreElasticsearch replaces the indices with the snapshotted copies
+func (s *snapshotter) restoreElasticsearch(ctx context.Context, manifest *Manifest) error {
+	// Remove the live indices; a missing index is not an error here
+	for _, index := range manifest.indices() {
+		indexURL := fmt.Sprintf("%s/%s", s.config.ElasticURL, index)
+		if err := s.doElastic(ctx, "DELETE", indexURL, nil); err != nil && !strings.Contains(err.Error(), "404") {
+			return err
+		}
+	}
+
+	url := fmt.Sprintf("%s/_snapshot/%s/%s/_restore?wait_for_completion=true",
+		s.config.ElasticURL, manifest.ESRepository, manifest.ESSnapshot)
+	body := map[string]interface{}{
+		"indices":              strings.Join(manifest.indices(), ","),
+		"include_global_state": false,
+	}
+	return s.doElastic(ctx, "POST", url, body)
+}
+
+// doElastic sends a JSON request to Elasticsearch and checks the status
+func (s *snapshotter) doElastic(ctx context.Context, method, url string, payload interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Elasticsearch request failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	return nil
+}
@@ -6,25 +6,202 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // Store defines the interface for persistent storage
 type Store interface {
-	// SaveDocument saves a document
+	// SaveDocument saves a document. Saving over a soft-deleted document
+	// keeps it deleted and sets doc.DeletedAt.
 	SaveDocument(ctx context.Context, doc *Document) error
 
 	// GetDocument retrieves a document by ID
 	GetDocument(ctx context.Context, id string) (*Document, error)
 
+	// ListDocuments retrieves all stored documents
+	ListDocuments(ctx context.Context) ([]*Document, error)
+
+	// ListFingerprints lists the near-duplicate fingerprints of the
+	// documents that are not soft-deleted, without reading their content
+	ListFingerprints(ctx context.Context) ([]*DocumentFingerprint, error)
+
+	// SetFingerprint records the fingerprint of a document saved without one
+	SetFingerprint(ctx context.Context, id string, fingerprint uint64) error
+
+	// DeleteDocument deletes a document and its chunks
+	DeleteDocument(ctx context.Context, id string) error
+
+	// SoftDeleteDocument marks a document as deleted without removing it
+	SoftDeleteDocument(ctx context.Context, id string) error
+
+	// RestoreDocument clears the deletion mark of a soft-deleted document
+	RestoreDocument(ctx context.Context, id string) error
+
+	// ListDeletedDocumentIDs returns soft-deleted document IDs, limited to
+	// those deleted before the given time unless it is zero
+	ListDeletedDocumentIDs(ctx context.Context, deletedBefore time.Time) ([]string, error)
+
 	// SaveChunks saves document chunks
 	SaveChunks(ctx context.Context, docID string, chunks []*chunker.Chunk) error
 
 	// GetChunks retrieves chunks for a document
 	GetChunks(ctx context.Context, docID string) ([]*chunker.Chunk, error)
 
+	// GetChunkDocumentID returns the ID of the document a chunk belongs to
+	GetChunkDocumentID(ctx context.Context, chunkID string) (string, error)
+
+	// AddFrontierURLs records pending URLs of a crawl, ignoring known URLs
+	AddFrontierURLs(ctx context.Context, crawlID string, urls []*FrontierURL) error
+
+	// MarkFrontierVisited marks a crawl URL as visited
+	MarkFrontierVisited(ctx context.Context, crawlID string, url string) error
+
+	// GetFrontier retrieves the pending and visited URLs of a crawl
+	GetFrontier(ctx context.Context, crawlID string) (pending []*FrontierURL, visited []string, err error)
+
+	// ClearFrontier removes the pending and visited URLs of a crawl
+	ClearFrontier(ctx context.Context, crawlID string) error
+
+	// RecordImpressions counts one appearance in search results for each document
+	RecordImpressions(ctx context.Context, documentIDs []string) error
+
+	// ListPopularDocuments returns the documents that appeared most in search results
+	ListPopularDocuments(ctx context.Context, limit int) ([]*DocumentTraffic, error)
+
+	// ListRecrawlCandidates returns every live document with the time since
+	// it was last crawled and its search traffic
+	ListRecrawlCandidates(ctx context.Context) ([]*RecrawlCandidate, error)
+
+	// MarkCrawled records that a document was just crawled and the HTTP
+	// status it was served with, 0 when the fetch failed without a response
+	MarkCrawled(ctx context.Context, id string, status int) error
+
+	// CarryProvenance hands the crawl history of a document to the document
+	// replacing it after its content changed, counting the change
+	CarryProvenance(ctx context.Context, fromID, toID string) error
+
+	// GetProvenance returns the crawl history of the given documents,
+	// skipping those that do not exist
+	GetProvenance(ctx context.Context, ids []string) (map[string]*Provenance, error)
+
+	// GetLanguageVariants returns the URLs of the translations the given
+	// documents declare, by hreflang, skipping documents that declare none
+	GetLanguageVariants(ctx context.Context, ids []string) (map[string]map[string]string, error)
+
+	// UpdateDocumentMeta sets the given metadata keys of a document,
+	// keeping the others
+	UpdateDocumentMeta(ctx context.Context, id string, meta map[string]interface{}) error
+
+	// AddAlternateURL records url as another address of a stored document
+	// whose own URL differs. It returns false if no such document exists.
+	AddAlternateURL(ctx context.Context, id string, url string) (bool, error)
+
+	// GetRobotsRules returns the unexpired robots.txt rules stored for a
+	// host, or nil when there are none
+	GetRobotsRules(ctx context.Context, host string) ([]byte, error)
+
+	// SaveRobotsRules stores robots.txt rules for a host for the given time
+	SaveRobotsRules(ctx context.Context, host string, rules []byte, ttl time.Duration) error
+
+	// AddDeadLetter records a pipeline item that permanently failed
+	AddDeadLetter(ctx context.Context, item *DeadLetter) error
+
+	// ListDeadLetters returns failed pipeline items, oldest first, limited
+	// to one stage unless stage is empty
+	ListDeadLetters(ctx context.Context, stage string) ([]*DeadLetter, error)
+
+	// UpdateDeadLetter records another failed attempt at a dead letter
+	UpdateDeadLetter(ctx context.Context, id int64, errMsg string) error
+
+	// DeleteDeadLetter removes a dead letter once it has been reprocessed
+	DeleteDeadLetter(ctx context.Context, id int64) error
+
+	// LogQuery records a search and the chunks it returned, in rank order
+	LogQuery(ctx context.Context, entry *QueryLog) error
+
+	// ListQueryLogs returns the logged searches for the given queries
+	ListQueryLogs(ctx context.Context, queries []string) ([]*QueryLog, error)
+
+	// AddFeedback records whether a chunk was relevant to a query,
+	// replacing earlier feedback on the same pair
+	AddFeedback(ctx context.Context, feedback *Feedback) error
+
+	// ListFeedback returns all relevance feedback
+	ListFeedback(ctx context.Context) ([]*Feedback, error)
+
+	// ListQueryLogsBetween returns the searches logged from one time up to
+	// another, oldest first, at most limit of them unless limit is 0
+	ListQueryLogsBetween(ctx context.Context, from, to time.Time, limit int) ([]*QueryLog, error)
+
+	// ListFeedbackBetween returns the feedback recorded from one time up to
+	// another, oldest first, at most limit of them unless limit is 0
+	ListFeedbackBetween(ctx context.Context, from, to time.Time, limit int) ([]*Feedback, error)
+
+	// SaveCrawlRun records the stats of a finished crawl
+	SaveCrawlRun(ctx context.Context, run *CrawlRun) error
+
+	// ListCrawlRunsBetween returns the crawls recorded from one time up to
+	// another, oldest first, at most limit of them unless limit is 0
+	ListCrawlRunsBetween(ctx context.Context, from, to time.Time, limit int) ([]*CrawlRun, error)
+
+	// AddCrawlUpdate records a change to a running crawl for its processes
+	// to apply
+	AddCrawlUpdate(ctx context.Context, update *CrawlUpdate) error
+
+	// ListCrawlUpdates returns the updates of a crawl recorded after the
+	// one with the given ID, oldest first
+	ListCrawlUpdates(ctx context.Context, crawlID string, afterID int64) ([]*CrawlUpdate, error)
+
+	// CurrentTimestamp returns the database's current time as its
+	// created_at columns record it, in the database's time zone, for
+	// windows over them that the application's clock would skew
+	CurrentTimestamp(ctx context.Context) (time.Time, error)
+
+	// GetExportCursor returns the time up to which an export has shipped
+	// its rows, zero when it never ran
+	GetExportCursor(ctx context.Context, name string) (time.Time, error)
+
+	// SaveExportCursor records the time up to which an export has shipped
+	// its rows
+	SaveExportCursor(ctx context.Context, name string, until time.Time) error
+
+	// AddAPIKeyUsage adds requests and LLM tokens to an API key's usage in
+	// a month, formatted 2006-01
+	AddAPIKeyUsage(ctx context.Context, keyID, month string, requests, tokens int64) error
+
+	// GetAPIKeyUsage returns an API key's usage in a month, zero when it
+	// made no requests
+	GetAPIKeyUsage(ctx context.Context, keyID, month string) (*APIKeyUsage, error)
+
+	// GetChunkTexts returns the text of the given chunks, skipping chunks
+	// that no longer exist or belong to soft-deleted documents
+	GetChunkTexts(ctx context.Context, chunkIDs []string) (map[string]string, error)
+
+	// ReplaceSuggestions replaces the query suggestions with a newly built
+	// set
+	ReplaceSuggestions(ctx context.Context, suggestions []*Suggestion) error
+
+	// ListSuggestions returns the suggestions starting with a prefix, best
+	// first
+	ListSuggestions(ctx context.Context, prefix string, limit int) ([]*Suggestion, error)
+
+	// AddSavedSearch registers a query whose new matches are alerted
+	AddSavedSearch(ctx context.Context, search *SavedSearch) error
+
+	// ListSavedSearches returns every saved search, oldest first
+	ListSavedSearches(ctx context.Context) ([]*SavedSearch, error)
+
+	// DeleteSavedSearch removes a saved search. It returns false if no
+	// such search exists.
+	DeleteSavedSearch(ctx context.Context, id int64) (bool, error)
+
+	// RecordAlerts records that a saved search matched documents and
+	// returns those it had not matched before, so that each is alerted once
+	RecordAlerts(ctx context.Context, searchID int64, documentIDs []string) ([]string, error)
+
 	// Close closes the store
 	Close() error
 }
@@ -38,6 +215,154 @@ type Document struct {
 	Meta      map[string]interface{}
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	DeletedAt *time.Time
+
+	// Fingerprint is the SimHash of the content for near-duplicate
+	// detection, nil when it was saved without one
+	Fingerprint *uint64
+
+	// Provenance is the crawl history of the document, filled when it is
+	// read back and ignored when it is saved
+	Provenance *Provenance
+}
+
+// DocumentFingerprint is what near-duplicate detection reads of a document
+type DocumentFingerprint struct {
+	ID          string
+	URL         string
+	Title       string
+	Length      int     // Of the content, in bytes
+	Fingerprint *uint64 // Nil for documents saved without one
+}
+
+// LanguageVariantsMetaKey is the document metadata key holding the URLs of
+// the translations a page declares, by hreflang
+const LanguageVariantsMetaKey = "language_variants"
+
+// Provenance is the crawl history of a document, for judging how fresh and
+// reliable it is. It follows a page across content changes, which give it a
+// new document ID.
+type Provenance struct {
+	FirstSeen    time.Time `json:"first_seen"`
+	LastCrawled  time.Time `json:"last_crawled"`
+	TimesChanged int       `json:"times_changed"` // Recrawls that found new content
+	LastStatus   int       `json:"last_status"`   // HTTP status of the last crawl, 0 when it got no response
+}
+
+// FrontierURL represents a URL in a persisted crawl frontier
+type FrontierURL struct {
+	URL   string
+	Depth int
+}
+
+// RecrawlCandidate is a document considered for recrawling
+type RecrawlCandidate struct {
+	DocumentID  string
+	URL         string
+	ContentHash string
+	Age         time.Duration // Time since the document was last crawled
+	Impressions int64
+}
+
+// DeadLetter is a pipeline item that failed after all retries, kept with
+// what is needed to process it again
+type DeadLetter struct {
+	ID         int64     `json:"id"`
+	Stage      string    `json:"stage"` // Pipeline stage that failed, e.g. "elasticsearch"
+	DocumentID string    `json:"document_id"`
+	ChunkID    string    `json:"chunk_id,omitempty"`
+	Payload    []byte    `json:"payload,omitempty"` // JSON of the item as it was to be written
+	Error      string    `json:"error"`
+	Attempts   int       `json:"attempts"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// QueryLog is a search and the chunks it returned
+type QueryLog struct {
+	ID        int64     `json:"id"`
+	Query     string    `json:"query"`
+	Language  string    `json:"language,omitempty"`
+	ChunkIDs  []string  `json:"chunk_ids"` // In rank order
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Feedback is a judgement of whether a search result answered a query
+type Feedback struct {
+	Query     string    `json:"query"`
+	ChunkID   string    `json:"chunk_id"`
+	Relevant  bool      `json:"relevant"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CrawlRun is the outcome of a crawl
+type CrawlRun struct {
+	ID              int64         `json:"id"`
+	CrawlID         string        `json:"crawl_id"`
+	StartURL        string        `json:"start_url"`
+	Fetched         int64         `json:"fetched"`
+	Indexed         int64         `json:"indexed"`
+	Failed          int64         `json:"failed"`
+	SkippedByRobots int64         `json:"skipped_by_robots"`
+	Duplicates      int64         `json:"duplicates"`
+	Filtered        int64         `json:"filtered"`
+	BytesDownloaded int64         `json:"bytes_downloaded"`
+	Duration        time.Duration `json:"duration"`
+	Stopped         bool          `json:"stopped"` // Interrupted before it finished
+	CreatedAt       time.Time     `json:"created_at"`
+}
+
+// CrawlUpdate is a change to a running crawl: seeds to add, URL patterns
+// to stop crawling and a lower rate limit
+type CrawlUpdate struct {
+	ID           int64     `json:"id"`
+	CrawlID      string    `json:"crawl_id"`
+	Seeds        []string  `json:"seeds,omitempty"`
+	DenyPatterns []string  `json:"deny_patterns,omitempty"` // Regular expressions matched against URLs
+	RateLimit    float64   `json:"rate_limit,omitempty"`    // Requests per second to each host, 0 to keep it
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// APIKeyUsage is the metered usage of an API key in a month
+type APIKeyUsage struct {
+	KeyID    string `json:"key_id"`
+	Month    string `json:"month"` // 2006-01
+	Requests int64  `json:"requests"`
+	Tokens   int64  `json:"tokens"` // LLM tokens consumed by its requests
+}
+
+// Suggestion is a phrase offered to complete queries, extracted from the
+// corpus
+type Suggestion struct {
+	Phrase    string  `json:"phrase"`
+	Documents int     `json:"documents"` // Documents the phrase occurs in
+	Score     float64 `json:"score"`
+}
+
+// SavedSearch is a query run against newly indexed documents, whose
+// matches scoring at least MinScore are sent to its webhook or email
+type SavedSearch struct {
+	ID       int64             `json:"id"`
+	Name     string            `json:"name"`
+	Query    string            `json:"query"`
+	Filters  map[string]string `json:"filters,omitempty"`
+	MinScore float32           `json:"min_score"`
+	Webhook  string            `json:"webhook,omitempty"`
+	Email    string            `json:"email,omitempty"`
+
+	// Principals limit matches to documents whose ACL grants one of them;
+	// without any every document can match
+	Principals []string `json:"principals,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DocumentTraffic counts how often a document appeared in search results
+type DocumentTraffic struct {
+	DocumentID  string    `json:"document_id"`
+	URL         string    `json:"url"`
+	Impressions int64     `json:"impressions"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+	UpdatedAt   time.Time `json:"updated_at"` // When the document was last crawled
 }
 
 // Config holds store configuration
@@ -123,6 +448,152 @@ func (s *postgresStore) initSchema() error {
 		FOREIGN KEY (document_id) REFERENCES documents (id) ON DELETE CASCADE
 	);`
 
+	// Create crawl frontier table
+	frontierSQL := `
+	CREATE TABLE IF NOT EXISTS crawl_frontier (
+		crawl_id TEXT NOT NULL,
+		url TEXT NOT NULL,
+		depth INTEGER NOT NULL,
+		visited BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (crawl_id, url)
+	);`
+
+	// Create document traffic table
+	trafficSQL := `
+	CREATE TABLE IF NOT EXISTS document_traffic (
+		document_id VARCHAR(255) PRIMARY KEY,
+		impressions BIGINT NOT NULL DEFAULT 0,
+		last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (document_id) REFERENCES documents (id) ON DELETE CASCADE
+	);`
+
+	// Create robots.txt rules table
+	robotsSQL := `
+	CREATE TABLE IF NOT EXISTS robots_rules (
+		host TEXT PRIMARY KEY,
+		rules JSONB NOT NULL,
+		expires_at TIMESTAMP NOT NULL
+	);`
+
+	// Create dead-letter table
+	deadLettersSQL := `
+	CREATE TABLE IF NOT EXISTS dead_letters (
+		id BIGSERIAL PRIMARY KEY,
+		stage VARCHAR(50) NOT NULL,
+		document_id VARCHAR(255) NOT NULL,
+		chunk_id VARCHAR(255),
+		payload JSONB,
+		error TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 1,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (document_id) REFERENCES documents (id) ON DELETE CASCADE
+	);`
+
+	// Create search query log and feedback tables
+	queryLogSQL := `
+	CREATE TABLE IF NOT EXISTS query_log (
+		id BIGSERIAL PRIMARY KEY,
+		query TEXT NOT NULL,
+		language VARCHAR(35),
+		chunk_ids TEXT[] NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	feedbackSQL := `
+	CREATE TABLE IF NOT EXISTS search_feedback (
+		query TEXT NOT NULL,
+		chunk_id VARCHAR(255) NOT NULL,
+		relevant BOOLEAN NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (query, chunk_id)
+	);`
+
+	// Create crawl run and export cursor tables for analytics exports
+	crawlRunsSQL := `
+	CREATE TABLE IF NOT EXISTS crawl_runs (
+		id BIGSERIAL PRIMARY KEY,
+		crawl_id TEXT NOT NULL,
+		start_url TEXT NOT NULL,
+		fetched BIGINT NOT NULL,
+		indexed BIGINT NOT NULL,
+		failed BIGINT NOT NULL,
+		skipped_by_robots BIGINT NOT NULL,
+		duplicates BIGINT NOT NULL,
+		filtered BIGINT NOT NULL,
+		bytes_downloaded BIGINT NOT NULL,
+		duration_ms BIGINT NOT NULL,
+		stopped BOOLEAN NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	crawlUpdatesSQL := `
+	CREATE TABLE IF NOT EXISTS crawl_updates (
+		id BIGSERIAL PRIMARY KEY,
+		crawl_id TEXT NOT NULL,
+		seeds TEXT[],
+		deny_patterns TEXT[],
+		rate_limit DOUBLE PRECISION NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	exportCursorsSQL := `
+	CREATE TABLE IF NOT EXISTS export_cursors (
+		name TEXT PRIMARY KEY,
+		exported_until TIMESTAMP NOT NULL
+	);`
+
+	apiKeyUsageSQL := `
+	CREATE TABLE IF NOT EXISTS api_key_usage (
+		key_id TEXT NOT NULL,
+		month TEXT NOT NULL,
+		requests BIGINT NOT NULL DEFAULT 0,
+		tokens BIGINT NOT NULL DEFAULT 0,
+		PRIMARY KEY (key_id, month)
+	);`
+
+	suggestionsSQL := `
+	CREATE TABLE IF NOT EXISTS suggestions (
+		phrase TEXT PRIMARY KEY,
+		documents INTEGER NOT NULL,
+		score DOUBLE PRECISION NOT NULL
+	);`
+
+	// Create saved search tables; saved_search_alerts remembers the
+	// documents each search has already alerted
+	savedSearchesSQL := `
+	CREATE TABLE IF NOT EXISTS saved_searches (
+		id BIGSERIAL PRIMARY KEY,
+		name TEXT NOT NULL,
+		query TEXT NOT NULL,
+		filters JSONB,
+		min_score REAL NOT NULL DEFAULT 0,
+		webhook TEXT,
+		email TEXT,
+		principals TEXT[],
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	savedSearchAlertsSQL := `
+	CREATE TABLE IF NOT EXISTS saved_search_alerts (
+		saved_search_id BIGINT NOT NULL,
+		document_id VARCHAR(255) NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (saved_search_id, document_id),
+		FOREIGN KEY (saved_search_id) REFERENCES saved_searches (id) ON DELETE CASCADE
+	);`
+
+	// Add soft delete column to existing databases
+	migrationsSQL := []string{
+		"ALTER TABLE documents ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;",
+		"ALTER TABLE documents ADD COLUMN IF NOT EXISTS crawled_at TIMESTAMP;",
+		"ALTER TABLE documents ADD COLUMN IF NOT EXISTS first_seen_at TIMESTAMP;",
+		"ALTER TABLE documents ADD COLUMN IF NOT EXISTS times_changed INTEGER NOT NULL DEFAULT 0;",
+		"ALTER TABLE documents ADD COLUMN IF NOT EXISTS last_status INTEGER;",
+		"ALTER TABLE documents ADD COLUMN IF NOT EXISTS simhash BIGINT;",
+	}
+
 	// Create indexes
 	indexesSQL := []string{
 		"CREATE INDEX IF NOT EXISTS idx_documents_url ON documents (url);",
@@ -130,6 +601,14 @@ func (s *postgresStore) initSchema() error {
 		"CREATE INDEX IF NOT EXISTS idx_chunks_text ON chunks USING gin(to_tsvector('english', text));",
 		"CREATE INDEX IF NOT EXISTS idx_documents_meta ON documents USING gin(meta);",
 		"CREATE INDEX IF NOT EXISTS idx_chunks_metadata ON chunks USING gin(metadata);",
+		"CREATE INDEX IF NOT EXISTS idx_documents_deleted_at ON documents (deleted_at) WHERE deleted_at IS NOT NULL;",
+		"CREATE INDEX IF NOT EXISTS idx_dead_letters_stage ON dead_letters (stage, created_at);",
+		"CREATE INDEX IF NOT EXISTS idx_dead_letters_document_id ON dead_letters (document_id);",
+		"CREATE INDEX IF NOT EXISTS idx_query_log_query ON query_log (query);",
+		"CREATE INDEX IF NOT EXISTS idx_query_log_created_at ON query_log (created_at);",
+		"CREATE INDEX IF NOT EXISTS idx_search_feedback_created_at ON search_feedback (created_at);",
+		"CREATE INDEX IF NOT EXISTS idx_crawl_updates_crawl_id ON crawl_updates (crawl_id, id);",
+		"CREATE INDEX IF NOT EXISTS idx_suggestions_phrase_prefix ON suggestions (phrase text_pattern_ops);",
 	}
 
 	if _, err := s.db.Exec(documentsSQL); err != nil {
@@ -140,6 +619,64 @@ func (s *postgresStore) initSchema() error {
 		return fmt.Errorf("failed to create chunks table: %w", err)
 	}
 
+	if _, err := s.db.Exec(frontierSQL); err != nil {
+		return fmt.Errorf("failed to create crawl_frontier table: %w", err)
+	}
+
+	if _, err := s.db.Exec(trafficSQL); err != nil {
+		return fmt.Errorf("failed to create document_traffic table: %w", err)
+	}
+
+	if _, err := s.db.Exec(robotsSQL); err != nil {
+		return fmt.Errorf("failed to create robots_rules table: %w", err)
+	}
+
+	if _, err := s.db.Exec(deadLettersSQL); err != nil {
+		return fmt.Errorf("failed to create dead_letters table: %w", err)
+	}
+
+	if _, err := s.db.Exec(queryLogSQL); err != nil {
+		return fmt.Errorf("failed to create query_log table: %w", err)
+	}
+
+	if _, err := s.db.Exec(feedbackSQL); err != nil {
+		return fmt.Errorf("failed to create search_feedback table: %w", err)
+	}
+
+	if _, err := s.db.Exec(crawlRunsSQL); err != nil {
+		return fmt.Errorf("failed to create crawl_runs table: %w", err)
+	}
+
+	if _, err := s.db.Exec(crawlUpdatesSQL); err != nil {
+		return fmt.Errorf("failed to create crawl_updates table: %w", err)
+	}
+
+	if _, err := s.db.Exec(exportCursorsSQL); err != nil {
+		return fmt.Errorf("failed to create export_cursors table: %w", err)
+	}
+
+	if _, err := s.db.Exec(apiKeyUsageSQL); err != nil {
+		return fmt.Errorf("failed to create api_key_usage table: %w", err)
+	}
+
+	if _, err := s.db.Exec(suggestionsSQL); err != nil {
+		return fmt.Errorf("failed to create suggestions table: %w", err)
+	}
+
+	if _, err := s.db.Exec(savedSearchesSQL); err != nil {
+		return fmt.Errorf("failed to create saved_searches table: %w", err)
+	}
+
+	if _, err := s.db.Exec(savedSearchAlertsSQL); err != nil {
+		return fmt.Errorf("failed to create saved_search_alerts table: %w", err)
+	}
+
+	for _, migrationSQL := range migrationsSQL {
+		if _, err := s.db.Exec(migrationSQL); err != nil {
+			return fmt.Errorf("failed to migrate schema: %w", err)
+		}
+	}
+
 	for _, indexSQL := range indexesSQL {
 		if _, err := s.db.Exec(indexSQL); err != nil {
 			return fmt.Errorf("failed to create index: %w", err)
@@ -162,19 +699,36 @@ func (s *postgresStore) SaveDocument(ctx context.Context, doc *Document) error {
 	}
 
 	query := `
-	INSERT INTO documents (id, url, title, content, meta, updated_at)
-	VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP)
+	INSERT INTO documents (id, url, title, content, meta, simhash, updated_at, crawled_at, first_seen_at, last_status)
+	VALUES ($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, 200)
 	ON CONFLICT (id) DO UPDATE SET
 		url = EXCLUDED.url,
 		title = EXCLUDED.title,
 		content = EXCLUDED.content,
-		meta = EXCLUDED.meta,
-		updated_at = CURRENT_TIMESTAMP`
+		simhash = EXCLUDED.simhash,
+		meta = CASE WHEN documents.meta ? 'alternate_urls'
+			THEN COALESCE(EXCLUDED.meta, '{}'::jsonb) || jsonb_build_object('alternate_urls', documents.meta->'alternate_urls')
+			ELSE EXCLUDED.meta
+		END,
+		updated_at = CURRENT_TIMESTAMP,
+		crawled_at = CURRENT_TIMESTAMP,
+		last_status = 200
+	RETURNING deleted_at`
+
+	// Fingerprints are stored as signed BIGINTs of the same bits
+	var simhash sql.NullInt64
+	if doc.Fingerprint != nil {
+		simhash = sql.NullInt64{Int64: int64(*doc.Fingerprint), Valid: true}
+	}
 
-	_, err := s.db.ExecContext(ctx, query, doc.ID, doc.URL, doc.Title, doc.Content, metaJSON)
+	var deletedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, query, doc.ID, doc.URL, doc.Title, doc.Content, metaJSON, simhash).Scan(&deletedAt)
 	if err != nil {
 		return fmt.Errorf("failed to save document: %w", err)
 	}
+	if deletedAt.Valid {
+		doc.DeletedAt = &deletedAt.Time
+	}
 
 	return nil
 }
@@ -182,14 +736,20 @@ func (s *postgresStore) SaveDocument(ctx context.Context, doc *Document) error {
 // GetDocument retrieves a document by ID
 func (s *postgresStore) GetDocument(ctx context.Context, id string) (*Document, error) {
 	query := `
-	SELECT id, url, title, content, meta, created_at, updated_at
+	SELECT id, url, title, content, meta, created_at, updated_at, deleted_at, simhash,
+		COALESCE(first_seen_at, created_at), COALESCE(crawled_at, updated_at), times_changed, COALESCE(last_status, 0)
 	FROM documents WHERE id = $1`
 
 	var doc Document
 	var createdAt, updatedAt time.Time
+	var deletedAt sql.NullTime
+	var simhash sql.NullInt64
+	var metaJSON []byte
+	var provenance Provenance
 
 	err := s.db.QueryRowContext(ctx, query, id).Scan(
-		&doc.ID, &doc.URL, &doc.Title, &doc.Content, &doc.Meta, &createdAt, &updatedAt,
+		&doc.ID, &doc.URL, &doc.Title, &doc.Content, &metaJSON, &createdAt, &updatedAt, &deletedAt, &simhash,
+		&provenance.FirstSeen, &provenance.LastCrawled, &provenance.TimesChanged, &provenance.LastStatus,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -198,12 +758,190 @@ func (s *postgresStore) GetDocument(ctx context.Context, id string) (*Document,
 		return nil, fmt.Errorf("failed to get document: %w", err)
 	}
 
+	if len(metaJSON) > 0 {
+		if err := json.Unmarshal(metaJSON, &doc.Meta); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+		}
+	}
+
 	doc.CreatedAt = createdAt
 	doc.UpdatedAt = updatedAt
+	if deletedAt.Valid {
+		doc.DeletedAt = &deletedAt.Time
+	}
+	if simhash.Valid {
+		fingerprint := uint64(simhash.Int64)
+		doc.Fingerprint = &fingerprint
+	}
+	doc.Provenance = &provenance
 
 	return &doc, nil
 }
 
+// ListDocuments retrieves all stored documents that are not soft-deleted
+func (s *postgresStore) ListDocuments(ctx context.Context) ([]*Document, error) {
+	query := `
+	SELECT id, url, title, content, meta, created_at, updated_at
+	FROM documents WHERE deleted_at IS NULL ORDER BY created_at`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query documents: %w", err)
+	}
+	defer rows.Close()
+
+	var docs []*Document
+	for rows.Next() {
+		var doc Document
+		var metaJSON []byte
+
+		err := rows.Scan(&doc.ID, &doc.URL, &doc.Title, &doc.Content, &metaJSON, &doc.CreatedAt, &doc.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+
+		if len(metaJSON) > 0 {
+			if err := json.Unmarshal(metaJSON, &doc.Meta); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+			}
+		}
+
+		docs = append(docs, &doc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate documents: %w", err)
+	}
+
+	return docs, nil
+}
+
+// ListFingerprints lists the fingerprints of the documents that are not
+// soft-deleted, oldest first
+func (s *postgresStore) ListFingerprints(ctx context.Context) ([]*DocumentFingerprint, error) {
+	query := `
+	SELECT id, url, COALESCE(title, ''), octet_length(content), simhash
+	FROM documents WHERE deleted_at IS NULL ORDER BY created_at`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query fingerprints: %w", err)
+	}
+	defer rows.Close()
+
+	var fingerprints []*DocumentFingerprint
+	for rows.Next() {
+		var doc DocumentFingerprint
+		var simhash sql.NullInt64
+		if err := rows.Scan(&doc.ID, &doc.URL, &doc.Title, &doc.Length, &simhash); err != nil {
+			return nil, fmt.Errorf("failed to scan fingerprint: %w", err)
+		}
+		if simhash.Valid {
+			fingerprint := uint64(simhash.Int64)
+			doc.Fingerprint = &fingerprint
+		}
+		fingerprints = append(fingerprints, &doc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate fingerprints: %w", err)
+	}
+
+	return fingerprints, nil
+}
+
+// SetFingerprint records the fingerprint of a document
+func (s *postgresStore) SetFingerprint(ctx context.Context, id string, fingerprint uint64) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE documents SET simhash = $2 WHERE id = $1", id, int64(fingerprint))
+	if err != nil {
+		return fmt.Errorf("failed to set fingerprint: %w", err)
+	}
+	return nil
+}
+
+// DeleteDocument deletes a document and its chunks
+func (s *postgresStore) DeleteDocument(ctx context.Context, id string) error {
+	// Chunks are removed by the ON DELETE CASCADE foreign key
+	result, err := s.db.ExecContext(ctx, "DELETE FROM documents WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("document not found: %s", id)
+	}
+
+	return nil
+}
+
+// SoftDeleteDocument marks a document as deleted without removing it
+func (s *postgresStore) SoftDeleteDocument(ctx context.Context, id string) error {
+	query := `
+	UPDATE documents SET deleted_at = CURRENT_TIMESTAMP
+	WHERE id = $1 AND deleted_at IS NULL`
+
+	result, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to soft delete document: %w", err)
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("document not found or already deleted: %s", id)
+	}
+
+	return nil
+}
+
+// RestoreDocument clears the deletion mark of a soft-deleted document
+func (s *postgresStore) RestoreDocument(ctx context.Context, id string) error {
+	query := `
+	UPDATE documents SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	result, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to restore document: %w", err)
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("no deleted document found: %s", id)
+	}
+
+	return nil
+}
+
+// ListDeletedDocumentIDs returns soft-deleted document IDs, limited to
+// those deleted before the given time unless it is zero
+func (s *postgresStore) ListDeletedDocumentIDs(ctx context.Context, deletedBefore time.Time) ([]string, error) {
+	query := "SELECT id FROM documents WHERE deleted_at IS NOT NULL"
+	var args []interface{}
+	if !deletedBefore.IsZero() {
+		query += " AND deleted_at < $1"
+		args = append(args, deletedBefore)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deleted documents: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan document ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate deleted documents: %w", err)
+	}
+
+	return ids, nil
+}
+
 // SaveChunks saves document chunks
 func (s *postgresStore) SaveChunks(ctx context.Context, docID string, chunks []*chunker.Chunk) error {
 	if len(chunks) == 0 {
@@ -270,12 +1008,19 @@ func (s *postgresStore) GetChunks(ctx context.Context, docID string) ([]*chunker
 	var chunks []*chunker.Chunk
 	for rows.Next() {
 		var chunk chunker.Chunk
+		var metadataJSON []byte
 
-		err := rows.Scan(&chunk.ID, &chunk.Text, &chunk.StartPos, &chunk.EndPos, &chunk.Metadata)
+		err := rows.Scan(&chunk.ID, &chunk.Text, &chunk.StartPos, &chunk.EndPos, &metadataJSON)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan chunk: %w", err)
 		}
 
+		if len(metadataJSON) > 0 {
+			if err := json.Unmarshal(metadataJSON, &chunk.Metadata); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal chunk metadata: %w", err)
+			}
+		}
+
 		chunks = append(chunks, &chunk)
 	}
 
@@ -286,6 +1031,992 @@ func (s *postgresStore) GetChunks(ctx context.Context, docID string) ([]*chunker
 	return chunks, nil
 }
 
+// GetChunkDocumentID returns the ID of the document a chunk belongs to
+func (s *postgresStore) GetChunkDocumentID(ctx context.Context, chunkID string) (string, error) {
+	var docID string
+	err := s.db.QueryRowContext(ctx, "SELECT document_id FROM chunks WHERE id = $1", chunkID).Scan(&docID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", fmt.Errorf("chunk not found: %s", chunkID)
+		}
+		return "", fmt.Errorf("failed to get chunk: %w", err)
+	}
+
+	return docID, nil
+}
+
+// AddFrontierURLs records pending URLs of a crawl, ignoring known URLs
+func (s *postgresStore) AddFrontierURLs(ctx context.Context, crawlID string, urls []*FrontierURL) error {
+	if len(urls) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	insertQuery := `
+	INSERT INTO crawl_frontier (crawl_id, url, depth)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (crawl_id, url) DO NOTHING`
+
+	for _, u := range urls {
+		if _, err := tx.ExecContext(ctx, insertQuery, crawlID, u.URL, u.Depth); err != nil {
+			return fmt.Errorf("failed to insert frontier URL: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// MarkFrontierVisited marks a crawl URL as visited
+func (s *postgresStore) MarkFrontierVisited(ctx context.Context, crawlID string, url string) error {
+	query := `
+	INSERT INTO crawl_frontier (crawl_id, url, depth, visited)
+	VALUES ($1, $2, 0, TRUE)
+	ON CONFLICT (crawl_id, url) DO UPDATE SET
+		visited = TRUE,
+		updated_at = CURRENT_TIMESTAMP`
+
+	if _, err := s.db.ExecContext(ctx, query, crawlID, url); err != nil {
+		return fmt.Errorf("failed to mark frontier URL visited: %w", err)
+	}
+
+	return nil
+}
+
+// ClearFrontier removes the pending and visited URLs of a crawl
+func (s *postgresStore) ClearFrontier(ctx context.Context, crawlID string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM crawl_frontier WHERE crawl_id = $1`, crawlID); err != nil {
+		return fmt.Errorf("failed to clear frontier: %w", err)
+	}
+
+	return nil
+}
+
+// GetFrontier retrieves the pending and visited URLs of a crawl
+func (s *postgresStore) GetFrontier(ctx context.Context, crawlID string) ([]*FrontierURL, []string, error) {
+	query := `
+	SELECT url, depth, visited
+	FROM crawl_frontier WHERE crawl_id = $1
+	ORDER BY depth, created_at`
+
+	rows, err := s.db.QueryContext(ctx, query, crawlID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query frontier: %w", err)
+	}
+	defer rows.Close()
+
+	var pending []*FrontierURL
+	var visited []string
+	for rows.Next() {
+		var u FrontierURL
+		var isVisited bool
+
+		if err := rows.Scan(&u.URL, &u.Depth, &isVisited); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan frontier URL: %w", err)
+		}
+
+		if isVisited {
+			visited = append(visited, u.URL)
+		} else {
+			pending = append(pending, &u)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to iterate frontier: %w", err)
+	}
+
+	return pending, visited, nil
+}
+
+// RecordImpressions counts one appearance in search results for each document
+func (s *postgresStore) RecordImpressions(ctx context.Context, documentIDs []string) error {
+	if len(documentIDs) == 0 {
+		return nil
+	}
+
+	// Only stored documents are counted; the rest are skipped by the join
+	query := `
+	INSERT INTO document_traffic (document_id, impressions, last_seen_at)
+	SELECT id, 1, CURRENT_TIMESTAMP FROM documents WHERE id = ANY($1)
+	ON CONFLICT (document_id) DO UPDATE SET
+		impressions = document_traffic.impressions + 1,
+		last_seen_at = CURRENT_TIMESTAMP`
+
+	if _, err := s.db.ExecContext(ctx, query, pq.Array(documentIDs)); err != nil {
+		return fmt.Errorf("failed to record impressions: %w", err)
+	}
+
+	return nil
+}
+
+// ListPopularDocuments returns the documents that appeared most in search results
+func (s *postgresStore) ListPopularDocuments(ctx context.Context, limit int) ([]*DocumentTraffic, error) {
+	query := `
+	SELECT t.document_id, d.url, t.impressions, t.last_seen_at, COALESCE(d.crawled_at, d.updated_at)
+	FROM document_traffic t
+	JOIN documents d ON d.id = t.document_id
+	WHERE d.deleted_at IS NULL
+	ORDER BY t.impressions DESC, t.last_seen_at DESC
+	LIMIT $1`
+
+	rows, err := s.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query document traffic: %w", err)
+	}
+	defer rows.Close()
+
+	var traffic []*DocumentTraffic
+	for rows.Next() {
+		var t DocumentTraffic
+		if err := rows.Scan(&t.DocumentID, &t.URL, &t.Impressions, &t.LastSeenAt, &t.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan document traffic: %w", err)
+		}
+		traffic = append(traffic, &t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate document traffic: %w", err)
+	}
+
+	return traffic, nil
+}
+
+// ListRecrawlCandidates returns every live document with the time since
+// it was last crawled and its search traffic
+func (s *postgresStore) ListRecrawlCandidates(ctx context.Context) ([]*RecrawlCandidate, error) {
+	// Ages are computed by the database so they compare with its own clock
+	query := `
+	SELECT d.id, d.url, COALESCE(d.meta->>'content_hash', d.id),
+		EXTRACT(EPOCH FROM CURRENT_TIMESTAMP - COALESCE(d.crawled_at, d.updated_at)),
+		COALESCE(t.impressions, 0)
+	FROM documents d
+	LEFT JOIN document_traffic t ON t.document_id = d.id
+	WHERE d.deleted_at IS NULL`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recrawl candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []*RecrawlCandidate
+	for rows.Next() {
+		var c RecrawlCandidate
+		var ageSeconds float64
+		if err := rows.Scan(&c.DocumentID, &c.URL, &c.ContentHash, &ageSeconds, &c.Impressions); err != nil {
+			return nil, fmt.Errorf("failed to scan recrawl candidate: %w", err)
+		}
+		c.Age = time.Duration(ageSeconds * float64(time.Second))
+		candidates = append(candidates, &c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate recrawl candidates: %w", err)
+	}
+
+	return candidates, nil
+}
+
+// MarkCrawled records that a document was just crawled and the HTTP
+// status it was served with
+func (s *postgresStore) MarkCrawled(ctx context.Context, id string, status int) error {
+	query := `UPDATE documents SET crawled_at = CURRENT_TIMESTAMP, last_status = $2 WHERE id = $1`
+
+	if _, err := s.db.ExecContext(ctx, query, id, status); err != nil {
+		return fmt.Errorf("failed to mark document crawled: %w", err)
+	}
+
+	return nil
+}
+
+// CarryProvenance hands the crawl history of a document to the document
+// replacing it, keeping the earliest first sighting and counting the change
+func (s *postgresStore) CarryProvenance(ctx context.Context, fromID, toID string) error {
+	query := `
+	UPDATE documents d SET
+		first_seen_at = LEAST(COALESCE(d.first_seen_at, d.created_at), COALESCE(f.first_seen_at, f.created_at)),
+		times_changed = d.times_changed + f.times_changed + 1
+	FROM documents f
+	WHERE d.id = $2 AND f.id = $1`
+
+	if _, err := s.db.ExecContext(ctx, query, fromID, toID); err != nil {
+		return fmt.Errorf("failed to carry document provenance: %w", err)
+	}
+
+	return nil
+}
+
+// GetProvenance returns the crawl history of the given documents
+func (s *postgresStore) GetProvenance(ctx context.Context, ids []string) (map[string]*Provenance, error) {
+	provenance := make(map[string]*Provenance)
+	if len(ids) == 0 {
+		return provenance, nil
+	}
+
+	query := `
+	SELECT id, COALESCE(first_seen_at, created_at), COALESCE(crawled_at, updated_at), times_changed, COALESCE(last_status, 0)
+	FROM documents WHERE id = ANY($1)`
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query document provenance: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var p Provenance
+		if err := rows.Scan(&id, &p.FirstSeen, &p.LastCrawled, &p.TimesChanged, &p.LastStatus); err != nil {
+			return nil, fmt.Errorf("failed to scan document provenance: %w", err)
+		}
+		provenance[id] = &p
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate document provenance: %w", err)
+	}
+
+	return provenance, nil
+}
+
+// GetLanguageVariants returns the language variants of the given documents
+func (s *postgresStore) GetLanguageVariants(ctx context.Context, ids []string) (map[string]map[string]string, error) {
+	variants := make(map[string]map[string]string)
+	if len(ids) == 0 {
+		return variants, nil
+	}
+
+	query := `SELECT id, meta->$2 FROM documents WHERE id = ANY($1) AND meta ? $2`
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(ids), LanguageVariantsMetaKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query language variants: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var variantsJSON []byte
+		if err := rows.Scan(&id, &variantsJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan language variants: %w", err)
+		}
+		var documentVariants map[string]string
+		if err := json.Unmarshal(variantsJSON, &documentVariants); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal language variants: %w", err)
+		}
+		variants[id] = documentVariants
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate language variants: %w", err)
+	}
+
+	return variants, nil
+}
+
+// UpdateDocumentMeta sets the given metadata keys of a document, keeping the others
+func (s *postgresStore) UpdateDocumentMeta(ctx context.Context, id string, meta map[string]interface{}) error {
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	query := `UPDATE documents SET meta = COALESCE(meta, '{}'::jsonb) || $2::jsonb WHERE id = $1`
+
+	if _, err := s.db.ExecContext(ctx, query, id, string(metaJSON)); err != nil {
+		return fmt.Errorf("failed to update document metadata: %w", err)
+	}
+
+	return nil
+}
+
+// AddAlternateURL records url as another address of a stored document
+func (s *postgresStore) AddAlternateURL(ctx context.Context, id string, url string) (bool, error) {
+	query := `
+	UPDATE documents SET meta = jsonb_set(
+		COALESCE(meta, '{}'::jsonb),
+		'{alternate_urls}',
+		CASE WHEN COALESCE(meta->'alternate_urls', '[]'::jsonb) ? $2
+			THEN meta->'alternate_urls'
+			ELSE COALESCE(meta->'alternate_urls', '[]'::jsonb) || to_jsonb($2::text)
+		END)
+	WHERE id = $1 AND url <> $2 AND deleted_at IS NULL
+	RETURNING id`
+
+	err := s.db.QueryRowContext(ctx, query, id, url).Scan(&id)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to add alternate URL: %w", err)
+	}
+
+	return true, nil
+}
+
+// GetRobotsRules returns the unexpired robots.txt rules stored for a host
+func (s *postgresStore) GetRobotsRules(ctx context.Context, host string) ([]byte, error) {
+	query := `SELECT rules FROM robots_rules WHERE host = $1 AND expires_at > CURRENT_TIMESTAMP`
+
+	var rules []byte
+	err := s.db.QueryRowContext(ctx, query, host).Scan(&rules)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get robots.txt rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// SaveRobotsRules stores robots.txt rules for a host for the given time
+func (s *postgresStore) SaveRobotsRules(ctx context.Context, host string, rules []byte, ttl time.Duration) error {
+	// Expiry is computed by the database so it compares with its own clock
+	query := `
+	INSERT INTO robots_rules (host, rules, expires_at)
+	VALUES ($1, $2, CURRENT_TIMESTAMP + make_interval(secs => $3))
+	ON CONFLICT (host) DO UPDATE SET
+		rules = EXCLUDED.rules,
+		expires_at = EXCLUDED.expires_at`
+
+	if _, err := s.db.ExecContext(ctx, query, host, rules, ttl.Seconds()); err != nil {
+		return fmt.Errorf("failed to save robots.txt rules: %w", err)
+	}
+
+	return nil
+}
+
+// AddDeadLetter records a pipeline item that permanently failed
+func (s *postgresStore) AddDeadLetter(ctx context.Context, item *DeadLetter) error {
+	query := `
+	INSERT INTO dead_letters (stage, document_id, chunk_id, payload, error, attempts)
+	VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6)
+	RETURNING id, created_at`
+
+	var payload interface{}
+	if len(item.Payload) > 0 {
+		payload = string(item.Payload)
+	}
+
+	err := s.db.QueryRowContext(ctx, query, item.Stage, item.DocumentID, item.ChunkID, payload, item.Error, item.Attempts).
+		Scan(&item.ID, &item.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add dead letter: %w", err)
+	}
+
+	return nil
+}
+
+// ListDeadLetters returns failed pipeline items, oldest first, limited to
+// one stage unless stage is empty
+func (s *postgresStore) ListDeadLetters(ctx context.Context, stage string) ([]*DeadLetter, error) {
+	query := `
+	SELECT id, stage, document_id, COALESCE(chunk_id, ''), payload, error, attempts, created_at
+	FROM dead_letters`
+	var args []interface{}
+	if stage != "" {
+		query += " WHERE stage = $1"
+		args = append(args, stage)
+	}
+	query += " ORDER BY id"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*DeadLetter
+	for rows.Next() {
+		var item DeadLetter
+		if err := rows.Scan(&item.ID, &item.Stage, &item.DocumentID, &item.ChunkID, &item.Payload,
+			&item.Error, &item.Attempts, &item.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan dead letter: %w", err)
+		}
+		items = append(items, &item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate dead letters: %w", err)
+	}
+
+	return items, nil
+}
+
+// UpdateDeadLetter records another failed attempt at a dead letter
+func (s *postgresStore) UpdateDeadLetter(ctx context.Context, id int64, errMsg string) error {
+	query := "UPDATE dead_letters SET error = $2, attempts = attempts + 1 WHERE id = $1"
+
+	if _, err := s.db.ExecContext(ctx, query, id, errMsg); err != nil {
+		return fmt.Errorf("failed to update dead letter: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteDeadLetter removes a dead letter once it has been reprocessed
+func (s *postgresStore) DeleteDeadLetter(ctx context.Context, id int64) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM dead_letters WHERE id = $1", id); err != nil {
+		return fmt.Errorf("failed to delete dead letter: %w", err)
+	}
+
+	return nil
+}
+
+// LogQuery records a search and the chunks it returned, in rank order
+func (s *postgresStore) LogQuery(ctx context.Context, entry *QueryLog) error {
+	query := `
+	INSERT INTO query_log (query, language, chunk_ids)
+	VALUES ($1, NULLIF($2, ''), $3)
+	RETURNING id, created_at`
+
+	err := s.db.QueryRowContext(ctx, query, entry.Query, entry.Language, pq.Array(entry.ChunkIDs)).
+		Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to log query: %w", err)
+	}
+
+	return nil
+}
+
+// ListQueryLogs returns the logged searches for the given queries
+func (s *postgresStore) ListQueryLogs(ctx context.Context, queries []string) ([]*QueryLog, error) {
+	query := `
+	SELECT id, query, COALESCE(language, ''), chunk_ids, created_at
+	FROM query_log WHERE query = ANY($1)
+	ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(queries))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query query log: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*QueryLog
+	for rows.Next() {
+		var entry QueryLog
+		if err := rows.Scan(&entry.ID, &entry.Query, &entry.Language, pq.Array(&entry.ChunkIDs), &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan query log: %w", err)
+		}
+		entries = append(entries, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate query log: %w", err)
+	}
+
+	return entries, nil
+}
+
+// AddFeedback records whether a chunk was relevant to a query, replacing
+// earlier feedback on the same pair
+func (s *postgresStore) AddFeedback(ctx context.Context, feedback *Feedback) error {
+	query := `
+	INSERT INTO search_feedback (query, chunk_id, relevant)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (query, chunk_id) DO UPDATE SET
+		relevant = EXCLUDED.relevant,
+		created_at = CURRENT_TIMESTAMP
+	RETURNING created_at`
+
+	err := s.db.QueryRowContext(ctx, query, feedback.Query, feedback.ChunkID, feedback.Relevant).
+		Scan(&feedback.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add feedback: %w", err)
+	}
+
+	return nil
+}
+
+// ListFeedback returns all relevance feedback
+func (s *postgresStore) ListFeedback(ctx context.Context) ([]*Feedback, error) {
+	query := `
+	SELECT query, chunk_id, relevant, created_at
+	FROM search_feedback ORDER BY query, created_at`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feedback: %w", err)
+	}
+	defer rows.Close()
+
+	var feedback []*Feedback
+	for rows.Next() {
+		var f Feedback
+		if err := rows.Scan(&f.Query, &f.ChunkID, &f.Relevant, &f.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan feedback: %w", err)
+		}
+		feedback = append(feedback, &f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate feedback: %w", err)
+	}
+
+	return feedback, nil
+}
+
+// GetChunkTexts returns the text of the given chunks, skipping chunks that
+// no longer exist or belong to soft-deleted documents
+func (s *postgresStore) GetChunkTexts(ctx context.Context, chunkIDs []string) (map[string]string, error) {
+	texts := make(map[string]string)
+	if len(chunkIDs) == 0 {
+		return texts, nil
+	}
+
+	query := `
+	SELECT c.id, c.text
+	FROM chunks c
+	JOIN documents d ON d.id = c.document_id
+	WHERE c.id = ANY($1) AND d.deleted_at IS NULL`
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(chunkIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chunk texts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, text string
+		if err := rows.Scan(&id, &text); err != nil {
+			return nil, fmt.Errorf("failed to scan chunk text: %w", err)
+		}
+		texts[id] = text
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate chunk texts: %w", err)
+	}
+
+	return texts, nil
+}
+
+// ReplaceSuggestions replaces the query suggestions with a newly built set
+func (s *postgresStore) ReplaceSuggestions(ctx context.Context, suggestions []*Suggestion) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM suggestions"); err != nil {
+		return fmt.Errorf("failed to delete suggestions: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("suggestions", "phrase", "documents", "score"))
+	if err != nil {
+		return fmt.Errorf("failed to prepare suggestions copy: %w", err)
+	}
+	for _, suggestion := range suggestions {
+		if _, err := stmt.ExecContext(ctx, suggestion.Phrase, suggestion.Documents, suggestion.Score); err != nil {
+			stmt.Close()
+			return fmt.Errorf("failed to copy suggestion: %w", err)
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return fmt.Errorf("failed to copy suggestions: %w", err)
+	}
+	if err := stmt.Close(); err != nil {
+		return fmt.Errorf("failed to copy suggestions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ListSuggestions returns the suggestions starting with a prefix, best first
+func (s *postgresStore) ListSuggestions(ctx context.Context, prefix string, limit int) ([]*Suggestion, error) {
+	// Escape LIKE wildcards so the prefix matches literally
+	pattern := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix) + "%"
+
+	query := `
+	SELECT phrase, documents, score
+	FROM suggestions WHERE phrase LIKE $1
+	ORDER BY score DESC, phrase
+	LIMIT $2`
+
+	rows, err := s.db.QueryContext(ctx, query, pattern, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query suggestions: %w", err)
+	}
+	defer rows.Close()
+
+	var suggestions []*Suggestion
+	for rows.Next() {
+		var suggestion Suggestion
+		if err := rows.Scan(&suggestion.Phrase, &suggestion.Documents, &suggestion.Score); err != nil {
+			return nil, fmt.Errorf("failed to scan suggestion: %w", err)
+		}
+		suggestions = append(suggestions, &suggestion)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate suggestions: %w", err)
+	}
+
+	return suggestions, nil
+}
+
+// ListQueryLogsBetween returns the searches logged from one time up to
+// another, oldest first, at most limit of them unless limit is 0
+func (s *postgresStore) ListQueryLogsBetween(ctx context.Context, from, to time.Time, limit int) ([]*QueryLog, error) {
+	query := `
+	SELECT id, query, COALESCE(language, ''), chunk_ids, created_at
+	FROM query_log WHERE created_at >= $1 AND created_at < $2
+	ORDER BY created_at, id
+	LIMIT NULLIF($3, 0)`
+
+	rows, err := s.db.QueryContext(ctx, query, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query query log: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*QueryLog
+	for rows.Next() {
+		var entry QueryLog
+		if err := rows.Scan(&entry.ID, &entry.Query, &entry.Language, pq.Array(&entry.ChunkIDs), &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan query log: %w", err)
+		}
+		entries = append(entries, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate query log: %w", err)
+	}
+
+	return entries, nil
+}
+
+// ListFeedbackBetween returns the feedback recorded from one time up to
+// another, oldest first, at most limit of them unless limit is 0. Feedback given again on the same pair moves to the
+// time it was last given.
+func (s *postgresStore) ListFeedbackBetween(ctx context.Context, from, to time.Time, limit int) ([]*Feedback, error) {
+	query := `
+	SELECT query, chunk_id, relevant, created_at
+	FROM search_feedback WHERE created_at >= $1 AND created_at < $2
+	ORDER BY created_at
+	LIMIT NULLIF($3, 0)`
+
+	rows, err := s.db.QueryContext(ctx, query, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feedback: %w", err)
+	}
+	defer rows.Close()
+
+	var feedback []*Feedback
+	for rows.Next() {
+		var f Feedback
+		if err := rows.Scan(&f.Query, &f.ChunkID, &f.Relevant, &f.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan feedback: %w", err)
+		}
+		feedback = append(feedback, &f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate feedback: %w", err)
+	}
+
+	return feedback, nil
+}
+
+// SaveCrawlRun records the stats of a finished crawl
+func (s *postgresStore) SaveCrawlRun(ctx context.Context, run *CrawlRun) error {
+	query := `
+	INSERT INTO crawl_runs (crawl_id, start_url, fetched, indexed, failed, skipped_by_robots,
+		duplicates, filtered, bytes_downloaded, duration_ms, stopped)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	RETURNING id, created_at`
+
+	err := s.db.QueryRowContext(ctx, query, run.CrawlID, run.StartURL, run.Fetched, run.Indexed, run.Failed,
+		run.SkippedByRobots, run.Duplicates, run.Filtered, run.BytesDownloaded, run.Duration.Milliseconds(), run.Stopped).
+		Scan(&run.ID, &run.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save crawl run: %w", err)
+	}
+
+	return nil
+}
+
+// ListCrawlRunsBetween returns the crawls recorded from one time up to
+// another, oldest first, at most limit of them unless limit is 0
+func (s *postgresStore) ListCrawlRunsBetween(ctx context.Context, from, to time.Time, limit int) ([]*CrawlRun, error) {
+	query := `
+	SELECT id, crawl_id, start_url, fetched, indexed, failed, skipped_by_robots,
+		duplicates, filtered, bytes_downloaded, duration_ms, stopped, created_at
+	FROM crawl_runs WHERE created_at >= $1 AND created_at < $2
+	ORDER BY created_at, id
+	LIMIT NULLIF($3, 0)`
+
+	rows, err := s.db.QueryContext(ctx, query, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query crawl runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []*CrawlRun
+	for rows.Next() {
+		var run CrawlRun
+		var durationMs int64
+		if err := rows.Scan(&run.ID, &run.CrawlID, &run.StartURL, &run.Fetched, &run.Indexed, &run.Failed,
+			&run.SkippedByRobots, &run.Duplicates, &run.Filtered, &run.BytesDownloaded, &durationMs,
+			&run.Stopped, &run.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan crawl run: %w", err)
+		}
+		run.Duration = time.Duration(durationMs) * time.Millisecond
+		runs = append(runs, &run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate crawl runs: %w", err)
+	}
+
+	return runs, nil
+}
+
+// AddCrawlUpdate records a change to a running crawl for its processes to
+// apply
+func (s *postgresStore) AddCrawlUpdate(ctx context.Context, update *CrawlUpdate) error {
+	query := `
+	INSERT INTO crawl_updates (crawl_id, seeds, deny_patterns, rate_limit)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at`
+
+	err := s.db.QueryRowContext(ctx, query, update.CrawlID, pq.Array(update.Seeds), pq.Array(update.DenyPatterns), update.RateLimit).
+		Scan(&update.ID, &update.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add crawl update: %w", err)
+	}
+
+	return nil
+}
+
+// ListCrawlUpdates returns the updates of a crawl recorded after the one
+// with the given ID, oldest first
+func (s *postgresStore) ListCrawlUpdates(ctx context.Context, crawlID string, afterID int64) ([]*CrawlUpdate, error) {
+	query := `
+	SELECT id, crawl_id, seeds, deny_patterns, rate_limit, created_at
+	FROM crawl_updates WHERE crawl_id = $1 AND id > $2
+	ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query, crawlID, afterID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query crawl updates: %w", err)
+	}
+	defer rows.Close()
+
+	var updates []*CrawlUpdate
+	for rows.Next() {
+		var update CrawlUpdate
+		if err := rows.Scan(&update.ID, &update.CrawlID, pq.Array(&update.Seeds), pq.Array(&update.DenyPatterns),
+			&update.RateLimit, &update.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan crawl update: %w", err)
+		}
+		updates = append(updates, &update)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate crawl updates: %w", err)
+	}
+
+	return updates, nil
+}
+
+// CurrentTimestamp returns the database's current time. Columns default to
+// CURRENT_TIMESTAMP stored without a time zone, so LOCALTIMESTAMP is their
+// clock.
+func (s *postgresStore) CurrentTimestamp(ctx context.Context) (time.Time, error) {
+	var now time.Time
+	if err := s.db.QueryRowContext(ctx, "SELECT LOCALTIMESTAMP").Scan(&now); err != nil {
+		return time.Time{}, fmt.Errorf("failed to get database time: %w", err)
+	}
+
+	return now, nil
+}
+
+// GetExportCursor returns the time up to which an export has shipped its
+// rows, zero when it never ran
+func (s *postgresStore) GetExportCursor(ctx context.Context, name string) (time.Time, error) {
+	var until time.Time
+	err := s.db.QueryRowContext(ctx, "SELECT exported_until FROM export_cursors WHERE name = $1", name).Scan(&until)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get export cursor: %w", err)
+	}
+
+	return until, nil
+}
+
+// SaveExportCursor records the time up to which an export has shipped its
+// rows
+func (s *postgresStore) SaveExportCursor(ctx context.Context, name string, until time.Time) error {
+	query := `
+	INSERT INTO export_cursors (name, exported_until)
+	VALUES ($1, $2)
+	ON CONFLICT (name) DO UPDATE SET exported_until = EXCLUDED.exported_until`
+
+	if _, err := s.db.ExecContext(ctx, query, name, until); err != nil {
+		return fmt.Errorf("failed to save export cursor: %w", err)
+	}
+
+	return nil
+}
+
+// AddAPIKeyUsage adds requests and LLM tokens to an API key's usage in a
+// month
+func (s *postgresStore) AddAPIKeyUsage(ctx context.Context, keyID, month string, requests, tokens int64) error {
+	query := `
+	INSERT INTO api_key_usage (key_id, month, requests, tokens)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (key_id, month) DO UPDATE SET
+		requests = api_key_usage.requests + EXCLUDED.requests,
+		tokens = api_key_usage.tokens + EXCLUDED.tokens`
+
+	if _, err := s.db.ExecContext(ctx, query, keyID, month, requests, tokens); err != nil {
+		return fmt.Errorf("failed to add API key usage: %w", err)
+	}
+
+	return nil
+}
+
+// GetAPIKeyUsage returns an API key's usage in a month, zero when it made
+// no requests
+func (s *postgresStore) GetAPIKeyUsage(ctx context.Context, keyID, month string) (*APIKeyUsage, error) {
+	usage := &APIKeyUsage{KeyID: keyID, Month: month}
+	err := s.db.QueryRowContext(ctx,
+		"SELECT requests, tokens FROM api_key_usage WHERE key_id = $1 AND month = $2", keyID, month).
+		Scan(&usage.Requests, &usage.Tokens)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get API key usage: %w", err)
+	}
+
+	return usage, nil
+}
+
+// AddSavedSearch registers a query whose new matches are alerted
+func (s *postgresStore) AddSavedSearch(ctx context.Context, search *SavedSearch) error {
+	var filtersJSON []byte
+	if len(search.Filters) > 0 {
+		var err error
+		filtersJSON, err = json.Marshal(search.Filters)
+		if err != nil {
+			return fmt.Errorf("failed to marshal filters: %w", err)
+		}
+	}
+
+	query := `
+	INSERT INTO saved_searches (name, query, filters, min_score, webhook, email, principals)
+	VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7)
+	RETURNING id, created_at`
+
+	err := s.db.QueryRowContext(ctx, query, search.Name, search.Query, filtersJSON, search.MinScore,
+		search.Webhook, search.Email, pq.Array(search.Principals)).
+		Scan(&search.ID, &search.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add saved search: %w", err)
+	}
+
+	return nil
+}
+
+// ListSavedSearches returns every saved search, oldest first
+func (s *postgresStore) ListSavedSearches(ctx context.Context) ([]*SavedSearch, error) {
+	query := `
+	SELECT id, name, query, filters, min_score, COALESCE(webhook, ''), COALESCE(email, ''), principals, created_at
+	FROM saved_searches ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query saved searches: %w", err)
+	}
+	defer rows.Close()
+
+	var searches []*SavedSearch
+	for rows.Next() {
+		var search SavedSearch
+		var filtersJSON []byte
+		if err := rows.Scan(&search.ID, &search.Name, &search.Query, &filtersJSON, &search.MinScore,
+			&search.Webhook, &search.Email, pq.Array(&search.Principals), &search.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan saved search: %w", err)
+		}
+		if len(filtersJSON) > 0 {
+			if err := json.Unmarshal(filtersJSON, &search.Filters); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal filters: %w", err)
+			}
+		}
+		searches = append(searches, &search)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate saved searches: %w", err)
+	}
+
+	return searches, nil
+}
+
+// DeleteSavedSearch removes a saved search and its alert history
+func (s *postgresStore) DeleteSavedSearch(ctx context.Context, id int64) (bool, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM saved_searches WHERE id = $1", id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete saved search: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete saved search: %w", err)
+	}
+
+	return deleted > 0, nil
+}
+
+// RecordAlerts records that a saved search matched documents and returns
+// those it had not matched before. Concurrent servers racing on the same
+// event each get a disjoint share, so no document is alerted twice.
+func (s *postgresStore) RecordAlerts(ctx context.Context, searchID int64, documentIDs []string) ([]string, error) {
+	query := `
+	INSERT INTO saved_search_alerts (saved_search_id, document_id)
+	SELECT $1, unnest($2::text[])
+	ON CONFLICT DO NOTHING
+	RETURNING document_id`
+
+	rows, err := s.db.QueryContext(ctx, query, searchID, pq.Array(documentIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to record alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var recorded []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan alert: %w", err)
+		}
+		recorded = append(recorded, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate alerts: %w", err)
+	}
+
+	return recorded, nil
+}
+
 // Close closes the store
 func (s *postgresStore) Close() error {
 	return s.db.Close()
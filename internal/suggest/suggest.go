@@ -0,0 +1,195 @@
+package suggest
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"ai-search/internal/indexer"
+	"ai-search/internal/parser"
+	"ai-search/internal/store"
+)
+
+// maxCandidates bounds the phrases counted at once. Past it, phrases seen
+// in a single document so far are dropped, which only loses phrases too
+// rare to suggest unless they recur in the documents still to come.
+const maxCandidates = 2_000_000
+
+// extraStopwords are function words that make poor phrase boundaries but
+// are too rare to tell languages apart by
+var extraStopwords = map[string]bool{
+	"a": true, "an": true, "or": true, "at": true, "from": true, "but": true, "if": true,
+	"we": true, "our": true, "your": true, "its": true, "their": true, "they": true,
+	"can": true, "will": true, "has": true, "had": true, "were": true, "been": true,
+	"which": true, "who": true, "what": true, "when": true, "how": true, "there": true,
+	"these": true, "those": true, "than": true, "then": true, "so": true, "into": true,
+	"about": true, "all": true, "also": true, "more": true, "may": true, "such": true,
+	"i": true, "he": true, "she": true, "his": true, "her": true, "do": true, "does": true,
+}
+
+// Builder extracts query suggestions from the corpus
+type Builder interface {
+	// Build extracts the suggestions from every stored document and
+	// replaces the stored suggestions with them, returning them best first
+	Build(ctx context.Context) ([]*store.Suggestion, error)
+}
+
+// Config holds suggestion building configuration
+type Config struct {
+	Store store.Store
+
+	MaxWords      int     // Longest phrase in words, 3 by default
+	MinDocuments  int     // Documents a phrase must occur in, 3 by default
+	MaxShare      float64 // Share of documents above which a phrase is too common to inform, 0.5 by default
+	MaxPhrases    int     // Suggestions kept, 50000 by default
+	MinWordLength int     // Shortest single-word suggestion in letters, 3 by default
+}
+
+// builder implements the Builder interface
+type builder struct {
+	config Config
+}
+
+// NewBuilder creates a new suggestion builder
+func NewBuilder(config Config) Builder {
+	if config.MaxWords <= 0 {
+		config.MaxWords = 3
+	}
+	if config.MinDocuments <= 0 {
+		config.MinDocuments = 3
+	}
+	if config.MaxShare <= 0 {
+		config.MaxShare = 0.5
+	}
+	if config.MaxPhrases <= 0 {
+		config.MaxPhrases = 50000
+	}
+	if config.MinWordLength <= 0 {
+		config.MinWordLength = 3
+	}
+
+	return &builder{config: config}
+}
+
+// Build counts the documents each phrase of up to MaxWords words occurs in,
+// keeps those frequent enough to suggest but not so common they inform
+// nothing, and scores them by document frequency weighted by inverse
+// document frequency, favouring longer phrases
+func (b *builder) Build(ctx context.Context) ([]*store.Suggestion, error) {
+	docs, err := b.config.Store.ListDocuments(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Suggestions are shown to every caller, so documents with an ACL do
+	// not contribute phrases
+	public := docs[:0]
+	for _, doc := range docs {
+		attributes, _ := doc.Meta["attributes"].(map[string]interface{})
+		if acl, _ := attributes[indexer.ACLAttribute].(string); len(indexer.ParseACL(acl)) == 0 {
+			public = append(public, doc)
+		}
+	}
+	docs = public
+
+	counts := make(map[string]int)
+	for _, doc := range docs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for phrase := range b.phrases(doc.Title + "\n" + doc.Content) {
+			counts[phrase]++
+		}
+		if len(counts) > maxCandidates {
+			for phrase, count := range counts {
+				if count == 1 {
+					delete(counts, phrase)
+				}
+			}
+		}
+	}
+
+	total := float64(len(docs))
+	maxDocuments := max(b.config.MinDocuments, int(b.config.MaxShare*total))
+	suggestions := make([]*store.Suggestion, 0, len(counts))
+	for phrase, count := range counts {
+		if count < b.config.MinDocuments || count > maxDocuments {
+			continue
+		}
+		words := strings.Count(phrase, " ") + 1
+		score := float64(count) * math.Log(1+total/float64(count)) * (1 + 0.5*float64(words-1))
+		suggestions = append(suggestions, &store.Suggestion{Phrase: phrase, Documents: count, Score: score})
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].Phrase < suggestions[j].Phrase
+	})
+	if len(suggestions) > b.config.MaxPhrases {
+		suggestions = suggestions[:b.config.MaxPhrases]
+	}
+
+	if err := b.config.Store.ReplaceSuggestions(ctx, suggestions); err != nil {
+		return nil, fmt.Errorf("failed to save suggestions: %w", err)
+	}
+	return suggestions, nil
+}
+
+// phrases returns the distinct phrases of a text that could be suggested.
+// Phrases do not cross punctuation, start or end with a stopword, or
+// contain a number alone.
+func (b *builder) phrases(text string) map[string]bool {
+	phrases := make(map[string]bool)
+	for _, segment := range strings.FieldsFunc(text, isPhraseBreak) {
+		words := strings.FieldsFunc(strings.ToLower(segment), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for start := range words {
+			if isStopword(words[start]) {
+				continue
+			}
+			for end := start; end < len(words) && end-start < b.config.MaxWords; end++ {
+				if isNumber(words[end]) {
+					break
+				}
+				if isStopword(words[end]) {
+					continue
+				}
+				if end == start && utf8.RuneCountInString(words[start]) < b.config.MinWordLength {
+					continue
+				}
+				phrases[strings.Join(words[start:end+1], " ")] = true
+			}
+		}
+	}
+	return phrases
+}
+
+// isPhraseBreak reports whether a character ends a phrase
+func isPhraseBreak(r rune) bool {
+	switch r {
+	case '\n', '.', ',', ';', ':', '!', '?', '(', ')', '[', ']', '{', '}', '"', '|', '/', '•':
+		return true
+	}
+	return false
+}
+
+// isStopword reports whether a word makes a poor start or end of a phrase
+func isStopword(word string) bool {
+	return extraStopwords[word] || parser.IsStopword(word)
+}
+
+// isNumber reports whether a word is made of digits only
+func isNumber(word string) bool {
+	for _, r := range word {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}